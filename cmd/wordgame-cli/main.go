@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameclient"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const usage = `Commands:
+  create [challenge seconds]        create a game, optionally with challenges
+  join <game id> <name>             join a game
+  resume <game id> <player id>      resume a session in a game
+  start                             start the game so no one else can join
+  board                             show the board, scores and your rack
+  play <square> <across|down> <tiles>
+                                    play tiles from a square such as H8, using
+                                    lowercase letters for blanks
+  swap <tiles>                      swap tiles with the bag, using ? for blanks
+  challenge                         challenge the last play
+  pass                              give up your turn
+  resign                            concede the game
+  history                           show every move made in the game
+  cancel                            cancel the game
+  help                              show this message
+  quit                              exit`
+
+// cli holds the player's session while they interact with the server
+type cli struct {
+	client  *wordgameclient.Client
+	session *wordgameclient.Session
+	state   wordgameserver.GameStateResponse
+}
+
+func main() {
+	serverURL := flag.String("server", "http://localhost:8080", "URL of the Word Game server")
+	flag.Parse()
+
+	c := cli{client: wordgameclient.New(*serverURL)}
+
+	fmt.Println(usage)
+	scanner := bufio.NewScanner(os.Stdin)
+	for fmt.Print("\n> "); scanner.Scan(); fmt.Print("\n> ") {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		} else if args[0] == "quit" || args[0] == "exit" {
+			return
+		}
+
+		if err := c.run(args[0], args[1:]); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+}
+
+// run carries out a single command
+func (c *cli) run(cmd string, args []string) error {
+	if cmd == "help" {
+		fmt.Println(usage)
+		return nil
+	} else if cmd == "create" {
+		return c.create(args)
+	} else if cmd == "join" || cmd == "resume" {
+		return c.join(cmd, args)
+	}
+
+	if c.session == nil {
+		return errors.New("Join or resume a game first")
+	}
+
+	var err error
+	switch cmd {
+	case "start":
+		if err = c.client.StartGame(c.session.GameID); err != nil {
+			return err
+		}
+		c.state, err = c.client.State(*c.session)
+	case "board":
+		if c.state.Active {
+			c.state, err = c.client.State(*c.session)
+		} else {
+			// State is only served by started games, so resync instead
+			c.state, err = c.client.Resume(*c.session)
+		}
+	case "play":
+		err = c.play(args)
+	case "swap":
+		if len(args) != 1 {
+			return errors.New("Usage: swap <tiles>")
+		}
+		c.state, err = c.client.Swap(*c.session, wordgameserver.Letters(strings.Replace(strings.ToUpper(args[0]), "?", " ", -1)))
+	case "challenge":
+		c.state, err = c.client.Challenge(*c.session)
+	case "pass":
+		c.state, err = c.client.Pass(*c.session)
+	case "resign":
+		c.state, err = c.client.Resign(*c.session)
+	case "history":
+		h, err := c.client.History(c.session.GameID)
+		if err == nil {
+			renderHistory(os.Stdout, h)
+		}
+		return err
+	case "cancel":
+		if err = c.client.Cancel(*c.session); err == nil {
+			c.session = nil
+			fmt.Println("Game cancelled")
+		}
+		return err
+	default:
+		return errors.New("Unknown command " + cmd + ", try help")
+	}
+
+	if err != nil {
+		return err
+	}
+	renderState(os.Stdout, c.state)
+	return nil
+}
+
+func (c *cli) create(args []string) error {
+	var opts wordgameserver.GameOptions
+
+	if len(args) > 0 {
+		window, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.New("Usage: create [challenge seconds]")
+		}
+		opts.ChallengeWindow = window
+	}
+
+	gameID, err := c.client.CreateGame(&opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Created game", gameID)
+	return nil
+}
+
+func (c *cli) join(cmd string, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Usage: " + cmd + " <game id> <name or player id>")
+	}
+
+	gameID, err := uuid.Parse(args[0])
+	if err != nil {
+		return errors.New("Invalid game ID")
+	}
+
+	var s wordgameclient.Session
+	if cmd == "join" {
+		if s, err = c.client.JoinGame(gameID, args[1]); err != nil {
+			return err
+		}
+		fmt.Println("Joined as player", s.PlayerID, "- use this ID to resume later")
+	} else {
+		s.GameID = gameID
+		if s.PlayerID, err = uuid.Parse(args[1]); err != nil {
+			return errors.New("Invalid player ID")
+		}
+	}
+
+	if c.state, err = c.client.Resume(s); err != nil {
+		return err
+	}
+	c.session = &s
+
+	renderState(os.Stdout, c.state)
+	return nil
+}
+
+func (c *cli) play(args []string) error {
+	if len(args) != 3 || (args[1] != "across" && args[1] != "down") {
+		return errors.New("Usage: play <square> <across|down> <tiles>")
+	}
+
+	start, err := parseSquare(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Use the latest board so tiles skip over squares that are now taken
+	if c.state, err = c.client.State(*c.session); err != nil {
+		return err
+	}
+
+	play, err := wordgameclient.NewPlay(c.state.Board, start, args[1] == "down", args[2])
+	if err != nil {
+		return err
+	}
+
+	c.state, err = c.client.Play(*c.session, play)
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/pkg/errors"
+)
+
+// renderState prints the board, the players' scores and the player's rack
+func renderState(w io.Writer, s wordgameserver.GameStateResponse) {
+	wordgameserver.WriteBoardText(w, s.Board)
+
+	fmt.Fprintln(w)
+	for _, p := range s.Players {
+		marker := " "
+		if s.Active && p.Number == s.PlayerTurn {
+			marker = ">"
+		}
+		name := p.Name
+		if p.Resigned {
+			name += " (resigned)"
+		}
+		fmt.Fprintf(w, "%s %-20s %4d\n", marker, name, p.Score)
+	}
+
+	if !s.Active {
+		fmt.Fprintln(w, "\nGame has not started")
+		return
+	}
+
+	if s.Finished {
+		var winners []string
+		for _, n := range s.Winners {
+			if n < len(s.Players) {
+				winners = append(winners, s.Players[n].Name)
+			}
+		}
+		fmt.Fprintf(w, "\nGame over, won by %s\n", strings.Join(winners, " and "))
+	}
+
+	fmt.Fprintf(w, "\nRack: %s\n", strings.Replace(s.RackLetters().String(), " ", "?", -1))
+
+	if c := s.Challenge; c != nil {
+		outcome := "upheld, play removed"
+		if !c.Successful {
+			outcome = "failed"
+		}
+		fmt.Fprintf(w, "Last challenge by player %d against %s: %s\n",
+			c.Challenger+1, strings.Join(c.Words, ", "), outcome)
+	}
+}
+
+// parseSquare converts a square such as H8 into a board coordinate
+func parseSquare(s string) (wordgameserver.SquareCoordinate, error) {
+	var sc wordgameserver.SquareCoordinate
+	var col rune
+	var row int
+
+	if _, err := fmt.Sscanf(strings.ToUpper(s), "%c%d", &col, &row); err != nil || col < 'A' || col > 'Z' {
+		return sc, errors.New("Squares are a column letter followed by a row number, such as H8")
+	}
+
+	sc.Row = row - 1
+	sc.Col = int(col - 'A')
+	return sc, nil
+}
+
+// renderHistory prints a scoresheet of every move made in the game
+func renderHistory(w io.Writer, h wordgameserver.GameHistoryResponse) {
+	for i, m := range h.Moves {
+		name := "?"
+		if m.Player < len(h.Players) {
+			name = h.Players[m.Player].Name
+		}
+
+		var move string
+		switch {
+		case m.Swap:
+			move = "swapped tiles"
+		case m.Pass:
+			move = "passed"
+		case m.Resign:
+			move = "resigned"
+		default:
+			move = strings.Join(m.Words, ", ")
+		}
+		if m.TimedOut {
+			move += " (out of time)"
+		} else if m.Retracted {
+			move += " (challenged off)"
+		}
+
+		fmt.Fprintf(w, "%3d. %-20s %-30s %4d\n", i+1, name, move, m.Score)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run compiles the word list named on the command line into a DAWG, which the
+// server and bots load in place of the list without compiling it themselves
+func run() error {
+	out := flag.String("o", "", "File to write the compiled DAWG to, the word list's name ending in .dawg if empty")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: wordgame-dict [-o output] <word list>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return errors.New("A word list to compile is needed")
+	}
+	path := flag.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(path, ".txt") + ".dawg"
+	}
+	if *out == path {
+		return errors.New("Compiling " + path + " would overwrite it")
+	}
+
+	wl, err := dictionary.LoadWordList(path)
+	if err != nil {
+		return err
+	}
+	d := wl.DAWG()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create DAWG")
+	}
+	size, err := d.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = errors.Wrap(closeErr, "Failed to write DAWG")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Compiled %d words into %d nodes, %d bytes written to %s\n", d.Len(), d.Nodes(), size, *out)
+	return nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/bot"
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameclient"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// loadTest plays games against a server, timing every request its players
+// send
+type loadTest struct {
+	client   *wordgameclient.Client
+	rec      *recorder
+	bot      *bot.Bot // chooses the players' plays, nil for scripted players
+	level    string   // difficulty the bot plays at
+	players  int      // players in each game
+	maxMoves int      // moves after which players resign, so every game ends
+	poll     time.Duration
+}
+
+func run() error {
+	serverURL := flag.String("server", "http://localhost:8080", "URL of the Word Game server")
+	games := flag.Int("games", 10, "Number of games to play at once")
+	players := flag.Int("players", 2, "Number of players in each game")
+	mode := flag.String("mode", "scripted", "How players choose their moves: scripted, swapping a tile every turn, or bot, playing words from the dictionary")
+	dictPath := flag.String("dictionary", "", "Word list bot players find their plays in, which should match the server's")
+	botLevel := flag.String("bot-level", bot.DefaultLevel, "Difficulty of bot players: easy, medium, hard or championship")
+	maxMoves := flag.Int("moves", 100, "Moves after which players resign, so games end even if they can't be played out")
+	poll := flag.Duration("poll", 100*time.Millisecond, "How often players waiting for their turn ask for the game's state")
+	timeout := flag.Duration("timeout", 10*time.Minute, "How long to wait for every game to finish")
+	flag.Parse()
+
+	if *games < 1 || *players < 2 {
+		return errors.New("At least one game of two players is needed")
+	}
+
+	lt := loadTest{
+		client:   wordgameclient.New(*serverURL),
+		rec:      newRecorder(),
+		level:    *botLevel,
+		players:  *players,
+		maxMoves: *maxMoves,
+		poll:     *poll,
+	}
+
+	// Every player keeps a connection open, rather than queueing for the
+	// default transport's few idle ones
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = *games * *players
+	lt.client.HTTPClient = &http.Client{Transport: transport}
+
+	switch *mode {
+	case "scripted":
+	case "bot":
+		if *dictPath == "" {
+			return errors.New("Bot players need a -dictionary")
+		}
+		wl, err := dictionary.LoadWordList(*dictPath)
+		if err != nil {
+			return err
+		}
+		lt.bot = bot.New(wl)
+	default:
+		return errors.New("Unknown mode " + *mode + ", expected scripted or bot")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var finished atomic.Int64
+	start := time.Now()
+	for i := 0; i < *games; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if err := lt.runGame(ctx, n); err != nil {
+				fmt.Fprintf(os.Stderr, "Game %d: %v\n", n, err)
+				return
+			}
+			finished.Add(1)
+		}(i + 1)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	fmt.Printf("%d of %d games finished in %v, %.2f games/s\n",
+		finished.Load(), *games, elapsed.Round(time.Millisecond), float64(finished.Load())/elapsed.Seconds())
+	lt.rec.report(os.Stdout, elapsed)
+	return nil
+}
+
+// runGame creates the game numbered n, joins its players and starts it, then
+// has each player take their turns until it finishes
+func (lt *loadTest) runGame(ctx context.Context, n int) error {
+	var gameID uuid.UUID
+	err := lt.rec.time("create", func() (err error) {
+		gameID, err = lt.client.CreateGame(&wordgameserver.GameOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	sessions := make([]wordgameclient.Session, lt.players)
+	names := make([]string, lt.players)
+	for i := range sessions {
+		names[i] = fmt.Sprintf("loadtest-%d-%d", n, i+1)
+		err = lt.rec.time("join", func() (err error) {
+			sessions[i], err = lt.client.JoinGame(gameID, names[i])
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = lt.rec.time("start", func() error { return lt.client.StartGame(gameID) }); err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(sessions))
+	for i := range sessions {
+		go func(i int) {
+			errs <- lt.play(ctx, sessions[i], names[i])
+		}(i)
+	}
+	for range sessions {
+		if playErr := <-errs; playErr != nil && err == nil {
+			err = playErr
+		}
+	}
+	return err
+}
+
+// play takes the player's turns until the game finishes or they resign,
+// asking for the state every poll interval while it is someone else's turn
+func (lt *loadTest) play(ctx context.Context, s wordgameclient.Session, name string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var state wordgameserver.GameStateResponse
+		err := lt.rec.time("state", func() (err error) {
+			state, err = lt.client.State(s)
+			return err
+		})
+		if err != nil {
+			return err
+		} else if state.Finished {
+			return nil
+		}
+
+		me := -1
+		for _, p := range state.Players {
+			if p.Name == name {
+				me = p.Number
+				if p.Resigned {
+					return nil
+				}
+			}
+		}
+		if me != state.PlayerTurn {
+			select {
+			case <-ctx.Done():
+			case <-time.After(lt.poll):
+			}
+			continue
+		}
+
+		if err = lt.move(s, state); err != nil {
+			return err
+		}
+	}
+}
+
+// move takes the player's turn. Bots make the play they choose, while scripted
+// players, and bots with nothing to play, swap a tile, or pass once the bag is
+// too low to swap. Players resign once the game has gone on for the most moves
+// allowed.
+func (lt *loadTest) move(s wordgameclient.Session, state wordgameserver.GameStateResponse) error {
+	if state.MoveCount >= lt.maxMoves {
+		return lt.rec.time("resign", func() error {
+			_, err := lt.client.Resign(s)
+			return err
+		})
+	}
+
+	if lt.bot != nil {
+		if play := lt.bot.NextMove(state, lt.level); !play.Swap {
+			err := lt.rec.time("play", func() error {
+				_, err := lt.client.Play(s, play)
+				return err
+			})
+			if err == nil {
+				return nil
+			}
+		}
+	}
+
+	if rack := state.RackLetters(); len(rack) > 0 {
+		err := lt.rec.time("swap", func() error {
+			_, err := lt.client.Swap(s, rack[:1])
+			return err
+		})
+		if err == nil {
+			return nil
+		}
+	}
+
+	return lt.rec.time("pass", func() error {
+		_, err := lt.client.Pass(s)
+		return err
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recorder collects how long each kind of request took, from every player at
+// once
+type recorder struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+	}
+}
+
+// time sends a request of the kind, recording how long it took and whether it
+// failed
+func (rec *recorder) time(kind string, request func() error) error {
+	start := time.Now()
+	err := request()
+	took := time.Since(start)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.latencies[kind] = append(rec.latencies[kind], took)
+	if err != nil {
+		rec.errors[kind]++
+	}
+	return err
+}
+
+// percentile returns the latency the given percentage of the sorted latencies
+// are within
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// report writes the throughput of the requests sent over the time the test
+// ran, and their latency percentiles by kind
+func (rec *recorder) report(w io.Writer, elapsed time.Duration) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	kinds := make([]string, 0, len(rec.latencies))
+	var all []time.Duration
+	errors := 0
+	for kind, l := range rec.latencies {
+		kinds = append(kinds, kind)
+		all = append(all, l...)
+		errors += rec.errors[kind]
+	}
+	sort.Strings(kinds)
+
+	fmt.Fprintf(w, "%d requests in %v, %.1f requests/s, %d errors\n\n",
+		len(all), elapsed.Round(time.Millisecond), float64(len(all))/elapsed.Seconds(), errors)
+	fmt.Fprintf(w, "%-10s %8s %7s %10s %10s %10s %10s\n", "request", "count", "errors", "p50", "p90", "p99", "max")
+	for _, kind := range append(kinds, "all") {
+		l := all
+		if kind != "all" {
+			l = rec.latencies[kind]
+		}
+		sorted := append([]time.Duration(nil), l...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		fmt.Fprintf(w, "%-10s %8d %7d %10v %10v %10v %10v\n", kind, len(sorted), rec.errorCount(kind),
+			percentile(sorted, 50).Round(time.Microsecond), percentile(sorted, 90).Round(time.Microsecond),
+			percentile(sorted, 99).Round(time.Microsecond), percentile(sorted, 100).Round(time.Microsecond))
+	}
+}
+
+// errorCount returns how many requests of the kind failed, or of every kind
+// for "all". The recorder must be locked by the caller.
+func (rec *recorder) errorCount(kind string) int {
+	if kind != "all" {
+		return rec.errors[kind]
+	}
+	n := 0
+	for _, e := range rec.errors {
+		n += e
+	}
+	return n
+}
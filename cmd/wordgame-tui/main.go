@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameclient"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	serverURL := flag.String("server", "http://localhost:8080", "URL of the Word Game server")
+	create := flag.Bool("create", false, "Create a new game and join it")
+	challenge := flag.Int("challenge", 0, "Seconds to challenge a play in a new game, 0 to check words as they are played")
+	bots := flag.Int("bots", 0, "Number of computer players to add to a new game")
+	botLevel := flag.String("bot-level", "", "Difficulty of the computer players: easy, medium, hard or championship")
+	variant := flag.String("variant", "", "Board and tiles of a new game: standard, super or wwf")
+	gameID := flag.String("game", "", "ID of the game to join or resume")
+	name := flag.String("name", "", "Name to join the game with")
+	playerID := flag.String("player", "", "ID of the player to resume as, instead of joining")
+	flag.Parse()
+
+	client := wordgameclient.New(*serverURL)
+
+	session, err := connect(client, *create, *challenge, *bots, *botLevel, *variant, *gameID, *name, *playerID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Game %v, player %v\n", session.GameID, session.PlayerID)
+
+	// Resuming fetches the state even before the game starts, and must happen
+	// before watching since it disconnects any existing WebSockets
+	state, err := client.Resume(session)
+	if err != nil {
+		return err
+	}
+
+	updates, closeWatch, err := client.Watch(session)
+	if err != nil {
+		return errors.Wrap(err, "Failed to watch game")
+	}
+	defer closeWatch()
+
+	m := model{
+		client:  client,
+		session: session,
+		updates: updates,
+		state:   state,
+		cursor:  wordgameserver.SquareCoordinate{Row: len(state.Board) / 2, Col: len(state.Board) / 2},
+	}
+	return tea.NewProgram(m).Start()
+}
+
+// connect creates, joins or resumes a game according to the flags given
+func connect(client *wordgameclient.Client, create bool, challenge, bots int, botLevel, variant, gameID, name, playerID string) (wordgameclient.Session, error) {
+	var session wordgameclient.Session
+	var err error
+
+	if create {
+		opts := &wordgameserver.GameOptions{ChallengeWindow: challenge, Bots: bots, BotLevel: botLevel, Variant: variant}
+		if session.GameID, err = client.CreateGame(opts); err != nil {
+			return session, err
+		}
+	} else if session.GameID, err = uuid.Parse(gameID); err != nil {
+		return session, errors.New("Pass -create or a valid -game ID")
+	}
+
+	if playerID != "" {
+		if session.PlayerID, err = uuid.Parse(playerID); err != nil {
+			return session, errors.New("Invalid -player ID")
+		}
+		return session, nil
+	}
+
+	if name == "" {
+		return session, errors.New("Pass -name to join the game or -player to resume")
+	}
+	return client.JoinGame(session.GameID, name)
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameclient"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/muesli/termenv"
+)
+
+const help = `arrows move  letters place  tab direction  backspace undo  esc clear
+enter play  ctrl+s swap  ctrl+p pass  ctrl+g challenge  ctrl+t start  ctrl+c quit`
+
+// pendingTile is a tile placed on the board that hasn't been submitted yet
+type pendingTile struct {
+	square wordgameserver.SquareCoordinate
+	tile   wordgameserver.Letter // tile from the rack, ' ' for blanks
+	letter wordgameserver.Letter // letter shown on the board
+}
+
+// stateMsg carries a game state pushed by the server or returned by a request
+type stateMsg wordgameserver.GameStateResponse
+
+// errMsg carries an error from a request to the server
+type errMsg struct{ err error }
+
+// model is the state of the TUI
+type model struct {
+	client  *wordgameclient.Client
+	session wordgameclient.Session
+	updates <-chan wordgameserver.GameStateResponse
+
+	state    wordgameserver.GameStateResponse
+	cursor   wordgameserver.SquareCoordinate
+	down     bool                   // true to place tiles down rather than across
+	pending  []pendingTile          // tiles placed but not yet played
+	swapping bool                   // true while choosing tiles to swap
+	swap     wordgameserver.Letters // tiles chosen to swap
+	status   string                 // message shown below the board
+}
+
+// waitForUpdate is a command that delivers the next state pushed by the server
+func (m model) waitForUpdate() tea.Msg {
+	state, ok := <-m.updates
+	if !ok {
+		return errMsg{fmt.Errorf("Lost connection to server, restart to resume")}
+	}
+	return stateMsg(state)
+}
+
+func (m model) Init() tea.Cmd {
+	return m.waitForUpdate
+}
+
+// request runs a call to the server as a command
+func request(fn func() (wordgameserver.GameStateResponse, error)) tea.Cmd {
+	return func() tea.Msg {
+		state, err := fn()
+		if err != nil {
+			return errMsg{err}
+		}
+		return stateMsg(state)
+	}
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case stateMsg:
+		m.state = wordgameserver.GameStateResponse(msg)
+		m.pending = m.pending[:0]
+		m.status = ""
+		return m, nil
+	case errMsg:
+		m.status = msg.err.Error()
+		return m, nil
+	case tea.KeyMsg:
+		if m.swapping {
+			return m.updateSwap(msg)
+		}
+		return m.updateBoard(msg)
+	}
+	return m, nil
+}
+
+// updateBoard handles keys while placing tiles on the board
+func (m model) updateBoard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyUp:
+		m.moveCursor(-1, 0)
+	case tea.KeyDown:
+		m.moveCursor(1, 0)
+	case tea.KeyLeft:
+		m.moveCursor(0, -1)
+	case tea.KeyRight:
+		m.moveCursor(0, 1)
+	case tea.KeyTab:
+		m.down = !m.down
+	case tea.KeyEsc:
+		m.pending = m.pending[:0]
+	case tea.KeyBackspace:
+		if n := len(m.pending); n > 0 {
+			m.cursor = m.pending[n-1].square
+			m.pending = m.pending[:n-1]
+		}
+	case tea.KeyEnter:
+		if len(m.pending) == 0 {
+			m.status = "Place some tiles first"
+			return m, nil
+		}
+		play := m.pendingPlay()
+		m.status = "Playing..."
+		return m, request(func() (wordgameserver.GameStateResponse, error) {
+			return m.client.Play(m.session, play)
+		})
+	case tea.KeyCtrlS:
+		m.pending = m.pending[:0]
+		m.swapping = true
+		m.swap = nil
+		m.status = "Type tiles to swap (? for blank), enter to swap, esc to cancel"
+	case tea.KeyCtrlP:
+		m.status = "Passing..."
+		return m, request(func() (wordgameserver.GameStateResponse, error) {
+			return m.client.Pass(m.session)
+		})
+	case tea.KeyCtrlG:
+		m.status = "Challenging..."
+		return m, request(func() (wordgameserver.GameStateResponse, error) {
+			return m.client.Challenge(m.session)
+		})
+	case tea.KeyCtrlT:
+		return m, func() tea.Msg {
+			if err := m.client.StartGame(m.session.GameID); err != nil {
+				return errMsg{err}
+			}
+			return nil
+		}
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			m.placeTile(r)
+		}
+	}
+	return m, nil
+}
+
+// updateSwap handles keys while choosing tiles to swap
+func (m model) updateSwap(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.swapping = false
+		m.status = ""
+	case tea.KeyBackspace:
+		if n := len(m.swap); n > 0 {
+			m.swap = m.swap[:n-1]
+		}
+	case tea.KeyEnter:
+		m.swapping = false
+		tiles := m.swap
+		m.status = "Swapping..."
+		return m, request(func() (wordgameserver.GameStateResponse, error) {
+			return m.client.Swap(m.session, tiles)
+		})
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			t := wordgameserver.Letter(unicode.ToUpper(r))
+			if r == '?' {
+				t = ' '
+			}
+			if m.countInRack(t) > countTiles(m.swap, t) {
+				m.swap = append(m.swap, t)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *model) moveCursor(rows, cols int) {
+	sc := wordgameserver.SquareCoordinate{Row: m.cursor.Row + rows, Col: m.cursor.Col + cols}
+	if sc.Row >= 0 && sc.Row < len(m.state.Board) && sc.Col >= 0 && sc.Col < len(m.state.Board[0]) {
+		m.cursor = sc
+	}
+}
+
+// placeTile puts the tile for the letter at the cursor and advances the cursor
+// past any occupied squares. A blank is used when the rack has no tile for the
+// letter.
+func (m *model) placeTile(r rune) {
+	letter := wordgameserver.Letter(unicode.ToUpper(r))
+	if !unicode.IsLetter(r) {
+		return
+	} else if m.occupied(m.cursor) {
+		m.status = "Square already has a tile"
+		return
+	}
+
+	tile := letter
+	if m.countInRack(letter) <= m.countPending(letter) {
+		if m.countInRack(' ') <= m.countPending(' ') {
+			m.status = "No " + letter.String() + " or blank left in your rack"
+			return
+		}
+		tile = ' '
+	}
+
+	m.pending = append(m.pending, pendingTile{square: m.cursor, tile: tile, letter: letter})
+	m.status = ""
+
+	// Move along the line of play to the next empty square
+	rows, cols := 0, 1
+	if m.down {
+		rows, cols = 1, 0
+	}
+	for next := m.cursor; ; {
+		next = wordgameserver.SquareCoordinate{Row: next.Row + rows, Col: next.Col + cols}
+		if next.Row >= len(m.state.Board) || next.Col >= len(m.state.Board[0]) {
+			return
+		} else if !m.occupied(next) {
+			m.cursor = next
+			return
+		}
+	}
+}
+
+func (m *model) occupied(sc wordgameserver.SquareCoordinate) bool {
+	if m.state.Board[sc.Row][sc.Col].Letter != 0 {
+		return true
+	}
+	for _, p := range m.pending {
+		if p.square == sc {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *model) countInRack(t wordgameserver.Letter) int {
+	return countTiles(m.state.RackLetters(), t)
+}
+
+func (m *model) countPending(t wordgameserver.Letter) int {
+	n := 0
+	for _, p := range m.pending {
+		if p.tile == t {
+			n++
+		}
+	}
+	return n
+}
+
+func countTiles(tiles wordgameserver.Letters, t wordgameserver.Letter) int {
+	n := 0
+	for _, c := range tiles {
+		if c == t {
+			n++
+		}
+	}
+	return n
+}
+
+// pendingPlay turns the pending tiles into a play, ordered along the board
+func (m *model) pendingPlay() wordgameserver.GamePlayRequest {
+	sort.Slice(m.pending, func(i, j int) bool {
+		a, b := m.pending[i].square, m.pending[j].square
+		return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+	})
+
+	play := wordgameserver.GamePlayRequest{
+		StartPos: m.pending[0].square,
+		EndPos:   m.pending[len(m.pending)-1].square,
+	}
+	for _, p := range m.pending {
+		play.Tiles = append(play.Tiles, p.tile)
+		if p.tile == ' ' {
+			play.Blanks = append(play.Blanks, p.letter)
+		}
+	}
+	return play
+}
+
+// premiumColors are the background colors of each type of empty square
+var premiumColors = map[string]string{
+	"star":            "5",
+	"doubleLetter":    "6",
+	"tripleLetter":    "4",
+	"quadrupleLetter": "12",
+	"doubleWord":      "13",
+	"tripleWord":      "1",
+	"quadrupleWord":   "9",
+}
+
+func (m model) View() string {
+	p := termenv.ColorProfile()
+
+	var board []string
+	header := "   "
+	for col := range m.state.Board[0] {
+		header += fmt.Sprintf(" %c ", 'A'+col)
+	}
+	board = append(board, header)
+
+	pending := make(map[wordgameserver.SquareCoordinate]pendingTile, len(m.pending))
+	for _, t := range m.pending {
+		pending[t.square] = t
+	}
+
+	for row := range m.state.Board {
+		line := fmt.Sprintf("%2d ", row+1)
+		for col, squ := range m.state.Board[row] {
+			sc := wordgameserver.SquareCoordinate{Row: row, Col: col}
+			cell := termenv.String(" · ").Foreground(p.Color("8"))
+
+			if t, ok := pending[sc]; ok {
+				cell = termenv.String(" " + tileLetter(t.letter, t.tile == ' ') + " ").
+					Foreground(p.Color("0")).Background(p.Color("2")).Bold()
+			} else if squ.Letter != 0 {
+				cell = termenv.String(" " + tileLetter(squ.Letter, squ.Blank) + " ").
+					Foreground(p.Color("0")).Background(p.Color("3")).Bold()
+			} else if c, ok := premiumColors[squ.SquareType]; ok {
+				cell = termenv.String("   ").Background(p.Color(c))
+			}
+
+			if sc == m.cursor {
+				cell = cell.Reverse()
+			}
+			line += cell.String()
+		}
+		board = append(board, line)
+	}
+
+	side := []string{"Players:"}
+	for _, pl := range m.state.Players {
+		marker := "  "
+		if m.state.Active && pl.Number == m.state.PlayerTurn {
+			marker = "> "
+		}
+		line := fmt.Sprintf("%s%-16s %4d", marker, pl.Name, pl.Score)
+		if pl.Number < len(m.state.Clocks) {
+			line += "  " + formatClock(m.state.Clocks[pl.Number])
+		}
+		side = append(side, line)
+	}
+	if lm := m.state.LastMove; lm != nil && lm.Player < len(m.state.Players) {
+		side = append(side, "", "Last: "+m.state.Players[lm.Player].Name+" "+describeMove(*lm))
+	}
+	if m.state.Active {
+		side = append(side, fmt.Sprintf("Tiles in bag: %d", m.state.BagCount))
+	}
+
+	if m.state.Finished {
+		var winners []string
+		for _, n := range m.state.Winners {
+			if n < len(m.state.Players) {
+				winners = append(winners, m.state.Players[n].Name)
+			}
+		}
+		side = append(side, "", "Game over, won by "+strings.Join(winners, " and "))
+	} else if !m.state.Active {
+		side = append(side, "", "Waiting for game to start")
+	} else {
+		side = append(side, "", "Rack: "+strings.Replace(m.state.RackLetters().String(), " ", "?", -1))
+	}
+
+	if m.state.TurnEnds != nil {
+		side = append(side, "Turn ends at "+m.state.TurnEnds.Local().Format("15:04:05"))
+	}
+	if n := m.state.TimedOut; n != nil && *n < len(m.state.Players) {
+		side = append(side, m.state.Players[*n].Name+" ran out of time")
+	}
+
+	direction := "across"
+	if m.down {
+		direction = "down"
+	}
+	side = append(side, "Placing "+direction)
+
+	if m.swapping {
+		side = append(side, "Swap: "+strings.Replace(string(m.swap), " ", "?", -1))
+	}
+
+	if c := m.state.Challenge; c != nil {
+		outcome := "upheld"
+		if !c.Successful {
+			outcome = "failed"
+		}
+		side = append(side, "", "Last challenge "+outcome+": "+strings.Join(c.Words, ", "))
+	}
+
+	var b strings.Builder
+	for i, line := range board {
+		b.WriteString(line)
+		if i < len(side) {
+			b.WriteString("   " + side[i])
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n" + m.status + "\n\n" + help + "\n")
+	return b.String()
+}
+
+// tileLetter shows tiles played as blanks in lowercase
+func tileLetter(letter wordgameserver.Letter, blank bool) string {
+	if blank {
+		return strings.ToLower(letter.String())
+	}
+	return letter.String()
+}
+
+// formatClock shows the seconds left on a clock as minutes and seconds
+func formatClock(seconds float64) string {
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := int(seconds)
+	return fmt.Sprintf("%s%d:%02d", sign, s/60, s%60)
+}
+
+// describeMove summarizes a move for the side panel
+func describeMove(mv wordgameserver.Move) string {
+	switch {
+	case mv.Resign:
+		return "resigned"
+	case mv.Swap:
+		return "swapped"
+	case mv.Pass:
+		return "passed"
+	case mv.Retracted:
+		return "had " + strings.Join(mv.Words, ", ") + " challenged off"
+	}
+	return fmt.Sprintf("played %s for %d", strings.Join(mv.Words, ", "), mv.Score)
+}
@@ -1,12 +1,312 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/fantashley/wordgame-controller/pkg/bot"
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/pgstore"
+	"github.com/fantashley/wordgame-controller/pkg/push"
+	"github.com/fantashley/wordgame-controller/pkg/redisstore"
 	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the server with the configuration given by the config file, the
+// environment and flags, and blocks until it has shut down
+func run() error {
+	defaults := wordgameserver.DefaultConfig()
+	configPath := flag.String("config", "", "YAML file to load the configuration from, which WORDGAME_* environment variables and flags override")
+	bindAddr := flag.String("addr", defaults.BindAddr, "address for the server to listen on")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate file to serve HTTPS with, instead of HTTP")
+	tlsKey := flag.String("tls-key", "", "PEM private key file for the TLS certificate")
+	tlsClientCA := flag.String("tls-client-ca", "", "PEM file of certificate authorities that clients must present a certificate signed by")
+	maxGames := flag.Int("max-games", defaults.MaxGames, "most games the server holds at once, 0 for no limit")
+	maxPlayers := flag.Int("max-players", defaults.MaxPlayers, "most players, including bots, in each game")
+	dictPath := flag.String("dictionary", "", "word list used to validate plays, such as TWL or SOWPODS, read again on SIGHUP")
+	layoutDir := flag.String("layouts", "", "Directory of JSON board layouts games can choose by name")
+	tileSetDir := flag.String("tile-sets", "", "Directory of JSON tile sets games can choose by name")
+	definitions := flag.String("definitions", "", "file of words followed by what they mean, shown when words are looked up")
+	idleTTL := flag.Duration("idle-ttl", defaults.IdleTTL, "how long a game can go without activity before it is removed, 0 keeps games forever")
+	shutdownTimeout := flag.Duration("shutdown-timeout", defaults.ShutdownTimeout, "how long in-flight requests are given to finish when shutting down")
+	requestTimeout := flag.Duration("request-timeout", defaults.RequestTimeout, "how long a request waits for a game to respond, 0 for no limit")
+	redisAddr := flag.String("redis-addr", "", "host:port of a Redis server to store games in, instead of memory")
+	redisPassword := flag.String("redis-password", "", "password for the Redis server")
+	redisPoolSize := flag.Int("redis-pool-size", 0, "maximum number of Redis connections, 0 uses the default")
+	postgresDSN := flag.String("postgres", "", "PostgreSQL connection string to store games in, instead of memory")
+	redisTTL := flag.Duration("redis-ttl", 0, "how long games are kept in Redis after their last change, 0 keeps them forever")
+	logLevel := flag.String("log-level", "info", "lowest level of request logs to write: debug, info, warn or error")
+	logJSON := flag.Bool("log-json", false, "write request logs as JSON instead of text")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "URL of an OpenTelemetry collector to send traces to, such as http://localhost:4318, tracing is off if empty")
+	flag.Parse()
+
+	cfg, err := wordgameserver.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	// Flags given explicitly take precedence over the file and environment
+	var storeFlags int
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.BindAddr = *bindAddr
+		case "tls-cert":
+			cfg.TLS.Cert = *tlsCert
+		case "tls-key":
+			cfg.TLS.Key = *tlsKey
+		case "tls-client-ca":
+			cfg.TLS.ClientCA = *tlsClientCA
+		case "max-games":
+			cfg.MaxGames = *maxGames
+		case "max-players":
+			cfg.MaxPlayers = *maxPlayers
+		case "dictionary":
+			cfg.Dictionary = *dictPath
+		case "layouts":
+			cfg.LayoutDir = *layoutDir
+		case "tile-sets":
+			cfg.TileSetDir = *tileSetDir
+		case "definitions":
+			cfg.Definitions = *definitions
+		case "idle-ttl":
+			cfg.IdleTTL = *idleTTL
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = *shutdownTimeout
+		case "request-timeout":
+			cfg.RequestTimeout = *requestTimeout
+		case "redis-addr":
+			cfg.Store.Backend = wordgameserver.StoreRedis
+			cfg.Store.Redis.Addr = *redisAddr
+			storeFlags++
+		case "redis-password":
+			cfg.Store.Redis.Password = *redisPassword
+		case "redis-pool-size":
+			cfg.Store.Redis.PoolSize = *redisPoolSize
+		case "redis-ttl":
+			cfg.Store.Redis.TTL = *redisTTL
+		case "postgres":
+			cfg.Store.Backend = wordgameserver.StorePostgres
+			cfg.Store.Postgres.DSN = *postgresDSN
+			storeFlags++
+		}
+	})
+	if storeFlags > 1 {
+		return errors.New("Only one of -postgres and -redis-addr can be used")
+	} else if err = cfg.Validate(); err != nil {
+		return err
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if *logJSON {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	logger := slog.New(handler)
+
+	// Bots need a dictionary to find words to play. Every word list is read
+	// again when the server is sent SIGHUP.
+	var validator dictionary.WordValidator
+	var strategy wordgameserver.BotStrategy
+	var english *dictionary.WordFile
+	var wordFiles []*dictionary.WordFile
+	var bots *bot.Bot
+	if cfg.Dictionary != "" {
+		english, err = dictionary.LoadWordFile(cfg.Dictionary)
+		if err != nil {
+			return err
+		}
+		log.Printf("Loaded %v words from %v", english.WordList().Len(), cfg.Dictionary)
+		wordFiles = append(wordFiles, english)
+		validator = english
+		bots = bot.New(english.WordList())
+		strategy = bots
+	}
+	if len(cfg.Dictionaries) > 0 || len(cfg.Lexicons) > 0 {
+		languages := dictionary.Languages{
+			English:  validator,
+			Others:   make(map[string]dictionary.WordValidator),
+			Lexicons: make(map[string]dictionary.WordValidator),
+		}
+		for code, path := range cfg.Dictionaries {
+			wf, err := dictionary.LoadWordFile(path)
+			if err != nil {
+				return err
+			}
+			log.Printf("Loaded %v %v words from %v", wf.WordList().Len(), code, path)
+			wordFiles = append(wordFiles, wf)
+			languages.Others[code] = wf
+		}
+		for name, path := range cfg.Lexicons {
+			wf, err := dictionary.LoadWordFile(path)
+			if err != nil {
+				return err
+			}
+			log.Printf("Loaded %v words of lexicon %v from %v", wf.WordList().Len(), name, path)
+			wordFiles = append(wordFiles, wf)
+			languages.Lexicons[name] = wf
+		}
+		validator = languages
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			reloadDictionaries(wordFiles, english, bots)
+		}
+	}()
+
+	var store wordgameserver.GameStore
+	switch cfg.Store.Backend {
+	case wordgameserver.StorePostgres:
+		ps, err := pgstore.Open(cfg.Store.Postgres.DSN, validator)
+		if err != nil {
+			return err
+		}
+		defer ps.Close()
+		store = ps
+	case wordgameserver.StoreRedis:
+		rs, err := redisstore.New(redisstore.Options{
+			Addr:      cfg.Store.Redis.Addr,
+			Password:  cfg.Store.Redis.Password,
+			PoolSize:  cfg.Store.Redis.PoolSize,
+			TTL:       cfg.Store.Redis.TTL,
+			Validator: validator,
+		})
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		store = rs
+	}
+
+	// Shut down gracefully on interrupt or termination
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *otlpEndpoint != "" {
+		shutdown, err := setupTracing(ctx, *otlpEndpoint)
+		if err != nil {
+			return err
+		}
+		defer shutdown()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		log.Print("Shutting down")
+		cancel()
+	}()
+
+	s, err := wordgameserver.NewServer(cfg, validator, store, strategy, logger)
+	if err != nil {
+		return err
+	}
+	if err = addPushProviders(s, cfg.Push); err != nil {
+		return err
+	}
+	return s.ListenAndServe(ctx)
+}
+
+// addPushProviders has the server send notifications through each push
+// service that is configured
+func addPushProviders(s *wordgameserver.Server, cfg wordgameserver.PushConfig) error {
+	if cfg.FCM.Credentials != "" {
+		credentials, err := os.ReadFile(cfg.FCM.Credentials)
+		if err != nil {
+			return err
+		}
+		fcm, err := push.NewFCM(credentials)
+		if err != nil {
+			return err
+		}
+		s.AddPushProvider(wordgameserver.PushFCM, fcm)
+	}
+	if cfg.APNs.Key != "" {
+		key, err := os.ReadFile(cfg.APNs.Key)
+		if err != nil {
+			return err
+		}
+		apns, err := push.NewAPNs(key, cfg.APNs.KeyID, cfg.APNs.TeamID, cfg.APNs.Topic, cfg.APNs.Sandbox)
+		if err != nil {
+			return err
+		}
+		s.AddPushProvider(wordgameserver.PushAPNs, apns)
+	}
+	if cfg.WebPush.PrivateKey != "" {
+		wp, err := push.NewWebPush(cfg.WebPush.PrivateKey, cfg.WebPush.Subject)
+		if err != nil {
+			return err
+		}
+		log.Printf("Sending Web Push notifications with VAPID public key %v", wp.PublicKey())
+		s.AddPushProvider(wordgameserver.PushWebPush, wp)
+	}
+	return nil
+}
+
+// reloadDictionaries reads every word list again, so games already being
+// played check words against the new lists. Lists that fail to load are kept
+// as they were. Bots play from the new English list.
+func reloadDictionaries(wordFiles []*dictionary.WordFile, english *dictionary.WordFile, bots *bot.Bot) {
+	for _, wf := range wordFiles {
+		if err := wf.Reload(); err != nil {
+			log.Printf("Failed to reload %v: %v", wf.Path(), err)
+			continue
+		}
+		log.Printf("Reloaded %v words from %v", wf.WordList().Len(), wf.Path())
+	}
+	if bots != nil {
+		bots.Reload(english.WordList())
+	}
+}
+
+// setupTracing sends the server's traces to the OpenTelemetry collector at the
+// endpoint, and continues traces propagated by clients and gateways. The
+// returned function flushes any spans not yet sent.
+func setupTracing(ctx context.Context, endpoint string) (func(), error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "wordgameserver"))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 
-	log.Fatal(wordgameserver.StartWordGameServer(":8080"))
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}, nil
 }
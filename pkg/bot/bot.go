@@ -0,0 +1,131 @@
+// Package bot provides computer players that find the plays available from a
+// rack and choose which one to make
+package bot
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+)
+
+// Candidate is a legal play along with the words it forms and its score
+type Candidate struct {
+	Play  wordgameserver.GamePlayRequest
+	Words []string
+	Score int
+}
+
+// Difficulty levels a bot can play at
+const (
+	Easy   = "easy"   // plays any legal word at random
+	Medium = "medium" // plays one of the highest scoring words at random
+	Hard   = "hard"   // plays the word with the best equity, valuing the tiles kept
+	// Championship plays like hard until near the end of the game, then plays
+	// out its best plays against the racks its opponents could hold
+	Championship = "championship"
+)
+
+// DefaultLevel is the difficulty used when no level is chosen
+const DefaultLevel = Medium
+
+// mediumChoices is the number of top scoring plays a medium bot chooses from
+const mediumChoices = 5
+
+// Bot is a computer player that finds every play available from its rack and
+// chooses one according to its difficulty level, swapping its whole rack when
+// it has no play
+type Bot struct {
+	lexicon atomic.Pointer[lexicon]
+}
+
+// lexicon is the words a bot plays from, compiled for generating plays
+type lexicon struct {
+	words *dictionary.DAWG
+}
+
+// New creates a bot that plays words from the word list
+func New(wl *dictionary.WordList) *Bot {
+	var b Bot
+	b.Reload(wl)
+	return &b
+}
+
+// Reload has the bot play words from a new word list. Moves already being
+// chosen finish with the old list.
+func (b *Bot) Reload(wl *dictionary.WordList) {
+	b.lexicon.Store(&lexicon{words: wl.DAWG()})
+}
+
+// Levels lists the difficulty levels the bot can play at, from easiest to
+// hardest
+func (b *Bot) Levels() []string {
+	return []string{Easy, Medium, Hard, Championship}
+}
+
+// NextMove chooses a play available from the bot's rack according to the
+// difficulty level, or swaps every tile if there isn't one
+func (b *Bot) NextMove(state wordgameserver.GameStateResponse, level string) wordgameserver.GamePlayRequest {
+	rack := state.RackLetters()
+	candidates := b.candidates(state.Variant, state.Board, rack)
+	if len(candidates) == 0 {
+		return wordgameserver.GamePlayRequest{
+			Tiles: rack,
+			Swap:  true,
+		}
+	}
+
+	if level == "" {
+		level = DefaultLevel
+	}
+
+	switch level {
+	case Easy:
+		return candidates[rand.Intn(len(candidates))].Play
+	case Hard:
+		return bestEquity(candidates, rack).Play
+	case Championship:
+		return b.simulate(state, rack, candidates).Play
+	default:
+		n := mediumChoices
+		if len(candidates) < n {
+			n = len(candidates)
+		}
+		return candidates[rand.Intn(n)].Play
+	}
+}
+
+// Candidates finds every legal play that can be made on the board from the
+// rack, highest scoring first. The first play must cover the center square and
+// every later play must connect to tiles already on the board.
+func (b *Bot) Candidates(board wordgameserver.ScrabbleBoard, rack wordgameserver.Letters) []Candidate {
+	return b.candidates(wordgameserver.VariantStandard, board, rack)
+}
+
+// Solve lists the plays available from the rack like Candidates, scoring them
+// by the rules of the named variant, so the server can answer solve requests
+func (b *Bot) Solve(variant string, board wordgameserver.ScrabbleBoard, rack wordgameserver.Letters) []wordgameserver.Solution {
+	candidates := b.candidates(variant, board, rack)
+	solutions := make([]wordgameserver.Solution, len(candidates))
+	for i, c := range candidates {
+		solutions[i] = wordgameserver.Solution{Play: c.Play, Words: c.Words, Score: c.Score}
+	}
+	return solutions
+}
+
+// candidates finds the plays available like Candidates, scoring them by the
+// rules of the named variant. Blanks are played as any letter that makes a
+// word.
+func (b *Bot) candidates(variant string, board wordgameserver.ScrabbleBoard, rack wordgameserver.Letters) []Candidate {
+	moves, err := wordgameserver.VariantMoves(variant, board, rack, b.lexicon.Load().words)
+	if err != nil {
+		return nil
+	}
+
+	candidates := make([]Candidate, len(moves))
+	for i, m := range moves {
+		candidates[i] = Candidate{Play: m.Play, Words: m.Words, Score: m.Score}
+	}
+	return candidates
+}
@@ -0,0 +1,518 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+)
+
+// createTestBot creates a bot with a small word list
+func createTestBot(t *testing.T) *Bot {
+	t.Helper()
+
+	wl, err := dictionary.NewWordList(strings.NewReader("CAT\nCATS\nACT\nAT\nTA\nZAX\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(wl)
+}
+
+func TestCandidates(t *testing.T) {
+	b := createTestBot(t)
+
+	board := wordgameserver.NewBoard()
+	candidates := b.Candidates(board, wordgameserver.Letters("CATQQQQ"))
+	if len(candidates) == 0 {
+		t.Fatal("No candidates found for first play")
+	}
+
+	for _, c := range candidates {
+		if !covers(c.Play, center(board)) {
+			t.Errorf("First play %v doesn't cover the center square", c.Words)
+		}
+	}
+
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Score > candidates[i-1].Score {
+			t.Fatal("Candidates are not ordered by score")
+		}
+	}
+
+	if w := candidates[0].Words[0]; len(w) != 3 {
+		t.Errorf("Best play is %v, expected a three letter word", w)
+	}
+}
+
+// center returns the square the first play of a game must cover
+func center(board wordgameserver.ScrabbleBoard) wordgameserver.SquareCoordinate {
+	return wordgameserver.SquareCoordinate{Row: len(board) / 2, Col: len(board) / 2}
+}
+
+// covers reports whether the play runs over the square
+func covers(play wordgameserver.GamePlayRequest, target wordgameserver.SquareCoordinate) bool {
+	for sc := play.StartPos; ; {
+		if sc == target {
+			return true
+		}
+		if sc == play.EndPos {
+			return false
+		} else if sc.Row == play.EndPos.Row {
+			sc.Col++
+		} else {
+			sc.Row++
+		}
+	}
+}
+
+func TestCandidatesSuper(t *testing.T) {
+	b := createTestBot(t)
+
+	board, err := wordgameserver.NewVariantBoard(wordgameserver.VariantSuper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates := b.Candidates(board, wordgameserver.Letters("CATQQQQ"))
+	if len(candidates) == 0 {
+		t.Fatal("No candidates found for first play on a Super Scrabble board")
+	}
+	for _, c := range candidates {
+		if !covers(c.Play, wordgameserver.SquareCoordinate{Row: 10, Col: 10}) {
+			t.Errorf("First play %v doesn't cover the center square", c.Words)
+		}
+	}
+}
+
+func TestCandidatesConnect(t *testing.T) {
+	b := createTestBot(t)
+
+	board := wordgameserver.NewBoard()
+	for i, l := range wordgameserver.Letters("CAT") {
+		board[7][6+i].Letter = l
+		board[7][6+i].Value = 1
+	}
+
+	candidates := b.Candidates(board, wordgameserver.Letters("S"))
+	if len(candidates) != 1 {
+		t.Fatalf("Found %v candidates, expected 1", len(candidates))
+	}
+
+	c := candidates[0]
+	if c.Words[0] != "CATS" {
+		t.Errorf("Play formed %v, expected CATS", c.Words)
+	} else if c.Play.StartPos != (wordgameserver.SquareCoordinate{Row: 7, Col: 9}) {
+		t.Errorf("Play starts at %v, expected row 7 column 9", c.Play.StartPos)
+	}
+}
+
+func TestNextMoveBlank(t *testing.T) {
+	b := createTestBot(t)
+
+	move := b.NextMove(wordgameserver.GameStateResponse{
+		Board:       wordgameserver.NewBoard(),
+		PlayerTiles: rackTiles("ZA QQQQ"),
+	}, Hard)
+	if move.Swap {
+		t.Fatal("Bot swapped instead of playing ZAX with a blank")
+	} else if string(move.Tiles) != "ZA " || string(move.Blanks) != "X" {
+		t.Errorf("Bot played tiles %q with blanks %q, expected \"ZA \" with \"X\"", move.Tiles, move.Blanks)
+	}
+}
+
+func TestNextMoveLeave(t *testing.T) {
+	b := createTestBot(t)
+
+	// CATS scores more, but the S is worth keeping for a later turn
+	move := b.NextMove(wordgameserver.GameStateResponse{
+		Board:       wordgameserver.NewBoard(),
+		PlayerTiles: rackTiles("CATSQQQ"),
+	}, Hard)
+	if len(move.Tiles) != 3 || strings.Contains(string(move.Tiles), "S") {
+		t.Errorf("Bot played %q, expected three tiles keeping the S", move.Tiles)
+	}
+}
+
+func TestNextMoveSwap(t *testing.T) {
+	b := createTestBot(t)
+
+	move := b.NextMove(wordgameserver.GameStateResponse{
+		Board:       wordgameserver.NewBoard(),
+		PlayerTiles: rackTiles("QQQQQQQ"),
+	}, "")
+	if !move.Swap || string(move.Tiles) != "QQQQQQQ" {
+		t.Errorf("Bot should swap every tile when it can't play, got %+v", move)
+	}
+}
+
+func TestNextMoveLevels(t *testing.T) {
+	b := createTestBot(t)
+	state := wordgameserver.GameStateResponse{
+		Board:       wordgameserver.NewBoard(),
+		PlayerTiles: rackTiles("CATSQQQ"),
+	}
+
+	for _, level := range append(b.Levels(), "") {
+		move := b.NextMove(state, level)
+		if move.Swap {
+			t.Errorf("Bot at level %q swapped instead of playing", level)
+		} else if _, _, err := wordgameserver.ScorePlay(state.Board, move); err != nil {
+			t.Errorf("Bot at level %q made invalid play: %v", level, err)
+		}
+	}
+}
+
+func TestNextMoveChampionship(t *testing.T) {
+	b := createTestBot(t)
+	players := []*wordgameserver.Player{{Number: 0}, {Number: 1}}
+
+	// With the bag still full there is nothing to simulate
+	board := wordgameserver.NewBoard()
+	state := wordgameserver.GameStateResponse{Board: board, Players: players, PlayerTiles: rackTiles("CATSQQQ"), BagCount: 86}
+	if hard, move := b.NextMove(state, Hard), b.NextMove(state, Championship); !reflect.DeepEqual(move, hard) {
+		t.Errorf("Bot played %+v early in the game, expected %+v as a hard bot", move, hard)
+	}
+
+	// Once the bag is empty the opponent's rack is known, and the plays are
+	// played out against it
+	board = endgameBoard(t, "CAT", "CATS", "S")
+	state = wordgameserver.GameStateResponse{Board: board, Players: players, PlayerTiles: rackTiles("CATS")}
+	hard, move := b.NextMove(state, Hard), b.NextMove(state, Championship)
+	if _, _, err := wordgameserver.ScorePlay(board, move); err != nil {
+		t.Fatalf("Bot made invalid play: %v", err)
+	} else if again := b.NextMove(state, Championship); !reflect.DeepEqual(again, move) {
+		t.Errorf("Bot played %+v, then %+v from the same position", move, again)
+	} else if reflect.DeepEqual(move, hard) {
+		t.Errorf("Bot played %+v like a hard bot, expected a play that does better played out", move)
+	}
+
+	tiles, bingo, err := wordgameserver.VariantTiles(wordgameserver.VariantStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := simulation{words: b.lexicon.Load().words, set: tiles, bingo: bingo}
+	value := func(play wordgameserver.GamePlayRequest) float64 {
+		score, words, err := wordgameserver.ScorePlay(board, play)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := Candidate{Play: play, Words: words, Score: score}
+		return sim.playout(board, wordgameserver.Letters("CATS"), wordgameserver.Letters("S"), 0, 1, c, rand.New(rand.NewSource(1)))
+	}
+	if v, h := value(move), value(hard); v <= h {
+		t.Errorf("Bot's play came out %v ahead, the hard bot's %v", v, h)
+	}
+
+	// Playing the last tile goes out, which wins the value of the tiles the
+	// opponent holds as well as taking it off their score
+	candidates := b.Candidates(board, wordgameserver.Letters("S"))
+	if len(candidates) == 0 {
+		t.Fatal("No play found for the S")
+	}
+	c := candidates[0]
+	if v := sim.playout(board, wordgameserver.Letters("S"), wordgameserver.Letters("CATS"), 0, 1, c, rand.New(rand.NewSource(1))); v != float64(c.Score+12) {
+		t.Errorf("Going out with %v came out %v ahead, expected %v", c.Words, v, c.Score+12)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	b := createTestBot(t)
+
+	rack := wordgameserver.Letters("CATQQQQ")
+	candidates := b.Candidates(wordgameserver.NewBoard(), rack)
+	solutions := b.Solve(wordgameserver.VariantStandard, wordgameserver.NewBoard(), rack)
+	if len(solutions) != len(candidates) {
+		t.Fatalf("Found %v solutions, expected %v", len(solutions), len(candidates))
+	}
+	for i, c := range candidates {
+		if s := solutions[i]; s.Score != c.Score || !reflect.DeepEqual(s.Words, c.Words) || !reflect.DeepEqual(s.Play, c.Play) {
+			t.Errorf("Solution %v is %+v, expected %+v", i, s, c)
+		}
+	}
+}
+
+// TestVariantMoves checks each play the engine's move generator finds is
+// legal, and that the bot plays from every one of them
+func TestVariantMoves(t *testing.T) {
+	wl := benchmarkWordList()
+	b := New(wl)
+
+	for _, board := range []wordgameserver.ScrabbleBoard{wordgameserver.NewBoard(), benchmarkBoard(b, 3), benchmarkBoard(b, 15)} {
+		for _, rack := range []string{"AEINRST", "QUIZ ", "EEE"} {
+			moves, err := wordgameserver.VariantMoves(wordgameserver.VariantStandard, board, wordgameserver.Letters(rack), wl.DAWG())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, m := range moves {
+				score, words, err := wordgameserver.ScorePlay(board, m.Play)
+				if err != nil {
+					t.Errorf("Move %v from %q is illegal: %v", m.Words, rack, err)
+				} else if score != m.Score || !reflect.DeepEqual(words, m.Words) {
+					t.Errorf("Move %v from %q scored %v, expected %v for %v", m.Words, rack, m.Score, score, words)
+				}
+				for _, w := range words {
+					if !wl.Valid(w) {
+						t.Errorf("Move %v from %q forms %v, which isn't a word", m.Words, rack, w)
+					}
+				}
+			}
+
+			if solutions := b.Solve(wordgameserver.VariantStandard, board, wordgameserver.Letters(rack)); !reflect.DeepEqual(solutions, moves) {
+				t.Errorf("Bot found %v moves from %q, expected the %v the generator finds", len(solutions), rack, len(moves))
+			}
+		}
+	}
+}
+
+// TestBlankCandidates checks the bot plays a blank as any letter that makes a
+// word, including letters it holds a tile for
+func TestBlankCandidates(t *testing.T) {
+	b := createTestBot(t)
+
+	found := make(map[string]bool)
+	for _, c := range b.Candidates(wordgameserver.NewBoard(), wordgameserver.Letters("CAT ")) {
+		if len(c.Play.Blanks) > 0 {
+			found[c.Words[0]+" "+string(c.Play.Blanks)] = true
+		}
+	}
+	for _, want := range []string{"CATS S", "CAT A", "ACT C", "TA T", "AT A"} {
+		if !found[want] {
+			t.Errorf("Bot didn't find %v with the blank as %v", strings.Fields(want)[0], strings.Fields(want)[1])
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	b := createTestBot(t)
+
+	wl, err := dictionary.NewWordList(strings.NewReader("TA\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reload(wl)
+
+	candidates := b.Candidates(wordgameserver.NewBoard(), wordgameserver.Letters("CATQQQQ"))
+	if len(candidates) == 0 {
+		t.Fatal("Found no candidates after reloading")
+	}
+	for _, c := range candidates {
+		if c.Words[0] != "TA" {
+			t.Errorf("Reloaded bot played %v, expected only TA", c.Words)
+		}
+	}
+}
+
+// rackTiles returns the tiles of a rack as the server sends them
+func rackTiles(letters string) []wordgameserver.Tile {
+	var tiles []wordgameserver.Tile
+	for _, l := range letters {
+		tiles = append(tiles, wordgameserver.Tile{Letter: wordgameserver.Letter(l)})
+	}
+	return tiles
+}
+
+func TestSelfPlay(t *testing.T) {
+	// Every string of up to four of the letters in the tile set is a word,
+	// and there are few enough tiles that the game ends quickly
+	tiles := &wordgameserver.TileSet{Name: "small", Tiles: map[string]wordgameserver.TileSpec{
+		"A": {Count: 8, Value: 1}, "E": {Count: 8, Value: 1}, "T": {Count: 5, Value: 1},
+		"S": {Count: 5, Value: 1}, "N": {Count: 4, Value: 2},
+	}}
+	words := []string{""}
+	for i := 0; i < len(words); i++ {
+		if len(words[i]) < 4 {
+			for _, l := range "AENST" {
+				words = append(words, words[i]+string(l))
+			}
+		}
+	}
+	words = words[6:]
+	b := New(dictionary.WordListOf(words))
+
+	seed := int64(7)
+	play := func() wordgameserver.SimulationResult {
+		t.Helper()
+
+		sim, err := wordgameserver.CreateGame(wordgameserver.GameOptions{TileSet: tiles, Words: words, Seed: &seed}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if err = sim.AddBot("", b, Hard); err != nil {
+				t.Fatal(err)
+			}
+		}
+		result, err := sim.RunToCompletion(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := play()
+	if len(result.Winners) == 0 {
+		t.Fatal("Game finished without a winner")
+	}
+	total := 0
+	for _, m := range result.Moves {
+		if !m.Swap && !m.Pass && m.Score <= 0 {
+			t.Errorf("Play of %v scored %v", m.Words, m.Score)
+		}
+		total += m.Score
+	}
+	if total == 0 {
+		t.Error("Bots scored no points")
+	}
+
+	// Hard bots choose their moves the same way every time, so seeded games
+	// play out the same way
+	again := play()
+	if len(again.Moves) != len(result.Moves) {
+		t.Fatalf("Replayed game took %v moves, expected %v", len(again.Moves), len(result.Moves))
+	}
+	for i := range result.Moves {
+		if !reflect.DeepEqual(again.Moves[i].Words, result.Moves[i].Words) {
+			t.Fatalf("Move %v of the replayed game was %v, expected %v", i+1, again.Moves[i].Words, result.Moves[i].Words)
+		}
+	}
+}
+
+// benchmarkWordList returns a made up word list the size of a small
+// dictionary, of words from two to eight letters drawn as often as each
+// letter's tiles are, so plays can be found on boards of any density
+func benchmarkWordList() *dictionary.WordList {
+	const letters = "AAAAAAAAABBCCDDDDEEEEEEEEEEEEFFGGGHHIIIIIIIIIJKLLLLMMNNNNNNOOOOOOOOPPQRRRRRRSSSSTTTTTTUUUUVVWWXYYZ"
+
+	r := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	var words []string
+	for len(words) < 20000 {
+		word := make([]byte, 2+r.Intn(7))
+		for i := range word {
+			word[i] = letters[r.Intn(len(letters))]
+		}
+		if w := string(word); !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return dictionary.WordListOf(words)
+}
+
+// benchmarkBoard returns a standard board after the bot has made the given
+// number of plays on it, from racks drawn from a shuffled bag without blanks
+func benchmarkBoard(b *Bot, plays int) wordgameserver.ScrabbleBoard {
+	const bag = "AAAAAAAAABBCCDDDDEEEEEEEEEEEEFFGGGHHIIIIIIIIIJKLLLLMMNNNNNNOOOOOOOOPPQRRRRRRSSSSTTTTTTUUUUVVWWXYYZ"
+
+	r := rand.New(rand.NewSource(2))
+	board := wordgameserver.NewBoard()
+	for i := 0; i < plays; i++ {
+		rack := make(wordgameserver.Letters, 7)
+		for j := range rack {
+			rack[j] = wordgameserver.Letter(bag[r.Intn(len(bag))])
+		}
+		candidates := b.Candidates(board, rack)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		play := candidates[0].Play
+		tiles := play.Tiles
+		for sc := play.StartPos; len(tiles) > 0; {
+			if squ := &board[sc.Row][sc.Col]; squ.Letter == 0 {
+				squ.Letter, squ.Value, tiles = tiles[0], 1, tiles[1:]
+			}
+			if sc.Row == play.EndPos.Row {
+				sc.Col++
+			} else {
+				sc.Row++
+			}
+		}
+	}
+	return board
+}
+
+// BenchmarkCandidates finds the plays available on empty, sparse and dense
+// boards, from racks with and without blanks, with a dictionary-sized word
+// list. Blanks multiply the words a rack can spell, so they are the costliest.
+func BenchmarkCandidates(b *testing.B) {
+	wl := benchmarkWordList()
+	bot := New(wl)
+	sparse, dense := benchmarkBoard(bot, 3), benchmarkBoard(bot, 25)
+
+	benchmarks := []struct {
+		name  string
+		board wordgameserver.ScrabbleBoard
+		rack  string
+	}{
+		{"Empty", wordgameserver.NewBoard(), "AEINRST"},
+		{"EmptyBlank", wordgameserver.NewBoard(), "AEINRS "},
+		{"Sparse", sparse, "AEINRST"},
+		{"SparseBlank", sparse, "AEINRS "},
+		{"SparseTwoBlanks", sparse, "AEINR  "},
+		{"Dense", dense, "AEINRST"},
+		{"DenseBlank", dense, "AEINRS "},
+		{"DenseTwoBlanks", dense, "AEINR  "},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			rack := wordgameserver.Letters(bm.rack)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bot.Candidates(bm.board, rack)
+			}
+		})
+		b.Run(bm.name+"DAWG", func(b *testing.B) {
+			rack := wordgameserver.Letters(bm.rack)
+			d := wl.DAWG()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				wordgameserver.VariantMoves(wordgameserver.VariantStandard, bm.board, rack, d)
+			}
+		})
+	}
+}
+
+// BenchmarkNew builds the bot's index of a dictionary-sized word list, as it
+// does when the server starts and whenever the dictionary is reloaded
+func BenchmarkNew(b *testing.B) {
+	wl := benchmarkWordList()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		New(wl)
+	}
+}
+
+// endgameBoard returns a standard board with the word across the center and
+// every tile but those in the racks given laid out in the top rows, which
+// form no words, so the bag is empty and the racks hold the only tiles left
+func endgameBoard(t *testing.T, word string, racks ...string) wordgameserver.ScrabbleBoard {
+	t.Helper()
+
+	tiles, _, err := wordgameserver.VariantTiles(wordgameserver.VariantStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := wordgameserver.NewBoard()
+	held := wordgameserver.Letters(word + strings.Join(racks, ""))
+	rest, err := wordgameserver.UnseenTiles(wordgameserver.VariantStandard, board, held)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, l := range word {
+		board[7][7+i].Tile = tiles[wordgameserver.Letter(l)]
+	}
+	for i, l := range rest {
+		tile := tiles[l]
+		if l == ' ' {
+			tile = wordgameserver.Tile{Letter: 'Q', Blank: true}
+		}
+		board[i/len(board)][i%len(board)].Tile = tile
+	}
+	return board
+}
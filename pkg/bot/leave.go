@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"sort"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+)
+
+// remaining returns the rack without the tiles played
+func remaining(rack, played wordgameserver.Letters) wordgameserver.Letters {
+	leave := append(wordgameserver.Letters(nil), rack...)
+	for _, t := range played {
+		for i, r := range leave {
+			if r == t {
+				leave = append(leave[:i], leave[i+1:]...)
+				break
+			}
+		}
+	}
+	return leave
+}
+
+// equity is a candidate's score plus the value of the tiles it leaves on the
+// rack
+func equity(c Candidate, rack wordgameserver.Letters) float64 {
+	return float64(c.Score) + engine.LeaveValue(remaining(rack, c.Play.Tiles))
+}
+
+// bestEquity returns the candidate with the highest equity
+func bestEquity(candidates []Candidate, rack wordgameserver.Letters) Candidate {
+	return topEquity(candidates, rack, 1)[0]
+}
+
+// topEquity returns up to n candidates with the highest equity, best first,
+// keeping the order they were given in between candidates of equal equity
+func topEquity(candidates []Candidate, rack wordgameserver.Letters, n int) []Candidate {
+	type ranked struct {
+		c      Candidate
+		equity float64
+	}
+	ranking := make([]ranked, len(candidates))
+	for i, c := range candidates {
+		ranking[i] = ranked{c: c, equity: equity(c, rack)}
+	}
+	sort.SliceStable(ranking, func(i, j int) bool { return ranking[i].equity > ranking[j].equity })
+
+	top := make([]Candidate, min(n, len(ranking)))
+	for i := range top {
+		top[i] = ranking[i].c
+	}
+	return top
+}
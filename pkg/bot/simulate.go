@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"encoding/binary"
+	"math/rand"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+)
+
+// Limits on how championship bots simulate, which they only do once few
+// enough tiles are left in the bag for the racks their opponents could hold
+// to be sampled well
+const (
+	simBag        = 7  // most tiles left in the bag for plays to be simulated
+	simCandidates = 5  // plays with the best equity simulated
+	simIterations = 12 // racks sampled for the opponents for each play
+)
+
+// simulation plays out a game from a position by having every player make
+// their highest scoring play in turn
+type simulation struct {
+	words *dictionary.DAWG
+	set   map[wordgameserver.Letter]wordgameserver.Tile
+	bingo int
+}
+
+// simulate chooses the candidate that does best when played out against racks
+// the opponents could hold, sampled from the tiles the bot can't see that
+// aren't in the bag. Each opponent replies and then the bot moves again, after
+// which tiles left in hand count against their player if the bag is empty, or
+// the bot's leave is valued if it isn't. The best candidates by equity are
+// simulated, and the one with the best equity is played if it is too early in
+// the game to simulate.
+func (b *Bot) simulate(state wordgameserver.GameStateResponse, rack wordgameserver.Letters, candidates []Candidate) Candidate {
+	best := bestEquity(candidates, rack)
+
+	opponents := 0
+	for _, p := range state.Players {
+		if !p.Resigned && p.Number != state.PlayerTurn {
+			opponents++
+		}
+	}
+	set, bingo, err := wordgameserver.VariantTiles(state.Variant)
+	if err != nil || opponents == 0 {
+		return best
+	}
+	unseen, err := wordgameserver.UnseenTiles(state.Variant, state.Board, rack)
+	if err != nil {
+		return best
+	}
+	bag := state.BagCount
+	if bag > simBag || bag > len(unseen) {
+		return best
+	}
+
+	// A single opponent holds every unseen tile once the bag is empty, so
+	// there is only one rack to play out against
+	iterations := simIterations
+	if bag == 0 && opponents == 1 {
+		iterations = 1
+	}
+
+	sim := simulation{words: b.lexicon.Load().words, set: set, bingo: bingo}
+	seed := int64(binary.BigEndian.Uint64(state.GameID[:8])) + int64(state.MoveCount)
+	bestValue := 0.0
+	for i, c := range topEquity(candidates, rack, simCandidates) {
+		// Every candidate is played out against the same racks, so they are
+		// compared fairly
+		r := rand.New(rand.NewSource(seed))
+		total := 0.0
+		for n := 0; n < iterations; n++ {
+			total += sim.playout(state.Board, rack, unseen, bag, opponents, c, r)
+		}
+		if value := total / float64(iterations); i == 0 || value > bestValue {
+			best, bestValue = c, value
+		}
+	}
+	return best
+}
+
+// playout makes the candidate play from the rack, deals the opponents racks
+// from the unseen tiles, leaving the given number of them in the bag, and lets
+// each of them reply before the bot moves again. A player who uses the last of
+// their tiles with the bag empty goes out, which ends the game. It returns how
+// far ahead of the best opponent the bot gets.
+func (s *simulation) playout(board wordgameserver.ScrabbleBoard, rack, unseen wordgameserver.Letters, bagCount, opponents int, c Candidate, r *rand.Rand) float64 {
+	bag := append(wordgameserver.Letters(nil), unseen...)
+	r.Shuffle(len(bag), func(i, j int) { bag[i], bag[j] = bag[j], bag[i] })
+
+	// The bot's rack is first, followed by the opponents' in turn order. The
+	// tiles held between the opponents are shared out as evenly as they can
+	// be, since how many each holds isn't known.
+	racks := make([]wordgameserver.Letters, opponents+1)
+	held := len(bag) - bagCount
+	for i := 1; i < len(racks); i++ {
+		n := held / opponents
+		if i <= held%opponents {
+			n++
+		}
+		racks[i], bag = append(wordgameserver.Letters(nil), bag[:n]...), bag[n:]
+	}
+	gains := make([]float64, len(racks))
+
+	board = s.apply(board, engine.Play{StartPos: c.Play.StartPos, EndPos: c.Play.EndPos, Tiles: c.Play.Tiles, Blanks: c.Play.Blanks})
+	gains[0] = float64(c.Score)
+	racks[0], bag = draw(remaining(rack, c.Play.Tiles), bag)
+
+	out := -1
+	if len(racks[0]) == 0 && len(bag) == 0 {
+		out = 0
+	}
+	for turn := 1; turn <= len(racks) && out < 0; turn++ {
+		p := turn % len(racks)
+		moves := board.Moves(racks[p], s.words, s.set, s.bingo)
+		if len(moves) == 0 {
+			continue
+		}
+		board = s.apply(board, moves[0].Play)
+		gains[p] += float64(moves[0].Score)
+		racks[p], bag = draw(remaining(racks[p], moves[0].Play.Tiles), bag)
+		if len(racks[p]) == 0 && len(bag) == 0 {
+			out = p
+		}
+	}
+
+	// Once the bag is empty the tiles left in hand will be taken off their
+	// players' scores, and given to the player who went out if one did,
+	// while before then the tiles the bot keeps are valued as they are when
+	// choosing a play
+	if len(bag) == 0 {
+		for p, hand := range racks {
+			for _, l := range hand {
+				gains[p] -= float64(s.set[l].Value)
+				if out >= 0 {
+					gains[out] += float64(s.set[l].Value)
+				}
+			}
+		}
+	} else {
+		gains[0] += engine.LeaveValue(racks[0])
+	}
+
+	lead := gains[0] - gains[1]
+	for _, g := range gains[2:] {
+		lead = min(lead, gains[0]-g)
+	}
+	return lead
+}
+
+// apply returns the board with the play's tiles laid on it
+func (s *simulation) apply(board wordgameserver.ScrabbleBoard, p engine.Play) wordgameserver.ScrabbleBoard {
+	laid, _, _, err := board.LayTiles(p, s.set)
+	if err != nil {
+		return board
+	}
+	return laid
+}
+
+// draw fills the hand from the front of the bag, returning the hand and what
+// is left in the bag
+func draw(hand, bag wordgameserver.Letters) (wordgameserver.Letters, wordgameserver.Letters) {
+	n := min(engine.MaxTiles-len(hand), len(bag))
+	return append(append(wordgameserver.Letters(nil), hand...), bag[:n]...), bag[n:]
+}
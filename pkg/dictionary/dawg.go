@@ -0,0 +1,344 @@
+package dictionary
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// dawgMagic starts every compiled DAWG, so files holding one can be told apart
+// from plain-text word lists
+const dawgMagic = "WGDAWG1\n"
+
+// DAWG is a word list compiled into a directed acyclic word graph: a trie
+// whose identical branches are merged, so words sharing endings as well as
+// beginnings share nodes. Following letters from the root spells out the
+// prefixes of words, which move generators use to extend plays only while they
+// can still become words. A DAWG is a WordValidator and can't be changed once
+// it is built.
+type DAWG struct {
+	nodes []dawgNode // every node after those its edges lead to, so the root is last
+	edges []dawgEdge // the edges of each node, together and sorted by letter
+	words int
+}
+
+// dawgNode is a node of a DAWG, reached by following the letters of a prefix
+type dawgNode struct {
+	first uint32 // index of the node's first edge
+	count uint32 // number of edges leaving the node
+	final bool   // true if the prefix is a word
+}
+
+// dawgEdge leads from a node to the one reached by adding the letter
+type dawgEdge struct {
+	letter rune
+	node   uint32
+}
+
+// NewDAWG compiles the words into a DAWG, ignoring case and duplicates
+func NewDAWG(words []string) *DAWG {
+	sorted := make([]string, len(words))
+	for i, w := range words {
+		sorted[i] = strings.ToUpper(w)
+	}
+	sort.Strings(sorted)
+
+	b := dawgBuilder{register: make(map[string]*buildNode)}
+	b.path = []*buildNode{{}}
+	var prev []rune
+	count := 0
+	for i, w := range sorted {
+		if w == "" || (i > 0 && w == sorted[i-1]) {
+			continue
+		}
+		word := []rune(w)
+		common := 0
+		for common < len(word) && common < len(prev) && word[common] == prev[common] {
+			common++
+		}
+
+		b.minimize(common)
+		for _, l := range word[common:] {
+			n := &buildNode{}
+			parent := b.path[len(b.path)-1]
+			parent.edges = append(parent.edges, buildEdge{letter: l, node: n})
+			b.path = append(b.path, n)
+		}
+		b.path[len(b.path)-1].final = true
+		prev = word
+		count++
+	}
+	b.minimize(0)
+
+	return flatten(b.path[0], count)
+}
+
+// dawgBuilder builds a DAWG from words added in order, merging each branch
+// with an identical one already built as soon as no more words can be added
+// to it
+type dawgBuilder struct {
+	path     []*buildNode          // nodes spelling out the last word added, from the root
+	register map[string]*buildNode // merged nodes, by their signature
+}
+
+// buildNode is a node of a DAWG being built
+type buildNode struct {
+	edges []buildEdge
+	final bool
+	id    int // number the node was registered as, once it has been
+}
+
+type buildEdge struct {
+	letter rune
+	node   *buildNode
+}
+
+// minimize merges the nodes of the last word added below the given depth,
+// which later words can't extend since they come after it, with identical
+// nodes already registered
+func (b *dawgBuilder) minimize(depth int) {
+	for i := len(b.path) - 1; i > depth; i-- {
+		n, parent := b.path[i], b.path[i-1]
+		key := n.signature()
+		if existing, ok := b.register[key]; ok {
+			parent.edges[len(parent.edges)-1].node = existing
+		} else {
+			n.id = len(b.register) + 1
+			b.register[key] = n
+		}
+	}
+	b.path = b.path[:depth+1]
+}
+
+// signature identifies the node by whether it ends a word and its edges,
+// whose nodes have all been registered, so identical nodes share one
+func (n *buildNode) signature() string {
+	var sb strings.Builder
+	if n.final {
+		sb.WriteByte('!')
+	}
+	for _, e := range n.edges {
+		sb.WriteRune(e.letter)
+		sb.WriteString(strconv.Itoa(e.node.id))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// flatten lays the nodes reachable from the root out in arrays, numbering
+// each merged node once. Nodes come after every node their edges lead to, so
+// the root is the last.
+func flatten(root *buildNode, words int) *DAWG {
+	d := DAWG{words: words}
+	index := make(map[*buildNode]uint32)
+	var visit func(n *buildNode) uint32
+	visit = func(n *buildNode) uint32 {
+		if i, ok := index[n]; ok {
+			return i
+		}
+		children := make([]uint32, len(n.edges))
+		for j, e := range n.edges {
+			children[j] = visit(e.node)
+		}
+
+		node := dawgNode{first: uint32(len(d.edges)), count: uint32(len(n.edges)), final: n.final}
+		for j, e := range n.edges {
+			d.edges = append(d.edges, dawgEdge{letter: e.letter, node: children[j]})
+		}
+		index[n] = uint32(len(d.nodes))
+		d.nodes = append(d.nodes, node)
+		return index[n]
+	}
+	visit(root)
+	return &d
+}
+
+// Len returns the number of words in the DAWG
+func (d *DAWG) Len() int {
+	return d.words
+}
+
+// Nodes returns the number of nodes in the DAWG, which is the measure of how
+// well its branches were merged
+func (d *DAWG) Nodes() int {
+	return len(d.nodes)
+}
+
+// Valid reports whether the word is in the DAWG, ignoring case
+func (d *DAWG) Valid(word string) bool {
+	n := d.Root()
+	for _, l := range strings.ToUpper(word) {
+		var ok bool
+		if n, ok = n.Next(l); !ok {
+			return false
+		}
+	}
+	return n.Final()
+}
+
+// Words returns every word in the DAWG in alphabetical order
+func (d *DAWG) Words() []string {
+	words := make([]string, 0, d.words)
+	var walk func(n Node, prefix []rune)
+	walk = func(n Node, prefix []rune) {
+		if n.Final() {
+			words = append(words, string(prefix))
+		}
+		for i := 0; i < n.Edges(); i++ {
+			l, child := n.Edge(i)
+			walk(child, append(prefix, l))
+		}
+	}
+	walk(d.Root(), nil)
+	return words
+}
+
+// Node is a point in a DAWG, reached from its root by following the letters of
+// a prefix of one or more words
+type Node struct {
+	d *DAWG
+	i uint32
+}
+
+// Root returns the node of the empty prefix, which every word starts from
+func (d *DAWG) Root() Node {
+	return Node{d: d, i: uint32(len(d.nodes) - 1)}
+}
+
+// Final reports whether the prefix the node was reached by is a word
+func (n Node) Final() bool {
+	return n.d.nodes[n.i].final
+}
+
+// Edges returns how many letters the prefix can be followed by
+func (n Node) Edges() int {
+	return int(n.d.nodes[n.i].count)
+}
+
+// Edge returns the letter of the node's edge with the index, in alphabetical
+// order, and the node it leads to
+func (n Node) Edge(i int) (rune, Node) {
+	e := n.d.edges[int(n.d.nodes[n.i].first)+i]
+	return e.letter, Node{d: n.d, i: e.node}
+}
+
+// Next returns the node reached by adding the letter to the prefix, and
+// whether any word starts with the prefix it makes
+func (n Node) Next(letter rune) (Node, bool) {
+	node := n.d.nodes[n.i]
+	edges := n.d.edges[node.first : node.first+node.count]
+	i := sort.Search(len(edges), func(i int) bool { return edges[i].letter >= letter })
+	if i == len(edges) || edges[i].letter != letter {
+		return Node{}, false
+	}
+	return Node{d: n.d, i: edges[i].node}, true
+}
+
+// WriteTo writes the DAWG in its binary form, which ReadDAWG reads back far
+// faster than a word list can be compiled
+func (d *DAWG) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+	var written int64
+	put := func(v uint64) {
+		n, _ := bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+		written += int64(n)
+	}
+
+	n, _ := bw.WriteString(dawgMagic)
+	written += int64(n)
+	put(uint64(d.words))
+	put(uint64(len(d.nodes)))
+	put(uint64(len(d.edges)))
+	for _, node := range d.nodes {
+		flags := uint64(node.count) << 1
+		if node.final {
+			flags |= 1
+		}
+		put(flags)
+	}
+	for _, e := range d.edges {
+		put(uint64(e.letter))
+		put(uint64(e.node))
+	}
+
+	return written, errors.Wrap(bw.Flush(), "Failed to write DAWG")
+}
+
+// ReadDAWG reads a DAWG in the binary form written by WriteTo
+func ReadDAWG(r io.Reader) (*DAWG, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(dawgMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != dawgMagic {
+		return nil, errors.New("Not a compiled DAWG")
+	}
+
+	var err error
+	get := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(br)
+		return v
+	}
+
+	words, nodes, edges := get(), get(), get()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read DAWG")
+	} else if nodes == 0 || nodes > 1<<32 || edges > 1<<32 {
+		return nil, errors.New("DAWG is corrupt")
+	}
+
+	d := DAWG{words: int(words), nodes: make([]dawgNode, nodes), edges: make([]dawgEdge, edges)}
+	var first uint64
+	for i := range d.nodes {
+		flags := get()
+		count := flags >> 1
+		if first+count > edges {
+			return nil, errors.New("DAWG is corrupt")
+		}
+		d.nodes[i] = dawgNode{first: uint32(first), count: uint32(count), final: flags&1 == 1}
+		first += count
+	}
+	for i := range d.edges {
+		letter, node := get(), get()
+		if letter > unicode.MaxRune {
+			return nil, errors.New("DAWG is corrupt")
+		}
+		d.edges[i] = dawgEdge{letter: rune(letter), node: uint32(node)}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read DAWG")
+	} else if first != edges {
+		return nil, errors.New("DAWG is corrupt")
+	}
+
+	// Edges only ever lead back to earlier nodes, so the graph can't loop
+	for i, node := range d.nodes {
+		for _, e := range d.edges[node.first : node.first+node.count] {
+			if e.node >= uint32(i) {
+				return nil, errors.New("DAWG is corrupt")
+			}
+		}
+	}
+	return &d, nil
+}
+
+// LoadDAWG reads the compiled DAWG file at path
+func LoadDAWG(path string) (*DAWG, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open DAWG")
+	}
+	defer f.Close()
+
+	return ReadDAWG(f)
+}
@@ -0,0 +1,142 @@
+package dictionary
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDAWG(t *testing.T) {
+	d := NewDAWG([]string{"cats", "CAT", "bats", "BAT", "at", "cat", "ÑU", ""})
+
+	if d.Len() != 6 {
+		t.Errorf("DAWG has %v words, expected 6", d.Len())
+	}
+	for _, w := range []string{"CAT", "cats", "Bat", "BATS", "AT", "ñu"} {
+		if !d.Valid(w) {
+			t.Errorf("Word %v should be valid", w)
+		}
+	}
+	for _, w := range []string{"", "C", "CA", "BA", "CATSS", "DOG", "U"} {
+		if d.Valid(w) {
+			t.Errorf("Word %v should not be valid", w)
+		}
+	}
+
+	expected := []string{"AT", "BAT", "BATS", "CAT", "CATS", "ÑU"}
+	if words := d.Words(); !reflect.DeepEqual(words, expected) {
+		t.Errorf("DAWG holds %v, expected %v", words, expected)
+	}
+
+	// BAT and CAT share every node after their first letter, and every word
+	// ends on the same node: the root, A, Ñ, B and C, BA and CA, BAT and CAT,
+	// and the end
+	if d.Nodes() != 7 {
+		t.Errorf("DAWG has %v nodes, expected 7", d.Nodes())
+	}
+
+	n, ok := d.Root().Next('C')
+	if !ok || n.Final() || n.Edges() != 1 {
+		t.Fatal("C should start words without being one")
+	} else if l, a := n.Edge(0); l != 'A' || a.Edges() != 1 {
+		t.Errorf("C is followed by %q, expected A", l)
+	}
+	if _, ok = d.Root().Next('D'); ok {
+		t.Error("No words start with D")
+	}
+}
+
+func TestDAWGBinary(t *testing.T) {
+	d := NewDAWG([]string{"QI", "ZA", "ZAS", "QIS", "CH"})
+
+	var buf bytes.Buffer
+	n, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %v bytes, wrote %v", n, buf.Len())
+	}
+	data := buf.Bytes()
+
+	read, err := ReadDAWG(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(read, d) {
+		t.Errorf("Read %v words, expected %v", read.Words(), d.Words())
+	}
+
+	if _, err = ReadDAWG(strings.NewReader("QI\nZA\n")); err == nil {
+		t.Error("Reading a word list as a DAWG should fail")
+	} else if _, err = ReadDAWG(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("Reading a truncated DAWG should fail")
+	}
+
+	// An edge leading back to the root would make it loop
+	looped := append([]byte(nil), data...)
+	looped[len(looped)-1] = byte(d.Nodes() - 1)
+	if _, err = ReadDAWG(bytes.NewReader(looped)); err == nil {
+		t.Error("Reading a DAWG that loops should fail")
+	}
+
+	// Word lists can be loaded from compiled files as well as text
+	dir, err := ioutil.TempDir("", "dictionary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "words.dawg")
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	wl, err := LoadWordList(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if wl.Len() != 5 || !wl.Valid("zas") || wl.Valid("Z") {
+		t.Errorf("Loaded word list holds %v, expected %v", wl.Words(), d.Words())
+	} else if wl.DAWG() != wl.DAWG() || !wl.DAWG().Valid("QIS") {
+		t.Error("Loaded word list should keep the DAWG it was compiled to")
+	}
+
+	if _, err = LoadDAWG(path); err != nil {
+		t.Error(err)
+	} else if _, err = LoadDAWG(filepath.Join(dir, "missing.dawg")); err == nil {
+		t.Error("Loading a missing DAWG should fail")
+	}
+}
+
+// BenchmarkDAWG compiles a word list of made up words into a DAWG, and checks
+// words against it and the list
+func BenchmarkDAWG(b *testing.B) {
+	var words []string
+	for _, a := range "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		for _, c := range "AEIOU" {
+			for _, e := range "BDGLNRST" {
+				words = append(words, string([]rune{a, c, e}), string([]rune{a, c, e, 'S'}), string([]rune{'R', 'E', a, c, e}))
+			}
+		}
+	}
+	wl := WordListOf(words)
+
+	b.Run("Compile", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewDAWG(words)
+		}
+	})
+	b.Run("Valid", func(b *testing.B) {
+		d := wl.DAWG()
+		for i := 0; i < b.N; i++ {
+			d.Valid(words[i%len(words)])
+		}
+	})
+	b.Run("ValidList", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			wl.Valid(words[i%len(words)])
+		}
+	})
+}
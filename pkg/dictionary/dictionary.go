@@ -0,0 +1,276 @@
+// Package dictionary provides the word lists used to decide whether words
+// formed during a game are allowed to be played
+package dictionary
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// WordValidator decides whether a word may be played
+type WordValidator interface {
+	Valid(word string) bool
+}
+
+// WordList is a WordValidator backed by a plain-text word list such as TWL or
+// SOWPODS
+type WordList struct {
+	words map[string]struct{}
+
+	compile sync.Once
+	dawg    *DAWG // the list compiled for generating plays, once it has been
+}
+
+// LoadWordList reads the word list file at path, which can be plain text or a
+// DAWG compiled from it, which saves compiling it again
+func LoadWordList(path string) (*WordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open word list")
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(len(dawgMagic)); string(magic) != dawgMagic {
+		return NewWordList(br)
+	}
+
+	d, err := ReadDAWG(br)
+	if err != nil {
+		return nil, err
+	}
+	wl := WordListOf(d.Words())
+	wl.compile.Do(func() { wl.dawg = d })
+	return wl, nil
+}
+
+// NewWordList reads a word list containing one word per line. Only the first
+// field of each line is used, so lists with definitions alongside the words
+// can be loaded as-is. Blank lines and lines starting with '#' are skipped.
+func NewWordList(r io.Reader) (*WordList, error) {
+	wl := &WordList{
+		words: make(map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		wl.words[strings.ToUpper(fields[0])] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read word list")
+	}
+
+	return wl, nil
+}
+
+// Valid reports whether the word is in the list, ignoring case
+func (wl *WordList) Valid(word string) bool {
+	_, ok := wl.words[strings.ToUpper(word)]
+	return ok
+}
+
+// WordListOf creates a word list holding the words given
+func WordListOf(words []string) *WordList {
+	wl := &WordList{
+		words: make(map[string]struct{}, len(words)),
+	}
+	for _, w := range words {
+		wl.words[strings.ToUpper(w)] = struct{}{}
+	}
+	return wl
+}
+
+// Len returns the number of words in the list
+func (wl *WordList) Len() int {
+	return len(wl.words)
+}
+
+// DAWG returns the list compiled into a DAWG, for generating plays, compiling
+// it the first time it is asked for
+func (wl *WordList) DAWG() *DAWG {
+	wl.compile.Do(func() {
+		words := make([]string, 0, len(wl.words))
+		for w := range wl.words {
+			words = append(words, w)
+		}
+		wl.dawg = NewDAWG(words)
+	})
+	return wl.dawg
+}
+
+// Words returns every word in the list in alphabetical order, for generating
+// plays rather than checking them
+func (wl *WordList) Words() []string {
+	words := make([]string, 0, len(wl.words))
+	for w := range wl.words {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// WordFile is a WordValidator backed by a word list file that can be read
+// again while games are using it, so the list can be updated on disk without
+// restarting the server. Words are checked against whichever list was read
+// last.
+type WordFile struct {
+	path string
+	list atomic.Pointer[WordList]
+}
+
+// LoadWordFile reads the word list file at path
+func LoadWordFile(path string) (*WordFile, error) {
+	wf := WordFile{path: path}
+	if err := wf.Reload(); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+// Reload reads the file again and checks words against the new list. The old
+// list is kept if the file can't be read.
+func (wf *WordFile) Reload() error {
+	wl, err := LoadWordList(wf.path)
+	if err != nil {
+		return err
+	}
+	wf.list.Store(wl)
+	return nil
+}
+
+// Valid reports whether the word is in the list last read, ignoring case
+func (wf *WordFile) Valid(word string) bool {
+	return wf.list.Load().Valid(word)
+}
+
+// WordList returns the list last read
+func (wf *WordFile) WordList() *WordList {
+	return wf.list.Load()
+}
+
+// DAWG returns the list last read compiled into a DAWG
+func (wf *WordFile) DAWG() *DAWG {
+	return wf.list.Load().DAWG()
+}
+
+// Path returns the file the list is read from
+func (wf *WordFile) Path() string {
+	return wf.path
+}
+
+// Definitions holds what words mean, for explaining them to players
+type Definitions struct {
+	defs map[string]string
+}
+
+// LoadDefinitions reads the definitions file at path
+func LoadDefinitions(path string) (*Definitions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open definitions")
+	}
+	defer f.Close()
+
+	return NewDefinitions(f)
+}
+
+// NewDefinitions reads definitions laid out like a word list, with each word
+// followed on its line by what it means. Words without a definition are
+// skipped, as are blank lines and lines starting with '#'.
+func NewDefinitions(r io.Reader) (*Definitions, error) {
+	d := Definitions{
+		defs: make(map[string]string),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexFunc(line, unicode.IsSpace)
+		if i < 0 {
+			continue
+		}
+		d.defs[strings.ToUpper(line[:i])] = strings.TrimSpace(line[i:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read definitions")
+	}
+
+	return &d, nil
+}
+
+// Define returns what the word means, ignoring case, and whether there is a
+// definition for it
+func (d *Definitions) Define(word string) (string, bool) {
+	def, ok := d.defs[strings.ToUpper(word)]
+	return def, ok
+}
+
+// Languages is a WordValidator with a dictionary for each language other than
+// English, by language code, and any other lexicons games can choose by name.
+// Words are checked against the English dictionary unless another language or
+// lexicon is chosen.
+type Languages struct {
+	English  WordValidator            // dictionary words are checked against by default
+	Others   map[string]WordValidator // dictionaries of the other languages
+	Lexicons map[string]WordValidator // word lists games can choose instead, such as TWL or SOWPODS
+}
+
+// Valid reports whether the word is in the English dictionary, accepting any
+// word if there isn't one
+func (l Languages) Valid(word string) bool {
+	return l.English == nil || l.English.Valid(word)
+}
+
+// DAWG returns the English dictionary compiled into a DAWG, or nil if it can't
+// be compiled
+func (l Languages) DAWG() *DAWG {
+	if c, ok := l.English.(interface{ DAWG() *DAWG }); ok {
+		return c.DAWG()
+	}
+	return nil
+}
+
+// Language returns the dictionary of the language with the code, or nil if
+// there isn't one
+func (l Languages) Language(code string) WordValidator {
+	return l.Others[code]
+}
+
+// Lexicon returns the lexicon with the name, or nil if there isn't one
+func (l Languages) Lexicon(name string) WordValidator {
+	return l.Lexicons[name]
+}
+
+// LanguageCodes returns the codes of the languages with a dictionary, in order
+func (l Languages) LanguageCodes() []string {
+	return sortedKeys(l.Others)
+}
+
+// LexiconNames returns the names of the lexicons, in order
+func (l Languages) LexiconNames() []string {
+	return sortedKeys(l.Lexicons)
+}
+
+func sortedKeys(m map[string]WordValidator) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+package dictionary
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewWordList(t *testing.T) {
+	list := `# Sample word list
+aa
+CAT  a small domesticated carnivore
+
+dog
+`
+	wl, err := NewWordList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wl.Len() != 3 {
+		t.Fatalf("Word list has %v words, expected 3", wl.Len())
+	}
+
+	valid := []string{"AA", "cat", "Dog"}
+	for _, w := range valid {
+		if !wl.Valid(w) {
+			t.Errorf("Word %v should be valid", w)
+		}
+	}
+
+	invalid := []string{"", "#", "SAMPLE", "CATS", "A"}
+	for _, w := range invalid {
+		if wl.Valid(w) {
+			t.Errorf("Word %v should not be valid", w)
+		}
+	}
+}
+
+func TestLoadWordList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dictionary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "words.txt")
+	if err = ioutil.WriteFile(path, []byte("QI\nZA\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wl, err := LoadWordList(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if !wl.Valid("qi") || !wl.Valid("za") {
+		t.Error("Loaded word list is missing words")
+	}
+
+	if _, err = LoadWordList(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Loading a missing word list should fail")
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	en, err := NewWordList(strings.NewReader("CAT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := NewWordList(strings.NewReader("GATO\nÑU\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Languages{English: en, Others: map[string]WordValidator{"es": es}}
+
+	if !l.Valid("CAT") || l.Valid("GATO") {
+		t.Error("Words should be checked against the English dictionary by default")
+	}
+	if v := l.Language("es"); v == nil || !v.Valid("ñu") {
+		t.Error("Spanish dictionary should accept ÑU")
+	}
+	if l.Language("de") != nil {
+		t.Error("Languages without a dictionary should have none")
+	}
+
+	l.Lexicons = map[string]WordValidator{"SOWPODS": es}
+	if v := l.Lexicon("SOWPODS"); v == nil || !v.Valid("GATO") {
+		t.Error("Lexicon should be found by name")
+	} else if l.Lexicon("TWL") != nil {
+		t.Error("Unknown lexicons should be nil")
+	}
+	if codes, names := l.LanguageCodes(), l.LexiconNames(); len(codes) != 1 || codes[0] != "es" || len(names) != 1 || names[0] != "SOWPODS" {
+		t.Errorf("Listed languages %v and lexicons %v, expected [es] and [SOWPODS]", codes, names)
+	}
+	if !(Languages{}).Valid("ANYTHING") {
+		t.Error("Any word should be accepted without an English dictionary")
+	}
+	if d := l.DAWG(); d == nil || !d.Valid("CAT") {
+		t.Error("English dictionary should be compiled into a DAWG")
+	} else if (Languages{}).DAWG() != nil {
+		t.Error("No DAWG should be compiled without an English dictionary")
+	}
+}
+
+func TestWordFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dictionary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "words.txt")
+	if err = ioutil.WriteFile(path, []byte("QI\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wf, err := LoadWordFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if !wf.Valid("QI") || wf.Valid("ZA") {
+		t.Fatal("Word file should only accept QI before reloading")
+	}
+
+	if err = ioutil.WriteFile(path, []byte("ZA\n"), 0644); err != nil {
+		t.Fatal(err)
+	} else if err = wf.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if wf.Valid("QI") || !wf.Valid("ZA") || wf.WordList().Len() != 1 {
+		t.Error("Reloaded word file should only accept ZA")
+	} else if !wf.DAWG().Valid("ZA") {
+		t.Error("Reloaded word file should be compiled from the new list")
+	}
+
+	// A file that can't be read leaves the last list in place
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	} else if err = wf.Reload(); err == nil {
+		t.Error("Reloading a missing word file should fail")
+	} else if !wf.Valid("ZA") {
+		t.Error("Failed reload should keep the last list")
+	}
+}
+
+func TestWordListOf(t *testing.T) {
+	wl := WordListOf([]string{"qi", "ZA", "za"})
+	if wl.Len() != 2 || !wl.Valid("QI") || !wl.Valid("za") || wl.Valid("CAT") {
+		t.Errorf("Word list has %v, expected QI and ZA", wl.Words())
+	}
+}
+
+func TestNewDefinitions(t *testing.T) {
+	list := `# Sample definitions
+CAT  a small domesticated carnivore
+qi	the vital force in Chinese philosophy
+ZA
+`
+	d, err := NewDefinitions(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if def, ok := d.Define("cat"); !ok || def != "a small domesticated carnivore" {
+		t.Errorf("CAT is defined as %q, expected a small domesticated carnivore", def)
+	}
+	if def, ok := d.Define("QI"); !ok || def != "the vital force in Chinese philosophy" {
+		t.Errorf("QI is defined as %q, expected the vital force in Chinese philosophy", def)
+	}
+	if _, ok := d.Define("ZA"); ok {
+		t.Error("Words without a definition should not be defined")
+	}
+}
@@ -0,0 +1,190 @@
+package engine
+
+// SquareCoordinate represents a coordinate of a Scrabble board
+type SquareCoordinate struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// SquareType represents the underlying types of squares on a Scrabble board
+type SquareType struct {
+	Name             string `json:"name"`             // type of square, such as plain or tripleWord
+	LetterMultiplier int    `json:"letterMultiplier"` // multiplier for letters on square
+	WordMultiplier   int    `json:"wordMultiplier"`   // multiplier for words on square
+}
+
+// Square represents the squares on a Scrabble Board
+type Square struct {
+	SquareType string `json:"type"`
+	Tile       `json:"tile,omitempty"`
+}
+
+// Board represents the board containing a grid of Squares, indexed by row then
+// column. Boards are square, with a size set by the game's variant.
+type Board [][]Square
+
+// SquareTypes is a definition of the possible square types and the values they
+// hold
+var SquareTypes = map[string]SquareType{
+	"plain":           {Name: "plain", LetterMultiplier: 1, WordMultiplier: 1},
+	"star":            {Name: "star", LetterMultiplier: 1, WordMultiplier: 1},
+	"doubleLetter":    {Name: "doubleLetter", LetterMultiplier: 2, WordMultiplier: 1},
+	"doubleWord":      {Name: "doubleWord", LetterMultiplier: 1, WordMultiplier: 2},
+	"tripleLetter":    {Name: "tripleLetter", LetterMultiplier: 3, WordMultiplier: 1},
+	"tripleWord":      {Name: "tripleWord", LetterMultiplier: 1, WordMultiplier: 3},
+	"quadrupleLetter": {Name: "quadrupleLetter", LetterMultiplier: 4, WordMultiplier: 1},
+	"quadrupleWord":   {Name: "quadrupleWord", LetterMultiplier: 1, WordMultiplier: 4},
+}
+
+// LayoutBoard creates an empty board of the size with the premium squares
+// placed. Premiums are given by their coordinates in the top left quadrant,
+// and mirrored into the other three.
+func LayoutBoard(size int, premiums map[string][]SquareCoordinate) Board {
+
+	sb := make(Board, size)
+
+	// Initialize board with plain squares
+	for i := range sb {
+		sb[i] = make([]Square, size)
+		for j := range sb[i] {
+			sb[i][j] = Square{
+				SquareType: "plain",
+			}
+		}
+	}
+
+	// Place remaining squares based on coordinates
+	for name, coordinates := range premiums {
+		for _, sc := range coordinates {
+
+			squ := Square{
+				SquareType: name,
+			}
+
+			// Quadrant 1
+			sb[sc.Row][size-1-sc.Col] = squ
+
+			// Quadrant 2
+			sb[sc.Row][sc.Col] = squ
+
+			// Quadrant 3
+			sb[size-1-sc.Row][sc.Col] = squ
+
+			// Quadrant 4
+			sb[size-1-sc.Row][size-1-sc.Col] = squ
+		}
+	}
+
+	return sb
+}
+
+// Clone returns a copy of the board that can be changed without affecting the
+// original
+func (sb Board) Clone() Board {
+	if sb == nil {
+		return nil
+	}
+	c := make(Board, len(sb))
+	for i, row := range sb {
+		c[i] = append([]Square(nil), row...)
+	}
+	return c
+}
+
+// OnBoard reports whether the coordinate falls within the board
+func (sb Board) OnBoard(sc SquareCoordinate) bool {
+	return sc.Row >= 0 && sc.Row < len(sb) && sc.Col >= 0 && sc.Col < len(sb[sc.Row])
+}
+
+// FormedWord is a word created by a play along with the squares it covers
+type FormedWord struct {
+	Word    string
+	Squares []SquareCoordinate
+}
+
+// Next returns the coordinate one step away in the given direction
+func (sc SquareCoordinate) Next(step SquareCoordinate) SquareCoordinate {
+	return SquareCoordinate{Row: sc.Row + step.Row, Col: sc.Col + step.Col}
+}
+
+// Prev returns the coordinate one step back from the given direction
+func (sc SquareCoordinate) Prev(step SquareCoordinate) SquareCoordinate {
+	return SquareCoordinate{Row: sc.Row - step.Row, Col: sc.Col - step.Col}
+}
+
+// Occupied reports whether a tile has been placed on the square
+func (s Square) Occupied() bool {
+	return s.Letter != 0
+}
+
+// Empty reports whether no tiles have been placed on the board
+func (sb Board) Empty() bool {
+	for _, row := range sb {
+		for _, squ := range row {
+			if squ.Occupied() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// At returns the square at the coordinate
+func (sb Board) At(sc SquareCoordinate) *Square {
+	return &sb[sc.Row][sc.Col]
+}
+
+// WordAt finds the full word running through the coordinate in the direction
+// of step, extending both ways until an empty square or the edge is reached
+func (sb Board) WordAt(sc SquareCoordinate, step SquareCoordinate) FormedWord {
+	// Move back to the first letter of the word
+	for p := sc.Prev(step); sb.OnBoard(p) && sb.At(p).Occupied(); p = p.Prev(step) {
+		sc = p
+	}
+
+	var w FormedWord
+	for ; sb.OnBoard(sc) && sb.At(sc).Occupied(); sc = sc.Next(step) {
+		w.Word += sb.At(sc).Letter.String()
+		w.Squares = append(w.Squares, sc)
+	}
+	return w
+}
+
+// WordsFormed returns every word of two or more letters created by tiles
+// placed in a line in the direction of step. The first word returned is the
+// one running along the line of play, followed by any cross words.
+func (sb Board) WordsFormed(placed []SquareCoordinate, step SquareCoordinate) []FormedWord {
+	var words []FormedWord
+	if len(placed) == 0 {
+		return words
+	}
+
+	if w := sb.WordAt(placed[0], step); len(w.Squares) > 1 {
+		words = append(words, w)
+	}
+
+	cross := SquareCoordinate{Row: step.Col, Col: step.Row}
+	for _, sc := range placed {
+		if w := sb.WordAt(sc, cross); len(w.Squares) > 1 {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// ScoreWord totals the value of a word's tiles, applying premium squares only
+// to the tiles that were placed this turn
+func (sb Board) ScoreWord(w FormedWord, placed map[SquareCoordinate]bool) int {
+	score, multiplier := 0, 1
+	for _, sc := range w.Squares {
+		squ := sb.At(sc)
+		value := squ.Value
+		if placed[sc] {
+			st := SquareTypes[squ.SquareType]
+			value *= st.LetterMultiplier
+			multiplier *= st.WordMultiplier
+		}
+		score += value
+	}
+	return score * multiplier
+}
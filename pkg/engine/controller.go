@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"time"
+)
+
+// Handler is what a controller hands a game's requests to, along with the
+// moments the game has to act without one, such as when a clock runs out.
+// Handlers report whether the game has finished, which stops the controller.
+type Handler[R any] interface {
+	Handle(request R) (finished bool)
+	Deadline() (time.Time, bool) // when the game next has to act on its own, if it does
+	Expire() (finished bool)     // act on the deadline having passed
+}
+
+// Control hands requests from the queue to the handler one at a time, so the
+// game never handles two at once, whatever carries them. It returns once done
+// is closed or the handler reports the game has finished.
+func Control[R any](queue <-chan R, done <-chan struct{}, h Handler[R]) {
+	for {
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if deadline, ok := h.Deadline(); ok {
+			timer = time.NewTimer(time.Until(deadline))
+			timeout = timer.C
+		}
+
+		var finished bool
+		select {
+		case request := <-queue:
+			finished = h.Handle(request)
+		case <-timeout:
+			finished = h.Expire()
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if finished {
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// countingHandler finishes once it has handled enough requests, and has a
+// deadline until it has expired once
+type countingHandler struct {
+	handled, finishAt int
+	expired           bool
+	expiring          chan struct{} // closed when the deadline expires
+}
+
+func (h *countingHandler) Handle(request int) bool {
+	h.handled += request
+	return h.handled >= h.finishAt
+}
+
+func (h *countingHandler) Deadline() (time.Time, bool) {
+	return time.Now().Add(time.Millisecond), !h.expired
+}
+
+func (h *countingHandler) Expire() bool {
+	h.expired = true
+	close(h.expiring)
+	return false
+}
+
+func TestControl(t *testing.T) {
+	queue := make(chan int)
+	done := make(chan struct{})
+	h := &countingHandler{finishAt: 2, expiring: make(chan struct{})}
+	returned := make(chan struct{})
+	go func() {
+		Control[int](queue, done, h)
+		close(returned)
+	}()
+
+	// The deadline passes between requests, and the handler finishing stops
+	// the controller
+	queue <- 1
+	select {
+	case <-h.expiring:
+	case <-time.After(time.Second):
+		t.Fatal("Deadline never expired")
+	}
+	queue <- 1
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("Controller still running after the handler finished")
+	}
+
+	// So does closing done
+	returned = make(chan struct{})
+	go func() {
+		Control[int](queue, done, &countingHandler{finishAt: 2, expired: true})
+		close(returned)
+	}()
+	close(done)
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("Controller still running after done was closed")
+	}
+}
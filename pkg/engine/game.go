@@ -0,0 +1,15 @@
+package engine
+
+// Game is a game being played, as seen by whatever carries its moves, be it
+// the server's API, a bot or a command line. Players are known by their number
+// in turn order, and each method fails if the player can't make the move.
+// Games take their turns at a Table, so the rules of how turns go are the
+// table's wherever the moves come from.
+type Game interface {
+	Turn() int                            // number of the player to move
+	Rack(player int) Letters              // tiles the player holds
+	Play(player int, p Play) error        // lay tiles on the board
+	Swap(player int, tiles Letters) error // exchange tiles with the bag
+	Pass(player int) error                // give up the turn
+	Over() bool                           // true once no more moves can be made
+}
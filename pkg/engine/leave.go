@@ -0,0 +1,88 @@
+package engine
+
+// tileLeaves is roughly how much keeping each tile for later turns is worth in
+// points. Blanks and S make future plays easier, while awkward letters make
+// them harder.
+var tileLeaves = map[Letter]float64{
+	' ': 25, 'S': 8, 'Z': 3, 'X': 3, 'E': 3, 'R': 1.5, 'H': 1,
+	'N': 0.5, 'T': 0.5, 'M': 0.5, 'D': 0.5, 'L': 0.5, 'A': 0.5, 'C': 0.5,
+	'I': -0.5, 'P': 0, 'K': -0.5, 'Y': -0.5, 'G': -2, 'O': -1, 'F': -2,
+	'B': -2, 'J': -1.5, 'W': -4, 'U': -3, 'V': -5.5, 'Q': -7,
+}
+
+// comboLeaves adjusts the worth of tiles kept together, by the tiles in
+// alphabetical order. Some make common word endings or cover for each other,
+// like a U for a Q, while others are harder to play together than apart.
+var comboLeaves = map[string]float64{
+	"QU": 6, "ER": 1.5, "ES": 2, "EST": 1.5, "ERS": 1.5, "INS": 1, "IN": 1,
+	"GIN": 4, "DE": 1, "ST": 1, "ACE": 0.5, "AER": 1, "EIR": 0.5, "ELR": 0.5,
+	"CK": 1.5, "CH": 1, "HT": 0.5, "LY": 1, "IO": -1, "IU": -2, "OU": -1,
+	"VW": -3, "QV": -3, "JQ": -5, "KV": -2, "FV": -2, " S": 3, "  ": -6,
+}
+
+// duplicatePenalty is subtracted for each extra copy of a tile kept
+const duplicatePenalty = 3.0
+
+// balancePenalty is subtracted for each vowel or consonant the leave is away
+// from an even split
+const balancePenalty = 2.0
+
+// LeaveValue estimates in points how useful the tiles left on a rack after a
+// play will be on later turns, from static tables of the worth of each tile
+// and of combinations of tiles kept together, less penalties for duplicates
+// and for too many vowels or consonants. A play's equity, its score plus the
+// value of its leave, is a better measure of how good it is than its score.
+func LeaveValue(leave Letters) float64 {
+	value := 0.0
+	for combo, v := range comboLeaves {
+		if contains(leave, combo) {
+			value += v
+		}
+	}
+
+	seen := make(map[Letter]bool, len(leave))
+	vowels, consonants := 0, 0
+	for _, t := range leave {
+		value += tileLeaves[t]
+		if seen[t] && t != ' ' {
+			value -= duplicatePenalty
+		}
+		seen[t] = true
+
+		switch t {
+		case ' ':
+		case 'A', 'E', 'I', 'O', 'U':
+			vowels++
+		default:
+			consonants++
+		}
+	}
+
+	diff := vowels - consonants
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > 1 {
+		value -= balancePenalty * float64(diff-1)
+	}
+	return value
+}
+
+// contains reports whether the tiles include every tile of the combination,
+// each as many times as it appears in it
+func contains(tiles Letters, combo string) bool {
+	left := append(Letters(nil), tiles...)
+	for _, t := range combo {
+		found := false
+		for i, l := range left {
+			if l == Letter(t) {
+				left, found = append(left[:i], left[i+1:]...), true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
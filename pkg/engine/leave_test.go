@@ -0,0 +1,17 @@
+package engine
+
+import "testing"
+
+func TestLeaveValue(t *testing.T) {
+	if LeaveValue(Letters(" S")) <= LeaveValue(Letters("QV")) {
+		t.Error("Keeping a blank and S should be worth more than Q and V")
+	} else if LeaveValue(Letters("EE")) >= LeaveValue(Letters("ER")) {
+		t.Error("Keeping duplicate tiles should be worth less than distinct ones")
+	} else if LeaveValue(Letters("RTN")) >= LeaveValue(Letters("RTE")) {
+		t.Error("Keeping only consonants should be worth less than a balanced leave")
+	} else if LeaveValue(Letters("QU")) <= LeaveValue(Letters("Q")) {
+		t.Error("Keeping a U with a Q should be worth more than the Q alone")
+	} else if LeaveValue(Letters("NIG")) <= LeaveValue(Letters("NIK")) {
+		t.Error("Keeping ING should be worth more than its tiles apart")
+	}
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Letter is what is written on a tile. Letters are the runes they spell,
+// except for digraphs, which take runes from Unicode's private use area since
+// they have none of their own. Blank tiles are a space.
+type Letter rune
+
+// Letters beyond A to Z played in languages other than English
+const (
+	TileCH Letter = '\uE000'
+	TileLL Letter = '\uE001'
+	TileRR Letter = '\uE002'
+	TileÄ  Letter = 'Ä'
+	TileÑ  Letter = 'Ñ'
+	TileÖ  Letter = 'Ö'
+	TileÜ  Letter = 'Ü'
+)
+
+// extraLetters holds what each letter beyond A to Z spells
+var extraLetters = map[Letter]string{
+	TileCH: "CH",
+	TileLL: "LL",
+	TileRR: "RR",
+	TileÄ:  "Ä",
+	TileÑ:  "Ñ",
+	TileÖ:  "Ö",
+	TileÜ:  "Ü",
+}
+
+// String returns what the letter spells in a word
+func (l Letter) String() string {
+	if s, ok := extraLetters[l]; ok {
+		return s
+	}
+	return string(rune(l))
+}
+
+// ParseLetter returns the letter that spells the text, and whether there is
+// one. Digraphs are recognized in either case.
+func ParseLetter(text string) (Letter, bool) {
+	if r, size := utf8.DecodeRuneInString(text); size > 0 && size == len(text) && r != utf8.RuneError {
+		return Letter(r), true
+	}
+	upper := strings.ToUpper(text)
+	for l, s := range extraLetters {
+		if s == upper {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// KnownLetter reports whether the letter is from A to Z or one of the other
+// letters languages are played with
+func KnownLetter(l Letter) bool {
+	_, ok := extraLetters[l]
+	return ok || (l >= 'A' && l <= 'Z')
+}
+
+// ToUpper returns the letter in upper case, so blanks can be designated in
+// either
+func (l Letter) ToUpper() Letter {
+	return Letter(unicode.ToUpper(rune(l)))
+}
+
+// MarshalText writes the letter as the text it spells, such as "CH"
+func (l Letter) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText reads a letter written as the text it spells
+func (l *Letter) UnmarshalText(text []byte) error {
+	parsed, ok := ParseLetter(string(text))
+	if !ok {
+		return errors.New("Unknown letter '" + string(text) + "'")
+	}
+	*l = parsed
+	return nil
+}
+
+// Letters are the tiles of a rack, play or bag, written in JSON as an array of
+// the text each spells, with blanks as " "
+type Letters []Letter
+
+// String returns the letters spelled out one after another
+func (ls Letters) String() string {
+	var sb strings.Builder
+	for _, l := range ls {
+		sb.WriteString(l.String())
+	}
+	return sb.String()
+}
+
+// UnmarshalJSON reads letters from an array of text, or from the base64
+// encoded bytes games were saved with before letters were runes
+func (ls *Letters) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '"' {
+		var letters []Letter
+		if err := json.Unmarshal(data, &letters); err != nil {
+			return err
+		}
+		*ls = letters
+		return nil
+	}
+
+	var b []byte
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	letters := make(Letters, len(b))
+	for i, c := range b {
+		letters[i] = legacyLetter(c)
+	}
+	*ls = letters
+	return nil
+}
+
+// legacyLetter returns the letter a tile was saved as when tiles were bytes,
+// with accented letters as their Latin-1 bytes and digraphs as bytes Latin-1
+// leaves unused
+func legacyLetter(b byte) Letter {
+	switch b {
+	case 0x80:
+		return TileCH
+	case 0x81:
+		return TileLL
+	case 0x82:
+		return TileRR
+	}
+	return Letter(b)
+}
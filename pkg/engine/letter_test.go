@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLettersJSON(t *testing.T) {
+	letters := Letters{'C', TileCH, TileÑ, ' '}
+
+	data, err := json.Marshal(letters)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(data) != `["C","CH","Ñ"," "]` {
+		t.Errorf("Marshaled letters as %s, expected [\"C\",\"CH\",\"Ñ\",\" \"]", data)
+	}
+
+	var decoded Letters
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(decoded, letters) {
+		t.Errorf("Unmarshaled letters %v, expected %v", decoded, letters)
+	}
+
+	// Digraphs can be sent in lower case
+	if err = json.Unmarshal([]byte(`["ll"]`), &decoded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(decoded, Letters{TileLL}) {
+		t.Errorf("Unmarshaled letters %v, expected LL", decoded)
+	}
+
+	if err = json.Unmarshal([]byte(`["QX"]`), &decoded); err == nil {
+		t.Error("Unmarshaling an unknown letter should fail")
+	}
+}
+
+func TestLettersLegacyJSON(t *testing.T) {
+	// Games saved when tiles were bytes hold them base64 encoded, with
+	// digraphs as bytes from 0x80
+	data, err := json.Marshal([]byte{'C', 0x80, 0xD1, ' '})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Letters
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	} else if expected := (Letters{'C', TileCH, TileÑ, ' '}); !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Unmarshaled legacy letters %v, expected %v", decoded, expected)
+	}
+}
@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+// Move is a legal play found on a board, with the words it forms and what it
+// scores
+type Move struct {
+	Play  Play
+	Words []string // the word along the line of play first, then any cross words
+	Score int
+}
+
+// Moves finds every legal play of tiles from the rack on the board that forms
+// only words in the DAWG, valued from the set of tiles given and with the
+// bingo bonus added to plays of a full hand. Moves are returned highest
+// scoring first.
+//
+// Plays are found by starting at each square a word can start on and
+// following the DAWG along the line, so only prefixes of words are ever
+// extended. Tiles placed must also form words across the line, which is
+// checked for each square as it is filled.
+func (sb Board) Moves(rack Letters, words *dictionary.DAWG, set map[Letter]Tile, bingo int) []Move {
+	g := moveGenerator{
+		board: sb,
+		words: words,
+		set:   set,
+		bingo: bingo,
+		empty: sb.Empty(),
+		rack:  make(map[Letter]int),
+	}
+
+	for _, l := range rack {
+		t, ok := set[l]
+		if !ok {
+			continue
+		} else if t.Letter == ' ' {
+			g.blankTiles++
+		} else if g.rack[l]++; g.rack[l] == 1 {
+			g.letters = append(g.letters, l)
+		}
+		g.size++
+	}
+	sort.Slice(g.letters, func(i, j int) bool { return g.letters[i] < g.letters[j] })
+
+	// Blanks can stand for any letter from A to Z, or any other letter among
+	// the tiles
+	for l := Letter('A'); l <= 'Z'; l++ {
+		g.alphabet = append(g.alphabet, l)
+	}
+	for l := range set {
+		if l != ' ' && (l < 'A' || l > 'Z') {
+			g.alphabet = append(g.alphabet, l)
+		}
+	}
+	sort.Slice(g.alphabet, func(i, j int) bool { return g.alphabet[i] < g.alphabet[j] })
+
+	for _, step := range []SquareCoordinate{{Row: 0, Col: 1}, {Row: 1, Col: 0}} {
+		g.step = step
+		g.checks = make(map[crossCheck]bool)
+		for row := range sb {
+			for col := range sb[row] {
+				start := SquareCoordinate{Row: row, Col: col}
+				if p := start.Prev(step); sb.OnBoard(p) && sb.At(p).Occupied() {
+					continue
+				} else if !g.reachesAnchor(start) {
+					continue
+				}
+				g.start = start
+				g.extend(start, words.Root(), false)
+			}
+		}
+	}
+
+	sort.SliceStable(g.moves, func(i, j int) bool { return g.moves[i].Score > g.moves[j].Score })
+	return g.moves
+}
+
+// moveGenerator holds what is known about the board and rack while plays are
+// searched for, and the tiles of the play being built
+type moveGenerator struct {
+	board Board
+	words *dictionary.DAWG
+	set   map[Letter]Tile
+	bingo int
+	empty bool // true if the board is empty, so plays must cover the center
+
+	rack       map[Letter]int // tiles left in the rack other than blanks
+	letters    []Letter       // each letter in the rack, in order
+	blankTiles int            // blank tiles in the rack
+	size       int            // tiles in the rack that can be played
+	alphabet   []Letter       // letters blanks can stand for
+
+	step   SquareCoordinate    // direction of the line plays are being found along
+	checks map[crossCheck]bool // letters known to form a word across the line, or not
+	start  SquareCoordinate    // square the word being built starts on
+
+	tiles  Letters            // tiles placed so far
+	blanks Letters            // letters the blanks placed stand for
+	placed []SquareCoordinate // squares the tiles were placed on
+
+	moves []Move
+}
+
+type crossCheck struct {
+	sc     SquareCoordinate
+	letter Letter
+}
+
+// anchor reports whether a play covering the empty square would connect to
+// the tiles on the board, or cover the center square of an empty board
+func (g *moveGenerator) anchor(sc SquareCoordinate) bool {
+	if g.empty {
+		return sc == SquareCoordinate{Row: len(g.board) / 2, Col: len(g.board) / 2}
+	}
+	for _, n := range []SquareCoordinate{{Row: sc.Row - 1, Col: sc.Col}, {Row: sc.Row + 1, Col: sc.Col},
+		{Row: sc.Row, Col: sc.Col - 1}, {Row: sc.Row, Col: sc.Col + 1}} {
+		if g.board.OnBoard(n) && g.board.At(n).Occupied() {
+			return true
+		}
+	}
+	return false
+}
+
+// reachesAnchor reports whether a word starting on the square could connect
+// before the rack runs out, which saves searching lines where none can
+func (g *moveGenerator) reachesAnchor(start SquareCoordinate) bool {
+	for sc, empty := start, 0; g.board.OnBoard(sc); sc = sc.Next(g.step) {
+		if g.board.At(sc).Occupied() || g.anchor(sc) {
+			return true
+		} else if empty++; empty >= g.size {
+			return false
+		}
+	}
+	return false
+}
+
+// fits reports whether the letter can go on the empty square, forming a word
+// with any tiles either side of it across the line
+func (g *moveGenerator) fits(sc SquareCoordinate, l Letter) bool {
+	key := crossCheck{sc: sc, letter: l}
+	if ok, known := g.checks[key]; known {
+		return ok
+	}
+
+	cross := SquareCoordinate{Row: g.step.Col, Col: g.step.Row}
+	before, after := g.wordFrom(sc.Prev(cross), cross), g.wordFrom(sc.Next(cross), cross)
+	ok := (before == "" && after == "") || g.words.Valid(before+l.String()+after)
+	g.checks[key] = ok
+	return ok
+}
+
+// extend builds the word from the square onwards, following the node reached
+// by the letters before it. Each time the word could end it is kept as a move
+// if it is one.
+func (g *moveGenerator) extend(sc SquareCoordinate, n dictionary.Node, connected bool) {
+	if !g.board.OnBoard(sc) || !g.board.At(sc).Occupied() {
+		if n.Final() && connected && len(g.placed) > 0 && g.length(sc) > 1 {
+			g.record()
+		}
+		if !g.board.OnBoard(sc) || len(g.placed) == g.size {
+			return
+		}
+	}
+
+	if squ := g.board.At(sc); squ.Occupied() {
+		if next, ok := follow(n, squ.Letter); ok {
+			g.extend(sc.Next(g.step), next, true)
+		}
+		return
+	}
+
+	connected = connected || g.anchor(sc)
+	g.placed = append(g.placed, sc)
+	for _, l := range g.letters {
+		if g.rack[l] == 0 || !g.fits(sc, l) {
+			continue
+		}
+		if next, ok := follow(n, l); ok {
+			g.rack[l]--
+			g.tiles = append(g.tiles, l)
+			g.extend(sc.Next(g.step), next, connected)
+			g.tiles = g.tiles[:len(g.tiles)-1]
+			g.rack[l]++
+		}
+	}
+	if len(g.blanks) < g.blankTiles {
+		for _, l := range g.alphabet {
+			if !g.fits(sc, l) {
+				continue
+			}
+			if next, ok := follow(n, l); ok {
+				g.tiles = append(g.tiles, ' ')
+				g.blanks = append(g.blanks, l)
+				g.extend(sc.Next(g.step), next, connected)
+				g.blanks = g.blanks[:len(g.blanks)-1]
+				g.tiles = g.tiles[:len(g.tiles)-1]
+			}
+		}
+	}
+	g.placed = g.placed[:len(g.placed)-1]
+}
+
+// wordFrom returns the word running through the square in the direction of
+// step, or nothing if the square is empty or off the board
+func (g *moveGenerator) wordFrom(sc, step SquareCoordinate) string {
+	if !g.board.OnBoard(sc) || !g.board.At(sc).Occupied() {
+		return ""
+	}
+	return g.board.WordAt(sc, step).Word
+}
+
+// length returns how many squares the word being built covers, if it ends
+// before the square
+func (g *moveGenerator) length(end SquareCoordinate) int {
+	return end.Row - g.start.Row + end.Col - g.start.Col
+}
+
+// record keeps the play built so far as a move. A single tile forming words
+// both along and across the line is found from both directions, so it is only
+// kept the first time, when playing across.
+func (g *moveGenerator) record() {
+	if len(g.placed) == 1 && g.step.Row == 1 {
+		across := SquareCoordinate{Row: 0, Col: 1}
+		if g.wordFrom(g.placed[0].Prev(across), across) != "" || g.wordFrom(g.placed[0].Next(across), across) != "" {
+			return
+		}
+	}
+
+	p := Play{
+		StartPos: g.placed[0],
+		EndPos:   g.placed[len(g.placed)-1],
+		Tiles:    append(Letters(nil), g.tiles...),
+	}
+	if len(g.blanks) > 0 {
+		p.Blanks = append(Letters(nil), g.blanks...)
+	}
+
+	laid, placed, formed, err := g.board.LayTiles(p, g.set)
+	if err != nil {
+		return
+	}
+	m := Move{Play: p, Score: laid.ScorePlay(placed, formed, g.bingo)}
+	for _, w := range formed {
+		m.Words = append(m.Words, w.Word)
+	}
+	g.moves = append(g.moves, m)
+}
+
+// follow returns the node reached by adding what the letter spells, which is
+// more than one rune for digraphs, and whether any word starts that way
+func follow(n dictionary.Node, l Letter) (dictionary.Node, bool) {
+	for _, r := range l.String() {
+		var ok bool
+		if n, ok = n.Next(r); !ok {
+			return n, false
+		}
+	}
+	return n, true
+}
@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+func TestMoves(t *testing.T) {
+	set := map[Letter]Tile{
+		' ': {Letter: ' ', Value: 0},
+		'A': {Letter: 'A', Value: 1},
+		'C': {Letter: 'C', Value: 3},
+		'S': {Letter: 'S', Value: 1},
+		'T': {Letter: 'T', Value: 1},
+	}
+	words := dictionary.NewDAWG([]string{"AA", "AS", "AT", "TA", "ACT", "CAT", "CATS", "SAT", "TAT", "SCAT", "TACT", "TACTS"})
+	board := LayoutBoard(5, map[string][]SquareCoordinate{
+		"doubleWord": {{Row: 0, Col: 0}},
+		"star":       {{Row: 2, Col: 2}},
+	})
+
+	moves := board.Moves(Letters("CAT"), words, set, BingoBonus)
+	if len(moves) == 0 {
+		t.Fatal("No moves found on an empty board")
+	}
+	for i, m := range moves {
+		if i > 0 && m.Score > moves[i-1].Score {
+			t.Errorf("Move %v scoring %v came after one scoring %v", m.Words, m.Score, moves[i-1].Score)
+		}
+	}
+
+	laid, _, _, err := board.LayTiles(Play{
+		StartPos: SquareCoordinate{Row: 2, Col: 1},
+		EndPos:   SquareCoordinate{Row: 2, Col: 3},
+		Tiles:    Letters("CAT"),
+	}, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// S can go before or after CAT, or below its A
+	moves = laid.Moves(Letters("S"), words, set, BingoBonus)
+	var expected []Move
+	for _, m := range []struct {
+		sc    SquareCoordinate
+		word  string
+		score int
+	}{
+		{SquareCoordinate{Row: 2, Col: 0}, "SCAT", 6},
+		{SquareCoordinate{Row: 2, Col: 4}, "CATS", 6},
+		{SquareCoordinate{Row: 3, Col: 2}, "AS", 2},
+	} {
+		expected = append(expected, Move{
+			Play:  Play{StartPos: m.sc, EndPos: m.sc, Tiles: Letters("S")},
+			Words: []string{m.word},
+			Score: m.score,
+		})
+	}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("Found %v, expected %v", moves, expected)
+	}
+
+	// Every legal play found by trying every placement of the rack's tiles is
+	// found, and nothing else
+	for _, b := range []Board{board, laid} {
+		rack := Letters("AST ")
+		found := make(map[string]int)
+		for _, m := range b.Moves(rack, words, set, BingoBonus) {
+			laid, placed, _, err := b.LayTiles(m.Play, set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := playKey(laid, placed)
+			if _, ok := found[key]; ok {
+				t.Errorf("Found %v twice", key)
+			}
+			found[key] = m.Score
+		}
+
+		legal := allPlays(b, rack, words, set)
+		if !reflect.DeepEqual(found, legal) {
+			t.Errorf("Found %v moves, expected %v", len(found), len(legal))
+			for key, score := range legal {
+				if found[key] != score {
+					t.Errorf("Play %v scoring %v found scoring %v", key, score, found[key])
+				}
+			}
+			for key := range found {
+				if _, ok := legal[key]; !ok {
+					t.Errorf("Play %v isn't legal", key)
+				}
+			}
+		}
+	}
+
+	// Digraphs spell more than one letter of a word
+	set[TileCH] = Tile{Letter: TileCH, Value: 5}
+	set['O'] = Tile{Letter: 'O', Value: 1}
+	moves = board.Moves(Letters{'O', TileCH, 'O'}, dictionary.NewDAWG([]string{"OCHO"}), set, BingoBonus)
+	if len(moves) != 6 || moves[0].Words[0] != "OCHO" {
+		t.Errorf("Found %v, expected OCHO six ways", moves)
+	}
+}
+
+// playKey identifies a play by the squares its tiles are placed on and the
+// letters they show, however its start and end are given
+func playKey(laid Board, placed []SquareCoordinate) string {
+	key := ""
+	for _, sc := range placed {
+		squ := laid.At(sc)
+		key += fmt.Sprintf("%v,%v:%v%v ", sc.Row, sc.Col, squ.Letter.String(), map[bool]string{true: "?"}[squ.Blank])
+	}
+	return key
+}
+
+// allPlays tries placing every arrangement of tiles from the rack between
+// every start and end on the board, returning the score of each legal play
+func allPlays(sb Board, rack Letters, words *dictionary.DAWG, set map[Letter]Tile) map[string]int {
+	legal := make(map[string]int)
+	try := func(p Play) {
+		laid, placed, formed, err := sb.LayTiles(p, set)
+		if err != nil || sb.CheckPlacement(placed, formed) != nil {
+			return
+		}
+		for _, w := range formed {
+			if !words.Valid(w.Word) {
+				return
+			}
+		}
+		legal[playKey(laid, placed)] = laid.ScorePlay(placed, formed, BingoBonus)
+	}
+
+	var arrange func(p Play, used []bool)
+	arrange = func(p Play, used []bool) {
+		try(p)
+		for i, l := range rack {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			q := p
+			q.Tiles = append(append(Letters(nil), p.Tiles...), l)
+			if l != ' ' {
+				arrange(q, used)
+			} else {
+				for b := Letter('A'); b <= 'Z'; b++ {
+					q.Blanks = append(append(Letters(nil), p.Blanks...), b)
+					arrange(q, used)
+				}
+			}
+			used[i] = false
+		}
+	}
+
+	for row := range sb {
+		for col := range sb[row] {
+			start := SquareCoordinate{Row: row, Col: col}
+			for _, step := range []SquareCoordinate{{Row: 0, Col: 1}, {Row: 1, Col: 0}} {
+				for end := start; sb.OnBoard(end); end = end.Next(step) {
+					arrange(Play{StartPos: start, EndPos: end}, make([]bool, len(rack)))
+				}
+			}
+		}
+	}
+	return legal
+}
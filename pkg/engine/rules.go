@@ -0,0 +1,175 @@
+// Package engine holds the rules of the game: the board and its premium
+// squares, letters and tiles, the bag they are drawn from, where tiles can be
+// placed and what they score, and how turns pass around the table until the
+// game ends. Games are played through the Game interface, with Control handing
+// them their moves one at a time. None of it knows how moves reach a game, so
+// it is shared by the server, bots and anything else that plays.
+package engine
+
+import (
+	"errors"
+)
+
+// BingoBonus is awarded for playing every tile in a full hand in one turn, in
+// variants other than Words With Friends
+const BingoBonus = 50
+
+// PlacementError is returned for a play whose tiles break the rules of where
+// they can be placed on the board, with a code clients can tell the rules
+// apart by
+type PlacementError struct {
+	Code    string
+	Message string
+}
+
+func (e *PlacementError) Error() string {
+	return e.Message
+}
+
+// Errors returned for plays that break each placement rule
+var (
+	ErrNotInLine = &PlacementError{Code: "not_in_line",
+		Message: "Tiles must be played left to right along a row or top to bottom along a column"}
+	ErrNotContiguous = &PlacementError{Code: "not_contiguous",
+		Message: "Not enough tiles to fill squares between start and end positions"}
+	ErrOffCenter = &PlacementError{Code: "off_center",
+		Message: "First play must cover the center square"}
+	ErrNotConnected = &PlacementError{Code: "not_connected",
+		Message: "Play must connect to tiles already on the board"}
+)
+
+// Play is the tiles a player places on the board in a turn, filling the empty
+// squares from the start position to the end position in order
+type Play struct {
+	StartPos SquareCoordinate
+	EndPos   SquareCoordinate
+	Tiles    Letters
+	Blanks   Letters // letters the blank tiles played stand for, in order
+}
+
+// LayTiles places the tiles of a play on the empty squares between its start
+// and end positions, valued from the set of tiles given. The board is returned
+// with the tiles on it, along with the squares they were placed on and the
+// words formed, leaving the original board unchanged.
+func (sb Board) LayTiles(p Play, set map[Letter]Tile) (Board, []SquareCoordinate, []FormedWord, error) {
+	step, err := sb.PlayDirection(p.StartPos, p.EndPos)
+	if err != nil {
+		return sb, nil, nil, err
+	}
+
+	sb = sb.Clone()
+
+	placed := make([]SquareCoordinate, 0, len(p.Tiles))
+	blanks := p.Blanks
+	for sc := p.StartPos; ; sc = sc.Next(step) {
+		if squ := sb.At(sc); !squ.Occupied() {
+			if len(placed) == len(p.Tiles) {
+				return sb, nil, nil, ErrNotContiguous
+			}
+
+			t, ok := set[p.Tiles[len(placed)]]
+			if !ok {
+				return sb, nil, nil, errors.New("Invalid tile '" + p.Tiles[len(placed)].String() + "'")
+			} else if t.Letter == ' ' {
+				// Blank tiles take the next designated letter but keep no value,
+				// and are marked so clients can show them differently
+				if len(blanks) == 0 {
+					return sb, nil, nil, errors.New("Blank tile played without a designated letter")
+				}
+				letter := blanks[0].ToUpper()
+
+				// Blanks can be any letter from A to Z, or any other letter
+				// among the game's tiles such as Ñ or CH
+				if _, ok := set[letter]; (letter < 'A' || letter > 'Z') && (!ok || letter == ' ') {
+					return sb, nil, nil, errors.New("Blank tile designated as invalid letter '" + blanks[0].String() + "'")
+				}
+				t.Letter, t.Blank, blanks = letter, true, blanks[1:]
+			}
+
+			squ.Tile = Tile{Letter: t.Letter, Value: t.Value, Blank: t.Blank}
+			placed = append(placed, sc)
+		}
+
+		if sc == p.EndPos {
+			break
+		}
+	}
+
+	if len(placed) == 0 {
+		return sb, nil, nil, errors.New("No tiles played")
+	} else if len(placed) < len(p.Tiles) {
+		return sb, nil, nil, errors.New("Too many tiles for squares between start and end positions")
+	} else if len(blanks) > 0 {
+		return sb, nil, nil, errors.New("More blank designations than blank tiles played")
+	}
+
+	words := sb.WordsFormed(placed, step)
+	if len(words) == 0 {
+		return sb, nil, nil, errors.New("Play must form a word of at least two letters")
+	}
+
+	return sb, placed, words, nil
+}
+
+// CheckPlacement makes sure tiles placed on the board, before they are laid on
+// it, follow the rules of where tiles can go. The first play must cover the
+// center square, and every later play must connect to tiles already on the
+// board, so a word it forms runs through one of them.
+func (sb Board) CheckPlacement(placed []SquareCoordinate, words []FormedWord) error {
+	newTiles := make(map[SquareCoordinate]bool, len(placed))
+	for _, sc := range placed {
+		newTiles[sc] = true
+	}
+
+	if sb.Empty() {
+		if !newTiles[SquareCoordinate{Row: len(sb) / 2, Col: len(sb) / 2}] {
+			return ErrOffCenter
+		}
+		return nil
+	}
+
+	for _, w := range words {
+		for _, sc := range w.Squares {
+			if !newTiles[sc] {
+				return nil
+			}
+		}
+	}
+	return ErrNotConnected
+}
+
+// ScorePlay totals every word formed by tiles placed on the board, with
+// premiums applied only to the new tiles, adding the bingo bonus if a full
+// hand was played
+func (sb Board) ScorePlay(placed []SquareCoordinate, words []FormedWord, bingo int) int {
+	newTiles := make(map[SquareCoordinate]bool, len(placed))
+	for _, sc := range placed {
+		newTiles[sc] = true
+	}
+
+	score := 0
+	for _, w := range words {
+		score += sb.ScoreWord(w, newTiles)
+	}
+	if len(placed) == MaxTiles {
+		score += bingo
+	}
+	return score
+}
+
+// PlayDirection determines the direction tiles are played in, which must be
+// along a single row or column from the start position to the end position
+func (sb Board) PlayDirection(start, end SquareCoordinate) (SquareCoordinate, error) {
+	if !sb.OnBoard(start) || !sb.OnBoard(end) {
+		return SquareCoordinate{}, errors.New("Start and end positions must be on the board")
+	}
+
+	switch {
+	case start.Row == end.Row && start.Col <= end.Col:
+		return SquareCoordinate{Row: 0, Col: 1}, nil
+	case start.Col == end.Col && start.Row < end.Row:
+		return SquareCoordinate{Row: 1, Col: 0}, nil
+	default:
+		return SquareCoordinate{}, ErrNotInLine
+	}
+}
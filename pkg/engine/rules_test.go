@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	set := map[Letter]Tile{
+		' ': {Letter: ' ', Value: 0},
+		'A': {Letter: 'A', Value: 1},
+		'C': {Letter: 'C', Value: 3},
+		'T': {Letter: 'T', Value: 1},
+	}
+	board := LayoutBoard(5, map[string][]SquareCoordinate{
+		"doubleWord": {{Row: 0, Col: 0}},
+		"star":       {{Row: 2, Col: 2}},
+	})
+
+	// The first play must cover the center square
+	laid, placed, words, err := board.LayTiles(Play{
+		StartPos: SquareCoordinate{Row: 0, Col: 0},
+		EndPos:   SquareCoordinate{Row: 0, Col: 2},
+		Tiles:    Letters("CAT"),
+	}, set)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = board.CheckPlacement(placed, words); err != ErrOffCenter {
+		t.Errorf("Play off center returned %v, expected %v", err, ErrOffCenter)
+	} else if score := laid.ScorePlay(placed, words, BingoBonus); score != 10 {
+		t.Errorf("CAT on a double word scored %v, expected 10", score)
+	}
+
+	laid, placed, words, err = board.LayTiles(Play{
+		StartPos: SquareCoordinate{Row: 2, Col: 1},
+		EndPos:   SquareCoordinate{Row: 2, Col: 3},
+		Tiles:    Letters("C T"),
+		Blanks:   Letters("a"),
+	}, set)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = board.CheckPlacement(placed, words); err != nil {
+		t.Error(err)
+	} else if words[0].Word != "CAT" || !laid.At(SquareCoordinate{Row: 2, Col: 2}).Blank {
+		t.Errorf("Play formed %v, expected CAT with a blank A", words)
+	} else if score := laid.ScorePlay(placed, words, BingoBonus); score != 4 {
+		t.Errorf("CAT with a blank scored %v, expected 4", score)
+	} else if board.At(SquareCoordinate{Row: 2, Col: 2}).Occupied() {
+		t.Error("Laying tiles changed the original board")
+	}
+
+	// Later plays must connect to the tiles already on the board
+	_, placed, words, err = laid.LayTiles(Play{
+		StartPos: SquareCoordinate{Row: 0, Col: 0},
+		EndPos:   SquareCoordinate{Row: 0, Col: 1},
+		Tiles:    Letters("AT"),
+	}, set)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = laid.CheckPlacement(placed, words); err != ErrNotConnected {
+		t.Errorf("Unconnected play returned %v, expected %v", err, ErrNotConnected)
+	}
+
+	if _, _, _, err = laid.LayTiles(Play{
+		StartPos: SquareCoordinate{Row: 3, Col: 3},
+		EndPos:   SquareCoordinate{Row: 1, Col: 1},
+		Tiles:    Letters("AT"),
+	}, set); err != ErrNotInLine {
+		t.Errorf("Play backwards returned %v, expected %v", err, ErrNotInLine)
+	}
+}
+
+func TestTiles(t *testing.T) {
+	bag := TileBag("CATS")
+	if drawn := bag.Draw(3); !reflect.DeepEqual(drawn, Letters("CAT")) || !reflect.DeepEqual(bag, TileBag("S")) {
+		t.Errorf("Drew %v leaving %v, expected CAT leaving S", drawn, bag)
+	} else if drawn = bag.Draw(7); !reflect.DeepEqual(drawn, Letters("S")) || len(bag) != 0 {
+		t.Errorf("Drew %v leaving %v, expected S leaving nothing", drawn, bag)
+	}
+
+	hand := Letters("CAAT")
+	if !HasTiles(hand, Letters("ACA")) || HasTiles(hand, Letters("TT")) {
+		t.Error("HasTiles miscounted duplicate letters")
+	}
+	if hand, err := RemoveTiles(hand, Letters("AT")); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(hand, Letters("CA")) {
+		t.Errorf("Removing AT left %v, expected CA", hand)
+	} else if _, err = RemoveTiles(hand, Letters("S")); err == nil {
+		t.Error("Removing a tile not in the hand should fail")
+	}
+}
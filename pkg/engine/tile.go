@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// MaxTiles is how many tiles a player holds in their hand
+const MaxTiles = 7
+
+// Tile represents a Scrabble tile that would be played on a board
+type Tile struct {
+	Letter Letter `json:"letter"`          // the letter written on the tile, a space for a blank
+	Count  int    `json:"-"`               // the number of tiles with the letter
+	Value  int    `json:"value"`           // the point value of playing the tile
+	Blank  bool   `json:"blank,omitempty"` // true if a blank tile was played as the letter
+}
+
+// TileBag represents the bag of undistributed tiles in a game
+type TileBag []Letter
+
+// UnmarshalJSON reads the bag as it reads Letters, including bags saved before
+// letters were runes
+func (tb *TileBag) UnmarshalJSON(data []byte) error {
+	return (*Letters)(tb).UnmarshalJSON(data)
+}
+
+// Shuffle make sure the tiles are in random order in the tile bag
+func (tb TileBag) Shuffle(r *rand.Rand) {
+	r.Shuffle(len(tb), func(i, j int) {
+		tb[i], tb[j] = tb[j], tb[i]
+	})
+}
+
+// Draw takes the given number of tiles from the bag, or as many as remain
+func (tb *TileBag) Draw(tileCount int) Letters {
+	if tileCount > len(*tb) {
+		tileCount = len(*tb)
+	}
+	var drawn TileBag
+	drawn, *tb = (*tb)[:tileCount], (*tb)[tileCount:]
+	return Letters(drawn)
+}
+
+// RemoveTiles takes the tiles out of the hand, failing if any of them isn't
+// in it. The hand is changed in place.
+func RemoveTiles(hand Letters, tiles Letters) (Letters, error) {
+	var tileFound bool
+	for _, t := range tiles {
+		tileFound = false
+		for i, pt := range hand {
+			// Check for matching tile in player's hand
+			if t == pt {
+				// Remove tile from player's hand
+				hand = append(hand[:i], hand[i+1:]...)
+				tileFound = true
+				break
+			}
+		}
+		if !tileFound {
+			return hand, errors.New("Tile '" + t.String() + "' not in player's hand")
+		}
+	}
+	return hand, nil
+}
+
+// HasTiles reports whether every tile played can be taken from the hand,
+// accounting for duplicate letters
+func HasTiles(hand, played Letters) bool {
+	counts := make(map[Letter]int, len(hand))
+	for _, t := range hand {
+		counts[t]++
+	}
+	for _, t := range played {
+		if counts[t] == 0 {
+			return false
+		}
+		counts[t]--
+	}
+	return true
+}
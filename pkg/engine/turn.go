@@ -0,0 +1,259 @@
+package engine
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+// Seat is a player's place in a game: the tiles they hold, their score and
+// whether they still take turns
+type Seat struct {
+	Tiles    Letters `json:"-"`                  // tiles currenty in possession
+	Score    int     `json:"score"`              // current score in the game
+	Skip     bool    `json:"-"`                  // true if the player loses their next turn
+	Resigned bool    `json:"resigned,omitempty"` // true if the player has conceded and no longer takes turns
+}
+
+// Table is what players take their turns at: the board, the bag and how many
+// turns have been taken. Seats are kept by whatever runs the game, and given
+// to the table in turn order.
+type Table struct {
+	TurnCount int     // counter that increments for each turn played
+	Board     Board   // board representation with current tiles
+	TileBag   TileBag // bag of tiles not yet distributed
+}
+
+// Rules are what a game is played with that decide how its turns go
+type Rules struct {
+	Tiles      map[Letter]Tile          // value of each tile
+	Bingo      int                      // bonus for playing every tile in a full hand in one turn
+	Validator  dictionary.WordValidator // dictionary for words played, nil accepts any word
+	Challenges bool                     // true if words are left for players to challenge instead of being checked when played
+}
+
+// Played is what a play did once it was made
+type Played struct {
+	Placed []SquareCoordinate // squares the tiles were placed on
+	Tiles  []Tile             // tiles placed on each of the squares
+	Words  []string           // words formed
+	Drawn  Letters            // tiles drawn to replace those played
+	Score  int                // points awarded for the play
+	Out    bool               // true if the player used the last of their tiles with the bag empty
+	Ended  bool               // true if going out ended the game, which waits for a challenge in games with them
+}
+
+// Current returns the number of the player whose turn it is
+func (t *Table) Current(seats []*Seat) int {
+	return t.TurnCount % len(seats)
+}
+
+// CheckTurn makes sure it is the player's turn
+func (t *Table) CheckTurn(seats []*Seat, player int) error {
+	if current := t.Current(seats); current != player {
+		return errors.New("Playing out of turn. Expected Player " + strconv.Itoa(current))
+	}
+	return nil
+}
+
+// CheckPlay places the tiles on a copy of the board, checks the words formed
+// against the rules' dictionary unless they are left to be challenged, and
+// scores the play, returning the words it forms
+func (t *Table) CheckPlay(seat *Seat, p Play, r Rules) ([]string, int, error) {
+	if len(p.Tiles) > MaxTiles {
+		return nil, 0, errors.New("Cannot play more than " + strconv.Itoa(MaxTiles) + " tiles")
+	} else if !HasTiles(seat.Tiles, p.Tiles) {
+		return nil, 0, errors.New("Tiles played are not all in player's hand")
+	}
+
+	board, placed, words, err := t.Board.LayTiles(p, r.Tiles)
+	if err != nil {
+		return nil, 0, err
+	} else if err = t.Board.CheckPlacement(placed, words); err != nil {
+		return nil, 0, err
+	}
+
+	formed := make([]string, len(words))
+	for i, w := range words {
+		formed[i] = w.Word
+	}
+
+	if r.Validator != nil && !r.Challenges {
+		var invalid []string
+		for _, w := range formed {
+			if !r.Validator.Valid(w) {
+				invalid = append(invalid, w)
+			}
+		}
+		if len(invalid) > 0 {
+			return nil, 0, errors.New("Words not in dictionary: " + strings.Join(invalid, ", "))
+		}
+	}
+
+	return formed, board.ScorePlay(placed, words, r.Bingo), nil
+}
+
+// CheckSwap makes sure the player can swap the tiles. Tiles can only be
+// swapped while the bag holds at least a full hand.
+func (t *Table) CheckSwap(seat *Seat, tiles Letters) error {
+	if len(tiles) == 0 {
+		return errors.New("No tiles chosen to swap")
+	} else if len(tiles) > MaxTiles {
+		return errors.New("Cannot swap more than " + strconv.Itoa(MaxTiles) + " tiles")
+	} else if len(t.TileBag) < MaxTiles {
+		return errors.New("Cannot swap with fewer than " + strconv.Itoa(MaxTiles) + " tiles left in the bag, " + strconv.Itoa(len(t.TileBag)) + " remain")
+	} else if !HasTiles(seat.Tiles, tiles) {
+		return errors.New("Tiles swapped are not all in player's hand")
+	}
+	return nil
+}
+
+// Play lays the player's tiles on the board, scores them and draws
+// replacements from the bag, which takes their turn. A player who uses the
+// last of their tiles once the bag is empty goes out, which ends the game
+// straight away unless the play can be challenged. Only the turn is checked,
+// so the play must have been checked with CheckPlay first.
+func (t *Table) Play(seats []*Seat, player int, p Play, r Rules) (Played, error) {
+	if err := t.CheckTurn(seats, player); err != nil {
+		return Played{}, err
+	}
+	seat := seats[player]
+
+	board, placed, words, err := t.Board.LayTiles(p, r.Tiles)
+	if err != nil {
+		return Played{}, err
+	}
+	if seat.Tiles, err = RemoveTiles(seat.Tiles, p.Tiles); err != nil {
+		return Played{}, err
+	}
+
+	played := Played{
+		Placed: placed,
+		Tiles:  make([]Tile, len(placed)),
+		Drawn:  t.TileBag.Draw(len(p.Tiles)),
+		Score:  board.ScorePlay(placed, words, r.Bingo),
+	}
+	for i, sc := range placed {
+		played.Tiles[i] = board.At(sc).Tile
+	}
+	for _, w := range words {
+		played.Words = append(played.Words, w.Word)
+	}
+
+	t.Board = board
+	seat.Tiles = append(seat.Tiles, played.Drawn...)
+	seat.Score += played.Score
+	t.Advance(seats)
+
+	played.Out = len(seat.Tiles) == 0 && len(t.TileBag) == 0
+	if played.Out && !r.Challenges {
+		GoOut(seats, seat, r)
+		played.Ended = true
+	}
+	return played, nil
+}
+
+// Swap exchanges the player's tiles for ones drawn from the bag, which takes
+// their turn. The bag is left as given, which is how it is once the tiles have
+// been drawn and the swapped tiles shuffled back in.
+func (t *Table) Swap(seats []*Seat, player int, tiles Letters, bag TileBag) error {
+	if err := t.CheckTurn(seats, player); err != nil {
+		return err
+	}
+	seat := seats[player]
+
+	var err error
+	if seat.Tiles, err = RemoveTiles(seat.Tiles, tiles); err != nil {
+		return err
+	}
+	seat.Tiles = append(seat.Tiles, t.TileBag.Draw(len(tiles))...)
+	t.TileBag = append(TileBag(nil), bag...)
+	t.Advance(seats)
+	return nil
+}
+
+// Pass gives up the player's turn, given how many passes have been made in a
+// row including it. Once every player still in the game has passed in a row
+// the game ends, with the value of each player's tiles taken off their score,
+// and Pass returns true.
+func (t *Table) Pass(seats []*Seat, player, passes int, r Rules) (bool, error) {
+	if err := t.CheckTurn(seats, player); err != nil {
+		return false, err
+	}
+	t.Advance(seats)
+	if passes < Active(seats) {
+		return false, nil
+	}
+	for _, s := range seats {
+		s.Score -= RackValue(s.Tiles, r)
+	}
+	return true, nil
+}
+
+// Advance passes play to the next player, skipping any who have lost their
+// turn or resigned
+func (t *Table) Advance(seats []*Seat) {
+	t.TurnCount++
+	for s := seats[t.Current(seats)]; s.Skip || s.Resigned; s = seats[t.Current(seats)] {
+		if Active(seats) == 0 {
+			return
+		}
+		s.Skip = false
+		t.TurnCount++
+	}
+}
+
+// Active counts the players who haven't resigned
+func Active(seats []*Seat) int {
+	n := 0
+	for _, s := range seats {
+		if !s.Resigned {
+			n++
+		}
+	}
+	return n
+}
+
+// RackValue totals the values of the tiles
+func RackValue(tiles Letters, r Rules) int {
+	total := 0
+	for _, l := range tiles {
+		total += r.Tiles[l].Value
+	}
+	return total
+}
+
+// GoOut scores the end of a game for the player who went out. The value of
+// each other player's tiles is taken off their score and added to that of the
+// player who went out.
+func GoOut(seats []*Seat, out *Seat, r Rules) {
+	for _, s := range seats {
+		if s == out {
+			continue
+		}
+		value := RackValue(s.Tiles, r)
+		s.Score -= value
+		out.Score += value
+	}
+}
+
+// Winners returns the numbers of the players with the highest score once the
+// game has ended, leaving out those who resigned and the loser, if there is
+// one, such as a player who ran out of time. Loser is -1 if there isn't.
+func Winners(seats []*Seat, loser int) []int {
+	var winners []int
+	best := 0
+	for i, s := range seats {
+		if i == loser || s.Resigned {
+			continue
+		}
+		if len(winners) == 0 || s.Score > best {
+			winners, best = []int{i}, s.Score
+		} else if s.Score == best {
+			winners = append(winners, i)
+		}
+	}
+	return winners
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	rules := Rules{Tiles: map[Letter]Tile{'A': {Letter: 'A', Value: 1}}, Bingo: BingoBonus}
+	board := LayoutBoard(9, map[string][]SquareCoordinate{"star": {{Row: 4, Col: 4}}})
+
+	// Passing in a row ends the game with each player's tiles taken off
+	// their score
+	table := Table{Board: board.Clone(), TileBag: TileBag("AAA")}
+	seats := []*Seat{{Tiles: Letters("AAAAAAA")}, {Tiles: Letters("AAA")}}
+	if _, err := table.Pass(seats, 1, 1, rules); err == nil {
+		t.Error("Passing out of turn succeeded")
+	} else if err = table.CheckSwap(seats[1], seats[1].Tiles); err == nil {
+		t.Error("Swapping with fewer than a full hand in the bag succeeded")
+	}
+	if over, err := table.Pass(seats, 0, 1, rules); err != nil || over {
+		t.Fatalf("First pass returned %v, %v, expected the game to go on", over, err)
+	} else if over, err = table.Pass(seats, 1, 2, rules); err != nil || !over {
+		t.Fatalf("Second pass returned %v, %v, expected the game to end", over, err)
+	} else if seats[0].Score != -7 || seats[1].Score != -3 {
+		t.Errorf("Scores after passing were %v and %v, expected -7 and -3", seats[0].Score, seats[1].Score)
+	}
+
+	// Playing the last tiles with the bag empty goes out, which wins the
+	// value of the other player's tiles
+	table = Table{Board: board.Clone()}
+	seats = []*Seat{{Tiles: Letters("AAAAAAA")}, {Tiles: Letters("A")}}
+	play := Play{
+		StartPos: SquareCoordinate{Row: 4, Col: 1},
+		EndPos:   SquareCoordinate{Row: 4, Col: 7},
+		Tiles:    Letters("AAAAAAA"),
+	}
+	if _, score, err := table.CheckPlay(seats[0], play, rules); err != nil {
+		t.Fatal(err)
+	} else if score != 57 {
+		t.Errorf("Play scored %v, expected 57", score)
+	}
+	played, err := table.Play(seats, 0, play, rules)
+	if err != nil {
+		t.Fatal(err)
+	} else if !played.Out || !played.Ended {
+		t.Fatalf("Play went out %v and ended the game %v, expected both", played.Out, played.Ended)
+	} else if seats[0].Score != 58 || seats[1].Score != -1 {
+		t.Errorf("Scores after going out were %v and %v, expected 58 and -1", seats[0].Score, seats[1].Score)
+	} else if winners := Winners(seats, -1); len(winners) != 1 || winners[0] != 0 {
+		t.Errorf("Winners were %v, expected [0]", winners)
+	} else if table.Current(seats) != 1 {
+		t.Errorf("Player %v to move after the play, expected 1", table.Current(seats))
+	}
+
+	// With challenges the game waits for the play to be accepted
+	table = Table{Board: board.Clone()}
+	seats = []*Seat{{Tiles: Letters("AAAAAAA")}, {Tiles: Letters("A")}}
+	challenges := rules
+	challenges.Challenges = true
+	if played, err = table.Play(seats, 0, play, challenges); err != nil {
+		t.Fatal(err)
+	} else if !played.Out || played.Ended || seats[1].Score != 0 {
+		t.Errorf("Play went out %v and ended the game %v, expected it to go out and wait", played.Out, played.Ended)
+	}
+}
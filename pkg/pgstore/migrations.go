@@ -0,0 +1,158 @@
+package pgstore
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// migrations are applied in order to bring the database schema up to date.
+// Each entry's version is its index plus one. Existing entries must never be
+// changed once released; add a new entry instead.
+var migrations = []string{
+	// 1: games, their players and the moves made in them
+	`CREATE TABLE games (
+		id UUID PRIMARY KEY,
+		active BOOLEAN NOT NULL,
+		turn_count INTEGER NOT NULL,
+		version BIGINT NOT NULL DEFAULT 1,
+		state JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+
+	CREATE TABLE players (
+		game_id UUID NOT NULL REFERENCES games (id) ON DELETE CASCADE,
+		id UUID NOT NULL,
+		name TEXT NOT NULL,
+		number INTEGER NOT NULL,
+		score INTEGER NOT NULL,
+		PRIMARY KEY (game_id, id)
+	);
+
+	CREATE TABLE moves (
+		game_id UUID NOT NULL REFERENCES games (id) ON DELETE CASCADE,
+		number INTEGER NOT NULL,
+		player INTEGER NOT NULL,
+		swap BOOLEAN NOT NULL,
+		words TEXT[] NOT NULL,
+		squares JSONB NOT NULL,
+		score INTEGER NOT NULL,
+		retracted BOOLEAN NOT NULL,
+		played_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (game_id, number)
+	);`,
+
+	// 2: players' ratings
+	`CREATE TABLE ratings (
+		player TEXT PRIMARY KEY,
+		rating INTEGER NOT NULL,
+		games INTEGER NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);`,
+
+	// 3: results of finished games, kept after the games are deleted
+	`CREATE TABLE results (
+		game_id UUID NOT NULL,
+		player TEXT NOT NULL,
+		score INTEGER NOT NULL,
+		won BOOLEAN NOT NULL,
+		bingos INTEGER NOT NULL,
+		finished_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (game_id, player)
+	);
+
+	CREATE INDEX results_finished_at ON results (finished_at);`,
+
+	// 4: registered player accounts
+	`CREATE TABLE accounts (
+		id UUID PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		password_hash BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	);`,
+
+	// 5: details of each player's moves in their results, for their statistics
+	`ALTER TABLE results
+		ADD COLUMN best_word TEXT NOT NULL DEFAULT '',
+		ADD COLUMN best_score INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN turns INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN turn_time BIGINT NOT NULL DEFAULT 0;
+
+	CREATE INDEX results_player ON results (player);`,
+
+	// 6: tournaments, stored whole since they are small and always read whole
+	`CREATE TABLE tournaments (
+		id UUID PRIMARY KEY,
+		state JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+
+	// 7: email addresses players can be sent reminders at
+	`ALTER TABLE accounts ADD COLUMN email TEXT NOT NULL DEFAULT ''`,
+
+	// 8: how each player's moves compared with the best plays available
+	`ALTER TABLE results
+		ADD COLUMN analyzed_moves INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN equity_lost DOUBLE PRECISION NOT NULL DEFAULT 0,
+		ADD COLUMN biggest_miss TEXT NOT NULL DEFAULT '',
+		ADD COLUMN biggest_miss_loss DOUBLE PRECISION NOT NULL DEFAULT 0,
+		ADD COLUMN missed_bingos INTEGER NOT NULL DEFAULT 0`,
+
+	// 9: ratings keyed by account rather than name, since only players with
+	// an account are rated, and the account each result belongs to
+	`UPDATE ratings SET player = accounts.id::text FROM accounts WHERE ratings.player = accounts.username;
+	DELETE FROM ratings WHERE player NOT IN (SELECT id::text FROM accounts);
+
+	ALTER TABLE results ADD COLUMN account UUID;`,
+
+	// 10: join codes of private games, so games can be found by code
+	`CREATE TABLE join_codes (
+		code TEXT PRIMARY KEY,
+		game_id UUID NOT NULL
+	);
+
+	INSERT INTO join_codes (code, game_id)
+	SELECT state->>'join_code', id FROM games WHERE state->>'join_code' <> ''
+	ON CONFLICT (code) DO NOTHING;`,
+}
+
+// Migrate applies any migrations that haven't yet been run against the
+// database. It is safe to call from several servers at once.
+func Migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create migrations table")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed to begin migration")
+	}
+	defer tx.Rollback()
+
+	// Stop other servers from migrating at the same time
+	if _, err = tx.Exec(`LOCK TABLE schema_migrations IN EXCLUSIVE MODE`); err != nil {
+		return errors.Wrap(err, "Failed to lock migrations table")
+	}
+
+	var current int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read schema version")
+	}
+
+	for i := current; i < len(migrations); i++ {
+		if _, err = tx.Exec(migrations[i]); err != nil {
+			return errors.Wrapf(err, "Failed to apply migration %v", i+1)
+		}
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			return errors.Wrapf(err, "Failed to record migration %v", i+1)
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "Failed to commit migrations")
+}
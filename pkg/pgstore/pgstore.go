@@ -0,0 +1,472 @@
+// Package pgstore provides a wordgameserver.GameStore that keeps games, their
+// players and their move history in PostgreSQL, so the server is durable
+// across deploys and finished games can be queried later
+package pgstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// cachedGame is a game loaded by this process along with the version it was
+// last saved or loaded at
+type cachedGame struct {
+	game    *wordgameserver.ScrabbleGame
+	version int64
+}
+
+// GameStore is a wordgameserver.GameStore backed by PostgreSQL. Games are
+// cached while they are in use, and reloaded whenever another server has saved
+// a newer version of them.
+type GameStore struct {
+	db        *sql.DB
+	validator dictionary.WordValidator
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedGame
+}
+
+// Open connects to the database described by the connection string, applies
+// any outstanding migrations, and creates a game store. Words played in games
+// loaded from the database are checked against the validator.
+func Open(dsn string, validator dictionary.WordValidator) (*GameStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open database")
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "Failed to connect to database")
+	}
+
+	if err = Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &GameStore{
+		db:        db,
+		validator: validator,
+		cache:     make(map[uuid.UUID]cachedGame),
+	}, nil
+}
+
+// Close stops every cached game and closes the database
+func (ps *GameStore) Close() error {
+	ps.mu.Lock()
+	for id, c := range ps.cache {
+		c.game.Stop()
+		delete(ps.cache, id)
+	}
+	ps.mu.Unlock()
+
+	return ps.db.Close()
+}
+
+// Get retrieves the game with the given ID. A cached copy is returned unless
+// a newer version has been saved to the database.
+func (ps *GameStore) Get(id uuid.UUID) (*wordgameserver.ScrabbleGame, error) {
+	var version int64
+	var state []byte
+
+	err := ps.db.QueryRow(`SELECT version, state FROM games WHERE id = $1`, id).Scan(&version, &state)
+	if err == sql.ErrNoRows {
+		ps.evict(id)
+		return nil, wordgameserver.ErrGameNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Failed to get game from database")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	c, ok := ps.cache[id]
+	if ok && c.version == version {
+		return c.game, nil
+	}
+
+	g, err := wordgameserver.DecodeGame(state, ps.validator)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached copy is stale, so its controller is no longer needed
+	if ok {
+		c.game.Stop()
+	}
+	ps.cache[id] = cachedGame{game: g, version: version}
+
+	return g, nil
+}
+
+// Put saves the game along with its players and move history
+func (ps *GameStore) Put(g *wordgameserver.ScrabbleGame) error {
+	state, err := wordgameserver.EncodeGame(g)
+	if err != nil {
+		return err
+	}
+
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed to begin saving game")
+	}
+	defer tx.Rollback()
+
+	var version int64
+	err = tx.QueryRow(`
+		INSERT INTO games (id, active, turn_count, state)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			active = EXCLUDED.active,
+			turn_count = EXCLUDED.turn_count,
+			state = EXCLUDED.state,
+			version = games.version + 1,
+			updated_at = now()
+		RETURNING version`,
+		g.ID, g.Active, g.TurnCount, state).Scan(&version)
+	if err != nil {
+		return errors.Wrap(err, "Failed to save game")
+	}
+
+	for _, p := range g.Players {
+		_, err = tx.Exec(`
+			INSERT INTO players (game_id, id, name, number, score)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (game_id, id) DO UPDATE SET score = EXCLUDED.score`,
+			g.ID, p.ID, p.Name, p.Number, p.Score)
+		if err != nil {
+			return errors.Wrap(err, "Failed to save player")
+		}
+	}
+
+	// Moves past the end of the history have been undone, and the moves
+	// replacing them are saved whole below
+	history := g.History()
+	_, err = tx.Exec(`DELETE FROM moves WHERE game_id = $1 AND number >= $2`, g.ID, len(history))
+	if err != nil {
+		return errors.Wrap(err, "Failed to remove undone moves")
+	}
+
+	for i, m := range history {
+		squares, err := json.Marshal(m.Squares)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode move")
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO moves (game_id, number, player, swap, words, squares, score, retracted, played_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (game_id, number) DO UPDATE SET
+				player = EXCLUDED.player,
+				swap = EXCLUDED.swap,
+				words = EXCLUDED.words,
+				squares = EXCLUDED.squares,
+				score = EXCLUDED.score,
+				retracted = EXCLUDED.retracted,
+				played_at = EXCLUDED.played_at`,
+			g.ID, i, m.Player, m.Swap, pq.Array(m.Words), squares, m.Score, m.Retracted, m.Time)
+		if err != nil {
+			return errors.Wrap(err, "Failed to save move")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "Failed to commit game")
+	}
+
+	ps.mu.Lock()
+	ps.cache[g.ID] = cachedGame{game: g, version: version}
+	ps.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes the game, its players and its moves from the database
+func (ps *GameStore) Delete(id uuid.UUID) error {
+	if _, err := ps.db.Exec(`DELETE FROM games WHERE id = $1`, id); err != nil {
+		return errors.Wrap(err, "Failed to delete game")
+	}
+	ps.evict(id)
+	return nil
+}
+
+// evict stops and removes a game from the cache
+func (ps *GameStore) evict(id uuid.UUID) {
+	ps.mu.Lock()
+	if c, ok := ps.cache[id]; ok {
+		c.game.Stop()
+		delete(ps.cache, id)
+	}
+	ps.mu.Unlock()
+}
+
+// List retrieves every game in the database
+func (ps *GameStore) List() ([]*wordgameserver.ScrabbleGame, error) {
+	rows, err := ps.db.Query(`SELECT id FROM games ORDER BY created_at`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list games")
+	}
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, errors.Wrap(err, "Failed to read game ID")
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to list games")
+	}
+
+	games := make([]*wordgameserver.ScrabbleGame, 0, len(ids))
+	for _, id := range ids {
+		g, err := ps.Get(id)
+		if err == wordgameserver.ErrGameNotFound {
+			// Deleted since the list was read
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+// ClaimJoinCode indexes the join code for the game with the ID, unless another
+// game has it
+func (ps *GameStore) ClaimJoinCode(code string, id uuid.UUID) (bool, error) {
+	res, err := ps.db.Exec(`INSERT INTO join_codes (code, game_id) VALUES ($1, $2) ON CONFLICT (code) DO NOTHING`, code, id)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to claim join code")
+	}
+	n, err := res.RowsAffected()
+	return n == 1, errors.Wrap(err, "Failed to claim join code")
+}
+
+// GameByJoinCode retrieves the ID of the game with the join code
+func (ps *GameStore) GameByJoinCode(code string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := ps.db.QueryRow(`SELECT game_id FROM join_codes WHERE code = $1`, code).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, wordgameserver.ErrGameNotFound
+	}
+	return id, errors.Wrap(err, "Failed to find game by join code")
+}
+
+// ReleaseJoinCode removes the join code from the index
+func (ps *GameStore) ReleaseJoinCode(code string) error {
+	_, err := ps.db.Exec(`DELETE FROM join_codes WHERE code = $1`, code)
+	return errors.Wrap(err, "Failed to release join code")
+}
+
+// History retrieves the moves made in a game from the database, including
+// games that are no longer active
+func (ps *GameStore) History(id uuid.UUID) ([]wordgameserver.Move, error) {
+	rows, err := ps.db.Query(`
+		SELECT player, swap, words, squares, score, retracted, played_at
+		FROM moves WHERE game_id = $1 ORDER BY number`, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get game history")
+	}
+	defer rows.Close()
+
+	var moves []wordgameserver.Move
+	for rows.Next() {
+		var m wordgameserver.Move
+		var squares []byte
+
+		err = rows.Scan(&m.Player, &m.Swap, pq.Array(&m.Words), &squares, &m.Score, &m.Retracted, &m.Time)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read move")
+		}
+		if err = json.Unmarshal(squares, &m.Squares); err != nil {
+			return nil, errors.Wrap(err, "Failed to decode move")
+		}
+		moves = append(moves, m)
+	}
+	return moves, errors.Wrap(rows.Err(), "Failed to get game history")
+}
+
+// GetRatings retrieves the ratings of the players who have one
+func (ps *GameStore) GetRatings(players []string) (map[string]wordgameserver.Rating, error) {
+	rows, err := ps.db.Query(`SELECT player, rating, games FROM ratings WHERE player = ANY($1)`, pq.Array(players))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get ratings")
+	}
+	defer rows.Close()
+
+	ratings := make(map[string]wordgameserver.Rating)
+	for rows.Next() {
+		var r wordgameserver.Rating
+		if err = rows.Scan(&r.Player, &r.Rating, &r.Games); err != nil {
+			return nil, errors.Wrap(err, "Failed to read rating")
+		}
+		ratings[r.Player] = r
+	}
+	return ratings, errors.Wrap(rows.Err(), "Failed to get ratings")
+}
+
+// PutRatings saves the ratings, replacing any for the same players
+func (ps *GameStore) PutRatings(ratings []wordgameserver.Rating) error {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed to begin saving ratings")
+	}
+	defer tx.Rollback()
+
+	for _, r := range ratings {
+		_, err = tx.Exec(`
+			INSERT INTO ratings (player, rating, games)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (player) DO UPDATE SET
+				rating = EXCLUDED.rating,
+				games = EXCLUDED.games,
+				updated_at = now()`,
+			r.Player, r.Rating, r.Games)
+		if err != nil {
+			return errors.Wrap(err, "Failed to save rating")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "Failed to commit ratings")
+}
+
+// PutResults saves the results of a game
+func (ps *GameStore) PutResults(results []wordgameserver.GameResult) error {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed to begin saving results")
+	}
+	defer tx.Rollback()
+
+	for _, r := range results {
+		_, err = tx.Exec(`
+			INSERT INTO results (game_id, player, account, score, won, bingos, best_word, best_score, turns, turn_time, finished_at,
+				analyzed_moves, equity_lost, biggest_miss, biggest_miss_loss, missed_bingos)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			ON CONFLICT (game_id, player) DO NOTHING`,
+			r.GameID, r.Player, r.Account, r.Score, r.Won, r.Bingos, r.BestWord, r.BestScore, r.Turns, int64(r.TurnTime), r.Finished,
+			r.AnalyzedMoves, r.EquityLost, r.BiggestMiss, r.BiggestMissLoss, r.MissedBingos)
+		if err != nil {
+			return errors.Wrap(err, "Failed to save result")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "Failed to commit results")
+}
+
+// GetResults retrieves the results of games finished since the time
+func (ps *GameStore) GetResults(since time.Time) ([]wordgameserver.GameResult, error) {
+	return ps.getResults(`WHERE finished_at >= $1`, since)
+}
+
+// GetPlayerResults retrieves the results of the player's games
+func (ps *GameStore) GetPlayerResults(player string) ([]wordgameserver.GameResult, error) {
+	return ps.getResults(`WHERE player = $1`, player)
+}
+
+// getResults retrieves the results matching the where clause, oldest first
+func (ps *GameStore) getResults(where string, arg interface{}) ([]wordgameserver.GameResult, error) {
+	rows, err := ps.db.Query(`
+		SELECT game_id, player, account, score, won, bingos, best_word, best_score, turns, turn_time, finished_at,
+			analyzed_moves, equity_lost, biggest_miss, biggest_miss_loss, missed_bingos
+		FROM results `+where+` ORDER BY finished_at`, arg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get results")
+	}
+	defer rows.Close()
+
+	var results []wordgameserver.GameResult
+	for rows.Next() {
+		var r wordgameserver.GameResult
+		var turnTime int64
+		err = rows.Scan(&r.GameID, &r.Player, &r.Account, &r.Score, &r.Won, &r.Bingos, &r.BestWord, &r.BestScore, &r.Turns, &turnTime, &r.Finished,
+			&r.AnalyzedMoves, &r.EquityLost, &r.BiggestMiss, &r.BiggestMissLoss, &r.MissedBingos)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read result")
+		}
+		r.TurnTime = time.Duration(turnTime)
+		results = append(results, r)
+	}
+	return results, errors.Wrap(rows.Err(), "Failed to get results")
+}
+
+// CreateAccount saves a new account, unless its username is taken
+func (ps *GameStore) CreateAccount(a wordgameserver.Account) error {
+	res, err := ps.db.Exec(`
+		INSERT INTO accounts (id, username, password_hash, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (username) DO NOTHING`,
+		a.ID, a.Username, a.PasswordHash, a.Email, a.Created)
+	if err != nil {
+		return errors.Wrap(err, "Failed to save account")
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "Failed to save account")
+	} else if n == 0 {
+		return wordgameserver.ErrUsernameTaken
+	}
+	return nil
+}
+
+// GetAccount retrieves the account with the ID
+func (ps *GameStore) GetAccount(id uuid.UUID) (wordgameserver.Account, error) {
+	return ps.getAccount(`SELECT id, username, password_hash, email, created_at FROM accounts WHERE id = $1`, id)
+}
+
+// GetAccountByName retrieves the account with the username
+func (ps *GameStore) GetAccountByName(username string) (wordgameserver.Account, error) {
+	return ps.getAccount(`SELECT id, username, password_hash, email, created_at FROM accounts WHERE username = $1`, username)
+}
+
+// getAccount retrieves the account selected by the query
+func (ps *GameStore) getAccount(query string, arg interface{}) (wordgameserver.Account, error) {
+	var a wordgameserver.Account
+	err := ps.db.QueryRow(query, arg).Scan(&a.ID, &a.Username, &a.PasswordHash, &a.Email, &a.Created)
+	if err == sql.ErrNoRows {
+		return a, wordgameserver.ErrAccountNotFound
+	}
+	return a, errors.Wrap(err, "Failed to get account")
+}
+
+// PutTournament adds or replaces the tournament
+func (ps *GameStore) PutTournament(t *wordgameserver.Tournament) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode tournament")
+	}
+	_, err = ps.db.Exec(`
+		INSERT INTO tournaments (id, state, updated_at) VALUES ($1, $2, now())
+		ON CONFLICT (id) DO UPDATE SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at`,
+		t.ID, data)
+	return errors.Wrap(err, "Failed to save tournament")
+}
+
+// GetTournament retrieves the tournament with the ID
+func (ps *GameStore) GetTournament(id uuid.UUID) (*wordgameserver.Tournament, error) {
+	var data []byte
+	err := ps.db.QueryRow(`SELECT state FROM tournaments WHERE id = $1`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, wordgameserver.ErrTournamentNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Failed to get tournament")
+	}
+
+	var t wordgameserver.Tournament
+	if err = json.Unmarshal(data, &t); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode tournament")
+	}
+	return &t, nil
+}
@@ -0,0 +1,291 @@
+package pgstore
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+)
+
+// openTestStore connects to the database given by WORDGAME_POSTGRES_DSN,
+// skipping the test if it isn't set
+func openTestStore(t *testing.T) *GameStore {
+	t.Helper()
+
+	dsn := os.Getenv("WORDGAME_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("WORDGAME_POSTGRES_DSN not set")
+	}
+
+	ps, err := Open(dsn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ps
+}
+
+// gameData returns a saved game in which the player has played CAT, followed
+// by a pass if pass is true
+func gameData(gameID, playerID uuid.UUID, pass bool) string {
+	now := `"time":"` + time.Now().UTC().Format(time.RFC3339) + `"`
+	player := `"player":"` + playerID.String() + `"`
+
+	events := `{"type":"game_created",` + now + `,"bag":"Q0FUWFhYWA=="},` +
+		`{"type":"player_joined",` + now + `,` + player + `,"name":"ashley1"},` +
+		`{"type":"game_started",` + now + `},` +
+		`{"type":"move_played",` + now + `,` + player + `,"start_pos":{"row":7,"col":6},"end_pos":{"row":7,"col":8},"tiles":"Q0FU"}`
+	if pass {
+		events += `,{"type":"turn_passed",` + now + `,` + player + `}`
+	}
+	return `{"id":"` + gameID.String() + `","events":[` + events + `]}`
+}
+
+func TestGameStore(t *testing.T) {
+	ps := openTestStore(t)
+	defer ps.Close()
+
+	// Migrations should be safe to run again
+	if err := Migrate(ps.db); err != nil {
+		t.Fatal(err)
+	}
+
+	gameID, playerID := uuid.New(), uuid.New()
+	data := gameData(gameID, playerID, false)
+
+	g, err := wordgameserver.DecodeGame([]byte(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ps.Put(g); err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Delete(g.ID)
+
+	if cached, err := ps.Get(g.ID); err != nil {
+		t.Fatal(err)
+	} else if cached != g {
+		t.Error("Expected cached game to be returned")
+	}
+
+	// A second store simulates another server or a restart
+	other := openTestStore(t)
+	defer other.Close()
+
+	loaded, err := other.Get(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if p, ok := loaded.Players[playerID]; !ok || p.Score != g.Players[playerID].Score || p.Score == 0 {
+		t.Error("Loaded game does not match saved game")
+	}
+
+	// Saving the game after another move should replace the cached copy
+	changed, err := wordgameserver.DecodeGame([]byte(gameData(gameID, playerID, true)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = other.Put(changed); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := ps.Get(g.ID); err != nil {
+		t.Fatal(err)
+	} else if reloaded == g || reloaded.TurnCount != 2 {
+		t.Error("Expected stale cached game to be reloaded")
+	}
+
+	moves, err := ps.History(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(moves) != 2 || moves[0].Words[0] != "CAT" || moves[0].Squares[0].Row != 7 {
+		t.Errorf("History does not match moves saved: %+v", moves)
+	}
+
+	// Moves that are no longer in the game's history, as when they have been
+	// undone, are removed
+	if err = other.Put(g); err != nil {
+		t.Fatal(err)
+	}
+	if moves, err = ps.History(g.ID); err != nil {
+		t.Fatal(err)
+	} else if len(moves) != 1 || moves[0].Words[0] != "CAT" {
+		t.Errorf("History after undoing a move is %+v, expected only CAT", moves)
+	}
+
+	if err = ps.Delete(g.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.Get(g.ID); err != wordgameserver.ErrGameNotFound {
+		t.Errorf("Expected ErrGameNotFound after delete, got %v", err)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	ps := openTestStore(t)
+	defer ps.Close()
+
+	player := "ashley-" + uuid.New().String()
+	defer ps.db.Exec(`DELETE FROM ratings WHERE player = $1`, player)
+
+	for _, want := range []wordgameserver.Rating{
+		{Player: player, Rating: 1516, Games: 1},
+		{Player: player, Rating: 1500, Games: 2},
+	} {
+		if err := ps.PutRatings([]wordgameserver.Rating{want}); err != nil {
+			t.Fatal(err)
+		}
+		ratings, err := ps.GetRatings([]string{player, "nobody-" + player})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(ratings) != 1 || ratings[player] != want {
+			t.Errorf("Got ratings %+v, expected only %+v", ratings, want)
+		}
+	}
+}
+
+func TestResults(t *testing.T) {
+	ps := openTestStore(t)
+	defer ps.Close()
+
+	gameID := uuid.New()
+	defer ps.db.Exec(`DELETE FROM results WHERE game_id = $1`, gameID)
+
+	finished := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	result := wordgameserver.GameResult{GameID: gameID, Player: "ashley1", Score: 300, Won: true, Bingos: 1,
+		BestWord: "RETAINS", BestScore: 80, Turns: 12, TurnTime: 3 * time.Minute, Finished: finished,
+		AnalyzedMoves: 12, EquityLost: 40.5, BiggestMiss: "QUIXOTIC", BiggestMissLoss: 20, MissedBingos: 1}
+
+	// Saving the same results twice shouldn't count them twice
+	for i := 0; i < 2; i++ {
+		if err := ps.PutResults([]wordgameserver.GameResult{result}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := ps.GetResults(finished)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(results) != 1 || results[0].GameID != gameID || !results[0].Finished.Equal(finished) || !results[0].Won {
+		t.Errorf("Got results %+v, expected only %+v", results, result)
+	}
+
+	results, err = ps.GetPlayerResults("ashley1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, r := range results {
+		if r.GameID == gameID {
+			found = true
+			if r.BestWord != result.BestWord || r.BestScore != result.BestScore || r.Turns != result.Turns || r.TurnTime != result.TurnTime ||
+				r.EquityLost != result.EquityLost || r.BiggestMiss != result.BiggestMiss || r.MissedBingos != result.MissedBingos {
+				t.Errorf("Got result %+v, expected %+v", r, result)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Results of ashley1 don't include game %v", gameID)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	ps := openTestStore(t)
+	defer ps.Close()
+
+	a := wordgameserver.Account{
+		ID:           uuid.New(),
+		Username:     "ashley_" + uuid.NewString()[:8],
+		PasswordHash: []byte("hash"),
+		Created:      time.Now().UTC().Truncate(time.Second),
+	}
+	defer ps.db.Exec(`DELETE FROM accounts WHERE id = $1`, a.ID)
+
+	if err := ps.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+	taken := a
+	taken.ID = uuid.New()
+	if err := ps.CreateAccount(taken); err != wordgameserver.ErrUsernameTaken {
+		t.Errorf("Creating an account with a taken username returned %v, expected %v", err, wordgameserver.ErrUsernameTaken)
+	}
+
+	byID, err := ps.GetAccount(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName, err := ps.GetAccountByName(a.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []wordgameserver.Account{byID, byName} {
+		if got.ID != a.ID || got.Username != a.Username || string(got.PasswordHash) != "hash" || !got.Created.Equal(a.Created) {
+			t.Errorf("Got account %+v, expected %+v", got, a)
+		}
+	}
+
+	if _, err = ps.GetAccount(uuid.New()); err != wordgameserver.ErrAccountNotFound {
+		t.Errorf("Getting a missing account returned %v, expected %v", err, wordgameserver.ErrAccountNotFound)
+	}
+}
+
+func TestTournaments(t *testing.T) {
+	ps := openTestStore(t)
+	defer ps.Close()
+
+	// Stores must be usable as tournament stores by servers
+	var _ wordgameserver.TournamentStore = ps
+
+	tour := &wordgameserver.Tournament{
+		ID:     uuid.New(),
+		Format: wordgameserver.TournamentElimination,
+		Status: wordgameserver.TournamentRegistering,
+	}
+	defer ps.db.Exec(`DELETE FROM tournaments WHERE id = $1`, tour.ID)
+
+	if err := ps.PutTournament(tour); err != nil {
+		t.Fatal(err)
+	}
+	tour.Status = wordgameserver.TournamentRunning
+	tour.Players = []wordgameserver.TournamentPlayer{{AccountID: uuid.New(), Name: "ashley", Seed: 1}}
+	if err := ps.PutTournament(tour); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ps.GetTournament(tour.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if got.Status != wordgameserver.TournamentRunning || len(got.Players) != 1 || got.Players[0] != tour.Players[0] {
+		t.Errorf("Got tournament %+v, expected %+v", got, tour)
+	}
+
+	if _, err = ps.GetTournament(uuid.New()); err != wordgameserver.ErrTournamentNotFound {
+		t.Errorf("Getting a missing tournament returned %v, expected %v", err, wordgameserver.ErrTournamentNotFound)
+	}
+}
+
+func TestJoinCodes(t *testing.T) {
+	ps := openTestStore(t)
+	defer ps.Close()
+	code := "T" + strings.ToUpper(uuid.NewString()[:5])
+	defer ps.db.Exec(`DELETE FROM join_codes WHERE code = $1`, code)
+
+	id := uuid.New()
+	if ok, err := ps.ClaimJoinCode(code, id); err != nil || !ok {
+		t.Fatalf("Claiming an unused code returned %v, %v", ok, err)
+	}
+	if ok, err := ps.ClaimJoinCode(code, uuid.New()); err != nil || ok {
+		t.Errorf("Claiming a code another game has returned %v, %v", ok, err)
+	}
+	if got, err := ps.GameByJoinCode(code); err != nil || got != id {
+		t.Errorf("Found game %v by code, expected %v", got, id)
+	}
+
+	if err := ps.ReleaseJoinCode(code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ps.GameByJoinCode(code); err != wordgameserver.ErrGameNotFound {
+		t.Errorf("Finding a released code returned %v, expected %v", err, wordgameserver.ErrGameNotFound)
+	}
+}
@@ -0,0 +1,141 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/pkg/errors"
+)
+
+// Hosts of the Apple Push Notification service
+const (
+	apnsProduction  = "https://api.push.apple.com"
+	apnsDevelopment = "https://api.sandbox.push.apple.com"
+)
+
+// apnsTokenTTL is how long each provider token is used for. APNs rejects
+// tokens more than an hour old, and ones replaced more often than every 20
+// minutes.
+const apnsTokenTTL = 50 * time.Minute
+
+// APNs sends notifications through the Apple Push Notification service, to
+// devices identified by their device tokens. Requests are authenticated with
+// a token signing key, rather than a certificate.
+type APNs struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string
+	host   string // URL of the APNs server
+	client *http.Client
+
+	mu     sync.Mutex // guards the provider token
+	token  string
+	issued time.Time
+}
+
+// NewAPNs creates a provider that signs its requests with the .p8 key given,
+// whose ID and team are shown in the Apple developer account it was created
+// in. The topic is the bundle ID of the app notifications are sent to. Apps
+// built for development receive notifications from the sandbox environment.
+func NewAPNs(key []byte, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	signer, err := parsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := signer.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("APNs key must be an ECDSA key")
+	}
+
+	host := apnsProduction
+	if sandbox {
+		host = apnsDevelopment
+	}
+	return &APNs{
+		key:    ecKey,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		host:   host,
+		client: defaultClient,
+	}, nil
+}
+
+// Push sends the notification to the device with the token as an alert, which
+// is grouped with the other notifications about its game
+func (a *APNs) Push(ctx context.Context, token string, n wordgameserver.Notification) error {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert":     map[string]string{"title": n.Title, "body": n.Body},
+			"sound":     "default",
+			"thread-id": n.GameID.String(),
+		},
+		"kind":    n.Kind,
+		"game_id": n.GameID.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("Apns-Topic", a.topic)
+	req.Header.Set("Apns-Push-Type", "alert")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reason)
+	switch {
+	case resp.StatusCode == http.StatusGone || reason.Reason == "BadDeviceToken" || reason.Reason == "Unregistered":
+		return wordgameserver.ErrDeviceUnregistered
+	case reason.Reason != "":
+		return errors.Errorf("APNs responded with status %v: %v", resp.Status, reason.Reason)
+	}
+	return errors.Errorf("APNs responded with status %v", resp.Status)
+}
+
+// providerToken returns the token requests are authenticated with, signing a
+// new one once it is due to be replaced
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.token != "" && now.Sub(a.issued) < apnsTokenTTL {
+		return a.token, nil
+	}
+	token, err := signJWT(a.key, a.keyID, map[string]interface{}{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	a.token, a.issued = token, now
+	return token, nil
+}
@@ -0,0 +1,75 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+)
+
+func TestAPNs(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAPNs(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "KEY123", "TEAM456", "com.example.wordgame", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "bearer ") || r.Header.Get("Apns-Topic") != "com.example.wordgame" {
+			t.Errorf("Request has headers %v, expected a provider token and the app's topic", r.Header)
+		}
+		var body struct {
+			APS struct {
+				Alert struct {
+					Title string `json:"title"`
+				} `json:"alert"`
+			} `json:"aps"`
+			Kind string `json:"kind"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		} else if body.APS.Alert.Title != "Your turn" || body.Kind != wordgameserver.NotifyYourTurn {
+			t.Errorf("Pushed %+v, expected an alert for the player's turn", body)
+		}
+
+		switch r.URL.Path {
+		case "/3/device/phone":
+		case "/3/device/uninstalled":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"TopicDisallowed"}`))
+		}
+	}))
+	defer ts.Close()
+	a.host, a.client = ts.URL, ts.Client()
+
+	n := wordgameserver.Notification{Kind: wordgameserver.NotifyYourTurn, GameID: uuid.New(), Title: "Your turn", Body: "It's your turn"}
+	if err = a.Push(context.Background(), "phone", n); err != nil {
+		t.Error(err)
+	}
+	if err = a.Push(context.Background(), "uninstalled", n); err != wordgameserver.ErrDeviceUnregistered {
+		t.Errorf("Push to an uninstalled app returned %v, expected %v", err, wordgameserver.ErrDeviceUnregistered)
+	}
+	if err = a.Push(context.Background(), "tablet", n); err == nil || !strings.Contains(err.Error(), "TopicDisallowed") {
+		t.Errorf("Rejected push returned %v, expected the reason given", err)
+	}
+}
@@ -0,0 +1,166 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/pkg/errors"
+)
+
+// fcmScope is the OAuth 2.0 scope access tokens for sending messages need
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// serviceAccount is the part of a Google service account key file needed to
+// get access tokens
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCM sends notifications through Firebase Cloud Messaging's HTTP v1 API, to
+// devices identified by their registration tokens
+type FCM struct {
+	account  serviceAccount
+	key      crypto.Signer
+	endpoint string // URL messages are posted to
+	client   *http.Client
+
+	mu          sync.Mutex // guards the access token
+	accessToken string
+	expires     time.Time
+}
+
+// NewFCM creates a provider that sends messages as the service account whose
+// JSON key file is given, to the Firebase project the account belongs to
+func NewFCM(credentials []byte) (*FCM, error) {
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, errors.Wrap(err, "Invalid service account key")
+	} else if account.ProjectID == "" || account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, errors.New("Service account key is missing its project, email or token URI")
+	}
+	key, err := parsePrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FCM{
+		account:  account,
+		key:      key,
+		endpoint: "https://fcm.googleapis.com/v1/projects/" + account.ProjectID + "/messages:send",
+		client:   defaultClient,
+	}, nil
+}
+
+// Push sends the notification to the device with the registration token
+func (f *FCM) Push(ctx context.Context, token string, n wordgameserver.Notification) error {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	type notification struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	type message struct {
+		Token        string            `json:"token"`
+		Notification notification      `json:"notification"`
+		Data         map[string]string `json:"data"`
+	}
+	body, err := json.Marshal(map[string]message{"message": {
+		Token:        token,
+		Notification: notification{Title: n.Title, Body: n.Body},
+		Data:         map[string]string{"kind": n.Kind, "game_id": n.GameID.String()},
+	}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// Tokens are unregistered once the app is uninstalled or the token
+		// expires
+		return wordgameserver.ErrDeviceUnregistered
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return responseError("FCM", resp)
+	}
+	return nil
+}
+
+// token returns an access token to send messages with, getting a new one from
+// Google's OAuth server once the last has nearly expired
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if f.accessToken != "" && now.Before(f.expires.Add(-time.Minute)) {
+		return f.accessToken, nil
+	}
+
+	assertion, err := signJWT(f.key, "", map[string]interface{}{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("Google OAuth", resp)
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", errors.Wrap(err, "Invalid access token response")
+	} else if t.AccessToken == "" {
+		return "", errors.New("Google OAuth did not return an access token")
+	}
+	f.accessToken = t.AccessToken
+	f.expires = now.Add(time.Duration(t.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
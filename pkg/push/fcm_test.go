@@ -0,0 +1,86 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+)
+
+func TestFCM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("assertion") == "" {
+			t.Error("Token request has no assertion")
+		}
+		tokens++
+		w.Write([]byte(`{"access_token":"secret","expires_in":3600}`))
+	})
+	mux.HandleFunc("/send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Message sent with authorization %q", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Message struct {
+				Token string            `json:"token"`
+				Data  map[string]string `json:"data"`
+			} `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		} else if body.Message.Data["kind"] != wordgameserver.NotifyGameFinished {
+			t.Errorf("Sent %+v, expected the kind of notification in its data", body.Message)
+		}
+		if body.Message.Token != "phone" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	credentials, err := json.Marshal(serviceAccount{
+		ProjectID:   "wordgame",
+		ClientEmail: "push@wordgame.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    ts.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFCM(credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.endpoint = ts.URL + "/send"
+
+	n := wordgameserver.Notification{Kind: wordgameserver.NotifyGameFinished, GameID: uuid.New(), Title: "Game over", Body: "Your game has finished"}
+	for i := 0; i < 2; i++ {
+		if err = f.Push(context.Background(), "phone", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("Got %v access tokens, expected the first to be reused", tokens)
+	}
+	if err = f.Push(context.Background(), "uninstalled", n); err != wordgameserver.ErrDeviceUnregistered {
+		t.Errorf("Push to an uninstalled app returned %v, expected %v", err, wordgameserver.ErrDeviceUnregistered)
+	}
+}
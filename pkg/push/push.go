@@ -0,0 +1,105 @@
+// Package push provides wordgameserver.PushProviders that deliver players'
+// notifications through Firebase Cloud Messaging, the Apple Push Notification
+// service and the Web Push protocol
+package push
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// requestTimeout is how long a push service has to respond to each request,
+// if the context given doesn't end sooner
+const requestTimeout = 30 * time.Second
+
+var defaultClient = &http.Client{Timeout: requestTimeout}
+
+// parsePrivateKey reads the PKCS #8 private key from PEM data, as service
+// account and APNs key files hold them
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("No PEM encoded private key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid private key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("Unsupported private key type")
+	}
+	return signer, nil
+}
+
+// signJWT returns a JSON Web Token with the claims, signed with the key. RSA
+// keys sign with RS256 and P-256 keys with ES256, with the key ID given in the
+// header unless it is empty.
+func signJWT(key crypto.Signer, keyID string, claims interface{}) (string, error) {
+	header := map[string]string{"typ": "JWT"}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		header["alg"] = "RS256"
+	case *ecdsa.PrivateKey:
+		header["alg"] = "ES256"
+	default:
+		return "", errors.New("Unsupported private key type")
+	}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+
+	var parts []string
+	for _, v := range []interface{}{header, claims} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, base64.RawURLEncoding.EncodeToString(data))
+	}
+	digest := sha256.Sum256([]byte(strings.Join(parts, ".")))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		// JWTs hold the two halves of an ECDSA signature side by side, rather
+		// than ASN.1 encoded
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", err
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return strings.Join(parts, ".") + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// responseError returns an error describing a push service's response, which
+// includes as much of its body as fits on a line
+func responseError(service string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := strings.Join(strings.Fields(string(body)), " ")
+	if msg == "" {
+		return errors.Errorf("%v responded with status %v", service, resp.Status)
+	}
+	return errors.Errorf("%v responded with status %v: %v", service, resp.Status, msg)
+}
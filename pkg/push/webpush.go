@@ -0,0 +1,224 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// webPushTTL is how long push services keep a notification for a browser that
+// isn't connected, since a turn can wait that long
+const webPushTTL = 24 * time.Hour
+
+// webPushRecordSize is the record size given in the header of encrypted
+// payloads, which every notification fits in one record of
+const webPushRecordSize = 4096
+
+// WebPush sends notifications to browsers with the Web Push protocol. Devices
+// are identified by the JSON push subscription the browser gives the app,
+// whose payloads are encrypted as RFC 8291 describes. Requests are
+// authenticated to push services with a VAPID key pair, whose public key the
+// app subscribes with.
+type WebPush struct {
+	key       *ecdsa.PrivateKey
+	publicKey []byte // uncompressed P-256 point of the VAPID key
+	subject   string
+	client    *http.Client
+}
+
+// subscription is a browser's push subscription, as given by
+// PushSubscription.toJSON
+type subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// NewWebPush creates a provider that authenticates with the VAPID private key,
+// given as the base64url encoded P-256 scalar that web push libraries generate.
+// The subject is a mailto: or https: URL push services can contact the
+// server's operator at.
+func NewWebPush(privateKey, subject string) (*WebPush, error) {
+	d, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid VAPID private key")
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid VAPID private key")
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, errors.New("VAPID subject must be a mailto: or https: URL")
+	}
+
+	public := ecdhKey.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &WebPush{key: key, publicKey: public, subject: subject, client: defaultClient}, nil
+}
+
+// PublicKey returns the base64url encoded VAPID public key, which apps give
+// browsers as the applicationServerKey when subscribing
+func (wp *WebPush) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(wp.publicKey)
+}
+
+// Push sends the notification, encoded as JSON, to the browser whose push
+// subscription is the token
+func (wp *WebPush) Push(ctx context.Context, token string, n wordgameserver.Notification) error {
+	var sub subscription
+	if err := json.Unmarshal([]byte(token), &sub); err != nil {
+		return errors.Wrap(err, "Invalid push subscription")
+	}
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return errors.New("Invalid push subscription endpoint")
+	}
+	uaPublic, err := decodeBase64URL(sub.Keys.P256dh)
+	if err != nil {
+		return errors.Wrap(err, "Invalid push subscription key")
+	}
+	authSecret, err := decodeBase64URL(sub.Keys.Auth)
+	if err != nil {
+		return errors.Wrap(err, "Invalid push subscription secret")
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	body, err := encryptWebPush(payload, uaPublic, authSecret)
+	if err != nil {
+		return err
+	}
+
+	vapid, err := signJWT(wp.key, "", map[string]interface{}{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": wp.subject,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(webPushTTL.Seconds())))
+	req.Header.Set("Authorization", "vapid t="+vapid+", k="+wp.PublicKey())
+
+	resp, err := wp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// The subscription has expired or the user has unsubscribed
+		return wordgameserver.ErrDeviceUnregistered
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return responseError("Push service", resp)
+	}
+	return nil
+}
+
+// encryptWebPush encrypts the payload for the browser with the public key and
+// authentication secret of its subscription, with the aes128gcm content
+// encoding as RFC 8291 describes. The payload is sent as a single record.
+func encryptWebPush(payload, uaPublic, authSecret []byte) ([]byte, error) {
+	curve := ecdh.P256()
+	ua, err := curve.NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid push subscription key")
+	}
+	as, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := as.ECDH(ua)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := as.PublicKey().Bytes()
+
+	// Combine the shared secret with the subscription's authentication secret
+	info := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, authSecret, info), ikm); err != nil {
+		return nil, err
+	}
+
+	// Derive the content encryption key and nonce from a random salt
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek := make([]byte, 16)
+	if _, err = io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err = io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload)+1+gcm.Overhead() > webPushRecordSize {
+		return nil, errors.New("Notification is too large to push")
+	}
+
+	// The header gives the salt, record size and sender's public key, and the
+	// record is padded with the delimiter marking it as the last
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, append(payload, 2), nil), nil
+}
+
+// decodeBase64URL decodes base64url data with or without padding, since
+// browsers and libraries differ in which they give
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,132 @@
+package push
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/hkdf"
+)
+
+// decryptWebPush decrypts a payload as a browser would, with the private key
+// and authentication secret of its subscription
+func decryptWebPush(t *testing.T, body []byte, ua *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+
+	salt, rs, idLen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	if rs != webPushRecordSize || idLen != 65 {
+		t.Fatalf("Header has record size %v and key length %v", rs, idLen)
+	}
+	as, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := ua.ECDH(as)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := append(append([]byte("WebPush: info\x00"), ua.PublicKey().Bytes()...), as.Bytes()...)
+	ikm := make([]byte, 32)
+	io.ReadFull(hkdf.New(sha256.New, secret, authSecret, info), ikm)
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek, nonce := make([]byte, 16), make([]byte, 12)
+	io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: aes128gcm\x00")), cek)
+	io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: nonce\x00")), nonce)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if record[len(record)-1] != 2 {
+		t.Fatal("Record is not marked as the last")
+	}
+	return record[:len(record)-1]
+}
+
+func TestWebPush(t *testing.T) {
+	vapid, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wp, err := NewWebPush(base64.RawURLEncoding.EncodeToString(vapid.Bytes()), "mailto:ashley@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewWebPush(base64.RawURLEncoding.EncodeToString(vapid.Bytes()), "ashley@example.com"); err == nil {
+		t.Error("Subject that isn't a URL was accepted")
+	}
+
+	ua, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+
+	status := http.StatusCreated
+	received := make(chan wordgameserver.Notification, 1)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "vapid t=") || !strings.HasSuffix(auth, ", k="+wp.PublicKey()) {
+			t.Errorf("Request has authorization %q, expected the VAPID key", auth)
+		}
+		if r.Header.Get("Content-Encoding") != "aes128gcm" {
+			t.Errorf("Payload has encoding %q", r.Header.Get("Content-Encoding"))
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n wordgameserver.Notification
+		if err = json.Unmarshal(decryptWebPush(t, body, ua, authSecret), &n); err != nil {
+			t.Error(err)
+		}
+		received <- n
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	wp.client = ts.Client()
+
+	var sub subscription
+	sub.Endpoint = ts.URL + "/push/browser"
+	sub.Keys.P256dh = base64.URLEncoding.EncodeToString(ua.PublicKey().Bytes())
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString(authSecret)
+	token, err := json.Marshal(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := wordgameserver.Notification{Kind: wordgameserver.NotifyYourTurn, GameID: uuid.New(), Title: "Your turn", Body: "It's your turn"}
+	if err = wp.Push(context.Background(), string(token), n); err != nil {
+		t.Fatal(err)
+	} else if got := <-received; got != n {
+		t.Errorf("Browser received %+v, expected %+v", got, n)
+	}
+
+	status = http.StatusGone
+	if err = wp.Push(context.Background(), string(token), n); err != wordgameserver.ErrDeviceUnregistered {
+		t.Errorf("Push to an expired subscription returned %v, expected %v", err, wordgameserver.ErrDeviceUnregistered)
+	}
+	<-received
+}
@@ -0,0 +1,444 @@
+// Package redisstore provides a wordgameserver.GameStore that keeps games in
+// Redis, so they survive restarts and can be shared by several servers
+package redisstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/go-redis/redis/v7"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Keys used when none are configured. Game keys are formed by prepending the
+// prefix to their IDs, and join code keys by prepending theirs to the codes.
+const (
+	defaultKeyPrefix      = "wordgame:game:"
+	defaultJoinCodePrefix = "wordgame:joincode:"
+	defaultRatingsKey     = "wordgame:ratings"
+	defaultResultsKey     = "wordgame:results"
+	defaultAccountsKey    = "wordgame:accounts"
+	defaultTournamentsKey = "wordgame:tournaments"
+)
+
+// Options configures the connection to Redis and how games are stored
+type Options struct {
+	Addr           string                   // host:port of the Redis server
+	Password       string                   // optional password for the Redis server
+	DB             int                      // database to select after connecting
+	PoolSize       int                      // maximum connections in the pool, 0 uses the client default
+	KeyPrefix      string                   // prefix for game keys, defaults to "wordgame:game:"
+	JoinCodePrefix string                   // prefix for keys holding the game with each join code, defaults to "wordgame:joincode:"
+	RatingsKey     string                   // key of the hash holding players' ratings, defaults to "wordgame:ratings"
+	ResultsKey     string                   // key of the sorted set holding games' results, defaults to "wordgame:results"
+	AccountsKey    string                   // key of the hash holding accounts, defaults to "wordgame:accounts"
+	TournamentsKey string                   // key of the hash holding tournaments, defaults to "wordgame:tournaments"
+	TTL            time.Duration            // expiry refreshed each time a game is saved, 0 never expires
+	Validator      dictionary.WordValidator // dictionary attached to games loaded from Redis
+}
+
+// cachedGame is a game loaded by this process along with the encoding it was
+// last saved or loaded with
+type cachedGame struct {
+	game *wordgameserver.ScrabbleGame
+	data []byte
+}
+
+// GameStore is a wordgameserver.GameStore backed by Redis. Games are cached
+// while they are in use, and reloaded whenever another server has saved newer
+// state for them.
+type GameStore struct {
+	client         *redis.Client
+	keyPrefix      string
+	joinCodePrefix string
+	ratingsKey     string
+	resultsKey     string
+	accountsKey    string
+	tournamentsKey string
+	ttl            time.Duration
+	validator      dictionary.WordValidator
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedGame
+}
+
+// New connects to Redis and creates a game store using the connection pool
+func New(opts Options) (*GameStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+		PoolSize: opts.PoolSize,
+	})
+
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, errors.Wrap(err, "Failed to connect to Redis")
+	}
+
+	rs := GameStore{
+		client:         client,
+		keyPrefix:      opts.KeyPrefix,
+		joinCodePrefix: opts.JoinCodePrefix,
+		ratingsKey:     opts.RatingsKey,
+		resultsKey:     opts.ResultsKey,
+		accountsKey:    opts.AccountsKey,
+		tournamentsKey: opts.TournamentsKey,
+		ttl:            opts.TTL,
+		validator:      opts.Validator,
+		cache:          make(map[uuid.UUID]cachedGame),
+	}
+	if rs.keyPrefix == "" {
+		rs.keyPrefix = defaultKeyPrefix
+	}
+	if rs.joinCodePrefix == "" {
+		rs.joinCodePrefix = defaultJoinCodePrefix
+	}
+	if rs.ratingsKey == "" {
+		rs.ratingsKey = defaultRatingsKey
+	}
+	if rs.resultsKey == "" {
+		rs.resultsKey = defaultResultsKey
+	}
+	if rs.accountsKey == "" {
+		rs.accountsKey = defaultAccountsKey
+	}
+	if rs.tournamentsKey == "" {
+		rs.tournamentsKey = defaultTournamentsKey
+	}
+
+	return &rs, nil
+}
+
+// Close stops every cached game and closes the connection pool
+func (rs *GameStore) Close() error {
+	rs.mu.Lock()
+	for id, c := range rs.cache {
+		c.game.Stop()
+		delete(rs.cache, id)
+	}
+	rs.mu.Unlock()
+
+	return rs.client.Close()
+}
+
+func (rs *GameStore) key(id uuid.UUID) string {
+	return rs.keyPrefix + id.String()
+}
+
+// Get retrieves the game with the given ID. A cached copy is returned unless
+// the game has changed in Redis since it was cached.
+func (rs *GameStore) Get(id uuid.UUID) (*wordgameserver.ScrabbleGame, error) {
+	data, err := rs.client.Get(rs.key(id)).Bytes()
+	if err == redis.Nil {
+		rs.evict(id)
+		return nil, wordgameserver.ErrGameNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Failed to get game from Redis")
+	}
+
+	return rs.load(id, data)
+}
+
+// load returns the cached game if it matches the data from Redis, otherwise it
+// decodes the data and replaces the cached game
+func (rs *GameStore) load(id uuid.UUID, data []byte) (*wordgameserver.ScrabbleGame, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	c, ok := rs.cache[id]
+	if ok && bytes.Equal(c.data, data) {
+		return c.game, nil
+	}
+
+	g, err := wordgameserver.DecodeGame(data, rs.validator)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached copy is stale, so its controller is no longer needed
+	if ok {
+		c.game.Stop()
+	}
+	rs.cache[id] = cachedGame{game: g, data: data}
+
+	return g, nil
+}
+
+// Put saves the game to Redis and refreshes its expiry, and that of its join
+// code if it has one
+func (rs *GameStore) Put(g *wordgameserver.ScrabbleGame) error {
+	data, err := wordgameserver.EncodeGame(g)
+	if err != nil {
+		return err
+	}
+
+	if err = rs.client.Set(rs.key(g.ID), data, rs.ttl).Err(); err != nil {
+		return errors.Wrap(err, "Failed to save game to Redis")
+	}
+	if g.JoinCode != "" && rs.ttl > 0 {
+		if err = rs.client.Expire(rs.joinCodePrefix+g.JoinCode, rs.ttl).Err(); err != nil {
+			return errors.Wrap(err, "Failed to refresh join code in Redis")
+		}
+	}
+
+	rs.mu.Lock()
+	rs.cache[g.ID] = cachedGame{game: g, data: data}
+	rs.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes the game from Redis and stops it if it is cached
+func (rs *GameStore) Delete(id uuid.UUID) error {
+	if err := rs.client.Del(rs.key(id)).Err(); err != nil {
+		return errors.Wrap(err, "Failed to delete game from Redis")
+	}
+	rs.evict(id)
+	return nil
+}
+
+// evict stops and removes a game from the cache
+func (rs *GameStore) evict(id uuid.UUID) {
+	rs.mu.Lock()
+	if c, ok := rs.cache[id]; ok {
+		c.game.Stop()
+		delete(rs.cache, id)
+	}
+	rs.mu.Unlock()
+}
+
+// List retrieves every game stored in Redis
+func (rs *GameStore) List() ([]*wordgameserver.ScrabbleGame, error) {
+	var games []*wordgameserver.ScrabbleGame
+
+	iter := rs.client.Scan(0, rs.keyPrefix+"*", 100).Iterator()
+	for iter.Next() {
+		id, err := uuid.Parse(iter.Val()[len(rs.keyPrefix):])
+		if err != nil {
+			continue
+		}
+
+		g, err := rs.Get(id)
+		if err == wordgameserver.ErrGameNotFound {
+			// Expired or deleted since the scan
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to list games in Redis")
+	}
+
+	return games, nil
+}
+
+// ClaimJoinCode indexes the join code for the game with the ID, unless another
+// game has it. The code expires along with the game.
+func (rs *GameStore) ClaimJoinCode(code string, id uuid.UUID) (bool, error) {
+	ok, err := rs.client.SetNX(rs.joinCodePrefix+code, id.String(), rs.ttl).Result()
+	return ok, errors.Wrap(err, "Failed to claim join code in Redis")
+}
+
+// GameByJoinCode retrieves the ID of the game with the join code
+func (rs *GameStore) GameByJoinCode(code string) (uuid.UUID, error) {
+	val, err := rs.client.Get(rs.joinCodePrefix + code).Result()
+	if err == redis.Nil {
+		return uuid.Nil, wordgameserver.ErrGameNotFound
+	} else if err != nil {
+		return uuid.Nil, errors.Wrap(err, "Failed to find game by join code in Redis")
+	}
+	id, err := uuid.Parse(val)
+	return id, errors.Wrap(err, "Failed to decode game ID")
+}
+
+// ReleaseJoinCode removes the join code from the index
+func (rs *GameStore) ReleaseJoinCode(code string) error {
+	return errors.Wrap(rs.client.Del(rs.joinCodePrefix+code).Err(), "Failed to release join code in Redis")
+}
+
+// GetRatings retrieves the ratings of the players who have one. Ratings never
+// expire.
+func (rs *GameStore) GetRatings(players []string) (map[string]wordgameserver.Rating, error) {
+	ratings := make(map[string]wordgameserver.Rating)
+	if len(players) == 0 {
+		return ratings, nil
+	}
+
+	values, err := rs.client.HMGet(rs.ratingsKey, players...).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get ratings from Redis")
+	}
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			// The player has no rating
+			continue
+		}
+		var r wordgameserver.Rating
+		if err = json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, errors.Wrap(err, "Failed to decode rating")
+		}
+		ratings[r.Player] = r
+	}
+	return ratings, nil
+}
+
+// PutRatings saves the ratings, replacing any for the same players
+func (rs *GameStore) PutRatings(ratings []wordgameserver.Rating) error {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(ratings))
+	for _, r := range ratings {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode rating")
+		}
+		fields[r.Player] = data
+	}
+	return errors.Wrap(rs.client.HSet(rs.ratingsKey, fields).Err(), "Failed to save ratings to Redis")
+}
+
+// PutResults saves the results of a game. Results are scored by when the game
+// finished, so they can be read back from a point in time, and never expire.
+// Each player's results are also kept in a sorted set of their own, at the
+// results key plus ":player:" and their name.
+func (rs *GameStore) PutResults(results []wordgameserver.GameResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	_, err := rs.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		for _, r := range results {
+			data, err := json.Marshal(r)
+			if err != nil {
+				return errors.Wrap(err, "Failed to encode result")
+			}
+			member := &redis.Z{Score: float64(r.Finished.Unix()), Member: data}
+			pipe.ZAdd(rs.resultsKey, member)
+			pipe.ZAdd(rs.playerResultsKey(r.Player), member)
+		}
+		return nil
+	})
+	return errors.Wrap(err, "Failed to save results to Redis")
+}
+
+// GetResults retrieves the results of games finished since the time
+func (rs *GameStore) GetResults(since time.Time) ([]wordgameserver.GameResult, error) {
+	min := "-inf"
+	if !since.IsZero() {
+		min = strconv.FormatInt(since.Unix(), 10)
+	}
+	return rs.getResults(rs.resultsKey, min)
+}
+
+// GetPlayerResults retrieves the results of the player's games
+func (rs *GameStore) GetPlayerResults(player string) ([]wordgameserver.GameResult, error) {
+	return rs.getResults(rs.playerResultsKey(player), "-inf")
+}
+
+// playerResultsKey returns the key of the sorted set holding the player's
+// results
+func (rs *GameStore) playerResultsKey(player string) string {
+	return rs.resultsKey + ":player:" + player
+}
+
+// getResults retrieves the results in the sorted set at the key from the
+// minimum score onwards
+func (rs *GameStore) getResults(key, min string) ([]wordgameserver.GameResult, error) {
+	values, err := rs.client.ZRangeByScore(key, &redis.ZRangeBy{Min: min, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get results from Redis")
+	}
+
+	results := make([]wordgameserver.GameResult, len(values))
+	for i, v := range values {
+		if err = json.Unmarshal([]byte(v), &results[i]); err != nil {
+			return nil, errors.Wrap(err, "Failed to decode result")
+		}
+	}
+	return results, nil
+}
+
+// CreateAccount saves a new account, unless its username is taken. Usernames
+// are claimed first, in a hash of account IDs at the accounts key plus
+// ":usernames", so two servers can't register the same one. Accounts never
+// expire.
+func (rs *GameStore) CreateAccount(a wordgameserver.Account) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode account")
+	}
+
+	claimed, err := rs.client.HSetNX(rs.accountsKey+":usernames", a.Username, a.ID.String()).Result()
+	if err != nil {
+		return errors.Wrap(err, "Failed to save account to Redis")
+	} else if !claimed {
+		return wordgameserver.ErrUsernameTaken
+	}
+	return errors.Wrap(rs.client.HSet(rs.accountsKey, a.ID.String(), data).Err(), "Failed to save account to Redis")
+}
+
+// GetAccount retrieves the account with the ID
+func (rs *GameStore) GetAccount(id uuid.UUID) (wordgameserver.Account, error) {
+	var a wordgameserver.Account
+	data, err := rs.client.HGet(rs.accountsKey, id.String()).Bytes()
+	if err == redis.Nil {
+		return a, wordgameserver.ErrAccountNotFound
+	} else if err != nil {
+		return a, errors.Wrap(err, "Failed to get account from Redis")
+	}
+	return a, errors.Wrap(json.Unmarshal(data, &a), "Failed to decode account")
+}
+
+// GetAccountByName retrieves the account with the username
+func (rs *GameStore) GetAccountByName(username string) (wordgameserver.Account, error) {
+	id, err := rs.client.HGet(rs.accountsKey+":usernames", username).Result()
+	if err == redis.Nil {
+		return wordgameserver.Account{}, wordgameserver.ErrAccountNotFound
+	} else if err != nil {
+		return wordgameserver.Account{}, errors.Wrap(err, "Failed to get account from Redis")
+	}
+
+	accountID, err := uuid.Parse(id)
+	if err != nil {
+		return wordgameserver.Account{}, errors.Wrap(err, "Failed to decode account ID")
+	}
+	return rs.GetAccount(accountID)
+}
+
+// PutTournament saves the tournament in the tournaments hash. Tournaments never
+// expire.
+func (rs *GameStore) PutTournament(t *wordgameserver.Tournament) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode tournament")
+	}
+	return errors.Wrap(rs.client.HSet(rs.tournamentsKey, t.ID.String(), data).Err(), "Failed to save tournament to Redis")
+}
+
+// GetTournament retrieves the tournament with the ID
+func (rs *GameStore) GetTournament(id uuid.UUID) (*wordgameserver.Tournament, error) {
+	data, err := rs.client.HGet(rs.tournamentsKey, id.String()).Bytes()
+	if err == redis.Nil {
+		return nil, wordgameserver.ErrTournamentNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Failed to get tournament from Redis")
+	}
+
+	var t wordgameserver.Tournament
+	if err = json.Unmarshal(data, &t); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode tournament")
+	}
+	return &t, nil
+}
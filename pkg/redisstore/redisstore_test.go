@@ -0,0 +1,309 @@
+package redisstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+)
+
+func newTestStore(t *testing.T, mr *miniredis.Miniredis) *GameStore {
+	t.Helper()
+
+	rs, err := New(Options{
+		Addr: mr.Addr(),
+		TTL:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+// newTestGame decodes a saved game with the given ID, joined by as many players
+// as there are names
+func newTestGame(t *testing.T, id uuid.UUID, names ...string) *wordgameserver.ScrabbleGame {
+	t.Helper()
+
+	now := `"time":"` + time.Now().UTC().Format(time.RFC3339) + `"`
+	events := `{"type":"game_created",` + now + `,"bag":"QUJD"}`
+	for _, name := range names {
+		events += `,{"type":"player_joined",` + now + `,"player":"` + uuid.New().String() + `","name":"` + name + `"}`
+	}
+	data := `{"id":"` + id.String() + `","events":[` + events + `]}`
+
+	g, err := wordgameserver.DecodeGame([]byte(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGameStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := newTestStore(t, mr)
+	defer rs.Close()
+
+	g := newTestGame(t, uuid.New(), "ashley1")
+	if err = rs.Put(g); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl := mr.TTL(defaultKeyPrefix + g.ID.String()); ttl != time.Hour {
+		t.Errorf("Game saved with TTL %v, expected %v", ttl, time.Hour)
+	}
+
+	// Unchanged games should come from the cache
+	if cached, err := rs.Get(g.ID); err != nil {
+		t.Fatal(err)
+	} else if cached != g {
+		t.Error("Expected cached game to be returned")
+	}
+
+	// A second store simulates another server or a restart
+	other := newTestStore(t, mr)
+	defer other.Close()
+
+	loaded, err := other.Get(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, p := range g.Players {
+		lp, ok := loaded.Players[id]
+		if !ok {
+			t.Fatal("Loaded game is missing player")
+		} else if lp.Name != p.Name || lp.Score != p.Score || string(lp.Tiles) != string(p.Tiles) {
+			t.Errorf("Loaded player %+v does not match saved player %+v", lp, p)
+		}
+	}
+
+	// Changes saved by the other store should replace the cached game
+	changed := newTestGame(t, g.ID, "ashley1", "ashley2")
+	if err = other.Put(changed); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := rs.Get(g.ID); err != nil {
+		t.Fatal(err)
+	} else if reloaded == g || len(reloaded.Players) != 2 {
+		t.Error("Expected stale cached game to be reloaded")
+	}
+
+	if games, err := rs.List(); err != nil {
+		t.Fatal(err)
+	} else if len(games) != 1 {
+		t.Errorf("Listed %v games, expected 1", len(games))
+	}
+
+	if err = rs.Delete(g.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.Get(g.ID); err != wordgameserver.ErrGameNotFound {
+		t.Errorf("Expected ErrGameNotFound after delete, got %v", err)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := newTestStore(t, mr)
+	defer rs.Close()
+
+	// Stores must be usable as rating stores by servers
+	var _ wordgameserver.RatingStore = rs
+
+	saved := []wordgameserver.Rating{
+		{Player: "ashley1", Rating: 1516, Games: 1},
+		{Player: "ashley2", Rating: 1484, Games: 1},
+	}
+	if err = rs.PutRatings(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	ratings, err := rs.GetRatings([]string{"ashley1", "ashley2", "ashley3"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ratings) != 2 {
+		t.Errorf("Got %v ratings, expected 2", len(ratings))
+	}
+	for _, r := range saved {
+		if ratings[r.Player] != r {
+			t.Errorf("Got rating %+v, expected %+v", ratings[r.Player], r)
+		}
+	}
+}
+
+func TestResults(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := newTestStore(t, mr)
+	defer rs.Close()
+
+	var _ wordgameserver.ResultStore = rs
+
+	now := time.Now().UTC().Truncate(time.Second)
+	old := wordgameserver.GameResult{GameID: uuid.New(), Player: "ashley1", Score: 300, Won: true, Finished: now.AddDate(0, 0, -10)}
+	recent := wordgameserver.GameResult{GameID: uuid.New(), Player: "ashley1", Score: 250, Bingos: 1, Finished: now}
+	if err = rs.PutResults([]wordgameserver.GameResult{old}); err != nil {
+		t.Fatal(err)
+	}
+	if err = rs.PutResults([]wordgameserver.GameResult{recent}); err != nil {
+		t.Fatal(err)
+	}
+
+	if results, err := rs.GetResults(time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if len(results) != 2 {
+		t.Errorf("Got %v results, expected 2", len(results))
+	}
+
+	results, err := rs.GetResults(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(results) != 1 || !results[0].Finished.Equal(recent.Finished) || results[0].Bingos != 1 {
+		t.Errorf("Got results %+v, expected only %+v", results, recent)
+	}
+
+	other := wordgameserver.GameResult{GameID: recent.GameID, Player: "ashley2", Score: 200, Finished: now}
+	if err = rs.PutResults([]wordgameserver.GameResult{other}); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = rs.GetPlayerResults("ashley1"); err != nil {
+		t.Fatal(err)
+	} else if len(results) != 2 || results[0].Score != old.Score || results[1].Score != recent.Score {
+		t.Errorf("Got results %+v for ashley1, expected %+v and %+v", results, old, recent)
+	}
+}
+
+func TestNewUnreachable(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := mr.Addr()
+	mr.Close()
+
+	if _, err = New(Options{Addr: addr}); err == nil {
+		t.Error("Expected error connecting to stopped Redis server")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := newTestStore(t, mr)
+	defer rs.Close()
+
+	// Stores must be usable as account stores by servers
+	var _ wordgameserver.AccountStore = rs
+
+	a := wordgameserver.Account{ID: uuid.New(), Username: "ashley", PasswordHash: []byte("hash"), Created: time.Now().UTC()}
+	if err = rs.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+	taken := a
+	taken.ID = uuid.New()
+	if err = rs.CreateAccount(taken); err != wordgameserver.ErrUsernameTaken {
+		t.Errorf("Creating an account with a taken username returned %v, expected %v", err, wordgameserver.ErrUsernameTaken)
+	}
+
+	byID, err := rs.GetAccount(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName, err := rs.GetAccountByName("ashley")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []wordgameserver.Account{byID, byName} {
+		if got.ID != a.ID || got.Username != a.Username || string(got.PasswordHash) != "hash" || !got.Created.Equal(a.Created) {
+			t.Errorf("Got account %+v, expected %+v", got, a)
+		}
+	}
+
+	if _, err = rs.GetAccountByName("ashley2"); err != wordgameserver.ErrAccountNotFound {
+		t.Errorf("Getting a missing account returned %v, expected %v", err, wordgameserver.ErrAccountNotFound)
+	}
+}
+
+func TestTournaments(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := newTestStore(t, mr)
+	defer rs.Close()
+
+	// Stores must be usable as tournament stores by servers
+	var _ wordgameserver.TournamentStore = rs
+
+	tour := &wordgameserver.Tournament{
+		ID:      uuid.New(),
+		Format:  wordgameserver.TournamentSwiss,
+		Status:  wordgameserver.TournamentRegistering,
+		Players: []wordgameserver.TournamentPlayer{{AccountID: uuid.New(), Name: "ashley", Seed: 1}},
+	}
+	if err = rs.PutTournament(tour); err != nil {
+		t.Fatal(err)
+	}
+	got, err := rs.GetTournament(tour.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if got.Format != tour.Format || len(got.Players) != 1 || got.Players[0] != tour.Players[0] {
+		t.Errorf("Got tournament %+v, expected %+v", got, tour)
+	}
+
+	if _, err = rs.GetTournament(uuid.New()); err != wordgameserver.ErrTournamentNotFound {
+		t.Errorf("Getting a missing tournament returned %v, expected %v", err, wordgameserver.ErrTournamentNotFound)
+	}
+}
+
+func TestJoinCodes(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := newTestStore(t, mr)
+	defer rs.Close()
+	code := "ABC234"
+
+	id := uuid.New()
+	if ok, err := rs.ClaimJoinCode(code, id); err != nil || !ok {
+		t.Fatalf("Claiming an unused code returned %v, %v", ok, err)
+	}
+	if ok, err := rs.ClaimJoinCode(code, uuid.New()); err != nil || ok {
+		t.Errorf("Claiming a code another game has returned %v, %v", ok, err)
+	}
+	if got, err := rs.GameByJoinCode(code); err != nil || got != id {
+		t.Errorf("Found game %v by code, expected %v", got, id)
+	}
+
+	if err := rs.ReleaseJoinCode(code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rs.GameByJoinCode(code); err != wordgameserver.ErrGameNotFound {
+		t.Errorf("Finding a released code returned %v, expected %v", err, wordgameserver.ErrGameNotFound)
+	}
+}
@@ -0,0 +1,779 @@
+// Package wordgameclient is a client for the Word Game HTTP API, for building
+// programs that play games hosted by a wordgameserver
+package wordgameclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// apiVersion is the path prefix of the version of the API the client uses
+const apiVersion = "/v2"
+
+// Client sends requests to a Word Game server
+type Client struct {
+	BaseURL    string       // URL of the server, such as http://localhost:8080
+	HTTPClient *http.Client // client used to send requests
+	Token      string       // token of the account requests are made as, set by Register and Login, empty to play as a guest
+}
+
+// Session identifies a player in a game
+type Session struct {
+	GameID   uuid.UUID
+	PlayerID uuid.UUID
+}
+
+// New creates a client for the server at the base URL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Register creates an account and makes the client's requests as it, so
+// players it joins to games are linked to the account
+func (c *Client) Register(username, password string) (wordgameserver.AccountResponse, error) {
+	return c.authenticate("/accounts", wordgameserver.AccountRequest{Username: username, Password: password})
+}
+
+// RegisterWithEmail creates an account like Register, with an email address
+// the server can send reminders to
+func (c *Client) RegisterWithEmail(username, password, email string) (wordgameserver.AccountResponse, error) {
+	return c.authenticate("/accounts", wordgameserver.AccountRequest{Username: username, Password: password, Email: email})
+}
+
+// Login logs in to an account and makes the client's requests as it
+func (c *Client) Login(username, password string) (wordgameserver.AccountResponse, error) {
+	return c.authenticate("/accounts/login", wordgameserver.AccountRequest{Username: username, Password: password})
+}
+
+// authenticate sends the account request to the path, keeping the token the
+// server responds with
+func (c *Client) authenticate(path string, req wordgameserver.AccountRequest) (wordgameserver.AccountResponse, error) {
+	var resp wordgameserver.AccountResponse
+
+	err := c.post(path, req, &resp)
+	if err != nil {
+		return resp, err
+	} else if resp.Token == "" {
+		return resp, errors.New("Server did not return a token")
+	}
+	c.Token = resp.Token
+	return resp, nil
+}
+
+// Account retrieves the account the client is logged in to
+func (c *Client) Account() (wordgameserver.AccountResponse, error) {
+	var resp wordgameserver.AccountResponse
+
+	err := c.get("/accounts/me", &resp)
+	return resp, err
+}
+
+// RegisterDevice has the account the client is logged in to send push
+// notifications to the device, identified by its token with the provider, such
+// as wordgameserver.PushFCM
+func (c *Client) RegisterDevice(provider, token string) (wordgameserver.Device, error) {
+	var resp wordgameserver.Device
+
+	err := c.post("/accounts/me/devices", wordgameserver.DeviceRequest{Provider: provider, Token: token}, &resp)
+	return resp, err
+}
+
+// Devices lists the devices the account the client is logged in to sends push
+// notifications to
+func (c *Client) Devices() ([]wordgameserver.Device, error) {
+	var resp wordgameserver.DevicesResponse
+
+	err := c.get("/accounts/me/devices", &resp)
+	return resp.Devices, err
+}
+
+// RemoveDevice stops push notifications being sent to the device
+func (c *Client) RemoveDevice(id uuid.UUID) error {
+	return c.send(http.MethodDelete, "/accounts/me/devices/"+id.String(), nil, nil)
+}
+
+// NotificationPreferences retrieves the kinds of push notification the
+// account the client is logged in to is sent
+func (c *Client) NotificationPreferences() (wordgameserver.NotificationPreferences, error) {
+	var resp wordgameserver.NotificationPreferences
+
+	err := c.get("/accounts/me/notifications", &resp)
+	return resp, err
+}
+
+// SetNotificationPreferences chooses the kinds of push notification the
+// account the client is logged in to is sent
+func (c *Client) SetNotificationPreferences(prefs wordgameserver.NotificationPreferences) (wordgameserver.NotificationPreferences, error) {
+	var resp wordgameserver.NotificationPreferences
+
+	err := c.send(http.MethodPut, "/accounts/me/notifications", prefs, &resp)
+	return resp, err
+}
+
+// CreateGame creates a new game with the options given, or the defaults if
+// they are nil
+func (c *Client) CreateGame(opts *wordgameserver.GameOptions) (uuid.UUID, error) {
+	var resp wordgameserver.GeneralGameRequest
+
+	err := c.post("/games", wordgameserver.GeneralGameRequest{Options: opts}, &resp)
+	return resp.GameID, err
+}
+
+// CreatePrivateGame creates a new game with the options given that is kept out
+// of the lobby, returning its ID along with the code players can join it with
+func (c *Client) CreatePrivateGame(opts wordgameserver.GameOptions) (uuid.UUID, string, error) {
+	var resp wordgameserver.GeneralGameRequest
+
+	opts.Private = true
+	if err := c.post("/games", wordgameserver.GeneralGameRequest{Options: &opts}, &resp); err != nil {
+		return uuid.Nil, "", err
+	} else if resp.JoinCode == nil {
+		return uuid.Nil, "", errors.New("Server did not return a join code")
+	}
+	return resp.GameID, *resp.JoinCode, nil
+}
+
+// CreateGameWithInvites creates a new game with the options given, returning
+// its ID along with invites that each let one person join it
+func (c *Client) CreateGameWithInvites(opts *wordgameserver.GameOptions, invites int) (uuid.UUID, []wordgameserver.Invite, error) {
+	var resp wordgameserver.GeneralGameRequest
+
+	err := c.post("/games", wordgameserver.GeneralGameRequest{Options: opts, Invites: invites}, &resp)
+	return resp.GameID, resp.InviteList, err
+}
+
+// ImportGame creates a game from a GCG file or a position, which starts
+// straight away. A session is returned for each player, in turn order.
+func (c *Client) ImportGame(req wordgameserver.GameImportRequest) ([]Session, error) {
+	var resp wordgameserver.GameImportResponse
+
+	if err := c.post("/games/import", req, &resp); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(resp.PlayerIDs))
+	for i, id := range resp.PlayerIDs {
+		sessions[i] = Session{GameID: resp.GameID, PlayerID: id}
+	}
+	return sessions, nil
+}
+
+// ForkGame creates a game that plays on from the finished game before the
+// move with the index is made, which starts straight away. If a bot level is
+// given, bots take the seats of everyone but the player to move. A session is
+// returned for each player who isn't a bot, in turn order.
+func (c *Client) ForkGame(gameID uuid.UUID, move int, botLevel *string) ([]Session, error) {
+	var resp wordgameserver.GameImportResponse
+
+	err := c.post(gamePath(gameID, "/fork"), wordgameserver.GameForkRequest{Move: move, BotLevel: botLevel}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(resp.PlayerIDs))
+	for i, id := range resp.PlayerIDs {
+		sessions[i] = Session{GameID: resp.GameID, PlayerID: id}
+	}
+	return sessions, nil
+}
+
+// JoinGame adds a player with the given name to the game. Players joined by a
+// logged in client take its account's username instead.
+func (c *Client) JoinGame(gameID uuid.UUID, name string) (Session, error) {
+	return c.join(gameID, wordgameserver.GeneralGameRequest{PlayerName: &name})
+}
+
+// JoinProtectedGame adds a player with the given name to a game that needs a
+// passphrase to join
+func (c *Client) JoinProtectedGame(gameID uuid.UUID, name, passphrase string) (Session, error) {
+	return c.join(gameID, wordgameserver.GeneralGameRequest{PlayerName: &name, Passphrase: &passphrase})
+}
+
+// join sends the request to join the game
+func (c *Client) join(gameID uuid.UUID, req wordgameserver.GeneralGameRequest) (Session, error) {
+	var resp wordgameserver.GeneralGameRequest
+
+	err := c.post(gamePath(gameID, "/players"), req, &resp)
+	if err != nil {
+		return Session{}, err
+	} else if resp.PlayerID == nil {
+		return Session{}, errors.New("Server did not return a player ID")
+	}
+
+	return Session{GameID: gameID, PlayerID: *resp.PlayerID}, nil
+}
+
+// JoinGameByCode adds a player with the given name to the private game with
+// the join code
+func (c *Client) JoinGameByCode(code, name string) (Session, error) {
+	return c.joinBy(wordgameserver.GeneralGameRequest{PlayerName: &name, JoinCode: &code})
+}
+
+// JoinGameByInvite adds a player with the given name to the game the invite's
+// token is for
+func (c *Client) JoinGameByInvite(token, name string) (Session, error) {
+	return c.joinBy(wordgameserver.GeneralGameRequest{PlayerName: &name, Invite: &token})
+}
+
+// joinBy sends the request to join a game found by its join code or an
+// invite
+func (c *Client) joinBy(req wordgameserver.GeneralGameRequest) (Session, error) {
+	var resp wordgameserver.GeneralGameRequest
+
+	err := c.post("/games/join", req, &resp)
+	if err != nil {
+		return Session{}, err
+	} else if resp.PlayerID == nil {
+		return Session{}, errors.New("Server did not return a player ID")
+	}
+
+	return Session{GameID: resp.GameID, PlayerID: *resp.PlayerID}, nil
+}
+
+// StartGame starts the game so no more players can join
+func (c *Client) StartGame(gameID uuid.UUID) error {
+	return c.post(gamePath(gameID, "/start"), nil, nil)
+}
+
+// State retrieves the current state of the game from the player's view
+func (c *Client) State(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.get(gamePath(s.GameID, "")+"?"+s.query(), &resp)
+	return resp, err
+}
+
+// StateSince retrieves the player's view of the game like State, but with
+// what has changed since the number of moves given in its diff instead of the
+// whole board. The board is sent whole if no moves have been seen or the
+// server can't tell what has changed.
+func (c *Client) StateSince(s Session, since int) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.get(gamePath(s.GameID, "")+"?"+s.query()+"&since="+strconv.Itoa(since), &resp)
+	return resp, err
+}
+
+// Play submits the player's turn, returning the state of the game afterwards
+func (c *Client) Play(s Session, play wordgameserver.GamePlayRequest) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/moves"), moveRequest(s, play), &resp)
+	return resp, err
+}
+
+// Validate asks the server whether the play or swap could be made now, and
+// what a play would form and score, without making it
+func (c *Client) Validate(s Session, play wordgameserver.GamePlayRequest) (wordgameserver.MoveValidationResponse, error) {
+	var resp wordgameserver.MoveValidationResponse
+
+	err := c.post(gamePath(s.GameID, "/validate"), moveRequest(s, play), &resp)
+	return resp, err
+}
+
+// moveRequest converts a play or swap by the session's player to the format
+// the server's moves are sent in
+func moveRequest(s Session, play wordgameserver.GamePlayRequest) wordgameserver.GameMoveRequest {
+	move := wordgameserver.GameMoveRequest{
+		PlayerID: s.PlayerID,
+		Action:   wordgameserver.ActionPlay,
+		StartPos: play.StartPos,
+		EndPos:   play.EndPos,
+		Tiles:    play.Tiles,
+		Blanks:   play.Blanks,
+
+		Placements: play.Placements,
+	}
+	if play.Swap {
+		move.Action = wordgameserver.ActionSwap
+	}
+	return move
+}
+
+// Swap exchanges tiles in the player's hand for tiles from the bag
+func (c *Client) Swap(s Session, tiles wordgameserver.Letters) (wordgameserver.GameStateResponse, error) {
+	return c.Play(s, wordgameserver.GamePlayRequest{
+		Tiles: tiles,
+		Swap:  true,
+	})
+}
+
+// Challenge challenges the most recent play in the game
+func (c *Client) Challenge(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/challenges"), s.request(), &resp)
+	return resp, err
+}
+
+// Undo asks to undo the most recent move of a friendly game if the player made
+// it, or agrees to undo it if another player asked
+func (c *Client) Undo(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/undo"), s.request(), &resp)
+	return resp, err
+}
+
+// Kick removes the player with the number from the game, or votes to if the
+// session's player doesn't own it
+func (c *Client) Kick(s Session, number int) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/kicks"), wordgameserver.KickRequest{PlayerID: s.PlayerID, Player: number}, &resp)
+	return resp, err
+}
+
+// Pause pauses the game, if its options allow it, freezing its timers and
+// clocks until every player still in it calls Unpause
+func (c *Client) Pause(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/pause"), s.request(), &resp)
+	return resp, err
+}
+
+// Unpause asks to resume the paused game, which it does once every player
+// still in it has asked
+func (c *Client) Unpause(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/unpause"), s.request(), &resp)
+	return resp, err
+}
+
+// Pass gives up the player's turn without playing or swapping tiles
+func (c *Client) Pass(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/moves"), s.move(wordgameserver.ActionPass), &resp)
+	return resp, err
+}
+
+// Resign concedes the game for the player
+func (c *Client) Resign(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/moves"), s.move(wordgameserver.ActionResign), &resp)
+	return resp, err
+}
+
+// Hint asks the server for a move the player could make, as good as the level,
+// which is one of the server's bot levels or empty for its default. The move
+// can be passed to Play to make it, unless it has no tiles, when the player can
+// only pass.
+func (c *Client) Hint(s Session, level string) (wordgameserver.GamePlayRequest, error) {
+	var resp wordgameserver.GamePlayRequest
+
+	q := url.Values{"player_id": {s.PlayerID.String()}}
+	if level != "" {
+		q.Set("level", level)
+	}
+	err := c.get(gamePath(s.GameID, "/hint")+"?"+q.Encode(), &resp)
+	return resp, err
+}
+
+// Unseen retrieves the tiles the player can't see, in the bag and the other
+// players' racks, for tracking which tiles are still to come
+func (c *Client) Unseen(s Session) (wordgameserver.UnseenTilesResponse, error) {
+	var resp wordgameserver.UnseenTilesResponse
+
+	err := c.get(gamePath(s.GameID, "/unseen")+"?player_id="+s.PlayerID.String(), &resp)
+	return resp, err
+}
+
+// LobbyQuery filters and pages the games listed by ListGames. Zero values
+// leave the server's defaults in place.
+type LobbyQuery struct {
+	OpenSeats int    // fewest open seats a game can have
+	Timer     *bool  // if set, whether games must have a turn timer or clock
+	Variant   string // if set, one of wordgameserver.VariantStandard, VariantSuper or VariantWordsWithFriends
+	Limit     int    // most games to return
+	Offset    int    // number of games to skip
+}
+
+// ListGames lists the games waiting for players in the server's lobby, newest
+// first
+func (c *Client) ListGames(q LobbyQuery) (wordgameserver.LobbyResponse, error) {
+	var resp wordgameserver.LobbyResponse
+
+	params := url.Values{}
+	if q.OpenSeats > 0 {
+		params.Set("open_seats", strconv.Itoa(q.OpenSeats))
+	}
+	if q.Timer != nil {
+		params.Set("timer", strconv.FormatBool(*q.Timer))
+	}
+	if q.Variant != "" {
+		params.Set("variant", q.Variant)
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		params.Set("offset", strconv.Itoa(q.Offset))
+	}
+
+	path := "/games"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	err := c.get(path, &resp)
+	return resp, err
+}
+
+// LeaderboardQuery sorts and pages the players listed by Leaderboard. Zero
+// values leave the server's defaults in place.
+type LeaderboardQuery struct {
+	Sort   string // one of the wordgameserver.Leaderboard orders
+	Window string // wordgameserver.LeaderboardWeekly or LeaderboardAllTime
+	Limit  int    // most players to return
+	Offset int    // number of players to skip
+}
+
+// Leaderboard lists the server's top players
+func (c *Client) Leaderboard(q LeaderboardQuery) (wordgameserver.LeaderboardResponse, error) {
+	var resp wordgameserver.LeaderboardResponse
+
+	params := url.Values{}
+	if q.Sort != "" {
+		params.Set("sort", q.Sort)
+	}
+	if q.Window != "" {
+		params.Set("window", q.Window)
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		params.Set("offset", strconv.Itoa(q.Offset))
+	}
+
+	path := "/leaderboard"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	err := c.get(path, &resp)
+	return resp, err
+}
+
+// PlayerStats retrieves the statistics of a registered player, identified by
+// their account ID or username
+func (c *Client) PlayerStats(player string) (wordgameserver.PlayerStats, error) {
+	var resp wordgameserver.PlayerStats
+
+	err := c.get("/players/"+url.PathEscape(player)+"/stats", &resp)
+	return resp, err
+}
+
+// PlayerGamesQuery filters and pages the games listed by PlayerGames. Zero
+// values leave the server's defaults in place.
+type PlayerGamesQuery struct {
+	Status string // one of wordgameserver.GameWaiting, GameActive or GameFinished
+	Limit  int    // most games to return
+	Offset int    // number of games to skip
+}
+
+// PlayerGames lists a registered player's games, identified by their account
+// ID or username, most recently updated first. Games the client's own account
+// is still playing include the session needed to resume them.
+func (c *Client) PlayerGames(player string, q PlayerGamesQuery) (wordgameserver.PlayerGamesResponse, error) {
+	var resp wordgameserver.PlayerGamesResponse
+
+	params := url.Values{}
+	if q.Status != "" {
+		params.Set("status", q.Status)
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		params.Set("offset", strconv.Itoa(q.Offset))
+	}
+
+	path := "/players/" + url.PathEscape(player) + "/games"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	err := c.get(path, &resp)
+	return resp, err
+}
+
+// CreateTournament creates a tournament that registered players can sign up
+// for
+func (c *Client) CreateTournament(req wordgameserver.TournamentRequest) (wordgameserver.Tournament, error) {
+	var resp wordgameserver.Tournament
+
+	err := c.post("/tournaments", req, &resp)
+	return resp, err
+}
+
+// RegisterTournament signs the client's account up for the tournament. The
+// client must have registered or logged in first.
+func (c *Client) RegisterTournament(id uuid.UUID) (wordgameserver.TournamentPlayer, error) {
+	var resp wordgameserver.TournamentPlayer
+
+	err := c.post(tournamentPath(id, "/players"), nil, &resp)
+	return resp, err
+}
+
+// StartTournament closes registration and starts the games of the first round
+func (c *Client) StartTournament(id uuid.UUID) (wordgameserver.Tournament, error) {
+	var resp wordgameserver.Tournament
+
+	err := c.post(tournamentPath(id, "/start"), nil, &resp)
+	return resp, err
+}
+
+// Tournament retrieves the tournament, including the pairings of every round
+// played so far
+func (c *Client) Tournament(id uuid.UUID) (wordgameserver.Tournament, error) {
+	var resp wordgameserver.Tournament
+
+	err := c.get(tournamentPath(id, ""), &resp)
+	return resp, err
+}
+
+// TournamentStandings retrieves the tournament's players ranked by their
+// results so far
+func (c *Client) TournamentStandings(id uuid.UUID) (wordgameserver.TournamentStandingsResponse, error) {
+	var resp wordgameserver.TournamentStandingsResponse
+
+	err := c.get(tournamentPath(id, "/standings"), &resp)
+	return resp, err
+}
+
+// NextPuzzle retrieves a puzzle the logged in player hasn't answered, close to
+// their puzzle rating
+func (c *Client) NextPuzzle() (wordgameserver.Puzzle, error) {
+	var resp wordgameserver.Puzzle
+
+	err := c.get("/puzzles/next", &resp)
+	return resp, err
+}
+
+// Puzzle retrieves the puzzle with the ID
+func (c *Client) Puzzle(id uuid.UUID) (wordgameserver.Puzzle, error) {
+	var resp wordgameserver.Puzzle
+
+	err := c.get("/puzzles/"+id.String(), &resp)
+	return resp, err
+}
+
+// AnswerPuzzle answers the puzzle with the play, returning how it compares with
+// the best play. Only the logged in player's first answer to a puzzle is
+// rated.
+func (c *Client) AnswerPuzzle(id uuid.UUID, play wordgameserver.GamePlayRequest) (wordgameserver.PuzzleResult, error) {
+	var resp wordgameserver.PuzzleResult
+
+	err := c.post("/puzzles/"+id.String()+"/answers", play, &resp)
+	return resp, err
+}
+
+// History retrieves every move made in the game, in order. It doesn't need a
+// player, so spectators can use it too.
+func (c *Client) History(gameID uuid.UUID) (wordgameserver.GameHistoryResponse, error) {
+	var resp wordgameserver.GameHistoryResponse
+
+	err := c.get(gamePath(gameID, "/moves"), &resp)
+	return resp, err
+}
+
+// Chat posts a message from the player to the game's chat
+func (c *Client) Chat(s Session, text string) (wordgameserver.ChatMessage, error) {
+	var resp wordgameserver.ChatMessage
+
+	err := c.post(gamePath(s.GameID, "/chat"), wordgameserver.ChatRequest{PlayerID: &s.PlayerID, Text: text}, &resp)
+	return resp, err
+}
+
+// React reacts to the most recent move of the game with one of
+// wordgameserver.Emotes
+func (c *Client) React(s Session, emote string) (wordgameserver.Reaction, error) {
+	var resp wordgameserver.Reaction
+
+	err := c.post(gamePath(s.GameID, "/reactions"), wordgameserver.ReactionRequest{PlayerID: s.PlayerID, Emote: emote}, &resp)
+	return resp, err
+}
+
+// Invite emails the addresses a link to join the game, with the message if it
+// isn't empty. Only the player who created the game can invite people.
+func (c *Client) Invite(s Session, emails []string, message string) (wordgameserver.InvitationResponse, error) {
+	var resp wordgameserver.InvitationResponse
+
+	err := c.post(gamePath(s.GameID, "/invitations"), wordgameserver.InvitationRequest{PlayerID: s.PlayerID, Emails: emails, Message: message}, &resp)
+	return resp, err
+}
+
+// ChatMessages retrieves the messages posted to the game's chat, skipping the
+// number already seen. It doesn't need a player, so spectators can use it too.
+func (c *Client) ChatMessages(gameID uuid.UUID, after int) (wordgameserver.ChatResponse, error) {
+	var resp wordgameserver.ChatResponse
+
+	err := c.get(gamePath(gameID, "/chat")+"?after="+strconv.Itoa(after), &resp)
+	return resp, err
+}
+
+// SpectatorChat posts a message to the game's chat from a spectator, under the
+// name given. Players don't see it until the game has finished.
+func (c *Client) SpectatorChat(gameID uuid.UUID, name, text string) (wordgameserver.ChatMessage, error) {
+	var resp wordgameserver.ChatMessage
+
+	err := c.post(gamePath(gameID, "/chat"), wordgameserver.ChatRequest{Name: name, Text: text}, &resp)
+	return resp, err
+}
+
+// SpectatorChatMessages retrieves the messages spectators have posted to the
+// game's chat, skipping the number already seen
+func (c *Client) SpectatorChatMessages(gameID uuid.UUID, after int) (wordgameserver.ChatResponse, error) {
+	var resp wordgameserver.ChatResponse
+
+	err := c.get(gamePath(gameID, "/chat")+"?channel="+wordgameserver.ChatSpectators+"&after="+strconv.Itoa(after), &resp)
+	return resp, err
+}
+
+// Resume re-establishes the player's session after losing their connection,
+// returning the full state of the game
+func (c *Client) Resume(s Session) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/resume"), s.request(), &resp)
+	return resp, err
+}
+
+// Rematch starts a new game with the players of the session's finished game,
+// in the reverse order, returning the game and the player's ID in it. Every
+// player gets the same game, whoever asks first.
+func (c *Client) Rematch(s Session) (wordgameserver.RematchResponse, error) {
+	var resp wordgameserver.RematchResponse
+
+	err := c.post(gamePath(s.GameID, "/rematch"), s.request(), &resp)
+	return resp, err
+}
+
+// Cancel stops the game and removes it from the server, if the session's player
+// owns it
+func (c *Client) Cancel(s Session) error {
+	return c.send(http.MethodDelete, gamePath(s.GameID, "")+"?"+s.query(), nil, nil)
+}
+
+// SetOptions changes the options of a game before it starts, returning them as
+// changed. Only the game's owner can change them, and only those that don't
+// shape the game when it is created.
+func (c *Client) SetOptions(s Session, opts wordgameserver.GameOptions) (wordgameserver.GameOptions, error) {
+	var resp wordgameserver.GeneralGameRequest
+
+	err := c.send(http.MethodPut, gamePath(s.GameID, "/options"), wordgameserver.OptionsRequest{PlayerID: s.PlayerID, Options: opts}, &resp)
+	if err != nil {
+		return wordgameserver.GameOptions{}, err
+	} else if resp.Options == nil {
+		return wordgameserver.GameOptions{}, errors.New("Server did not return the options")
+	}
+	return *resp.Options, nil
+}
+
+// TransferOwnership hands the game over to the player with the number, if the
+// session's player owns it
+func (c *Client) TransferOwnership(s Session, number int) (wordgameserver.GameStateResponse, error) {
+	var resp wordgameserver.GameStateResponse
+
+	err := c.post(gamePath(s.GameID, "/owner"), wordgameserver.OwnerRequest{PlayerID: s.PlayerID, Player: number}, &resp)
+	return resp, err
+}
+
+// gamePath returns the path of a game's resource, or of the sub-resource if
+// it isn't empty
+func gamePath(gameID uuid.UUID, sub string) string {
+	return "/games/" + gameID.String() + sub
+}
+
+// tournamentPath returns the path of a tournament, or of the sub-resource if
+// it isn't empty
+func tournamentPath(id uuid.UUID, sub string) string {
+	return "/tournaments/" + id.String() + sub
+}
+
+func (s Session) request() wordgameserver.PlayerRequest {
+	return wordgameserver.PlayerRequest{PlayerID: s.PlayerID}
+}
+
+func (s Session) move(action string) wordgameserver.GameMoveRequest {
+	return wordgameserver.GameMoveRequest{PlayerID: s.PlayerID, Action: action}
+}
+
+// query returns the query string identifying the player, for requests without
+// a body
+func (s Session) query() string {
+	return url.Values{"player_id": {s.PlayerID.String()}}.Encode()
+}
+
+// post sends the request body as JSON, unless it is nil, and decodes the JSON
+// response into resp, unless it is nil. Error responses from the server are
+// returned as errors.
+func (c *Client) post(path string, body interface{}, resp interface{}) error {
+	return c.send(http.MethodPost, path, body, resp)
+}
+
+// get fetches the path and decodes the JSON response into resp
+func (c *Client) get(path string, resp interface{}) error {
+	return c.send(http.MethodGet, path, nil, resp)
+}
+
+// send makes a request with the method to the path, sending the body as JSON
+// unless it is nil, and decodes the JSON response into resp unless it is nil
+func (c *Client) send(method, path string, body interface{}, resp interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode request")
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+apiVersion+path, payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	r, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(r, resp)
+}
+
+// decodeResponse closes the response after decoding its JSON body into resp,
+// unless it is nil. Error responses from the server are returned as errors.
+func decodeResponse(r *http.Response, resp interface{}) error {
+	defer r.Body.Close()
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(r.Body)
+		return errors.New(strings.TrimSpace(string(msg)))
+	}
+
+	if resp == nil {
+		return nil
+	}
+	return errors.Wrap(json.NewDecoder(r.Body).Decode(resp), "Failed to decode response")
+}
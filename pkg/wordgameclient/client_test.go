@@ -0,0 +1,225 @@
+package wordgameclient
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+)
+
+// startServer runs a server on a free port until the test finishes
+func startServer(t *testing.T) *Client {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg := wordgameserver.DefaultConfig()
+	cfg.BindAddr = addr
+	cfg.IdleTTL = 0
+	go wordgameserver.StartWordGameServer(ctx, cfg, nil, nil, nil, nil)
+
+	c := New("http://" + addr + "/")
+	for i := 0; i < 50; i++ {
+		if _, err = c.CreateGame(nil); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := startServer(t)
+
+	gameID, err := c.CreateGame(&wordgameserver.GameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := c.JoinGame(gameID, "ashley1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.JoinGame(gameID, "ashley2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lobby, err := c.ListGames(LobbyQuery{OpenSeats: 2})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lobby.Games) == 0 || lobby.Games[0].GameID != gameID || lobby.Games[0].Creator != "ashley1" {
+		t.Errorf("Lobby should list the game created by ashley1 first, got %+v", lobby.Games)
+	}
+
+	if _, err = c.PlayerStats("nobody"); err == nil {
+		t.Error("Getting the stats of an unregistered player should fail")
+	}
+
+	if _, err = c.Leaderboard(LeaderboardQuery{Sort: wordgameserver.LeaderboardWins, Window: wordgameserver.LeaderboardWeekly}); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = c.State(first); err == nil {
+		t.Error("State should fail before the game starts")
+	}
+
+	updates, closeWatch, err := c.Watch(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeWatch()
+
+	if err = c.StartGame(gameID); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case s := <-updates:
+		if len(s.PlayerTiles) != 7 {
+			t.Errorf("Pushed state has %v tiles, expected 7", len(s.PlayerTiles))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No state pushed when game started")
+	}
+
+	s, err := c.State(first)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(s.PlayerTiles) != 7 || len(s.Players) != 2 {
+		t.Fatalf("Unexpected state: %+v", s)
+	}
+
+	if _, err = c.Swap(second, s.RackLetters()[:1]); err == nil {
+		t.Error("Playing out of turn should return an error")
+	}
+
+	if s, err = c.Swap(first, s.RackLetters()[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := c.History(first.GameID)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(h.Moves) != 1 || !h.Moves[0].Swap || len(h.Players) != 2 {
+		t.Errorf("History should show the swap, got %+v", h)
+	}
+
+	// Both players passing in a row ends the game
+	if _, err = c.Pass(second); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = c.Pass(first); err != nil {
+		t.Fatal(err)
+	} else if !s.Finished || len(s.Winners) == 0 {
+		t.Errorf("Game should be finished with a winner after every player passed: %+v", s)
+	}
+
+	if s, err = c.Resume(second); err != nil {
+		t.Fatal(err)
+	} else if !s.Active {
+		t.Error("Resumed state should show game as started")
+	}
+
+	// Only the game's owner can cancel it
+	if err = c.Cancel(second); err == nil {
+		t.Error("Game was cancelled by a player who doesn't own it")
+	}
+	if err = c.Cancel(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.State(first); err == nil {
+		t.Error("State should fail for cancelled game")
+	}
+}
+
+func TestAccount(t *testing.T) {
+	c := startServer(t)
+
+	registered, err := c.Register("ashley", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Login("ashley", "hunter21"); err == nil {
+		t.Error("Logging in with the wrong password should fail")
+	}
+	if account, err := c.Account(); err != nil {
+		t.Fatal(err)
+	} else if account.ID != registered.ID {
+		t.Errorf("Logged in to account %v, expected %v", account.ID, registered.ID)
+	}
+
+	gameID, err := c.CreateGame(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.JoinGame(gameID, "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	games, err := c.PlayerGames("ashley", PlayerGamesQuery{Status: wordgameserver.GameWaiting})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(games.Games) != 1 || games.Games[0].PlayerID == nil || *games.Games[0].PlayerID != s.PlayerID {
+		t.Errorf("Listed games %+v, expected game %v with player %v", games.Games, gameID, s.PlayerID)
+	} else if games.Games[0].Players[0] != "ashley" {
+		t.Errorf("Joined game as %v, expected the account's username", games.Games[0].Players[0])
+	}
+
+	if stats, err := c.PlayerStats(registered.ID.String()); err != nil {
+		t.Fatal(err)
+	} else if stats.Player != "ashley" || stats.Games != 0 {
+		t.Errorf("Stats are %+v, expected no games for ashley", stats)
+	}
+
+	tour, err := c.CreateTournament(wordgameserver.TournamentRequest{Format: wordgameserver.TournamentSwiss})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := c.RegisterTournament(tour.ID); err != nil {
+		t.Fatal(err)
+	} else if p.AccountID != registered.ID || p.Seed != 1 {
+		t.Errorf("Registered as %+v, expected the first seed", p)
+	}
+	if _, err = c.StartTournament(tour.ID); err == nil {
+		t.Error("Starting a tournament with one player should fail")
+	}
+	if standings, err := c.TournamentStandings(tour.ID); err != nil {
+		t.Fatal(err)
+	} else if len(standings.Standings) != 1 || standings.Status != wordgameserver.TournamentRegistering {
+		t.Errorf("Standings are %+v, expected ashley registered", standings)
+	}
+}
+
+func TestNewPlay(t *testing.T) {
+	board := wordgameserver.NewBoard()
+	board[7][8].Letter = 'A'
+
+	play, err := NewPlay(board, wordgameserver.SquareCoordinate{Row: 7, Col: 7}, false, "CtS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(play.Tiles) != "C S" || string(play.Blanks) != "T" {
+		t.Errorf("Play has tiles %q and blanks %q, expected \"C S\" and \"T\"", play.Tiles, play.Blanks)
+	} else if end := play.EndPos; end.Row != 7 || end.Col != 10 {
+		t.Errorf("Play ends at %+v, expected row 7 column 10", end)
+	}
+
+	if _, err = NewPlay(board, wordgameserver.SquareCoordinate{Row: 12, Col: 7}, true, "CATS"); err == nil {
+		t.Error("Play running off the board should fail")
+	}
+}
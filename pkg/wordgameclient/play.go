@@ -0,0 +1,56 @@
+package wordgameclient
+
+import (
+	"unicode"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/pkg/errors"
+)
+
+// NewPlay builds a play placing tiles on the board from the start square,
+// going across or down and skipping over squares that already have tiles.
+// Tiles are given as letters, with lowercase letters standing for blank tiles
+// played as that letter.
+func NewPlay(board wordgameserver.ScrabbleBoard, start wordgameserver.SquareCoordinate, down bool, tiles string) (wordgameserver.GamePlayRequest, error) {
+	play := wordgameserver.GamePlayRequest{
+		StartPos: start,
+	}
+
+	step := wordgameserver.SquareCoordinate{Col: 1}
+	if down {
+		step = wordgameserver.SquareCoordinate{Row: 1}
+	}
+
+	letters := []rune(tiles)
+	if len(letters) == 0 {
+		return play, errors.New("No tiles to play")
+	}
+
+	sc := start
+	for i := 0; i < len(letters); sc.Row, sc.Col = sc.Row+step.Row, sc.Col+step.Col {
+		if sc.Row < 0 || sc.Row >= len(board) || sc.Col < 0 || sc.Col >= len(board[sc.Row]) {
+			return play, errors.New("Tiles run off the edge of the board")
+		}
+
+		// Skip squares that already have tiles
+		if board[sc.Row][sc.Col].Letter != 0 {
+			continue
+		}
+
+		t := letters[i]
+		switch {
+		case unicode.IsLower(t):
+			play.Tiles = append(play.Tiles, ' ')
+			play.Blanks = append(play.Blanks, wordgameserver.Letter(unicode.ToUpper(t)))
+		case unicode.IsUpper(t):
+			play.Tiles = append(play.Tiles, wordgameserver.Letter(t))
+		default:
+			return play, errors.New("Invalid tile '" + string(t) + "'")
+		}
+
+		play.EndPos = sc
+		i++
+	}
+
+	return play, nil
+}
@@ -0,0 +1,36 @@
+package wordgameclient
+
+import (
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+	"github.com/gorilla/websocket"
+)
+
+// Watch opens a WebSocket on which the server pushes the player's game state
+// whenever it changes. The channel is closed when the connection ends, which
+// can be forced by calling the returned close function. The channel must be
+// read from until it is closed.
+func (c *Client) Watch(s Session) (<-chan wordgameserver.GameStateResponse, func() error, error) {
+	// http:// becomes ws:// and https:// becomes wss://
+	u := "ws" + strings.TrimPrefix(c.BaseURL, "http") + apiVersion + gamePath(s.GameID, "/ws") + "?" + s.query()
+
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := make(chan wordgameserver.GameStateResponse)
+	go func() {
+		defer close(updates)
+		for {
+			var state wordgameserver.GameStateResponse
+			if err := conn.ReadJSON(&state); err != nil {
+				return
+			}
+			updates <- state
+		}
+	}()
+
+	return updates, conn.Close, nil
+}
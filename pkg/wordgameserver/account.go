@@ -0,0 +1,401 @@
+package wordgameserver
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned by an AccountStore
+var (
+	ErrAccountNotFound = errors.New("Account does not exist")
+	ErrUsernameTaken   = errors.New("Username is already taken")
+)
+
+// ErrInvalidToken is returned when a request is made with a token that the
+// server didn't issue, or that has expired
+var ErrInvalidToken = errors.New("Invalid or expired token")
+
+// Usernames are shown as player names, so they are kept short. Passwords are
+// limited by what bcrypt can hash.
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 20
+	minPasswordLength = 8
+	maxPasswordLength = 72
+	tokenTTL          = 30 * 24 * time.Hour
+)
+
+// missingAccountHash is compared with the password given for a username that
+// isn't registered, so logging in takes as long as it does for one that is.
+// It is hashed at the same cost as passwords are when they are set.
+var missingAccountHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("missing account"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// Account is a registered player, whose identity lasts across games. Players
+// who join a game while logged in are linked to their account and play under
+// its username.
+type Account struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"password_hash"`
+	Email        string    `json:"email,omitempty"` // address reminders are sent to, if the player gave one
+	Created      time.Time `json:"created"`
+}
+
+// AccountStore holds registered accounts. Implementations must be safe for
+// concurrent use. A GameStore that also implements AccountStore is used for
+// accounts by the server it is given to.
+type AccountStore interface {
+	CreateAccount(a Account) error                     // add an account, or ErrUsernameTaken
+	GetAccount(id uuid.UUID) (Account, error)          // retrieve an account, or ErrAccountNotFound
+	GetAccountByName(username string) (Account, error) // retrieve an account by its username, or ErrAccountNotFound
+}
+
+// MemoryAccountStore is the default AccountStore, which keeps accounts in
+// memory for the lifetime of the process
+type MemoryAccountStore struct {
+	sync.Mutex
+	accounts map[uuid.UUID]Account
+	names    map[string]uuid.UUID
+}
+
+// NewMemoryAccountStore creates an empty in-memory account store
+func NewMemoryAccountStore() *MemoryAccountStore {
+	return &MemoryAccountStore{
+		accounts: make(map[uuid.UUID]Account),
+		names:    make(map[string]uuid.UUID),
+	}
+}
+
+// CreateAccount adds the account to the store unless its username is taken
+func (ms *MemoryAccountStore) CreateAccount(a Account) error {
+	ms.Lock()
+	defer ms.Unlock()
+	if _, ok := ms.names[a.Username]; ok {
+		return ErrUsernameTaken
+	}
+	ms.accounts[a.ID] = a
+	ms.names[a.Username] = a.ID
+	return nil
+}
+
+// GetAccount retrieves the account with the ID
+func (ms *MemoryAccountStore) GetAccount(id uuid.UUID) (Account, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	a, ok := ms.accounts[id]
+	if !ok {
+		return Account{}, ErrAccountNotFound
+	}
+	return a, nil
+}
+
+// GetAccountByName retrieves the account with the username
+func (ms *MemoryAccountStore) GetAccountByName(username string) (Account, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	id, ok := ms.names[username]
+	if !ok {
+		return Account{}, ErrAccountNotFound
+	}
+	return ms.accounts[id], nil
+}
+
+// normalizeUsername lowercases the username so accounts can't be registered
+// that differ only in case, and checks that it is valid
+func normalizeUsername(username string) (string, error) {
+	username = strings.ToLower(username)
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return "", errors.Errorf("Username must be between %v and %v characters", minUsernameLength, maxUsernameLength)
+	}
+	for _, c := range username {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return "", errors.New("Username can only contain letters, digits and underscores")
+		}
+	}
+	return username, nil
+}
+
+// newAccount creates an account with the username and a hash of the password
+func newAccount(username, password string) (Account, error) {
+	username, err := normalizeUsername(username)
+	if err != nil {
+		return Account{}, err
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return Account{}, errors.Errorf("Password must be between %v and %v bytes", minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return Account{}, errors.Wrap(err, "Failed to hash password")
+	}
+	return Account{
+		ID:           uuid.New(),
+		Username:     username,
+		PasswordHash: hash,
+		Created:      time.Now(),
+	}, nil
+}
+
+// newTokenKey returns the key login tokens are signed with. Without a
+// configured secret a random key is used, so tokens only last as long as the
+// server does.
+func newTokenKey(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate token key")
+	}
+	return key, nil
+}
+
+// issueToken creates a login token for the account, which lasts for tokenTTL.
+// Tokens are the account ID and expiry, signed by the server, so they don't
+// need to be stored.
+func (s *Server) issueToken(id uuid.UUID, now time.Time) string {
+	payload := make([]byte, 16+8)
+	copy(payload, id[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(now.Add(tokenTTL).Unix()))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.signToken(payload))
+}
+
+// signToken returns the signature of a token's payload
+func (s *Server) signToken(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.tokenKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// checkToken returns the ID of the account the token was issued to, or
+// ErrInvalidToken
+func (s *Server) checkToken(token string, now time.Time) (uuid.UUID, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil || len(payload) != 16+8 {
+		return uuid.Nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, s.signToken(payload)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if now.Unix() >= int64(binary.BigEndian.Uint64(payload[16:])) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	var id uuid.UUID
+	copy(id[:], payload)
+	return id, nil
+}
+
+// requestAccount returns the account whose token the request is authorized
+// with, or nil if it doesn't have one. Responds with an error if the token is
+// invalid, the account can't be retrieved or it has been banned.
+func (s *Server) requestAccount(w http.ResponseWriter, r *http.Request) (*Account, bool) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil, true
+	}
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		http.Error(w, "Authorization must be a bearer token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := s.checkToken(token, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	a, err := s.accounts.GetAccount(id)
+	if err == ErrAccountNotFound {
+		http.Error(w, ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !s.checkBanned(w, a.ID) {
+		return nil, false
+	}
+	return &a, true
+}
+
+// checkPlayerName responds with an error unless a player joining with the name
+// may use it. Names of registered accounts can only be used by their owners,
+// so ratings and results recorded under them belong to the account.
+func (s *Server) checkPlayerName(w http.ResponseWriter, name string) bool {
+	username, err := normalizeUsername(name)
+	if err != nil {
+		// Not a valid username, so no account can have it
+		return true
+	}
+	_, err = s.accounts.GetAccountByName(username)
+	if err == ErrAccountNotFound {
+		return true
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	http.Error(w, "Name '"+name+"' belongs to a registered player", http.StatusForbidden)
+	return false
+}
+
+// AccountRequest is the format of requests to register or log in. An email
+// address can be given when registering, for the server to send the player
+// reminders.
+type AccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+}
+
+// AccountResponse is the format of the response sent to clients when they
+// register, log in or request their account. The token is only sent when
+// registering or logging in, and is given as a bearer token to act as the
+// account.
+type AccountResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email,omitempty"`
+	Created  time.Time `json:"created"`
+	Token    string    `json:"token,omitempty"`
+}
+
+// decodeAccountRequest decodes the request body, responding with an error if
+// it is invalid
+func decodeAccountRequest(w http.ResponseWriter, r *http.Request) (AccountRequest, bool) {
+	var req AccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	} else if req.Username == "" || req.Password == "" {
+		http.Error(w, "Missing username or password", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// writeAccount responds with the account, along with the token if it isn't
+// empty
+func writeAccount(w http.ResponseWriter, a Account, token string, status int) {
+	resp, err := json.Marshal(AccountResponse{
+		ID:       a.ID,
+		Username: a.Username,
+		Email:    a.Email,
+		Created:  a.Created,
+		Token:    token,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+// registerHandler handles requests to register an account, responding with
+// a token for it
+func (s *Server) registerHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAccountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := newAccount(req.Username, req.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Email != "" {
+		if a.Email, err = normalizeEmail(req.Email); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err = s.accounts.CreateAccount(a); err == ErrUsernameTaken {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeAccount(w, a, s.issueToken(a.ID, time.Now()), http.StatusCreated)
+}
+
+// loginHandler handles requests to log in to an account, responding with a
+// token for it
+func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAccountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Unknown usernames and wrong passwords get the same error, after as long
+	// spent checking the password, so logging in can't be used to find which
+	// usernames are registered
+	const incorrect = "Incorrect username or password"
+	a, err := s.accounts.GetAccountByName(strings.ToLower(req.Username))
+	if err == ErrAccountNotFound {
+		bcrypt.CompareHashAndPassword(missingAccountHash(), []byte(req.Password))
+		http.Error(w, incorrect, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = bcrypt.CompareHashAndPassword(a.PasswordHash, []byte(req.Password)); err != nil {
+		http.Error(w, incorrect, http.StatusUnauthorized)
+		return
+	} else if !s.checkBanned(w, a.ID) {
+		return
+	}
+
+	writeAccount(w, a, s.issueToken(a.ID, time.Now()), http.StatusOK)
+}
+
+// requireAccount returns the account whose token the request is authorized
+// with, like requestAccount, but responds with an error if it doesn't have one
+func (s *Server) requireAccount(w http.ResponseWriter, r *http.Request) (*Account, bool) {
+	a, ok := s.requestAccount(w, r)
+	if ok && a == nil {
+		http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+		return nil, false
+	}
+	return a, ok
+}
+
+// accountHandler handles requests for the account the request is authorized
+// as
+func (s *Server) accountHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	writeAccount(w, *a, "", http.StatusOK)
+}
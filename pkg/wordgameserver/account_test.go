@@ -0,0 +1,155 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAccounts(t *testing.T) {
+	srv := newTestServer(t)
+
+	send := func(handler http.HandlerFunc, method, token string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			var err error
+			if payload, err = json.Marshal(body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req, err := http.NewRequest(method, "/", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(srv.registerHandler, "POST", "", AccountRequest{Username: "Ashley", Password: "hunter22"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Registering returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var registered AccountResponse
+	if err := json.NewDecoder(rr.Body).Decode(&registered); err != nil {
+		t.Fatal(err)
+	} else if registered.Username != "ashley" || registered.Token == "" {
+		t.Errorf("Registered %+v, expected username ashley with a token", registered)
+	}
+
+	failures := []struct {
+		name    string
+		handler http.HandlerFunc
+		req     AccountRequest
+		status  int
+	}{
+		{"taken username", srv.registerHandler, AccountRequest{Username: "ASHLEY", Password: "hunter22"}, http.StatusConflict},
+		{"short username", srv.registerHandler, AccountRequest{Username: "as", Password: "hunter22"}, http.StatusBadRequest},
+		{"username with spaces", srv.registerHandler, AccountRequest{Username: "ash ley", Password: "hunter22"}, http.StatusBadRequest},
+		{"short password", srv.registerHandler, AccountRequest{Username: "ashley2", Password: "hunter2"}, http.StatusBadRequest},
+		{"wrong password", srv.loginHandler, AccountRequest{Username: "ashley", Password: "hunter23"}, http.StatusUnauthorized},
+		{"unknown username", srv.loginHandler, AccountRequest{Username: "ashley2", Password: "hunter22"}, http.StatusUnauthorized},
+	}
+	for _, f := range failures {
+		if rr = send(f.handler, "POST", "", f.req); rr.Code != f.status {
+			t.Errorf("%v: returned status code %v, expected %v", f.name, rr.Code, f.status)
+		}
+	}
+
+	// Unknown usernames have a password checked against a hash as costly as
+	// those of registered accounts, and are refused the same way
+	if cost, err := bcrypt.Cost(missingAccountHash()); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("Missing account hash has cost %v (%v), expected %v", cost, err, bcrypt.DefaultCost)
+	}
+	wrong := send(srv.loginHandler, "POST", "", AccountRequest{Username: "ashley", Password: "hunter23"})
+	if unknown := send(srv.loginHandler, "POST", "", AccountRequest{Username: "ashley2", Password: "hunter22"}); unknown.Body.String() != wrong.Body.String() {
+		t.Errorf("Unknown username returned %q, expected %q like a wrong password", unknown.Body, wrong.Body)
+	}
+
+	rr = send(srv.loginHandler, "POST", "", AccountRequest{Username: "ASHLEY", Password: "hunter22"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Logging in returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var login AccountResponse
+	if err := json.NewDecoder(rr.Body).Decode(&login); err != nil {
+		t.Fatal(err)
+	} else if login.ID != registered.ID {
+		t.Errorf("Logged in to account %v, expected %v", login.ID, registered.ID)
+	}
+
+	rr = send(srv.accountHandler, "GET", login.Token, nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Getting the account returned status code %v, expected %v", rr.Code, http.StatusOK)
+	} else if strings.Contains(rr.Body.String(), "password") {
+		t.Error("Account response should not include the password hash")
+	}
+	expired := srv.issueToken(registered.ID, time.Now().Add(-tokenTTL))
+	for _, token := range []string{"", "garbage", login.Token + "x", expired} {
+		if rr = send(srv.accountHandler, "GET", token, nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Getting the account with token %q returned status code %v, expected %v", token, rr.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Players joining with a token are linked to the account, and guests
+	// can't take its name
+	rr = send(srv.createGameHandler, "POST", "", nil)
+	var created GeneralGameRequest
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	gameID := created.GameID
+	join := func(token, name string) *httptest.ResponseRecorder {
+		return send(srv.joinGameHandler, "POST", token, GeneralGameRequest{GameID: gameID, PlayerName: &name})
+	}
+	if rr = join("", "Ashley"); rr.Code != http.StatusForbidden {
+		t.Errorf("Guest joining as a registered player returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+	}
+	if rr = join(login.Token, "someone else"); rr.Code != http.StatusOK {
+		t.Fatalf("Joining with a token returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rr = join(login.Token, ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Joining a game twice with the same account returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	g, err := srv.games.Get(gameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Lock()
+	players := g.playerList()
+	g.Unlock()
+	if len(players) != 1 || players[0].Name != "ashley" || players[0].Account == nil || *players[0].Account != registered.ID {
+		t.Errorf("Game has players %+v, expected ashley linked to account %v", players, registered.ID)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	srv := newTestServer(t)
+	id := uuid.New()
+	now := time.Now()
+
+	token := srv.issueToken(id, now)
+	if got, err := srv.checkToken(token, now.Add(tokenTTL-time.Minute)); err != nil || got != id {
+		t.Errorf("Token was for account %v with error %v, expected %v", got, err, id)
+	}
+	if _, err := srv.checkToken(token, now.Add(tokenTTL)); err != ErrInvalidToken {
+		t.Errorf("Expired token returned %v, expected %v", err, ErrInvalidToken)
+	}
+
+	// Tokens from servers with another key aren't accepted
+	other := newTestServer(t)
+	if _, err := other.checkToken(token, now); err != ErrInvalidToken {
+		t.Errorf("Token from another server returned %v, expected %v", err, ErrInvalidToken)
+	}
+}
@@ -0,0 +1,356 @@
+package wordgameserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Statuses the admin API can list games by
+const (
+	adminStatusWaiting  = "waiting"  // games that haven't started
+	adminStatusActive   = "active"   // games being played
+	adminStatusFinished = "finished" // games that have ended
+)
+
+// AdminGame summarizes a game for the server's operators
+type AdminGame struct {
+	GameID       uuid.UUID  `json:"game_id"`
+	Status       string     `json:"status"`  // waiting, active or finished
+	Players      []string   `json:"players"` // names of the players, in turn order
+	Owner        string     `json:"owner,omitempty"`
+	Private      bool       `json:"private,omitempty"`
+	TournamentID *uuid.UUID `json:"tournament_id,omitempty"`
+	Moves        int        `json:"moves"`    // number of moves made
+	Events       int        `json:"events"`   // number of events recorded
+	Watchers     int        `json:"watchers"` // number of clients subscribed to updates
+	Controller   bool       `json:"controller"`
+	Created      time.Time  `json:"created"`
+	LastActivity time.Time  `json:"last_activity"`
+}
+
+// AdminGamesResponse is the format of the response sent to operators when
+// they list the server's games
+type AdminGamesResponse struct {
+	Games       []AdminGame `json:"games"`
+	Total       int         `json:"total"`       // number of games matching the filter, across every page
+	Controllers int         `json:"controllers"` // number of games with a controller running
+}
+
+// AdminPlayer is a player as operators see them, secrets and rack included
+type AdminPlayer struct {
+	ID       uuid.UUID  `json:"id"`
+	Name     string     `json:"name"`
+	Number   int        `json:"number"`
+	Account  *uuid.UUID `json:"account_id,omitempty"`
+	Bot      bool       `json:"bot,omitempty"`
+	BotLevel string     `json:"bot_level,omitempty"`
+	Rating   int        `json:"rating,omitempty"`
+	Score    int        `json:"score"`
+	Tiles    Letters    `json:"tiles"`
+	Skip     bool       `json:"skip,omitempty"`
+	Resigned bool       `json:"resigned,omitempty"`
+	Kicked   bool       `json:"kicked,omitempty"`
+	Clock    *float64   `json:"clock,omitempty"` // seconds left on the player's clock, if the game has clocks
+}
+
+// AdminGameDetail is the internal state of a game, as operators see it
+type AdminGameDetail struct {
+	Game          AdminGame     `json:"game"`
+	Players       []AdminPlayer `json:"players"`
+	Board         ScrabbleBoard `json:"board"`
+	Bag           Letters       `json:"bag"`
+	TurnCount     int           `json:"turn_count"`
+	TurnStarted   time.Time     `json:"turn_started"`
+	Winners       []int         `json:"winners,omitempty"`
+	Options       GameOptions   `json:"options"`
+	JoinCode      string        `json:"join_code,omitempty"`
+	Protected     bool          `json:"protected,omitempty"`      // true if a passphrase is needed to join
+	Webhooks      int           `json:"webhooks"`                 // number of URLs the game's events are posted to
+	KickVotes     map[int][]int `json:"kick_votes,omitempty"`     // numbers of the players who have voted to remove each player, by number
+	UndoRequested bool          `json:"undo_requested,omitempty"` // true while players are agreeing to undo the last move
+	Events        []Event       `json:"events"`
+}
+
+// adminRoutes registers the routes of the admin API
+func (s *Server) adminRoutes(r *mux.Router) {
+	r.HandleFunc("/health", s.adminHealthHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games", s.adminGamesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}", s.adminGameHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}", s.adminDeleteGameHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/games/{id}/end", s.adminEndGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/bans", s.listBansHandler).Methods(http.MethodGet)
+	r.HandleFunc("/bans/{player}", s.banHandler).Methods(http.MethodPut)
+	r.HandleFunc("/bans/{player}", s.unbanHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/puzzles", s.createPuzzleHandler).Methods(http.MethodPost)
+}
+
+// requireAdmin returns middleware that only lets requests through when they
+// are authorized with the configured admin token as a bearer token
+func requireAdmin(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				http.Error(w, "Invalid or missing admin token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// adminGame summarizes the game for operators. The game must be locked by the
+// caller.
+func adminGame(g *ScrabbleGame) AdminGame {
+	entry := AdminGame{
+		GameID:       g.ID,
+		Status:       adminStatusWaiting,
+		Players:      []string{},
+		Private:      g.Options.Private,
+		Moves:        len(g.history),
+		Events:       len(g.events),
+		Controller:   g.controllerRunning && !g.stopped(),
+		LastActivity: g.LastActivity,
+	}
+	if g.Finished {
+		entry.Status = adminStatusFinished
+	} else if g.Active {
+		entry.Status = adminStatusActive
+	}
+	for _, p := range g.playerList() {
+		entry.Players = append(entry.Players, p.Name)
+	}
+	if owner := g.owner(); owner != nil {
+		entry.Owner = owner.Name
+	}
+	if g.TournamentID != uuid.Nil {
+		id := g.TournamentID
+		entry.TournamentID = &id
+	}
+	if len(g.events) > 0 {
+		entry.Created = g.events[0].Time
+	}
+
+	g.watchMu.Lock()
+	entry.Watchers = len(g.watchers)
+	g.watchMu.Unlock()
+	return entry
+}
+
+// adminGameDetail describes the internal state of the game for operators. The
+// game must be locked by the caller.
+func adminGameDetail(g *ScrabbleGame) AdminGameDetail {
+	detail := AdminGameDetail{
+		Game:          adminGame(g),
+		Players:       []AdminPlayer{},
+		Board:         g.Board,
+		Bag:           Letters(append(TileBag{}, g.TileBag...)),
+		TurnCount:     g.TurnCount,
+		TurnStarted:   g.TurnStarted,
+		Winners:       g.Winners,
+		Options:       g.Options,
+		JoinCode:      g.JoinCode,
+		Protected:     g.passphraseHash != nil,
+		Webhooks:      len(g.webhooks),
+		UndoRequested: g.undoConsent != nil,
+		Events:        g.Events(),
+	}
+	for _, p := range g.playerList() {
+		ap := AdminPlayer{
+			ID:       p.ID,
+			Name:     p.Name,
+			Number:   p.Number,
+			Account:  p.Account,
+			Bot:      p.Bot,
+			BotLevel: p.BotLevel,
+			Rating:   p.Rating,
+			Score:    p.Score,
+			Tiles:    append(Letters{}, p.Tiles...),
+			Skip:     p.Skip,
+			Resigned: p.Resigned,
+			Kicked:   p.Kicked,
+		}
+		if g.Options.Clock > 0 {
+			clock := p.TimeLeft.Seconds()
+			ap.Clock = &clock
+		}
+		detail.Players = append(detail.Players, ap)
+	}
+	for target, voters := range g.kickVotes {
+		t, ok := g.Players[target]
+		if !ok {
+			continue
+		}
+		if detail.KickVotes == nil {
+			detail.KickVotes = make(map[int][]int)
+		}
+		for voter := range voters {
+			if v, ok := g.Players[voter]; ok {
+				detail.KickVotes[t.Number] = append(detail.KickVotes[t.Number], v.Number)
+			}
+		}
+		sort.Ints(detail.KickVotes[t.Number])
+	}
+	return detail
+}
+
+// writeAdminGame responds with the internal state of the game. The game must
+// be locked by the caller.
+func writeAdminGame(w http.ResponseWriter, g *ScrabbleGame) {
+	resp, err := json.Marshal(adminGameDetail(g))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// adminGamesHandler handles requests from operators to list every game on the
+// server, most recently active first. The status query parameter limits the
+// list to games that are waiting, active or finished. Pages of the list are
+// chosen with the limit and offset parameters.
+func (s *Server) adminGamesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, offset, ok := pageParams(w, query)
+	if !ok {
+		return
+	}
+	status := query.Get("status")
+	if status != "" && status != adminStatusWaiting && status != adminStatusActive && status != adminStatusFinished {
+		http.Error(w, "Status must be waiting, active or finished", http.StatusBadRequest)
+		return
+	}
+
+	list, err := s.games.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	games := []AdminGame{}
+	for _, g := range list {
+		g.Lock()
+		entry := adminGame(g)
+		g.Unlock()
+		if status == "" || entry.Status == status {
+			games = append(games, entry)
+		}
+	}
+
+	// Order most recently active first, breaking ties by ID so pages don't
+	// overlap
+	sort.Slice(games, func(i, j int) bool {
+		if !games[i].LastActivity.Equal(games[j].LastActivity) {
+			return games[i].LastActivity.After(games[j].LastActivity)
+		}
+		return games[i].GameID.String() < games[j].GameID.String()
+	})
+
+	start, end := page(len(games), limit, offset)
+	resp, err := json.Marshal(AdminGamesResponse{
+		Games:       games[start:end],
+		Total:       len(games),
+		Controllers: s.Controllers(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// adminGameHandler handles requests from operators for the internal state of
+// the game identified by the request's path
+func (s *Server) adminGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	writeAdminGame(w, g)
+}
+
+// endEarly ends the game as it stands, with the highest score among the
+// players still in it winning. The game is rated and reported to its
+// tournament as if it had finished. The game must be locked by the caller.
+func (sg *ScrabbleGame) endEarly() error {
+	if !sg.Active {
+		return errors.New("Game has not started")
+	} else if sg.Finished {
+		return errors.New("Game is over")
+	}
+	if err := sg.record(Event{Type: GameEnded}); err != nil {
+		return err
+	}
+	sg.broadcast(sg.playerList())
+	return nil
+}
+
+// adminEndGameHandler handles requests from operators to end the game
+// identified by the request's path, such as one abandoned by its players. It
+// responds with the game's internal state.
+func (s *Server) adminEndGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if err = g.endEarly(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = s.saveGame(g, w); err != nil {
+		return
+	}
+
+	// Nothing more can happen in the game, so its controller isn't needed
+	g.Stop()
+	writeAdminGame(w, g)
+}
+
+// adminDeleteGameHandler handles requests from operators to delete the game
+// identified by the request's path, whoever owns it and whether or not it has
+// finished
+func (s *Server) adminDeleteGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	if err = s.deleteGame(g, w); err != nil {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
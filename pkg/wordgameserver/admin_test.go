@@ -0,0 +1,185 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAPI(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminToken = "s3cret"
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			if payload, err = json.Marshal(body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req, err := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("GET", "/admin/games", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Listing games without a token returned status code %v, expected %v", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := send("GET", "/admin/games", "wrong", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Listing games with the wrong token returned status code %v, expected %v", rr.Code, http.StatusUnauthorized)
+	}
+
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	waiting := createScrabbleGame()
+	srv.games.Put(g)
+	srv.games.Put(waiting)
+	g.Lock()
+	if err = g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	rr := send("GET", "/admin/games?status=active", cfg.AdminToken, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var list AdminGamesResponse
+	if err = json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	} else if list.Total != 1 || list.Games[0].GameID != g.ID || len(list.Games[0].Players) != 2 {
+		t.Errorf("Listed %+v, expected only the active game", list)
+	}
+
+	// Operators see what players can't, such as every rack
+	rr = send("GET", "/admin/games/"+g.ID.String(), cfg.AdminToken, nil)
+	var detail AdminGameDetail
+	if err = json.NewDecoder(rr.Body).Decode(&detail); err != nil {
+		t.Fatal(err)
+	} else if len(detail.Players) != 2 || detail.Players[1].ID != ids[1] || len(detail.Players[1].Tiles) != maxTiles {
+		t.Errorf("Game has players %+v, expected their IDs and racks", detail.Players)
+	}
+
+	if rr = send("POST", "/admin/games/"+waiting.ID.String()+"/end", cfg.AdminToken, nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Ending a game that hasn't started returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	rr = send("POST", "/admin/games/"+g.ID.String()+"/end", cfg.AdminToken, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	if err = json.NewDecoder(rr.Body).Decode(&detail); err != nil {
+		t.Fatal(err)
+	} else if detail.Game.Status != adminStatusFinished || len(detail.Winners) != 2 {
+		t.Errorf("Ended game is %v with winners %v, expected it finished as a tie", detail.Game.Status, detail.Winners)
+	}
+
+	// Games ended early stay ended once restored from their events
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := DecodeGame(data, nil); err != nil {
+		t.Fatal(err)
+	} else if !d.Finished {
+		t.Error("Decoded game is no longer finished")
+	}
+
+	if rr = send("DELETE", "/admin/games/"+waiting.ID.String(), cfg.AdminToken, nil); rr.Code != http.StatusOK {
+		t.Errorf("Deleting a game returned status code %v, expected %v", rr.Code, http.StatusOK)
+	}
+	if _, err = srv.games.Get(waiting.ID); err != ErrGameNotFound {
+		t.Errorf("Deleted game is still stored: %v", err)
+	}
+}
+
+func TestAdminDisabled(t *testing.T) {
+	srv := newTestServer(t)
+	req, err := http.NewRequest("GET", "/admin/games", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Admin API without a token configured returned status code %v, expected %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestBans(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminToken = "s3cret"
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	creds := AccountRequest{Username: "ashley", Password: "hunter22"}
+	rr := send("POST", "/v2/accounts", "", creds)
+	var account AccountResponse
+	if err = json.NewDecoder(rr.Body).Decode(&account); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr = send("PUT", "/admin/bans/nobody", cfg.AdminToken, BanRequest{}); rr.Code != http.StatusNotFound {
+		t.Errorf("Banning an unknown player returned status code %v, expected %v", rr.Code, http.StatusNotFound)
+	}
+	if rr = send("PUT", "/admin/bans/ashley", cfg.AdminToken, BanRequest{Reason: "Spamming chat"}); rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	// Banned accounts can neither log in nor use tokens they already have
+	if rr = send("POST", "/v2/accounts/login", "", creds); rr.Code != http.StatusForbidden {
+		t.Errorf("Logging in while banned returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+	}
+	if rr = send("GET", "/v2/accounts/me", account.Token, nil); rr.Code != http.StatusForbidden {
+		t.Errorf("Using a token while banned returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+	}
+
+	rr = send("GET", "/admin/bans", cfg.AdminToken, nil)
+	var bans BanListResponse
+	if err = json.NewDecoder(rr.Body).Decode(&bans); err != nil {
+		t.Fatal(err)
+	} else if len(bans.Bans) != 1 || bans.Bans[0].Account != account.ID || bans.Bans[0].Reason != "Spamming chat" {
+		t.Errorf("Listed bans %+v, expected ashley's", bans.Bans)
+	}
+
+	if rr = send("DELETE", "/admin/bans/"+account.ID.String(), cfg.AdminToken, nil); rr.Code != http.StatusOK {
+		t.Errorf("Lifting a ban returned status code %v, expected %v", rr.Code, http.StatusOK)
+	}
+	if rr = send("POST", "/v2/accounts/login", "", creds); rr.Code != http.StatusOK {
+		t.Errorf("Logging in once the ban was lifted returned status code %v, expected %v", rr.Code, http.StatusOK)
+	}
+}
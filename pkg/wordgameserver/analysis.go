@@ -0,0 +1,180 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/google/uuid"
+)
+
+// WordGenerator is a WordValidator whose words can be compiled into a DAWG, so
+// the plays available from a rack can be found as well as checked. Only games
+// checked against one can be analysed.
+type WordGenerator interface {
+	dictionary.WordValidator
+	DAWG() *dictionary.DAWG // the words compiled, nil if they can't be
+}
+
+// GameAnalysis is how well each move of a finished game was made, compared
+// with the best play the move generator finds from the same rack
+type GameAnalysis struct {
+	GameID uuid.UUID      `json:"game_id"`
+	Moves  []MoveAnalysis `json:"moves"`
+}
+
+// MoveAnalysis compares a move with the best play that could have been made
+// instead. Plays are compared by equity, their score plus the value of the
+// tiles they leave on the rack, so the best play may score less than others
+// if it keeps better tiles.
+type MoveAnalysis struct {
+	Move       int       `json:"move"`           // number of the move in the game, from 1
+	Player     int       `json:"player"`         // number of the player who moved
+	Equity     float64   `json:"equity"`         // equity of the move made
+	Best       *Solution `json:"best,omitempty"` // play with the best equity, nil if there wasn't one
+	BestEquity float64   `json:"best_equity"`    // equity of the best play, or of the move made if there was no play
+	EquityLost float64   `json:"equity_lost"`    // equity given up by not making the best play
+
+	MissedBingo bool `json:"missed_bingo,omitempty"` // whether a bingo could have been played but wasn't
+}
+
+// AnalyzeGame replays a finished game, finding the plays available before each
+// move from the words given and reporting how much equity each move gave up
+// against the best of them. Resignations, and moves made before racks were
+// recorded, aren't analysed.
+func AnalyzeGame(r GameReplay, words *dictionary.DAWG) (GameAnalysis, error) {
+	v, err := lookupVariant(r.Options.Variant)
+	if err != nil {
+		return GameAnalysis{}, err
+	}
+	v = v.withTiles(r.Options.tileSet())
+
+	a := GameAnalysis{GameID: r.GameID, Moves: []MoveAnalysis{}}
+	board := v.boardFor(r.Options.Layout)
+	for i, m := range r.Moves {
+		if !m.Resign && len(m.Rack) > 0 {
+			a.Moves = append(a.Moves, analyzeMove(board, m, i, v, words))
+		}
+		if m.Retracted {
+			continue
+		} else if err := replayMove(board, m, i); err != nil {
+			return a, err
+		}
+	}
+	return a, nil
+}
+
+// analyzeMove compares the move, numbered by its index in the game, with the
+// plays available on the board before it was made
+func analyzeMove(board ScrabbleBoard, m Move, index int, v *variant, words *dictionary.DAWG) MoveAnalysis {
+	ma := MoveAnalysis{Move: index + 1, Player: m.Player}
+
+	// Swaps keep the tiles not put back, and passes and plays challenged off
+	// keep every tile
+	kept, score, bingo := m.Rack, 0, false
+	switch {
+	case m.Swap:
+		kept = leave(m.Rack, m.Swapped)
+	case !m.Pass && !m.Retracted:
+		played := make(Letters, len(m.Tiles))
+		for i, t := range m.Tiles {
+			played[i] = t.Letter
+			if t.Blank {
+				played[i] = ' '
+			}
+		}
+		kept = leave(m.Rack, played)
+		score, bingo = m.Score, len(played) == engine.MaxTiles
+	}
+	ma.Equity = float64(score) + engine.LeaveValue(kept)
+	ma.BestEquity = ma.Equity
+
+	best, equity, bingoAvailable := bestPlay(board, m.Rack, v, words)
+	if best != nil {
+		ma.Best, ma.BestEquity = best, equity
+	}
+	ma.MissedBingo = bingoAvailable && !bingo
+	ma.EquityLost = max(0, ma.BestEquity-ma.Equity)
+	return ma
+}
+
+// bestPlay returns the play with the best equity that can be made on the board
+// from the rack, or nil if there isn't one, and whether any play available
+// uses every tile of a full rack
+func bestPlay(board ScrabbleBoard, rack Letters, v *variant, words *dictionary.DAWG) (*Solution, float64, bool) {
+	var best *Solution
+	var bestEquity float64
+	bingo := false
+	for _, c := range board.Moves(rack, words, v.tiles, v.bingo) {
+		if len(c.Play.Tiles) == engine.MaxTiles {
+			bingo = true
+		}
+		if equity := float64(c.Score) + engine.LeaveValue(leave(rack, c.Play.Tiles)); best == nil || equity > bestEquity {
+			best = &Solution{
+				Play:  GamePlayRequest{StartPos: c.Play.StartPos, EndPos: c.Play.EndPos, Tiles: c.Play.Tiles, Blanks: c.Play.Blanks},
+				Words: c.Words,
+				Score: c.Score,
+			}
+			bestEquity = equity
+		}
+	}
+	return best, bestEquity, bingo
+}
+
+// leave returns the tiles left from the rack once the tiles given are taken out
+// of it, without changing the rack, which is shared with the game's history
+func leave(rack, tiles Letters) Letters {
+	left, _ := engine.RemoveTiles(append(Letters(nil), rack...), tiles)
+	return left
+}
+
+// gameAnalysisHandler handles requests to analyse a finished game, identified
+// by its path or the game_id query parameter, against the dictionary its words
+// were checked against
+func (s *Server) gameAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	finished := g.Finished
+	replay := g.replay()
+	validator := g.Validator
+	g.Unlock()
+	if !finished {
+		http.Error(w, "Game has not finished", http.StatusBadRequest)
+		return
+	}
+
+	var words *dictionary.DAWG
+	if wg, ok := validator.(WordGenerator); ok {
+		words = wg.DAWG()
+	}
+	if words == nil {
+		http.Error(w, "Game's dictionary can't be used to find plays", http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := AnalyzeGame(replay, words)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp, err := json.Marshal(analysis)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
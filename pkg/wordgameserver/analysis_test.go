@@ -0,0 +1,93 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGameAnalysisHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	analyze := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/game/analysis?game_id="+g.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.gameAnalysisHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := analyze().Code; c != http.StatusBadRequest {
+		t.Errorf("Analysing an unfinished game returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+
+	// The second player passes instead of playing DOGS or making CATS, and
+	// then both players passing ends the game
+	for _, id := range []uuid.UUID{ids[1], ids[0]} {
+		if err = g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := analyze()
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", c, http.StatusOK)
+	}
+	var a GameAnalysis
+	if err = json.NewDecoder(rr.Body).Decode(&a); err != nil {
+		t.Fatal(err)
+	} else if len(a.Moves) != 3 {
+		t.Fatalf("Analysed %v moves, expected 3", len(a.Moves))
+	}
+
+	if m := a.Moves[0]; m.Move != 1 || m.Player != 0 || m.Best == nil || m.Best.Score != 5 || m.EquityLost != 0 {
+		t.Errorf("First move analysed as %+v, expected CAT to be the best play", m)
+	}
+	if m := a.Moves[1]; m.Player != 1 || m.Best == nil || m.EquityLost <= 0 || m.BestEquity != m.Equity+m.EquityLost {
+		t.Errorf("Second move analysed as %+v, expected passing to lose equity", m)
+	} else if m.Best.Words[0] != "DOGS" && m.Best.Words[0] != "CATS" {
+		t.Errorf("Best play instead of passing formed %v, expected DOGS or CATS", m.Best.Words)
+	}
+
+	// The players' results include what they missed, once they have been
+	// analysed in the background
+	g.saving.Wait()
+	saved, err := srv.results.GetPlayerResults("ashley2")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(saved) != 1 {
+		t.Fatalf("Saved %v results for the second player, expected 1", len(saved))
+	}
+	if r, m := saved[0], a.Moves[1]; r.AnalyzedMoves != 1 || r.EquityLost != m.EquityLost || r.BiggestMissLoss != m.EquityLost ||
+		r.BiggestMiss != m.Best.Words[0] || r.MissedBingos != 0 {
+		t.Errorf("Saved result %+v, expected passing instead of %v to be analysed", r, m.Best.Words)
+	}
+
+	if r := g.History()[0].Rack; string(r) != "CATXXXX" {
+		t.Errorf("Analysis changed the first rack to %v, expected CATXXXX", r)
+	}
+
+	// Games whose words weren't checked can't be analysed
+	g.Validator = nil
+	if c := analyze().Code; c != http.StatusBadRequest {
+		t.Errorf("Analysing a game without a dictionary returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+}
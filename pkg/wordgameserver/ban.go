@@ -0,0 +1,196 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrBanNotFound is returned by a BanStore for an account that isn't banned
+var ErrBanNotFound = errors.New("Account is not banned")
+
+// ErrAccountBanned is returned when a banned account tries to log in or use
+// its token
+var ErrAccountBanned = errors.New("Account has been banned")
+
+// Ban stops an account from logging in or acting with its token, as decided
+// by the server's operators
+type Ban struct {
+	Account  uuid.UUID `json:"account_id"`
+	Username string    `json:"username"`
+	Reason   string    `json:"reason,omitempty"` // why the account was banned, for other operators
+	Created  time.Time `json:"created"`
+}
+
+// BanStore holds the accounts operators have banned. Implementations must be
+// safe for concurrent use. A GameStore that also implements BanStore is used
+// for bans by the server it is given to.
+type BanStore interface {
+	PutBan(b Ban) error                    // ban an account, replacing any earlier ban of it
+	GetBan(account uuid.UUID) (Ban, error) // retrieve the ban of an account, or ErrBanNotFound
+	DeleteBan(account uuid.UUID) error     // lift the ban of an account, or ErrBanNotFound
+	ListBans() ([]Ban, error)              // every ban, oldest first
+}
+
+// MemoryBanStore is the default BanStore, which keeps bans in memory for the
+// lifetime of the process
+type MemoryBanStore struct {
+	sync.Mutex
+	bans map[uuid.UUID]Ban
+}
+
+// NewMemoryBanStore creates an empty in-memory ban store
+func NewMemoryBanStore() *MemoryBanStore {
+	return &MemoryBanStore{bans: make(map[uuid.UUID]Ban)}
+}
+
+// PutBan bans the account
+func (ms *MemoryBanStore) PutBan(b Ban) error {
+	ms.Lock()
+	defer ms.Unlock()
+	ms.bans[b.Account] = b
+	return nil
+}
+
+// GetBan retrieves the ban of the account
+func (ms *MemoryBanStore) GetBan(account uuid.UUID) (Ban, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	b, ok := ms.bans[account]
+	if !ok {
+		return Ban{}, ErrBanNotFound
+	}
+	return b, nil
+}
+
+// DeleteBan lifts the ban of the account
+func (ms *MemoryBanStore) DeleteBan(account uuid.UUID) error {
+	ms.Lock()
+	defer ms.Unlock()
+	if _, ok := ms.bans[account]; !ok {
+		return ErrBanNotFound
+	}
+	delete(ms.bans, account)
+	return nil
+}
+
+// ListBans returns every ban, oldest first
+func (ms *MemoryBanStore) ListBans() ([]Ban, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	bans := make([]Ban, 0, len(ms.bans))
+	for _, b := range ms.bans {
+		bans = append(bans, b)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Created.Before(bans[j].Created)
+	})
+	return bans, nil
+}
+
+// checkBanned responds with an error if the account has been banned, or if
+// its bans can't be retrieved
+func (s *Server) checkBanned(w http.ResponseWriter, account uuid.UUID) bool {
+	_, err := s.bans.GetBan(account)
+	if err == ErrBanNotFound {
+		return true
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	http.Error(w, ErrAccountBanned.Error(), http.StatusForbidden)
+	return false
+}
+
+// BanRequest is the format of the request operators send to ban an account
+type BanRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// BanListResponse is the format of the response sent to operators when they
+// list the banned accounts
+type BanListResponse struct {
+	Bans []Ban `json:"bans"`
+}
+
+// banHandler handles requests from operators to ban the account identified by
+// the request's path, by its ID or username. The request body is optional and
+// may give the reason. Players already seated in games keep their seats, so
+// those games should be ended or deleted if they mustn't go on.
+func (s *Server) banHandler(w http.ResponseWriter, r *http.Request) {
+	var j BanRequest
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&j); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+
+	b := Ban{Account: a.ID, Username: a.Username, Reason: j.Reason, Created: time.Now()}
+	if err := s.bans.PutBan(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// unbanHandler handles requests from operators to lift the ban of the account
+// identified by the request's path
+func (s *Server) unbanHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.bans.DeleteBan(a.ID); err == ErrBanNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// listBansHandler handles requests from operators to list the banned
+// accounts, oldest ban first
+func (s *Server) listBansHandler(w http.ResponseWriter, r *http.Request) {
+	bans, err := s.bans.ListBans()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if bans == nil {
+		bans = []Ban{}
+	}
+
+	resp, err := json.Marshal(BanListResponse{Bans: bans})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
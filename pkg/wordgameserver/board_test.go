@@ -1,28 +1,73 @@
 package wordgameserver
 
 import (
+	"math/rand"
 	"reflect"
 	"testing"
 )
 
 func TestBoard(t *testing.T) {
-	occurrences := map[string]int{
-		"star":         1,
-		"doubleLetter": 24,
-		"doubleWord":   16,
-		"tripleLetter": 12,
-		"tripleWord":   8,
-		"plain":        164,
+	tests := []struct {
+		variant     *variant
+		occurrences map[string]int
+		tiles       int
+	}{
+		{standardVariant, map[string]int{
+			"star":         1,
+			"doubleLetter": 24,
+			"doubleWord":   16,
+			"tripleLetter": 12,
+			"tripleWord":   8,
+			"plain":        164,
+		}, 100},
+		{superVariant, map[string]int{
+			"star":            1,
+			"doubleLetter":    36,
+			"doubleWord":      40,
+			"tripleLetter":    20,
+			"tripleWord":      16,
+			"quadrupleLetter": 8,
+			"quadrupleWord":   4,
+			"plain":           316,
+		}, 200},
+		{wwfVariant, map[string]int{
+			"star":         1,
+			"doubleLetter": 24,
+			"doubleWord":   12,
+			"tripleLetter": 16,
+			"tripleWord":   8,
+			"plain":        164,
+		}, 104},
 	}
 
-	count := make(map[string]int)
-	for _, row := range initializedBoard {
-		for _, square := range row {
-			count[square.SquareType]++
+	for _, tt := range tests {
+		board := tt.variant.newBoard()
+		if len(board) != tt.variant.size {
+			t.Errorf("%v board has %v rows, expected %v", tt.variant.name, len(board), tt.variant.size)
+		}
+
+		count := make(map[string]int)
+		for _, row := range board {
+			if len(row) != tt.variant.size {
+				t.Errorf("%v board has a row of %v squares, expected %v", tt.variant.name, len(row), tt.variant.size)
+			}
+			for _, square := range row {
+				count[square.SquareType]++
+			}
+		}
+		if !reflect.DeepEqual(tt.occurrences, count) {
+			t.Errorf("%v board has square counts %v, expected %v", tt.variant.name, count, tt.occurrences)
+		}
+
+		if bag := tt.variant.newBag(rand.New(rand.NewSource(1))); len(bag) != tt.tiles {
+			t.Errorf("%v bag has %v tiles, expected %v", tt.variant.name, len(bag), tt.tiles)
 		}
 	}
 
-	if !reflect.DeepEqual(occurrences, count) {
-		t.Error("Scrabble board does not have correct square counts")
+	// Boards of different games don't share squares
+	a, b := NewBoard(), NewBoard()
+	a[0][0].Letter = 'A'
+	if b[0][0].Letter != 0 {
+		t.Error("Placing a tile on one board changed another")
 	}
 }
@@ -0,0 +1,140 @@
+package wordgameserver
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// BotStrategy chooses the moves made by computer players
+type BotStrategy interface {
+	// NextMove returns the play or swap to make from the bot's view of the
+	// game when it is the bot's turn, playing at the difficulty level
+	NextMove(state GameStateResponse, level string) GamePlayRequest
+
+	// Levels lists the difficulty levels bots can play at. An empty level
+	// is always allowed and plays at the strategy's default level.
+	Levels() []string
+}
+
+// validBotLevel reports whether the strategy can play at the difficulty level
+func validBotLevel(strategy BotStrategy, level string) bool {
+	if level == "" {
+		return true
+	}
+	for _, l := range strategy.Levels() {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// addBot adds a computer player to the game that plays at the difficulty
+// level. Bots without a name are numbered.
+func (sg *ScrabbleGame) addBot(name string, level string) (uuid.UUID, error) {
+	if name == "" {
+		bots := 1
+		for _, p := range sg.Players {
+			if p.Bot {
+				bots++
+			}
+		}
+		name = "Bot " + strconv.Itoa(bots)
+	}
+
+	return sg.join(Event{Name: name, Bot: true, BotLevel: level})
+}
+
+// addBots fills seats in the game with computer players
+func (sg *ScrabbleGame) addBots(count int, level string) error {
+	for i := 0; i < count; i++ {
+		if _, err := sg.addBot("", level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBots starts a goroutine for each bot in an active game to make its moves
+// using the strategy, unless they are already running. The game must be locked
+// by the caller.
+func (sg *ScrabbleGame) runBots(strategy BotStrategy) {
+	if !sg.Active || sg.botsRunning || strategy == nil {
+		return
+	}
+	sg.botsRunning = true
+
+	for _, p := range sg.Players {
+		if p.Bot {
+			go sg.runBot(p.ID, p.Number, p.BotLevel, strategy)
+		}
+	}
+}
+
+// runBot makes a bot's move whenever it is their turn, until the game stops
+func (sg *ScrabbleGame) runBot(playerID uuid.UUID, number int, level string, strategy BotStrategy) {
+	updates := sg.subscribe(playerID)
+	defer sg.unsubscribe(updates)
+
+	// The bot may have been started on its own turn, so act on the current
+	// state before waiting for changes
+	state, err := sg.request(GamePlayRequest{
+		GameID:   sg.ID,
+		PlayerID: playerID,
+	})
+	if err != nil {
+		return
+	}
+
+	for {
+		if state.Active && !state.Finished && !state.Paused && state.PlayerTurn == number {
+			sg.botMove(state, level, strategy)
+		}
+
+		var ok bool
+		select {
+		case state, ok = <-updates:
+			if !ok {
+				return
+			}
+		case <-sg.done:
+			return
+		}
+	}
+}
+
+// botMove makes the move chosen by the strategy, swapping every tile instead if
+// the move is rejected or passing if that fails too, and saves the game
+// afterwards
+func (sg *ScrabbleGame) botMove(state GameStateResponse, level string, strategy BotStrategy) {
+	move := strategy.NextMove(state, level)
+	move.GameID = sg.ID
+	move.PlayerID = state.PlayerID
+	move.Type = playRequest
+
+	_, err := sg.request(move)
+	if err != nil && err != ErrGameStopped {
+		_, err = sg.request(GamePlayRequest{
+			GameID:   sg.ID,
+			PlayerID: state.PlayerID,
+			Tiles:    state.RackLetters(),
+			Swap:     true,
+			Type:     playRequest,
+		})
+	}
+	if err != nil && err != ErrGameStopped {
+		_, err = sg.request(GamePlayRequest{
+			GameID:   sg.ID,
+			PlayerID: state.PlayerID,
+			Type:     passRequest,
+		})
+	}
+	if err != nil {
+		return
+	}
+
+	sg.Lock()
+	sg.persist()
+	sg.Unlock()
+}
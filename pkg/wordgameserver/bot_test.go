@@ -0,0 +1,121 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// swapBot is a BotStrategy that always swaps its first tile
+type swapBot struct{}
+
+func (swapBot) NextMove(state GameStateResponse, level string) GamePlayRequest {
+	return GamePlayRequest{
+		Tiles: state.RackLetters()[:1],
+		Swap:  true,
+	}
+}
+
+func (swapBot) Levels() []string {
+	return []string{"easy"}
+}
+
+func TestRunBots(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX")
+	if err := g.addBots(1, "easy"); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.runBots(swapBot{})
+	g.Unlock()
+
+	_, err := g.request(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+		Type:     playRequest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bot should take its turn straight after the player
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.Lock()
+		turns := len(g.history)
+		g.Unlock()
+
+		if turns == 2 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Bot did not move, %v moves made", turns)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if m := g.history[1]; !m.Swap || m.Player != 1 {
+		t.Errorf("Bot made move %+v, expected a swap by player 1", m)
+	} else if g.TurnCount%len(g.Players) != 0 {
+		t.Error("Turn should have passed back to the player")
+	}
+}
+
+func TestJoinGameHandlerBot(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv, err := NewServer(DefaultConfig(), nil, nil, swapBot{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.games.Put(newGame)
+
+	join := func(level string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(GeneralGameRequest{
+			GameID:   newGame.ID,
+			BotLevel: &level,
+		})
+		req, err := http.NewRequest("POST", "/game/join", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.joinGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := join("impossible"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Joined bot with unknown level, returned status code %v", rr.Code)
+	}
+
+	rr := join("easy")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	var j GeneralGameRequest
+	if err := json.NewDecoder(rr.Body).Decode(&j); err != nil {
+		t.Fatal(err)
+	} else if j.PlayerID != nil {
+		t.Error("Bot's player ID should not be returned")
+	}
+
+	newGame.Lock()
+	defer newGame.Unlock()
+	for _, p := range newGame.Players {
+		if !p.Bot || p.BotLevel != "easy" || p.Name != "Bot 1" {
+			t.Errorf("Added player %+v, expected easy bot named Bot 1", p)
+		}
+	}
+}
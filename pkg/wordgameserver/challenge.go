@@ -0,0 +1,144 @@
+package wordgameserver
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// playRecord holds everything needed to retract a play if it is successfully
+// challenged
+type playRecord struct {
+	PlayerID uuid.UUID          // player who made the play
+	Placed   []SquareCoordinate // squares the tiles were placed on
+	Played   Letters            // tiles taken from the player's hand
+	Drawn    Letters            // tiles dealt to the player afterwards
+	Words    []string           // words formed by the play
+	Score    int                // points awarded for the play
+	Time     time.Time          // when the play was made
+}
+
+// ChallengeResult describes the outcome of a challenge against a play
+type ChallengeResult struct {
+	Challenger   int      `json:"challenger"`              // number of the challenging player
+	Challenged   int      `json:"challenged"`              // number of the player whose play was challenged
+	Words        []string `json:"words"`                   // words formed by the challenged play
+	InvalidWords []string `json:"invalid_words,omitempty"` // words not found in the dictionary
+	Successful   bool     `json:"successful"`              // true if the play was retracted
+}
+
+// challengePlay adjudicates a challenge against the most recent play. If any
+// word formed is invalid, the play is retracted and the tiles are returned to
+// the player who made it. Otherwise the challenger loses their next turn.
+func (sg *ScrabbleGame) challengePlay(j GamePlayRequest) error {
+	lp := sg.lastPlay
+	window := time.Duration(sg.Options.ChallengeWindow) * time.Second
+
+	if window == 0 {
+		return errors.New("Challenges are not enabled for this game")
+	} else if sg.Validator == nil {
+		return errors.New("No dictionary available to adjudicate challenges")
+	} else if lp == nil {
+		return errors.New("No play available to challenge")
+	} else if lp.PlayerID == j.PlayerID {
+		return errors.New("Cannot challenge your own play")
+	} else if time.Since(lp.Time) > window {
+		return errors.New("Challenge window has closed")
+	}
+
+	challenger := sg.Players[j.PlayerID]
+	challenged := sg.Players[lp.PlayerID]
+
+	result := ChallengeResult{
+		Challenger: challenger.Number,
+		Challenged: challenged.Number,
+		Words:      lp.Words,
+	}
+	for _, w := range lp.Words {
+		if !sg.Validator.Valid(w) {
+			result.InvalidWords = append(result.InvalidWords, w)
+		}
+	}
+	result.Successful = len(result.InvalidWords) > 0
+
+	e := Event{Type: PlayChallenged, Player: challenger.ID, Challenge: &result}
+	if result.Successful {
+		e.Bag = sg.reshuffled(0, lp.Drawn)
+	}
+	return sg.record(e)
+}
+
+// applyChallenge retracts the last play if the challenge succeeded, otherwise
+// the challenger loses their next turn, or the game ends if the play went out
+func (sg *ScrabbleGame) applyChallenge(e Event) error {
+	lp := sg.lastPlay
+	if lp == nil || e.Challenge == nil {
+		return errors.New("No play available to challenge")
+	}
+	challenger := sg.Players[e.Player]
+
+	if e.Challenge.Successful {
+		if err := sg.retractPlay(lp, e.Bag); err != nil {
+			return err
+		}
+	} else if out := sg.playedOut(); out != nil {
+		sg.goOut(out)
+	} else if sg.playerList()[sg.TurnCount%len(sg.Players)] == challenger {
+		sg.advanceTurn(e.Time)
+	} else {
+		challenger.Skip = true
+	}
+
+	// Each play can only be challenged once
+	sg.lastPlay = nil
+	sg.lastChallenge = e.Challenge
+
+	return nil
+}
+
+// acceptDeadline returns when the challenge window of a play that went out
+// closes, which ends the game if the play hasn't been challenged or accepted
+// by then. There is no deadline unless the last play went out.
+func (sg *ScrabbleGame) acceptDeadline() (time.Time, bool) {
+	if sg.playedOut() == nil {
+		return time.Time{}, false
+	}
+	return sg.lastPlay.Time.Add(time.Duration(sg.Options.ChallengeWindow) * time.Second), true
+}
+
+// applyAccepted ends the game with the player who went out scoring the tiles
+// left in the other players' hands, now their play can no longer be
+// challenged. The current player's turn ends when the window closed.
+func (sg *ScrabbleGame) applyAccepted(e Event) {
+	out := sg.playedOut()
+	sg.lastPlay = nil
+	sg.chargeClock(sg.playerList()[sg.Current(sg.seats())], e.Time)
+	sg.TurnStarted = e.Time
+	if out != nil {
+		sg.goOut(out)
+	}
+}
+
+// retractPlay removes a play's tiles from the board and returns them to the
+// player's hand, putting the tiles they drew back in the bag, which leaves it
+// as given
+func (sg *ScrabbleGame) retractPlay(lp *playRecord, bag TileBag) error {
+	p := sg.Players[lp.PlayerID]
+
+	if err := removeTiles(p, lp.Drawn); err != nil {
+		return err
+	}
+	sg.TileBag = append(TileBag(nil), bag...)
+
+	for _, sc := range lp.Placed {
+		sg.Board.At(sc).Tile = Tile{}
+	}
+	p.Tiles = append(p.Tiles, lp.Played...)
+	p.Score -= lp.Score
+
+	// The last play is always the most recent move
+	sg.history[len(sg.history)-1].Retracted = true
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package wordgameserver
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+)
+
+func TestChallengeSuccessful(t *testing.T) {
+	g, ids := createTestGame(t, "TACXXXX", "DOGSXXX")
+	g.Options.ChallengeWindow = 30
+	bagSize := len(g.TileBag)
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 7},
+		EndPos:   SquareCoordinate{Row: 7, Col: 9},
+		Tiles:    Letters("TAC"),
+	})
+	if err != nil {
+		t.Fatalf("Invalid word should be accepted when challenges are enabled: %v", err)
+	}
+
+	if err = g.challengePlay(GamePlayRequest{PlayerID: ids[0]}); err == nil {
+		t.Error("Player should not be able to challenge their own play")
+	}
+
+	if err = g.challengePlay(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := g.lastChallenge; c == nil || !c.Successful {
+		t.Fatal("Challenge should have been successful")
+	} else if p := g.Players[ids[0]]; p.Score != 0 || len(p.Tiles) != maxTiles || !engine.HasTiles(p.Tiles, Letters("TAC")) {
+		t.Errorf("Challenged player should have their tiles back with no score, has %q with score %v",
+			p.Tiles, p.Score)
+	} else if !reflect.DeepEqual(g.Board, NewBoard()) {
+		t.Error("Challenged play should be removed from the board")
+	} else if len(g.TileBag) != bagSize {
+		t.Errorf("Tile bag has %v tiles, expected %v", len(g.TileBag), bagSize)
+	} else if g.TurnCount%len(g.Players) != 1 {
+		t.Error("Challenged player should lose their turn")
+	}
+
+	if err = g.challengePlay(GamePlayRequest{PlayerID: ids[1]}); err == nil {
+		t.Error("Play should only be challengeable once")
+	}
+}
+
+func TestChallengeUnsuccessful(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "DOGSXXX")
+	g.Options.ChallengeWindow = 30
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 7},
+		EndPos:   SquareCoordinate{Row: 7, Col: 9},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third player challenges, so they should miss their next turn
+	if err = g.challengePlay(GamePlayRequest{PlayerID: ids[2]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := g.lastChallenge; c == nil || c.Successful {
+		t.Fatal("Challenge should have been unsuccessful")
+	} else if g.Players[ids[0]].Score == 0 {
+		t.Error("Play should keep its score")
+	}
+
+	g.advanceTurn(time.Now())
+	if turn := g.TurnCount % len(g.Players); turn != 0 {
+		t.Errorf("Turn passed to player %v, expected challenger to be skipped", turn)
+	}
+}
+
+func TestChallengeWindow(t *testing.T) {
+	g, ids := createTestGame(t, "TACXXXX", "DOGSXXX")
+
+	if err := g.challengePlay(GamePlayRequest{PlayerID: ids[1]}); err == nil {
+		t.Error("Challenge should fail when challenges are disabled")
+	}
+
+	g.Options.ChallengeWindow = 30
+	if err := g.challengePlay(GamePlayRequest{PlayerID: ids[1]}); err == nil {
+		t.Error("Challenge should fail with no play made")
+	}
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 7},
+		EndPos:   SquareCoordinate{Row: 7, Col: 9},
+		Tiles:    Letters("TAC"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.lastPlay.Time = time.Now().Add(-time.Minute)
+	if err = g.challengePlay(GamePlayRequest{PlayerID: ids[1]}); err == nil {
+		t.Error("Challenge should fail after the window has closed")
+	}
+}
+
+func TestChallengeGoingOut(t *testing.T) {
+	g, ids := createTestGame(t, "CAT", "DOGS")
+	g.Options.ChallengeWindow = 30
+	g.TileBag = TileBag{}
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if g.Finished {
+		t.Fatal("Game finished before the play going out could be challenged")
+	}
+	score := g.Players[ids[0]].Score
+
+	// The play can only be challenged or accepted
+	err = g.executePlay(GamePlayRequest{
+		PlayerID: ids[1],
+		StartPos: SquareCoordinate{Row: 8, Col: 7},
+		EndPos:   SquareCoordinate{Row: 9, Col: 7},
+		Tiles:    Letters("DS"),
+	})
+	if err == nil {
+		t.Error("Player made a play after the last play went out")
+	}
+	if err = g.challengePlay(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	}
+
+	// DOGS is worth 6 points
+	if !g.Finished {
+		t.Fatal("Game didn't finish when the challenge of a play going out failed")
+	} else if p := g.Players[ids[0]]; p.Score != score+6 {
+		t.Errorf("Player who went out scored %v, expected %v", p.Score, score+6)
+	} else if p = g.Players[ids[1]]; p.Score != -6 {
+		t.Errorf("Player left with DOGS scored %v, expected -6", p.Score)
+	}
+}
+
+func TestChallengeWindowClosesAfterGoingOut(t *testing.T) {
+	g, ids := createTestGame(t, "CAT", "DOGS")
+	g.Options.ChallengeWindow = 1
+	g.TileBag = TileBag{}
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	state, err := g.request(GamePlayRequest{
+		PlayerID: ids[0],
+		Type:     playRequest,
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if state.Finished {
+		t.Fatal("Game finished before the play going out could be challenged")
+	}
+	g.Lock()
+	score := g.Players[ids[0]].Score
+	g.Unlock()
+
+	// Nobody challenges or passes, so the game ends once the window closes
+	deadline := time.Now().Add(5 * time.Second)
+	for !state.Finished && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		if state, err = g.request(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !state.Finished {
+		t.Fatal("Game didn't finish when the challenge window of a play going out closed")
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if out, left := g.Players[ids[0]].Score, g.Players[ids[1]].Score; out != score+6 || left != -6 {
+		t.Errorf("Scores are %v and %v, expected %v and -6", out, left, score+6)
+	}
+	if e := g.events[len(g.events)-1]; e.Type != PlayAccepted || e.Player != ids[0] {
+		t.Errorf("Last event is %v for %v, expected %v for the player who went out", e.Type, e.Player, PlayAccepted)
+	}
+}
@@ -0,0 +1,200 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Limits on a game's chat
+const (
+	maxChatLength = 500 // most characters in a message or a spectator's name
+	chatInState   = 50  // most recent messages sent with each game state
+)
+
+// ChatSpectators is the channel spectators' messages go to while the game is
+// being played, so players can't be coached. It is listed on its own until
+// the game finishes, when its messages join the public log.
+const ChatSpectators = "spectators"
+
+// ChatMessage is a message sent to a game's chat by one of its players or a
+// spectator
+type ChatMessage struct {
+	Player  *int      `json:"player,omitempty"` // number of the player who sent the message, unset for spectators
+	Name    string    `json:"name"`
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel,omitempty"` // ChatSpectators if a spectator posted it during the game, otherwise empty
+}
+
+// ChatRequest is the format of the request a client sends to post a message
+// to a game's chat. Players are identified by their ID, so their messages are
+// marked as theirs, while spectators give a name to post under.
+type ChatRequest struct {
+	GameID   uuid.UUID  `json:"game_id,omitempty"`
+	PlayerID *uuid.UUID `json:"player_id,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	Text     string     `json:"text"`
+}
+
+// ChatResponse is the format of the response sent to clients with the
+// messages posted to a game's chat
+type ChatResponse struct {
+	GameID   uuid.UUID     `json:"game_id"`
+	Channel  string        `json:"channel,omitempty"` // ChatSpectators if only spectators' messages were listed
+	Messages []ChatMessage `json:"messages"`
+}
+
+// postChat adds the message to the game's chat and sends every subscribed
+// player the new state, which carries the latest messages. Spectators'
+// messages go to their own channel until the game has finished, and players
+// aren't sent them. Chat is kept for as long as the game is, whether or not
+// it has finished.
+func (sg *ScrabbleGame) postChat(j ChatRequest, at time.Time) (ChatMessage, error) {
+	sg.Lock()
+	defer sg.Unlock()
+
+	m := ChatMessage{Name: strings.TrimSpace(j.Name), Text: strings.TrimSpace(j.Text), Time: at}
+	if j.PlayerID != nil {
+		p, ok := sg.Players[*j.PlayerID]
+		if !ok {
+			return m, errors.New("No player with that ID in game")
+		}
+		number := p.Number
+		m.Player, m.Name = &number, p.Name
+	} else if m.Name == "" || utf8.RuneCountInString(m.Name) > maxChatLength {
+		return m, errors.New("Spectators must give a name of up to " + strconv.Itoa(maxChatLength) + " characters")
+	}
+	if m.Text == "" || utf8.RuneCountInString(m.Text) > maxChatLength {
+		return m, errors.New("Message must be between 1 and " + strconv.Itoa(maxChatLength) + " characters")
+	}
+
+	if m.Player == nil && !sg.Finished {
+		m.Channel = ChatSpectators
+	}
+
+	sg.chat = append(sg.chat, m)
+	if m.Channel == "" && len(sg.Players) > 0 {
+		sg.broadcast(sg.playerList())
+	}
+	return m, nil
+}
+
+// chatChannel returns the messages posted to the channel, in order. The public
+// log, with no channel name, includes the spectators' channel once the game
+// has finished. The game must be locked by the caller.
+func (sg *ScrabbleGame) chatChannel(channel string) []ChatMessage {
+	chat := []ChatMessage{}
+	for _, m := range sg.chat {
+		if m.Channel == channel || (channel == "" && sg.Finished) {
+			chat = append(chat, m)
+		}
+	}
+	return chat
+}
+
+// recentChat returns the most recent messages posted to the game's public
+// chat, to be sent with its state
+func (sg *ScrabbleGame) recentChat() []ChatMessage {
+	chat := sg.chatChannel("")
+	if len(chat) > chatInState {
+		chat = chat[len(chat)-chatInState:]
+	}
+	return chat
+}
+
+// postChatHandler handles requests from players and spectators to post a
+// message to a game's chat, identified by the request's path or its game_id.
+// It responds with the message as posted.
+func (s *Server) postChatHandler(w http.ResponseWriter, r *http.Request) {
+	var j ChatRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+	m, err := g.postChat(j, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := json.Marshal(m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// getChatHandler handles requests for the messages posted to a game's chat,
+// identified by its path or the game_id query parameter. No player ID is
+// needed, so spectators can follow along. The public log is listed unless the
+// channel query parameter names the spectators' channel. Clients that have
+// already seen some messages can skip them with the after query parameter.
+func (s *Server) getChatHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	after := 0
+	if a := r.URL.Query().Get("after"); a != "" {
+		if after, err = strconv.Atoi(a); err != nil || after < 0 {
+			http.Error(w, "Invalid after parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	channel := r.URL.Query().Get("channel")
+	if channel != "" && channel != ChatSpectators {
+		http.Error(w, "Invalid channel parameter", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	messages := g.chatChannel(channel)
+	g.Unlock()
+	if after < len(messages) {
+		messages = messages[after:]
+	} else {
+		messages = []ChatMessage{}
+	}
+
+	resp, err := json.Marshal(ChatResponse{GameID: gameID, Channel: channel, Messages: messages})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -0,0 +1,146 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChat(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	defer g.Stop()
+
+	srv, err := NewServer(DefaultConfig(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.games.Put(g)
+
+	updates := g.subscribe(ids[1])
+	defer g.unsubscribe(updates)
+
+	post := func(path string, j ChatRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		b, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", path, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post("/v1/game/chat", ChatRequest{GameID: g.ID, PlayerID: &ids[0], Name: "someone else", Text: " good luck "})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var m ChatMessage
+	if err = json.NewDecoder(rr.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	} else if m.Player == nil || *m.Player != 0 || m.Name != "ashley1" || m.Text != "good luck" {
+		t.Errorf("Posted %+v, expected player 0's message under their own name", m)
+	}
+
+	// Subscribed players are sent the message with the game's state
+	select {
+	case state := <-updates:
+		if len(state.Chat) != 1 || state.Chat[0].Text != "good luck" {
+			t.Errorf("State has chat %+v, expected the message posted", state.Chat)
+		}
+	default:
+		t.Error("Posting a message should send players the new state")
+	}
+
+	path := "/v2/games/" + g.ID.String() + "/chat"
+	if rr = post(path, ChatRequest{Name: "kibitzer", Text: "nice board"}); rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	if rr = post(path, ChatRequest{Text: "who am I"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Message from a nameless spectator returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr = post(path, ChatRequest{PlayerID: &g.ID, Text: "hello"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Message from an unknown player returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr = post(path, ChatRequest{PlayerID: &ids[1], Text: "   "}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Empty message returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	get := func(query string) ChatResponse {
+		t.Helper()
+		req, err := http.NewRequest("GET", path+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Listing %v returned status code %v, expected %v. Error: %v", query, rr.Code, http.StatusOK, rr.Body)
+		}
+		var resp ChatResponse
+		if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Spectators have their own channel while the game is played, which
+	// players aren't sent
+	select {
+	case state := <-updates:
+		t.Errorf("Spectator's message sent players chat %+v", state.Chat)
+	default:
+	}
+	if resp := get("?after=1"); len(resp.Messages) != 0 {
+		t.Errorf("Listed %+v after the first message, expected none during the game", resp.Messages)
+	}
+	if resp := get("?channel=spectators"); len(resp.Messages) != 1 || resp.Messages[0].Name != "kibitzer" ||
+		resp.Messages[0].Channel != ChatSpectators || resp.Channel != ChatSpectators {
+		t.Errorf("Listed %+v in the spectators' channel, expected the spectator's message", resp)
+	}
+	req, err := http.NewRequest("GET", path+"?channel=players", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Listing an unknown channel returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Once the game has finished the spectators' messages join the public log,
+	// and spectators post to it directly
+	g.Lock()
+	g.Finished = true
+	g.Unlock()
+	if rr = post(path, ChatRequest{Name: "kibitzer", Text: "well played"}); rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	if resp := get("?after=1"); len(resp.Messages) != 2 || resp.Messages[0].Text != "nice board" || resp.Messages[1].Channel != "" {
+		t.Errorf("Listed %+v after the first message, expected both of the spectator's", resp.Messages)
+	}
+	if resp := get("?channel=spectators"); len(resp.Messages) != 1 {
+		t.Errorf("Listed %+v in the spectators' channel, expected only the message posted during the game", resp.Messages)
+	}
+
+	// Chat is kept when the game is saved
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+	if len(d.chat) != 3 || d.chat[1].Channel != ChatSpectators {
+		t.Errorf("Decoded game has chat %+v, expected 3 messages", d.chat)
+	}
+}
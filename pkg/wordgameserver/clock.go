@@ -0,0 +1,69 @@
+package wordgameserver
+
+import (
+	"time"
+)
+
+// clockPenaltyPoints is deducted for every minute or part of a minute a player
+// goes over their clock, when the game uses the penalty consequence
+const clockPenaltyPoints = 10
+
+// chargeClock takes the time spent on the current turn, up to the given time,
+// off the player's clock and adds the increment. The game must be locked by
+// the caller.
+func (sg *ScrabbleGame) chargeClock(p *Player, at time.Time) {
+	if sg.Options.Clock == 0 {
+		return
+	}
+	p.TimeLeft -= at.Sub(sg.TurnStarted)
+	p.TimeLeft += time.Duration(sg.Options.ClockIncrement) * time.Second
+}
+
+// timeLeft returns the time left on the player's clock, counting the turn in
+// progress up to when the game was paused if it is. The game must be locked by
+// the caller.
+func (sg *ScrabbleGame) timeLeft(p *Player, playerList []*Player) time.Duration {
+	left := p.TimeLeft
+	if sg.Active && !sg.Finished && playerList[sg.TurnCount%len(playerList)] == p {
+		if sg.paused {
+			left -= sg.pausedFor(sg.TurnStarted)
+		} else {
+			left -= time.Since(sg.TurnStarted)
+		}
+	}
+	return left
+}
+
+// clocks returns the seconds left on each player's clock in turn order, or nil
+// if the game doesn't use clocks. The game must be locked by the caller.
+func (sg *ScrabbleGame) clocks(playerList []*Player) []float64 {
+	if sg.Options.Clock == 0 {
+		return nil
+	}
+
+	c := make([]float64, len(playerList))
+	for i, p := range playerList {
+		c[i] = sg.timeLeft(p, playerList).Seconds()
+	}
+	return c
+}
+
+// clockDeadline returns when the current player's clock runs out, if running
+// out ends the game. The game must be locked by the caller.
+func (sg *ScrabbleGame) clockDeadline(cp *Player) (time.Time, bool) {
+	if sg.Options.Clock == 0 || sg.Options.OutOfTime == OutOfTimePenalty {
+		return time.Time{}, false
+	}
+	return sg.TurnStarted.Add(cp.TimeLeft), true
+}
+
+// clockPenalty returns the points lost for going over the clock by the time
+// left, which is negative once the clock has run out
+func clockPenalty(left time.Duration) int {
+	if left >= 0 {
+		return 0
+	}
+	over := -left
+	minutes := int((over + time.Minute - 1) / time.Minute)
+	return minutes * clockPenaltyPoints
+}
@@ -0,0 +1,99 @@
+package wordgameserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockPenalty(t *testing.T) {
+	tests := []struct {
+		left    time.Duration
+		penalty int
+	}{
+		{left: time.Minute, penalty: 0},
+		{left: 0, penalty: 0},
+		{left: -time.Second, penalty: 10},
+		{left: -time.Minute, penalty: 10},
+		{left: -61 * time.Second, penalty: 20},
+	}
+
+	for _, tc := range tests {
+		if p := clockPenalty(tc.left); p != tc.penalty {
+			t.Errorf("Penalty for %v left is %v, expected %v", tc.left, p, tc.penalty)
+		}
+	}
+}
+
+func TestClockIncrement(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Clock = 60
+	g.Options.ClockIncrement = 5
+	g.Active = true
+	for _, p := range g.Players {
+		p.TimeLeft = time.Minute
+	}
+
+	g.TurnStarted = time.Now().Add(-20 * time.Second)
+	g.advanceTurn(time.Now())
+
+	if left := g.Players[ids[0]].TimeLeft; left > 46*time.Second || left < 44*time.Second {
+		t.Errorf("Player has %v left, expected about 45s", left)
+	} else if left := g.Players[ids[1]].TimeLeft; left != time.Minute {
+		t.Errorf("Player who hasn't moved has %v left, expected 1m", left)
+	}
+
+	clocks := g.clocks(g.playerList())
+	if len(clocks) != 2 || clocks[1] > 60 || clocks[1] < 59 {
+		t.Errorf("Reported clocks %v, expected about 45 and 60 seconds", clocks)
+	}
+}
+
+func TestClockLoss(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Clock = 60
+	g.Active = true
+	for _, p := range g.Players {
+		p.TimeLeft = time.Minute
+	}
+	g.Players[ids[0]].Score = 50
+	playerList := g.playerList()
+
+	g.TurnStarted = time.Now().Add(-30 * time.Second)
+	g.expireTime(playerList)
+	if g.Finished {
+		t.Fatal("Game ended before the clock ran out")
+	}
+
+	g.TurnStarted = time.Now().Add(-time.Minute)
+	g.expireTime(playerList)
+	if !g.Finished {
+		t.Fatal("Game should end when a clock runs out")
+	} else if len(g.Winners) != 1 || g.Winners[0] != 1 {
+		t.Errorf("Winners are %v, expected player 1 despite the lower score", g.Winners)
+	}
+
+	go g.handleRequest(GamePlayRequest{PlayerID: ids[1], Type: playRequest, Swap: true}, playerList)
+	if state := <-g.Players[ids[1]].Play; state.Error == nil {
+		t.Error("Moves should not be allowed once the game is over")
+	}
+}
+
+func TestClockPenaltyAtEnd(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Clock = 60
+	g.Options.OutOfTime = OutOfTimePenalty
+	g.Players[ids[0]].Score = 50
+	g.Players[ids[0]].TimeLeft = -90 * time.Second
+	g.Players[ids[1]].Score = 35
+
+	if _, ok := g.nextDeadline(); ok {
+		t.Error("Clocks should not end the game with the penalty consequence")
+	}
+
+	g.endGame(nil)
+	if s := g.Players[ids[0]].Score; s != 30 {
+		t.Errorf("Player scored %v after penalty, expected 30", s)
+	} else if len(g.Winners) != 1 || g.Winners[0] != 1 {
+		t.Errorf("Winners are %v, expected player 1", g.Winners)
+	}
+}
@@ -0,0 +1,169 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// forwardedHeader marks requests one node has forwarded to another. They are
+// always served by the node they were sent to, so nodes that briefly disagree
+// about who is in the cluster can't pass a request back and forth.
+const forwardedHeader = "X-Wordgame-Forwarded"
+
+// ringReplicas is how many points each node has on the hash ring. More points
+// spread games more evenly between nodes.
+const ringReplicas = 100
+
+// hashRing assigns game IDs to nodes by consistent hashing, so adding or
+// removing a node only moves the games it gains or loses
+type hashRing struct {
+	points []uint32          // hashes of every node's points, in order
+	nodes  map[uint32]string // node each point belongs to
+}
+
+// newHashRing creates a ring with points for each of the nodes
+func newHashRing(nodes []string) *hashRing {
+	h := &hashRing{nodes: make(map[uint32]string, len(nodes)*ringReplicas)}
+	for _, node := range nodes {
+		for i := 0; i < ringReplicas; i++ {
+			point := ringHash([]byte(node + "#" + strconv.Itoa(i)))
+			h.points = append(h.points, point)
+			h.nodes[point] = node
+		}
+	}
+	sort.Slice(h.points, func(i, j int) bool { return h.points[i] < h.points[j] })
+	return h
+}
+
+// owner returns the node that owns the game with the ID, which is the node of
+// the first point at or after its hash
+func (h *hashRing) owner(id uuid.UUID) string {
+	hash := ringHash(id[:])
+	i := sort.Search(len(h.points), func(i int) bool { return h.points[i] >= hash })
+	if i == len(h.points) {
+		i = 0
+	}
+	return h.nodes[h.points[i]]
+}
+
+// ringHash places the data on the ring
+func ringHash(data []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(data)
+	return h.Sum32()
+}
+
+// cluster routes requests for games to the nodes that own them. A nil cluster
+// is a server running on its own, which owns every game.
+type cluster struct {
+	self    string
+	ring    *hashRing
+	proxies map[string]*httputil.ReverseProxy // forwards requests to each of the other nodes
+}
+
+// newCluster creates the cluster configured, or returns nil if no nodes are
+// configured
+func newCluster(cfg ClusterConfig) (*cluster, error) {
+	if len(cfg.Nodes) == 0 {
+		return nil, nil
+	}
+
+	c := &cluster{
+		self:    cfg.Self,
+		ring:    newHashRing(cfg.Nodes),
+		proxies: make(map[string]*httputil.ReverseProxy),
+	}
+	for _, node := range cfg.Nodes {
+		if node == cfg.Self {
+			continue
+		}
+		u, err := url.Parse(node)
+		if err != nil {
+			return nil, errors.New("Invalid cluster node URL '" + node + "'")
+		}
+		c.proxies[node] = httputil.NewSingleHostReverseProxy(u)
+	}
+	return c, nil
+}
+
+// owns returns whether the game with the ID belongs to this node
+func (c *cluster) owns(id uuid.UUID) bool {
+	return c == nil || c.ring.owner(id) == c.self
+}
+
+// claim gives a game that has just been created an ID this node owns, so it
+// is served where it was created. The game must not have been saved yet.
+func (c *cluster) claim(g *ScrabbleGame) {
+	for !c.owns(g.ID) {
+		g.ID = uuid.New()
+	}
+}
+
+// forward sends the request to the node that owns the game with the ID and
+// relays its response, returning whether it did. Requests for games this node
+// owns, and those already forwarded by another node, are left to be served
+// here.
+func (c *cluster) forward(w http.ResponseWriter, r *http.Request, id uuid.UUID) bool {
+	if c.owns(id) || r.Header.Get(forwardedHeader) != "" {
+		return false
+	}
+	r.Header.Set(forwardedHeader, c.self)
+	c.proxies[c.ring.owner(id)].ServeHTTP(w, r)
+	return true
+}
+
+// routeGames forwards requests for games owned by other nodes to them. Games
+// are identified by the ID in the request's path, its game_id query parameter
+// or the game_id of its JSON body, as the route they match expects. Requests
+// that identify a game some other way are forwarded by their handlers.
+func routeGames(c *cluster) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := requestGameID(r)
+			if err != nil && r.Body != nil && r.Body != http.NoBody {
+				body, readErr := ioutil.ReadAll(r.Body)
+				r.Body.Close()
+				if readErr != nil {
+					http.Error(w, readErr.Error(), http.StatusBadRequest)
+					return
+				}
+				setRequestBody(r, body)
+
+				var j struct {
+					GameID uuid.UUID `json:"game_id"`
+				}
+				if json.Unmarshal(body, &j) == nil && j.GameID != uuid.Nil {
+					id, err = j.GameID, nil
+				}
+			}
+
+			if err == nil && c.forward(w, r, id) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setRequestBody replaces the body of the request, so it can be read again or
+// forwarded
+func setRequestBody(r *http.Request, body []byte) {
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+}
@@ -0,0 +1,130 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHashRing(t *testing.T) {
+	nodes := []string{"http://a:8080", "http://b:8080", "http://c:8080"}
+	ring := newHashRing(nodes)
+	smaller := newHashRing(nodes[:2])
+
+	owned := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		id := uuid.New()
+		owner := ring.owner(id)
+		if ring.owner(id) != owner {
+			t.Fatal("Owner of a game changed")
+		}
+		owned[owner]++
+
+		// Only the games of a node that leaves are moved
+		if owner != nodes[2] && smaller.owner(id) != owner {
+			t.Fatalf("Game moved from %v to %v when another node left", owner, smaller.owner(id))
+		}
+	}
+	for _, node := range nodes {
+		if owned[node] < 500 {
+			t.Errorf("Node %v owns %v of 3000 games, expected a fairer share", node, owned[node])
+		}
+	}
+}
+
+func TestClusterRouting(t *testing.T) {
+	// Each node counts the requests forwarded to it by the other
+	var forwarded [2]atomic.Int32
+	servers := make([]*httptest.Server, 2)
+	nodes := make([]string, 2)
+	for i := range servers {
+		servers[i] = httptest.NewUnstartedServer(nil)
+		nodes[i] = "http://" + servers[i].Listener.Addr().String()
+		defer servers[i].Close()
+	}
+
+	store := NewMemoryGameStore()
+	srvs := make([]*Server, 2)
+	for i := range servers {
+		cfg := DefaultConfig()
+		cfg.Store = StoreConfig{Backend: StoreRedis, Redis: RedisConfig{Addr: "localhost:6379"}}
+		cfg.Cluster = ClusterConfig{Self: nodes[i], Nodes: nodes}
+		var err error
+		if srvs[i], err = NewServer(cfg, nil, store, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		handler, count := srvs[i].Handler(), &forwarded[i]
+		servers[i].Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(forwardedHeader) != "" {
+				count.Add(1)
+			}
+			handler.ServeHTTP(w, r)
+		})
+		servers[i].Start()
+	}
+
+	post := func(node int, path string, body interface{}) *http.Response {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(servers[node].URL+path, "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Games are owned by the node they are created on
+	resp := post(0, "/v2/games", map[string]interface{}{"options": map[string]interface{}{"private": true}})
+	var created GeneralGameRequest
+	err := json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v", resp.StatusCode, http.StatusCreated)
+	} else if owner := srvs[0].cluster.ring.owner(created.GameID); owner != nodes[0] {
+		t.Fatalf("Game created on %v is owned by %v", nodes[0], owner)
+	}
+
+	// Requests for it made to the other node are served by its owner, whether
+	// they name it in their path, body or join code
+	resp = post(1, "/v2/games/"+created.GameID.String()+"/players", map[string]string{"player_name": "ashley1"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		t.Errorf("Join by path returned status code %v", resp.StatusCode)
+	}
+	resp = post(1, "/game/join", map[string]interface{}{"game_id": created.GameID, "player_name": "ashley2"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Join by body returned status code %v", resp.StatusCode)
+	}
+	resp = post(1, "/v2/games/join", map[string]interface{}{"join_code": *created.JoinCode, "player_name": "ashley3"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Join by code returned status code %v", resp.StatusCode)
+	}
+	if n := forwarded[0].Load(); n != 3 {
+		t.Errorf("Owner was forwarded %v requests, expected 3", n)
+	} else if n = forwarded[1].Load(); n != 0 {
+		t.Errorf("Other node was forwarded %v requests, expected none", n)
+	}
+
+	g, err := store.Get(created.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Lock()
+	players := len(g.Players)
+	g.Unlock()
+	if players != 3 {
+		t.Errorf("Game has %v players, expected 3", players)
+	}
+}
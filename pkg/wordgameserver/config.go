@@ -0,0 +1,283 @@
+package wordgameserver
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of a server. It can be loaded from a YAML file,
+// with any setting overridden by the environment variable named in its env
+// tag.
+type Config struct {
+	BindAddr        string            `yaml:"bind_addr" env:"WORDGAME_BIND_ADDR"`               // address for the server to listen on
+	TLS             TLSFiles          `yaml:"tls"`                                              // certificates to serve HTTPS with, if any
+	MaxGames        int               `yaml:"max_games" env:"WORDGAME_MAX_GAMES"`               // most games the server holds at once, 0 for no limit
+	MaxPlayers      int               `yaml:"max_players" env:"WORDGAME_MAX_PLAYERS"`           // most players, including bots, in each game
+	IdleTTL         time.Duration     `yaml:"idle_ttl" env:"WORDGAME_IDLE_TTL"`                 // how long a game can go without activity before it is removed, 0 keeps games forever
+	ShutdownTimeout time.Duration     `yaml:"shutdown_timeout" env:"WORDGAME_SHUTDOWN_TIMEOUT"` // how long in-flight requests are given to finish when shutting down
+	RequestTimeout  time.Duration     `yaml:"request_timeout" env:"WORDGAME_REQUEST_TIMEOUT"`   // how long a request waits for a game to respond, 0 for no limit
+	Dictionary      string            `yaml:"dictionary" env:"WORDGAME_DICTIONARY"`             // word list used to validate plays, such as TWL or SOWPODS
+	Dictionaries    map[string]string `yaml:"dictionaries"`                                     // word lists for games played in languages other than English, by language code
+	Lexicons        map[string]string `yaml:"lexicons"`                                         // word lists games can choose to check words against instead, by name
+	Definitions     string            `yaml:"definitions" env:"WORDGAME_DEFINITIONS"`           // file of what words mean, shown when words are looked up, if any
+	LayoutDir       string            `yaml:"layout_dir" env:"WORDGAME_LAYOUT_DIR"`             // directory of JSON board layouts games can choose by name, if any
+	TileSetDir      string            `yaml:"tile_set_dir" env:"WORDGAME_TILE_SET_DIR"`         // directory of JSON tile sets games can choose by name, if any
+	Store           StoreConfig       `yaml:"store"`                                            // where games are kept
+	Snapshots       SnapshotConfig    `yaml:"snapshots"`                                        // where games are copied to disk, so the memory store survives restarts
+	Cluster         ClusterConfig     `yaml:"cluster"`                                          // other servers sharing the store, if any
+	AccountSecret   string            `yaml:"account_secret" env:"WORDGAME_ACCOUNT_SECRET"`     // key login tokens and invites are signed with, which only last until a restart if empty
+	Push            PushConfig        `yaml:"push"`                                             // services players' devices are sent notifications through, if any
+	Email           EmailConfig       `yaml:"email"`                                            // mail server invitations and reminders are sent through, if any
+	JoinURL         string            `yaml:"join_url" env:"WORDGAME_JOIN_URL"`                 // page of the app that opens games, which links add the game or an invite to as a query parameter
+	AdminToken      string            `yaml:"admin_token" env:"WORDGAME_ADMIN_TOKEN"`           // bearer token operators use for the admin API, which is disabled if empty
+}
+
+// TLSFiles are the PEM files the server's TLS configuration is loaded from.
+// TLS is only used if a certificate is given.
+type TLSFiles struct {
+	Cert     string `yaml:"cert" env:"WORDGAME_TLS_CERT"`           // certificate to serve HTTPS with
+	Key      string `yaml:"key" env:"WORDGAME_TLS_KEY"`             // private key of the certificate
+	ClientCA string `yaml:"client_ca" env:"WORDGAME_TLS_CLIENT_CA"` // authorities that clients must present a certificate signed by, if any
+}
+
+// StoreConfig chooses the backend games are stored in. Stores are created by
+// the program starting the server, since they are packages of their own.
+type StoreConfig struct {
+	Backend  string         `yaml:"backend" env:"WORDGAME_STORE"` // StoreMemory, StoreRedis or StorePostgres
+	Redis    RedisConfig    `yaml:"redis"`
+	Postgres PostgresConfig `yaml:"postgres"`
+}
+
+// SnapshotConfig has games snapshotted to files in a directory while the
+// server runs, and restored from them when it starts. Snapshots are only taken
+// if a directory is given.
+type SnapshotConfig struct {
+	Dir      string        `yaml:"dir" env:"WORDGAME_SNAPSHOT_DIR"`           // directory of the snapshots, one file per game
+	Interval time.Duration `yaml:"interval" env:"WORDGAME_SNAPSHOT_INTERVAL"` // how often games are snapshotted, 0 only when the server stops
+}
+
+// ClusterConfig lets several servers share a store behind a load balancer.
+// Each game is owned by one node, chosen by hashing its ID, and requests for it
+// are forwarded to that node, so its controller, timers and watchers are all in
+// one place. Servers run on their own if no nodes are given.
+type ClusterConfig struct {
+	Self  string   `yaml:"self" env:"WORDGAME_CLUSTER_SELF"`   // URL the other nodes reach this one at
+	Nodes []string `yaml:"nodes" env:"WORDGAME_CLUSTER_NODES"` // URLs of every node including this one, comma separated in the environment
+}
+
+// Backends games can be stored in
+const (
+	StoreMemory   = "memory"   // games only last as long as the process
+	StoreRedis    = "redis"    // games are kept in Redis
+	StorePostgres = "postgres" // games are kept in PostgreSQL
+)
+
+// RedisConfig is used by the Redis store backend
+type RedisConfig struct {
+	Addr     string        `yaml:"addr" env:"WORDGAME_REDIS_ADDR"`           // host:port of the Redis server
+	Password string        `yaml:"password" env:"WORDGAME_REDIS_PASSWORD"`   // password for the Redis server
+	PoolSize int           `yaml:"pool_size" env:"WORDGAME_REDIS_POOL_SIZE"` // maximum number of connections, 0 uses the default
+	TTL      time.Duration `yaml:"ttl" env:"WORDGAME_REDIS_TTL"`             // how long games are kept after their last change, 0 keeps them forever
+}
+
+// PostgresConfig is used by the PostgreSQL store backend
+type PostgresConfig struct {
+	DSN string `yaml:"dsn" env:"WORDGAME_POSTGRES_DSN"` // connection string of the database
+}
+
+// PushConfig has the credentials of the services push notifications are sent
+// through. Each service is only used if it is configured. Providers are
+// created by the program starting the server, since they are a package of
+// their own.
+type PushConfig struct {
+	FCM     FCMConfig     `yaml:"fcm"`
+	APNs    APNsConfig    `yaml:"apns"`
+	WebPush WebPushConfig `yaml:"webpush"`
+}
+
+// FCMConfig is used to send notifications through Firebase Cloud Messaging
+type FCMConfig struct {
+	Credentials string `yaml:"credentials" env:"WORDGAME_FCM_CREDENTIALS"` // JSON key file of a service account allowed to send messages
+}
+
+// APNsConfig is used to send notifications through the Apple Push Notification
+// service
+type APNsConfig struct {
+	Key     string `yaml:"key" env:"WORDGAME_APNS_KEY"`         // .p8 file of the token signing key
+	KeyID   string `yaml:"key_id" env:"WORDGAME_APNS_KEY_ID"`   // ID of the signing key
+	TeamID  string `yaml:"team_id" env:"WORDGAME_APNS_TEAM_ID"` // ID of the team the key belongs to
+	Topic   string `yaml:"topic" env:"WORDGAME_APNS_TOPIC"`     // bundle ID of the app
+	Sandbox bool   `yaml:"sandbox"`                             // true to send to development builds of the app
+}
+
+// WebPushConfig is used to send notifications to browsers
+type WebPushConfig struct {
+	PrivateKey string `yaml:"private_key" env:"WORDGAME_WEBPUSH_PRIVATE_KEY"` // base64url encoded VAPID private key
+	Subject    string `yaml:"subject" env:"WORDGAME_WEBPUSH_SUBJECT"`         // mailto: or https: URL push services can contact the operator at
+}
+
+// EmailConfig is used to send players invitations and reminders by email.
+// Email is only sent if an SMTP server is given.
+type EmailConfig struct {
+	SMTPAddr      string        `yaml:"smtp_addr" env:"WORDGAME_SMTP_ADDR"`           // host:port of the SMTP server
+	Username      string        `yaml:"username" env:"WORDGAME_SMTP_USERNAME"`        // user to authenticate to the SMTP server as, if it needs one
+	Password      string        `yaml:"password" env:"WORDGAME_SMTP_PASSWORD"`        // password for the SMTP server
+	From          string        `yaml:"from" env:"WORDGAME_EMAIL_FROM"`               // address email is sent from
+	ReminderDelay time.Duration `yaml:"reminder_delay" env:"WORDGAME_REMINDER_DELAY"` // how long a turn goes before the player is reminded, 0 sends no reminders
+}
+
+// DefaultConfig returns the configuration used for any setting that isn't
+// given
+func DefaultConfig() Config {
+	return Config{
+		BindAddr:        ":8080",
+		MaxPlayers:      maxPlayers,
+		IdleTTL:         24 * time.Hour,
+		ShutdownTimeout: shutdownTimeout,
+		RequestTimeout:  requestTimeout,
+		Store:           StoreConfig{Backend: StoreMemory},
+		Snapshots:       SnapshotConfig{Interval: snapshotInterval},
+	}
+}
+
+// LoadConfig reads the configuration from the YAML file at the path, unless it
+// is empty, then applies any overrides from the environment. Settings missing
+// from both keep their defaults. The configuration isn't validated, so more
+// overrides can be applied to it first.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return cfg, err
+		}
+		defer f.Close()
+
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		if err = dec.Decode(&cfg); err != nil && err != io.EOF {
+			return cfg, errors.New("Invalid config file " + path + ": " + err.Error())
+		}
+	}
+
+	return cfg, applyEnv(reflect.ValueOf(&cfg).Elem())
+}
+
+// applyEnv sets the fields of the struct whose environment variables are set,
+// including those of nested structs
+func applyEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		f, field := v.Type().Field(i), v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := f.Tag.Get("env")
+		value, ok := os.LookupEnv(name)
+		if name == "" || !ok {
+			continue
+		}
+
+		switch {
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return errors.New("Invalid duration in " + name + ": " + value)
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid integer in " + name + ": " + value)
+			}
+			field.SetInt(int64(n))
+		case field.Kind() == reflect.String:
+			field.SetString(value)
+		case field.Type() == reflect.TypeOf([]string(nil)):
+			field.Set(reflect.ValueOf(strings.Split(value, ",")))
+		}
+	}
+	return nil
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	switch {
+	case c.BindAddr == "":
+		return errors.New("Missing bind address")
+	case c.MaxGames < 0:
+		return errors.New("Maximum number of games can't be negative")
+	case c.MaxPlayers < 2 || c.MaxPlayers > maxPlayers:
+		return errors.New("Maximum number of players must be between 2 and " + strconv.Itoa(maxPlayers))
+	case c.IdleTTL < 0 || c.ShutdownTimeout < 0 || c.RequestTimeout < 0 || c.Store.Redis.TTL < 0 || c.Email.ReminderDelay < 0 || c.Snapshots.Interval < 0:
+		return errors.New("Durations can't be negative")
+	case (c.TLS.Cert == "") != (c.TLS.Key == ""):
+		return errors.New("TLS requires both a certificate and a key")
+	case c.TLS.ClientCA != "" && c.TLS.Cert == "":
+		return errors.New("Verifying client certificates requires TLS")
+	}
+
+	for code := range c.Dictionaries {
+		if ts, err := lookupLanguage(code); err != nil {
+			return err
+		} else if ts == nil {
+			return errors.New("The English word list is set by the dictionary setting")
+		}
+	}
+	for name := range c.Lexicons {
+		if name == "" || name == LexiconCustom {
+			return errors.New("Lexicons must have a name other than '" + LexiconCustom + "'")
+		}
+	}
+
+	if c.Push.APNs.Key != "" && (c.Push.APNs.KeyID == "" || c.Push.APNs.TeamID == "" || c.Push.APNs.Topic == "") {
+		return errors.New("APNs requires a key ID, team ID and topic")
+	} else if c.Push.WebPush.PrivateKey != "" && c.Push.WebPush.Subject == "" {
+		return errors.New("Web Push requires a subject")
+	} else if c.Email.SMTPAddr != "" && (c.Email.From == "" || c.JoinURL == "") {
+		return errors.New("Email requires a from address and join URL")
+	}
+
+	if len(c.Cluster.Nodes) > 0 {
+		if c.Store.Backend == StoreMemory {
+			return errors.New("Clustering requires a shared store")
+		}
+		self := false
+		for _, node := range c.Cluster.Nodes {
+			if u, err := url.Parse(node); err != nil || u.Scheme == "" || u.Host == "" {
+				return errors.New("Invalid cluster node URL '" + node + "'")
+			}
+			self = self || node == c.Cluster.Self
+		}
+		if !self {
+			return errors.New("Cluster nodes must include this node's URL")
+		}
+	}
+
+	switch c.Store.Backend {
+	case StoreMemory:
+	case StoreRedis:
+		if c.Store.Redis.Addr == "" {
+			return errors.New("Redis store requires an address")
+		}
+	case StorePostgres:
+		if c.Store.Postgres.DSN == "" {
+			return errors.New("PostgreSQL store requires a connection string")
+		}
+	default:
+		return errors.New("Unknown store backend '" + c.Store.Backend + "'")
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+bind_addr: ":9090"
+max_games: 100
+idle_ttl: 2h
+store:
+  backend: redis
+  redis:
+    addr: localhost:6379
+    ttl: 168h
+cluster:
+  self: http://a:8080
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The environment overrides the file
+	t.Setenv("WORDGAME_MAX_PLAYERS", "2")
+	t.Setenv("WORDGAME_REDIS_ADDR", "redis:6379")
+	t.Setenv("WORDGAME_CLUSTER_NODES", "http://a:8080,http://b:8080")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultConfig()
+	want.BindAddr = ":9090"
+	want.MaxGames = 100
+	want.MaxPlayers = 2
+	want.IdleTTL = 2 * time.Hour
+	want.Store = StoreConfig{
+		Backend: StoreRedis,
+		Redis:   RedisConfig{Addr: "redis:6379", TTL: 168 * time.Hour},
+	}
+	want.Cluster = ClusterConfig{Self: "http://a:8080", Nodes: []string{"http://a:8080", "http://b:8080"}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Loaded config %+v, expected %+v", cfg, want)
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Errorf("Loaded config is invalid: %v", err)
+	}
+
+	if err = os.WriteFile(path, []byte("bind_adr: \":9090\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadConfig(path); err == nil {
+		t.Error("Loaded config with an unknown setting")
+	}
+
+	t.Setenv("WORDGAME_IDLE_TTL", "soon")
+	if _, err = LoadConfig(""); err == nil {
+		t.Error("Loaded config with an invalid duration in the environment")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []func(c *Config){
+		func(c *Config) { c.BindAddr = "" },
+		func(c *Config) { c.MaxGames = -1 },
+		func(c *Config) { c.MaxPlayers = 1 },
+		func(c *Config) { c.MaxPlayers = maxPlayers + 1 },
+		func(c *Config) { c.IdleTTL = -time.Second },
+		func(c *Config) { c.TLS.Cert = "server.crt" },
+		func(c *Config) { c.TLS.ClientCA = "ca.crt" },
+		func(c *Config) { c.Store.Backend = "mongodb" },
+		func(c *Config) { c.Store.Backend = StoreRedis },
+		func(c *Config) { c.Store.Backend = StorePostgres },
+		func(c *Config) { c.Dictionaries = map[string]string{"xx": "words.txt"} },
+		func(c *Config) { c.Dictionaries = map[string]string{LanguageEnglish: "twl.txt"} },
+		func(c *Config) { c.Lexicons = map[string]string{LexiconCustom: "words.txt"} },
+		func(c *Config) { c.Push.APNs.Key = "AuthKey.p8" },
+		func(c *Config) { c.Push.WebPush.PrivateKey = "vapid" },
+		func(c *Config) { c.Email.SMTPAddr = "smtp.example.com:587" },
+		func(c *Config) { c.Email.ReminderDelay = -time.Hour },
+		func(c *Config) { c.Snapshots.Interval = -time.Second },
+		func(c *Config) { c.Cluster = ClusterConfig{Self: "http://a:8080", Nodes: []string{"http://a:8080"}} },
+		func(c *Config) {
+			c.Store = StoreConfig{Backend: StoreRedis, Redis: RedisConfig{Addr: "redis:6379"}}
+			c.Cluster = ClusterConfig{Self: "http://c:8080", Nodes: []string{"http://a:8080", "http://b:8080"}}
+		},
+		func(c *Config) {
+			c.Store = StoreConfig{Backend: StoreRedis, Redis: RedisConfig{Addr: "redis:6379"}}
+			c.Cluster = ClusterConfig{Self: "a:8080", Nodes: []string{"a:8080"}}
+		},
+	}
+
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("Default config is invalid: %v", err)
+	}
+	for i, change := range tests {
+		cfg := DefaultConfig()
+		change(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Config %v %+v passed validation", i, cfg)
+		}
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxGames = 1
+	cfg.MaxPlayers = 2
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/game/create", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := create()
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Handler returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var game GeneralGameRequest
+	if err := json.NewDecoder(rr.Body).Decode(&game); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr = create(); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Creating a game over the limit returned status code %v, expected %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	for i, code := range []int{http.StatusOK, http.StatusOK, http.StatusBadRequest} {
+		name := "ashley" + string(rune('1'+i))
+		payload, err := json.Marshal(GeneralGameRequest{GameID: game.GameID, PlayerName: &name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/join", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(srv.joinGameHandler).ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Errorf("Joining as player %v returned status code %v, expected %v", i+1, rr.Code, code)
+		}
+	}
+}
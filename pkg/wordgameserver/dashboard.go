@@ -0,0 +1,198 @@
+package wordgameserver
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// dashboardPage is the admin dashboard, which asks for the admin token and
+// polls the admin API with it
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// maxRecentErrors is how many server errors are kept for the dashboard
+const maxRecentErrors = 50
+
+// maxErrorMessage is how much of an error response is kept for the dashboard
+const maxErrorMessage = 200
+
+// ServerError is a request the server failed to handle, as shown to operators
+type ServerError struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	Route   string    `json:"route"`
+	Status  int       `json:"status"`
+	Message string    `json:"message"` // start of the response sent to the client
+}
+
+// errorLog keeps the most recent server errors, oldest first
+type errorLog struct {
+	mu     sync.Mutex
+	errors []ServerError
+}
+
+// add keeps the error, dropping the oldest once the log is full
+func (l *errorLog) add(e ServerError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.errors) == maxRecentErrors {
+		l.errors = append(l.errors[:0], l.errors[1:]...)
+	}
+	l.errors = append(l.errors, e)
+}
+
+// recent returns the errors kept, most recent first
+func (l *errorLog) recent() []ServerError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]ServerError, len(l.errors))
+	for i, e := range l.errors {
+		recent[len(l.errors)-1-i] = e
+	}
+	return recent
+}
+
+// errorRecorder remembers the start of a response once it is known to be a
+// server error
+type errorRecorder struct {
+	*statusRecorder
+	body []byte
+}
+
+func (er *errorRecorder) Write(b []byte) (int, error) {
+	n, err := er.statusRecorder.Write(b)
+	if er.status >= 500 && len(er.body) < maxErrorMessage {
+		er.body = append(er.body, b[:min(n, maxErrorMessage-len(er.body))]...)
+	}
+	return n, err
+}
+
+// recordErrors returns middleware that keeps every request answered with a
+// server error in the log, for operators to see on the dashboard
+func recordErrors(l *errorLog) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			er := &errorRecorder{statusRecorder: &statusRecorder{ResponseWriter: w}}
+			next.ServeHTTP(er, r)
+			if er.status < 500 {
+				return
+			}
+
+			route := r.URL.Path
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+			l.add(ServerError{
+				Time:    time.Now(),
+				Method:  r.Method,
+				Route:   route,
+				Status:  er.status,
+				Message: strings.TrimSpace(string(er.body)),
+			})
+		})
+	}
+}
+
+// BusyGame is a game with requests waiting for its controller
+type BusyGame struct {
+	GameID   uuid.UUID `json:"game_id"`
+	Queued   int       `json:"queued"`   // number of requests waiting
+	Capacity int       `json:"capacity"` // number of requests that can wait before more are refused
+}
+
+// AdminHealth is the format of the response sent to operators when they ask
+// how the server is doing
+type AdminHealth struct {
+	Started         time.Time     `json:"started"`
+	Goroutines      int           `json:"goroutines"`
+	Controllers     int           `json:"controllers"` // number of games with a controller running
+	Games           int           `json:"games"`
+	WaitingGames    int           `json:"waiting_games"`
+	ActiveGames     int           `json:"active_games"`
+	FinishedGames   int           `json:"finished_games"`
+	Players         int           `json:"players"`          // players still in active games, not counting bots
+	Watchers        int           `json:"watchers"`         // clients subscribed to updates
+	StalledWatchers int           `json:"stalled_watchers"` // subscribers yet to pick up their last update, who miss any sent meanwhile
+	BusyGames       []BusyGame    `json:"busy_games"`
+	RecentErrors    []ServerError `json:"recent_errors"` // most recent first
+}
+
+// adminHealthHandler handles requests from operators for how the server is
+// doing: its goroutines and games, the health of the channels its games use
+// and the errors it has responded with most recently
+func (s *Server) adminHealthHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := s.games.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	health := AdminHealth{
+		Started:      s.started,
+		Goroutines:   runtime.NumGoroutine(),
+		Controllers:  s.Controllers(),
+		Games:        len(list),
+		BusyGames:    []BusyGame{},
+		RecentErrors: s.recentErrors.recent(),
+	}
+	for _, g := range list {
+		g.Lock()
+		switch {
+		case g.Finished:
+			health.FinishedGames++
+		case g.Active:
+			health.ActiveGames++
+			for _, p := range g.Players {
+				if !p.Bot && !p.Resigned {
+					health.Players++
+				}
+			}
+		default:
+			health.WaitingGames++
+		}
+		g.Unlock()
+
+		if queued := len(g.Action); queued > 0 {
+			health.BusyGames = append(health.BusyGames, BusyGame{GameID: g.ID, Queued: queued, Capacity: cap(g.Action)})
+		}
+
+		g.watchMu.Lock()
+		health.Watchers += len(g.watchers)
+		for ch := range g.watchers {
+			if len(ch) == cap(ch) {
+				health.StalledWatchers++
+			}
+		}
+		g.watchMu.Unlock()
+	}
+
+	resp, err := json.Marshal(health)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// dashboardHandler serves the admin dashboard. The page itself holds nothing
+// secret, so it is served to anyone, and only shows anything once it is given
+// the admin token.
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(dashboardPage)
+}
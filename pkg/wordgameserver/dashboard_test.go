@@ -0,0 +1,98 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminToken = "s3cret"
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The page is served to anyone, as it only shows what the token fetches
+	req, err := http.NewRequest("GET", "/admin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", rr.Code, http.StatusOK)
+	} else if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") || !strings.Contains(rr.Body.String(), "/health") {
+		t.Error("Dashboard page wasn't served")
+	}
+
+	g, _ := createTestGame(t, "CATXXXX", "DOGSXXX")
+	srv.games.Put(g)
+	g.Lock()
+	if err = g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+	srv.recentErrors.add(ServerError{Method: "POST", Route: "/v2/games", Status: http.StatusInternalServerError, Message: "Disk full"})
+
+	req, err = http.NewRequest("GET", "/admin/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.AdminToken)
+	rr = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var health AdminHealth
+	if err = json.NewDecoder(rr.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	} else if health.ActiveGames != 1 || health.Players != 2 || health.Goroutines == 0 {
+		t.Errorf("Health is %+v, expected one active game of two players", health)
+	} else if len(health.RecentErrors) != 1 || health.RecentErrors[0].Message != "Disk full" {
+		t.Errorf("Recent errors are %+v, expected the one added", health.RecentErrors)
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	var l errorLog
+	h := recordErrors(&l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "Failed to save game "+r.URL.Query().Get("fail"), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+	}))
+
+	serve := func(target string) {
+		req, err := http.NewRequest("POST", target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Client errors aren't the server's problem
+	serve("/game/play")
+	if errs := l.recent(); len(errs) != 0 {
+		t.Errorf("Kept %+v, expected no errors", errs)
+	}
+
+	for i := 0; i < maxRecentErrors+5; i++ {
+		serve("/game/play?fail=" + strconv.Itoa(i))
+	}
+	errs := l.recent()
+	if len(errs) != maxRecentErrors {
+		t.Fatalf("Kept %v errors, expected %v", len(errs), maxRecentErrors)
+	}
+	last := "Failed to save game " + strconv.Itoa(maxRecentErrors+4)
+	if errs[0].Message != last || errs[0].Status != http.StatusInternalServerError {
+		t.Errorf("Most recent error is %+v, expected %q", errs[0], last)
+	}
+}
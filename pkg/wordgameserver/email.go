@@ -0,0 +1,326 @@
+package wordgameserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxInvitations is the most addresses a player can invite at once
+const maxInvitations = 10
+
+// Mailer sends email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	SendMail(to, subject, body string) error // send a plain text message to the address
+}
+
+// SMTPMailer is the Mailer used when the server is configured with an SMTP
+// server
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a mailer that sends email through the configured SMTP
+// server, authenticating if it has a username
+func NewSMTPMailer(cfg EmailConfig) *SMTPMailer {
+	m := &SMTPMailer{addr: cfg.SMTPAddr, from: cfg.From}
+	if cfg.Username != "" {
+		host, _, _ := strings.Cut(cfg.SMTPAddr, ":")
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return m
+}
+
+// SendMail sends the message to the address
+func (m *SMTPMailer) SendMail(to, subject, body string) error {
+	// Names players chose end up in subjects, so they can't be allowed to
+	// start new headers
+	subject = strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, subject)
+
+	msg := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg))
+}
+
+// SetMailer has the server send invitations and reminders with the mailer,
+// instead of through the configured SMTP server. It must be called before the
+// server handles any requests.
+func (s *Server) SetMailer(m Mailer) {
+	s.mailer = m
+}
+
+// normalizeEmail checks that the address is a bare email address, returning
+// it without any surrounding space
+func normalizeEmail(address string) (string, error) {
+	a, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil || a.Name != "" || a.Address != strings.TrimSpace(address) {
+		return "", errors.New("Invalid email address '" + address + "'")
+	}
+	return a.Address, nil
+}
+
+// gameLink returns the link to the game on the app's join page, which has its
+// join code if it is private since those games can't be joined by ID. The game
+// must be locked by the caller.
+func (s *Server) gameLink(g *ScrabbleGame) string {
+	u, err := url.Parse(s.cfg.JoinURL)
+	if err != nil {
+		return s.cfg.JoinURL
+	}
+	q := u.Query()
+	if g.JoinCode != "" {
+		q.Set("join_code", g.JoinCode)
+	} else {
+		q.Set("game_id", g.ID.String())
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// InvitationRequest is the format of the request a game's owner sends to
+// invite people to join it by email
+type InvitationRequest struct {
+	GameID   uuid.UUID `json:"game_id,omitempty"`
+	PlayerID uuid.UUID `json:"player_id"`
+	Emails   []string  `json:"emails"`
+	Message  string    `json:"message,omitempty"` // note from the owner included in the email
+}
+
+// InvitationResponse is the format of the response sent to clients once
+// invitations are on their way
+type InvitationResponse struct {
+	GameID  uuid.UUID `json:"game_id"`
+	Link    string    `json:"link"`    // link the invitations give to join the game
+	Invited []string  `json:"invited"` // addresses invited, without duplicates
+}
+
+// inviteHandler handles requests from the owner of a game, identified by the
+// request's path or its game_id, to invite people to join it by email. The
+// emails are sent in the background, so the response only says who they are
+// being sent to.
+func (s *Server) inviteHandler(w http.ResponseWriter, r *http.Request) {
+	var j InvitationRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	if s.mailer == nil {
+		http.Error(w, "Server can't send email", http.StatusBadRequest)
+		return
+	}
+	var invited []string
+	seen := make(map[string]bool)
+	for _, e := range j.Emails {
+		address, err := normalizeEmail(e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !seen[strings.ToLower(address)] {
+			seen[strings.ToLower(address)] = true
+			invited = append(invited, address)
+		}
+	}
+	if len(invited) == 0 || len(invited) > maxInvitations {
+		http.Error(w, "Must invite between 1 and "+strconv.Itoa(maxInvitations)+" addresses", http.StatusBadRequest)
+		return
+	} else if len(j.Message) > maxChatLength {
+		http.Error(w, "Message can be at most "+strconv.Itoa(maxChatLength)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	p, ok := g.Players[j.PlayerID]
+	owner, started, link := ok && p == g.owner(), g.Active, s.gameLink(g)
+	g.Unlock()
+	if !ok {
+		http.Error(w, "No player with that ID in game", http.StatusBadRequest)
+		return
+	} else if !owner {
+		http.Error(w, "Only the game's owner can invite players", http.StatusForbidden)
+		return
+	} else if started {
+		http.Error(w, "Game has already started", http.StatusBadRequest)
+		return
+	}
+
+	body := p.Name + " has invited you to play a word game with them.\n\n"
+	if msg := strings.TrimSpace(j.Message); msg != "" {
+		body += msg + "\n\n"
+	}
+	body += "Join the game at " + link + "\n"
+	go func() {
+		for _, address := range invited {
+			if err := s.mailer.SendMail(address, p.Name+" invited you to a word game", body); err != nil {
+				log.Printf("Failed to send invitation to game %v: %v", j.GameID, err)
+			}
+		}
+	}()
+
+	resp, err := json.Marshal(InvitationResponse{GameID: j.GameID, Link: link, Invited: invited})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(resp)
+}
+
+// reminder is an email due to a player whose turn has waited too long
+type reminder struct {
+	account     uuid.UUID
+	turnStarted time.Time
+	opponents   []string
+	link        string
+}
+
+// remindIdlePlayers periodically emails players whose turn has waited longer
+// than the delay, until the context is cancelled
+func (s *Server) remindIdlePlayers(ctx context.Context, delay time.Duration) {
+	// Check often enough that reminders aren't sent much later than the delay
+	interval := delay / 10
+	if interval < time.Second {
+		interval = time.Second
+	} else if interval > time.Hour {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reminded := make(map[uuid.UUID]time.Time)
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sendReminders(delay, reminded, time.Now()); err != nil {
+				log.Printf("Failed to send reminders: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendReminders emails every player linked to an account whose turn has waited
+// longer than the delay. Players are reminded once a turn, so reminded holds
+// when the turn each game's player was last reminded of began, and games that
+// no longer exist are dropped from it.
+func (s *Server) sendReminders(delay time.Duration, reminded map[uuid.UUID]time.Time, now time.Time) error {
+	list, err := s.games.List()
+	if err != nil {
+		return err
+	}
+
+	exists := make(map[uuid.UUID]bool)
+	for _, g := range list {
+		exists[g.ID] = true
+		if !s.cluster.owns(g.ID) {
+			// The node that owns the game reminds its players
+			continue
+		}
+
+		g.Lock()
+		r, ok := g.reminderDue(delay, now)
+		if ok {
+			r.link = s.gameLink(g)
+		}
+		g.Unlock()
+
+		if !ok || reminded[g.ID].Equal(r.turnStarted) {
+			continue
+		}
+		reminded[g.ID] = r.turnStarted
+		if err = s.remind(r); err != nil {
+			log.Printf("Failed to send reminder for game %v: %v", g.ID, err)
+		}
+	}
+
+	for id := range reminded {
+		if !exists[id] {
+			delete(reminded, id)
+		}
+	}
+	return nil
+}
+
+// reminderDue returns the reminder due to the player whose turn it is, if it
+// has waited longer than the delay and they are linked to an account. Games
+// whose turns run out before the delay has passed are played too quickly to
+// need reminders. The game must be locked by the caller.
+func (sg *ScrabbleGame) reminderDue(delay time.Duration, now time.Time) (reminder, bool) {
+	if !sg.Active || sg.Finished || sg.paused || len(sg.Players) == 0 || now.Sub(sg.TurnStarted) < delay {
+		return reminder{}, false
+	}
+	if deadline, ok := sg.nextDeadline(); ok && deadline.Before(sg.TurnStarted.Add(delay)) {
+		return reminder{}, false
+	}
+
+	playerList := sg.playerList()
+	cp := playerList[sg.TurnCount%len(playerList)]
+	if cp.Account == nil || cp.Bot {
+		return reminder{}, false
+	}
+	r := reminder{account: *cp.Account, turnStarted: sg.TurnStarted}
+	for _, p := range playerList {
+		if p != cp {
+			r.opponents = append(r.opponents, p.Name)
+		}
+	}
+	return r, true
+}
+
+// remind emails the reminder to its account, if it has an email address and
+// wants reminders
+func (s *Server) remind(r reminder) error {
+	a, err := s.accounts.GetAccount(r.account)
+	if err != nil || a.Email == "" {
+		return err
+	}
+	prefs, err := s.notifications.GetPreferences(a.ID)
+	if err != nil || !prefs.wants(NotifyReminder) {
+		return err
+	}
+
+	body := "It has been your turn since " + r.turnStarted.UTC().Format(time.RFC1123) +
+		" in your game against " + strings.Join(r.opponents, ", ") + ".\n\n" +
+		"Take your turn at " + r.link + "\n"
+	return s.mailer.SendMail(a.Email, "Your turn is waiting", body)
+}
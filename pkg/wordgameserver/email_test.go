@@ -0,0 +1,174 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sentMail is an email sent through a fakeMailer
+type sentMail struct {
+	to, subject, body string
+}
+
+// fakeMailer records the email sent through it
+type fakeMailer struct {
+	sent chan sentMail
+}
+
+func (fm *fakeMailer) SendMail(to, subject, body string) error {
+	fm.sent <- sentMail{to, subject, body}
+	return nil
+}
+
+func TestInvitations(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.JoinURL = "https://wordgame.example.com/join"
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	srv.games.Put(g)
+
+	invite := func(j InvitationRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		b, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games/"+g.ID.String()+"/invitations", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	j := InvitationRequest{PlayerID: ids[0], Emails: []string{"friend@example.com"}}
+	if rr := invite(j); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invitation from a server without email returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	mailer := &fakeMailer{sent: make(chan sentMail, 10)}
+	srv.SetMailer(mailer)
+
+	failures := []struct {
+		name   string
+		req    InvitationRequest
+		status int
+	}{
+		{"invalid address", InvitationRequest{PlayerID: ids[0], Emails: []string{"Friend <friend@example.com>"}}, http.StatusBadRequest},
+		{"no addresses", InvitationRequest{PlayerID: ids[0]}, http.StatusBadRequest},
+		{"not the creator", InvitationRequest{PlayerID: ids[1], Emails: j.Emails}, http.StatusForbidden},
+		{"unknown player", InvitationRequest{PlayerID: g.ID, Emails: j.Emails}, http.StatusBadRequest},
+	}
+	for _, f := range failures {
+		if rr := invite(f.req); rr.Code != f.status {
+			t.Errorf("%v: returned status code %v, expected %v", f.name, rr.Code, f.status)
+		}
+	}
+
+	j.Emails = append(j.Emails, " FRIEND@example.com", "other@example.com")
+	j.Message = "Fancy a game?"
+	rr := invite(j)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusAccepted, rr.Body)
+	}
+	var resp InvitationResponse
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Invited) != 2 || resp.Link != cfg.JoinURL+"?game_id="+g.ID.String() {
+		t.Errorf("Responded with %+v, expected two addresses invited to the game's link", resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case m := <-mailer.sent:
+			if !strings.Contains(m.body, "Fancy a game?") || !strings.Contains(m.body, resp.Link) {
+				t.Errorf("Invitation to %v has body %q, expected the message and link", m.to, m.body)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Invitation not sent")
+		}
+	}
+}
+
+func TestReminders(t *testing.T) {
+	srv := newTestServer(t)
+	mailer := &fakeMailer{sent: make(chan sentMail, 10)}
+	srv.SetMailer(mailer)
+
+	// Only accounts with an email address can be reminded
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/v2/accounts", strings.NewReader(`{"username":"ashley","password":"hunter22","email":"not an address"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Registering with an invalid email returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	a, err := newAccount("ashley", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Email = "ashley@example.com"
+	if err = srv.accounts.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+
+	newGame := func(opts GameOptions) *ScrabbleGame {
+		g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+		g.Players[ids[0]].Account = &a.ID
+		g.Options = opts
+		g.Lock()
+		defer g.Unlock()
+		srv.adoptGame(g)
+		if err := g.start(); err != nil {
+			t.Fatal(err)
+		}
+		srv.games.Put(g)
+		return g
+	}
+	slow := newGame(GameOptions{})
+	defer slow.Stop()
+	fast := newGame(GameOptions{TurnTimer: 60})
+	defer fast.Stop()
+
+	reminded := make(map[uuid.UUID]time.Time)
+	send := func(at time.Time) int {
+		t.Helper()
+		if err := srv.sendReminders(time.Hour, reminded, at); err != nil {
+			t.Fatal(err)
+		}
+		return len(mailer.sent)
+	}
+
+	if n := send(time.Now()); n != 0 {
+		t.Errorf("Sent %v reminders before the delay, expected none", n)
+	}
+	if n := send(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Fatalf("Sent %v reminders after the delay, expected one for the slow game", n)
+	}
+	if m := <-mailer.sent; m.to != a.Email || !strings.Contains(m.body, "ashley2") {
+		t.Errorf("Sent %+v, expected a reminder of the game against ashley2", m)
+	}
+	if n := send(time.Now().Add(3 * time.Hour)); n != 0 {
+		t.Errorf("Sent %v more reminders for the same turn, expected none", n)
+	}
+
+	if err = srv.notifications.SetPreferences(a.ID, NotificationPreferences{}); err != nil {
+		t.Fatal(err)
+	}
+	delete(reminded, slow.ID)
+	if n := send(time.Now().Add(2 * time.Hour)); n != 0 {
+		t.Errorf("Sent %v reminders to an account that turned them off, expected none", n)
+	}
+}
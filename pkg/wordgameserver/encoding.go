@@ -0,0 +1,79 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// gameSnapshot is the serialized form of a ScrabbleGame used by persistent
+// game stores. Everything else about the game is rebuilt from its events.
+type gameSnapshot struct {
+	ID             uuid.UUID     `json:"id"`
+	Options        GameOptions   `json:"options"`
+	JoinCode       string        `json:"join_code,omitempty"`
+	TournamentID   *uuid.UUID    `json:"tournament_id,omitempty"`
+	PassphraseHash []byte        `json:"passphrase_hash,omitempty"`
+	Webhooks       []Webhook     `json:"webhooks,omitempty"`
+	Events         []Event       `json:"events"`
+	Chat           []ChatMessage `json:"chat,omitempty"`
+}
+
+// EncodeGame serializes the full state of a game so it can be saved by a
+// GameStore. The game must be locked by the caller.
+func EncodeGame(sg *ScrabbleGame) ([]byte, error) {
+	snapshot := gameSnapshot{
+		ID:             sg.ID,
+		Options:        sg.Options,
+		JoinCode:       sg.JoinCode,
+		PassphraseHash: sg.passphraseHash,
+		Webhooks:       sg.webhooks,
+		Events:         sg.events,
+		Chat:           sg.chat,
+	}
+	if sg.TournamentID != uuid.Nil {
+		snapshot.TournamentID = &sg.TournamentID
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode game")
+	}
+	return data, nil
+}
+
+// DecodeGame restores a game serialized by EncodeGame by applying its events
+// in order, checking words played afterwards against the validator, or its
+// dictionary for the game's language if it is a LanguageValidator. If the
+// game had started, its controller is resumed when a server looks it up.
+func DecodeGame(data []byte, validator dictionary.WordValidator) (*ScrabbleGame, error) {
+	var s gameSnapshot
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode game")
+	}
+
+	sg := newScrabbleGame()
+	sg.ID = s.ID
+	sg.Options = s.Options
+	sg.JoinCode = s.JoinCode
+	if s.TournamentID != nil {
+		sg.TournamentID = *s.TournamentID
+	}
+	sg.passphraseHash = s.PassphraseHash
+	sg.webhooks = s.Webhooks
+	sg.chat = s.Chat
+	sg.Validator = gameValidator(validator, s.Options)
+
+	// Events are applied without being recorded again, so webhooks aren't
+	// sent them a second time
+	for i, e := range s.Events {
+		if err := sg.apply(e); err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode game: event %v", i+1)
+		}
+		sg.events = append(sg.events, e)
+	}
+
+	return sg, nil
+}
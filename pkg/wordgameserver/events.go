@@ -0,0 +1,196 @@
+package wordgameserver
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies what happened in a game event
+type EventType string
+
+// Types of event recorded in a game's log
+const (
+	GameCreated      EventType = "game_created"      // the game was created with a full bag
+	PlayerJoined     EventType = "player_joined"     // a player or bot took a seat
+	GameStarted      EventType = "game_started"      // tiles were dealt and the first turn began
+	MovePlayed       EventType = "move_played"       // a player placed tiles on the board
+	TilesExchanged   EventType = "tiles_exchanged"   // a player swapped tiles with the bag
+	TurnPassed       EventType = "turn_passed"       // a player gave up their turn
+	PlayerResigned   EventType = "player_resigned"   // a player conceded the game
+	PlayChallenged   EventType = "play_challenged"   // the last play was challenged
+	ClockExpired     EventType = "clock_expired"     // a player lost by running out of time
+	PositionImported EventType = "position_imported" // the game was set up from a GCG file or position
+	MoveUndone       EventType = "move_undone"       // the last move was taken back with every player's agreement
+	PlayerKicked     EventType = "player_kicked"     // a player was removed by the game's owner or a vote of the others
+	OwnerChanged     EventType = "owner_changed"     // the game's owner handed it over to another player
+	GameEnded        EventType = "game_ended"        // an operator ended the game early, as it stood
+	GamePaused       EventType = "game_paused"       // a player paused the game
+	GameResumed      EventType = "game_resumed"      // every player still in the game agreed to resume it
+	RematchCreated   EventType = "rematch_created"   // a new game was started for the players to play again
+	PlayAccepted     EventType = "play_accepted"     // a play that went out was left unchallenged until its window closed
+)
+
+// Event is an entry in a game's append-only log. The state of a game is what
+// results from applying its events in order, so each event holds everything
+// needed to apply it again, including the order of the bag after any shuffle.
+// Events name players by their secret IDs, so aren't meant for clients.
+type Event struct {
+	Type   EventType `json:"type"`
+	Time   time.Time `json:"time"`
+	Player uuid.UUID `json:"player"` // player the event concerns, if any
+
+	Variant string       `json:"variant,omitempty"`  // variant a game was created as, empty for the standard game
+	Layout  *BoardLayout `json:"layout,omitempty"`   // board a game was created with instead of its variant's, if any
+	TileSet *TileSet     `json:"tile_set,omitempty"` // tiles a game was created with instead of its variant's, if any
+
+	Name     string     `json:"name,omitempty"`      // name of a joining player
+	Bot      bool       `json:"bot,omitempty"`       // true if a joining player is a bot
+	BotLevel string     `json:"bot_level,omitempty"` // difficulty a joining bot plays at
+	Rating   int        `json:"rating,omitempty"`    // rating of a joining player
+	Account  *uuid.UUID `json:"account,omitempty"`   // account a joining player is linked to
+	Invite   *uuid.UUID `json:"invite,omitempty"`    // grant of the invite a player joined with, if any
+
+	StartPos SquareCoordinate `json:"start_pos"`           // where a play starts
+	EndPos   SquareCoordinate `json:"end_pos"`             // where a play ends
+	Tiles    Letters          `json:"tiles,omitempty"`     // tiles played or swapped
+	Blanks   Letters          `json:"blanks,omitempty"`    // letters designated for blanks played
+	Bag      TileBag          `json:"bag,omitempty"`       // the bag once any tiles have been drawn and returned, and it is shuffled
+	TimedOut bool             `json:"timed_out,omitempty"` // true if a pass or swap was made because the turn ran out
+
+	Challenge *ChallengeResult `json:"challenge,omitempty"` // outcome of a challenge
+
+	Board  *ScrabbleBoard `json:"board,omitempty"`  // board of an imported position
+	Racks  []Letters      `json:"racks,omitempty"`  // tiles held by each player in an imported position
+	Scores []int          `json:"scores,omitempty"` // score of each player in an imported position
+	Turn   int            `json:"turn,omitempty"`   // number of the player to move in an imported position
+	Moves  []Move         `json:"moves,omitempty"`  // moves recorded before an imported position
+
+	Order []uuid.UUID `json:"order,omitempty"` // players in the turn order drawn when a seeded game started
+
+	Rematch *Rematch `json:"rematch,omitempty"` // game started for the players to play again
+}
+
+// record applies the event to the game, appends it to the log and lets the
+// game's webhooks know what changed. When the event finishes the game, its
+// results are saved, its tournament is told the outcome if it is part of one
+// and, if it is rated, its players' ratings are updated. The game must be
+// locked by the caller.
+func (sg *ScrabbleGame) record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	moves, finished := len(sg.history), sg.Finished
+	turn := 0
+	if len(sg.Players) > 0 {
+		turn = sg.TurnCount % len(sg.Players)
+	}
+
+	if err := sg.apply(e); err != nil {
+		return err
+	}
+	sg.events = append(sg.events, e)
+
+	sg.notify(e, moves, turn, finished)
+	sg.notifyPlayers(e, turn, finished)
+	if sg.Finished && !finished {
+		sg.rate(e)
+		sg.saveResults(e.Time)
+		sg.reportTournamentResult()
+	}
+	return nil
+}
+
+// apply changes the state of the game according to the event. Events are
+// checked before they are recorded, so applying one only fails if the log has
+// been corrupted.
+func (sg *ScrabbleGame) apply(e Event) error {
+	if e.Type != GameCreated && e.Type != PlayerJoined && e.Type != GameStarted && e.Type != PositionImported && e.Type != GameEnded {
+		if _, ok := sg.Players[e.Player]; !ok {
+			return errors.New("Event '" + string(e.Type) + "' is for an unknown player")
+		}
+	}
+
+	// A timed out turn is shown to players until the next event, as are a
+	// request to undo the last move and reactions to it
+	sg.timedOut = nil
+	sg.undoConsent = nil
+	sg.reactions = nil
+	sg.keepUndo(e)
+	if !e.TimedOut && e.Type != ClockExpired && e.Type != PlayAccepted {
+		sg.LastActivity = e.Time
+		// A player who does something themselves isn't unresponsive
+		delete(sg.kickVotes, e.Player)
+	}
+
+	switch e.Type {
+	case GameCreated:
+		v, err := lookupVariant(e.Variant)
+		if err != nil {
+			return err
+		}
+		sg.variant = v.withTiles(e.TileSet)
+		sg.Board = v.boardFor(e.Layout)
+		sg.TileBag = append(TileBag(nil), e.Bag...)
+	case PlayerJoined:
+		sg.applyJoin(e)
+	case GameStarted:
+		sg.applyStart(e)
+	case MovePlayed:
+		return sg.applyPlay(e)
+	case TilesExchanged:
+		return sg.applySwap(e)
+	case TurnPassed:
+		return sg.applyPass(e)
+	case PlayerResigned:
+		sg.applyResign(e)
+	case PlayerKicked:
+		sg.applyKick(e)
+	case OwnerChanged:
+		sg.ownerID = e.Player
+	case GameEnded:
+		sg.endGame(nil)
+	case GamePaused:
+		sg.applyPause(e)
+	case GameResumed:
+		sg.applyResume(e)
+	case RematchCreated:
+		sg.rematch = e.Rematch
+	case PlayChallenged:
+		return sg.applyChallenge(e)
+	case ClockExpired:
+		sg.applyClockExpired(e)
+	case PositionImported:
+		return sg.applyPosition(e)
+	case MoveUndone:
+		return sg.applyUndo(e)
+	case PlayAccepted:
+		sg.applyAccepted(e)
+	default:
+		return errors.New("Unknown event '" + string(e.Type) + "'")
+	}
+	return nil
+}
+
+// Events returns every event recorded in the game, in order. The game must be
+// locked by the caller.
+func (sg *ScrabbleGame) Events() []Event {
+	events := make([]Event, len(sg.events))
+	copy(events, sg.events)
+	return events
+}
+
+// reshuffled returns the bag as it will be once the number of tiles have been
+// drawn from it and the returned tiles put back, shuffled
+func (sg *ScrabbleGame) reshuffled(drawn int, returned Letters) TileBag {
+	if drawn > len(sg.TileBag) {
+		drawn = len(sg.TileBag)
+	}
+	bag := make(TileBag, 0, len(sg.TileBag)-drawn+len(returned))
+	bag = append(bag, sg.TileBag[drawn:]...)
+	bag = append(bag, returned...)
+	bag.Shuffle(sg.random())
+	return bag
+}
@@ -0,0 +1,84 @@
+package wordgameserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeGameReplaysEvents(t *testing.T) {
+	g := createScrabbleGame()
+	first, _ := g.addPlayer("ashley1")
+	second, _ := g.addPlayer("ashley2")
+
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	// Any word is accepted without a dictionary, so play the first two tiles
+	play := GamePlayRequest{
+		PlayerID: first,
+		StartPos: SquareCoordinate{Row: 7, Col: 7},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    append(Letters(nil), g.Players[first].Tiles[:2]...),
+	}
+	for _, t := range play.Tiles {
+		if t == ' ' {
+			play.Blanks = append(play.Blanks, 'A')
+		}
+	}
+	if err := g.executePlay(play); err != nil {
+		t.Fatal(err)
+	}
+
+	swap := GamePlayRequest{
+		PlayerID: second,
+		Tiles:    append(Letters(nil), g.Players[second].Tiles[:3]...),
+		Swap:     true,
+	}
+	if err := g.executePlay(swap); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.pass(GamePlayRequest{PlayerID: first}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	d.Lock()
+	defer d.Unlock()
+	g.Lock()
+	defer g.Unlock()
+
+	if !reflect.DeepEqual(d.Board, g.Board) {
+		t.Error("Decoded board doesn't match")
+	} else if string(d.TileBag) != string(g.TileBag) {
+		t.Errorf("Decoded bag %q, expected %q", d.TileBag, g.TileBag)
+	} else if d.TurnCount != g.TurnCount || !d.Active {
+		t.Errorf("Decoded game is on turn %v, expected %v", d.TurnCount, g.TurnCount)
+	} else if len(d.History()) != 3 || len(d.Events()) != len(g.Events()) {
+		t.Errorf("Decoded %v moves and %v events, expected 3 and %v", len(d.History()), len(d.Events()), len(g.Events()))
+	} else if !d.LastActivity.Equal(g.LastActivity) || !d.TurnStarted.Equal(g.TurnStarted) {
+		t.Error("Decoded times don't match")
+	}
+
+	for id, p := range g.Players {
+		dp, ok := d.Players[id]
+		if !ok {
+			t.Fatalf("Decoded game is missing player %v", p.Name)
+		} else if dp.Name != p.Name || dp.Score != p.Score || string(dp.Tiles) != string(p.Tiles) {
+			t.Errorf("Decoded player %+v, expected %+v", dp, p)
+		}
+	}
+}
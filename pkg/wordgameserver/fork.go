@@ -0,0 +1,163 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GameForkRequest is the format of the request a client sends to play on from
+// a point in a finished game, to try out other moves
+type GameForkRequest struct {
+	GameID   uuid.UUID `json:"game_id,omitempty"`
+	Move     int       `json:"move"`                // number of moves made before the position played on from
+	BotLevel *string   `json:"bot_level,omitempty"` // if given, bots at this level take the seats of everyone but the player to move
+}
+
+// fork creates a private game at the position before the move with the index
+// is made, ready to be started. The board, scores and earlier moves are those
+// of the finished game, and each player holds the tiles they held then, which
+// are the tiles they had for their next move, or at the end if they had none.
+// The bag is made up of the tiles left over. If a bot level is given, only the
+// player to move is seated and bots take the other seats. The game must be
+// locked by the caller.
+func (sg *ScrabbleGame) fork(move int, botLevel *string) (*ScrabbleGame, error) {
+	if !sg.Finished {
+		return nil, errors.New("Game has not finished")
+	} else if !sg.Options.standard() {
+		return nil, errors.New("Only games played on the standard board with English tiles can be forked")
+	} else if move < 0 || move >= len(sg.history) {
+		return nil, errors.New("Move must be between 0 and " + strconv.Itoa(len(sg.history)-1))
+	}
+
+	replay := sg.replay()
+	for _, m := range replay.Moves[:move] {
+		if m.Resign {
+			return nil, errors.New("Games can't be forked after a player has resigned")
+		}
+	}
+	state, err := ReplayGame(replay, move)
+	if err != nil {
+		return nil, err
+	}
+
+	fg := createScrabbleGame()
+	fg.Options = GameOptions{
+		ChallengeWindow: sg.Options.ChallengeWindow,
+		Lexicon:         sg.Options.Lexicon,
+		Words:           sg.Options.Words,
+		Private:         true,
+	}
+	fg.Board = state.Board
+
+	turn := replay.Moves[move].Player
+	for i, p := range replay.Players {
+		var id uuid.UUID
+		if botLevel != nil && i != turn {
+			fg.Options.Bots++
+			fg.Options.BotLevel = *botLevel
+			id, err = fg.addBot("", *botLevel)
+		} else {
+			id, err = fg.addPlayer(p.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fp := fg.Players[id]
+		fp.Score = state.Scores[i]
+		fp.Tiles = append(Letters(nil), p.Tiles...)
+		for _, m := range replay.Moves[move:] {
+			if m.Player == i {
+				fp.Tiles = append(Letters(nil), m.Rack...)
+				break
+			}
+		}
+	}
+	fg.history = replay.Moves[:move]
+
+	if err = fg.setPosition(turn, nil); err != nil {
+		return nil, err
+	}
+	return fg, nil
+}
+
+// forkGameHandler handles requests to play on from a point in a finished game,
+// identified by its path or the request's game_id. The IDs of the players
+// who aren't bots are returned, in turn order.
+func (s *Server) forkGameHandler(w http.ResponseWriter, r *http.Request) {
+	var j GameForkRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	if j.BotLevel != nil {
+		if s.bot == nil {
+			http.Error(w, "Server has no bots available", http.StatusBadRequest)
+			return
+		} else if !validBotLevel(s.bot, *j.BotLevel) {
+			http.Error(w, "Unknown bot level '"+*j.BotLevel+"'", http.StatusBadRequest)
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	fg, err := g.fork(j.Move, j.BotLevel)
+	g.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.cluster.claim(fg)
+	fg.Validator = gameValidator(s.validator, fg.Options)
+
+	if !s.checkGameLimit(w) {
+		return
+	}
+
+	resp := GameImportResponse{GameID: fg.ID}
+	fg.Lock()
+	defer fg.Unlock()
+	for _, p := range fg.playerList() {
+		if !p.Bot {
+			resp.PlayerIDs = append(resp.PlayerIDs, p.ID)
+		}
+	}
+
+	s.adoptGame(fg)
+	if err = fg.start(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = s.saveGame(fg, w); err != nil {
+		return
+	}
+	fg.runBots(s.bot)
+
+	gameData, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(gameData)
+}
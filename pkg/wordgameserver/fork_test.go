@@ -0,0 +1,118 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestForkGameHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATEIOU", "DOGSEIR")
+	g.TileBag = TileBag("LNRT")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	fork := func(j GameForkRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games/"+g.ID.String()+"/fork", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := fork(GameForkRequest{Move: 1}).Code; c != http.StatusBadRequest {
+		t.Errorf("Forking an unfinished game returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+
+	for _, id := range []uuid.UUID{ids[1], ids[0]} {
+		if err = g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.Lock()
+	drawn := g.history[2].Rack
+	g.Unlock()
+
+	// Play on from the second player's pass, with both players seated
+	rr := fork(GameForkRequest{Move: 1})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var resp GameImportResponse
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	} else if len(resp.PlayerIDs) != 2 {
+		t.Fatalf("Returned %v player IDs, expected 2", len(resp.PlayerIDs))
+	}
+
+	fg, err := srv.lookupGame(context.Background(), resp.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fg.Lock()
+	list := fg.playerList()
+	if !fg.Active || !fg.Options.Private || len(fg.history) != 1 {
+		t.Errorf("Forked game is active %v, private %v with %v moves, expected an active private game with 1 move", fg.Active, fg.Options.Private, len(fg.history))
+	} else if fg.Board[7][7].Letter != 'A' {
+		t.Errorf("Forked board has %q in the centre, expected CAT on the board", fg.Board[7][7].Letter)
+	} else if list[0].Score != 5 || list[1].Score != 0 {
+		t.Errorf("Forked game has scores %v and %v, expected 5 and 0", list[0].Score, list[1].Score)
+	} else if fg.TurnCount%2 != 1 {
+		t.Errorf("Forked game is on turn %v, expected the second player's turn", fg.TurnCount)
+	} else if string(list[1].Tiles) != "DOGSEIR" || string(list[0].Tiles) != string(drawn) {
+		t.Errorf("Forked game has racks %v and %v, expected %v and DOGSEIR", list[0].Tiles, list[1].Tiles, drawn)
+	}
+	fg.Unlock()
+
+	// A bot takes the first player's seat
+	level := ""
+	if c := fork(GameForkRequest{Move: 1, BotLevel: &level}).Code; c != http.StatusBadRequest {
+		t.Errorf("Forking against a bot without bots returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+	srv.bot = swapBot{}
+	rr = fork(GameForkRequest{Move: 1, BotLevel: &level})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Forking against a bot returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if fg, err = srv.lookupGame(context.Background(), resp.GameID); err != nil {
+		t.Fatal(err)
+	}
+	fg.Lock()
+	list = fg.playerList()
+	if len(resp.PlayerIDs) != 1 || !list[0].Bot || list[1].ID != resp.PlayerIDs[0] {
+		t.Errorf("Forked game against a bot returned players %v, expected only ashley2", resp.PlayerIDs)
+	}
+	fg.Unlock()
+
+	unknown := "unknown"
+	for _, j := range []GameForkRequest{{Move: -1}, {Move: 3}, {Move: 0, BotLevel: &unknown}} {
+		if c := fork(j).Code; c != http.StatusBadRequest {
+			t.Errorf("Forking at move %v returned status code %v, expected %v", j.Move, c, http.StatusBadRequest)
+		}
+	}
+}
@@ -1,22 +1,21 @@
 package wordgameserver
 
 import (
+	"context"
 	"math/rand"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Tile represents a Scrabble tile that would be played on a board
-type Tile struct {
-	Letter byte `json:"letter"` // the character written on the tile
-	Count  int  `json:"-"`      // the number of tiles with the character
-	Value  int  `json:"value"`  // the point value of playing the tile
-}
-
-var tiles = map[byte]Tile{
+var tiles = map[Letter]Tile{
 	' ': {Letter: ' ', Count: 2, Value: 0},
 	'A': {Letter: 'A', Count: 9, Value: 1},
 	'B': {Letter: 'B', Count: 2, Value: 3},
@@ -48,165 +47,672 @@ var tiles = map[byte]Tile{
 
 // Player represents an instance of a player and stores their current state
 type Player struct {
-	ID     uuid.UUID              `json:"-"`      // unique identifier
-	Name   string                 `json:"name"`   // player's chosen display name
-	Number int                    `json:"number"` // number that dictates their turn
-	Tiles  []byte                 `json:"-"`      // tiles currenty in possession
-	Score  int                    `json:"score"`  // current score in the game
-	State  chan GameStateResponse `json:"-"`      // channel on which to send state responses
-	Play   chan GameStateResponse `json:"-"`      // channel on which to send play responses
+	engine.Seat // tiles in possession, score and whether the player still takes turns
+
+	ID       uuid.UUID              `json:"-"`                    // unique identifier
+	Name     string                 `json:"name"`                 // player's chosen display name
+	Number   int                    `json:"number"`               // number that dictates their turn
+	Kicked   bool                   `json:"kicked,omitempty"`     // true if the player was removed by the others, which also marks them resigned
+	TimeLeft time.Duration          `json:"-"`                    // time left on the player's clock, negative once it runs out
+	Bot      bool                   `json:"bot,omitempty"`        // true if the server makes the player's moves
+	BotLevel string                 `json:"bot_level,omitempty"`  // difficulty the bot plays at, empty for the default
+	Rating   int                    `json:"rating,omitempty"`     // player's rating when they joined, 0 for bots and guests
+	Account  *uuid.UUID             `json:"account_id,omitempty"` // account the player is linked to, if they joined while logged in
+	State    chan GameStateResponse `json:"-"`                    // channel on which to send state responses
+	Play     chan GameStateResponse `json:"-"`                    // channel on which to send play responses
+}
+
+const maxTiles = engine.MaxTiles
+
+const maxPlayers = 4
+
+// actionQueueSize is how many requests can wait for a game's controller before
+// more have to wait to be queued
+const actionQueueSize = 16
+
+// Move is a record of a turn taken in a game
+type Move struct {
+	Player    int                `json:"player"`              // number of the player who moved
+	Swap      bool               `json:"swap,omitempty"`      // true if tiles were swapped instead of played
+	Pass      bool               `json:"pass,omitempty"`      // true if the turn was given up
+	Resign    bool               `json:"resign,omitempty"`    // true if the player conceded the game
+	Kicked    bool               `json:"kicked,omitempty"`    // true if the player was removed from the game, which also marks it a resignation
+	TimedOut  bool               `json:"timed_out,omitempty"` // true if the move was made because the turn timer ran out
+	Words     []string           `json:"words,omitempty"`     // words formed by the play
+	Squares   []SquareCoordinate `json:"squares,omitempty"`   // squares the tiles were placed on
+	Tiles     []Tile             `json:"tiles,omitempty"`     // tiles placed on each of the squares
+	Rack      Letters            `json:"rack,omitempty"`      // tiles in the player's hand before the move
+	Swapped   Letters            `json:"swapped,omitempty"`   // tiles put back in the bag by a swap
+	Score     int                `json:"score"`               // points awarded for the play
+	Retracted bool               `json:"retracted,omitempty"` // true if the play was successfully challenged
+	Time      time.Time          `json:"time"`                // when the move was made
+}
+
+// GameOptions are the settings chosen by the creator of a game
+type GameOptions struct {
+	ChallengeWindow int          `json:"challenge_window,omitempty"` // seconds a play can be challenged for, 0 validates words when played instead
+	Bots            int          `json:"bots,omitempty"`             // number of seats filled by computer players
+	BotLevel        string       `json:"bot_level,omitempty"`        // difficulty the bots play at, empty for the default
+	TurnTimer       int          `json:"turn_timer,omitempty"`       // seconds each turn lasts before it is taken automatically, 0 for no limit
+	TimeoutSwap     bool         `json:"timeout_swap,omitempty"`     // true to swap every tile when a turn runs out, instead of passing
+	Clock           int          `json:"clock,omitempty"`            // seconds each player has for all of their turns, 0 for no limit
+	ClockIncrement  int          `json:"clock_increment,omitempty"`  // seconds added to a player's clock after each of their turns
+	OutOfTime       string       `json:"out_of_time,omitempty"`      // what happens when a clock runs out, OutOfTimeLoss or OutOfTimePenalty
+	ResignedTiles   string       `json:"resigned_tiles,omitempty"`   // what happens to a resigning player's tiles, ResignedTilesBag or ResignedTilesAside
+	Private         bool         `json:"private,omitempty"`          // true to keep the game out of the lobby and have players join with a code
+	Rated           bool         `json:"rated,omitempty"`            // true if the result changes the players' ratings
+	Variant         string       `json:"variant,omitempty"`          // board and tiles played with, VariantStandard if empty
+	Layout          *BoardLayout `json:"layout,omitempty"`           // board played on instead of the variant's, if any
+	TileSet         *TileSet     `json:"tile_set,omitempty"`         // tiles played with instead of the variant's, if any
+	Language        string       `json:"language,omitempty"`         // language words are played in, whose tiles replace the variant's unless a tile set is chosen, LanguageEnglish if empty
+	Lexicon         string       `json:"lexicon,omitempty"`          // name of the server's lexicon words are checked against instead of the language's dictionary, if any
+	Words           []string     `json:"words,omitempty"`            // words checked against instead of a lexicon, for games played with a word list of their own
+	Friendly        bool         `json:"friendly,omitempty"`         // true to let players undo the last move when everyone agrees
+	Pausable        bool         `json:"pausable,omitempty"`         // true to let any player pause the game until everyone agrees to resume
+	Seed            *int64       `json:"seed,omitempty"`             // shuffles the bag and draws the turn order the same way every time, nil for a random bag and turns in the order players joined
+}
+
+// Consequences of a player's clock running out
+const (
+	OutOfTimeLoss    = "loss"    // the player loses and the game ends
+	OutOfTimePenalty = "penalty" // the player loses points for every minute over at the end of the game
+)
+
+// What happens to the tiles of a player who resigns
+const (
+	ResignedTilesBag   = "bag"   // the tiles are returned to the bag for others to draw
+	ResignedTilesAside = "aside" // the tiles are set aside and take no further part in the game
+)
+
+// validate checks that the options chosen for a game are usable
+func (o GameOptions) validate() error {
+	if o.ChallengeWindow < 0 {
+		return errors.New("Challenge window cannot be negative")
+	} else if o.Bots < 0 || o.Bots > maxPlayers-1 {
+		return errors.New("Number of bots must be between 0 and " + strconv.Itoa(maxPlayers-1))
+	} else if o.TurnTimer < 0 {
+		return errors.New("Turn timer cannot be negative")
+	} else if o.Clock < 0 || o.ClockIncrement < 0 {
+		return errors.New("Clock and increment cannot be negative")
+	} else if o.OutOfTime != "" && o.OutOfTime != OutOfTimeLoss && o.OutOfTime != OutOfTimePenalty {
+		return errors.New("Out of time consequence must be '" + OutOfTimeLoss + "' or '" + OutOfTimePenalty + "'")
+	} else if o.ResignedTiles != "" && o.ResignedTiles != ResignedTilesBag && o.ResignedTiles != ResignedTilesAside {
+		return errors.New("Resigned tiles must be '" + ResignedTilesBag + "' or '" + ResignedTilesAside + "'")
+	} else if o.Friendly && o.Rated {
+		return errors.New("Rated games cannot be friendly")
+	} else if _, err := lookupVariant(o.Variant); err != nil {
+		return err
+	} else if ts, err := lookupLanguage(o.Language); err != nil {
+		return err
+	} else if ts != nil && o.Bots > 0 {
+		return errors.New("Bots can only play in English")
+	} else if err = o.validateLexicon(); err != nil {
+		return err
+	}
+
+	if o.Layout != nil {
+		if o.Layout.Size == 0 && o.Layout.Name == "" {
+			return errors.New("Layout must be given in full or by name")
+		} else if o.Layout.Size != 0 {
+			if err := o.Layout.validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.TileSet != nil {
+		if len(o.TileSet.Tiles) == 0 && o.TileSet.Name == "" {
+			return errors.New("Tile set must be given in full or by name")
+		} else if len(o.TileSet.Tiles) != 0 {
+			return o.TileSet.validate()
+		}
+	}
+	return nil
 }
 
-// TileBag represents the bag of undistributed tiles in a game
-type TileBag []byte
+// standard reports whether games with the options are played on the standard
+// board with English tiles
+func (o GameOptions) standard() bool {
+	return (o.Variant == "" || o.Variant == VariantStandard) && o.Layout == nil && o.tileSet() == nil
+}
 
-var initializedTileBag = initializeTileBag()
+// requestType identifies what a request sent to the stateController is for
+type requestType int
+
+const (
+	stateRequest     requestType = iota // return the game state
+	playRequest                         // play or swap tiles
+	challengeRequest                    // challenge the last play
+	passRequest                         // give up the turn
+	resignRequest                       // concede the game
+	undoRequest                         // undo the last move, or agree to
+	kickRequest                         // remove another player, or vote to
+	pauseRequest                        // pause the game
+	unpauseRequest                      // resume a paused game, or agree to
+)
 
-const maxTiles = 7
+func (t requestType) String() string {
+	switch t {
+	case stateRequest:
+		return "state"
+	case playRequest:
+		return "play"
+	case challengeRequest:
+		return "challenge"
+	case passRequest:
+		return "pass"
+	case resignRequest:
+		return "resign"
+	case undoRequest:
+		return "undo"
+	case kickRequest:
+		return "kick"
+	case pauseRequest:
+		return "pause"
+	case unpauseRequest:
+		return "unpause"
+	}
+	return "unknown"
+}
 
 // ScrabbleGame represents the state of an active game instance
 type ScrabbleGame struct {
 	sync.Mutex
-	ID        uuid.UUID             // unique identifier
-	Active    bool                  // true if the game has started
-	Action    chan GamePlayRequest  // channel for receiving player's turns
-	TurnCount int                   // counter that increments for each turn played
-	Board     ScrabbleBoard         // board representation with current tiles
-	TileBag   TileBag               // bag of tiles not yet distributed
-	Players   map[uuid.UUID]*Player // players indexed by UUID
+	engine.Table // board, bag and count of turns played
+
+	ID           uuid.UUID                // unique identifier
+	Active       bool                     // true if the game has started
+	Finished     bool                     // true if the game has ended
+	Winners      []int                    // numbers of the players with the highest score once the game has ended
+	Action       chan GamePlayRequest     // channel for receiving player's turns
+	Players      map[uuid.UUID]*Player    // players indexed by UUID
+	Validator    dictionary.WordValidator // dictionary for words played, nil accepts any word
+	Options      GameOptions              // settings chosen at creation
+	JoinCode     string                   // short code players can join a private game with instead of its ID
+	TournamentID uuid.UUID                // tournament the game is part of, uuid.Nil if it isn't
+
+	passphraseHash []byte // bcrypt hash of the passphrase needed to join, nil if anyone can
+
+	ownerID uuid.UUID // player who runs the game, the first to join who isn't a bot unless they hand it over, uuid.Nil for tournament games
+
+	variant *variant // board and tiles the game is played with, set when it is created
+
+	LastActivity time.Time // when a player last joined, started the game or moved
+	TurnStarted  time.Time // when the current turn began
+
+	events        []Event            // every change made to the game, in order
+	history       []Move             // every move made, in order
+	lastPlay      *playRecord        // most recent play, kept until it can no longer be challenged
+	lastChallenge *ChallengeResult   // outcome of the most recent challenge
+	timedOut      *int               // number of the player whose turn last ran out, until the next move
+	undoable      *undoRecord        // state before the most recent move, kept until something else happens
+	undoConsent   map[uuid.UUID]bool // players who have agreed to undo the most recent move, nil if no one has asked
+	undone        *undoneMoves       // moves that have been undone, nil if none have
+	chat          []ChatMessage      // messages posted by players and spectators, in order
+	reactions     []Reaction         // players' reactions to the most recent move, until the next event
+	rematch       *Rematch           // game started for the players to play again, once there is one
+
+	kickVotes map[uuid.UUID]map[uuid.UUID]bool // players who have voted to remove each player, until that player does something
+
+	paused      bool               // true while play is paused, until every player still in the game asks to resume
+	pausedAt    time.Time          // when the game was paused
+	resumeVotes map[uuid.UUID]bool // players who have asked to resume the paused game
+
+	watchMu  sync.Mutex                           // guards watchers
+	watchers map[chan GameStateResponse]uuid.UUID // push subscriptions by player
+
+	view        atomic.Pointer[stateView] // copy of the state as of the last change, which reads are served from, nil until the game starts
+	viewVersion uint64                    // number of copies made of the state, which goes up with every change
+
+	botsRunning bool // true once goroutines are making the bots' moves
+
+	webhooks []Webhook // URLs the game's events are posted to
+
+	store   GameStore   // where the game is saved by its server, once it has been
+	ratings RatingStore // where the server keeps its players' ratings
+	results ResultStore // where the server keeps the results of finished games
+
+	saving sync.WaitGroup // results of the game being analysed and saved in the background
+
+	tournamentResults func(tournamentResult)                  // reports the outcome of a tournament game to the server holding it
+	push              func(account uuid.UUID, n Notification) // sends a notification to the devices of an account on the server holding the game
+
+	controllers       *controllerGroup // counts the controller of the server holding the game
+	controllerRunning bool             // true once the stateController has been started
+
+	done     chan struct{} // closed to stop the stateController
+	stopOnce sync.Once
 }
 
-// createScrabbleGame initializes a game instance
+// ErrGameStopped is returned for requests made to a game that has been stopped
+var ErrGameStopped = errors.New("Game is no longer running")
+
+// createScrabbleGame initializes a standard game instance with a freshly
+// shuffled bag
 func createScrabbleGame() *ScrabbleGame {
+	return createVariantGame(standardVariant, nil, nil, nil)
+}
+
+// createVariantGame initializes a game instance of the variant, with its board
+// and a freshly shuffled bag of its tiles, or the layout and tile set if they
+// are given. The bag is shuffled by the seed if there is one.
+func createVariantGame(v *variant, layout *BoardLayout, ts *TileSet, seed *int64) *ScrabbleGame {
+	game := newScrabbleGame()
+	game.Options.Seed = seed
+
+	event := Event{Type: GameCreated, Bag: v.withTiles(ts).newBag(game.random()), Layout: layout, TileSet: ts}
+	if v != standardVariant {
+		event.Variant = v.name
+	}
+	game.record(event)
+
+	return game
+}
+
+// newScrabbleGame initializes a game instance with no events, ready for them
+// to be applied
+func newScrabbleGame() *ScrabbleGame {
 
 	game := ScrabbleGame{}
 
 	game.ID = uuid.New()
 
-	game.Action = make(chan GamePlayRequest)
+	game.Action = make(chan GamePlayRequest, actionQueueSize)
 
-	// Initialize squares on board
-	game.Board = initializedBoard
+	// Initialize squares on board, until the game's creation sets its variant
+	game.variant = standardVariant
+	game.Board = standardVariant.newBoard()
 
-	// Populate tile bag
-	game.TileBag = make(TileBag, len(initializedTileBag))
-	copy(game.TileBag, initializedTileBag)
+	game.Players = make(map[uuid.UUID]*Player)
 
-	// Shuffle tile bag
-	game.TileBag.shuffle()
+	game.watchers = make(map[chan GameStateResponse]uuid.UUID)
 
-	game.Players = make(map[uuid.UUID]*Player)
+	game.done = make(chan struct{})
 
 	return &game
 }
 
 // dealTiles disperses tiles from the tile bag to players so they always have 7
-// tiles in their hand
+// tiles in their hand, or as many as remain in the bag
 func dealTiles(p *Player, tb *TileBag, tileCount int) {
-	var tilesDealt []byte
-	tilesDealt, *tb = (*tb)[:tileCount], (*tb)[tileCount:]
-	p.Tiles = append(p.Tiles, tilesDealt...)
-}
-
-func removeTiles(p *Player, tiles []byte) error {
-	var tileFound bool
-	for _, t := range tiles {
-		tileFound = false
-		for i, pt := range p.Tiles {
-			// Check for matching tile in player's hand
-			if t == pt {
-				// Remove tile from player's hand
-				p.Tiles = append(p.Tiles[:i], p.Tiles[i+1:]...)
-				tileFound = true
-				break
-			}
-		}
-		if !tileFound {
-			return errors.New("Tile '" + string(t) + "' not in player's hand")
-		}
-	}
-	return nil
+	p.Tiles = append(p.Tiles, tb.Draw(tileCount)...)
 }
 
-// initializeTileBag fills the tile bag with tiles before the game begins
-func initializeTileBag() TileBag {
-	bag := TileBag{}
-	for t := range tiles {
-		for i := 0; i < tiles[t].Count; i++ {
-			bag = append(bag, t)
-		}
-	}
-	return bag
+func removeTiles(p *Player, tiles Letters) error {
+	var err error
+	p.Tiles, err = engine.RemoveTiles(p.Tiles, tiles)
+	return err
 }
 
-// shuffle make sure the tiles are in random order in the tile bag
-func (tb TileBag) shuffle() {
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
-	r.Shuffle(len(tb), func(i, j int) {
-		tb[i], tb[j] = tb[j], tb[i]
-	})
+// random returns the source of randomness for the event the game records
+// next. Seeded games draw it from their seed and the number of events already
+// recorded, so the same moves always shuffle the bag the same way, even once
+// the game has been saved and loaded again.
+func (sg *ScrabbleGame) random() *rand.Rand {
+	if sg.Options.Seed == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(*sg.Options.Seed ^ int64(len(sg.events))<<32))
 }
 
 func (sg *ScrabbleGame) start() error {
+	if err := sg.begin(); err != nil {
+		return err
+	}
+
+	sg.runController()
+
+	return nil
+}
 
+// begin records the start of the game without running its controller, which
+// games played without one, such as simulations, don't need
+func (sg *ScrabbleGame) begin() error {
 	if sg.Active {
 		return errors.New("Game has already started")
 	} else if len(sg.Players) < 2 {
 		return errors.New("At least two players needed to start game")
 	}
 
+	// Players of seeded games take their turns in an order drawn from the
+	// seed, rather than the order they joined in
+	e := Event{Type: GameStarted}
+	if sg.Options.Seed != nil {
+		for _, p := range sg.playerList() {
+			e.Order = append(e.Order, p.ID)
+		}
+		sg.random().Shuffle(len(e.Order), func(i, j int) {
+			e.Order[i], e.Order[j] = e.Order[j], e.Order[i]
+		})
+	}
+	return sg.record(e)
+}
+
+// applyStart seats the players in the order drawn for a seeded game, deals
+// them tiles and starts the first turn
+func (sg *ScrabbleGame) applyStart(e Event) {
 	sg.Active = true
+	sg.TurnStarted = e.Time
 
-	go sg.stateController()
+	for i, id := range e.Order {
+		if p, ok := sg.Players[id]; ok {
+			p.Number = i
+		}
+	}
 
-	return nil
+	// Deal tiles to players and set their clocks. Players in imported games
+	// may already hold some tiles.
+	for _, p := range sg.playerList() {
+		dealTiles(p, &sg.TileBag, maxTiles-len(p.Tiles))
+		p.TimeLeft = time.Duration(sg.Options.Clock) * time.Second
+	}
 }
 
-// stateController is the main goroutine for the game that handles state
-// requests and play requests
-func (sg *ScrabbleGame) stateController() {
+// endGame finishes the game so no more moves can be made, applying any clock
+// penalties to the scores. The players with the highest score win, except a
+// player who lost on time, who is given when the game ends that way, and any
+// player who resigned.
+func (sg *ScrabbleGame) endGame(loser *Player) {
+	sg.Finished = true
+	sg.paused = false
+
+	if sg.Options.Clock > 0 && sg.Options.OutOfTime == OutOfTimePenalty {
+		for _, p := range sg.Players {
+			p.Score -= clockPenalty(p.TimeLeft)
+		}
+	}
+
+	lost := -1
+	if loser != nil {
+		lost = loser.Number
+	}
+	sg.Winners = engine.Winners(sg.seats(), lost)
+}
+
+// Stop ends the game's controller goroutine. Requests made to the game
+// afterwards fail with ErrGameStopped, unless the game has finished.
+func (sg *ScrabbleGame) Stop() {
+	sg.stopOnce.Do(func() {
+		close(sg.done)
+	})
+}
+
+// stopped reports whether the game has been stopped
+func (sg *ScrabbleGame) stopped() bool {
+	select {
+	case <-sg.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// runController starts the game's stateController if the game is underway and
+// it isn't already running. The game must be locked by the caller.
+func (sg *ScrabbleGame) runController() {
+	if !sg.Active || sg.Finished || sg.controllerRunning || sg.stopped() {
+		return
+	}
+	sg.controllerRunning = true
+
+	group := sg.controllers
+	group.add()
+	go func() {
+		defer group.done()
+		sg.stateController()
+	}()
+}
+
+// controllerGroup keeps track of the stateControllers running for a server's
+// games, so they can be counted and waited for when it shuts down
+type controllerGroup struct {
+	wg      sync.WaitGroup
+	running atomic.Int64
+}
+
+// add counts a controller that is about to start. Games that don't belong to a
+// server have a nil group, which counts nothing.
+func (cg *controllerGroup) add() {
+	if cg == nil {
+		return
+	}
+	cg.wg.Add(1)
+	cg.running.Add(1)
+}
 
-	// Deal tiles to players
-	for p := range sg.Players {
-		dealTiles(sg.Players[p], &sg.TileBag, 7)
+// done counts a controller that has returned
+func (cg *controllerGroup) done() {
+	if cg == nil {
+		return
 	}
+	cg.running.Add(-1)
+	cg.wg.Done()
+}
+
+// wait blocks until every controller has returned or the context is done
+func (cg *controllerGroup) wait(ctx context.Context) error {
+	returned := make(chan struct{})
+	go func() {
+		cg.wg.Wait()
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stateController is the main goroutine for the game that handles state
+// requests and play requests. The game is locked while each request is
+// handled, so its state can be safely read by anything else holding the lock.
+func (sg *ScrabbleGame) stateController() {
 
 	// Get ordered list of players to send to clients
+	sg.Lock()
 	playerList := sg.playerList()
 
-	// Loop on requests in queue
-	for request := range sg.Action {
-		switch request.Play {
-		case false: // Return the game state
-			sg.Players[request.PlayerID].State <- sg.getState(request.PlayerID, playerList)
-		default: // Execute play
-			err := sg.executePlay(request)
-			gameState := sg.getState(request.PlayerID, playerList)
-			if err != nil {
-				gameState.Error = err
-			}
-			sg.Players[request.PlayerID].Play <- gameState
+	// Let subscribed clients know the game has started
+	sg.broadcast(playerList)
+	sg.Unlock()
+
+	// Handle requests in the queue until the game is stopped or finishes,
+	// acting on any player who runs out of time. Nothing more can happen in a
+	// finished game, so its controller isn't needed. Requests made from then
+	// on are answered by request.
+	engine.Control[GamePlayRequest](sg.Action, sg.done, controllerHandler{sg, playerList})
+	sg.Stop()
+}
+
+// controllerHandler hands the requests and deadlines the engine's controller
+// acts on to the game, locking it for each
+type controllerHandler struct {
+	sg         *ScrabbleGame
+	playerList []*Player
+}
+
+// Handle carries out the request
+func (h controllerHandler) Handle(request GamePlayRequest) bool {
+	h.sg.Lock()
+	defer h.sg.Unlock()
+	h.sg.handleRequest(request, h.playerList)
+	return h.sg.Finished
+}
+
+// Deadline returns when a player next runs out of time
+func (h controllerHandler) Deadline() (time.Time, bool) {
+	h.sg.Lock()
+	defer h.sg.Unlock()
+	return h.sg.nextDeadline()
+}
+
+// Expire acts on the player who ran out of time
+func (h controllerHandler) Expire() bool {
+	h.sg.Lock()
+	defer h.sg.Unlock()
+	h.sg.expireTime(h.playerList)
+	return h.sg.Finished
+}
+
+// handleRequest carries out a request received by the stateController and
+// sends the response to the requesting player
+func (sg *ScrabbleGame) handleRequest(request GamePlayRequest, playerList []*Player) {
+	_, span := tracer().Start(requestContext(request), "ScrabbleGame.handleRequest")
+	defer span.End()
+
+	// Requests given up on while queued aren't acted on, so a client that
+	// retries can't end up making the same move twice
+	if err := requestContext(request).Err(); err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	switch request.Type {
+	case stateRequest: // Return the game state
+		respond(request, sg.Players[request.PlayerID].State, sg.getState(request.PlayerID, playerList))
+	default: // Execute play or challenge
+		var err error
+		if sg.Finished {
+			err = errors.New("Game is over")
+		} else if sg.paused && (request.Type == playRequest || request.Type == challengeRequest || request.Type == passRequest || request.Type == undoRequest) {
+			err = errors.New("Game is paused")
+		} else if request.Type == challengeRequest {
+			err = sg.challengePlay(request)
+		} else if request.Type == passRequest {
+			err = sg.pass(request)
+		} else if request.Type == resignRequest {
+			err = sg.resign(request)
+		} else if request.Type == undoRequest {
+			err = sg.undo(request)
+		} else if request.Type == kickRequest {
+			err = sg.kick(request.PlayerID, request.kick)
+		} else if request.Type == pauseRequest {
+			err = sg.pause(request)
+		} else if request.Type == unpauseRequest {
+			err = sg.resume(request)
+		} else {
+			err = sg.executePlay(request)
+		}
+		gameState := sg.getState(request.PlayerID, playerList)
+		if err != nil {
+			gameState.Error = err
+			span.RecordError(err)
 		}
+		if err == nil {
+			// The view reads are served from is brought up to date before
+			// the player hears back, so they never read an older state
+			sg.broadcast(playerList)
+		}
+		respond(request, sg.Players[request.PlayerID].Play, gameState)
 	}
 }
 
-func (sg *ScrabbleGame) request(r GamePlayRequest) (GameStateResponse, error) {
+// request sends the request to the stateController and waits for its
+// response. The round trip is traced, with events marking when the request was
+// queued and when the controller responded, and the controller's handling of
+// it is a span of its own, so time spent waiting behind other requests can be
+// told apart from time spent handling it. If the request's context is done
+// before the response arrives, such as when the client disconnects, it is
+// abandoned and the context's error is returned. Requests for the state are
+// answered from the game's view once it has one, so they don't wait behind
+// moves.
+func (sg *ScrabbleGame) request(r GamePlayRequest) (j GameStateResponse, err error) {
+	if r.Type == stateRequest {
+		if state, ok := sg.viewState(r.PlayerID); ok {
+			return state, nil
+		}
+	}
+
+	var ctx context.Context
+	var span trace.Span
+	ctx, span = tracer().Start(requestContext(r), "ScrabbleGame.request", trace.WithAttributes(
+		gameIDKey.String(sg.ID.String()),
+		playerIDKey.String(r.PlayerID.String()),
+		requestTypeKey.String(r.Type.String()),
+	))
+	defer func() { endSpan(span, err) }()
+	r.ctx = ctx
+
+	// Queue request for game controller, unless whoever made it stops waiting.
+	// There may be room in the queue of a stopped game, so that is checked
+	// first.
+	select {
+	case <-sg.done:
+		return sg.stoppedResponse(r)
+	default:
+	}
+	select {
+	case sg.Action <- r:
+		span.AddEvent("Request queued for controller")
+	case <-sg.done:
+		return sg.stoppedResponse(r)
+	case <-ctx.Done():
+		return j, ctx.Err()
+	}
 
-	var j GameStateResponse
+	response := sg.Players[r.PlayerID].Play
+	if r.Type == stateRequest {
+		response = sg.Players[r.PlayerID].State
+	}
+	select {
+	case j = <-response:
+	case <-sg.done:
+		return sg.stoppedResponse(r)
+	case <-ctx.Done():
+		return j, ctx.Err()
+	}
+	span.AddEvent("Response received from controller")
+	return j, j.Error
+}
 
-	// Send request to game controller
-	sg.Action <- r
+// stoppedResponse answers a request made to a game whose controller has
+// stopped. A finished game's final state can still be seen, but no more moves
+// can be made in it.
+func (sg *ScrabbleGame) stoppedResponse(r GamePlayRequest) (GameStateResponse, error) {
+	sg.Lock()
+	defer sg.Unlock()
 
-	switch r.Play {
-	case false:
-		return <-sg.Players[r.PlayerID].State, nil
-	default:
-		if j = <-sg.Players[r.PlayerID].Play; j.Error != nil {
-			return j, j.Error
-		}
-		return j, nil
+	if !sg.Finished {
+		return GameStateResponse{}, ErrGameStopped
 	}
+	state := sg.getState(r.PlayerID, sg.playerList())
+	if r.Type != stateRequest {
+		state.Error = errors.New("Game is over")
+	}
+	return state, state.Error
+}
+
+// respond sends the controller's response to a request, giving up if whoever
+// made it has stopped waiting so the controller can't be blocked by them
+func respond(request GamePlayRequest, ch chan GameStateResponse, state GameStateResponse) {
+	select {
+	case ch <- state:
+	case <-requestContext(request).Done():
+	}
+}
+
+// History returns every move made in the game, in order. The game must be
+// locked by the caller.
+func (sg *ScrabbleGame) History() []Move {
+	h := make([]Move, len(sg.history))
+	copy(h, sg.history)
+	return h
+}
+
+// advanceTurn passes play to the next player at the given time, skipping any
+// player who has lost their turn or resigned
+func (sg *ScrabbleGame) advanceTurn(at time.Time) {
+	sg.endTurn(at)
+	sg.Advance(sg.seats())
+}
+
+// endTurn charges the current player's clock for their turn, which ends at the
+// given time, for the table to pass play on
+func (sg *ScrabbleGame) endTurn(at time.Time) {
+	sg.chargeClock(sg.playerList()[sg.Current(sg.seats())], at)
+	sg.TurnStarted = at
 }
 
 // playerList generates an ordered list of players for consistency across all
@@ -219,42 +725,139 @@ func (sg *ScrabbleGame) playerList() []*Player {
 	return p
 }
 
+// getState returns the player's view of the game. The game must be locked by
+// the caller.
 func (sg *ScrabbleGame) getState(playerID uuid.UUID, playerList []*Player) GameStateResponse {
+	state := sg.sharedState(playerList)
+	state.PlayerID = playerID
+	state.PlayerTiles = sg.variant.rack(sg.Players[playerID].Tiles)
+	state.Rematch = sg.rematch.forPlayer(playerID)
+	return state
+}
+
+// sharedState returns the parts of the game's state every player sees alike,
+// leaving out whose view it is and their rack. The game must be locked by the
+// caller.
+func (sg *ScrabbleGame) sharedState(playerList []*Player) GameStateResponse {
+	var deadline *time.Time
+	if sg.Active && !sg.Finished && !sg.paused && sg.Options.TurnTimer > 0 {
+		d := sg.turnDeadline()
+		deadline = &d
+	}
+
 	return GameStateResponse{
-		GameID:      sg.ID,
-		PlayerID:    playerID,
-		Players:     playerList,
-		Board:       sg.Board,
-		PlayerTurn:  sg.TurnCount % len(playerList),
-		PlayerTiles: sg.Players[playerID].Tiles,
+		GameID:        sg.ID,
+		Active:        sg.Active,
+		Finished:      sg.Finished,
+		Winners:       sg.Winners,
+		Players:       playerList,
+		Board:         sg.Board.Clone(),
+		Variant:       sg.Options.Variant,
+		Lexicon:       sg.Options.lexicon(),
+		PlayerTurn:    sg.TurnCount % len(playerList),
+		Challenge:     sg.lastChallenge,
+		TurnEnds:      deadline,
+		TimedOut:      sg.timedOut,
+		Clocks:        sg.clocks(playerList),
+		UndoRequested: sg.undoConsent != nil,
+		Chat:          sg.recentChat(),
+		Reactions:     append([]Reaction(nil), sg.reactions...),
+		Owner:         sg.ownerNumber(),
+		Paused:        sg.paused,
+		ResumeVotes:   sg.resumeVoteNumbers(),
+		MoveCount:     len(sg.history),
+		BagCount:      len(sg.TileBag),
+		LastMove:      sg.lastMove(),
+		Passes:        sg.consecutivePasses(),
+	}
+}
+
+// lastMove returns a copy of the most recent move, or nil if none have been
+// made. Its rack and any tiles swapped are left out until the game has
+// finished, as they would give away the tiles its player holds.
+func (sg *ScrabbleGame) lastMove() *Move {
+	if len(sg.history) == 0 {
+		return nil
+	}
+	m := sg.history[len(sg.history)-1]
+	if !sg.Finished {
+		m.Rack, m.Swapped = nil, nil
 	}
+	return &m
 }
 
 // addPlayer checks that a new player can be added to the game, and adds the
 // player if so
 func (sg *ScrabbleGame) addPlayer(name string) (uuid.UUID, error) {
+	return sg.join(Event{Name: name})
+}
+
+// addAccountPlayer adds a player linked to the account, playing under its
+// username, to the game. An account can only join a game once.
+func (sg *ScrabbleGame) addAccountPlayer(a Account) (uuid.UUID, error) {
+	return sg.addInvitedPlayer("", &a, nil)
+}
 
-	// Create player to be added to game
-	p := Player{
-		ID:    uuid.New(),
-		Name:  name,
-		Tiles: make([]byte, 0),
-		State: make(chan GameStateResponse),
+// addInvitedPlayer adds a player with the name, or linked to the account if
+// there is one, to the game. If the player was invited, the invite's grant is
+// recorded with them so it can't be used again.
+func (sg *ScrabbleGame) addInvitedPlayer(name string, a *Account, grant *uuid.UUID) (uuid.UUID, error) {
+	e := Event{Name: name, Invite: grant}
+	if a != nil {
+		for _, p := range sg.Players {
+			if p.Account != nil && *p.Account == a.ID {
+				return uuid.Nil, errors.New("Account has already joined the game")
+			}
+		}
+		e.Name, e.Account = a.Username, &a.ID
 	}
+	if grant != nil && sg.inviteUsed(*grant) {
+		return uuid.Nil, errors.New("Invite has already been used")
+	}
+	return sg.join(e)
+}
 
-	playerCount := len(sg.Players)
+// join adds the player or bot described by the join event to the game if
+// there is a seat for them
+func (sg *ScrabbleGame) join(e Event) (uuid.UUID, error) {
+	id := uuid.New()
 
 	// Check that game is valid to join
 	if sg.Active {
-		return p.ID, errors.New("Game has already started")
-	} else if playerCount == 4 {
-		return p.ID, errors.New("Maximum players reached for game")
+		return id, errors.New("Game has already started")
+	} else if len(sg.Players) == maxPlayers {
+		return id, errors.New("Maximum players reached for game")
 	}
 
-	// Assign player their number based on when they joined
-	p.Number = playerCount
-	// Add player to game
-	sg.Players[p.ID] = &p
+	e.Type = PlayerJoined
+	e.Player = id
+	if !e.Bot && e.Account != nil && sg.ratings != nil {
+		r, err := playerRating(sg.ratings, e.Account.String())
+		if err != nil {
+			return id, err
+		}
+		e.Rating = r.Rating
+	}
+	return id, sg.record(e)
+}
 
-	return p.ID, nil
+// applyJoin adds a player to the game, numbered by when they joined. The first
+// player to join who isn't a bot owns the game, unless it is part of a
+// tournament.
+func (sg *ScrabbleGame) applyJoin(e Event) {
+	if sg.ownerID == uuid.Nil && !e.Bot && sg.TournamentID == uuid.Nil {
+		sg.ownerID = e.Player
+	}
+	sg.Players[e.Player] = &Player{
+		ID:       e.Player,
+		Name:     e.Name,
+		Number:   len(sg.Players),
+		Seat:     engine.Seat{Tiles: make(Letters, 0)},
+		Bot:      e.Bot,
+		BotLevel: e.BotLevel,
+		Rating:   e.Rating,
+		Account:  e.Account,
+		State:    make(chan GameStateResponse),
+		Play:     make(chan GameStateResponse),
+	}
 }
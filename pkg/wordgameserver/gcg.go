@@ -0,0 +1,180 @@
+package wordgameserver
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteGCG writes a game in the GCG annotation format read by analysis tools
+// such as Quackle and cross-tables. Challenged plays are followed by their
+// withdrawal, and points added or taken away when the game ended are written
+// as end of game adjustments.
+func WriteGCG(w io.Writer, r GameReplay) error {
+	v, err := lookupVariant(r.Options.Variant)
+	if err != nil {
+		return err
+	}
+	v = v.withTiles(r.Options.tileSet())
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#character-encoding UTF-8\n")
+
+	nicks := make([]string, len(r.Players))
+	for i, p := range r.Players {
+		nicks[i] = gcgNick(p.Name, i)
+		bw.WriteString("#player" + strconv.Itoa(i+1) + " " + nicks[i] + " " + p.Name + "\n")
+	}
+
+	board := v.boardFor(r.Options.Layout)
+	totals := make([]int, len(r.Players))
+
+	// event writes a line for a player's move or adjustment and keeps their
+	// running total
+	event := func(player int, rack Letters, move string, score int) {
+		totals[player] += score
+		bw.WriteString(">" + nicks[player] + ": " + gcgTiles(rack) + " " + move + " " + gcgScore(score) + " " + strconv.Itoa(totals[player]) + "\n")
+	}
+
+	for i, m := range r.Moves {
+		if m.Player < 0 || m.Player >= len(r.Players) {
+			return errors.New("Move " + strconv.Itoa(i+1) + " was made by an unknown player")
+		}
+
+		switch {
+		case m.Kicked:
+			bw.WriteString("#note " + nicks[m.Player] + " was removed\n")
+			continue
+		case m.Resign:
+			bw.WriteString("#note " + nicks[m.Player] + " resigned\n")
+			continue
+		case m.Pass:
+			event(m.Player, m.Rack, "-", 0)
+		case m.Swap:
+			event(m.Player, m.Rack, "-"+gcgTiles(m.Swapped), 0)
+		default:
+			played := board
+			if err := replayMove(played, m, i); err != nil {
+				return err
+			} else if len(m.Squares) == 0 {
+				return errors.New("Move " + strconv.Itoa(i+1) + " doesn't record the tiles placed")
+			}
+			event(m.Player, m.Rack, gcgPlay(played, m.Squares), m.Score)
+
+			if m.Retracted {
+				event(m.Player, m.Rack, "--", -m.Score)
+			} else {
+				board = played
+			}
+		}
+
+		if m.TimedOut {
+			bw.WriteString("#note Turn timer ran out\n")
+		}
+	}
+
+	// Whatever separates the final scores from the moves was added or taken
+	// away at the end of the game, for tiles left in hand or time used
+	for i, p := range r.Players {
+		diff := p.Score - totals[i]
+		if diff == 0 {
+			continue
+		}
+
+		if rack := gcgValue(p.Tiles, v.tiles); rack > 0 && diff <= -rack {
+			event(i, p.Tiles, "("+gcgTiles(p.Tiles)+")", -rack)
+			diff += rack
+		}
+		if diff != 0 {
+			event(i, p.Tiles, "(time)", diff)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// gcgPlay describes a play in GCG notation, given the squares its tiles were
+// placed on. The position is given as row then column for plays across and
+// column then row for plays down, and letters already on the board are shown
+// as dots.
+func gcgPlay(sb ScrabbleBoard, placed []SquareCoordinate) string {
+	step := SquareCoordinate{Row: 0, Col: 1}
+	if len(placed) > 1 && placed[0].Col == placed[1].Col {
+		step = SquareCoordinate{Row: 1, Col: 0}
+	} else if len(placed) == 1 && len(sb.WordAt(placed[0], step).Squares) < 2 {
+		// A single tile is described by the longer word it forms
+		step = SquareCoordinate{Row: 1, Col: 0}
+	}
+
+	isPlaced := make(map[SquareCoordinate]bool, len(placed))
+	for _, sc := range placed {
+		isPlaced[sc] = true
+	}
+
+	w := sb.WordAt(placed[0], step)
+	start := w.Squares[0]
+	row := strconv.Itoa(start.Row + 1)
+	col := string(rune('A' + start.Col))
+
+	pos := row + col
+	if step.Row == 1 {
+		pos = col + row
+	}
+
+	var word strings.Builder
+	for _, sc := range w.Squares {
+		squ := sb.At(sc)
+		switch {
+		case !isPlaced[sc]:
+			word.WriteByte('.')
+		case squ.Blank:
+			word.WriteString(strings.ToLower(squ.Letter.String()))
+		default:
+			word.WriteString(squ.Letter.String())
+		}
+	}
+
+	return pos + " " + word.String()
+}
+
+// gcgNick returns a player's name with spaces replaced, since GCG nicknames
+// can't contain them, or a numbered name if they have none
+func gcgNick(name string, index int) string {
+	nick := strings.Join(strings.Fields(name), "_")
+	if nick == "" {
+		nick = "player" + strconv.Itoa(index+1)
+	}
+	return nick
+}
+
+// gcgTiles writes tiles the way GCG expects, with blanks as question marks
+func gcgTiles(tiles Letters) string {
+	var s strings.Builder
+	for _, t := range tiles {
+		if t == ' ' {
+			s.WriteByte('?')
+		} else {
+			s.WriteString(t.String())
+		}
+	}
+	return s.String()
+}
+
+// gcgScore writes a score with its sign
+func gcgScore(score int) string {
+	if score < 0 {
+		return strconv.Itoa(score)
+	}
+	return "+" + strconv.Itoa(score)
+}
+
+// gcgValue totals the value of the tiles in the set
+func gcgValue(hand Letters, set map[Letter]Tile) int {
+	v := 0
+	for _, t := range hand {
+		v += set[t].Value
+	}
+	return v
+}
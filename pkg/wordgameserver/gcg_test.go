@@ -0,0 +1,72 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+)
+
+func TestWriteGCG(t *testing.T) {
+	tile := func(l Letter) Tile {
+		return Tile{Letter: l, Value: tiles[l].Value}
+	}
+
+	r := GameReplay{
+		Players: []*Player{
+			{Name: "ashley 1", Number: 0, Seat: engine.Seat{Score: 1, Tiles: Letters("AB ")}},
+			{Name: "ashley2", Number: 1, Seat: engine.Seat{Score: -14, Tiles: Letters("Q")}},
+		},
+		Moves: []Move{
+			{
+				Player:  0,
+				Rack:    Letters("CATXXXX"),
+				Squares: []SquareCoordinate{{Row: 7, Col: 6}, {Row: 7, Col: 7}, {Row: 7, Col: 8}},
+				Tiles:   []Tile{tile('C'), tile('A'), tile('T')},
+				Score:   5,
+			},
+			{
+				Player:  1,
+				Rack:    Letters("S DOGXX"),
+				Squares: []SquareCoordinate{{Row: 7, Col: 9}},
+				Tiles:   []Tile{tile('S')},
+				Score:   6,
+			},
+			{
+				Player:    0,
+				Rack:      Letters("XXXX B "),
+				Squares:   []SquareCoordinate{{Row: 8, Col: 9}},
+				Tiles:     []Tile{{Letter: 'X', Blank: true}},
+				Score:     1,
+				Retracted: true,
+			},
+			{Player: 1, Rack: Letters("DOGXX Q"), Swap: true, Swapped: Letters("XX")},
+			{Player: 0, Rack: Letters("AB "), Pass: true, TimedOut: true},
+			{Player: 1, Rack: Letters("Q"), Pass: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGCG(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `#character-encoding UTF-8
+#player1 ashley_1 ashley 1
+#player2 ashley2 ashley2
+>ashley_1: CATXXXX 8G CAT +5 5
+>ashley2: S?DOGXX 8G ...S +6 6
+>ashley_1: XXXX?B? J8 .x +1 6
+>ashley_1: XXXX?B? -- -1 5
+>ashley2: DOGXX?Q -XX +0 6
+>ashley_1: AB? - +0 5
+#note Turn timer ran out
+>ashley2: Q - +0 6
+>ashley_1: AB? (AB?) -4 1
+>ashley2: Q (Q) -10 -4
+>ashley2: Q (time) -10 -14
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Wrote GCG:\n%v\nexpected:\n%v", got, expected)
+	}
+}
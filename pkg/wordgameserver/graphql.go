@@ -0,0 +1,310 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// GraphQLRequest is the format of a GraphQL query sent by a client, either as
+// the body of a POST request or as query parameters of a GET request
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// gameView is a copy of a game's state taken while it is locked, so every
+// field of a query is resolved from the same moment in the game
+type gameView struct {
+	ID       string
+	Active   bool
+	Finished bool
+	Turn     int
+	Winners  []int
+	Players  []*Player
+	Board    ScrabbleBoard
+	History  []Move
+	Tiles    *string // rack of the player who asked, if they gave their ID
+	BagSize  int
+	TurnEnds *time.Time
+	Clocks   []float64
+	Paused   bool
+}
+
+// squareView is a square of the board along with where it is
+type squareView struct {
+	Row    int
+	Col    int
+	Type   string
+	Letter *string
+	Value  int
+	Blank  bool
+}
+
+// letterField resolves the letter on a Tile as a string
+var letterField = &graphql.Field{
+	Type: graphql.String,
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if t, ok := p.Source.(Tile); ok {
+			return string(t.Letter), nil
+		}
+		return nil, nil
+	},
+}
+
+var coordinateType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Coordinate",
+	Fields: graphql.Fields{
+		"row": &graphql.Field{Type: graphql.Int},
+		"col": &graphql.Field{Type: graphql.Int},
+	},
+})
+
+var tileType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Tile",
+	Fields: graphql.Fields{
+		"letter": letterField,
+		"value":  &graphql.Field{Type: graphql.Int},
+		"blank":  &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+var playerType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Player",
+	Fields: graphql.Fields{
+		"number":   &graphql.Field{Type: graphql.Int},
+		"name":     &graphql.Field{Type: graphql.String},
+		"score":    &graphql.Field{Type: graphql.Int},
+		"resigned": &graphql.Field{Type: graphql.Boolean},
+		"kicked":   &graphql.Field{Type: graphql.Boolean},
+		"bot":      &graphql.Field{Type: graphql.Boolean},
+		"botLevel": &graphql.Field{Type: graphql.String},
+		"rating":   &graphql.Field{Type: graphql.Int},
+	},
+})
+
+var squareType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Square",
+	Fields: graphql.Fields{
+		"row":    &graphql.Field{Type: graphql.Int},
+		"col":    &graphql.Field{Type: graphql.Int},
+		"type":   &graphql.Field{Type: graphql.String},
+		"letter": &graphql.Field{Type: graphql.String},
+		"value":  &graphql.Field{Type: graphql.Int},
+		"blank":  &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+var moveType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Move",
+	Fields: graphql.Fields{
+		"player":    &graphql.Field{Type: graphql.Int},
+		"swap":      &graphql.Field{Type: graphql.Boolean},
+		"pass":      &graphql.Field{Type: graphql.Boolean},
+		"resign":    &graphql.Field{Type: graphql.Boolean},
+		"kicked":    &graphql.Field{Type: graphql.Boolean},
+		"timedOut":  &graphql.Field{Type: graphql.Boolean},
+		"words":     &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"squares":   &graphql.Field{Type: graphql.NewList(coordinateType)},
+		"tiles":     &graphql.Field{Type: graphql.NewList(tileType)},
+		"score":     &graphql.Field{Type: graphql.Int},
+		"retracted": &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+var gameType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Game",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.ID},
+		"active":   &graphql.Field{Type: graphql.Boolean},
+		"finished": &graphql.Field{Type: graphql.Boolean},
+		"turn":     &graphql.Field{Type: graphql.Int},
+		"winners":  &graphql.Field{Type: graphql.NewList(graphql.Int)},
+		"players":  &graphql.Field{Type: graphql.NewList(playerType)},
+		"board": &graphql.Field{
+			Type:        graphql.NewList(squareType),
+			Description: "Squares of the board, row by row, or just those with tiles on if occupied is true",
+			Args: graphql.FieldConfigArgument{
+				"occupied": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+			},
+			Resolve: resolveBoard,
+		},
+		"history":  &graphql.Field{Type: graphql.NewList(moveType)},
+		"tiles":    &graphql.Field{Type: graphql.String, Description: "Rack of the player whose ID was given"},
+		"bagSize":  &graphql.Field{Type: graphql.Int},
+		"turnEnds": &graphql.Field{Type: graphql.DateTime},
+		"clocks":   &graphql.Field{Type: graphql.NewList(graphql.Float)},
+		"paused":   &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+var graphQLSchema = mustSchema(graphql.SchemaConfig{
+	Query: graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"game": &graphql.Field{
+				Type:        gameType,
+				Description: "Game with the ID, as seen by the player with playerId if given",
+				Args: graphql.FieldConfigArgument{
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"playerId": &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Resolve: resolveGame,
+			},
+		},
+	}),
+})
+
+func mustSchema(config graphql.SchemaConfig) graphql.Schema {
+	s, err := graphql.NewSchema(config)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// resolveGame copies the state of the requested game for the rest of the query
+// to be resolved from. Games are looked up in the server given as the root
+// value of the query.
+func resolveGame(p graphql.ResolveParams) (interface{}, error) {
+	root, _ := p.Info.RootValue.(map[string]interface{})
+	s, ok := root["server"].(*Server)
+	if !ok {
+		return nil, errors.New("No server to look up games in")
+	}
+
+	gameID, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, errors.New("Invalid game ID")
+	}
+
+	g, err := s.lookupGame(p.Context, gameID)
+	if err == ErrGameNotFound {
+		return nil, errors.New("No existing game with that ID")
+	} else if err != nil {
+		return nil, err
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	v := &gameView{
+		ID:       g.ID.String(),
+		Active:   g.Active,
+		Finished: g.Finished,
+		Winners:  g.Winners,
+		Board:    g.Board.Clone(),
+		History:  g.History(),
+		BagSize:  len(g.TileBag),
+		Paused:   g.paused,
+	}
+
+	playerList := g.playerList()
+	for _, player := range playerList {
+		copied := *player
+		v.Players = append(v.Players, &copied)
+	}
+	if len(playerList) > 0 {
+		v.Turn = g.TurnCount % len(playerList)
+	}
+	if g.Active && !g.Finished && !g.paused && g.Options.TurnTimer > 0 {
+		d := g.turnDeadline()
+		v.TurnEnds = &d
+	}
+	v.Clocks = g.clocks(playerList)
+
+	if id, ok := p.Args["playerId"].(string); ok {
+		playerID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("Invalid player ID")
+		}
+		player, ok := g.Players[playerID]
+		if !ok {
+			return nil, errors.New("No player with that ID in game")
+		}
+		tiles := string(player.Tiles)
+		v.Tiles = &tiles
+	}
+
+	return v, nil
+}
+
+// resolveBoard lists the squares of a game's board
+func resolveBoard(p graphql.ResolveParams) (interface{}, error) {
+	v, ok := p.Source.(*gameView)
+	if !ok {
+		return nil, nil
+	}
+	occupied, _ := p.Args["occupied"].(bool)
+
+	var squares []squareView
+	for row := range v.Board {
+		for col, sq := range v.Board[row] {
+			s := squareView{
+				Row:   row,
+				Col:   col,
+				Type:  sq.SquareType,
+				Value: sq.Value,
+				Blank: sq.Blank,
+			}
+			if sq.Letter != 0 {
+				letter := string(sq.Letter)
+				s.Letter = &letter
+			} else if occupied {
+				continue
+			}
+			squares = append(squares, s)
+		}
+	}
+	return squares, nil
+}
+
+// graphQLHandler handles GraphQL queries, so clients can ask for just the
+// parts of a game's state they need. Results and errors are returned in the
+// standard GraphQL response format.
+func (s *Server) graphQLHandler(w http.ResponseWriter, r *http.Request) {
+	var j GraphQLRequest
+
+	if r.Method == http.MethodGet {
+		j.Query = r.URL.Query().Get("query")
+		j.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &j.Variables); err != nil {
+				http.Error(w, "Invalid variables parameter", http.StatusBadRequest)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if j.Query == "" {
+		http.Error(w, "No query given", http.StatusBadRequest)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         graphQLSchema,
+		RequestString:  j.Query,
+		OperationName:  j.OperationName,
+		VariableValues: j.Variables,
+		RootObject:     map[string]interface{}{"server": s},
+		Context:        r.Context(),
+	})
+
+	resp, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
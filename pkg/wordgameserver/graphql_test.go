@@ -0,0 +1,110 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	firstID, _ := newGame.addPlayer("ashley1")
+	newGame.addPlayer("ashley2")
+
+	newGame.Lock()
+	err := newGame.start()
+	newGame.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := `query($id: ID!, $player: ID) {
+		game(id: $id, playerId: $player) {
+			turn
+			tiles
+			players { name score }
+			board(occupied: true) { row }
+		}
+	}`
+	payload, err := json.Marshal(GraphQLRequest{
+		Query: query,
+		Variables: map[string]interface{}{
+			"id":     newGame.ID.String(),
+			"player": firstID.String(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/graphql", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.graphQLHandler).ServeHTTP(rr, req)
+
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", c, http.StatusOK, rr.Body)
+	}
+
+	var result struct {
+		Data struct {
+			Game map[string]json.RawMessage `json:"game"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	if err = json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	} else if len(result.Errors) > 0 {
+		t.Fatalf("Query returned errors: %v", result.Errors)
+	}
+
+	// Only the fields asked for should be returned
+	if len(result.Data.Game) != 4 {
+		t.Errorf("Returned fields %v, expected turn, tiles, players and board", result.Data.Game)
+	}
+
+	var tiles string
+	if err = json.Unmarshal(result.Data.Game["tiles"], &tiles); err != nil {
+		t.Fatal(err)
+	} else if len(tiles) != maxTiles {
+		t.Errorf("Returned %v tiles, expected %v", len(tiles), maxTiles)
+	}
+
+	var players []map[string]interface{}
+	if err = json.Unmarshal(result.Data.Game["players"], &players); err != nil {
+		t.Fatal(err)
+	} else if len(players) != 2 || players[0]["name"] != "ashley1" || len(players[0]) != 2 {
+		t.Errorf("Returned players %v, expected names and scores of both players", players)
+	}
+
+	if string(result.Data.Game["board"]) != "[]" && string(result.Data.Game["board"]) != "null" {
+		t.Errorf("Returned occupied squares %s for empty board", result.Data.Game["board"])
+	}
+
+	// Queries can also be made with GET, and errors are reported in the result
+	q := url.Values{"query": {`{ game(id: "` + newGame.ID.String() + `", playerId: "` + newGame.ID.String() + `") { tiles } }`}}
+	req, err = http.NewRequest("GET", "/graphql?"+q.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(srv.graphQLHandler).ServeHTTP(rr, req)
+
+	result.Errors = nil
+	if err = json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	} else if len(result.Errors) == 0 {
+		t.Error("Expected error querying tiles of player not in game")
+	}
+}
@@ -0,0 +1,105 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// hintHandler handles requests from players for a move they could make with
+// their rack, chosen by the server's bot strategy at the bot_level given,
+// which sets how good the move is. The move is returned as a GamePlayRequest
+// that can be sent back to play it.
+func (s *Server) hintHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	var level string
+	if j.BotLevel != nil {
+		level = *j.BotLevel
+	}
+	s.writeHint(w, r, j.GameID, *j.PlayerID, level)
+}
+
+// getHintHandler handles requests from players, identified by the player_id
+// query parameter, for a move like hintHandler, at the level query parameter
+func (s *Server) getHintHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	playerID, err := uuid.Parse(r.URL.Query().Get("player_id"))
+	if err != nil {
+		http.Error(w, "Invalid player_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	s.writeHint(w, r, gameID, playerID, r.URL.Query().Get("level"))
+}
+
+// writeHint replies with a move the player could make in the game, chosen at
+// the level. When there is no play and too few tiles are left in the bag to
+// swap, the move has no tiles, meaning the player can only pass.
+func (s *Server) writeHint(w http.ResponseWriter, r *http.Request, gameID, playerID uuid.UUID, level string) {
+	if s.bot == nil {
+		http.Error(w, "Server has no hints available", http.StatusBadRequest)
+		return
+	} else if !validBotLevel(s.bot, level) {
+		http.Error(w, "Unknown hint level '"+level+"'", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+	g.Lock()
+	opts := g.Options
+	tournament := g.TournamentID != uuid.Nil
+	g.Unlock()
+
+	// Moves are found with the bots' English dictionary, and hints would
+	// give players an unfair advantage in games that count
+	if opts.tileSet() != nil || opts.lexicon() != "" {
+		http.Error(w, "Hints can only be given in English games played with the server's dictionary", http.StatusBadRequest)
+		return
+	} else if opts.Rated || tournament {
+		http.Error(w, "Hints can't be given in rated or tournament games", http.StatusBadRequest)
+		return
+	}
+
+	_, state, ok := s.sendGameRequest(GamePlayRequest{GameID: gameID, PlayerID: playerID}, w, r)
+	if !ok {
+		return
+	} else if state.Finished {
+		http.Error(w, "Game has finished", http.StatusBadRequest)
+		return
+	}
+
+	move := s.bot.NextMove(state, level)
+	if move.Swap && state.BagCount < maxTiles {
+		move = GamePlayRequest{}
+	}
+	move.GameID = gameID
+	move.PlayerID = playerID
+
+	resp, err := json.Marshal(move)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
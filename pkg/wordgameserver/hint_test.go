@@ -0,0 +1,85 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHintHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	defer g.Stop()
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+
+	srv, err := NewServer(DefaultConfig(), nil, nil, swapBot{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.games.Put(g)
+
+	hint := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest("GET", "/v2/games/"+g.ID.String()+"/hint?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := hint("player_id=" + ids[0].String() + "&level=impossible"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Hint at an unknown level returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := hint("player_id=" + ids[1].String() + "&level=easy")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var move GamePlayRequest
+	if err = json.NewDecoder(rr.Body).Decode(&move); err != nil {
+		t.Fatal(err)
+	} else if !move.Swap || move.Tiles.String() != "D" || move.GameID != g.ID || move.PlayerID != ids[1] {
+		t.Errorf("Hinted move %+v, expected a swap of D by the second player", move)
+	}
+
+	// Hints don't change the game
+	g.Lock()
+	if len(g.history) != 0 || g.Players[ids[1]].Tiles.String() != "DOGSXXX" {
+		t.Error("Giving a hint should not make a move")
+	}
+	// Tiles can't be swapped once fewer than a full hand are left in the bag,
+	// so the only move left is to pass
+	g.TileBag = g.TileBag[:maxTiles-1]
+	g.updateView(g.playerList())
+	g.Unlock()
+
+	rr = hint("player_id=" + ids[1].String() + "&level=easy")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	move = GamePlayRequest{}
+	if err = json.NewDecoder(rr.Body).Decode(&move); err != nil {
+		t.Fatal(err)
+	} else if move.Swap || len(move.Tiles) != 0 || move.PlayerID != ids[1] {
+		t.Errorf("Hinted move %+v with a near-empty bag, expected a pass", move)
+	}
+
+	g.Lock()
+	g.Options.Rated = true
+	g.Unlock()
+
+	if rr = hint("player_id=" + ids[0].String()); rr.Code != http.StatusBadRequest {
+		t.Errorf("Hint in a rated game returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	srv.bot = nil
+	if rr = hint("player_id=" + ids[0].String()); rr.Code != http.StatusBadRequest {
+		t.Errorf("Hint without a bot strategy returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -1,37 +1,106 @@
 package wordgameserver
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"log"
 	"net/http"
-	"sync"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type scrabbleServer struct {
-	activeGames map[uuid.UUID]*ScrabbleGame
-}
-
 // GeneralGameRequest is the catch-all request format for client requests that
 // don't require special fields
 type GeneralGameRequest struct {
-	GameID     uuid.UUID  `json:"game_id"`
-	PlayerID   *uuid.UUID `json:"player_id,omitempty"`
-	PlayerName *string    `json:"player_name,omitempty"`
+	GameID     uuid.UUID    `json:"game_id"`
+	PlayerID   *uuid.UUID   `json:"player_id,omitempty"`
+	PlayerName *string      `json:"player_name,omitempty"`
+	Options    *GameOptions `json:"options,omitempty"`
+	BotLevel   *string      `json:"bot_level,omitempty"`
+	Webhooks   []Webhook    `json:"webhooks,omitempty"`
+	JoinCode   *string      `json:"join_code,omitempty"`   // code for joining a private game, which can be used instead of its ID
+	Passphrase *string      `json:"passphrase,omitempty"`  // set when creating a game to protect it, and needed to join it afterwards
+	Invite     *string      `json:"invite,omitempty"`      // token of an invite, which can be used once instead of the game's ID, join code and passphrase
+	Invites    int          `json:"invites,omitempty"`     // number of invites to create along with a game
+	InviteList []Invite     `json:"invite_list,omitempty"` // invites created along with a game
+	Since      *int         `json:"since,omitempty"`       // number of moves seen by a client asking for the state, to be sent what has changed since
+	Timeout    *int         `json:"timeout,omitempty"`     // seconds a client waiting for the state to change is willing to wait
 }
 
 // GameStateResponse is the format of the response sent to clients when they
 // request the current game state
 type GameStateResponse struct {
-	GameID      uuid.UUID     `json:"game_id"`
-	PlayerID    uuid.UUID     `json:"-"`
-	Players     []*Player     `json:"players"`
-	Board       ScrabbleBoard `json:"board"`
-	PlayerTurn  int           `json:"turn"`
-	PlayerTiles []byte        `json:"tiles"`
-	Error       error         `json:"-"`
+	GameID        uuid.UUID        `json:"game_id"`
+	PlayerID      uuid.UUID        `json:"-"`
+	Active        bool             `json:"active"`
+	Finished      bool             `json:"finished"`
+	Winners       []int            `json:"winners,omitempty"` // numbers of the winning players once the game has finished
+	Players       []*Player        `json:"players"`
+	Board         ScrabbleBoard    `json:"board,omitempty"`   // whole board, left out when the diff is sent instead
+	Variant       string           `json:"variant,omitempty"` // variant the game is played as, empty for the standard game
+	Lexicon       string           `json:"lexicon,omitempty"` // lexicon words are checked against, LexiconCustom for the game's own words, empty for the dictionary of its language
+	PlayerTurn    int              `json:"turn"`
+	PlayerTiles   []Tile           `json:"tiles"` // tiles in the player's hand with their values
+	Challenge     *ChallengeResult `json:"challenge,omitempty"`
+	TurnEnds      *time.Time       `json:"turn_ends,omitempty"`      // when the current turn runs out, if turns are timed
+	TimedOut      *int             `json:"timed_out,omitempty"`      // number of the player whose turn just ran out
+	Clocks        []float64        `json:"clocks,omitempty"`         // seconds left on each player's clock, if the game has clocks
+	UndoRequested bool             `json:"undo_requested,omitempty"` // true once the last move's player has asked to undo it, until it is undone or the game moves on
+	Reactions     []Reaction       `json:"reactions,omitempty"`      // players' reactions to the most recent move
+	Chat          []ChatMessage    `json:"chat,omitempty"`           // most recent messages posted to the game's chat, oldest first
+	Owner         *int             `json:"owner,omitempty"`          // number of the player who owns the game, if it has an owner
+	Paused        bool             `json:"paused,omitempty"`         // true while play is paused, with timers and clocks frozen
+	ResumeVotes   []int            `json:"resume_votes,omitempty"`   // numbers of the players who have asked to resume the paused game
+	MoveCount     int              `json:"move_count"`               // number of moves made, to ask for what has changed since next time
+	BagCount      int              `json:"bag_count"`                // number of tiles left in the bag
+	LastMove      *Move            `json:"last_move,omitempty"`      // most recent move, without its rack until the game has finished
+	Passes        int              `json:"passes"`                   // number of passes in a row since the last play or swap
+	Rematch       *RematchResponse `json:"rematch,omitempty"`        // game the player has been seated in to play again, once the game has finished and one is created
+	Diff          *StateDiff       `json:"diff,omitempty"`           // what has changed since the moves the client has seen, sent in place of the board if it asked
+	Error         error            `json:"-"`
+
+	version string // identifies the view the state was read from, for its ETag, empty if it wasn't read from one
+}
+
+// StateDiff is what has changed in a game since a client last saw it, which
+// is sent instead of the board to clients that say how many moves they have
+// seen. Clients replace their moves from Since onwards with the moves given,
+// and each square given with its tile.
+type StateDiff struct {
+	Since   int            `json:"since"`   // number of moves the diff follows on from, fewer than the client saw if moves were undone
+	Moves   []Move         `json:"moves"`   // moves made since, without racks until the game has finished
+	Squares []SquareChange `json:"squares"` // squares whose tile may have changed since
+}
+
+// SquareChange is a square of the board as it is now
+type SquareChange struct {
+	Row  int   `json:"row"`
+	Col  int   `json:"col"`
+	Tile *Tile `json:"tile"` // tile on the square, null if it is empty
+}
+
+// RackLetters returns the letters of the tiles in the player's hand
+func (s GameStateResponse) RackLetters() Letters {
+	letters := make(Letters, len(s.PlayerTiles))
+	for i, t := range s.PlayerTiles {
+		letters[i] = t.Letter
+	}
+	return letters
+}
+
+// GameHistoryResponse is the format of the response sent to clients when they
+// request the moves made in a game
+type GameHistoryResponse struct {
+	GameID  uuid.UUID `json:"game_id"`
+	Players []*Player `json:"players"`
+	Moves   []Move    `json:"moves"`
 }
 
 // GamePlayRequest is the format of the request a client sends when they would
@@ -41,43 +110,340 @@ type GamePlayRequest struct {
 	PlayerID uuid.UUID        `json:"player_id"`
 	StartPos SquareCoordinate `json:"start_pos"`
 	EndPos   SquareCoordinate `json:"end_pos"`
-	Tiles    []byte           `json:"tiles"`
-	Blanks   []byte           `json:"blanks,omitempty"`
+	Tiles    Letters          `json:"tiles"`
+	Blanks   Letters          `json:"blanks,omitempty"`
 	Swap     bool             `json:"swap"`
-	Play     bool             `json:"-"`
+	Type     requestType      `json:"-"`
+
+	// Placements lists the squares tiles are placed on, in place of the
+	// start and end positions, tiles and blanks
+	Placements []TilePlacement `json:"placements,omitempty"`
+
+	// ctx carries the span of whoever made the request, so the
+	// stateController's handling of it is traced as part of the same operation
+	ctx context.Context
+
+	// kick is the number of the player a kick request is to remove
+	kick int
+
+	// since is the number of moves a client asking for the state has seen,
+	// so it is sent what has changed since instead of the whole board
+	since *int
 }
 
-var (
-	serverMu sync.Mutex
-	server   = scrabbleServer{
-		activeGames: make(map[uuid.UUID]*ScrabbleGame),
-	}
-)
+// shutdownTimeout is how long in-flight requests are given to finish when the
+// server is shutting down, unless configured otherwise
+const shutdownTimeout = 30 * time.Second
+
+// requestTimeout is how long a request waits for a game's controller to
+// respond, unless configured otherwise
+const requestTimeout = 10 * time.Second
+
+// retryAfter is how many seconds clients are told to wait before retrying a
+// request that a game was too busy to respond to
+const retryAfter = 1
+
+// apiVersions registers the routes of each version of the API. Every version is
+// served at once, under a path prefix of its name, so request formats can
+// change in a new version without breaking clients of older ones.
+var apiVersions = map[string]func(s *Server, r *mux.Router){
+	"v1": (*Server).v1Routes,
+	"v2": (*Server).v2Routes,
+}
+
+// currentAPIVersion is the version new clients should use. Responses from
+// older versions are marked as deprecated.
+const currentAPIVersion = "v2"
 
-// StartWordGameServer is the function that is run to start the Word Game HTTP
-// server
-func StartWordGameServer(bindAddr string) error {
+// legacyAPIVersion is also served without a prefix, for clients from before
+// the API was versioned
+const legacyAPIVersion = "v1"
+
+// newRouter returns a router serving every version of the API, along with the
+// admin API and dashboard if they are enabled
+func (s *Server) newRouter() *mux.Router {
 	r := mux.NewRouter()
-	r.HandleFunc("/game/create", createGameHandler)
-	r.HandleFunc("/game/join", joinGameHandler)
-	r.HandleFunc("/game/start", startGameHandler)
-	r.HandleFunc("/game/state", gameStateHandler)
+	r.Use(traceRequests(), recordErrors(&s.recentErrors), routeGames(s.cluster))
+	for version, routes := range apiVersions {
+		sub := r.PathPrefix("/" + version).Subrouter()
+		routes(s, sub)
+		sub.Use(validateRequestBody(version))
+		if version != currentAPIVersion {
+			sub.Use(deprecated(currentAPIVersion))
+		}
+	}
+
+	// The admin API and its dashboard are only served when operators have
+	// been given a token
+	if s.cfg.AdminToken != "" {
+		r.HandleFunc("/admin", dashboardHandler).Methods(http.MethodGet)
+		admin := r.PathPrefix("/admin").Subrouter()
+		s.adminRoutes(admin)
+		admin.Use(requireAdmin(s.cfg.AdminToken))
+	}
+
+	legacy := r.NewRoute().Subrouter()
+	apiVersions[legacyAPIVersion](s, legacy)
+	legacy.Use(validateRequestBody(legacyAPIVersion), deprecated(currentAPIVersion))
+
+	return r
+}
 
-	return http.ListenAndServe(bindAddr, r)
+// v1Routes registers the routes of version 1 of the API
+func (s *Server) v1Routes(r *mux.Router) {
+	r.HandleFunc("/game/create", s.createGameHandler)
+	r.HandleFunc("/game/import", s.importGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/join", s.joinGameHandler)
+	r.HandleFunc("/game/start", s.startGameHandler)
+	r.HandleFunc("/game/state", s.gameStateHandler)
+	r.HandleFunc("/game/state/wait", s.waitStateHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/play", s.gamePlayHandler)
+	r.HandleFunc("/game/challenge", s.challengeHandler)
+	r.HandleFunc("/game/pass", s.passHandler)
+	r.HandleFunc("/game/resign", s.resignHandler)
+	r.HandleFunc("/game/cancel", s.cancelGameHandler).Methods(http.MethodDelete, http.MethodPost)
+	r.HandleFunc("/game/resume", s.resumeHandler)
+	r.HandleFunc("/game/rematch", s.rematchHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/fork", s.forkGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/hint", s.hintHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/undo", s.undoHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/kick", s.kickHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/pause", s.pauseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/unpause", s.unpauseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/options", s.gameOptionsHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/owner", s.transferOwnerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/chat", s.getChatHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/chat", s.postChatHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/react", s.reactHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/invite", s.inviteHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/validate", s.validateMoveHandler).Methods(http.MethodPost)
+	r.HandleFunc("/game/history", s.gameHistoryHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/replay", s.gameReplayHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/gcg", s.gameGCGHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/analysis", s.gameAnalysisHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/unseen", s.unseenTilesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/{id}/board.png", s.boardPNGHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/{id}/board.svg", s.boardSVGHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/{id}/board.txt", s.boardTextHandler).Methods(http.MethodGet)
+	r.HandleFunc("/game/ws", s.gameSocketHandler)
+	r.HandleFunc("/game/events", s.gameEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/graphql", s.graphQLHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/openapi.json", apiDocumentHandler("v1")).Methods(http.MethodGet)
 }
 
 // createGameHandler handles API requests for creating a new Scrabble game
-// instance
-func createGameHandler(w http.ResponseWriter, r *http.Request) {
-	newGame := createScrabbleGame()
+// instance. The request body is optional and may contain the game's options.
+func (s *Server) createGameHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	if r.Body != nil {
+		err := json.NewDecoder(r.Body).Decode(&j)
+		if err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var opts GameOptions
+	if j.Options != nil {
+		if err := j.Options.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts = *j.Options
+	}
+
+	if err := s.resolveLayout(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = s.resolveTileSet(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	v, err := lookupVariant(opts.Variant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newGame := createVariantGame(v, opts.Layout, opts.tileSet(), opts.Seed)
+	newGame.Options = opts
+	s.cluster.claim(newGame)
+
+	if err := validateWebhooks(j.Webhooks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newGame.webhooks = j.Webhooks
+
+	if j.Passphrase != nil {
+		if err := newGame.setPassphrase(*j.Passphrase); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	resp := GeneralGameRequest{
-		GameID: newGame.ID,
+		GameID:  newGame.ID,
+		Options: &newGame.Options,
+	}
+
+	if err := s.checkLexicon(newGame.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newGame.Validator = gameValidator(s.validator, newGame.Options)
+	if newGame.Validator == nil && newGame.Options.ChallengeWindow > 0 {
+		http.Error(w, "Challenges require the server to have a dictionary", http.StatusBadRequest)
+		return
+	} else if newGame.Options.Bots > 0 {
+		if s.bot == nil {
+			http.Error(w, "Server has no bots available", http.StatusBadRequest)
+			return
+		} else if !validBotLevel(s.bot, newGame.Options.BotLevel) {
+			http.Error(w, "Unknown bot level '"+newGame.Options.BotLevel+"'", http.StatusBadRequest)
+			return
+		} else if newGame.Options.Bots >= s.cfg.MaxPlayers {
+			http.Error(w, "Number of bots must be between 0 and "+strconv.Itoa(s.cfg.MaxPlayers-1), http.StatusBadRequest)
+			return
+		}
+	}
+	if j.Invites < 0 || j.Invites > s.cfg.MaxPlayers-newGame.Options.Bots {
+		http.Error(w, "Number of invites must be between 0 and "+strconv.Itoa(s.cfg.MaxPlayers-newGame.Options.Bots), http.StatusBadRequest)
+		return
+	}
+
+	if !s.checkGameLimit(w) {
+		return
+	}
+
+	if newGame.Options.Private {
+		if err := s.assignJoinCode(newGame); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.JoinCode = &newGame.JoinCode
+	}
+
+	if err := newGame.addBots(newGame.Options.Bots, newGame.Options.BotLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i := 0; i < j.Invites; i++ {
+		inv, err := s.newInvite(newGame)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.InviteList = append(resp.InviteList, inv)
 	}
 
-	serverMu.Lock()
-	server.activeGames[newGame.ID] = newGame
-	serverMu.Unlock()
+	if err := s.saveGame(newGame, w); err != nil {
+		return
+	}
+
+	gameData, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(gameData)
+}
+
+// importGameHandler handles requests to create a game from a GCG file or a
+// position, which starts straight away with the players it names. The IDs of
+// the players are returned so each of them can resume the game.
+func (s *Server) importGameHandler(w http.ResponseWriter, r *http.Request) {
+	var j GameImportRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var g *ScrabbleGame
+	switch {
+	case j.GCG != "" && j.Position != nil:
+		http.Error(w, "Import either a GCG file or a position, not both", http.StatusBadRequest)
+		return
+	case j.GCG != "":
+		g, err = importGCG(j.GCG)
+	case j.Position != nil:
+		g, err = importPosition(*j.Position)
+	default:
+		http.Error(w, "Missing gcg or position", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.cluster.claim(g)
+
+	if j.Options != nil {
+		if err = j.Options.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if j.Options.Bots > 0 {
+			http.Error(w, "Bots cannot be added to imported games", http.StatusBadRequest)
+			return
+		} else if j.Options.Rated {
+			// Imported games may be made up, so they can't be rated
+			http.Error(w, "Imported games cannot be rated", http.StatusBadRequest)
+			return
+		} else if !j.Options.standard() {
+			http.Error(w, "Imported games must be played on the standard board with English tiles", http.StatusBadRequest)
+			return
+		} else if j.Options.Seed != nil {
+			// The bag and turn order of imported games come from the import
+			http.Error(w, "Imported games cannot be seeded", http.StatusBadRequest)
+			return
+		}
+		g.Options = *j.Options
+	}
+
+	if err = validateWebhooks(j.Webhooks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	g.webhooks = j.Webhooks
+
+	if err = s.checkLexicon(g.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	g.Validator = gameValidator(s.validator, g.Options)
+	if g.Validator == nil && g.Options.ChallengeWindow > 0 {
+		http.Error(w, "Challenges require the server to have a dictionary", http.StatusBadRequest)
+		return
+	} else if len(g.Players) > s.cfg.MaxPlayers {
+		http.Error(w, "Games can have at most "+strconv.Itoa(s.cfg.MaxPlayers)+" players", http.StatusBadRequest)
+		return
+	}
+
+	if !s.checkGameLimit(w) {
+		return
+	}
+
+	resp := GameImportResponse{GameID: g.ID}
+	g.Lock()
+	defer g.Unlock()
+	for _, p := range g.playerList() {
+		resp.PlayerIDs = append(resp.PlayerIDs, p.ID)
+	}
+
+	s.adoptGame(g)
+	if err = g.start(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = s.saveGame(g, w); err != nil {
+		return
+	}
 
 	gameData, err := json.Marshal(resp)
 	if err != nil {
@@ -91,10 +457,11 @@ func createGameHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // joinGameHandler handles requests from players to join a specified game. It
-// also creates a player and returns their ID to the client.
-func joinGameHandler(w http.ResponseWriter, r *http.Request) {
+// also creates a player and returns their ID to the client. If a bot level is
+// given, a computer player is added at that level instead and no player ID is
+// returned.
+func (s *Server) joinGameHandler(w http.ResponseWriter, r *http.Request) {
 	var j GeneralGameRequest
-	var g *ScrabbleGame
 
 	// Decode Game ID
 	err := json.NewDecoder(r.Body).Decode(&j)
@@ -103,23 +470,122 @@ func joinGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.joinGame(w, r, j, http.StatusOK)
+}
+
+// joinGame adds the player or bot described by the request to the game, found
+// by its invite or join code if the request has one, responding with the
+// request along with the new player's ID and the status given. Players joining
+// with an account's token are linked to the account and take its username.
+// Invited players don't need the game's passphrase, but each invite only lets
+// one player join.
+func (s *Server) joinGame(w http.ResponseWriter, r *http.Request, j GeneralGameRequest, status int) {
+	var grant *uuid.UUID
+	if j.Invite != nil {
+		gameID, g, err := s.checkInvite(*j.Invite)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if j.GameID != uuid.Nil && j.GameID != gameID {
+			http.Error(w, "Invite is for a different game", http.StatusBadRequest)
+			return
+		} else if j.BotLevel != nil {
+			http.Error(w, "Invites can't be used to add bots", http.StatusBadRequest)
+			return
+		}
+		j.GameID, grant = gameID, &g
+	} else if j.JoinCode != nil {
+		gameID, err := s.gameByJoinCode(*j.JoinCode)
+		if err == ErrGameNotFound {
+			http.Error(w, "No existing game with that join code", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		j.GameID = gameID
+	}
+
+	// Games found by code are only known now, so they couldn't be routed
+	// before the request got here
+	if !s.cluster.owns(j.GameID) {
+		body, err := json.Marshal(j)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		setRequestBody(r, body)
+		if s.cluster.forward(w, r, j.GameID) {
+			return
+		}
+	}
+
 	// Retrieve the game that matches ID requested
-	g, err = getGame(j.GameID, w)
+	g, err := s.getGame(r.Context(), j.GameID, w)
 	if err != nil {
 		return
 	}
 
+	account, ok := s.requestAccount(w, r)
+	if !ok {
+		return
+	}
+
+	if j.BotLevel != nil {
+		if s.bot == nil {
+			http.Error(w, "Server has no bots available", http.StatusBadRequest)
+			return
+		} else if !validBotLevel(s.bot, *j.BotLevel) {
+			http.Error(w, "Unknown bot level '"+*j.BotLevel+"'", http.StatusBadRequest)
+			return
+		}
+	} else if account != nil {
+		j.PlayerName = &account.Username
+	} else if j.PlayerName == nil {
+		http.Error(w, "Missing player_name", http.StatusBadRequest)
+		return
+	} else if !s.checkPlayerName(w, *j.PlayerName) {
+		return
+	}
+
+	if grant == nil {
+		if err = g.checkPassphrase(j.Passphrase); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	j.Passphrase = nil
+
 	g.Lock()
 	defer g.Unlock()
 
-	// Set field in response so player knows their ID
-	playerID, err := g.addPlayer(*j.PlayerName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if len(g.Players) >= s.cfg.MaxPlayers {
+		http.Error(w, "Maximum players reached for game", http.StatusBadRequest)
 		return
 	}
 
-	j.PlayerID = &playerID
+	if j.BotLevel != nil {
+		var name string
+		if j.PlayerName != nil {
+			name = *j.PlayerName
+		}
+		if _, err = g.addBot(name, *j.BotLevel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// Set field in response so player knows their ID
+		playerID, err := g.addInvitedPlayer(*j.PlayerName, account, grant)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		j.PlayerID = &playerID
+	}
+
+	if err = s.saveGame(g, w); err != nil {
+		return
+	}
 
 	// Create response containing game ID and new player ID
 	resp, err := json.Marshal(j)
@@ -129,16 +595,15 @@ func joinGameHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	w.Write(resp)
 }
 
 // startGameHandler is a handler that will start a game upon request, marking it
 // as active and no longer joinable by other players. It also kicks off the
 // goroutine for the specified game.
-func startGameHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) startGameHandler(w http.ResponseWriter, r *http.Request) {
 	var j GeneralGameRequest
-	var g *ScrabbleGame
 
 	// Decode Game ID
 	err := json.NewDecoder(r.Body).Decode(&j)
@@ -147,8 +612,13 @@ func startGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.startGame(w, r, j.GameID)
+}
+
+// startGame deals the tiles of the game and begins the first turn
+func (s *Server) startGame(w http.ResponseWriter, r *http.Request, gameID uuid.UUID) {
 	// Retrieve game instance
-	g, err = getGame(j.GameID, w)
+	g, err := s.getGame(r.Context(), gameID, w)
 	if err != nil {
 		return
 	}
@@ -162,13 +632,250 @@ func startGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = s.saveGame(g, w); err != nil {
+		return
+	}
+	g.runBots(s.bot)
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
+// cancelGameHandler handles requests from the owner of a game to cancel it,
+// which stops it and removes it from the server
+func (s *Server) cancelGameHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	s.cancelGame(w, r, j.GameID, *j.PlayerID)
+}
+
+// cancelGame removes the game at the request of its owner
+func (s *Server) cancelGame(w http.ResponseWriter, r *http.Request, gameID, playerID uuid.UUID) {
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	_, ok := g.Players[playerID]
+	owner := g.owner()
+	g.Unlock()
+	if !ok {
+		http.Error(w, "No player with that ID in game", http.StatusBadRequest)
+		return
+	} else if owner == nil || owner.ID != playerID {
+		http.Error(w, "Only the game's owner can cancel it", http.StatusForbidden)
+		return
+	}
+
+	if err = s.deleteGame(g, w); err != nil {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// resumeHandler handles requests from players reconnecting after losing their
+// connection, using their player ID as the token for their session. Any
+// WebSockets still open for the player are closed so the client can open a
+// fresh one, and the full game state is returned so the client can resync,
+// whether or not the game has started.
+func (s *Server) resumeHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	s.resumeGame(w, r, j.GameID, *j.PlayerID)
+}
+
+// resumeGame responds with the player's current state, disconnecting any of
+// their other sessions
+func (s *Server) resumeGame(w http.ResponseWriter, r *http.Request, gameID, playerID uuid.UUID) {
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	// The controller holds the lock while it changes the game, so the state
+	// can be read directly rather than queueing behind other requests
+	g.Lock()
+	if _, ok := g.Players[playerID]; !ok {
+		g.Unlock()
+		http.Error(w, "No player with that ID in game", http.StatusBadRequest)
+		return
+	}
+	resp, err := json.Marshal(g.getState(playerID, g.playerList()))
+	g.dropSubscriptions(playerID)
+	g.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// gameHistoryHandler handles requests for every move made in a game, in order,
+// so clients can show a scoresheet. The game is identified by its path or the
+// game_id query parameter. No player ID is needed, so spectators can catch up
+// too.
+func (s *Server) gameHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	moves := g.History()
+	if !g.Finished {
+		// Racks would give away the tiles players hold until the game is over
+		for i := range moves {
+			moves[i].Rack = nil
+			moves[i].Swapped = nil
+		}
+	}
+	resp, err := json.Marshal(GameHistoryResponse{
+		GameID:  g.ID,
+		Players: g.playerList(),
+		Moves:   moves,
+	})
+	g.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// gameReplayHandler handles requests to export a finished game, identified by
+// its path or the game_id query parameter, so it can be replayed. If the move query
+// parameter is given, the board and scores after that many moves are returned
+// instead.
+func (s *Server) gameReplayHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	move := -1
+	if m := r.URL.Query().Get("move"); m != "" {
+		if move, err = strconv.Atoi(m); err != nil {
+			http.Error(w, "Invalid move parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	finished := g.Finished
+	replay := g.replay()
+	players, err := json.Marshal(replay.Players)
+	g.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !finished {
+		http.Error(w, "Game has not finished", http.StatusBadRequest)
+		return
+	}
+
+	var resp []byte
+	if move >= 0 {
+		state, err := ReplayGame(replay, move)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err = json.Marshal(state)
+	} else {
+		// Players were encoded while the game was locked
+		resp, err = json.Marshal(struct {
+			GameReplay
+			Players json.RawMessage `json:"players"`
+		}{replay, players})
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// gameGCGHandler handles requests to download a finished game, identified by
+// its path or the game_id query parameter, in GCG format for analysis in other
+// tools
+func (s *Server) gameGCGHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	g.Lock()
+	finished := g.Finished
+	if finished {
+		err = WriteGCG(&buf, g.replay())
+	}
+	g.Unlock()
+	if !finished {
+		http.Error(w, "Game has not finished", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+gameID.String()+`.gcg"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
 // gameStateHandler handles requests for the game's current state. It will
 // respond using the GameStateResponse struct.
-func gameStateHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) gameStateHandler(w http.ResponseWriter, r *http.Request) {
 	var j GeneralGameRequest
 
 	// Decode game ID and player ID. Player ID is needed so the server knows
@@ -179,16 +886,22 @@ func gameStateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if j.Since != nil && *j.Since < 0 {
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+
 	// Send request to game controller
-	gameRequestHelper(GamePlayRequest{
+	s.gameRequestHelper(GamePlayRequest{
 		GameID:   j.GameID,
 		PlayerID: *j.PlayerID,
-	}, w)
+		since:    j.Since,
+	}, w, r)
 }
 
 // gamePlayHandler handles requests from players to play a word. It will respond
 // using the GameStateResponse struct.
-func gamePlayHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) gamePlayHandler(w http.ResponseWriter, r *http.Request) {
 	var j GamePlayRequest
 
 	err := json.NewDecoder(r.Body).Decode(&j)
@@ -197,46 +910,260 @@ func gamePlayHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gameRequestHelper(j, w)
+	j.Type = playRequest
+	s.gameRequestHelper(j, w, r)
 }
 
-// gameRequestHelper relays play and state requests to the game, since they are
-// the exact same flow
-func gameRequestHelper(j GamePlayRequest, w http.ResponseWriter) {
-	// Get game to send message to
-	g, err := getGame(j.GameID, w)
+// challengeHandler handles requests from players to challenge the most recent
+// play. It will respond using the GameStateResponse struct, which includes the
+// outcome of the challenge.
+func (s *Server) challengeHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
 		return
 	}
 
-	// Send state or play request and wait for response
-	state, err := g.request(j)
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   j.GameID,
+		PlayerID: *j.PlayerID,
+		Type:     challengeRequest,
+	}, w, r)
+}
+
+// passHandler handles requests from players to give up their turn without
+// playing or swapping tiles. The game ends once every player has passed in a
+// row. It will respond using the GameStateResponse struct.
+func (s *Server) passHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
 	}
 
-	// Return GameStateResponse as json
-	resp, err := json.Marshal(state)
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   j.GameID,
+		PlayerID: *j.PlayerID,
+		Type:     passRequest,
+	}, w, r)
+}
+
+// resignHandler handles requests from players to concede the game. They are
+// removed from the turn rotation, and the game ends if only one player remains.
+// It will respond using the GameStateResponse struct.
+func (s *Server) resignHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write(resp)
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   j.GameID,
+		PlayerID: *j.PlayerID,
+		Type:     resignRequest,
+	}, w, r)
 }
 
-// getGame is a concurrency-safe function that retrieves the requested game
-// instance from the list of active games on the server
-func getGame(gameID uuid.UUID, w http.ResponseWriter) (*ScrabbleGame, error) {
-	var g *ScrabbleGame
-	var ok bool
-	serverMu.Lock()
-	defer serverMu.Unlock()
-	if g, ok = server.activeGames[gameID]; !ok {
+// gameRequestHelper relays play and state requests to the game, since they are
+// the exact same flow. The state is encoded however the client prefers.
+func (s *Server) gameRequestHelper(j GamePlayRequest, w http.ResponseWriter, r *http.Request) {
+	g, state, ok := s.sendGameRequest(j, w, r)
+	if !ok {
+		return
+	}
+
+	if j.Type != stateRequest {
+		g.Lock()
+		err := s.saveGame(g, w)
+		g.Unlock()
+		if err != nil {
+			return
+		}
+	}
+
+	writeState(w, r, state)
+}
+
+// sendGameRequest sends the request to its game's controller and returns the
+// game and the state it responded with, replying to the client with an error
+// and returning false if it couldn't
+func (s *Server) sendGameRequest(j GamePlayRequest, w http.ResponseWriter, r *http.Request) (*ScrabbleGame, GameStateResponse, bool) {
+	// Get game to send message to
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return nil, GameStateResponse{}, false
+	}
+
+	// Games with a view have started, and their state is read from it
+	// without waiting for the game's lock
+	if j.Type == stateRequest {
+		state, ok := g.viewState(j.PlayerID)
+		if j.since != nil {
+			state, ok = g.viewDiff(j.PlayerID, *j.since)
+		}
+		if ok {
+			return g, state, true
+		}
+	}
+
+	// The game's controller only runs once it has started
+	g.Lock()
+	active := g.Active
+	g.Unlock()
+	if !active {
+		http.Error(w, "Game has not started", http.StatusBadRequest)
+		return nil, GameStateResponse{}, false
+	}
+
+	// Send state or play request and wait for response, giving up if the
+	// client disconnects or the game takes too long
+	j.ctx = r.Context()
+	if s.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		j.ctx, cancel = context.WithTimeout(j.ctx, s.cfg.RequestTimeout)
+		defer cancel()
+	}
+	state, err := g.request(j)
+	if errors.Is(err, context.DeadlineExceeded) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, "Game is busy, try again later", http.StatusServiceUnavailable)
+		return nil, GameStateResponse{}, false
+	} else if errors.Is(err, context.Canceled) {
+		return nil, GameStateResponse{}, false // the client has gone
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, GameStateResponse{}, false
+	}
+	return g, state, true
+}
+
+// getGame retrieves the requested game instance using lookupGame, replying to
+// the client with an error if it can't be found
+func (s *Server) getGame(ctx context.Context, gameID uuid.UUID, w http.ResponseWriter) (*ScrabbleGame, error) {
+	g, err := s.lookupGame(ctx, gameID)
+	if err == ErrGameNotFound {
 		http.Error(w, "No existing game with that ID", http.StatusBadRequest)
-		return nil, errors.New("Game does not exist")
+		return nil, err
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
 	}
 	return g, nil
 }
+
+// lookupGame retrieves the requested game instance from the server's game
+// store. Games loaded by persistent stores have their controller and bots
+// started again, unless another node in the cluster owns them.
+func (s *Server) lookupGame(ctx context.Context, gameID uuid.UUID) (g *ScrabbleGame, err error) {
+	_, span := tracer().Start(ctx, "lookupGame", trace.WithAttributes(gameIDKey.String(gameID.String())))
+	defer func() {
+		if err == ErrGameNotFound {
+			// Not finding a game is the client's mistake, not the server's
+			span.AddEvent("Game not found")
+			span.End()
+			return
+		}
+		endSpan(span, err)
+	}()
+	g, err = s.games.Get(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Lock()
+	s.adoptGame(g)
+	if s.cluster.owns(gameID) {
+		g.runController()
+		g.runBots(s.bot)
+	}
+	g.Unlock()
+	return g, nil
+}
+
+// checkGameLimit replies to the client with an error if the server already
+// holds as many games as it is configured to allow, returning whether another
+// can be added
+func (s *Server) checkGameLimit(w http.ResponseWriter) bool {
+	limit := s.cfg.MaxGames
+	if limit == 0 {
+		return true
+	}
+
+	list, err := s.games.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	} else if len(list) >= limit {
+		http.Error(w, "Server has reached its limit of "+strconv.Itoa(limit)+" games", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// saveGame adds the game to the server's game store, or saves the changes made
+// to it. The game must be locked by the caller.
+func (s *Server) saveGame(g *ScrabbleGame, w http.ResponseWriter) error {
+	s.adoptGame(g)
+	if err := s.games.Put(g); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+// adoptGame makes the game the server's, so it is saved to the server's store,
+// its players are rated and its results kept by the server, the server's
+// tournaments are told how it ends, and its controller is counted by the
+// server. The game must be locked by the caller.
+func (s *Server) adoptGame(g *ScrabbleGame) {
+	g.store = s.games
+	g.ratings = s.ratings
+	g.results = s.results
+	g.controllers = &s.controllers
+	g.tournamentResults = s.recordTournamentResult
+	g.push = s.push
+}
+
+// persist saves changes made to the game outside of a client's request, logging
+// any failure since there is no one to report it to. Games that haven't been
+// saved by a server yet have nowhere to be saved. The game must be locked by the
+// caller.
+func (sg *ScrabbleGame) persist() {
+	if sg.store == nil {
+		return
+	}
+	if err := sg.store.Put(sg); err != nil {
+		log.Printf("Failed to save game %v: %v", sg.ID, err)
+	}
+}
+
+// deleteGame stops the game and removes it and its join code from the
+// server's game store
+func (s *Server) deleteGame(g *ScrabbleGame, w http.ResponseWriter) error {
+	if err := s.games.Delete(g.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	g.Lock()
+	code := g.JoinCode
+	g.Unlock()
+	releaseJoinCode(s.games, code)
+	g.Stop()
+	return nil
+}
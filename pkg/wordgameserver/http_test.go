@@ -2,17 +2,34 @@ package wordgameserver
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
+// newTestServer creates a server with the default configuration and a game
+// store of its own
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	s, err := NewServer(DefaultConfig(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
 func TestCreateGameHandler(t *testing.T) {
 	var j GeneralGameRequest
+	srv := newTestServer(t)
 
 	req, err := http.NewRequest("GET", "/game/create", nil)
 	if err != nil {
@@ -20,7 +37,7 @@ func TestCreateGameHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	h := http.HandlerFunc(createGameHandler)
+	h := http.HandlerFunc(srv.createGameHandler)
 
 	h.ServeHTTP(rr, req)
 
@@ -37,10 +54,126 @@ func TestCreateGameHandler(t *testing.T) {
 		t.Error("Returned empty game_id")
 	}
 
-	_, err = getGame(j.GameID, rr)
+	_, err = srv.getGame(context.Background(), j.GameID, rr)
+	if err != nil {
+		t.Fatalf("No existing games with ID %v", j.GameID)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxGames = -1
+	if _, err := NewHandler(cfg); err == nil {
+		t.Error("Creating a handler with an invalid configuration should fail")
+	}
+
+	h, err := NewHandler(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The API can be mounted under a prefix of the embedding program's own
+	mux := http.NewServeMux()
+	mux.Handle("/wordgame/", http.StripPrefix("/wordgame", h))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	r, err := http.Post(s.URL+"/wordgame/v2/games", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	var j GeneralGameRequest
+	if r.StatusCode != http.StatusCreated {
+		t.Fatalf("Creating a game returned status code %v, expected %v", r.StatusCode, http.StatusCreated)
+	} else if err = json.NewDecoder(r.Body).Decode(&j); err != nil {
+		t.Fatal(err)
+	} else if j.GameID == (uuid.UUID{}) {
+		t.Error("Returned empty game_id")
+	}
+}
+
+func TestSeededGames(t *testing.T) {
+	srv := newTestServer(t)
+
+	// Games are dealt and seated by their seed, which is kept in their options
+	create := func(seed int64) *ScrabbleGame {
+		t.Helper()
+
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewReader(mustJSON(t, GeneralGameRequest{Options: &GameOptions{Seed: &seed}})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+
+		var j GeneralGameRequest
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+		} else if err = json.NewDecoder(rr.Body).Decode(&j); err != nil {
+			t.Fatal(err)
+		} else if j.Options == nil || j.Options.Seed == nil || *j.Options.Seed != seed {
+			t.Fatalf("Game was created with options %+v, expected seed %v", j.Options, seed)
+		}
+
+		g, err := srv.games.Get(j.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"ashley1", "ashley2", "ashley3"} {
+			if _, err = g.addPlayer(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		g.Lock()
+		err = g.begin()
+		g.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	deal := func(g *ScrabbleGame) string {
+		var seats []string
+		for _, p := range g.playerList() {
+			seats = append(seats, p.Name+":"+string(p.Tiles))
+		}
+		return strings.Join(seats, " ")
+	}
+
+	g, same, other := create(42), create(42), create(43)
+	if deal(g) != deal(same) {
+		t.Errorf("Games with the same seed were dealt %v and %v", deal(g), deal(same))
+	} else if deal(g) == deal(other) {
+		t.Errorf("Games with different seeds were both dealt %v", deal(g))
+	}
+
+	// The same moves shuffle the bag the same way, even in a game that has
+	// been saved and loaded again
+	data, err := EncodeGame(same)
 	if err != nil {
-		t.Fatalf("No existing games with ID %v. Map contents: %v",
-			j.GameID, server.activeGames)
+		t.Fatal(err)
+	}
+	if same, err = DecodeGame(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer same.Stop()
+	for _, sg := range []*ScrabbleGame{g, same} {
+		sg.Lock()
+		p := sg.playerList()[0]
+		err = sg.executePlay(GamePlayRequest{PlayerID: p.ID, Swap: true, Tiles: p.Tiles[:3]})
+		sg.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.Lock()
+	same.Lock()
+	defer g.Unlock()
+	defer same.Unlock()
+	if string(g.TileBag) != string(same.TileBag) {
+		t.Error("Swapping the same tiles shuffled the bags of games with the same seed differently")
 	}
 }
 
@@ -49,9 +182,8 @@ func TestJoinGameHandler(t *testing.T) {
 	newGame := createScrabbleGame()
 	maxPlayers := 4
 
-	serverMu.Lock()
-	server.activeGames[newGame.ID] = newGame
-	serverMu.Unlock()
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
 
 	playerNames := []string{
 		"ashley1",
@@ -67,7 +199,7 @@ func TestJoinGameHandler(t *testing.T) {
 
 	// Test concurrently joining players
 	for i := 0; i < maxPlayers; i++ {
-		go joinPlayer(newGame.ID, playerNames[i], joinCh, errCh)
+		go joinPlayer(srv, newGame.ID, playerNames[i], joinCh, errCh)
 	}
 
 	rrCount := 0
@@ -101,7 +233,7 @@ joinLoop:
 	}
 
 	for i := 4; i < 6; i++ {
-		go joinPlayer(newGame.ID, playerNames[i], joinCh, errCh)
+		go joinPlayer(srv, newGame.ID, playerNames[i], joinCh, errCh)
 	}
 
 	rrCount = 0
@@ -127,7 +259,7 @@ failJoinLoop:
 	}
 }
 
-func joinPlayer(gameID uuid.UUID, playerName string, joinCh chan *httptest.ResponseRecorder, errCh chan error) {
+func joinPlayer(srv *Server, gameID uuid.UUID, playerName string, joinCh chan *httptest.ResponseRecorder, errCh chan error) {
 	j := GeneralGameRequest{
 		GameID:     gameID,
 		PlayerName: &playerName,
@@ -143,7 +275,7 @@ func joinPlayer(gameID uuid.UUID, playerName string, joinCh chan *httptest.Respo
 	}
 
 	rr := httptest.NewRecorder()
-	h := http.HandlerFunc(joinGameHandler)
+	h := http.HandlerFunc(srv.joinGameHandler)
 
 	h.ServeHTTP(rr, req)
 
@@ -153,9 +285,8 @@ func joinPlayer(gameID uuid.UUID, playerName string, joinCh chan *httptest.Respo
 func TestStartGameHandler(t *testing.T) {
 	newGame := createScrabbleGame()
 
-	serverMu.Lock()
-	server.activeGames[newGame.ID] = newGame
-	serverMu.Unlock()
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
 
 	playerNames := []string{
 		"ashley1",
@@ -175,7 +306,7 @@ func TestStartGameHandler(t *testing.T) {
 		t.Fatal("Failed to marshal JSON request object")
 	}
 
-	rr, err := startGame(payload)
+	rr, err := sendStartRequest(srv, payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,7 +321,7 @@ func TestStartGameHandler(t *testing.T) {
 		newGame.addPlayer(playerNames[i])
 	}
 
-	rr, err = startGame(payload)
+	rr, err = sendStartRequest(srv, payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -201,7 +332,7 @@ func TestStartGameHandler(t *testing.T) {
 			c, http.StatusOK)
 	}
 
-	rr, err = startGame(payload)
+	rr, err = sendStartRequest(srv, payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -212,14 +343,14 @@ func TestStartGameHandler(t *testing.T) {
 	}
 }
 
-func startGame(payload []byte) (*httptest.ResponseRecorder, error) {
+func sendStartRequest(srv *Server, payload []byte) (*httptest.ResponseRecorder, error) {
 	req, err := http.NewRequest("GET", "/game/start", bytes.NewBuffer(payload))
 	if err != nil {
 		return nil, errors.New("Failed to generate HTTP request for game start")
 	}
 
 	rr := httptest.NewRecorder()
-	h := http.HandlerFunc(startGameHandler)
+	h := http.HandlerFunc(srv.startGameHandler)
 
 	h.ServeHTTP(rr, req)
 
@@ -232,9 +363,8 @@ func TestGameStateHandler(t *testing.T) {
 	var playerID uuid.UUID
 	var err error
 
-	serverMu.Lock()
-	server.activeGames[newGame.ID] = newGame
-	serverMu.Unlock()
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
 
 	playerNames := []string{
 		"ashley1",
@@ -268,7 +398,7 @@ func TestGameStateHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	h := http.HandlerFunc(gameStateHandler)
+	h := http.HandlerFunc(srv.gameStateHandler)
 
 	h.ServeHTTP(rr, req)
 
@@ -289,5 +419,495 @@ func TestGameStateHandler(t *testing.T) {
 		t.Fatalf("Game not in correct turn state")
 	} else if len(s.PlayerTiles) != 7 {
 		t.Fatal("Incorrect number of tiles for player")
+	} else if s.BagCount != 100-4*7 || s.LastMove != nil || s.Passes != 0 {
+		t.Errorf("State has %v tiles in the bag, last move %+v and %v passes, expected 72 and no moves", s.BagCount, s.LastMove, s.Passes)
+	}
+}
+
+func TestCancelGameHandler(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	playerID, err := newGame.addPlayer("ashley1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only players in the game should be able to cancel it
+	strangerID := uuid.New()
+	rr, err := sendCancelRequest(srv, GeneralGameRequest{GameID: newGame.ID, PlayerID: &strangerID})
+	if err != nil {
+		t.Fatal(err)
+	} else if rr.Code == http.StatusOK {
+		t.Fatal("Game should not be cancelled by player outside the game")
+	}
+
+	rr, err = sendCancelRequest(srv, GeneralGameRequest{GameID: newGame.ID, PlayerID: &playerID})
+	if err != nil {
+		t.Fatal(err)
+	} else if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", c, http.StatusOK, rr.Body)
+	}
+
+	if _, err = srv.getGame(context.Background(), newGame.ID, httptest.NewRecorder()); err == nil {
+		t.Error("Cancelled game should no longer exist")
+	}
+}
+
+func sendCancelRequest(srv *Server, j GeneralGameRequest) (*httptest.ResponseRecorder, error) {
+	payload, err := json.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", "/game/cancel", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, errors.New("Failed to generate HTTP request for game cancel")
+	}
+
+	rr := httptest.NewRecorder()
+	h := http.HandlerFunc(srv.cancelGameHandler)
+
+	h.ServeHTTP(rr, req)
+
+	return rr, nil
+}
+
+func TestServerShutdown(t *testing.T) {
+	// Find a free port for the server to listen on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := DefaultConfig()
+	cfg.BindAddr = addr
+	cfg.IdleTTL = 0
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ListenAndServe(ctx)
+	}()
+
+	// Retry until the server is accepting requests
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Post("http://"+addr+"/v1/game/create", "application/json", nil); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var j GeneralGameRequest
+	err = json.NewDecoder(resp.Body).Decode(&j)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("Server shut down with error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+
+	g, err := srv.getGame(context.Background(), j.GameID, httptest.NewRecorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.request(GamePlayRequest{}); err != ErrGameStopped {
+		t.Errorf("Game should be stopped after shutdown, got %v", err)
+	}
+}
+
+func TestGameRequestContext(t *testing.T) {
+	g := createScrabbleGame()
+	playerID, _ := g.addPlayer("ashley1")
+	g.addPlayer("ashley2")
+
+	g.Lock()
+	err := g.start()
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	// The controller can't take requests while the game is locked
+	g.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = g.request(GamePlayRequest{PlayerID: playerID, ctx: ctx})
+	g.Unlock()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Request to a busy game returned %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	// Responses to requests that were given up on don't block the controller
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		respond(GamePlayRequest{ctx: ctx}, make(chan GameStateResponse), GameStateResponse{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Response to an abandoned request blocked")
+	}
+
+	if _, err = g.request(GamePlayRequest{PlayerID: playerID}); err != nil {
+		t.Errorf("Request after a timeout failed: %v", err)
+	}
+}
+
+func TestGameRequestBusy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RequestTimeout = 50 * time.Millisecond
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := createScrabbleGame()
+	firstID, _ := g.addPlayer("ashley1")
+	secondID, _ := g.addPlayer("ashley2")
+	g.Lock()
+	err = g.start()
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+	srv.games.Put(g)
+
+	// Nobody reads the response to this, so the controller is stuck until it
+	// is read below
+	g.Action <- GamePlayRequest{GameID: g.ID, PlayerID: firstID}
+
+	payload, err := json.Marshal(GeneralGameRequest{GameID: g.ID, PlayerID: &secondID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "/game/state", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.gameStateHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Returned status code %v, expected %v", rr.Code, http.StatusServiceUnavailable)
+	} else if rr.Header().Get("Retry-After") == "" {
+		t.Error("Busy response has no Retry-After header")
+	}
+
+	<-g.Players[firstID].State
+}
+
+func TestGameControllers(t *testing.T) {
+	srv := newTestServer(t)
+
+	start := func() (*ScrabbleGame, uuid.UUID) {
+		g := createScrabbleGame()
+		playerID, _ := g.addPlayer("ashley1")
+		g.addPlayer("ashley2")
+		g.Lock()
+		srv.adoptGame(g)
+		err := g.start()
+		g.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g, playerID
+	}
+	waitFor := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for srv.Controllers() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("Server has %v controllers running, expected %v", srv.Controllers(), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	finished, playerID := start()
+	cancelled, _ := start()
+	waitFor(2)
+
+	// The controller returns once the game is over
+	if _, err := finished.request(GamePlayRequest{PlayerID: playerID, Type: resignRequest}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(1)
+
+	// A finished game's state can still be requested, but no more moves made
+	state, err := finished.request(GamePlayRequest{PlayerID: playerID})
+	if err != nil {
+		t.Errorf("State request to a finished game failed: %v", err)
+	} else if !state.Finished {
+		t.Error("State of a finished game should say it has finished")
+	}
+	if _, err = finished.request(GamePlayRequest{PlayerID: playerID, Type: passRequest}); err == nil {
+		t.Error("Passed in a finished game")
+	}
+
+	cancelled.Stop()
+	waitFor(0)
+
+	// Looking a game up again doesn't start a second controller
+	g, _ := start()
+	defer g.Stop()
+	srv.games.Put(g)
+	if _, err = srv.lookupGame(context.Background(), g.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(1)
+}
+
+func TestGameHistoryHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/game/history?game_id="+g.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.gameHistoryHandler).ServeHTTP(rr, req)
+
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", c, http.StatusOK)
+	}
+
+	var h GameHistoryResponse
+	if err = json.NewDecoder(rr.Body).Decode(&h); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h.Moves) != 1 {
+		t.Fatalf("History has %v moves, expected 1", len(h.Moves))
+	} else if m := h.Moves[0]; m.Player != 0 || m.Score != 5 || len(m.Words) != 1 || m.Words[0] != "CAT" || len(m.Squares) != 3 {
+		t.Errorf("Recorded move %+v, expected CAT for 5 by player 0", m)
+	} else if len(h.Players) != 2 || h.Players[0].Name != "ashley1" {
+		t.Errorf("History has players %+v, expected ashley1 and ashley2", h.Players)
+	} else if m.Rack != nil {
+		t.Errorf("History shows rack %q before the game has finished", m.Rack)
+	}
+}
+
+func TestGameReplayHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	replay := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/game/replay?game_id="+g.ID.String()+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.gameReplayHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := replay("").Code; c != http.StatusBadRequest {
+		t.Errorf("Replaying an unfinished game returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+
+	// Both players passing ends the game
+	for _, id := range []uuid.UUID{ids[1], ids[0]} {
+		if err = g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := replay("")
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", c, http.StatusOK)
+	}
+	var r GameReplay
+	if err = json.NewDecoder(rr.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	} else if len(r.Moves) != 3 || len(r.Players) != 2 || len(r.Winners) == 0 {
+		t.Errorf("Replay has %v moves, %v players and %v winners, expected 3 moves, 2 players and a winner", len(r.Moves), len(r.Players), len(r.Winners))
+	}
+
+	rr = replay("&move=1")
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", c, http.StatusOK)
+	}
+	var s ReplayState
+	if err = json.NewDecoder(rr.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	} else if s.Board[7][7].Letter != 'A' || s.Scores[0] != 5 {
+		t.Errorf("Replayed state %+v, expected CAT played for 5", s)
+	}
+
+	if c := replay("&move=4").Code; c != http.StatusBadRequest {
+		t.Errorf("Replaying past the last move returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+}
+
+func TestGameGCGHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uuid.UUID{ids[1], ids[0]} {
+		if err = g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/game/gcg?game_id="+g.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.gameGCGHandler).ServeHTTP(rr, req)
+
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", c, http.StatusOK)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, ">ashley1: CATXXXX 8G CAT +5 5\n") {
+		t.Errorf("GCG doesn't include the opening play:\n%v", body)
+	}
+}
+
+func TestImportGameHandler(t *testing.T) {
+	srv := newTestServer(t)
+	gcg := "#player1 ashley1\n#player2 ashley2\n>ashley1: CATXXXX 8G CAT +5 5\n"
+	body, err := json.Marshal(GameImportRequest{GCG: gcg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/game/import", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.importGameHandler).ServeHTTP(rr, req)
+
+	if c := rr.Code; c != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v: %v", c, http.StatusCreated, rr.Body)
+	}
+
+	var resp GameImportResponse
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	} else if len(resp.PlayerIDs) != 2 {
+		t.Fatalf("Returned %v player IDs, expected 2", len(resp.PlayerIDs))
+	}
+
+	g, err := srv.getGame(context.Background(), resp.GameID, rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	s, err := g.request(GamePlayRequest{GameID: g.ID, PlayerID: resp.PlayerIDs[1]})
+	if err != nil {
+		t.Fatal(err)
+	} else if !s.Active || s.PlayerTurn != 1 || len(s.PlayerTiles) != maxTiles {
+		t.Errorf("Imported game state %+v, expected second player's turn with a full hand", s)
+	} else if s.Board[7][7].Letter != 'A' {
+		t.Error("Imported game should have CAT on the board")
+	}
+}
+
+func TestNewRouterVersions(t *testing.T) {
+	srv := newTestServer(t)
+	s := httptest.NewServer(srv.newRouter())
+	defer s.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"POST", "/v1/game/create", "", http.StatusCreated},
+		{"POST", "/game/create", "", http.StatusCreated}, // unversioned routes are kept for older clients
+		{"GET", "/v1/openapi.json", "", http.StatusOK},
+		{"POST", "/v1/game/play", `{"game_id":"abc"}`, http.StatusBadRequest},
+		{"POST", "/game/play", `{"game_id":"abc"}`, http.StatusBadRequest},
+		{"POST", "/v0/game/create", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, s.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.code {
+			t.Errorf("%v %v returned status code %v, expected %v", tt.method, tt.path, resp.StatusCode, tt.code)
+		} else if tt.code == http.StatusBadRequest && !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			t.Errorf("%v %v was not rejected by request validation", tt.method, tt.path)
+		}
 	}
 }
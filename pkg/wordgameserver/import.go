@@ -0,0 +1,424 @@
+package wordgameserver
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/google/uuid"
+)
+
+// GamePosition describes a game part way through, to be imported and played on
+// from. Blanks are written as question marks in racks and the bag, and as
+// lowercase letters on the board.
+type GamePosition struct {
+	Board   []string         `json:"board"`         // rows from top to bottom, with dots for empty squares
+	Players []PositionPlayer `json:"players"`       // players in turn order
+	Bag     *string          `json:"bag,omitempty"` // tiles left in the bag, or everything not on the board or in a rack if omitted
+	Turn    int              `json:"turn"`          // number of the player whose turn it is
+}
+
+// PositionPlayer describes a player in an imported position
+type PositionPlayer struct {
+	Name  string `json:"name"`
+	Rack  string `json:"rack"` // tiles in hand, topped up from the bag if fewer than a full hand
+	Score int    `json:"score"`
+}
+
+// GameImportRequest is the format of the request a client sends to create a
+// game from a GCG file or a position
+type GameImportRequest struct {
+	GCG      string        `json:"gcg,omitempty"`
+	Position *GamePosition `json:"position,omitempty"`
+	Options  *GameOptions  `json:"options,omitempty"`
+	Webhooks []Webhook     `json:"webhooks,omitempty"`
+}
+
+// GameImportResponse is the format of the response sent to clients when they
+// import a game, with the IDs the players can resume the game with
+type GameImportResponse struct {
+	GameID    uuid.UUID   `json:"game_id"`
+	PlayerIDs []uuid.UUID `json:"player_ids"` // players in turn order
+}
+
+// importPosition creates a game at the position described, ready to be started
+func importPosition(pos GamePosition) (*ScrabbleGame, error) {
+	sg := createScrabbleGame()
+	if err := positionBoard(sg.Board, pos.Board); err != nil {
+		return nil, err
+	}
+
+	for _, pp := range pos.Players {
+		id, err := sg.addPlayer(pp.Name)
+		if err != nil {
+			return nil, err
+		}
+		p := sg.Players[id]
+		p.Score = pp.Score
+		if p.Tiles, err = positionTiles(pp.Rack); err != nil {
+			return nil, err
+		}
+	}
+
+	var bag Letters
+	if pos.Bag != nil {
+		var err error
+		if bag, err = positionTiles(*pos.Bag); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sg.setPosition(pos.Turn, bag); err != nil {
+		return nil, err
+	}
+	return sg, nil
+}
+
+// importGCG creates a game at the end of the moves recorded in a GCG file,
+// ready to be started. Racks given by #rack pragmas are kept, and other
+// players are dealt new racks from the tiles left over.
+func importGCG(gcg string) (*ScrabbleGame, error) {
+	sg := createScrabbleGame()
+
+	var ids []uuid.UUID
+	nicks := make(map[string]int)
+	racks := make(map[int]Letters)
+	turn := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(gcg))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(text)
+		lineErr := func(msg string) error {
+			return errors.New("Line " + strconv.Itoa(line) + ": " + msg)
+		}
+
+		switch {
+		case strings.HasPrefix(text, "#player"):
+			n, err := strconv.Atoi(strings.TrimPrefix(fields[0], "#player"))
+			if err != nil || n != len(ids)+1 || len(fields) < 2 {
+				return nil, lineErr("Players must be numbered in order with a nickname")
+			}
+			name := strings.Join(fields[2:], " ")
+			if name == "" {
+				name = fields[1]
+			}
+			id, err := sg.addPlayer(name)
+			if err != nil {
+				return nil, lineErr(err.Error())
+			}
+			ids = append(ids, id)
+			nicks[fields[1]] = n - 1
+
+		case strings.HasPrefix(text, "#rack"):
+			n, err := strconv.Atoi(strings.TrimPrefix(fields[0], "#rack"))
+			if err != nil || n < 1 || n > len(ids) {
+				return nil, lineErr("Rack given for an unknown player")
+			}
+			rack := ""
+			if len(fields) > 1 {
+				rack = fields[1]
+			}
+			if racks[n-1], err = positionTiles(rack); err != nil {
+				return nil, lineErr(err.Error())
+			}
+
+		case strings.HasPrefix(text, ">"):
+			if len(fields) < 4 {
+				return nil, lineErr("Move is missing fields")
+			}
+			player, ok := nicks[strings.TrimSuffix(strings.TrimPrefix(fields[0], ">"), ":")]
+			if !ok {
+				return nil, lineErr("Move made by an unknown player")
+			}
+			p := sg.Players[ids[player]]
+
+			// The rack may be left out, in which case the move follows the
+			// nickname. A play takes two fields and anything else one.
+			rack, move := Letters(nil), fields[1:]
+			if len(move) == 5 || (len(move) == 4 && (strings.HasPrefix(move[1], "-") || strings.HasPrefix(move[1], "("))) {
+				var err error
+				if rack, err = positionTiles(move[0]); err != nil {
+					return nil, lineErr(err.Error())
+				}
+				move = move[1:]
+			}
+
+			total, err := strconv.Atoi(move[len(move)-1])
+			if err != nil {
+				return nil, lineErr("Invalid total score")
+			}
+			score, err := strconv.Atoi(strings.TrimPrefix(move[len(move)-2], "+"))
+			if err != nil {
+				return nil, lineErr("Invalid score")
+			}
+
+			m := Move{Player: player, Rack: rack, Score: score, Time: time.Now()}
+			switch {
+			case move[0] == "--":
+				// The previous play was challenged off the board
+				last := len(sg.history) - 1
+				if last < 0 || sg.history[last].Player != player || len(sg.history[last].Squares) == 0 {
+					return nil, lineErr("Withdrawn play not found")
+				}
+				for _, sc := range sg.history[last].Squares {
+					sg.Board.At(sc).Tile = Tile{}
+				}
+				sg.history[last].Retracted = true
+				p.Score = total
+				continue
+			case strings.HasPrefix(move[0], "("):
+				if move[0] != "(challenge)" {
+					return nil, lineErr("Game has already ended")
+				}
+				p.Score = total
+				continue
+			case move[0] == "-":
+				m.Pass = true
+			case strings.HasPrefix(move[0], "-"):
+				m.Swap = true
+				if m.Swapped, err = positionTiles(move[0][1:]); err != nil {
+					return nil, lineErr(err.Error())
+				}
+			default:
+				if len(move) != 4 {
+					return nil, lineErr("Play must give a position and a word")
+				}
+				if err = sg.importPlay(&m, move[0], move[1]); err != nil {
+					return nil, lineErr(err.Error())
+				}
+			}
+
+			sg.history = append(sg.history, m)
+			p.Score = total
+			turn = (player + 1) % len(ids)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, rack := range racks {
+		sg.Players[ids[i]].Tiles = rack
+	}
+
+	if err := sg.setPosition(turn, nil); err != nil {
+		return nil, err
+	}
+	return sg, nil
+}
+
+// importPlay places a play written in GCG notation on the board and records
+// the squares, tiles and words in the move
+func (sg *ScrabbleGame) importPlay(m *Move, pos, word string) error {
+	var start SquareCoordinate
+	step := SquareCoordinate{Row: 0, Col: 1}
+
+	// Plays across give the row first, and plays down the column first
+	coords := pos
+	if len(coords) > 0 && coords[0] >= 'A' && coords[0] <= 'Z' {
+		step = SquareCoordinate{Row: 1, Col: 0}
+		coords = coords[1:] + coords[:1]
+	}
+	if len(coords) < 2 {
+		return errors.New("Invalid position '" + pos + "'")
+	}
+	row, err := strconv.Atoi(coords[:len(coords)-1])
+	col := coords[len(coords)-1]
+	if err != nil || col < 'A' || col > 'Z' {
+		return errors.New("Invalid position '" + pos + "'")
+	}
+	start = SquareCoordinate{Row: row - 1, Col: int(col - 'A')}
+
+	play := engine.Play{StartPos: start, EndPos: start}
+	for i := 0; i < len(word); i++ {
+		sc := SquareCoordinate{Row: start.Row + i*step.Row, Col: start.Col + i*step.Col}
+		if !sg.Board.OnBoard(sc) {
+			return errors.New("Play runs off the board")
+		}
+		play.EndPos = sc
+
+		// Letters already on the board are written as dots, or sometimes as
+		// the letters themselves
+		if squ := sg.Board.At(sc); squ.Occupied() {
+			if l := Letter(word[i]); l != '.' && l.ToUpper() != squ.Letter {
+				return errors.New("Play doesn't match the letters on the board")
+			}
+			continue
+		}
+
+		switch l := word[i]; {
+		case l >= 'A' && l <= 'Z':
+			play.Tiles = append(play.Tiles, Letter(l))
+		case l >= 'a' && l <= 'z':
+			play.Tiles = append(play.Tiles, ' ')
+			play.Blanks = append(play.Blanks, Letter(l))
+		default:
+			return errors.New("Play covers an empty square with '" + string(l) + "'")
+		}
+	}
+
+	board, placed, words, err := sg.Board.LayTiles(play, sg.variant.tiles)
+	if err != nil {
+		return err
+	}
+	sg.Board = board
+
+	m.Squares = placed
+	for _, sc := range placed {
+		m.Tiles = append(m.Tiles, board.At(sc).Tile)
+	}
+	for _, w := range words {
+		m.Words = append(m.Words, w.Word)
+	}
+	return nil
+}
+
+// setPosition checks the tiles on the board and in the players' hands could be
+// drawn from a single set, fills the bag with the tiles given or those left
+// over if none are, and sets whose turn it is. The position is built up on the
+// game by the importer, then recorded as a single event.
+func (sg *ScrabbleGame) setPosition(turn int, bag Letters) error {
+	if len(sg.Players) < 2 {
+		return errors.New("At least two players needed to start game")
+	} else if turn < 0 || turn >= len(sg.Players) {
+		return errors.New("Turn must be the number of a player")
+	}
+
+	left := make(map[Letter]int, len(tiles))
+	for t, tile := range tiles {
+		left[t] = tile.Count
+	}
+	for r := range sg.Board {
+		for c := range sg.Board[r] {
+			if t := sg.Board[r][c].Tile; t.Blank {
+				left[' ']--
+			} else if t.Letter != 0 {
+				left[t.Letter]--
+			}
+		}
+	}
+	for _, p := range sg.Players {
+		if len(p.Tiles) > maxTiles {
+			return errors.New("Players can hold at most " + strconv.Itoa(maxTiles) + " tiles")
+		}
+		for _, t := range p.Tiles {
+			left[t]--
+		}
+	}
+	if bag != nil {
+		for _, t := range bag {
+			left[t]--
+		}
+	}
+
+	e := Event{
+		Type:  PositionImported,
+		Bag:   TileBag{},
+		Turn:  turn,
+		Moves: sg.history,
+	}
+	for t, n := range left {
+		if n < 0 {
+			return errors.New("Position uses more '" + t.String() + "' tiles than a set has")
+		} else if bag == nil {
+			for i := 0; i < n; i++ {
+				e.Bag = append(e.Bag, t)
+			}
+		}
+	}
+	if bag != nil {
+		e.Bag = append(e.Bag, bag...)
+	}
+	e.Bag.Shuffle(sg.random())
+
+	for _, p := range sg.playerList() {
+		e.Racks = append(e.Racks, p.Tiles)
+		e.Scores = append(e.Scores, p.Score)
+	}
+
+	board := sg.Board.Clone()
+	e.Board = &board
+	return sg.record(e)
+}
+
+// applyPosition sets the board, the players' hands and scores, the bag and the
+// moves made before the imported position
+func (sg *ScrabbleGame) applyPosition(e Event) error {
+	playerList := sg.playerList()
+	if e.Board == nil || len(e.Racks) != len(playerList) || len(e.Scores) != len(playerList) {
+		return errors.New("Imported position doesn't match the players")
+	}
+
+	sg.Board = e.Board.Clone()
+	for i, p := range playerList {
+		p.Tiles = append(Letters(nil), e.Racks[i]...)
+		p.Score = e.Scores[i]
+	}
+	sg.TileBag = append(TileBag(nil), e.Bag...)
+	sg.TurnCount = e.Turn
+	sg.history = append([]Move(nil), e.Moves...)
+	return nil
+}
+
+// positionBoard places the tiles of an imported board's rows on the board, which
+// must be the same size
+func positionBoard(sb ScrabbleBoard, rows []string) error {
+	if len(rows) != len(sb) {
+		return errors.New("Board must have " + strconv.Itoa(len(sb)) + " rows")
+	}
+	for r, row := range rows {
+		if len(row) != len(sb[r]) {
+			return errors.New("Row " + strconv.Itoa(r+1) + " must have " + strconv.Itoa(len(sb[r])) + " squares")
+		}
+		for c := 0; c < len(row); c++ {
+			if row[c] == '.' {
+				continue
+			}
+			t, err := positionTile(row[c])
+			if err != nil {
+				return err
+			}
+			sb[r][c].Tile = t
+		}
+	}
+	return nil
+}
+
+// positionTile converts a letter on an imported board to a tile, with
+// lowercase letters for blanks
+func positionTile(l byte) (Tile, error) {
+	blank := l >= 'a' && l <= 'z'
+	if blank {
+		l -= 'a' - 'A'
+	}
+	t, ok := tiles[Letter(l)]
+	if !ok || l == ' ' {
+		return Tile{}, errors.New("Invalid tile '" + string(l) + "' on board")
+	} else if blank {
+		return Tile{Letter: Letter(l), Blank: true}, nil
+	}
+	return Tile{Letter: Letter(l), Value: t.Value}, nil
+}
+
+// positionTiles converts an imported rack or bag to tiles, with question marks
+// for blanks
+func positionTiles(s string) (Letters, error) {
+	ts := make(Letters, len(s))
+	for i := 0; i < len(s); i++ {
+		t := s[i]
+		if t == '?' {
+			t = ' '
+		} else if t >= 'a' && t <= 'z' {
+			t -= 'a' - 'A'
+		}
+		if _, ok := tiles[Letter(t)]; !ok {
+			return nil, errors.New("Invalid tile '" + string(s[i]) + "'")
+		}
+		ts[i] = Letter(t)
+	}
+	return ts, nil
+}
@@ -0,0 +1,103 @@
+package wordgameserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportGCG(t *testing.T) {
+	gcg := `#character-encoding UTF-8
+#player1 ashley_1 ashley 1
+#player2 ashley2 ashley2
+>ashley_1: CATXXXX 8G CAT +5 5
+>ashley2: S?DOGXX 8G ...S +6 6
+>ashley_1: XXXX?B? J8 .x +1 6
+>ashley_1: XXXX?B? -- -1 5
+>ashley2: DOGXX?Q -XX +0 6
+#rack1 AB?
+`
+	g, err := importGCG(gcg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	players := g.playerList()
+	if len(players) != 2 || players[0].Name != "ashley 1" {
+		t.Fatalf("Imported players %+v, expected ashley 1 and ashley2", players)
+	} else if players[0].Score != 5 || players[1].Score != 6 {
+		t.Errorf("Imported scores %v and %v, expected 5 and 6", players[0].Score, players[1].Score)
+	} else if string(players[0].Tiles) != "AB " || len(players[1].Tiles) != 0 {
+		t.Errorf("Imported racks %q and %q, expected \"AB \" and none", players[0].Tiles, players[1].Tiles)
+	}
+
+	for i, l := range Letters("CATS") {
+		if got := g.Board[7][6+i].Letter; got != l {
+			t.Errorf("Square 7,%v has %q, expected %q", 6+i, got, l)
+		}
+	}
+	if g.Board[8][9].Occupied() {
+		t.Error("Withdrawn play should be taken off the board")
+	}
+
+	if g.TurnCount != 0 {
+		t.Errorf("Imported game is on turn %v, expected 0", g.TurnCount)
+	} else if len(g.TileBag) != len(standardVariant.bag)-7 {
+		t.Errorf("Bag has %v tiles, expected %v", len(g.TileBag), len(standardVariant.bag)-7)
+	}
+
+	if h := g.History(); len(h) != 4 || !h[2].Retracted || !h[3].Swap || string(h[3].Swapped) != "XX" {
+		t.Errorf("Imported history %+v, expected a play, a withdrawn play and a swap", h)
+	}
+
+	if _, err = importGCG(gcg + ">ashley2: Q (time) -10 -4\n"); err == nil {
+		t.Error("Importing a game that has ended should fail")
+	}
+}
+
+func TestImportPosition(t *testing.T) {
+	board := make([]string, standardVariant.size)
+	for i := range board {
+		board[i] = strings.Repeat(".", standardVariant.size)
+	}
+	board[7] = "......CaT......"
+
+	bag := "QQ"
+	pos := GamePosition{
+		Board: board,
+		Players: []PositionPlayer{
+			{Name: "ashley1", Rack: "DOG?", Score: 5},
+			{Name: "ashley2", Rack: "XYZ", Score: 3},
+		},
+		Turn: 1,
+	}
+
+	g, err := importPosition(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if squ := g.Board[7][7]; squ.Letter != 'A' || !squ.Blank || squ.Value != 0 {
+		t.Errorf("Square 7,7 has %+v, expected a blank A", squ.Tile)
+	} else if g.Board[7][8].Value != tiles['T'].Value {
+		t.Errorf("Square 7,8 is worth %v, expected %v", g.Board[7][8].Value, tiles['T'].Value)
+	}
+
+	if players := g.playerList(); string(players[0].Tiles) != "DOG " || players[1].Score != 3 {
+		t.Errorf("Imported players %+v, expected racks and scores to be kept", players)
+	} else if g.TurnCount != 1 {
+		t.Errorf("Imported game is on turn %v, expected 1", g.TurnCount)
+	} else if len(g.TileBag) != len(standardVariant.bag)-10 {
+		t.Errorf("Bag has %v tiles, expected %v", len(g.TileBag), len(standardVariant.bag)-10)
+	}
+
+	pos.Bag = &bag
+	if _, err = importPosition(pos); err == nil {
+		t.Error("Importing a bag with more Qs than a set has should fail")
+	}
+
+	pos.Bag = nil
+	pos.Turn = 2
+	if _, err = importPosition(pos); err == nil {
+		t.Error("Importing a position with the turn of a missing player should fail")
+	}
+}
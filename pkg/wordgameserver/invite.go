@@ -0,0 +1,84 @@
+package wordgameserver
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidInvite is returned when an invite wasn't issued by the server
+var ErrInvalidInvite = errors.New("Invalid invite")
+
+// invitePrefix is signed along with invites' payloads, so an invite can never
+// pass as a login token or the other way round
+const invitePrefix = "invite:"
+
+// Invite is a link that lets one person join a game without knowing its ID,
+// join code or passphrase. Each can only be used once.
+type Invite struct {
+	Token string `json:"token"`          // signed game ID and grant, sent as invite to join
+	Link  string `json:"link,omitempty"` // the app's join page with the token, if the server has one configured
+}
+
+// newInvite creates an invite to join the game. Invites are the game's ID and
+// a random grant, signed by the server, so they don't need to be stored; a
+// game remembers which grants have been used through its players' join events.
+// The game must be locked by the caller.
+func (s *Server) newInvite(g *ScrabbleGame) (Invite, error) {
+	payload := make([]byte, 16+16)
+	copy(payload, g.ID[:])
+	if _, err := rand.Read(payload[16:]); err != nil {
+		return Invite{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.signToken(append([]byte(invitePrefix), payload...)))
+
+	inv := Invite{Token: token}
+	if s.cfg.JoinURL != "" {
+		if u, err := url.Parse(s.cfg.JoinURL); err == nil {
+			q := u.Query()
+			q.Set("invite", token)
+			u.RawQuery = q.Encode()
+			inv.Link = u.String()
+		}
+	}
+	return inv, nil
+}
+
+// checkInvite returns the game the invite is for and the grant it carries, or
+// ErrInvalidInvite
+func (s *Server) checkInvite(token string) (uuid.UUID, uuid.UUID, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, uuid.Nil, ErrInvalidInvite
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil || len(payload) != 16+16 {
+		return uuid.Nil, uuid.Nil, ErrInvalidInvite
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, s.signToken(append([]byte(invitePrefix), payload...))) {
+		return uuid.Nil, uuid.Nil, ErrInvalidInvite
+	}
+
+	var gameID, grant uuid.UUID
+	copy(gameID[:], payload[:16])
+	copy(grant[:], payload[16:])
+	return gameID, grant, nil
+}
+
+// inviteUsed returns true if a player has already joined the game with the
+// grant. The game must be locked by the caller.
+func (sg *ScrabbleGame) inviteUsed(grant uuid.UUID) bool {
+	for _, e := range sg.events {
+		if e.Type == PlayerJoined && e.Invite != nil && *e.Invite == grant {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestInvites(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.JoinURL = "https://wordgame.example.com/join"
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(j GeneralGameRequest) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := create(GeneralGameRequest{Invites: cfg.MaxPlayers + 1}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Creating a game with more invites than seats returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	passphrase := "open sesame"
+	rr := create(GeneralGameRequest{Invites: 2, Passphrase: &passphrase, Options: &GameOptions{Private: true}})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var created GeneralGameRequest
+	if err = json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	} else if len(created.InviteList) != 2 || created.InviteList[0].Token == created.InviteList[1].Token {
+		t.Fatalf("Created %+v, expected two different invites", created.InviteList)
+	}
+	link, err := url.Parse(created.InviteList[0].Link)
+	if err != nil {
+		t.Fatal(err)
+	} else if link.Host != "wordgame.example.com" || link.Query().Get("invite") != created.InviteList[0].Token {
+		t.Errorf("Invite has link %v, expected the join page with its token", link)
+	}
+
+	join := func(token string) *httptest.ResponseRecorder {
+		name := "ashley"
+		payload, err := json.Marshal(GeneralGameRequest{Invite: &token, PlayerName: &name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games/join", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Invited players need neither the game's ID, join code nor passphrase
+	token := created.InviteList[0].Token
+	if rr = join(token); rr.Code != http.StatusCreated {
+		t.Fatalf("Joining with an invite returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var joined GeneralGameRequest
+	if err = json.NewDecoder(rr.Body).Decode(&joined); err != nil {
+		t.Fatal(err)
+	} else if joined.GameID != created.GameID || joined.PlayerID == nil {
+		t.Errorf("Joining with an invite returned %+v, expected a player in game %v", joined, created.GameID)
+	}
+
+	if rr = join(token); rr.Code != http.StatusBadRequest {
+		t.Errorf("Reusing an invite returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	forged := token[:len(token)-2] + "AA"
+	if rr = join(forged); rr.Code != http.StatusBadRequest {
+		t.Errorf("Joining with a forged invite returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Used invites are remembered by persistent stores
+	g, err := srv.games.Get(created.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, grant, err := srv.checkInvite(token)
+	if err != nil {
+		t.Fatal(err)
+	} else if !d.inviteUsed(grant) {
+		t.Error("Decoded game has forgotten the invite was used")
+	}
+}
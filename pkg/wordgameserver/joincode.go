@@ -0,0 +1,78 @@
+package wordgameserver
+
+import (
+	"crypto/rand"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Join codes are short enough to be read out, and leave out characters that
+// are easily mistaken for one another, like 0 and O
+const (
+	joinCodeLength   = 6
+	joinCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	joinCodeAttempts = 10
+)
+
+// newJoinCode generates a random join code
+func newJoinCode() (string, error) {
+	code := make([]byte, joinCodeLength)
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to generate join code")
+		}
+		code[i] = joinCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// assignJoinCode gives the game a join code no other game of the server's has.
+// The game must be locked by the caller.
+func (s *Server) assignJoinCode(g *ScrabbleGame) error {
+	code, err := s.unusedJoinCode(g.ID)
+	if err != nil {
+		return err
+	}
+	g.JoinCode = code
+	return nil
+}
+
+// unusedJoinCode generates a join code no other game of the server's has and
+// claims it for the game with the ID in the server's game store
+func (s *Server) unusedJoinCode(id uuid.UUID) (string, error) {
+	for i := 0; i < joinCodeAttempts; i++ {
+		code, err := newJoinCode()
+		if err != nil {
+			return "", err
+		}
+		if ok, err := s.games.ClaimJoinCode(code, id); err != nil {
+			return "", err
+		} else if ok {
+			return code, nil
+		}
+	}
+	return "", errors.New("Failed to find an unused join code")
+}
+
+// gameByJoinCode finds the ID of the game with the join code, which isn't case
+// sensitive. ErrGameNotFound is returned if no game has it.
+func (s *Server) gameByJoinCode(code string) (uuid.UUID, error) {
+	return s.games.GameByJoinCode(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// releaseJoinCode frees the join code, if there is one, for other games. A
+// failure is only logged, since a code left claimed is just never reused.
+func releaseJoinCode(games GameStore, code string) {
+	if code == "" {
+		return
+	}
+	if err := games.ReleaseJoinCode(code); err != nil {
+		log.Printf("Failed to release join code %v: %v", code, err)
+	}
+}
@@ -0,0 +1,102 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJoinCode(t *testing.T) {
+	srv := newTestServer(t)
+
+	payload, err := json.Marshal(GeneralGameRequest{Options: &GameOptions{Private: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "/game/create", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	var created GeneralGameRequest
+	if err = json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	} else if created.JoinCode == nil {
+		t.Fatal("Private game was created without a join code")
+	}
+	code := *created.JoinCode
+	if len(code) != joinCodeLength || strings.Trim(code, joinCodeAlphabet) != "" {
+		t.Errorf("Join code %q is not %v characters from the alphabet", code, joinCodeLength)
+	}
+
+	join := func(code string) *httptest.ResponseRecorder {
+		name := "ashley"
+		payload, err := json.Marshal(GeneralGameRequest{JoinCode: &code, PlayerName: &name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/join", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.joinGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Codes aren't case sensitive, so they can be typed however they're heard
+	rr = join(strings.ToLower(code))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Joining by code returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var joined GeneralGameRequest
+	if err = json.NewDecoder(rr.Body).Decode(&joined); err != nil {
+		t.Fatal(err)
+	} else if joined.GameID != created.GameID || joined.PlayerID == nil {
+		t.Errorf("Joining by code returned %+v, expected a player in game %v", joined, created.GameID)
+	}
+
+	if rr = join("ZZZZZZ"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Joining with an unknown code returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Private games aren't listed in the lobby
+	g, err := srv.games.Get(created.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Lock()
+	_, listed := srv.lobbyGame(g)
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if listed {
+		t.Error("Private game should not be listed in the lobby")
+	}
+
+	// The code is kept by persistent stores
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.JoinCode != code {
+		t.Errorf("Decoded game has join code %q, expected %q", d.JoinCode, code)
+	}
+
+	// Deleting the game frees its code
+	if err = srv.deleteGame(g, httptest.NewRecorder()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srv.gameByJoinCode(code); err != ErrGameNotFound {
+		t.Errorf("Finding a deleted game's code returned %v, expected %v", err, ErrGameNotFound)
+	}
+}
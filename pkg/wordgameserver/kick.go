@@ -0,0 +1,163 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// KickRequest is the format of the request a player sends to remove another
+// player who has stopped responding, or to vote to
+type KickRequest struct {
+	GameID   uuid.UUID `json:"game_id,omitempty"`
+	PlayerID uuid.UUID `json:"player_id"` // player asking for the removal
+	Player   int       `json:"player"`    // number of the player to remove
+}
+
+// kick handles a request from the voter to remove the player with the number
+// from the game. The game's owner can remove anyone on their own, while
+// anyone else is removed once a majority of the other players still in the
+// game have asked. Votes are dropped once the player does something, and
+// aren't saved with the game.
+//
+// Before the game starts the player's seat is given up and the players who
+// joined after them move up, though the owner can't be removed then. Once
+// it has started the player is resigned for, so their turns are skipped and
+// their tiles are returned to the bag or set aside as if they had conceded.
+// The game must be locked by the caller.
+func (sg *ScrabbleGame) kick(voterID uuid.UUID, number int) error {
+	voter, ok := sg.Players[voterID]
+	playerList := sg.playerList()
+	if !ok {
+		return errors.New("No player with that ID in game")
+	} else if sg.TournamentID != uuid.Nil {
+		return errors.New("Players can't be removed from tournament games")
+	} else if sg.Finished {
+		return errors.New("Game is over")
+	} else if voter.Resigned {
+		return errors.New("Player has resigned")
+	} else if number < 0 || number >= len(playerList) {
+		return errors.New("No player with that number in game")
+	}
+
+	target, owner := playerList[number], sg.owner()
+	if target == voter {
+		return errors.New("Players can't remove themselves")
+	} else if target.Resigned {
+		return errors.New("Player has already left the game")
+	} else if !sg.Active && target == owner {
+		return errors.New("The game's owner can't be removed before it starts")
+	} else if sg.Active && target.Bot {
+		return errors.New("Bots can't be removed once the game has started")
+	}
+
+	if sg.kickVotes == nil {
+		sg.kickVotes = make(map[uuid.UUID]map[uuid.UUID]bool)
+	}
+	if sg.kickVotes[target.ID] == nil {
+		sg.kickVotes[target.ID] = make(map[uuid.UUID]bool)
+	}
+	sg.kickVotes[target.ID][voter.ID] = true
+	if voter != owner && !sg.kickAgreed(target) {
+		return nil
+	}
+
+	e := Event{Type: PlayerKicked, Player: target.ID}
+	if sg.Active && sg.Options.ResignedTiles != ResignedTilesAside {
+		e.Bag = sg.reshuffled(0, target.Tiles)
+	}
+	return sg.record(e)
+}
+
+// kickAgreed returns true once most of the players who can vote have voted to
+// remove the player. Bots and players who have resigned don't vote.
+func (sg *ScrabbleGame) kickAgreed(target *Player) bool {
+	voters, votes := 0, 0
+	for _, p := range sg.Players {
+		if p == target || p.Bot || p.Resigned {
+			continue
+		}
+		voters++
+		if sg.kickVotes[target.ID][p.ID] {
+			votes++
+		}
+	}
+	return votes*2 > voters
+}
+
+// applyKick removes the player from the game, giving up their seat if it
+// hasn't started and resigning for them if it has. An owner who is removed
+// hands the game over to the next player still in it.
+func (sg *ScrabbleGame) applyKick(e Event) {
+	sg.kickVotes = nil
+	if sg.Active {
+		sg.retire(e, true)
+		if e.Player == sg.ownerID {
+			sg.ownerID = uuid.Nil
+			for _, p := range sg.playerList() {
+				if !p.Bot && !p.Resigned {
+					sg.ownerID = p.ID
+					break
+				}
+			}
+		}
+		return
+	}
+
+	removed := sg.Players[e.Player]
+	delete(sg.Players, e.Player)
+	for _, p := range sg.Players {
+		if p.Number > removed.Number {
+			p.Number--
+		}
+	}
+}
+
+// kickHandler handles requests from players to remove another player from a
+// game, identified by the request's path or its game_id, or to vote to. It
+// will respond using the GameStateResponse struct.
+func (s *Server) kickHandler(w http.ResponseWriter, r *http.Request) {
+	var j KickRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	// Once the game has started, its controller has to know about the
+	// change of turn a removal can cause
+	g.Lock()
+	if g.Active {
+		g.Unlock()
+		s.gameRequestHelper(GamePlayRequest{
+			GameID:   j.GameID,
+			PlayerID: j.PlayerID,
+			Type:     kickRequest,
+			kick:     j.Player,
+		}, w, r)
+		return
+	}
+	defer g.Unlock()
+
+	if err = g.kick(j.PlayerID, j.Player); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = s.saveGame(g, w); err != nil {
+		return
+	}
+	writeState(w, r, g.getState(j.PlayerID, g.playerList()))
+}
@@ -0,0 +1,135 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestKickBeforeStart(t *testing.T) {
+	srv := newTestServer(t)
+	g := createScrabbleGame()
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		ids[i], _ = g.addPlayer("ashley" + string('1'+byte(i)))
+	}
+	srv.games.Put(g)
+
+	kick := func(voter uuid.UUID, number int) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(KickRequest{PlayerID: voter, Player: number})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games/"+g.ID.String()+"/kicks", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := kick(ids[1], 0); rr.Code != http.StatusBadRequest {
+		t.Errorf("Removing the creator before the game starts returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := kick(ids[0], 0); rr.Code != http.StatusBadRequest {
+		t.Errorf("Removing oneself returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// The creator doesn't need anyone else to agree
+	rr := kick(ids[0], 1)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var state GameStateResponse
+	if err := json.NewDecoder(rr.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	} else if len(state.Players) != 2 || state.Players[1].Name != "ashley3" || state.Players[1].Number != 1 {
+		t.Errorf("Players are %+v, expected ashley3 to take the removed player's seat", state.Players)
+	}
+
+	// Games restored from their events have the seat given up too
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := d.Players[ids[1]]; ok || len(d.Players) != 2 {
+		t.Error("Decoded game still has the removed player")
+	}
+}
+
+func TestKickDuringGame(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "ADXXXXX")
+	g.Lock()
+	defer g.Unlock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+	bag := len(g.TileBag)
+	rack := len(g.Players[ids[0]].Tiles)
+
+	// Removing the player whose turn it is takes a majority of the others
+	if err := g.kick(ids[1], 0); err != nil {
+		t.Fatal(err)
+	} else if g.Players[ids[0]].Resigned {
+		t.Fatal("Player was removed by one of two other players")
+	}
+	if err := g.kick(ids[1], 0); err != nil {
+		t.Fatal(err)
+	} else if g.Players[ids[0]].Resigned {
+		t.Fatal("Voting twice should only count once")
+	}
+	if err := g.kick(ids[2], 0); err != nil {
+		t.Fatal(err)
+	}
+
+	p := g.Players[ids[0]]
+	if !p.Resigned || !p.Kicked {
+		t.Fatal("Player should be removed once a majority agree")
+	} else if g.TurnCount%len(g.Players) != 1 {
+		t.Errorf("Turn is with player %v, expected it to move on", g.TurnCount%len(g.Players))
+	} else if len(p.Tiles) != 0 || len(g.TileBag) != bag+rack {
+		t.Error("Removed player's tiles should be returned to the bag")
+	} else if m := g.history[len(g.history)-1]; !m.Kicked || !m.Resign {
+		t.Errorf("Last move is %+v, expected the removal", m)
+	} else if g.Finished {
+		t.Error("Game should go on with two players left")
+	}
+
+	if err := g.kick(ids[0], 1); err == nil {
+		t.Error("Player who was removed could vote")
+	}
+}
+
+func TestKickVotesDropped(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "ADXXXXX")
+	g.Lock()
+	defer g.Unlock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	if err := g.kick(ids[2], 0); err != nil {
+		t.Fatal(err)
+	}
+	// Passing shows the player is still there
+	if err := g.pass(GamePlayRequest{PlayerID: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.kick(ids[1], 0); err != nil {
+		t.Fatal(err)
+	} else if g.Players[ids[0]].Resigned {
+		t.Error("Vote from before the player moved should have been dropped")
+	}
+}
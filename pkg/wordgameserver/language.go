@@ -0,0 +1,96 @@
+package wordgameserver
+
+import (
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/pkg/errors"
+)
+
+// Languages a game can be played in, by their ISO 639-1 codes
+const (
+	LanguageEnglish = "en" // the variant's own tiles, played when a game doesn't choose a language
+	LanguageSpanish = "es" // 100 tiles including Ñ and the digraphs CH, LL and RR
+	LanguageFrench  = "fr" // 102 tiles, with accents ignored as in French Scrabble
+	LanguageGerman  = "de" // 102 tiles including Ä, Ö and Ü
+)
+
+// languages holds the tiles of every language other than English, which are
+// played with instead of the variant's own
+var languages = map[string]TileSet{
+	LanguageSpanish: {Name: LanguageSpanish, Tiles: map[string]TileSpec{
+		"?": {Count: 2, Value: 0}, "A": {Count: 12, Value: 1}, "B": {Count: 2, Value: 3}, "C": {Count: 4, Value: 3},
+		"CH": {Count: 1, Value: 5}, "D": {Count: 5, Value: 2}, "E": {Count: 12, Value: 1}, "F": {Count: 1, Value: 4},
+		"G": {Count: 2, Value: 2}, "H": {Count: 2, Value: 4}, "I": {Count: 6, Value: 1}, "J": {Count: 1, Value: 8},
+		"L": {Count: 4, Value: 1}, "LL": {Count: 1, Value: 8}, "M": {Count: 2, Value: 3}, "N": {Count: 5, Value: 1},
+		"Ñ": {Count: 1, Value: 8}, "O": {Count: 9, Value: 1}, "P": {Count: 2, Value: 3}, "Q": {Count: 1, Value: 5},
+		"R": {Count: 5, Value: 1}, "RR": {Count: 1, Value: 8}, "S": {Count: 6, Value: 1}, "T": {Count: 4, Value: 1},
+		"U": {Count: 5, Value: 1}, "V": {Count: 1, Value: 4}, "X": {Count: 1, Value: 8}, "Y": {Count: 1, Value: 4},
+		"Z": {Count: 1, Value: 10},
+	}},
+	LanguageFrench: {Name: LanguageFrench, Tiles: map[string]TileSpec{
+		"?": {Count: 2, Value: 0}, "A": {Count: 9, Value: 1}, "B": {Count: 2, Value: 3}, "C": {Count: 2, Value: 3},
+		"D": {Count: 3, Value: 2}, "E": {Count: 15, Value: 1}, "F": {Count: 2, Value: 4}, "G": {Count: 2, Value: 2},
+		"H": {Count: 2, Value: 4}, "I": {Count: 8, Value: 1}, "J": {Count: 1, Value: 8}, "K": {Count: 1, Value: 10},
+		"L": {Count: 5, Value: 1}, "M": {Count: 3, Value: 2}, "N": {Count: 6, Value: 1}, "O": {Count: 6, Value: 1},
+		"P": {Count: 2, Value: 3}, "Q": {Count: 1, Value: 8}, "R": {Count: 6, Value: 1}, "S": {Count: 6, Value: 1},
+		"T": {Count: 6, Value: 1}, "U": {Count: 6, Value: 1}, "V": {Count: 2, Value: 4}, "W": {Count: 1, Value: 10},
+		"X": {Count: 1, Value: 10}, "Y": {Count: 1, Value: 10}, "Z": {Count: 1, Value: 10},
+	}},
+	LanguageGerman: {Name: LanguageGerman, Tiles: map[string]TileSpec{
+		"?": {Count: 2, Value: 0}, "A": {Count: 5, Value: 1}, "Ä": {Count: 1, Value: 6}, "B": {Count: 2, Value: 3},
+		"C": {Count: 2, Value: 4}, "D": {Count: 4, Value: 1}, "E": {Count: 15, Value: 1}, "F": {Count: 2, Value: 4},
+		"G": {Count: 3, Value: 2}, "H": {Count: 4, Value: 2}, "I": {Count: 6, Value: 1}, "J": {Count: 1, Value: 6},
+		"K": {Count: 2, Value: 4}, "L": {Count: 3, Value: 2}, "M": {Count: 4, Value: 3}, "N": {Count: 9, Value: 1},
+		"O": {Count: 3, Value: 2}, "Ö": {Count: 1, Value: 8}, "P": {Count: 1, Value: 4}, "Q": {Count: 1, Value: 10},
+		"R": {Count: 6, Value: 1}, "S": {Count: 7, Value: 1}, "T": {Count: 6, Value: 1}, "U": {Count: 6, Value: 1},
+		"Ü": {Count: 1, Value: 6}, "V": {Count: 1, Value: 6}, "W": {Count: 1, Value: 3}, "X": {Count: 1, Value: 8},
+		"Y": {Count: 1, Value: 10}, "Z": {Count: 1, Value: 3},
+	}},
+}
+
+// lookupLanguage returns the tiles of the language with the code, or nil for
+// English or an empty code
+func lookupLanguage(code string) (*TileSet, error) {
+	if code == "" || code == LanguageEnglish {
+		return nil, nil
+	}
+	ts, ok := languages[code]
+	if !ok {
+		return nil, errors.New("Unknown language '" + code + "'")
+	}
+	return &ts, nil
+}
+
+// tileSet returns the tiles the game is played with instead of its variant's,
+// either the tile set chosen or those of its language, or nil if it is played
+// with the variant's own
+func (o GameOptions) tileSet() *TileSet {
+	if o.TileSet != nil {
+		return o.TileSet
+	}
+	ts, _ := lookupLanguage(o.Language)
+	return ts
+}
+
+// LanguageValidator is a WordValidator with dictionaries for languages other
+// than English too. A server whose validator is a LanguageValidator checks the
+// words of games played in another language against its dictionary for the
+// language, and otherwise accepts any word in them.
+type LanguageValidator interface {
+	dictionary.WordValidator
+	Language(code string) dictionary.WordValidator // dictionary for the language, nil if there isn't one
+	LanguageCodes() []string                       // codes of the languages with a dictionary
+}
+
+// languageValidator returns the dictionary words played in the language are
+// checked against, given the server's validator
+func languageValidator(validator dictionary.WordValidator, code string) dictionary.WordValidator {
+	if code == "" || code == LanguageEnglish {
+		return validator
+	}
+	if lv, ok := validator.(LanguageValidator); ok {
+		if v := lv.Language(code); v != nil {
+			return v
+		}
+	}
+	return nil
+}
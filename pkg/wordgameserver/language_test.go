@@ -0,0 +1,145 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+)
+
+func TestLanguageTileSets(t *testing.T) {
+	sizes := map[string]int{LanguageSpanish: 100, LanguageFrench: 102, LanguageGerman: 102}
+	for code, size := range sizes {
+		ts, err := lookupLanguage(code)
+		if err != nil {
+			t.Fatal(err)
+		} else if err = ts.validate(); err != nil {
+			t.Errorf("Tiles of %v are invalid: %v", code, err)
+		} else if n := len(standardVariant.withTiles(ts).bag); n != size {
+			t.Errorf("Bag of %v has %v tiles, expected %v", code, n, size)
+		}
+	}
+
+	if ts, err := lookupLanguage(LanguageEnglish); ts != nil || err != nil {
+		t.Errorf("English returned tiles %+v and error %v, expected the variant's own", ts, err)
+	}
+	if _, err := lookupLanguage("xx"); err == nil {
+		t.Error("Looking up an unknown language should fail")
+	}
+}
+
+func TestSpanishGame(t *testing.T) {
+	en, err := dictionary.NewWordList(strings.NewReader("CAT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := dictionary.NewWordList(strings.NewReader("ÑU\nCHUZO\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := dictionary.Languages{English: en, Others: map[string]dictionary.WordValidator{LanguageSpanish: es}}
+	srv, err := NewServer(DefaultConfig(), validator, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(opts GameOptions) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, err := json.Marshal(GeneralGameRequest{Options: &opts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := create(GameOptions{Language: "xx"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Creating a game in an unknown language returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := create(GameOptions{Language: LanguageSpanish})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Creating a Spanish game returned status code %v. Error: %v", rr.Code, rr.Body)
+	}
+	var resp GeneralGameRequest
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	g, err := srv.games.Get(resp.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	g.Lock()
+	defer g.Unlock()
+	if len(g.TileBag) != 100 || g.variant.tiles[engine.TileCH].Value != 5 {
+		t.Fatalf("Game has %v tiles with CH worth %v, expected 100 worth 5", len(g.TileBag), g.variant.tiles[engine.TileCH].Value)
+	}
+
+	first, err := g.addPlayer("ashley1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := g.addPlayer("ashley2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Players[first].Tiles = Letters{engine.TileÑ, 'U', 'O', 'A', 'A', 'A', 'A'}
+	g.Players[second].Tiles = Letters{engine.TileCH, 'Z', 'O', ' ', 'A', 'A', 'A'}
+
+	// Words are checked against the Spanish dictionary, spelled with their
+	// accented letters
+	if err = g.executePlay(GamePlayRequest{
+		PlayerID: first,
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 7},
+		Tiles:    Letters{engine.TileÑ, 'O'},
+	}); err == nil {
+		t.Fatal("Playing a word missing from the Spanish dictionary should fail")
+	}
+	if err = g.executePlay(GamePlayRequest{
+		PlayerID: first,
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 7},
+		Tiles:    Letters{engine.TileÑ, 'U'},
+	}); err != nil {
+		t.Fatal(err)
+	} else if p := g.Players[first]; p.Score != 9 {
+		t.Errorf("ÑU scored %v, expected 9", p.Score)
+	}
+
+	// A digraph is a single tile worth its own value
+	if err = g.executePlay(GamePlayRequest{
+		PlayerID: second,
+		StartPos: SquareCoordinate{Row: 6, Col: 7},
+		EndPos:   SquareCoordinate{Row: 9, Col: 7},
+		Tiles:    Letters{engine.TileCH, 'Z', 'O'},
+	}); err != nil {
+		t.Fatal(err)
+	} else if p := g.Players[second]; p.Score != 17 {
+		t.Errorf("CHUZO scored %v, expected 17", p.Score)
+	}
+
+	// Blanks can stand for letters beyond A to Z
+	if _, _, words, err := g.Board.LayTiles(engine.Play{
+		StartPos: SquareCoordinate{Row: 10, Col: 7},
+		EndPos:   SquareCoordinate{Row: 10, Col: 7},
+		Tiles:    Letters(" "),
+		Blanks:   Letters{engine.TileLL},
+	}, g.variant.tiles); err != nil {
+		t.Fatal(err)
+	} else if words[0].Word != "CHUZOLL" {
+		t.Errorf("Blank formed %q, expected CHUZOLL", words[0].Word)
+	}
+}
@@ -0,0 +1,139 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/pkg/errors"
+)
+
+// Limits on the size of a custom board. Boards need a center square for the
+// first play, so have an odd size, and are no bigger than Super Scrabble's.
+const (
+	minLayoutSize = 5
+	maxLayoutSize = 21
+)
+
+// BoardLayout is a board designed by a game's creator, or loaded by the server
+// from its layout directory, played on instead of the variant's own. The star
+// is always placed on the center square.
+type BoardLayout struct {
+	Name     string                        `json:"name,omitempty"`     // name of a layout loaded by the server, or a label for one given in full
+	Size     int                           `json:"size,omitempty"`     // number of rows and columns, 0 to use the named layout
+	Premiums map[string][]SquareCoordinate `json:"premiums,omitempty"` // coordinates of each type of premium square, anywhere on the board
+}
+
+// validate checks that the layout describes a board that can be played on
+func (l BoardLayout) validate() error {
+	if l.Size < minLayoutSize || l.Size > maxLayoutSize || l.Size%2 == 0 {
+		return errors.New("Layout size must be an odd number between " + strconv.Itoa(minLayoutSize) + " and " + strconv.Itoa(maxLayoutSize))
+	}
+
+	center := SquareCoordinate{Row: l.Size / 2, Col: l.Size / 2}
+	seen := make(map[SquareCoordinate]bool)
+	for name, coordinates := range l.Premiums {
+		if _, ok := engine.SquareTypes[name]; !ok || name == "plain" || name == "star" {
+			return errors.New("Unknown premium square type '" + name + "'")
+		}
+		for _, sc := range coordinates {
+			switch {
+			case sc.Row < 0 || sc.Row >= l.Size || sc.Col < 0 || sc.Col >= l.Size:
+				return errors.New("Premium square at row " + strconv.Itoa(sc.Row) + " column " + strconv.Itoa(sc.Col) + " is off the board")
+			case sc == center:
+				return errors.New("The center square is reserved for the star")
+			case seen[sc]:
+				return errors.New("Row " + strconv.Itoa(sc.Row) + " column " + strconv.Itoa(sc.Col) + " is given more than one premium")
+			}
+			seen[sc] = true
+		}
+	}
+	return nil
+}
+
+// newBoard returns an empty board with the layout's premium squares
+func (l BoardLayout) newBoard() ScrabbleBoard {
+	sb := make(ScrabbleBoard, l.Size)
+	for i := range sb {
+		sb[i] = make([]Square, l.Size)
+		for j := range sb[i] {
+			sb[i][j] = Square{SquareType: "plain"}
+		}
+	}
+
+	for name, coordinates := range l.Premiums {
+		for _, sc := range coordinates {
+			sb[sc.Row][sc.Col] = Square{SquareType: name}
+		}
+	}
+	sb[l.Size/2][l.Size/2] = Square{SquareType: "star"}
+
+	return sb
+}
+
+// boardFor returns an empty board for a game of the variant, laid out by the
+// game's own layout if it has one
+func (v *variant) boardFor(layout *BoardLayout) ScrabbleBoard {
+	if layout != nil {
+		return layout.newBoard()
+	}
+	return v.newBoard()
+}
+
+// LoadLayouts reads every board layout in the directory, from JSON files
+// named after the layout they hold. Each layout is validated, so a server
+// refuses to start with one that can't be played on.
+func LoadLayouts(dir string) (map[string]BoardLayout, error) {
+	layouts := make(map[string]BoardLayout)
+	err := loadJSONDir(dir, func(name string, data []byte) error {
+		var l BoardLayout
+		if err := json.Unmarshal(data, &l); err != nil {
+			return err
+		} else if err = l.validate(); err != nil {
+			return err
+		}
+		l.Name = name
+		layouts[name] = l
+		return nil
+	})
+	return layouts, err
+}
+
+// loadJSONDir calls load with the contents of every JSON file in the
+// directory, named by the file without its extension
+func loadJSONDir(dir string, load func(name string, data []byte) error) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err = load(strings.TrimSuffix(filepath.Base(path), ".json"), data); err != nil {
+			return errors.New("Invalid file " + path + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// resolveLayout replaces a layout chosen by name in the options with the one
+// the server loaded, so the game keeps it even if the file later changes
+func (s *Server) resolveLayout(o *GameOptions) error {
+	if o.Layout == nil || o.Layout.Size != 0 {
+		return nil
+	}
+	l, ok := s.layouts[o.Layout.Name]
+	if !ok {
+		return errors.New("Unknown layout '" + o.Layout.Name + "'")
+	}
+	o.Layout = &l
+	return nil
+}
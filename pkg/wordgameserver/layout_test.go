@@ -0,0 +1,129 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoardLayoutValidate(t *testing.T) {
+	tests := []struct {
+		layout BoardLayout
+		valid  bool
+	}{
+		{BoardLayout{Size: 9}, true},
+		{BoardLayout{Size: 9, Premiums: map[string][]SquareCoordinate{"tripleWord": {{Row: 0, Col: 0}, {Row: 8, Col: 8}}}}, true},
+		{BoardLayout{Size: 3}, false},
+		{BoardLayout{Size: 10}, false},
+		{BoardLayout{Size: 23}, false},
+		{BoardLayout{Size: 9, Premiums: map[string][]SquareCoordinate{"sextupleWord": {{Row: 0, Col: 0}}}}, false},
+		{BoardLayout{Size: 9, Premiums: map[string][]SquareCoordinate{"star": {{Row: 0, Col: 0}}}}, false},
+		{BoardLayout{Size: 9, Premiums: map[string][]SquareCoordinate{"doubleWord": {{Row: 9, Col: 0}}}}, false},
+		{BoardLayout{Size: 9, Premiums: map[string][]SquareCoordinate{"doubleWord": {{Row: 4, Col: 4}}}}, false},
+		{BoardLayout{Size: 9, Premiums: map[string][]SquareCoordinate{"doubleWord": {{Row: 1, Col: 1}}, "tripleWord": {{Row: 1, Col: 1}}}}, false},
+	}
+	for _, test := range tests {
+		if err := test.layout.validate(); (err == nil) != test.valid {
+			t.Errorf("Validating %+v returned %v, expected valid to be %v", test.layout, err, test.valid)
+		}
+	}
+}
+
+func TestLayoutGame(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "corners.json"), []byte(`{
+	"size": 11,
+	"premiums": {
+		"quadrupleWord": [{"row": 0, "col": 0}, {"row": 0, "col": 10}, {"row": 10, "col": 0}, {"row": 10, "col": 10}]
+	}
+}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.LayoutDir = dir
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(layout BoardLayout) (int, *ScrabbleGame) {
+		t.Helper()
+		payload, err := json.Marshal(GeneralGameRequest{Options: &GameOptions{Layout: &layout}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			return rr.Code, nil
+		}
+
+		var resp GeneralGameRequest
+		if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		g, err := srv.games.Get(resp.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(g.Stop)
+		return rr.Code, g
+	}
+
+	for _, layout := range []BoardLayout{{Name: "missing"}, {}, {Size: 8}} {
+		if code, _ := create(layout); code != http.StatusBadRequest {
+			t.Errorf("Creating a game with layout %+v returned status code %v, expected %v", layout, code, http.StatusBadRequest)
+		}
+	}
+
+	_, named := create(BoardLayout{Name: "corners"})
+	if named == nil {
+		t.Fatal("Creating a game with a named layout failed")
+	}
+	named.Lock()
+	if len(named.Board) != 11 || named.Board[10][10].SquareType != "quadrupleWord" || named.Board[5][5].SquareType != "star" {
+		t.Errorf("Game with the corners layout has a board of %v rows, expected 11 with quadruple words in the corners", len(named.Board))
+	} else if named.Options.Layout == nil || named.Options.Layout.Size != 11 {
+		t.Errorf("Game options hold layout %+v, expected the loaded layout", named.Options.Layout)
+	}
+	named.Unlock()
+
+	_, custom := create(BoardLayout{Size: 7, Premiums: map[string][]SquareCoordinate{"tripleLetter": {{Row: 3, Col: 0}}}})
+	if custom == nil {
+		t.Fatal("Creating a game with a custom layout failed")
+	}
+
+	// The layout survives the game being saved and loaded
+	custom.Lock()
+	data, err := EncodeGame(custom)
+	custom.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(d.Board) != 7 || d.Board[3][0].SquareType != "tripleLetter" || d.Board[3][3].SquareType != "star" {
+		t.Errorf("Decoded game has a board of %v rows, expected 7 with a triple letter at row 3 column 0", len(d.Board))
+	}
+
+	if _, err = LoadLayouts(t.TempDir()); err != nil {
+		t.Errorf("Loading an empty layout directory failed: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "even.json"), []byte(`{"size": 12}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewServer(cfg, nil, nil, nil, nil); err == nil {
+		t.Error("Server started with an invalid layout")
+	}
+}
@@ -0,0 +1,344 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GameResult is how a player fared in a finished game
+type GameResult struct {
+	GameID    uuid.UUID     `json:"game_id"`
+	Player    string        `json:"player"`            // name of the player
+	Account   *uuid.UUID    `json:"account,omitempty"` // account the player was linked to, if any
+	Score     int           `json:"score"`
+	Won       bool          `json:"won"`
+	Bingos    int           `json:"bingos"`               // number of plays using every tile in a full hand
+	BestWord  string        `json:"best_word,omitempty"`  // main word of the player's highest-scoring play
+	BestScore int           `json:"best_score,omitempty"` // points scored by that play
+	Turns     int           `json:"turns"`                // number of moves the player made
+	TurnTime  time.Duration `json:"turn_time"`            // total time the player took over their moves
+	Finished  time.Time     `json:"finished"`             // when the game ended
+
+	// How the player's moves compared with the best plays available, if the
+	// game was analysed
+	AnalyzedMoves   int     `json:"analyzed_moves,omitempty"`    // number of the player's moves compared
+	EquityLost      float64 `json:"equity_lost,omitempty"`       // total equity given up over those moves
+	BiggestMiss     string  `json:"biggest_miss,omitempty"`      // main word of the play missed by the most equity
+	BiggestMissLoss float64 `json:"biggest_miss_loss,omitempty"` // equity given up by missing it
+	MissedBingos    int     `json:"missed_bingos,omitempty"`     // number of moves a bingo was available but not played
+}
+
+// ResultStore holds the results of finished games. Implementations must be
+// safe for concurrent use. A GameStore that also implements ResultStore is
+// used for results by the server it is given to.
+type ResultStore interface {
+	PutResults(results []GameResult) error                // add the results of a game
+	GetResults(since time.Time) ([]GameResult, error)     // results of games finished since the time, or all of them if it is zero
+	GetPlayerResults(player string) ([]GameResult, error) // results of every game the player has finished
+}
+
+// MemoryResultStore is the default ResultStore, which keeps results in memory
+// for the lifetime of the process
+type MemoryResultStore struct {
+	sync.Mutex
+	results []GameResult
+}
+
+// NewMemoryResultStore creates an empty in-memory result store
+func NewMemoryResultStore() *MemoryResultStore {
+	return &MemoryResultStore{}
+}
+
+// PutResults adds the results to the store
+func (ms *MemoryResultStore) PutResults(results []GameResult) error {
+	ms.Lock()
+	ms.results = append(ms.results, results...)
+	ms.Unlock()
+	return nil
+}
+
+// GetResults retrieves the results of games finished since the time
+func (ms *MemoryResultStore) GetResults(since time.Time) ([]GameResult, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	var results []GameResult
+	for _, r := range ms.results {
+		if !r.Finished.Before(since) {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// GetPlayerResults retrieves the results of the player's games
+func (ms *MemoryResultStore) GetPlayerResults(player string) ([]GameResult, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	var results []GameResult
+	for _, r := range ms.results {
+		if r.Player == player {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// saveResults adds the results of the game's players to the server's results
+// now the game has finished. Bots don't have results. Analysing the game can
+// take seconds, so the results are analysed and saved in the background from
+// a copy of the moves, leaving the game free to answer requests meanwhile. The
+// game must be locked by the caller.
+func (sg *ScrabbleGame) saveResults(finished time.Time) {
+	if sg.results == nil {
+		return
+	}
+
+	won := make(map[int]bool)
+	for _, n := range sg.Winners {
+		won[n] = true
+	}
+	// Each move's time is taken from the end of the move before it, or the
+	// start of the game. Moves recorded before an imported game started
+	// weren't timed.
+	var turnStart time.Time
+	for _, e := range sg.events {
+		if e.Type == GameStarted {
+			turnStart = e.Time
+		}
+	}
+	stats := make(map[int]*GameResult)
+	for _, p := range sg.Players {
+		stats[p.Number] = &GameResult{}
+	}
+	for _, m := range sg.history {
+		r := stats[m.Player]
+		if m.Time.After(turnStart) {
+			r.Turns++
+			r.TurnTime += m.Time.Sub(turnStart)
+			turnStart = m.Time
+		}
+		if m.Swap || m.Retracted {
+			continue
+		}
+		if len(m.Squares) == maxTiles {
+			r.Bingos++
+		}
+		if len(m.Words) > 0 && m.Score > r.BestScore {
+			r.BestWord, r.BestScore = m.Words[0], m.Score
+		}
+	}
+	var players []int
+	for _, p := range sg.playerList() {
+		if p.Bot {
+			continue
+		}
+		r := stats[p.Number]
+		r.GameID = sg.ID
+		r.Player = p.Name
+		r.Account = p.Account
+		r.Score = p.Score
+		r.Won = won[p.Number]
+		r.Finished = finished
+		players = append(players, p.Number)
+	}
+	if len(players) == 0 {
+		return
+	}
+
+	store, replay := sg.results, sg.replay()
+	wg, _ := sg.Validator.(WordGenerator)
+	sg.saving.Add(1)
+	go func() {
+		defer sg.saving.Done()
+		analyzeResults(replay, wg, stats)
+
+		results := make([]GameResult, len(players))
+		for i, n := range players {
+			results[i] = *stats[n]
+		}
+		if err := store.PutResults(results); err != nil {
+			log.Printf("Failed to save results of game %v: %v", replay.GameID, err)
+		}
+	}()
+}
+
+// analyzeResults adds how each player's moves in the replayed game compared
+// with the best plays available to their results, kept by player number, if
+// the game's dictionary can be used to find plays
+func analyzeResults(replay GameReplay, wg WordGenerator, stats map[int]*GameResult) {
+	if wg == nil || wg.DAWG() == nil {
+		return
+	}
+	a, err := AnalyzeGame(replay, wg.DAWG())
+	if err != nil {
+		log.Printf("Failed to analyse game %v: %v", replay.GameID, err)
+		return
+	}
+
+	for _, m := range a.Moves {
+		r := stats[m.Player]
+		r.AnalyzedMoves++
+		r.EquityLost += m.EquityLost
+		if m.MissedBingo {
+			r.MissedBingos++
+		}
+		if m.EquityLost > r.BiggestMissLoss {
+			r.BiggestMiss, r.BiggestMissLoss = m.Best.Words[0], m.EquityLost
+		}
+	}
+}
+
+// LeaderboardEntry is a player's standing on the leaderboard
+type LeaderboardEntry struct {
+	Player       string  `json:"player"`
+	Rating       int     `json:"rating,omitempty"` // current rating, if the player has played a rated game
+	Games        int     `json:"games"`
+	Wins         int     `json:"wins"`
+	AverageScore float64 `json:"average_score"`
+	Bingos       int     `json:"bingos"`
+}
+
+// LeaderboardResponse is the format of the response sent to clients when they
+// request the leaderboard
+type LeaderboardResponse struct {
+	Players []LeaderboardEntry `json:"players"`
+	Total   int                `json:"total"` // number of players on the leaderboard, across every page
+}
+
+// Orders the leaderboard can be sorted in, best first
+const (
+	LeaderboardRating       = "rating"
+	LeaderboardWins         = "wins"
+	LeaderboardAverageScore = "average_score"
+	LeaderboardBingos       = "bingos"
+)
+
+// Windows of time the leaderboard can cover
+const (
+	LeaderboardAllTime = "all"
+	LeaderboardWeekly  = "week"
+)
+
+// leaderboardLess reports whether entry a ranks above entry b when the
+// leaderboard is sorted in the order given
+var leaderboardLess = map[string]func(a, b LeaderboardEntry) bool{
+	LeaderboardRating:       func(a, b LeaderboardEntry) bool { return a.Rating > b.Rating },
+	LeaderboardWins:         func(a, b LeaderboardEntry) bool { return a.Wins > b.Wins },
+	LeaderboardAverageScore: func(a, b LeaderboardEntry) bool { return a.AverageScore > b.AverageScore },
+	LeaderboardBingos:       func(a, b LeaderboardEntry) bool { return a.Bingos > b.Bingos },
+}
+
+// leaderboard totals the results of each player, taking the ratings of those
+// with an account from the rating store
+func leaderboard(results []GameResult, ratings RatingStore) ([]LeaderboardEntry, error) {
+	entries := make(map[string]*LeaderboardEntry)
+	var names []string
+	accounts := make(map[string]string)
+	var ids []string
+	for _, r := range results {
+		e, ok := entries[r.Player]
+		if !ok {
+			e = &LeaderboardEntry{Player: r.Player}
+			entries[r.Player] = e
+			names = append(names, r.Player)
+		}
+		if _, ok = accounts[r.Player]; !ok && r.Account != nil {
+			accounts[r.Player] = r.Account.String()
+			ids = append(ids, accounts[r.Player])
+		}
+		e.Games++
+		if r.Won {
+			e.Wins++
+		}
+		e.Bingos += r.Bingos
+		e.AverageScore += float64(r.Score)
+	}
+
+	current, err := ratings.GetRatings(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	board := make([]LeaderboardEntry, 0, len(entries))
+	for _, name := range names {
+		e := entries[name]
+		e.AverageScore /= float64(e.Games)
+		if id, ok := accounts[name]; ok {
+			e.Rating = current[id].Rating
+		}
+		board = append(board, *e)
+	}
+	return board, nil
+}
+
+// leaderboardHandler handles requests for the leaderboard. Players are sorted
+// by the measure given by the sort query parameter, their rating by default,
+// and the window parameter limits the results counted to those of the last
+// week. Pages of the leaderboard are chosen with the limit and offset
+// parameters.
+func (s *Server) leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, offset, ok := pageParams(w, query)
+	if !ok {
+		return
+	}
+
+	order := LeaderboardRating
+	if v := query.Get("sort"); v != "" {
+		order = v
+	}
+	less, ok := leaderboardLess[order]
+	if !ok {
+		http.Error(w, "Unknown sort '"+order+"'", http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	switch window := query.Get("window"); window {
+	case LeaderboardAllTime, "":
+	case LeaderboardWeekly:
+		since = time.Now().AddDate(0, 0, -7)
+	default:
+		http.Error(w, "Unknown window '"+window+"'", http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.results.GetResults(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	board, err := leaderboard(results, s.ratings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Players tied on the measure are ordered by name so pages don't overlap
+	sort.Slice(board, func(i, j int) bool {
+		if less(board[i], board[j]) {
+			return true
+		} else if less(board[j], board[i]) {
+			return false
+		}
+		return board[i].Player < board[j].Player
+	})
+
+	start, end := page(len(board), limit, offset)
+	resp, err := json.Marshal(LeaderboardResponse{Players: board[start:end], Total: len(board)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
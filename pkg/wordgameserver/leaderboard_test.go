@@ -0,0 +1,210 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/google/uuid"
+)
+
+func TestSaveResults(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	if err := g.addBots(1, ""); err != nil {
+		t.Fatal(err)
+	}
+	results := NewMemoryResultStore()
+	g.results = results
+
+	started := time.Now()
+	g.events = append(g.events, Event{Type: GameStarted, Time: started})
+	g.history = []Move{
+		{Player: 0, Words: []string{"CAT"}, Squares: make([]SquareCoordinate, 3), Score: 10, Time: started.Add(10 * time.Second)},
+		{Player: 1, Words: []string{"DOGS"}, Squares: make([]SquareCoordinate, 4), Score: 12, Time: started.Add(40 * time.Second)},
+		{Player: 2, Pass: true, Time: started.Add(41 * time.Second)},
+		{Player: 0, Words: []string{"RETAINS", "AT"}, Squares: make([]SquareCoordinate, maxTiles), Score: 80, Time: started.Add(61 * time.Second)},
+		{Player: 1, Words: []string{"ZAX"}, Squares: make([]SquareCoordinate, maxTiles), Score: 95, Retracted: true, Time: started.Add(71 * time.Second)},
+		{Player: 2, Pass: true, Time: started.Add(72 * time.Second)},
+		{Player: 0, Swap: true, Swapped: make(Letters, maxTiles), Time: started.Add(82 * time.Second)},
+	}
+	g.Players[ids[0]].Score = 90
+	g.Players[ids[1]].Score = 12
+	g.endGame(nil)
+	finished := time.Now()
+	g.saveResults(finished)
+	g.saving.Wait()
+
+	saved, err := results.GetResults(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GameResult{
+		{GameID: g.ID, Player: "ashley1", Score: 90, Won: true, Bingos: 1, BestWord: "RETAINS", BestScore: 80,
+			Turns: 3, TurnTime: 40 * time.Second, Finished: finished},
+		{GameID: g.ID, Player: "ashley2", Score: 12, BestWord: "DOGS", BestScore: 12,
+			Turns: 2, TurnTime: 40 * time.Second, Finished: finished},
+	}
+	if len(saved) != len(want) {
+		t.Fatalf("Saved %v results, expected %v for the players who aren't bots", len(saved), len(want))
+	}
+	for i := range want {
+		if saved[i] != want[i] {
+			t.Errorf("Saved result %+v, expected %+v", saved[i], want[i])
+		}
+	}
+}
+
+// pendingWords is a dictionary whose words can't be compiled for analysis
+// until it is released
+type pendingWords struct {
+	*dictionary.WordList
+	release chan struct{}
+}
+
+func (w pendingWords) DAWG() *dictionary.DAWG {
+	<-w.release
+	return w.WordList.DAWG()
+}
+
+func TestResultsAnalysedInBackground(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	results := NewMemoryResultStore()
+	g.results = results
+	words := pendingWords{WordList: g.Validator.(*dictionary.WordList), release: make(chan struct{})}
+	g.Validator = words
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	request := func(r GamePlayRequest) GameStateResponse {
+		t.Helper()
+		answered := make(chan GameStateResponse, 1)
+		go func() {
+			state, err := g.request(r)
+			if err != nil {
+				t.Error(err)
+			}
+			answered <- state
+		}()
+		select {
+		case state := <-answered:
+			return state
+		case <-time.After(5 * time.Second):
+			close(words.release)
+			t.Fatal("Request wasn't answered while the game was being analysed")
+			return GameStateResponse{}
+		}
+	}
+
+	// Both players passing ends the game, which is answered and can still be
+	// seen while its results wait to be analysed
+	request(GamePlayRequest{PlayerID: ids[0], Type: passRequest})
+	if state := request(GamePlayRequest{PlayerID: ids[1], Type: passRequest}); !state.Finished {
+		t.Fatal("Game not finished after both players passed")
+	}
+	if state := request(GamePlayRequest{PlayerID: ids[0]}); !state.Finished {
+		t.Error("Finished game's state isn't finished")
+	}
+	if saved, _ := results.GetResults(time.Time{}); len(saved) != 0 {
+		t.Errorf("Saved %v results before the game was analysed, expected none", len(saved))
+	}
+
+	close(words.release)
+	g.saving.Wait()
+	if saved, _ := results.GetResults(time.Time{}); len(saved) != 2 {
+		t.Errorf("Saved %v results once the game was analysed, expected 2", len(saved))
+	} else if saved[0].AnalyzedMoves != 1 {
+		t.Errorf("First player has %v analysed moves, expected 1", saved[0].AnalyzedMoves)
+	}
+}
+
+func TestLeaderboardHandler(t *testing.T) {
+	srv := newTestServer(t)
+
+	// Ratings are found by account, so the guest ashley2 has none
+	account1, account3 := uuid.New(), uuid.New()
+	lastWeek := time.Now().AddDate(0, 0, -8)
+	srv.results.PutResults([]GameResult{
+		{GameID: uuid.New(), Player: "ashley1", Account: &account1, Score: 400, Won: true, Bingos: 2, Finished: lastWeek},
+		{GameID: uuid.New(), Player: "ashley2", Score: 200, Finished: lastWeek},
+		{GameID: uuid.New(), Player: "ashley1", Account: &account1, Score: 200, Finished: time.Now()},
+		{GameID: uuid.New(), Player: "ashley3", Account: &account3, Score: 350, Won: true, Bingos: 1, Finished: time.Now()},
+	})
+	srv.ratings.PutRatings([]Rating{
+		{Player: account1.String(), Rating: 1490, Games: 1},
+		{Player: account3.String(), Rating: 1516, Games: 1},
+		{Player: "ashley2", Rating: 1600, Games: 1},
+	})
+
+	list := func(query string) (int, LeaderboardResponse) {
+		t.Helper()
+		req, err := http.NewRequest("GET", "/leaderboard"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.leaderboardHandler).ServeHTTP(rr, req)
+
+		var resp LeaderboardResponse
+		if rr.Code == http.StatusOK {
+			if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, resp
+	}
+
+	tests := []struct {
+		query   string
+		players []string
+		total   int
+	}{
+		{"", []string{"ashley3", "ashley1", "ashley2"}, 3},
+		{"?sort=wins", []string{"ashley1", "ashley3", "ashley2"}, 3},
+		{"?sort=average_score", []string{"ashley3", "ashley1", "ashley2"}, 3},
+		{"?sort=bingos&window=week", []string{"ashley3", "ashley1"}, 2},
+		{"?sort=wins&limit=1&offset=1", []string{"ashley3"}, 3},
+	}
+	for _, test := range tests {
+		code, resp := list(test.query)
+		if code != http.StatusOK {
+			t.Errorf("Listing %q returned status code %v, expected %v", test.query, code, http.StatusOK)
+			continue
+		}
+		if resp.Total != test.total {
+			t.Errorf("Listing %q gave a total of %v, expected %v", test.query, resp.Total, test.total)
+		}
+		var players []string
+		for _, e := range resp.Players {
+			players = append(players, e.Player)
+		}
+		if len(players) != len(test.players) {
+			t.Errorf("Listing %q returned %v, expected %v", test.query, players, test.players)
+			continue
+		}
+		for i := range players {
+			if players[i] != test.players[i] {
+				t.Errorf("Listing %q returned %v, expected %v", test.query, players, test.players)
+				break
+			}
+		}
+	}
+
+	_, resp := list("?sort=wins")
+	want := LeaderboardEntry{Player: "ashley1", Rating: 1490, Games: 2, Wins: 1, AverageScore: 300, Bingos: 2}
+	if resp.Players[0] != want {
+		t.Errorf("Leaderboard entry is %+v, expected %+v", resp.Players[0], want)
+	}
+
+	for _, query := range []string{"?sort=luck", "?window=month", "?limit=0"} {
+		if code, _ := list(query); code != http.StatusBadRequest {
+			t.Errorf("Listing %q returned status code %v, expected %v", query, code, http.StatusBadRequest)
+		}
+	}
+}
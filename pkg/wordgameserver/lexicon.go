@@ -0,0 +1,74 @@
+package wordgameserver
+
+import (
+	"strconv"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/pkg/errors"
+)
+
+// LexiconCustom is the lexicon of games played with a word list given by their
+// creator
+const LexiconCustom = "custom"
+
+// maxCustomWords is the most words a game's own word list can have, enough for
+// the largest English lexicons
+const maxCustomWords = 300000
+
+// LexiconValidator is a WordValidator with other lexicons games can choose to
+// check words against by name, such as TWL or SOWPODS
+type LexiconValidator interface {
+	dictionary.WordValidator
+	Lexicon(name string) dictionary.WordValidator // lexicon with the name, nil if there isn't one
+	LexiconNames() []string                       // names of the lexicons games can choose
+}
+
+// lexicon returns the name of the lexicon words in the game are checked
+// against, or empty if it is the dictionary of the game's language
+func (o GameOptions) lexicon() string {
+	if len(o.Words) > 0 {
+		return LexiconCustom
+	}
+	return o.Lexicon
+}
+
+// validateLexicon checks the lexicon chosen for a game is usable
+func (o GameOptions) validateLexicon() error {
+	switch {
+	case o.Lexicon != "" && len(o.Words) > 0:
+		return errors.New("Only one of lexicon and words can be chosen")
+	case o.Lexicon == LexiconCustom:
+		return errors.New("Custom lexicons are chosen by giving their words")
+	case len(o.Words) > maxCustomWords:
+		return errors.New("Word list can have at most " + strconv.Itoa(maxCustomWords) + " words")
+	case o.lexicon() != "" && o.Bots > 0:
+		return errors.New("Bots can only play with the server's dictionary")
+	}
+	return nil
+}
+
+// gameValidator returns the dictionary words played in a game with the options
+// are checked against, given the server's validator. A lexicon the server
+// doesn't have accepts any word.
+func gameValidator(validator dictionary.WordValidator, o GameOptions) dictionary.WordValidator {
+	switch {
+	case len(o.Words) > 0:
+		return dictionary.WordListOf(o.Words)
+	case o.Lexicon != "":
+		if lv, ok := validator.(LexiconValidator); ok {
+			if v := lv.Lexicon(o.Lexicon); v != nil {
+				return v
+			}
+		}
+		return nil
+	}
+	return languageValidator(validator, o.Language)
+}
+
+// checkLexicon checks the server has the lexicon chosen for a game
+func (s *Server) checkLexicon(o GameOptions) error {
+	if o.Lexicon != "" && gameValidator(s.validator, o) == nil {
+		return errors.New("Unknown lexicon '" + o.Lexicon + "'")
+	}
+	return nil
+}
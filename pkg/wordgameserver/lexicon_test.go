@@ -0,0 +1,134 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+func TestLexiconGame(t *testing.T) {
+	twl, err := dictionary.NewWordList(strings.NewReader("CAT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sowpods, err := dictionary.NewWordList(strings.NewReader("CAT\nQI\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := dictionary.Languages{English: twl, Lexicons: map[string]dictionary.WordValidator{"SOWPODS": sowpods}}
+	srv, err := NewServer(DefaultConfig(), validator, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(opts GameOptions) (int, *ScrabbleGame) {
+		t.Helper()
+		payload, err := json.Marshal(GeneralGameRequest{Options: &opts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			return rr.Code, nil
+		}
+
+		var resp GeneralGameRequest
+		if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		g, err := srv.games.Get(resp.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(g.Stop)
+		return rr.Code, g
+	}
+
+	invalid := []GameOptions{
+		{Lexicon: "CSW"},
+		{Lexicon: LexiconCustom},
+		{Lexicon: "SOWPODS", Words: []string{"QI"}},
+		{Lexicon: "SOWPODS", Bots: 1},
+	}
+	for _, opts := range invalid {
+		if code, _ := create(opts); code != http.StatusBadRequest {
+			t.Errorf("Creating a game with options %+v returned status code %v, expected %v", opts, code, http.StatusBadRequest)
+		}
+	}
+
+	// play makes the first move of a game, playing QI across the center
+	play := func(g *ScrabbleGame) (GameStateResponse, error) {
+		t.Helper()
+		id, err := g.addPlayer("ashley1")
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err = g.addPlayer("ashley2"); err != nil {
+			t.Fatal(err)
+		}
+		g.Players[id].Tiles = Letters("QIAAAAA")
+		err = g.executePlay(GamePlayRequest{
+			PlayerID: id,
+			StartPos: SquareCoordinate{Row: 7, Col: 7},
+			EndPos:   SquareCoordinate{Row: 7, Col: 8},
+			Tiles:    Letters("QI"),
+		})
+		return g.getState(id, g.playerList()), err
+	}
+
+	_, standard := create(GameOptions{})
+	if standard == nil {
+		t.Fatal("Creating a game with the server's dictionary failed")
+	}
+	standard.Lock()
+	if _, err = play(standard); err == nil {
+		t.Error("QI should be rejected by the server's dictionary")
+	}
+	standard.Unlock()
+
+	_, named := create(GameOptions{Lexicon: "SOWPODS"})
+	if named == nil {
+		t.Fatal("Creating a game with a named lexicon failed")
+	}
+	named.Lock()
+	if s, err := play(named); err != nil {
+		t.Errorf("QI should be accepted by SOWPODS: %v", err)
+	} else if s.Lexicon != "SOWPODS" {
+		t.Errorf("Game state has lexicon %q, expected SOWPODS", s.Lexicon)
+	}
+	named.Unlock()
+
+	_, custom := create(GameOptions{Words: []string{"qi"}})
+	if custom == nil {
+		t.Fatal("Creating a game with its own words failed")
+	}
+	custom.Lock()
+	defer custom.Unlock()
+
+	// The words are checked against the same list once the game is loaded
+	data, err := EncodeGame(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, validator)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.Validator == nil || !d.Validator.Valid("QI") || d.Validator.Valid("CAT") {
+		t.Error("Decoded game should check words against its own list")
+	}
+
+	if s, err := play(custom); err != nil {
+		t.Errorf("QI should be accepted by the game's own words: %v", err)
+	} else if s.Lexicon != LexiconCustom {
+		t.Errorf("Game state has lexicon %q, expected %q", s.Lexicon, LexiconCustom)
+	}
+}
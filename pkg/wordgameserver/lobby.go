@@ -0,0 +1,208 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LobbyGame describes a game waiting for players in the public lobby
+type LobbyGame struct {
+	GameID    uuid.UUID   `json:"game_id"`
+	Creator   string      `json:"creator,omitempty"` // name of the game's owner, empty until someone has joined
+	Players   int         `json:"players"`           // number of seats taken, including bots
+	OpenSeats int         `json:"open_seats"`        // number of players who can still join
+	Options   GameOptions `json:"options"`
+	Protected bool        `json:"protected,omitempty"` // true if a passphrase is needed to join
+	Rating    int         `json:"rating,omitempty"`    // average rating of the players who have joined, not counting bots or guests
+	Created   time.Time   `json:"created"`
+}
+
+// LobbyResponse is the format of the response sent to clients when they list
+// the games in the lobby
+type LobbyResponse struct {
+	Games []LobbyGame `json:"games"`
+	Total int         `json:"total"` // number of games matching the filters, across every page
+}
+
+// Pagination of lists such as the lobby
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// lobbyFilter selects which games in the lobby a client is shown
+type lobbyFilter struct {
+	openSeats int    // fewest open seats a game can have
+	timed     *bool  // if set, whether games must have a turn timer or clock
+	variant   string // if set, the variant games must be played as
+}
+
+// matches reports whether the game should be listed
+func (f lobbyFilter) matches(g LobbyGame) bool {
+	if g.OpenSeats < f.openSeats {
+		return false
+	}
+	if f.timed != nil {
+		timed := g.Options.TurnTimer > 0 || g.Options.Clock > 0
+		if timed != *f.timed {
+			return false
+		}
+	}
+	if f.variant != "" {
+		variant := g.Options.Variant
+		if variant == "" {
+			variant = VariantStandard
+		}
+		if variant != f.variant {
+			return false
+		}
+	}
+	return true
+}
+
+// lobbyGame describes the game for the lobby, returning false if it can't be
+// joined or is private. The game must be locked by the caller.
+func (s *Server) lobbyGame(g *ScrabbleGame) (LobbyGame, bool) {
+	if g.Active || g.Finished || g.Options.Private {
+		return LobbyGame{}, false
+	}
+
+	entry := LobbyGame{
+		GameID:    g.ID,
+		Players:   len(g.Players),
+		OpenSeats: s.cfg.MaxPlayers - len(g.Players),
+		Options:   g.Options,
+		Protected: g.passphraseHash != nil,
+	}
+	if len(g.events) > 0 {
+		entry.Created = g.events[0].Time
+	}
+	if owner := g.owner(); owner != nil {
+		entry.Creator = owner.Name
+	}
+	rated, total := 0, 0
+	for _, p := range g.playerList() {
+		if p.Bot {
+			continue
+		}
+		if p.Rating > 0 {
+			rated++
+			total += p.Rating
+		}
+	}
+	if rated > 0 {
+		entry.Rating = total / rated
+	}
+	return entry, entry.OpenSeats > 0
+}
+
+// lobbyHandler handles requests to list the games waiting for players, newest
+// first. The open_seats query parameter sets how many seats must be free in
+// each game, timer limits the list to games with or without time limits and
+// variant to games played as that variant. Pages of the list are chosen with
+// the limit and offset parameters.
+func (s *Server) lobbyHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := lobbyFilter{openSeats: 1}
+
+	limit, offset, ok := pageParams(w, query)
+	if !ok {
+		return
+	}
+
+	var err error
+	if v := query.Get("open_seats"); v != "" {
+		if filter.openSeats, err = strconv.Atoi(v); err != nil || filter.openSeats < 1 {
+			http.Error(w, "Invalid open_seats parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("timer"); v != "" {
+		timed, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid timer parameter", http.StatusBadRequest)
+			return
+		}
+		filter.timed = &timed
+	}
+	if filter.variant = query.Get("variant"); filter.variant != "" {
+		if _, err = lookupVariant(filter.variant); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := s.games.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	games := []LobbyGame{}
+	for _, g := range list {
+		g.Lock()
+		entry, ok := s.lobbyGame(g)
+		g.Unlock()
+		if ok && filter.matches(entry) {
+			games = append(games, entry)
+		}
+	}
+
+	// Order newest first, breaking ties by ID so pages don't overlap
+	sort.Slice(games, func(i, j int) bool {
+		if !games[i].Created.Equal(games[j].Created) {
+			return games[i].Created.After(games[j].Created)
+		}
+		return games[i].GameID.String() < games[j].GameID.String()
+	})
+
+	start, end := page(len(games), limit, offset)
+	resp, err := json.Marshal(LobbyResponse{Games: games[start:end], Total: len(games)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// pageParams reads the limit and offset query parameters choosing a page of a
+// list, replying to the client with an error if they aren't valid
+func pageParams(w http.ResponseWriter, query url.Values) (limit int, offset int, ok bool) {
+	limit = defaultPageLimit
+
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxPageLimit {
+			http.Error(w, "Limit must be between 1 and "+strconv.Itoa(maxPageLimit), http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	return limit, offset, true
+}
+
+// page returns the bounds of the page of a list with the given length
+func page(length, limit, offset int) (start int, end int) {
+	start, end = offset, offset+limit
+	if start > length {
+		start = length
+	}
+	if end > length {
+		end = length
+	}
+	return start, end
+}
@@ -0,0 +1,103 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLobbyHandler(t *testing.T) {
+	srv := newTestServer(t)
+
+	// Each game is created a minute after the last, so they are listed in
+	// reverse order
+	created := time.Now().Add(-time.Hour)
+	newGame := func(players int, options GameOptions) *ScrabbleGame {
+		g := newScrabbleGame()
+		g.Options = options
+		g.record(Event{Type: GameCreated, Bag: standardVariant.newBag(g.random()), Time: created})
+		created = created.Add(time.Minute)
+		for i := 0; i < players; i++ {
+			g.addPlayer("ashley" + string(rune('1'+i)))
+		}
+		srv.games.Put(g)
+		return g
+	}
+
+	untimed := newGame(1, GameOptions{})
+	timed := newGame(2, GameOptions{TurnTimer: 60, Variant: VariantSuper})
+	newGame(srv.cfg.MaxPlayers, GameOptions{})
+	started := newGame(2, GameOptions{})
+	started.Lock()
+	started.start()
+	started.Unlock()
+	defer started.Stop()
+
+	list := func(query string) (int, LobbyResponse) {
+		t.Helper()
+		req, err := http.NewRequest("GET", "/games"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.lobbyHandler).ServeHTTP(rr, req)
+
+		var resp LobbyResponse
+		if rr.Code == http.StatusOK {
+			if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, resp
+	}
+
+	tests := []struct {
+		query string
+		games []uuid.UUID
+		total int
+	}{
+		{"", []uuid.UUID{timed.ID, untimed.ID}, 2},
+		{"?open_seats=3", []uuid.UUID{untimed.ID}, 1},
+		{"?timer=true", []uuid.UUID{timed.ID}, 1},
+		{"?timer=false", []uuid.UUID{untimed.ID}, 1},
+		{"?variant=super", []uuid.UUID{timed.ID}, 1},
+		{"?variant=standard", []uuid.UUID{untimed.ID}, 1},
+		{"?limit=1", []uuid.UUID{timed.ID}, 2},
+		{"?limit=1&offset=1", []uuid.UUID{untimed.ID}, 2},
+		{"?offset=5", nil, 2},
+	}
+	for _, test := range tests {
+		code, resp := list(test.query)
+		if code != http.StatusOK {
+			t.Errorf("Listing %q returned status code %v, expected %v", test.query, code, http.StatusOK)
+			continue
+		}
+		if resp.Total != test.total {
+			t.Errorf("Listing %q gave a total of %v, expected %v", test.query, resp.Total, test.total)
+		}
+		if len(resp.Games) != len(test.games) {
+			t.Errorf("Listing %q returned %v games, expected %v", test.query, len(resp.Games), len(test.games))
+			continue
+		}
+		for i, id := range test.games {
+			if resp.Games[i].GameID != id {
+				t.Errorf("Listing %q returned game %v at position %v, expected %v", test.query, resp.Games[i].GameID, i, id)
+			}
+		}
+	}
+
+	_, resp := list("?open_seats=3")
+	if g := resp.Games[0]; g.Creator != "ashley1" || g.Players != 1 || g.OpenSeats != 3 {
+		t.Errorf("Listed game %+v, expected one created by ashley1 with 3 open seats", g)
+	}
+
+	for _, query := range []string{"?open_seats=0", "?timer=maybe", "?variant=giant", "?limit=500", "?offset=-1"} {
+		if code, _ := list(query); code != http.StatusBadRequest {
+			t.Errorf("Listing %q returned status code %v, expected %v", query, code, http.StatusBadRequest)
+		}
+	}
+}
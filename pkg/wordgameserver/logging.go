@@ -0,0 +1,137 @@
+package wordgameserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// statusRecorder remembers the status code written to a response. It passes
+// flushes and hijacks through so event streams and WebSockets still work.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Response does not support hijacking")
+	}
+	if sr.status == 0 {
+		sr.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// logRequests returns middleware that logs every request once it has been
+// handled, with the route, the IDs of the game and player it was for, the
+// status of the response and how long it took. Server errors are logged at
+// error level and client errors at warning level.
+func logRequests(logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			gameID, playerID := requestIDs(r)
+
+			sr := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(sr, r)
+
+			route := r.URL.Path
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+			status := sr.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			} else if status >= 400 {
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if gameID != "" {
+				attrs = append(attrs, slog.String("game_id", gameID))
+			}
+			if playerID != "" {
+				attrs = append(attrs, slog.String("player_id", playerID))
+			}
+			logger.LogAttrs(r.Context(), level, "Handled request", attrs...)
+		})
+	}
+}
+
+// requestIDs finds the IDs of the game and player a request is for, wherever
+// the version of the API it uses puts them: the path, the query string or the
+// JSON body. The body is put back to be read by the handler.
+func requestIDs(r *http.Request) (gameID, playerID string) {
+	gameID = mux.Vars(r)["id"]
+	if gameID == "" {
+		gameID = r.URL.Query().Get("game_id")
+	}
+	playerID = r.URL.Query().Get("player_id")
+
+	if r.Body == nil || r.Body == http.NoBody || (gameID != "" && playerID != "") {
+		return gameID, playerID
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return gameID, playerID
+	}
+
+	var ids struct {
+		GameID   string `json:"game_id"`
+		PlayerID string `json:"player_id"`
+	}
+	if json.Unmarshal(body, &ids) == nil {
+		if gameID == "" {
+			gameID = ids.GameID
+		}
+		if playerID == "" {
+			playerID = ids.PlayerID
+		}
+	}
+	return gameID, playerID
+}
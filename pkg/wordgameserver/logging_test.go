@@ -0,0 +1,82 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLogRequests(t *testing.T) {
+	srv := newTestServer(t)
+	var logs bytes.Buffer
+	router := srv.newRouter()
+	router.Use(logRequests(slog.New(slog.NewJSONHandler(&logs, nil))))
+
+	gameID, playerID := uuid.New(), uuid.New()
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   map[string]interface{}
+	}{
+		{
+			method: "POST",
+			path:   "/v1/game/state",
+			body:   `{"game_id":"` + gameID.String() + `","player_id":"` + playerID.String() + `"}`,
+			want: map[string]interface{}{
+				"level":     "WARN",
+				"method":    "POST",
+				"route":     "/v1/game/state",
+				"status":    float64(http.StatusBadRequest),
+				"game_id":   gameID.String(),
+				"player_id": playerID.String(),
+			},
+		},
+		{
+			method: "GET",
+			path:   "/v2/games/" + gameID.String() + "?player_id=" + playerID.String(),
+			want: map[string]interface{}{
+				"route":     "/v2/games/{id}",
+				"game_id":   gameID.String(),
+				"player_id": playerID.String(),
+			},
+		},
+		{
+			method: "POST",
+			path:   "/v2/games",
+			want: map[string]interface{}{
+				"level":  "INFO",
+				"status": float64(http.StatusCreated),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		logs.Reset()
+
+		req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]interface{}
+		if err = json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("%v %v was not logged as JSON: %v", tt.method, tt.path, err)
+		}
+		for k, v := range tt.want {
+			if entry[k] != v {
+				t.Errorf("%v %v logged %v as %v, expected %v", tt.method, tt.path, k, entry[k], v)
+			}
+		}
+		if _, ok := entry["latency"]; !ok {
+			t.Errorf("%v %v logged without latency", tt.method, tt.path)
+		}
+	}
+}
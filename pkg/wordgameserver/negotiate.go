@@ -0,0 +1,352 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v4"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Media types state responses can be encoded as
+const (
+	mediaJSON     = "application/json"
+	mediaProtobuf = "application/x-protobuf" // see state.proto
+	mediaMsgpack  = "application/msgpack"    // same field names as JSON
+)
+
+// mediaAliases maps other names clients use for the supported media types
+var mediaAliases = map[string]string{
+	mediaJSON:                 mediaJSON,
+	mediaProtobuf:             mediaProtobuf,
+	"application/protobuf":    mediaProtobuf,
+	mediaMsgpack:              mediaMsgpack,
+	"application/x-msgpack":   mediaMsgpack,
+	"application/vnd.msgpack": mediaMsgpack,
+}
+
+// negotiateMediaType picks the supported media type the client most prefers
+// according to its Accept header, falling back to JSON
+func negotiateMediaType(accept string) string {
+	best, bestQ := mediaJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		m, ok := mediaAliases[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = m, q
+		}
+	}
+	return best
+}
+
+// writeState sends the state to the client, encoded in whichever supported
+// media type their Accept header prefers. States read from a game's view are
+// tagged with its version, and clients that already have it, according to
+// their If-None-Match header, are told so rather than sent it again.
+func writeState(w http.ResponseWriter, r *http.Request, state GameStateResponse) {
+	var (
+		resp []byte
+		err  error
+	)
+
+	mediaType := negotiateMediaType(r.Header.Get("Accept"))
+	w.Header().Add("Vary", "Accept")
+	if etag := stateETag(r, state); etag != "" {
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	switch mediaType {
+	case mediaProtobuf:
+		resp = appendStateProto(nil, state)
+	case mediaMsgpack:
+		var buf bytes.Buffer
+		err = msgpack.NewEncoder(&buf).UseJSONTag(true).Encode(state)
+		resp = buf.Bytes()
+	default:
+		mediaType = mediaJSON + "; charset=UTF-8"
+		resp, err = json.Marshal(state)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// stateETag returns the entity tag of the state as it is encoded for the
+// request, or an empty string if it wasn't read from a game's view. The tag
+// is weak, as running clocks change without a new version.
+func stateETag(r *http.Request, state GameStateResponse) string {
+	if state.version == "" {
+		return ""
+	}
+	mediaType := negotiateMediaType(r.Header.Get("Accept"))
+	return `W/"` + state.version + "-" + strings.TrimPrefix(mediaType, "application/") + `"`
+}
+
+// etagMatches returns whether the If-None-Match header lists the entity tag,
+// comparing them weakly as conditional GETs do
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// appendStateProto appends the state encoded as a GameState message, as
+// defined in state.proto
+func appendStateProto(b []byte, s GameStateResponse) []byte {
+	b = appendProtoString(b, 1, s.GameID.String())
+	b = appendProtoBool(b, 2, s.Active)
+	b = appendProtoBool(b, 3, s.Finished)
+	b = appendProtoPacked(b, 4, s.Winners)
+	for _, p := range s.Players {
+		b = appendProtoMessage(b, 5, appendPlayerProto(nil, p))
+	}
+	for _, row := range s.Board {
+		var r []byte
+		for _, sq := range row {
+			r = appendProtoMessage(r, 1, appendSquareProto(nil, sq))
+		}
+		b = appendProtoMessage(b, 6, r)
+	}
+	b = appendProtoInt(b, 7, s.PlayerTurn)
+	for _, t := range s.PlayerTiles {
+		b = appendProtoMessage(b, 14, appendTileProto(nil, t))
+	}
+	if c := s.Challenge; c != nil {
+		var m []byte
+		m = appendProtoInt(m, 1, c.Challenger)
+		m = appendProtoInt(m, 2, c.Challenged)
+		for _, word := range c.Words {
+			m = appendProtoString(m, 3, word)
+		}
+		for _, word := range c.InvalidWords {
+			m = appendProtoString(m, 4, word)
+		}
+		m = appendProtoBool(m, 5, c.Successful)
+		b = appendProtoMessage(b, 9, m)
+	}
+	if s.TurnEnds != nil {
+		// google.protobuf.Timestamp
+		var m []byte
+		m = appendProtoInt(m, 1, int(s.TurnEnds.Unix()))
+		m = appendProtoInt(m, 2, s.TurnEnds.Nanosecond())
+		b = appendProtoMessage(b, 10, m)
+	}
+	if s.TimedOut != nil {
+		// Optional fields are sent even if zero
+		b = protowire.AppendTag(b, 11, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*s.TimedOut))
+	}
+	if len(s.Clocks) > 0 {
+		var packed []byte
+		for _, c := range s.Clocks {
+			packed = protowire.AppendFixed64(packed, math.Float64bits(c))
+		}
+		b = protowire.AppendTag(b, 12, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed)
+	}
+	b = appendProtoString(b, 13, s.Variant)
+	b = appendProtoString(b, 15, s.Lexicon)
+	b = appendProtoBool(b, 16, s.UndoRequested)
+	for _, m := range s.Chat {
+		b = appendProtoMessage(b, 17, appendChatProto(nil, m))
+	}
+	for _, r := range s.Reactions {
+		b = appendProtoMessage(b, 18, appendReactionProto(nil, r))
+	}
+	if s.Owner != nil {
+		b = protowire.AppendTag(b, 19, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*s.Owner))
+	}
+	b = appendProtoBool(b, 20, s.Paused)
+	b = appendProtoPacked(b, 21, s.ResumeVotes)
+	b = appendProtoInt(b, 22, s.MoveCount)
+	if d := s.Diff; d != nil {
+		var m []byte
+		m = appendProtoInt(m, 1, d.Since)
+		for _, mv := range d.Moves {
+			m = appendProtoMessage(m, 2, appendMoveProto(nil, mv))
+		}
+		for _, c := range d.Squares {
+			var sq []byte
+			sq = appendProtoInt(sq, 1, c.Row)
+			sq = appendProtoInt(sq, 2, c.Col)
+			if c.Tile != nil {
+				sq = appendProtoMessage(sq, 3, appendTileProto(nil, *c.Tile))
+			}
+			m = appendProtoMessage(m, 3, sq)
+		}
+		b = appendProtoMessage(b, 23, m)
+	}
+	b = appendProtoInt(b, 24, s.BagCount)
+	if s.LastMove != nil {
+		b = appendProtoMessage(b, 25, appendMoveProto(nil, *s.LastMove))
+	}
+	b = appendProtoInt(b, 26, s.Passes)
+	if rm := s.Rematch; rm != nil {
+		var m []byte
+		m = appendProtoString(m, 1, rm.GameID.String())
+		m = appendProtoString(m, 2, rm.PlayerID.String())
+		b = appendProtoMessage(b, 27, m)
+	}
+	return b
+}
+
+func appendMoveProto(b []byte, m Move) []byte {
+	b = appendProtoInt(b, 1, m.Player)
+	b = appendProtoBool(b, 2, m.Swap)
+	b = appendProtoBool(b, 3, m.Pass)
+	b = appendProtoBool(b, 4, m.Resign)
+	b = appendProtoBool(b, 5, m.Kicked)
+	b = appendProtoBool(b, 6, m.TimedOut)
+	for _, word := range m.Words {
+		b = appendProtoString(b, 7, word)
+	}
+	for _, sc := range m.Squares {
+		var c []byte
+		c = appendProtoInt(c, 1, sc.Row)
+		c = appendProtoInt(c, 2, sc.Col)
+		b = appendProtoMessage(b, 8, c)
+	}
+	for _, t := range m.Tiles {
+		b = appendProtoMessage(b, 9, appendTileProto(nil, t))
+	}
+	b = appendProtoString(b, 10, m.Rack.String())
+	b = appendProtoString(b, 11, m.Swapped.String())
+	b = appendProtoInt(b, 12, m.Score)
+	b = appendProtoBool(b, 13, m.Retracted)
+	var t []byte
+	t = appendProtoInt(t, 1, int(m.Time.Unix()))
+	t = appendProtoInt(t, 2, m.Time.Nanosecond())
+	return appendProtoMessage(b, 14, t)
+}
+
+func appendPlayerProto(b []byte, p *Player) []byte {
+	b = appendProtoString(b, 1, p.Name)
+	b = appendProtoInt(b, 2, p.Number)
+	b = appendProtoInt(b, 3, p.Score)
+	b = appendProtoBool(b, 4, p.Resigned)
+	b = appendProtoBool(b, 5, p.Bot)
+	b = appendProtoString(b, 6, p.BotLevel)
+	b = appendProtoInt(b, 7, p.Rating)
+	b = appendProtoBool(b, 8, p.Kicked)
+	return b
+}
+
+func appendChatProto(b []byte, m ChatMessage) []byte {
+	if m.Player != nil {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*m.Player))
+	}
+	b = appendProtoString(b, 2, m.Name)
+	b = appendProtoString(b, 3, m.Text)
+	var t []byte
+	t = appendProtoInt(t, 1, int(m.Time.Unix()))
+	t = appendProtoInt(t, 2, m.Time.Nanosecond())
+	b = appendProtoMessage(b, 4, t)
+	return appendProtoString(b, 5, m.Channel)
+}
+
+func appendReactionProto(b []byte, r Reaction) []byte {
+	b = appendProtoInt(b, 1, r.Player)
+	b = appendProtoInt(b, 2, r.Move)
+	b = appendProtoString(b, 3, r.Emote)
+	var t []byte
+	t = appendProtoInt(t, 1, int(r.Time.Unix()))
+	t = appendProtoInt(t, 2, r.Time.Nanosecond())
+	return appendProtoMessage(b, 4, t)
+}
+
+func appendSquareProto(b []byte, sq Square) []byte {
+	b = appendProtoString(b, 1, sq.SquareType)
+	if sq.Letter != 0 {
+		b = appendProtoMessage(b, 2, appendTileProto(nil, sq.Tile))
+	}
+	return b
+}
+
+func appendTileProto(b []byte, t Tile) []byte {
+	b = appendProtoInt(b, 1, int(t.Letter))
+	b = appendProtoInt(b, 2, t.Value)
+	b = appendProtoBool(b, 3, t.Blank)
+	b = appendProtoString(b, 4, t.Letter.String())
+	return b
+}
+
+// The following append a field of a message, leaving it out if it has the
+// zero value as proto3 does
+
+func appendProtoInt(b []byte, num protowire.Number, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendProtoBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(v))
+}
+
+func appendProtoString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendProtoPacked(b []byte, num protowire.Number, v []int) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	var packed []byte
+	for _, n := range v {
+		packed = protowire.AppendVarint(packed, uint64(n))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, packed)
+}
+
+func appendProtoMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
@@ -0,0 +1,234 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v4"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mediaJSON},
+		{"*/*", mediaJSON},
+		{"application/x-protobuf", mediaProtobuf},
+		{"application/x-msgpack", mediaMsgpack},
+		{"application/json;q=0.5, application/msgpack", mediaMsgpack},
+		{"application/msgpack;q=0.2, application/protobuf;q=0.8", mediaProtobuf},
+		{"text/html, application/json;q=0.9", mediaJSON},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateMediaType(tt.accept); got != tt.want {
+			t.Errorf("Negotiated %v for Accept %q, expected %v", got, tt.accept, tt.want)
+		}
+	}
+}
+
+func TestStateEncodings(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	playerID, _ := newGame.addPlayer("ashley1")
+	newGame.addPlayer("ashley2")
+
+	newGame.Lock()
+	err := newGame.start()
+	newGame.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(GeneralGameRequest{GameID: newGame.ID, PlayerID: &playerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(accept string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest("POST", "/game/state", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.gameStateHandler).ServeHTTP(rr, req)
+
+		if c := rr.Code; c != http.StatusOK {
+			t.Fatalf("Returned status code %v, expected %v. Error: %v", c, http.StatusOK, rr.Body)
+		}
+		return rr
+	}
+
+	var state GameStateResponse
+	if err = json.NewDecoder(request(mediaJSON).Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := request(mediaMsgpack)
+	if ct := rr.Header().Get("Content-Type"); ct != mediaMsgpack {
+		t.Fatalf("Returned content type %v, expected %v", ct, mediaMsgpack)
+	}
+	var decoded GameStateResponse
+	if err = msgpack.NewDecoder(rr.Body).UseJSONTag(true).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded.GameID != state.GameID || !reflect.DeepEqual(decoded.PlayerTiles, state.PlayerTiles) || !reflect.DeepEqual(decoded.Board, state.Board) {
+		t.Errorf("Decoded msgpack state %+v does not match JSON state %+v", decoded, state)
+	}
+
+	rr = request(mediaProtobuf)
+	if ct := rr.Header().Get("Content-Type"); ct != mediaProtobuf {
+		t.Fatalf("Returned content type %v, expected %v", ct, mediaProtobuf)
+	}
+	if size := len(mustJSON(t, state)); rr.Body.Len() >= size {
+		t.Errorf("Protobuf state is %v bytes, expected it to be smaller than the %v bytes of JSON", rr.Body.Len(), size)
+	}
+
+	// Pick out the fields of the GameState message that identify the game
+	var gameID string
+	var tiles string
+	var rows int
+	for b := rr.Body.Bytes(); len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		if typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			switch num {
+			case 1:
+				gameID = string(v)
+			case 6:
+				rows++
+			case 14:
+				tiles += protoTileText(t, v)
+			}
+			b = b[n:]
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			b = b[n:]
+		}
+	}
+	if gameID != state.GameID.String() || tiles != state.RackLetters().String() || rows != standardVariant.size {
+		t.Errorf("Decoded protobuf game %v with tiles %q and %v rows, expected %v, %q and %v",
+			gameID, tiles, rows, state.GameID, state.RackLetters(), standardVariant.size)
+	}
+}
+
+// protoTileText picks out the text of a Tile message
+func protoTileText(t *testing.T, b []byte) string {
+	t.Helper()
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		if num == 4 && typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b)
+			return string(v)
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return ""
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestStateETag(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	srv := newTestServer(t)
+	srv.adoptGame(g)
+	srv.games.Put(g)
+
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	// Only states read from the game's view are tagged, and it has one once
+	// its controller has answered a request
+	if _, err := g.request(GamePlayRequest{PlayerID: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(GeneralGameRequest{GameID: g.ID, PlayerID: &ids[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest("POST", "/game/state", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.gameStateHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := request(mediaJSON, "")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", rr.Code, http.StatusOK)
+	} else if etag == "" {
+		t.Fatal("State was sent without an ETag")
+	}
+
+	// Clients that already have the state aren't sent it again
+	if rr = request(mediaJSON, `"other", `+etag); rr.Code != http.StatusNotModified {
+		t.Errorf("Returned status code %v for an unchanged state, expected %v", rr.Code, http.StatusNotModified)
+	} else if rr.Body.Len() != 0 {
+		t.Errorf("Sent %v bytes with an unchanged state, expected none", rr.Body.Len())
+	}
+
+	// The tag differs between encodings of the same state
+	if rr = request(mediaProtobuf, etag); rr.Code != http.StatusOK {
+		t.Errorf("Returned status code %v for another encoding, expected %v", rr.Code, http.StatusOK)
+	} else if rr.Header().Get("ETag") == etag {
+		t.Error("Protobuf state has the same ETag as JSON")
+	}
+
+	if _, err = g.request(GamePlayRequest{PlayerID: ids[0], Type: passRequest}); err != nil {
+		t.Fatal(err)
+	}
+	if rr = request(mediaJSON, etag); rr.Code != http.StatusOK {
+		t.Errorf("Returned status code %v after a move, expected %v", rr.Code, http.StatusOK)
+	} else if rr.Header().Get("ETag") == etag {
+		t.Error("ETag is unchanged after a move")
+	}
+}
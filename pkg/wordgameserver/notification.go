@@ -0,0 +1,449 @@
+package wordgameserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Services push notifications can be delivered through. Each needs a
+// PushProvider added to the server before devices can be registered with it.
+const (
+	PushFCM     = "fcm"     // Firebase Cloud Messaging, for Android and web apps using Firebase
+	PushAPNs    = "apns"    // Apple Push Notification service, for iOS and macOS
+	PushWebPush = "webpush" // the Web Push protocol, for browsers
+)
+
+// Kinds of notification, which players can turn off in their preferences
+const (
+	NotifyYourTurn     = "your_turn"     // play has passed to the player
+	NotifyGameFinished = "game_finished" // a game the player was in has ended
+	NotifyReminder     = "reminder"      // the player's turn has waited long enough to remind them by email
+)
+
+// ErrDeviceNotFound is returned by a NotificationStore for a device that isn't
+// registered to the account
+var ErrDeviceNotFound = errors.New("Device does not exist")
+
+// ErrDeviceUnregistered is returned by a PushProvider when the service no
+// longer accepts the device's token, such as once the app has been removed.
+// The device is then removed from its account.
+var ErrDeviceUnregistered = errors.New("Device is no longer registered")
+
+// maxDevices is the most devices an account can have registered. Registering
+// another removes the one registered longest ago.
+const maxDevices = 10
+
+// pushTimeout is how long a provider has to deliver each notification
+const pushTimeout = 10 * time.Second
+
+// Notification is a message pushed to a player's devices
+type Notification struct {
+	Kind   string    `json:"kind"` // NotifyYourTurn or NotifyGameFinished
+	GameID uuid.UUID `json:"game_id"`
+	Title  string    `json:"title"`
+	Body   string    `json:"body"`
+}
+
+// PushProvider delivers notifications through one push service.
+// Implementations must be safe for concurrent use.
+type PushProvider interface {
+	// Push delivers the notification to the device with the token, returning
+	// ErrDeviceUnregistered if the service no longer knows the device
+	Push(ctx context.Context, token string, n Notification) error
+}
+
+// Device is a phone, tablet or browser registered to an account to receive
+// push notifications
+type Device struct {
+	ID         uuid.UUID `json:"id"`
+	Provider   string    `json:"provider"` // PushFCM, PushAPNs or PushWebPush
+	Token      string    `json:"token"`    // registration token, device token or JSON push subscription, depending on the provider
+	Registered time.Time `json:"registered"`
+}
+
+// DeviceRequest is the format of the request a client sends to register a
+// device for push notifications
+type DeviceRequest struct {
+	Provider string `json:"provider"`
+	Token    string `json:"token"`
+}
+
+// DevicesResponse is the format of the response listing the devices
+// registered to an account
+type DevicesResponse struct {
+	Devices []Device `json:"devices"`
+}
+
+// NotificationPreferences are the kinds of notification an account's devices
+// are sent, and whether it is sent reminders by email
+type NotificationPreferences struct {
+	YourTurn     bool `json:"your_turn"`
+	GameFinished bool `json:"game_finished"`
+	Reminders    bool `json:"reminders"`
+}
+
+// DefaultNotificationPreferences are the preferences of accounts that haven't
+// set any
+var DefaultNotificationPreferences = NotificationPreferences{YourTurn: true, GameFinished: true, Reminders: true}
+
+// wants returns whether notifications of the kind are sent
+func (p NotificationPreferences) wants(kind string) bool {
+	switch kind {
+	case NotifyYourTurn:
+		return p.YourTurn
+	case NotifyGameFinished:
+		return p.GameFinished
+	case NotifyReminder:
+		return p.Reminders
+	}
+	return false
+}
+
+// NotificationStore holds the devices registered to accounts and their
+// notification preferences. Implementations must be safe for concurrent use. A
+// GameStore that also implements NotificationStore is used for them by the
+// server it is given to.
+type NotificationStore interface {
+	PutDevice(account uuid.UUID, d Device) (Device, error)             // register a device, replacing the one with the same provider and token but keeping its ID
+	DeleteDevice(account, id uuid.UUID) error                          // remove a device, or ErrDeviceNotFound
+	ListDevices(account uuid.UUID) ([]Device, error)                   // every device registered to the account, oldest first
+	GetPreferences(account uuid.UUID) (NotificationPreferences, error) // the account's preferences, or DefaultNotificationPreferences if it has none
+	SetPreferences(account uuid.UUID, p NotificationPreferences) error // replace the account's preferences
+}
+
+// MemoryNotificationStore is the default NotificationStore, which keeps
+// devices and preferences in memory for the lifetime of the process. Apps
+// register their device each time they start, so they are registered again
+// after a restart.
+type MemoryNotificationStore struct {
+	sync.Mutex
+	devices     map[uuid.UUID][]Device
+	preferences map[uuid.UUID]NotificationPreferences
+}
+
+// NewMemoryNotificationStore creates an empty in-memory notification store
+func NewMemoryNotificationStore() *MemoryNotificationStore {
+	return &MemoryNotificationStore{
+		devices:     make(map[uuid.UUID][]Device),
+		preferences: make(map[uuid.UUID]NotificationPreferences),
+	}
+}
+
+// PutDevice registers the device to the account
+func (ms *MemoryNotificationStore) PutDevice(account uuid.UUID, d Device) (Device, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	devices := ms.devices[account]
+	for i, other := range devices {
+		if other.Provider == d.Provider && other.Token == d.Token {
+			d.ID = other.ID
+			devices = append(devices[:i:i], devices[i+1:]...)
+			break
+		}
+	}
+	ms.devices[account] = append(devices, d)
+	return d, nil
+}
+
+// DeleteDevice removes the device from the account
+func (ms *MemoryNotificationStore) DeleteDevice(account, id uuid.UUID) error {
+	ms.Lock()
+	defer ms.Unlock()
+	devices := ms.devices[account]
+	for i, d := range devices {
+		if d.ID == id {
+			ms.devices[account] = append(devices[:i:i], devices[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDeviceNotFound
+}
+
+// ListDevices returns the devices registered to the account
+func (ms *MemoryNotificationStore) ListDevices(account uuid.UUID) ([]Device, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	return append([]Device(nil), ms.devices[account]...), nil
+}
+
+// GetPreferences returns the account's notification preferences
+func (ms *MemoryNotificationStore) GetPreferences(account uuid.UUID) (NotificationPreferences, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	p, ok := ms.preferences[account]
+	if !ok {
+		return DefaultNotificationPreferences, nil
+	}
+	return p, nil
+}
+
+// SetPreferences replaces the account's notification preferences
+func (ms *MemoryNotificationStore) SetPreferences(account uuid.UUID, p NotificationPreferences) error {
+	ms.Lock()
+	defer ms.Unlock()
+	ms.preferences[account] = p
+	return nil
+}
+
+// AddPushProvider has notifications for devices registered with the service
+// delivered by the provider, which lets devices be registered with it. It must
+// be called before the server handles any requests.
+func (s *Server) AddPushProvider(service string, p PushProvider) {
+	if s.pushProviders == nil {
+		s.pushProviders = make(map[string]PushProvider)
+	}
+	s.pushProviders[service] = p
+}
+
+// push sends the notification to every device registered to the account, if
+// its preferences allow, in the background. Devices whose service no longer
+// knows them are removed.
+func (s *Server) push(account uuid.UUID, n Notification) {
+	if len(s.pushProviders) == 0 {
+		return
+	}
+
+	go func() {
+		prefs, err := s.notifications.GetPreferences(account)
+		if err != nil {
+			log.Printf("Failed to get notification preferences of account %v: %v", account, err)
+			return
+		} else if !prefs.wants(n.Kind) {
+			return
+		}
+		devices, err := s.notifications.ListDevices(account)
+		if err != nil {
+			log.Printf("Failed to list devices of account %v: %v", account, err)
+			return
+		}
+
+		for _, d := range devices {
+			p, ok := s.pushProviders[d.Provider]
+			if !ok {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+			err := p.Push(ctx, d.Token, n)
+			cancel()
+			if errors.Is(err, ErrDeviceUnregistered) {
+				if err = s.notifications.DeleteDevice(account, d.ID); err != nil && err != ErrDeviceNotFound {
+					log.Printf("Failed to remove unregistered device %v: %v", d.ID, err)
+				}
+			} else if err != nil {
+				log.Printf("Failed to push notification to device %v: %v", d.ID, err)
+			}
+		}
+	}()
+}
+
+// notifyPlayers pushes notifications to players linked to accounts for
+// whatever changed when the event was applied, given whose turn it was and
+// whether the game had finished beforehand. Players watching the game over a
+// WebSocket or event stream see the change there, so they aren't notified. The
+// game must be locked by the caller.
+func (sg *ScrabbleGame) notifyPlayers(e Event, turn int, finished bool) {
+	if sg.push == nil || len(sg.Players) == 0 {
+		return
+	}
+
+	playerList := sg.playerList()
+	current := playerList[sg.TurnCount%len(playerList)]
+	if sg.Active && !sg.Finished && (e.Type == GameStarted || current.Number != turn) {
+		sg.pushTo(current, NotifyYourTurn, "Your turn", "It's your turn to play against ")
+	}
+	if sg.Finished && !finished {
+		for _, p := range playerList {
+			sg.pushTo(p, NotifyGameFinished, "Game over", "Your game has finished against ")
+		}
+	}
+}
+
+// pushTo sends a notification about the game to the player's account, unless
+// they aren't linked to one or are watching the game. The body is completed
+// with the names of the player's opponents.
+func (sg *ScrabbleGame) pushTo(p *Player, kind, title, body string) {
+	if p.Account == nil || p.Bot || sg.watching(p.ID) {
+		return
+	}
+
+	var opponents []string
+	for _, other := range sg.playerList() {
+		if other != p {
+			opponents = append(opponents, other.Name)
+		}
+	}
+	sg.push(*p.Account, Notification{
+		Kind:   kind,
+		GameID: sg.ID,
+		Title:  title,
+		Body:   body + strings.Join(opponents, ", "),
+	})
+}
+
+// watching returns whether the player is subscribed to the game's updates
+func (sg *ScrabbleGame) watching(playerID uuid.UUID) bool {
+	sg.watchMu.Lock()
+	defer sg.watchMu.Unlock()
+	for _, id := range sg.watchers {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// registerDeviceHandler handles requests to register a device to the account
+// the request is authorized as, so it is sent push notifications. Registering
+// a device again keeps its ID.
+func (s *Server) registerDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req DeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.pushProviders[req.Provider]; !ok {
+		http.Error(w, "Push notifications aren't available through '"+req.Provider+"'", http.StatusBadRequest)
+		return
+	} else if req.Token == "" {
+		http.Error(w, "Missing device token", http.StatusBadRequest)
+		return
+	}
+
+	devices, err := s.notifications.ListDevices(a.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	known := false
+	for _, d := range devices {
+		known = known || (d.Provider == req.Provider && d.Token == req.Token)
+	}
+	if !known && len(devices) >= maxDevices {
+		sort.Slice(devices, func(i, j int) bool { return devices[i].Registered.Before(devices[j].Registered) })
+		for _, d := range devices[:len(devices)-maxDevices+1] {
+			if err = s.notifications.DeleteDevice(a.ID, d.ID); err != nil && err != ErrDeviceNotFound {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	d, err := s.notifications.PutDevice(a.ID, Device{
+		ID:         uuid.New(),
+		Provider:   req.Provider,
+		Token:      req.Token,
+		Registered: time.Now(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp, err := json.Marshal(d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+// listDevicesHandler handles requests for the devices registered to the
+// account the request is authorized as
+func (s *Server) listDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	devices, err := s.notifications.ListDevices(a.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp, err := json.Marshal(DevicesResponse{Devices: append([]Device{}, devices...)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// deleteDeviceHandler handles requests to stop sending push notifications to
+// one of the devices registered to the account the request is authorized as
+func (s *Server) deleteDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["device"])
+	if err != nil {
+		http.Error(w, "Invalid device ID", http.StatusBadRequest)
+		return
+	}
+	if err = s.notifications.DeleteDevice(a.ID, id); err == ErrDeviceNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// notificationPreferencesHandler handles requests for the notification
+// preferences of the account the request is authorized as, and to replace
+// them
+func (s *Server) notificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var prefs NotificationPreferences
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.notifications.SetPreferences(a.ID, prefs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	prefs, err := s.notifications.GetPreferences(a.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp, err := json.Marshal(prefs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -0,0 +1,199 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakePushProvider records the notifications pushed through it
+type fakePushProvider struct {
+	pushed chan Notification
+	err    error // returned for every notification
+}
+
+func (fp *fakePushProvider) Push(ctx context.Context, token string, n Notification) error {
+	err := fp.err
+	fp.pushed <- n
+	return err
+}
+
+func TestDeviceHandlers(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddPushProvider(PushFCM, &fakePushProvider{})
+
+	a, err := newAccount("ashley", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.accounts.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+	token := srv.issueToken(a.ID, time.Now())
+
+	send := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			if payload, err = json.Marshal(body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req, err := http.NewRequest(method, "/v2"+path, bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	failures := []struct {
+		name   string
+		token  string
+		req    DeviceRequest
+		status int
+	}{
+		{"no account", "", DeviceRequest{Provider: PushFCM, Token: "phone"}, http.StatusUnauthorized},
+		{"unavailable provider", token, DeviceRequest{Provider: PushAPNs, Token: "phone"}, http.StatusBadRequest},
+		{"missing token", token, DeviceRequest{Provider: PushFCM}, http.StatusBadRequest},
+	}
+	for _, f := range failures {
+		if rr := send("POST", "/accounts/me/devices", f.token, f.req); rr.Code != f.status {
+			t.Errorf("%v: returned status code %v, expected %v", f.name, rr.Code, f.status)
+		}
+	}
+
+	// Registering a device again keeps its ID
+	var devices []Device
+	for _, device := range []string{"phone", "tablet", "phone"} {
+		rr := send("POST", "/accounts/me/devices", token, DeviceRequest{Provider: PushFCM, Token: device})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+		}
+		var d Device
+		if err = json.NewDecoder(rr.Body).Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		devices = append(devices, d)
+	}
+	if devices[2].ID != devices[0].ID {
+		t.Errorf("Registering a device again gave it ID %v, expected %v", devices[2].ID, devices[0].ID)
+	}
+
+	var list DevicesResponse
+	if err = json.NewDecoder(send("GET", "/accounts/me/devices", token, nil).Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	} else if len(list.Devices) != 2 {
+		t.Errorf("Listed %+v, expected 2 devices", list.Devices)
+	}
+
+	if rr := send("DELETE", "/accounts/me/devices/"+devices[1].ID.String(), token, nil); rr.Code != http.StatusOK {
+		t.Errorf("Removing a device returned status code %v, expected %v", rr.Code, http.StatusOK)
+	}
+	if rr := send("DELETE", "/accounts/me/devices/"+devices[1].ID.String(), token, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Removing a device twice returned status code %v, expected %v", rr.Code, http.StatusNotFound)
+	}
+
+	var prefs NotificationPreferences
+	if err = json.NewDecoder(send("GET", "/accounts/me/notifications", token, nil).Body).Decode(&prefs); err != nil {
+		t.Fatal(err)
+	} else if prefs != DefaultNotificationPreferences {
+		t.Errorf("New account has preferences %+v, expected %+v", prefs, DefaultNotificationPreferences)
+	}
+	rr := send("PUT", "/accounts/me/notifications", token, NotificationPreferences{YourTurn: true})
+	if err = json.NewDecoder(rr.Body).Decode(&prefs); err != nil {
+		t.Fatal(err)
+	} else if !prefs.YourTurn || prefs.GameFinished {
+		t.Errorf("Set preferences %+v, expected only turn notifications", prefs)
+	}
+}
+
+func TestPushNotifications(t *testing.T) {
+	srv := newTestServer(t)
+	provider := &fakePushProvider{pushed: make(chan Notification, 10)}
+	srv.AddPushProvider(PushFCM, provider)
+
+	account := uuid.New()
+	if _, err := srv.notifications.PutDevice(account, Device{ID: uuid.New(), Provider: PushFCM, Token: "phone"}); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() Notification {
+		t.Helper()
+		select {
+		case n := <-provider.pushed:
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("No notification pushed")
+		}
+		return Notification{}
+	}
+
+	// The second player is linked to the account, so is notified when it's
+	// their turn and when the game finishes
+	newGame := func() (*ScrabbleGame, []uuid.UUID) {
+		g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+		g.Players[ids[1]].Account = &account
+		g.Lock()
+		defer g.Unlock()
+		srv.adoptGame(g)
+		if err := g.start(); err != nil {
+			t.Fatal(err)
+		}
+		return g, ids
+	}
+	pass := func(g *ScrabbleGame, id uuid.UUID) {
+		t.Helper()
+		g.Lock()
+		defer g.Unlock()
+		if err := g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, ids := newGame()
+	defer g.Stop()
+	pass(g, ids[0])
+	if n := next(); n.Kind != NotifyYourTurn || n.GameID != g.ID || n.Body != "It's your turn to play against ashley1" {
+		t.Errorf("Pushed %+v, expected the second player's turn", n)
+	}
+	pass(g, ids[1])
+	if n := next(); n.Kind != NotifyGameFinished {
+		t.Errorf("Pushed %+v, expected the game to have finished", n)
+	}
+
+	// Players watching the game aren't notified
+	g, ids = newGame()
+	defer g.Stop()
+	updates := g.subscribe(ids[1])
+	pass(g, ids[0])
+	g.unsubscribe(updates)
+
+	// Devices the service no longer knows are removed
+	provider.err = ErrDeviceUnregistered
+	pass(g, ids[1])
+	if n := next(); n.Kind != NotifyGameFinished {
+		t.Errorf("Pushed %+v, expected only the game to have finished", n)
+	}
+	for i := 0; ; i++ {
+		devices, err := srv.notifications.ListDevices(account)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(devices) == 0 {
+			break
+		} else if i == 50 {
+			t.Fatal("Unregistered device was not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,548 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// FieldError describes a problem with one field of a request body
+type FieldError struct {
+	Field   string `json:"field"` // path to the field, such as options.turn_timer, or empty for the whole body
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is the format of the response sent to clients when
+// their request body doesn't match the OpenAPI document
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// apiSchema is an OpenAPI schema object, covering as much of it as the
+// server's types need
+type apiSchema struct {
+	Ref        string                `json:"$ref,omitempty"`
+	AllOf      []*apiSchema          `json:"allOf,omitempty"`
+	Type       string                `json:"type,omitempty"`
+	Format     string                `json:"format,omitempty"`
+	Properties map[string]*apiSchema `json:"properties,omitempty"`
+	Required   []string              `json:"required,omitempty"`
+	Items      *apiSchema            `json:"items,omitempty"`
+	MinItems   *int                  `json:"minItems,omitempty"`
+	MaxItems   *int                  `json:"maxItems,omitempty"`
+}
+
+// apiParameter is a query parameter taken by an operation
+type apiParameter struct {
+	Name     string    `json:"name"`
+	In       string    `json:"in"`
+	Required bool      `json:"required,omitempty"`
+	Schema   apiSchema `json:"schema"`
+}
+
+// apiOperation describes an endpoint of the server for the OpenAPI document
+type apiOperation struct {
+	Methods     []string
+	Path        string
+	Summary     string
+	Params      []apiParameter
+	Request     interface{} // value of the type of the JSON body, or nil if there isn't one
+	Required    []string    // fields the body must include
+	Status      int
+	Response    interface{} // value of the type of the JSON response, or nil if there isn't one
+	ContentType string      // type of the response if it isn't JSON
+	Negotiated  bool        // true if the response can also be encoded as protobuf or msgpack
+}
+
+var (
+	gameIDParam    = apiParameter{Name: "game_id", In: "query", Required: true, Schema: apiSchema{Type: "string", Format: "uuid"}}
+	playerIDParam  = apiParameter{Name: "player_id", In: "query", Required: true, Schema: apiSchema{Type: "string", Format: "uuid"}}
+	limitParam     = apiParameter{Name: "limit", In: "query", Schema: apiSchema{Type: "integer"}}
+	offsetParam    = apiParameter{Name: "offset", In: "query", Schema: apiSchema{Type: "integer"}}
+	chatAfterParam = apiParameter{Name: "after", In: "query", Schema: apiSchema{Type: "integer"}}         // number of messages already seen
+	channelParam   = apiParameter{Name: "channel", In: "query", Schema: apiSchema{Type: "string"}}        // "spectators" for the spectators' channel
+	sinceParam     = apiParameter{Name: "since", In: "query", Schema: apiSchema{Type: "integer"}}         // number of moves already seen, to be sent diffs instead of boards
+	authParam      = apiParameter{Name: "Authorization", In: "header", Schema: apiSchema{Type: "string"}} // "Bearer " followed by an account's token
+)
+
+// apiOperations lists the endpoints of each version of the API, which are
+// described by its OpenAPI document
+var apiOperations = map[string][]apiOperation{
+	"v1": v1Operations,
+	"v2": v2Operations,
+}
+
+// v1Operations lists the endpoints of version 1 of the API
+var v1Operations = []apiOperation{
+	{Methods: []string{http.MethodPost}, Path: "/game/create", Summary: "Create a game",
+		Request: GeneralGameRequest{}, Status: http.StatusCreated, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/import", Summary: "Create a game from a GCG file or position",
+		Request: GameImportRequest{}, Status: http.StatusCreated, Response: GameImportResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/join", Summary: "Join a game as a player or add a bot",
+		Request: GeneralGameRequest{}, Status: http.StatusOK, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/start", Summary: "Start a game",
+		Request: GeneralGameRequest{}, Required: []string{"game_id"}, Status: http.StatusOK},
+	{Methods: []string{http.MethodPost}, Path: "/game/state", Summary: "Get a player's view of a game",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/state/wait", Summary: "Wait for a player's view of a game to change from the version given in If-None-Match",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/play", Summary: "Play or swap tiles",
+		Request: GamePlayRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/challenge", Summary: "Challenge the last play",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/undo", Summary: "Ask to undo the last move of a friendly game, or agree to",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/kick", Summary: "Remove an unresponsive player from a game, or vote to",
+		Request: KickRequest{}, Required: []string{"game_id", "player_id", "player"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/pause", Summary: "Pause a game that allows it, freezing its timers",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/unpause", Summary: "Ask to resume a paused game, which it does once every player has",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/options", Summary: "Change the options of a game before it starts, as its owner",
+		Request: OptionsRequest{}, Required: []string{"game_id", "player_id", "options"}, Status: http.StatusOK, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/owner", Summary: "Hand a game over to another player, as its owner",
+		Request: OwnerRequest{}, Required: []string{"game_id", "player_id", "player"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodGet}, Path: "/game/chat", Summary: "List the messages posted to a game's chat",
+		Params: []apiParameter{gameIDParam, chatAfterParam, channelParam}, Status: http.StatusOK, Response: ChatResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/chat", Summary: "Post a message to a game's chat, as a player or a named spectator",
+		Request: ChatRequest{}, Required: []string{"game_id", "text"}, Status: http.StatusCreated, Response: ChatMessage{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/react", Summary: "React to the most recent move with an emote",
+		Request: ReactionRequest{}, Required: []string{"game_id", "player_id", "emote"}, Status: http.StatusCreated, Response: Reaction{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/invite", Summary: "Invite people to join a game by email, as its owner",
+		Request: InvitationRequest{}, Required: []string{"game_id", "player_id", "emails"}, Status: http.StatusAccepted, Response: InvitationResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/pass", Summary: "Pass the turn",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/game/resign", Summary: "Resign from a game",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodDelete, http.MethodPost}, Path: "/game/cancel", Summary: "Cancel a game, as its owner",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK},
+	{Methods: []string{http.MethodPost}, Path: "/game/resume", Summary: "Resume a session, disconnecting any others",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GameStateResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/rematch", Summary: "Start a new game with the players of a finished one, in the reverse order",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusCreated, Response: RematchResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/fork", Summary: "Play on from a point in a finished game, optionally against bots",
+		Request: GameForkRequest{}, Required: []string{"game_id", "move"}, Status: http.StatusCreated, Response: GameImportResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/hint", Summary: "Suggest a move the player could make, as good as the bot level given",
+		Request: GeneralGameRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: GamePlayRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/game/validate", Summary: "Check a play or swap without making it",
+		Request: GamePlayRequest{}, Required: []string{"game_id", "player_id"}, Status: http.StatusOK, Response: MoveValidationResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/game/history", Summary: "List the moves made in a game",
+		Params: []apiParameter{gameIDParam}, Status: http.StatusOK, Response: GameHistoryResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/game/replay", Summary: "Export a finished game to be replayed",
+		Params: []apiParameter{gameIDParam, {Name: "move", In: "query", Schema: apiSchema{Type: "integer"}}},
+		Status: http.StatusOK, Response: GameReplay{}},
+	{Methods: []string{http.MethodGet}, Path: "/game/gcg", Summary: "Export a finished game in GCG format",
+		Params: []apiParameter{gameIDParam}, Status: http.StatusOK, ContentType: "text/plain"},
+	{Methods: []string{http.MethodGet}, Path: "/game/analysis", Summary: "Compare each move of a finished game with the best play available",
+		Params: []apiParameter{gameIDParam}, Status: http.StatusOK, Response: GameAnalysis{}},
+	{Methods: []string{http.MethodGet}, Path: "/game/unseen", Summary: "List the tiles a player can't see, in the bag and the other players' racks",
+		Params: []apiParameter{gameIDParam, playerIDParam}, Status: http.StatusOK, Response: UnseenTilesResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/game/{id}/board.png", Summary: "Draw a game's board and scores as a PNG",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "image/png"},
+	{Methods: []string{http.MethodGet}, Path: "/game/{id}/board.svg", Summary: "Draw a game's board and scores as an SVG",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "image/svg+xml"},
+	{Methods: []string{http.MethodGet}, Path: "/game/{id}/board.txt", Summary: "Write a game's board and scores as plain text",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "text/plain"},
+	{Methods: []string{http.MethodGet}, Path: "/game/ws", Summary: "Receive state updates over a WebSocket",
+		Params: []apiParameter{gameIDParam, playerIDParam, sinceParam}, Status: http.StatusSwitchingProtocols},
+	{Methods: []string{http.MethodGet}, Path: "/game/events", Summary: "Receive state updates and moves as Server-Sent Events",
+		Params: []apiParameter{gameIDParam, playerIDParam, sinceParam}, Status: http.StatusOK, ContentType: "text/event-stream"},
+	{Methods: []string{http.MethodPost}, Path: "/graphql", Summary: "Query games with GraphQL",
+		Request: GraphQLRequest{}, Required: []string{"query"}, Status: http.StatusOK},
+}
+
+// apiSpec is the OpenAPI document describing a version of the API, along with
+// the schemas needed to validate requests against it
+type apiSpec struct {
+	document   []byte                // the document, encoded as JSON
+	components map[string]*apiSchema // schemas the document refers to
+	bodies     map[string]*apiSchema // schemas of request bodies, keyed by method and path
+}
+
+// apiSpecs holds the spec of each version of the API
+var apiSpecs = buildAPISpecs()
+
+func buildAPISpecs() map[string]*apiSpec {
+	specs := map[string]*apiSpec{}
+	for version, ops := range apiOperations {
+		specs[version] = buildAPISpec(version, ops)
+	}
+	return specs
+}
+
+// buildAPISpec generates the OpenAPI document for a version of the API from
+// its operations
+func buildAPISpec(version string, ops []apiOperation) *apiSpec {
+	g := schemaGenerator{components: map[string]*apiSchema{}}
+	bodies := map[string]*apiSchema{}
+
+	paths := map[string]map[string]interface{}{}
+	for _, op := range ops {
+		o := map[string]interface{}{
+			"summary": op.Summary,
+		}
+		if len(op.Params) > 0 {
+			o["parameters"] = op.Params
+		}
+		if op.Request != nil {
+			body := g.body(op)
+			for _, m := range op.Methods {
+				bodies[m+" "+op.Path] = body
+			}
+			o["requestBody"] = map[string]interface{}{
+				"required": len(op.Required) > 0,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": body},
+				},
+			}
+		}
+
+		resp := map[string]interface{}{"description": http.StatusText(op.Status)}
+		if op.Response != nil {
+			content := map[string]interface{}{
+				mediaJSON: map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.Response))},
+			}
+			if op.Negotiated {
+				content[mediaMsgpack] = content[mediaJSON]
+				content[mediaProtobuf] = map[string]interface{}{
+					"schema": apiSchema{Type: "string", Format: "binary"},
+				}
+			}
+			resp["content"] = content
+		} else if op.ContentType != "" {
+			resp["content"] = map[string]interface{}{
+				op.ContentType: map[string]interface{}{"schema": apiSchema{Type: "string"}},
+			}
+		}
+		responses := map[string]interface{}{strconv.Itoa(op.Status): resp}
+		if op.Request != nil {
+			responses[strconv.Itoa(http.StatusBadRequest)] = map[string]interface{}{
+				"description": "Invalid request",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(ValidationErrorResponse{}))},
+				},
+			}
+		}
+		if op.Negotiated {
+			responses[strconv.Itoa(http.StatusNotModified)] = map[string]interface{}{
+				"description": "State is unchanged since the ETag given in If-None-Match",
+			}
+		}
+		o["responses"] = responses
+
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]interface{}{}
+		}
+		for _, m := range op.Methods {
+			paths[op.Path][strings.ToLower(m)] = o
+		}
+	}
+
+	doc, _ := json.Marshal(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Word Game Server",
+			"version": strings.TrimPrefix(version, "v") + ".0.0",
+		},
+		"servers": []map[string]string{
+			{"url": "/" + version},
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.components},
+	})
+	return &apiSpec{
+		document:   doc,
+		components: g.components,
+		bodies:     bodies,
+	}
+}
+
+// schemaGenerator builds OpenAPI schemas from Go types, using their JSON
+// field names. Named struct types become components that are referred to.
+type schemaGenerator struct {
+	components map[string]*apiSchema
+}
+
+var (
+	uuidType   = reflect.TypeOf(uuid.UUID{})
+	timeType   = reflect.TypeOf(time.Time{})
+	letterType = reflect.TypeOf(Letter(0))
+)
+
+// body returns the schema of an operation's request body
+func (g *schemaGenerator) body(op apiOperation) *apiSchema {
+	s := g.schema(reflect.TypeOf(op.Request))
+	if len(op.Required) == 0 {
+		return s
+	}
+	return &apiSchema{AllOf: []*apiSchema{s}, Required: op.Required}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *apiSchema {
+	switch t {
+	case uuidType:
+		return &apiSchema{Type: "string", Format: "uuid"}
+	case timeType:
+		return &apiSchema{Type: "string", Format: "date-time"}
+	case letterType:
+		// Written as the text the letter spells
+		return &apiSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return &apiSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &apiSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &apiSchema{Type: "number"}
+	case reflect.String:
+		return &apiSchema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Encoded by encoding/json as base64
+			return &apiSchema{Type: "string", Format: "byte"}
+		}
+		return &apiSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &apiSchema{Type: "array", Items: g.schema(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			// Reserve the name first in case the type refers to itself
+			g.components[t.Name()] = nil
+			g.components[t.Name()] = g.object(t)
+		}
+		return &apiSchema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &apiSchema{}
+}
+
+// object returns the schema of a struct's fields
+func (g *schemaGenerator) object(t reflect.Type) *apiSchema {
+	s := &apiSchema{Type: "object", Properties: map[string]*apiSchema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if tag == "-" || f.PkgPath != "" {
+			continue
+		} else if k := f.Type.Kind(); k == reflect.Chan || k == reflect.Func || k == reflect.Interface {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			// Fields of untagged embedded structs are promoted
+			if e := g.object(f.Type); e != nil {
+				for n, p := range e.Properties {
+					s.Properties[n] = p
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+	}
+	return s
+}
+
+// apiDocumentHandler returns a handler serving the OpenAPI document describing
+// the version of the API
+func apiDocumentHandler(version string) http.HandlerFunc {
+	doc := apiSpecs[version].document
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(doc)
+	}
+}
+
+// validateRequestBody returns middleware that checks the JSON body of requests
+// to the operations in the version's OpenAPI document matches their schema, so
+// malformed requests are rejected with the fields at fault before reaching the
+// handler
+func validateRequestBody(version string) mux.MiddlewareFunc {
+	spec := apiSpecs[version]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Routes are matched by their template, so IDs in paths don't
+			// matter, and the version prefix is left out if they have one
+			path := r.URL.Path
+			if route := mux.CurrentRoute(r); route != nil {
+				if tpl, err := route.GetPathTemplate(); err == nil {
+					path = tpl
+				}
+			}
+			s, ok := spec.bodies[r.Method+" "+strings.TrimPrefix(path, "/"+version)]
+			if !ok || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			var fields []FieldError
+			if len(bytes.TrimSpace(body)) == 0 {
+				fields = validateValue(spec.components, s, map[string]interface{}{}, "")
+			} else {
+				var v interface{}
+				d := json.NewDecoder(bytes.NewReader(body))
+				d.UseNumber()
+				if err := d.Decode(&v); err != nil {
+					fields = []FieldError{{Message: "Invalid JSON: " + err.Error()}}
+				} else {
+					fields = validateValue(spec.components, s, v, "")
+				}
+			}
+
+			if len(fields) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			resp, err := json.Marshal(ValidationErrorResponse{
+				Error:  "Invalid request body",
+				Fields: fields,
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(resp)
+		})
+	}
+}
+
+// validateValue checks a value decoded from JSON against the schema, whose
+// references are to the components given, returning a FieldError for each
+// problem found. Nulls are accepted for any field, as they are decoded as zero
+// values.
+func validateValue(components map[string]*apiSchema, s *apiSchema, v interface{}, field string) []FieldError {
+	if s.Ref != "" {
+		s = components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if v == nil || s == nil {
+		return nil
+	}
+
+	var errs []FieldError
+	fail := func(msg string) []FieldError {
+		return append(errs, FieldError{Field: field, Message: msg})
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, validateValue(components, sub, v, field)...)
+	}
+	if len(s.Required) > 0 {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			// Already reported by the schemas it is made of
+			return errs
+		}
+		for _, name := range s.Required {
+			if obj[name] == nil {
+				errs = append(errs, FieldError{Field: joinField(field, name), Message: "Is required"})
+			}
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fail("Must be an object")
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := s.Properties[name]; ok {
+				errs = append(errs, validateValue(components, p, obj[name], joinField(field, name))...)
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fail("Must be an array")
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems || s.MaxItems != nil && len(arr) > *s.MaxItems {
+			return fail("Must have " + strconv.Itoa(*s.MinItems) + " items")
+		}
+		for i, item := range arr {
+			errs = append(errs, validateValue(components, s.Items, item, field+"["+strconv.Itoa(i)+"]")...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fail("Must be a string")
+		}
+		switch s.Format {
+		case "uuid":
+			if _, err := uuid.Parse(str); err != nil {
+				return fail("Must be a UUID")
+			}
+		case "byte":
+			if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+				return fail("Must be base64 encoded")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fail("Must be an RFC 3339 date and time")
+			}
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fail("Must be an integer")
+		} else if _, err := n.Int64(); err != nil {
+			return fail("Must be an integer")
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return fail("Must be a number")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fail("Must be a boolean")
+		}
+	}
+	return errs
+}
+
+// joinField returns the path to a property of the field
+func joinField(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
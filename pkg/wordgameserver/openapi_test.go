@@ -0,0 +1,136 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAPIDocumentHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	apiDocumentHandler("v1").ServeHTTP(rr, req)
+
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v", c, http.StatusOK)
+	}
+
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err = json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := doc.Paths["/game/play"]["post"]; !ok {
+		t.Error("Document does not describe POST /game/play")
+	}
+	if _, ok := doc.Paths["/game/cancel"]["delete"]; !ok {
+		t.Error("Document does not describe DELETE /game/cancel")
+	}
+
+	// Fields hidden from JSON shouldn't be described
+	props := doc.Components.Schemas["GameStateResponse"].Properties
+	if _, ok := props["game_id"]; !ok {
+		t.Errorf("GameStateResponse schema is missing game_id: %v", props)
+	} else if _, ok := props["PlayerID"]; ok {
+		t.Error("GameStateResponse schema describes hidden PlayerID field")
+	}
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	var received string
+	h := validateRequestBody("v1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		fields []FieldError
+	}{
+		{
+			name: "valid play",
+			path: "/game/play",
+			body: `{"game_id":"8a1e2a8e-2f5e-4c09-9c3b-4b7c4e2a9f10","player_id":"0b0c1d5e-7d4e-4a6c-8f7e-2a6d3c9b1e22","tiles":["C","A","T"]}`,
+		},
+		{
+			name: "malformed play",
+			path: "/game/play",
+			body: `{"game_id":"abc","tiles":5,"start_pos":{"row":"7"}}`,
+			fields: []FieldError{
+				{Field: "game_id", Message: "Must be a UUID"},
+				{Field: "start_pos.row", Message: "Must be an integer"},
+				{Field: "tiles", Message: "Must be an array"},
+				{Field: "player_id", Message: "Is required"},
+			},
+		},
+		{
+			name: "create without body",
+			path: "/game/create",
+		},
+		{
+			name: "nested options",
+			path: "/game/create",
+			body: `{"options":{"turn_timer":1.5},"webhooks":[{"url":true}]}`,
+			fields: []FieldError{
+				{Field: "options.turn_timer", Message: "Must be an integer"},
+				{Field: "webhooks[0].url", Message: "Must be a string"},
+			},
+		},
+		{
+			name:   "invalid JSON",
+			path:   "/game/join",
+			body:   `{"game_id":`,
+			fields: []FieldError{{Message: "Invalid JSON: unexpected EOF"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req, err := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if tt.fields == nil {
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+				} else if received != tt.body {
+					t.Errorf("Handler received body %q, expected %q", received, tt.body)
+				}
+				return
+			}
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+			}
+			var resp ValidationErrorResponse
+			if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(resp.Fields, tt.fields) {
+				t.Errorf("Returned field errors %+v, expected %+v", resp.Fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ErrNotOwner is returned when a player asks for something only the game's
+// owner can do
+var ErrNotOwner = errors.New("Only the game's owner can do that")
+
+// OptionsRequest is the format of the request a game's owner sends to change
+// its options before it starts
+type OptionsRequest struct {
+	GameID   uuid.UUID   `json:"game_id,omitempty"`
+	PlayerID uuid.UUID   `json:"player_id"`
+	Options  GameOptions `json:"options"` // every option of the game, as it should be from now on
+}
+
+// OwnerRequest is the format of the request a game's owner sends to hand it
+// over to another player
+type OwnerRequest struct {
+	GameID   uuid.UUID `json:"game_id,omitempty"`
+	PlayerID uuid.UUID `json:"player_id"`
+	Player   int       `json:"player"` // number of the player to become the owner
+}
+
+// owner returns the player who owns the game, or nil if it has no owner. The
+// game must be locked by the caller.
+func (sg *ScrabbleGame) owner() *Player {
+	return sg.Players[sg.ownerID]
+}
+
+// ownerNumber returns the number of the player who owns the game, or nil if it
+// has no owner. The game must be locked by the caller.
+func (sg *ScrabbleGame) ownerNumber() *int {
+	if p := sg.owner(); p != nil {
+		return &p.Number
+	}
+	return nil
+}
+
+// checkOwner returns ErrNotOwner unless the player owns the game. The game
+// must be locked by the caller.
+func (sg *ScrabbleGame) checkOwner(playerID uuid.UUID) error {
+	if p := sg.owner(); p == nil || p.ID != playerID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// fixedOptions returns the options that shape a game when it is created, as
+// its board, tiles, dictionary and bots are set up from them, leaving out the
+// rest
+func fixedOptions(o GameOptions) GameOptions {
+	return GameOptions{
+		Bots:     o.Bots,
+		BotLevel: o.BotLevel,
+		Variant:  o.Variant,
+		Layout:   o.Layout,
+		TileSet:  o.TileSet,
+		Language: o.Language,
+		Lexicon:  o.Lexicon,
+		Words:    o.Words,
+		Seed:     o.Seed,
+	}
+}
+
+// changeOptions replaces the options of a game that hasn't started at the
+// request of its owner. Only options that don't shape the game when it is
+// created can change, so the rest must be as they were. A game made private
+// is given the join code, which is dropped if it is made public.
+func (sg *ScrabbleGame) changeOptions(playerID uuid.UUID, o GameOptions, code string) error {
+	if err := sg.checkOwner(playerID); err != nil {
+		return err
+	} else if sg.Active {
+		return errors.New("Game has already started")
+	} else if err = o.validate(); err != nil {
+		return err
+	} else if !reflect.DeepEqual(fixedOptions(o), fixedOptions(sg.Options)) {
+		return errors.New("Bots, variant, layout, tile set, language, lexicon, words and seed can't be changed once a game is created")
+	} else if sg.Validator == nil && o.ChallengeWindow > 0 {
+		return errors.New("Challenges require the server to have a dictionary")
+	}
+
+	sg.Options = o
+	if !o.Private {
+		sg.JoinCode = ""
+	} else if sg.JoinCode == "" {
+		sg.JoinCode = code
+	}
+	return nil
+}
+
+// transferOwnership hands the game over from its owner to the player with the
+// number, who must still be in the game and not be a bot. The game must be
+// locked by the caller.
+func (sg *ScrabbleGame) transferOwnership(playerID uuid.UUID, number int) error {
+	if err := sg.checkOwner(playerID); err != nil {
+		return err
+	} else if sg.Finished {
+		return errors.New("Game is over")
+	}
+
+	playerList := sg.playerList()
+	if number < 0 || number >= len(playerList) {
+		return errors.New("No player with that number in game")
+	}
+	p := playerList[number]
+	if p.ID == playerID {
+		return errors.New("Player already owns the game")
+	} else if p.Bot || p.Resigned {
+		return errors.New("Games can only be handed over to players still in them")
+	}
+	return sg.record(Event{Type: OwnerChanged, Player: p.ID})
+}
+
+// ownerError responds with the error of a request only a game's owner can make
+func ownerError(w http.ResponseWriter, err error) {
+	if err == ErrNotOwner {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// gameOptionsHandler handles requests from the owner of a game, identified by
+// the request's path or its game_id, to change its options before it starts.
+// It responds with the game's options, and its join code if it is private.
+func (s *Server) gameOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	var j OptionsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	old := g.JoinCode
+	var code string
+	if j.Options.Private && old == "" {
+		if code, err = s.unusedJoinCode(g.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err = g.changeOptions(j.PlayerID, j.Options, code); err != nil {
+		releaseJoinCode(s.games, code)
+		ownerError(w, err)
+		return
+	} else if err = s.saveGame(g, w); err != nil {
+		return
+	}
+	// A game made public no longer needs its code
+	if g.JoinCode == "" {
+		releaseJoinCode(s.games, old)
+	}
+
+	resp := GeneralGameRequest{GameID: g.ID, Options: &g.Options}
+	if g.JoinCode != "" {
+		resp.JoinCode = &g.JoinCode
+	}
+	gameData, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(gameData)
+}
+
+// transferOwnerHandler handles requests from the owner of a game, identified
+// by the request's path or its game_id, to hand it over to another player. It
+// will respond using the GameStateResponse struct.
+func (s *Server) transferOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	var j OwnerRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if err = g.transferOwnership(j.PlayerID, j.Player); err != nil {
+		ownerError(w, err)
+		return
+	} else if err = s.saveGame(g, w); err != nil {
+		return
+	}
+	writeState(w, r, g.getState(j.PlayerID, g.playerList()))
+}
@@ -0,0 +1,106 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGameOwner(t *testing.T) {
+	srv := newTestServer(t)
+	g := createScrabbleGame()
+	first, _ := g.addPlayer("ashley1")
+	second, _ := g.addPlayer("ashley2")
+	srv.games.Put(g)
+	path := "/v2/games/" + g.ID.String()
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Only the owner, the first to join, can change the options
+	opts := GameOptions{TurnTimer: 60, Private: true}
+	if rr := send("PUT", path+"/options", OptionsRequest{PlayerID: second, Options: opts}); rr.Code != http.StatusForbidden {
+		t.Errorf("Changing options as another player returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+	}
+	if rr := send("PUT", path+"/options", OptionsRequest{PlayerID: first, Options: GameOptions{Variant: VariantWordsWithFriends}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Changing the variant returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	rr := send("PUT", path+"/options", OptionsRequest{PlayerID: first, Options: opts})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var resp GeneralGameRequest
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Options == nil || resp.Options.TurnTimer != 60 || resp.JoinCode == nil {
+		t.Errorf("Responded with %+v, expected the new options and a join code", resp)
+	}
+
+	// Handing the game over lets the new owner cancel it instead
+	if rr = send("POST", path+"/owner", OwnerRequest{PlayerID: second, Player: 1}); rr.Code != http.StatusForbidden {
+		t.Errorf("Taking over the game returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+	}
+	rr = send("POST", path+"/owner", OwnerRequest{PlayerID: first, Player: 1})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var state GameStateResponse
+	if err := json.NewDecoder(rr.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	} else if state.Owner == nil || *state.Owner != 1 {
+		t.Errorf("Game is owned by %v, expected player 1", state.Owner)
+	}
+
+	// Ownership is restored along with the game's events
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.ownerID != second {
+		t.Error("Decoded game has lost its new owner")
+	}
+
+	if rr = send("DELETE", path+"?player_id="+first.String(), nil); rr.Code != http.StatusForbidden {
+		t.Errorf("Cancelling as the old owner returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+	}
+	if rr = send("DELETE", path+"?player_id="+second.String(), nil); rr.Code != http.StatusOK {
+		t.Errorf("Cancelling as the new owner returned status code %v, expected %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestOwnerKicked(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "ADXXXXX")
+	g.Lock()
+	defer g.Unlock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	for _, voter := range ids[1:] {
+		if err := g.kick(voter, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.ownerID != ids[1] {
+		t.Error("Game should be handed over to the next player when its owner is removed")
+	}
+}
@@ -0,0 +1,49 @@
+package wordgameserver
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrWrongPassphrase is returned when joining a protected game without its
+// passphrase
+var ErrWrongPassphrase = errors.New("Incorrect passphrase for game")
+
+// maxPassphraseLength is the longest passphrase bcrypt can hash
+const maxPassphraseLength = 72
+
+// setPassphrase protects the game so players must give the passphrase to
+// join. Only a hash of it is kept.
+func (sg *ScrabbleGame) setPassphrase(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("Passphrase cannot be empty")
+	} else if len(passphrase) > maxPassphraseLength {
+		return errors.Errorf("Passphrase cannot be longer than %v bytes", maxPassphraseLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(passphrase), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrap(err, "Failed to hash passphrase")
+	}
+	sg.passphraseHash = hash
+	return nil
+}
+
+// checkPassphrase returns ErrWrongPassphrase unless the passphrase is the
+// game's, or the game isn't protected. Hashing is slow, so the game must not be
+// locked by the caller.
+func (sg *ScrabbleGame) checkPassphrase(passphrase *string) error {
+	sg.Lock()
+	hash := sg.passphraseHash
+	sg.Unlock()
+
+	if hash == nil {
+		return nil
+	} else if passphrase == nil {
+		return ErrWrongPassphrase
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(*passphrase)); err != nil {
+		return ErrWrongPassphrase
+	}
+	return nil
+}
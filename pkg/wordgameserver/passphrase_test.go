@@ -0,0 +1,98 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProtectedGame(t *testing.T) {
+	srv := newTestServer(t)
+
+	create := func(passphrase string) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(GeneralGameRequest{Passphrase: &passphrase})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, passphrase := range []string{"", strings.Repeat("x", maxPassphraseLength+1)} {
+		if rr := create(passphrase); rr.Code != http.StatusBadRequest {
+			t.Errorf("Creating a game with a %v byte passphrase returned status code %v, expected %v", len(passphrase), rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	rr := create("open sesame")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var created GeneralGameRequest
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	join := func(passphrase *string) *httptest.ResponseRecorder {
+		name := "ashley"
+		payload, err := json.Marshal(GeneralGameRequest{GameID: created.GameID, PlayerName: &name, Passphrase: passphrase})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/join", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.joinGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	wrong := "open barley"
+	for _, passphrase := range []*string{nil, &wrong} {
+		if rr = join(passphrase); rr.Code != http.StatusForbidden {
+			t.Errorf("Joining without the passphrase returned status code %v, expected %v", rr.Code, http.StatusForbidden)
+		}
+	}
+
+	right := "open sesame"
+	if rr = join(&right); rr.Code != http.StatusOK {
+		t.Fatalf("Joining with the passphrase returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	} else if strings.Contains(rr.Body.String(), right) {
+		t.Error("Passphrase should not be sent back to the client")
+	}
+
+	g, err := srv.games.Get(created.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Lock()
+	entry, _ := srv.lobbyGame(g)
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if !entry.Protected {
+		t.Error("Lobby should show the game is protected")
+	}
+
+	// Only the hash is saved by persistent stores, and it still works once
+	// the game is loaded
+	if err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(data, []byte(right)) {
+		t.Error("Encoded game contains the passphrase")
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = d.checkPassphrase(&right); err != nil {
+		t.Errorf("Decoded game rejected its passphrase: %v", err)
+	}
+}
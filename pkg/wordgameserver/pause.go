@@ -0,0 +1,133 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// pause handles a request from a player to pause a game that allows it. While
+// it is paused its timers and clocks are frozen and moves are refused, though
+// players can still resign or remove each other.
+func (sg *ScrabbleGame) pause(j GamePlayRequest) error {
+	p := sg.Players[j.PlayerID]
+	if !sg.Options.Pausable {
+		return errors.New("Pausing is not enabled for this game")
+	} else if sg.paused {
+		return errors.New("Game is already paused")
+	} else if p.Resigned {
+		return errors.New("Player has resigned")
+	}
+	return sg.record(Event{Type: GamePaused, Player: j.PlayerID})
+}
+
+// applyPause freezes the game's timers at the time of the event
+func (sg *ScrabbleGame) applyPause(e Event) {
+	sg.paused = true
+	sg.pausedAt = e.Time
+	sg.resumeVotes = nil
+}
+
+// resume handles a request from a player to resume a paused game. Play resumes
+// once every player still in the game has asked, including the one who paused
+// it. Bots don't need to be asked. Requests aren't saved with the game, so
+// players ask again after a restart.
+func (sg *ScrabbleGame) resume(j GamePlayRequest) error {
+	p := sg.Players[j.PlayerID]
+	if !sg.paused {
+		return errors.New("Game is not paused")
+	} else if p.Resigned {
+		return errors.New("Player has resigned")
+	}
+
+	if sg.resumeVotes == nil {
+		sg.resumeVotes = make(map[uuid.UUID]bool)
+	}
+	sg.resumeVotes[j.PlayerID] = true
+	for _, p := range sg.Players {
+		if !p.Resigned && !p.Bot && !sg.resumeVotes[p.ID] {
+			return nil
+		}
+	}
+	return sg.record(Event{Type: GameResumed, Player: j.PlayerID})
+}
+
+// applyResume restarts the game's timers from where they were frozen, so the
+// time it was paused for isn't counted against the current turn or the last
+// play's challenge window
+func (sg *ScrabbleGame) applyResume(e Event) {
+	sg.TurnStarted = e.Time.Add(-sg.pausedFor(sg.TurnStarted))
+	if sg.lastPlay != nil {
+		lp := *sg.lastPlay
+		lp.Time = e.Time.Add(-sg.pausedFor(lp.Time))
+		sg.lastPlay = &lp
+	}
+	sg.paused = false
+	sg.pausedAt = time.Time{}
+	sg.resumeVotes = nil
+}
+
+// pausedFor returns how long had passed since the time when the game was
+// paused, or nothing if it was after, as when a turn changed while paused
+func (sg *ScrabbleGame) pausedFor(since time.Time) time.Duration {
+	if d := sg.pausedAt.Sub(since); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// resumeVoteNumbers returns the numbers of the players who have asked to
+// resume the paused game, in order. The game must be locked by the caller.
+func (sg *ScrabbleGame) resumeVoteNumbers() []int {
+	var numbers []int
+	for id := range sg.resumeVotes {
+		if p, ok := sg.Players[id]; ok {
+			numbers = append(numbers, p.Number)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// pauseHandler handles requests from players to pause a game. It will respond
+// using the GameStateResponse struct.
+func (s *Server) pauseHandler(w http.ResponseWriter, r *http.Request) {
+	s.pauseRequestHelper(w, r, pauseRequest)
+}
+
+// unpauseHandler handles requests from players to resume a paused game, or to
+// agree to. It will respond using the GameStateResponse struct.
+func (s *Server) unpauseHandler(w http.ResponseWriter, r *http.Request) {
+	s.pauseRequestHelper(w, r, unpauseRequest)
+}
+
+// pauseRequestHelper sends a request to pause or resume the game identified
+// by the request's path or its game_id to the game's controller
+func (s *Server) pauseRequestHelper(w http.ResponseWriter, r *http.Request, t requestType) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   j.GameID,
+		PlayerID: *j.PlayerID,
+		Type:     t,
+	}, w, r)
+}
@@ -0,0 +1,90 @@
+package wordgameserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseAndResume(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "ADXXXXX")
+	g.Options.TurnTimer = 60
+	g.Options.Clock = 600
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	if _, err := g.request(GamePlayRequest{PlayerID: ids[1], Type: pauseRequest}); err == nil {
+		t.Fatal("Game was paused without allowing it")
+	}
+	g.Lock()
+	g.Options.Pausable = true
+	g.Unlock()
+
+	state, err := g.request(GamePlayRequest{PlayerID: ids[1], Type: pauseRequest})
+	if err != nil {
+		t.Fatal(err)
+	} else if !state.Paused || state.TurnEnds != nil {
+		t.Errorf("State is paused %v with turn ending %v, expected it paused without a deadline", state.Paused, state.TurnEnds)
+	}
+
+	// Moves are refused while paused, though the state can still be seen
+	if _, err = g.request(GamePlayRequest{PlayerID: ids[0], Type: passRequest}); err == nil {
+		t.Error("Turn was passed while the game was paused")
+	}
+	if _, err = g.request(GamePlayRequest{PlayerID: ids[0]}); err != nil {
+		t.Errorf("State couldn't be seen while paused: %v", err)
+	}
+
+	// Time spent paused isn't counted against the turn
+	g.Lock()
+	if _, ok := g.nextDeadline(); ok {
+		t.Error("Paused game still has a deadline")
+	}
+	g.pausedAt = g.pausedAt.Add(-time.Hour)
+	g.TurnStarted = g.TurnStarted.Add(-time.Hour)
+	clock := g.timeLeft(g.Players[ids[0]], g.playerList())
+	g.Unlock()
+	if clock < 590*time.Second {
+		t.Errorf("Clock shows %v left, expected the time paused not to count", clock)
+	}
+
+	// Everyone still in the game must ask to resume
+	for _, id := range ids[:2] {
+		if state, err = g.request(GamePlayRequest{PlayerID: id, Type: unpauseRequest}); err != nil {
+			t.Fatal(err)
+		} else if !state.Paused {
+			t.Fatal("Game resumed before every player asked")
+		}
+	}
+	if len(state.ResumeVotes) != 2 || state.ResumeVotes[0] != 0 || state.ResumeVotes[1] != 1 {
+		t.Errorf("Resume was asked for by %v, expected players 0 and 1", state.ResumeVotes)
+	}
+	if state, err = g.request(GamePlayRequest{PlayerID: ids[2], Type: unpauseRequest}); err != nil {
+		t.Fatal(err)
+	} else if state.Paused || state.TurnEnds == nil {
+		t.Fatal("Game should resume once every player has asked")
+	} else if left := time.Until(*state.TurnEnds); left < 50*time.Second {
+		t.Errorf("Turn ends in %v, expected the time paused to be given back", left)
+	}
+
+	if _, err = g.request(GamePlayRequest{PlayerID: ids[0], Type: passRequest}); err != nil {
+		t.Errorf("Turn couldn't be passed once resumed: %v", err)
+	}
+
+	// Pausing is restored along with the game's events
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.paused {
+		t.Error("Decoded game is still paused")
+	}
+}
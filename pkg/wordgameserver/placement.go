@@ -0,0 +1,80 @@
+package wordgameserver
+
+import (
+	"errors"
+	"sort"
+)
+
+// TilePlacement is a single tile placed on the board, an alternative to giving
+// a play's start and end positions that lists exactly which squares are filled
+type TilePlacement struct {
+	Square SquareCoordinate `json:"square"`
+	Tile   Letter           `json:"tile"`            // tile from the player's hand, " " for a blank
+	Blank  Letter           `json:"blank,omitempty"` // letter a blank tile stands for
+}
+
+// resolvePlacements converts the placements of a play to the start and end
+// positions, tiles and blanks the rest of the game works with, checking they
+// can all be placed on the board. Plays without placements are returned
+// unchanged.
+func resolvePlacements(sb ScrabbleBoard, j GamePlayRequest) (GamePlayRequest, error) {
+	if len(j.Placements) == 0 {
+		return j, nil
+	} else if j.Swap {
+		return j, errors.New("Swaps are made with tiles, not placements")
+	} else if len(j.Tiles) > 0 || len(j.Blanks) > 0 {
+		return j, errors.New("Only one of placements and tiles can be given")
+	}
+
+	placements := append([]TilePlacement(nil), j.Placements...)
+	sort.Slice(placements, func(a, b int) bool {
+		pa, pb := placements[a].Square, placements[b].Square
+		return pa.Row < pb.Row || pa.Row == pb.Row && pa.Col < pb.Col
+	})
+
+	for i := 1; i < len(placements); i++ {
+		if placements[i].Square == placements[i-1].Square {
+			return j, errors.New("Two tiles placed on the same square")
+		}
+	}
+
+	start, end := placements[0].Square, placements[len(placements)-1].Square
+	step, err := sb.PlayDirection(start, end)
+	if err != nil {
+		return j, err
+	}
+
+	// Every square between the first and last placement must either be
+	// filled by the play or already hold a tile
+	bySquare := make(map[SquareCoordinate]TilePlacement, len(placements))
+	for _, p := range placements {
+		bySquare[p.Square] = p
+	}
+	j.StartPos, j.EndPos, j.Placements = start, end, nil
+	for sc := start; ; sc = sc.Next(step) {
+		if p, ok := bySquare[sc]; ok {
+			if sb.At(sc).Occupied() {
+				return j, errors.New("Square already holds a tile")
+			}
+			j.Tiles = append(j.Tiles, p.Tile)
+			if p.Tile == ' ' {
+				j.Blanks = append(j.Blanks, p.Blank)
+			} else if p.Blank != 0 {
+				return j, errors.New("Only blank tiles can be designated a letter")
+			}
+			delete(bySquare, sc)
+		} else if !sb.At(sc).Occupied() {
+			return j, ErrNotContiguous
+		}
+
+		if sc == end {
+			break
+		}
+	}
+
+	// Placements left over are off the line of play
+	if len(bySquare) > 0 {
+		return j, ErrNotInLine
+	}
+	return j, nil
+}
@@ -0,0 +1,129 @@
+package wordgameserver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlayPlacements(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "C TXXXX")
+
+	// Placements can be given in any order
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		Placements: []TilePlacement{
+			{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'T'},
+			{Square: SquareCoordinate{Row: 7, Col: 6}, Tile: 'C'},
+			{Square: SquareCoordinate{Row: 7, Col: 7}, Tile: 'A'},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if p := g.Players[ids[0]]; p.Score != 5 {
+		t.Errorf("Player scored %v, expected 5", p.Score)
+	}
+
+	// Tiles already on the board between placements are skipped over
+	err = g.executePlay(GamePlayRequest{
+		PlayerID: ids[1],
+		Placements: []TilePlacement{
+			{Square: SquareCoordinate{Row: 8, Col: 7}, Tile: ' ', Blank: 't'},
+			{Square: SquareCoordinate{Row: 6, Col: 7}, Tile: 'C'},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if last := g.history[len(g.history)-1]; !reflect.DeepEqual(last.Words, []string{"CAT"}) || last.Score != 4 {
+		t.Errorf("Play formed %v scoring %v, expected CAT scoring 4", last.Words, last.Score)
+	} else if squ := g.Board[8][7]; squ.Letter != 'T' || !squ.Blank {
+		t.Errorf("Blank placed as %q, expected blank 'T'", squ.Letter)
+	}
+}
+
+func TestResolvePlacements(t *testing.T) {
+	board := NewBoard()
+	board[7][7].Tile = Tile{Letter: 'A', Value: 1}
+
+	j, err := resolvePlacements(board, GamePlayRequest{
+		Placements: []TilePlacement{
+			{Square: SquareCoordinate{Row: 7, Col: 9}, Tile: ' ', Blank: 's'},
+			{Square: SquareCoordinate{Row: 7, Col: 6}, Tile: 'C'},
+			{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'T'},
+		},
+	})
+	expected := GamePlayRequest{
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 9},
+		Tiles:    Letters("CT "),
+		Blanks:   Letters("s"),
+	}
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(j, expected) {
+		t.Errorf("Resolved %+v, expected %+v", j, expected)
+	}
+
+	tests := []struct {
+		name       string
+		placements []TilePlacement
+		expected   error
+	}{
+		{
+			name: "gap",
+			placements: []TilePlacement{
+				{Square: SquareCoordinate{Row: 7, Col: 5}, Tile: 'C'},
+				{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'T'},
+			},
+			expected: ErrNotContiguous,
+		},
+		{
+			name: "not in line",
+			placements: []TilePlacement{
+				{Square: SquareCoordinate{Row: 6, Col: 7}, Tile: 'C'},
+				{Square: SquareCoordinate{Row: 7, Col: 6}, Tile: 'T'},
+				{Square: SquareCoordinate{Row: 8, Col: 7}, Tile: 'T'},
+			},
+			expected: ErrNotInLine,
+		},
+		{
+			name: "occupied square",
+			placements: []TilePlacement{
+				{Square: SquareCoordinate{Row: 7, Col: 7}, Tile: 'C'},
+			},
+		},
+		{
+			name: "same square",
+			placements: []TilePlacement{
+				{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'C'},
+				{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'T'},
+			},
+		},
+		{
+			name: "designated letter tile",
+			placements: []TilePlacement{
+				{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'T', Blank: 's'},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		_, err = resolvePlacements(board, GamePlayRequest{Placements: tc.placements})
+		if err == nil {
+			t.Errorf("Placements %v should have failed", tc.name)
+		} else if tc.expected != nil && !errors.Is(err, tc.expected) {
+			t.Errorf("Placements %v returned %v, expected %v", tc.name, err, tc.expected)
+		}
+	}
+
+	if _, err = resolvePlacements(board, GamePlayRequest{Tiles: Letters("C")}); err != nil {
+		t.Errorf("Play without placements returned %v", err)
+	}
+	_, err = resolvePlacements(board, GamePlayRequest{
+		Tiles:      Letters("C"),
+		Placements: []TilePlacement{{Square: SquareCoordinate{Row: 7, Col: 8}, Tile: 'T'}},
+	})
+	if err == nil {
+		t.Error("Play with both tiles and placements should have failed")
+	}
+}
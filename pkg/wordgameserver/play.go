@@ -1,40 +1,398 @@
 package wordgameserver
 
-import "errors"
+import (
+	"errors"
 
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/google/uuid"
+)
+
+// checkTurn makes sure it is the player's turn
+func (sg *ScrabbleGame) checkTurn(playerID uuid.UUID) error {
+	return sg.CheckTurn(sg.seats(), sg.Players[playerID].Number)
+}
+
+// ScrabbleGame is played through the engine's Game interface by simulations,
+// with each move recorded as an event like those made through the server
+var _ engine.Game = (*ScrabbleGame)(nil)
+
+// Turn returns the number of the player to move. The game must be locked by
+// the caller, as it must for each of the Game methods.
+func (sg *ScrabbleGame) Turn() int {
+	return sg.Current(sg.seats())
+}
+
+// Rack returns the tiles the player holds
+func (sg *ScrabbleGame) Rack(player int) Letters {
+	return append(Letters(nil), sg.playerList()[player].Tiles...)
+}
+
+// Play lays the player's tiles on the board
+func (sg *ScrabbleGame) Play(player int, p engine.Play) error {
+	return sg.executePlay(sg.seatRequest(player, GamePlayRequest{StartPos: p.StartPos, EndPos: p.EndPos, Tiles: p.Tiles, Blanks: p.Blanks}))
+}
+
+// Swap exchanges the player's tiles for ones from the bag
+func (sg *ScrabbleGame) Swap(player int, tiles Letters) error {
+	return sg.executePlay(sg.seatRequest(player, GamePlayRequest{Tiles: tiles, Swap: true}))
+}
+
+// Pass gives up the player's turn
+func (sg *ScrabbleGame) Pass(player int) error {
+	return sg.pass(sg.seatRequest(player, GamePlayRequest{}))
+}
+
+// Over reports whether the game has ended
+func (sg *ScrabbleGame) Over() bool {
+	return sg.Finished
+}
+
+// seatRequest fills in the game and the player, known by their number, the
+// request is made for
+func (sg *ScrabbleGame) seatRequest(player int, j GamePlayRequest) GamePlayRequest {
+	j.GameID = sg.ID
+	j.PlayerID = sg.playerList()[player].ID
+	return j
+}
+
+// seats returns the players' seats at the table, in turn order
+func (sg *ScrabbleGame) seats() []*engine.Seat {
+	playerList := sg.playerList()
+	seats := make([]*engine.Seat, len(playerList))
+	for i, p := range playerList {
+		seats[i] = &p.Seat
+	}
+	return seats
+}
+
+// rules returns what the game's turns are played by
+func (sg *ScrabbleGame) rules() engine.Rules {
+	return engine.Rules{
+		Tiles:      sg.variant.tiles,
+		Bingo:      sg.variant.bingo,
+		Validator:  sg.Validator,
+		Challenges: sg.Options.ChallengeWindow > 0,
+	}
+}
+
+// executePlay plays tiles on the board, or swaps them for tiles from the bag
+// if the request is a swap, which takes the player's turn
 func (sg *ScrabbleGame) executePlay(j GamePlayRequest) error {
-	playerTurn := sg.TurnCount % len(sg.Players)
-	if playerTurn != sg.Players[j.PlayerID].Number {
-		return errors.New("Playing out of turn. Expected Player " + string(playerTurn))
-	} else if len(j.Tiles) > 7 {
-		return errors.New("Cannot play more than 7 tiles")
+	j, err := resolvePlacements(sg.Board, j)
+	if err != nil {
+		return err
+	} else if _, _, err = sg.checkMove(j); err != nil {
+		return err
 	}
 
 	if j.Swap {
-		return sg.swapTiles(j)
+		return sg.record(sg.swapEvent(sg.Players[j.PlayerID], j.Tiles))
+	}
+	return sg.record(Event{
+		Type:     MovePlayed,
+		Player:   j.PlayerID,
+		StartPos: j.StartPos,
+		EndPos:   j.EndPos,
+		Tiles:    append(Letters(nil), j.Tiles...),
+		Blanks:   append(Letters(nil), j.Blanks...),
+	})
+}
+
+// checkMove makes sure the player can make the play or swap without making it,
+// returning the words a play would form and the points it would score
+func (sg *ScrabbleGame) checkMove(j GamePlayRequest) ([]string, int, error) {
+	if err := sg.checkTurn(j.PlayerID); err != nil {
+		return nil, 0, err
+	}
+	j, err := resolvePlacements(sg.Board, j)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// A play that went out in a game with challenges can only be
+	// challenged, or accepted by passing
+	if sg.playedOut() != nil {
+		return nil, 0, errors.New("Last play went out, so it must be challenged or accepted by passing")
+	}
+
+	if j.Swap {
+		return nil, 0, sg.CheckSwap(&sg.Players[j.PlayerID].Seat, j.Tiles)
+	}
+	return sg.CheckPlay(&sg.Players[j.PlayerID].Seat, j.play(), sg.rules())
+}
+
+// swapEvent describes the player swapping the tiles, with the bag as it will be
+// once their new tiles are drawn and the swapped tiles shuffled back in
+func (sg *ScrabbleGame) swapEvent(cp *Player, swapped Letters) Event {
+	return Event{
+		Type:   TilesExchanged,
+		Player: cp.ID,
+		Tiles:  append(Letters(nil), swapped...),
+		Bag:    sg.reshuffled(len(swapped), swapped),
+	}
+}
+
+// applySwap exchanges tiles from the player's hand for new ones from the bag,
+// which takes their turn
+func (sg *ScrabbleGame) applySwap(e Event) error {
+	cp := sg.Players[e.Player]
+	rack := append(Letters(nil), cp.Tiles...)
+
+	// Swapping takes the player's turn
+	sg.endTurn(e.Time)
+	if err := sg.Table.Swap(sg.seats(), cp.Number, e.Tiles, e.Bag); err != nil {
+		return err
+	}
+
+	// The previous play can no longer be challenged
+	sg.lastPlay = nil
+
+	sg.history = append(sg.history, Move{
+		Player:   cp.Number,
+		Swap:     true,
+		TimedOut: e.TimedOut,
+		Rack:     rack,
+		Swapped:  append(Letters(nil), e.Tiles...),
+		Time:     e.Time,
+	})
+	if e.TimedOut {
+		sg.timedOut = &cp.Number
 	}
 
 	return nil
 }
 
-func (sg *ScrabbleGame) swapTiles(j GamePlayRequest) error {
-	if len(j.Tiles) > len(sg.TileBag) {
-		return errors.New("Not enough tiles available for swap")
+// pass handles a request from a player to give up their turn
+func (sg *ScrabbleGame) pass(j GamePlayRequest) error {
+	if err := sg.checkTurn(j.PlayerID); err != nil {
+		return err
+	}
+	return sg.record(Event{Type: TurnPassed, Player: j.PlayerID})
+}
+
+// applyPass gives up the player's turn without playing or swapping tiles. The
+// game ends once every player has passed in a row, or when the pass accepts a
+// play that went out.
+func (sg *ScrabbleGame) applyPass(e Event) error {
+	cp := sg.Players[e.Player]
+	out := sg.playedOut()
+	rack := append(Letters(nil), cp.Tiles...)
+
+	sg.endTurn(e.Time)
+	passed, err := sg.Table.Pass(sg.seats(), cp.Number, sg.consecutivePasses()+1, sg.rules())
+	if err != nil {
+		return err
 	}
 
-	// Remove tiles from player's hand
+	// The previous play can no longer be challenged
+	sg.lastPlay = nil
+
+	sg.history = append(sg.history, Move{
+		Player:   cp.Number,
+		Pass:     true,
+		TimedOut: e.TimedOut,
+		Rack:     rack,
+		Time:     e.Time,
+	})
+	if e.TimedOut {
+		sg.timedOut = &cp.Number
+	}
+
+	if out != nil {
+		sg.goOut(out)
+	} else if passed {
+		sg.endGame(nil)
+	}
+	return nil
+}
+
+// resign concedes the game for the player, at any point in it. They take no
+// more turns, and their tiles are returned to the bag or set aside depending
+// on the game's options. The game ends once only one player remains.
+func (sg *ScrabbleGame) resign(j GamePlayRequest) error {
 	cp := sg.Players[j.PlayerID]
-	err := removeTiles(cp, j.Tiles)
+	if cp.Resigned {
+		return errors.New("Player has already resigned")
+	}
+
+	e := Event{Type: PlayerResigned, Player: cp.ID}
+	if sg.Options.ResignedTiles != ResignedTilesAside {
+		e.Bag = sg.reshuffled(0, cp.Tiles)
+	}
+	return sg.record(e)
+}
+
+// applyResign takes the player out of the game, ending it if only one player
+// remains
+func (sg *ScrabbleGame) applyResign(e Event) {
+	sg.retire(e, false)
+}
+
+// retire takes the event's player out of the game as a resignation, which is
+// marked as a removal if they were kicked out, ending it if only one player
+// remains
+func (sg *ScrabbleGame) retire(e Event, kicked bool) {
+	cp := sg.Players[e.Player]
+	current := sg.Current(sg.seats()) == cp.Number
+	cp.Resigned, cp.Kicked = true, kicked
+	rack := append(Letters(nil), cp.Tiles...)
+
+	if sg.Options.ResignedTiles != ResignedTilesAside {
+		sg.TileBag = append(TileBag(nil), e.Bag...)
+		cp.Tiles = Letters{}
+	}
+
+	sg.history = append(sg.history, Move{
+		Player: cp.Number,
+		Resign: true,
+		Kicked: kicked,
+		Rack:   rack,
+		Time:   e.Time,
+	})
+
+	if engine.Active(sg.seats()) <= 1 {
+		sg.endGame(nil)
+	} else if current {
+		sg.advanceTurn(e.Time)
+	}
+}
+
+// consecutivePasses counts the passes made since the last play or swap
+func (sg *ScrabbleGame) consecutivePasses() int {
+	n := 0
+	for i := len(sg.history) - 1; i >= 0 && sg.history[i].Pass; i-- {
+		n++
+	}
+	return n
+}
+
+// goOut ends the game with the player having played every tile they held once
+// the bag was empty. The value of each other player's tiles is taken off their
+// score and added to that of the player who went out.
+func (sg *ScrabbleGame) goOut(out *Player) {
+	engine.GoOut(sg.seats(), &out.Seat, sg.rules())
+	sg.endGame(nil)
+}
+
+// playedOut returns the player whose play, still open to challenge, used the
+// last of their tiles with the bag empty, or nil if the last play didn't. In
+// games without challenges such a play ends the game straight away, so it is
+// never open to challenge.
+func (sg *ScrabbleGame) playedOut() *Player {
+	if sg.lastPlay == nil || len(sg.TileBag) > 0 {
+		return nil
+	}
+	if p := sg.Players[sg.lastPlay.PlayerID]; len(p.Tiles) == 0 {
+		return p
+	}
+	return nil
+}
+
+// applyPlay places the tiles on the board, scores the play and replenishes the
+// player's hand. A player who uses the last of their tiles once the bag is
+// empty goes out, which ends the game, though in games with challenges not
+// until the next player accepts the play.
+func (sg *ScrabbleGame) applyPlay(e Event) error {
+	cp := sg.Players[e.Player]
+	rack := append(Letters(nil), cp.Tiles...)
+
+	sg.endTurn(e.Time)
+	played, err := sg.Table.Play(sg.seats(), cp.Number, engine.Play{
+		StartPos: e.StartPos,
+		EndPos:   e.EndPos,
+		Tiles:    e.Tiles,
+		Blanks:   e.Blanks,
+	}, sg.rules())
 	if err != nil {
 		return err
 	}
 
-	// Deal new tiles to player
-	dealTiles(cp, &sg.TileBag, len(j.Tiles))
+	// Keep enough of the play to retract it if it is challenged
+	sg.lastPlay = &playRecord{
+		PlayerID: e.Player,
+		Placed:   played.Placed,
+		Played:   append(Letters(nil), e.Tiles...),
+		Drawn:    played.Drawn,
+		Words:    played.Words,
+		Score:    played.Score,
+		Time:     e.Time,
+	}
+
+	sg.history = append(sg.history, Move{
+		Player:  cp.Number,
+		Words:   played.Words,
+		Squares: played.Placed,
+		Tiles:   played.Tiles,
+		Rack:    rack,
+		Score:   played.Score,
+		Time:    e.Time,
+	})
 
-	// Add swapped tiles to bag and shuffle
-	sg.TileBag = append(sg.TileBag, j.Tiles...)
-	sg.TileBag.shuffle()
+	if played.Ended {
+		sg.endGame(nil)
+	}
 
 	return nil
 }
+
+// play returns the tiles the request places on the board
+func (j GamePlayRequest) play() engine.Play {
+	return engine.Play{StartPos: j.StartPos, EndPos: j.EndPos, Tiles: j.Tiles, Blanks: j.Blanks}
+}
+
+// ScorePlay works out the words a play would form on a standard board and the
+// points it would score, without checking the words against a dictionary or
+// the tiles against a player's hand. Computer players use it to rank the plays
+// available to them.
+func ScorePlay(board ScrabbleBoard, play GamePlayRequest) (int, []string, error) {
+	return ScoreVariantPlay(VariantStandard, board, play)
+}
+
+// ScoreVariantPlay works out the words and score of a play like ScorePlay,
+// using the tile values and bonuses of the named variant
+func ScoreVariantPlay(variant string, board ScrabbleBoard, play GamePlayRequest) (int, []string, error) {
+	v, err := lookupVariant(variant)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	laid, placed, words, err := board.LayTiles(play.play(), v.tiles)
+	if err != nil {
+		return 0, nil, err
+	} else if err = board.CheckPlacement(placed, words); err != nil {
+		return 0, nil, err
+	}
+
+	formed := make([]string, len(words))
+	for i, w := range words {
+		formed[i] = w.Word
+	}
+	return laid.ScorePlay(placed, words, v.bingo), formed, nil
+}
+
+// VariantMoves finds every legal play of tiles from the rack on a board of the
+// named variant that forms only words in the DAWG, scored with the variant's
+// tile values and bonuses, highest scoring first
+func VariantMoves(variant string, board ScrabbleBoard, rack Letters, words *dictionary.DAWG) ([]Solution, error) {
+	v, err := lookupVariant(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := board.Moves(rack, words, v.tiles, v.bingo)
+	solutions := make([]Solution, len(moves))
+	for i, m := range moves {
+		solutions[i] = Solution{
+			Play: GamePlayRequest{
+				StartPos: m.Play.StartPos,
+				EndPos:   m.Play.EndPos,
+				Tiles:    m.Play.Tiles,
+				Blanks:   m.Play.Blanks,
+			},
+			Words: m.Words,
+			Score: m.Score,
+		}
+	}
+	return solutions, nil
+}
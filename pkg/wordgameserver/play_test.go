@@ -0,0 +1,346 @@
+package wordgameserver
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/google/uuid"
+)
+
+// createTestGame creates a game with a player holding each of the hands given,
+// in turn order
+func createTestGame(t *testing.T, hands ...string) (*ScrabbleGame, []uuid.UUID) {
+	t.Helper()
+
+	wl, err := dictionary.NewWordList(strings.NewReader("CAT\nCATS\nDOG\nDOGS\nAD\nTA\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := createScrabbleGame()
+	g.Validator = wl
+
+	ids := make([]uuid.UUID, len(hands))
+	for i, h := range hands {
+		ids[i], err = g.addPlayer("ashley" + string('1'+byte(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Players[ids[i]].Tiles = Letters(h)
+	}
+	return g, ids
+}
+
+func TestPlayWord(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "S DOGXX")
+	bagSize := len(g.TileBag)
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := g.Players[ids[0]]; p.Score != 5 {
+		t.Errorf("Player scored %v, expected 5", p.Score)
+	} else if len(p.Tiles) != maxTiles {
+		t.Errorf("Player has %v tiles, expected %v", len(p.Tiles), maxTiles)
+	} else if len(g.TileBag) != bagSize-3 {
+		t.Errorf("Tile bag has %v tiles, expected %v", len(g.TileBag), bagSize-3)
+	} else if g.TurnCount != 1 {
+		t.Errorf("Turn count is %v, expected 1", g.TurnCount)
+	}
+
+	// Extend the word using a blank tile on the existing letters
+	err = g.executePlay(GamePlayRequest{
+		PlayerID: ids[1],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 9},
+		Tiles:    Letters(" "),
+		Blanks:   Letters("s"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := g.Players[ids[1]]; p.Score != 5 {
+		t.Errorf("Player scored %v, expected 5", p.Score)
+	} else if squ := g.Board[7][9]; squ.Letter != 'S' || squ.Value != 0 || !squ.Blank {
+		t.Errorf("Blank placed as %q worth %v, expected blank 'S' worth 0", squ.Letter, squ.Value)
+	}
+}
+
+func TestPlayWordInvalid(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	tests := []struct {
+		name string
+		play GamePlayRequest
+	}{
+		{
+			name: "out of turn",
+			play: GamePlayRequest{
+				PlayerID: ids[1],
+				StartPos: SquareCoordinate{Row: 7, Col: 7},
+				EndPos:   SquareCoordinate{Row: 7, Col: 9},
+				Tiles:    Letters("DOG"),
+			},
+		},
+		{
+			name: "not in dictionary",
+			play: GamePlayRequest{
+				PlayerID: ids[0],
+				StartPos: SquareCoordinate{Row: 7, Col: 7},
+				EndPos:   SquareCoordinate{Row: 7, Col: 9},
+				Tiles:    Letters("TAC"),
+			},
+		},
+		{
+			name: "not in hand",
+			play: GamePlayRequest{
+				PlayerID: ids[0],
+				StartPos: SquareCoordinate{Row: 7, Col: 7},
+				EndPos:   SquareCoordinate{Row: 7, Col: 9},
+				Tiles:    Letters("DOG"),
+			},
+		},
+		{
+			name: "diagonal",
+			play: GamePlayRequest{
+				PlayerID: ids[0],
+				StartPos: SquareCoordinate{Row: 7, Col: 7},
+				EndPos:   SquareCoordinate{Row: 9, Col: 9},
+				Tiles:    Letters("CAT"),
+			},
+		},
+		{
+			name: "too few squares",
+			play: GamePlayRequest{
+				PlayerID: ids[0],
+				StartPos: SquareCoordinate{Row: 7, Col: 7},
+				EndPos:   SquareCoordinate{Row: 7, Col: 8},
+				Tiles:    Letters("CAT"),
+			},
+		},
+		{
+			name: "off board",
+			play: GamePlayRequest{
+				PlayerID: ids[0],
+				StartPos: SquareCoordinate{Row: 7, Col: 13},
+				EndPos:   SquareCoordinate{Row: 7, Col: 15},
+				Tiles:    Letters("CAT"),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		if err := g.executePlay(tc.play); err == nil {
+			t.Errorf("Play %v should have failed", tc.name)
+		}
+	}
+
+	err := g.executePlay(tests[1].play)
+	if err == nil || !strings.Contains(err.Error(), "TAC") {
+		t.Errorf("Error should name the invalid word, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(g.Board, NewBoard()) || g.TurnCount != 0 {
+		t.Error("Failed plays should not change the game")
+	} else if string(g.Players[ids[0]].Tiles) != "CATXXXX" {
+		t.Error("Failed plays should not change the player's hand")
+	}
+}
+
+func TestPlacementRules(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 2, Col: 2},
+		EndPos:   SquareCoordinate{Row: 2, Col: 4},
+		Tiles:    Letters("CAT"),
+	})
+	if !errors.Is(err, ErrOffCenter) {
+		t.Fatalf("First play away from the center returned %v, expected %v", err, ErrOffCenter)
+	}
+
+	err = g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		play     GamePlayRequest
+		expected *PlacementError
+	}{
+		{
+			name: "not connected",
+			play: GamePlayRequest{
+				StartPos: SquareCoordinate{Row: 2, Col: 2},
+				EndPos:   SquareCoordinate{Row: 2, Col: 4},
+				Tiles:    Letters("DOG"),
+			},
+			expected: ErrNotConnected,
+		},
+		{
+			name: "gap",
+			play: GamePlayRequest{
+				StartPos: SquareCoordinate{Row: 7, Col: 9},
+				EndPos:   SquareCoordinate{Row: 7, Col: 11},
+				Tiles:    Letters("S"),
+			},
+			expected: ErrNotContiguous,
+		},
+		{
+			name: "diagonal",
+			play: GamePlayRequest{
+				StartPos: SquareCoordinate{Row: 6, Col: 9},
+				EndPos:   SquareCoordinate{Row: 8, Col: 11},
+				Tiles:    Letters("DOG"),
+			},
+			expected: ErrNotInLine,
+		},
+	}
+
+	for _, tc := range tests {
+		tc.play.PlayerID = ids[1]
+		if err = g.executePlay(tc.play); !errors.Is(err, tc.expected) {
+			t.Errorf("Play %v returned %v, expected %v", tc.name, err, tc.expected)
+		}
+	}
+}
+
+func TestPass(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "QDOGSXX")
+
+	if err := g.pass(GamePlayRequest{PlayerID: ids[1]}); err == nil {
+		t.Fatal("Passing out of turn should fail")
+	}
+
+	if err := g.pass(GamePlayRequest{PlayerID: ids[0]}); err != nil {
+		t.Fatal(err)
+	} else if g.TurnCount != 1 || g.Finished {
+		t.Fatal("Passing should only move play to the next player")
+	}
+
+	// The state carries the pass, without giving away the rack it was made
+	// from, until the game has finished
+	s := g.getState(ids[1], g.playerList())
+	if m := s.LastMove; m == nil || !m.Pass || m.Player != 0 || m.Rack != nil {
+		t.Errorf("State has last move %+v, expected player 0's pass without its rack", m)
+	} else if s.Passes != 1 || s.BagCount != len(g.TileBag) {
+		t.Errorf("State has %v passes and %v tiles in the bag, expected 1 and %v", s.Passes, s.BagCount, len(g.TileBag))
+	}
+
+	if err := g.pass(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	} else if !g.Finished {
+		t.Fatal("Game should end once every player has passed in a row")
+	}
+	if s = g.getState(ids[0], g.playerList()); s.Passes != 2 || s.LastMove == nil || string(s.LastMove.Rack) != "QDOGSXX" {
+		t.Errorf("State has %v passes and last move %+v, expected 2 and player 1's pass from QDOGSXX", s.Passes, s.LastMove)
+	}
+
+	// Each player loses the value of the tiles left in their hand
+	if s := g.Players[ids[0]].Score; s != -37 {
+		t.Errorf("Player has score %v, expected -37", s)
+	} else if s := g.Players[ids[1]].Score; s != -32 {
+		t.Errorf("Player has score %v, expected -32", s)
+	} else if len(g.Winners) != 1 || g.Winners[0] != 1 {
+		t.Errorf("Winners are %v, expected player 1", g.Winners)
+	}
+}
+
+func TestSwapTiles(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	if err := g.executePlay(GamePlayRequest{PlayerID: ids[0], Swap: true}); err == nil {
+		t.Error("Swapping no tiles should fail")
+	}
+	if err := g.executePlay(GamePlayRequest{PlayerID: ids[0], Tiles: Letters("QQ"), Swap: true}); err == nil {
+		t.Error("Swapping tiles not in hand should fail")
+	} else if string(g.Players[ids[0]].Tiles) != "CATXXXX" {
+		t.Error("Failed swap should not change the player's hand")
+	}
+
+	bagSize := len(g.TileBag)
+	if err := g.executePlay(GamePlayRequest{PlayerID: ids[0], Tiles: Letters("XX"), Swap: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := g.Players[ids[0]]; len(p.Tiles) != maxTiles || string(p.Tiles[:5]) != "CATXX" {
+		t.Errorf("Player has tiles %q after swap, expected CATXX and two new tiles", p.Tiles)
+	} else if len(g.TileBag) != bagSize {
+		t.Errorf("Tile bag has %v tiles, expected %v", len(g.TileBag), bagSize)
+	} else if g.TurnCount != 1 {
+		t.Error("Swapping should take the player's turn")
+	}
+
+	// Swaps need a full hand left in the bag
+	g.TileBag = g.TileBag[:maxTiles-1]
+	err := g.executePlay(GamePlayRequest{PlayerID: ids[1], Tiles: Letters("X"), Swap: true})
+	if err == nil || !strings.Contains(err.Error(), "fewer than 7") {
+		t.Errorf("Swap with %v tiles in bag should fail clearly, got: %v", len(g.TileBag), err)
+	}
+}
+
+func TestResign(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "QQQQQQQ")
+	bagSize := len(g.TileBag)
+
+	// Players can resign out of turn, and their tiles go back in the bag
+	if err := g.resign(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	} else if len(g.TileBag) != bagSize+maxTiles || len(g.Players[ids[1]].Tiles) != 0 {
+		t.Error("Resigned player's tiles should be returned to the bag")
+	} else if g.Finished {
+		t.Fatal("Game should continue while two players remain")
+	}
+
+	if err := g.resign(GamePlayRequest{PlayerID: ids[1]}); err == nil {
+		t.Error("Resigning twice should fail")
+	}
+
+	// Resigned players are skipped
+	if err := g.pass(GamePlayRequest{PlayerID: ids[0]}); err != nil {
+		t.Fatal(err)
+	} else if turn := g.TurnCount % len(g.Players); turn != 2 {
+		t.Fatalf("Turn passed to player %v, expected 2", turn)
+	}
+
+	g.Players[ids[0]].Score = -10
+	if err := g.resign(GamePlayRequest{PlayerID: ids[2]}); err != nil {
+		t.Fatal(err)
+	} else if !g.Finished {
+		t.Fatal("Game should end when only one player remains")
+	} else if len(g.Winners) != 1 || g.Winners[0] != 0 {
+		t.Errorf("Winners are %v, expected the remaining player 0", g.Winners)
+	}
+}
+
+func TestResignTilesAside(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX", "QQQQQQQ")
+	g.Options.ResignedTiles = ResignedTilesAside
+	bagSize := len(g.TileBag)
+
+	if err := g.resign(GamePlayRequest{PlayerID: ids[0]}); err != nil {
+		t.Fatal(err)
+	} else if len(g.TileBag) != bagSize {
+		t.Error("Tiles set aside should not be returned to the bag")
+	} else if turn := g.TurnCount % len(g.Players); turn != 1 {
+		t.Errorf("Turn passed to player %v, expected 1", turn)
+	}
+}
@@ -0,0 +1,168 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stages a player's game can be at
+const (
+	GameWaiting  = "waiting"  // players are still joining
+	GameActive   = "active"   // the game is being played
+	GameFinished = "finished" // the game has ended
+)
+
+// PlayerGame is one of a registered player's games. Games that have been
+// removed from the server only have the player's result.
+type PlayerGame struct {
+	GameID   uuid.UUID  `json:"game_id"`
+	Status   string     `json:"status"`              // GameWaiting, GameActive or GameFinished
+	Players  []string   `json:"players,omitempty"`   // names of everyone in the game, in turn order
+	Score    int        `json:"score"`               // the player's score
+	Won      bool       `json:"won,omitempty"`       // true if the player won the finished game
+	YourTurn bool       `json:"your_turn,omitempty"` // true if the active game is waiting for the player to move
+	PlayerID *uuid.UUID `json:"player_id,omitempty"` // the player's ID in a game still held, only sent to the player themselves
+	Updated  time.Time  `json:"updated"`             // when the game last changed
+}
+
+// PlayerGamesResponse is the format of the response sent to clients when they
+// request a player's games
+type PlayerGamesResponse struct {
+	Games []PlayerGame `json:"games"`
+	Total int          `json:"total"` // number of games matching the request, across every page
+}
+
+// accountGame returns the account's entry in the game, if they are one of its
+// players. The game must be locked by the caller.
+func accountGame(g *ScrabbleGame, accountID uuid.UUID) (PlayerGame, bool) {
+	var entry PlayerGame
+	var found bool
+	for _, p := range g.playerList() {
+		entry.Players = append(entry.Players, p.Name)
+		if p.Account == nil || *p.Account != accountID {
+			continue
+		}
+
+		found = true
+		id := p.ID
+		entry.PlayerID = &id
+		entry.Score = p.Score
+		for _, n := range g.Winners {
+			entry.Won = entry.Won || n == p.Number
+		}
+		entry.YourTurn = g.Active && !g.Finished && g.TurnCount%len(g.Players) == p.Number
+	}
+
+	entry.GameID = g.ID
+	entry.Updated = g.LastActivity
+	switch {
+	case g.Finished:
+		entry.Status = GameFinished
+	case g.Active:
+		entry.Status = GameActive
+	default:
+		entry.Status = GameWaiting
+	}
+	return entry, found
+}
+
+// playerGamesHandler handles requests for a registered player's games, both
+// those still held by the server and finished ones that have since been
+// removed, most recently updated first. The status query parameter limits the
+// list to games at that stage, and pages are chosen with the limit and offset
+// parameters. Players' IDs are only included when the request is made with
+// the account's token, so the list can be used to resume games.
+func (s *Server) playerGamesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, offset, ok := pageParams(w, query)
+	if !ok {
+		return
+	}
+	status := query.Get("status")
+	switch status {
+	case "", GameWaiting, GameActive, GameFinished:
+	default:
+		http.Error(w, "Unknown status '"+status+"'", http.StatusBadRequest)
+		return
+	}
+
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+	requester, ok := s.requestAccount(w, r)
+	if !ok {
+		return
+	}
+	self := requester != nil && requester.ID == a.ID
+
+	list, err := s.games.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	held := make(map[uuid.UUID]bool)
+	var games []PlayerGame
+	for _, g := range list {
+		g.Lock()
+		entry, found := accountGame(g, a.ID)
+		g.Unlock()
+		if !found {
+			continue
+		}
+		held[g.ID] = true
+		if !self {
+			entry.PlayerID = nil
+		}
+		games = append(games, entry)
+	}
+
+	results, err := s.results.GetPlayerResults(a.Username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, res := range results {
+		if held[res.GameID] {
+			continue
+		}
+		games = append(games, PlayerGame{
+			GameID:  res.GameID,
+			Status:  GameFinished,
+			Score:   res.Score,
+			Won:     res.Won,
+			Updated: res.Finished,
+		})
+	}
+
+	filtered := games[:0]
+	for _, g := range games {
+		if status == "" || g.Status == status {
+			filtered = append(filtered, g)
+		}
+	}
+	games = filtered
+
+	sort.Slice(games, func(i, j int) bool {
+		if !games[i].Updated.Equal(games[j].Updated) {
+			return games[i].Updated.After(games[j].Updated)
+		}
+		return games[i].GameID.String() < games[j].GameID.String()
+	})
+
+	start, end := page(len(games), limit, offset)
+	resp, err := json.Marshal(PlayerGamesResponse{Games: games[start:end], Total: len(games)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
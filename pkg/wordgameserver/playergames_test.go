@@ -0,0 +1,116 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPlayerGamesHandler(t *testing.T) {
+	srv := newTestServer(t)
+
+	a, err := newAccount("ashley", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.accounts.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// One game waiting for players, one being played and one that has been
+	// removed since it finished
+	newGame := func(start bool) (*ScrabbleGame, uuid.UUID) {
+		g := createScrabbleGame()
+		g.Lock()
+		defer g.Unlock()
+		srv.adoptGame(g)
+		playerID, err := g.addAccountPlayer(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.addPlayer("ashley2")
+		if start {
+			if err = g.start(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = srv.games.Put(g); err != nil {
+			t.Fatal(err)
+		}
+		return g, playerID
+	}
+	waiting, _ := newGame(false)
+	active, activePlayer := newGame(true)
+	defer active.Stop()
+	removed := uuid.New()
+	srv.results.PutResults([]GameResult{{GameID: removed, Player: "ashley", Score: 321, Won: true, Finished: time.Now().Add(-time.Hour)}})
+
+	// Games the account isn't in aren't listed
+	other := createScrabbleGame()
+	other.addPlayer("ashley3")
+	srv.games.Put(other)
+
+	list := func(query, token string) (int, PlayerGamesResponse) {
+		t.Helper()
+		req, err := http.NewRequest("GET", "/v2/players/ashley/games"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+
+		var resp PlayerGamesResponse
+		if rr.Code == http.StatusOK {
+			if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, resp
+	}
+
+	code, resp := list("", "")
+	if code != http.StatusOK {
+		t.Fatalf("Listing games returned status code %v, expected %v", code, http.StatusOK)
+	}
+	if resp.Total != 3 || len(resp.Games) != 3 {
+		t.Fatalf("Listed %v of %v games, expected 3", len(resp.Games), resp.Total)
+	}
+	want := []struct {
+		id     uuid.UUID
+		status string
+	}{{active.ID, GameActive}, {waiting.ID, GameWaiting}, {removed, GameFinished}}
+	for i, w := range want {
+		if resp.Games[i].GameID != w.id || resp.Games[i].Status != w.status {
+			t.Errorf("Game %v is %v %v, expected %v %v", i, resp.Games[i].Status, resp.Games[i].GameID, w.status, w.id)
+		}
+		if resp.Games[i].PlayerID != nil {
+			t.Error("Player IDs should only be sent to the player")
+		}
+	}
+	if g := resp.Games[0]; !g.YourTurn || len(g.Players) != 2 {
+		t.Errorf("Active game is %+v, expected ashley's turn against ashley2", g)
+	}
+	if g := resp.Games[2]; !g.Won || g.Score != 321 {
+		t.Errorf("Removed game is %+v, expected a win scoring 321", g)
+	}
+
+	// The player can find their ID to resume a game
+	_, resp = list("?status=active", srv.issueToken(a.ID, time.Now()))
+	if len(resp.Games) != 1 || resp.Games[0].PlayerID == nil || *resp.Games[0].PlayerID != activePlayer {
+		t.Errorf("Listed active games %+v, expected game %v with player ID %v", resp.Games, active.ID, activePlayer)
+	}
+
+	if _, resp = list("?limit=1&offset=2", ""); len(resp.Games) != 1 || resp.Games[0].GameID != removed || resp.Total != 3 {
+		t.Errorf("Listed page %+v of %v games, expected only game %v of 3", resp.Games, resp.Total, removed)
+	}
+	if code, _ = list("?status=abandoned", ""); code != http.StatusBadRequest {
+		t.Errorf("Listing games with an unknown status returned status code %v, expected %v", code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,590 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ErrPuzzleNotFound is returned by a PuzzleStore when no puzzle has the
+// requested ID
+var ErrPuzzleNotFound = errors.New("Puzzle does not exist")
+
+// Where puzzles come from
+const (
+	PuzzleGenerated = "generated" // taken from a position in a finished game
+	PuzzleCurated   = "curated"   // set by the server's operators
+)
+
+// equityTolerance is how close an answer's equity must be to the best play's
+// for it to be the best play
+const equityTolerance = 1e-9
+
+// puzzleNamespace names the puzzles generated from finished games, so the same
+// position is only ever made into one puzzle
+var puzzleNamespace = uuid.MustParse("5b0f2d9e-7c1a-4e36-9a58-0d4f3c6b2e71")
+
+// Puzzle is a position on the standard board where the player has to find the
+// best play from the rack. Answers are compared with the plays the move
+// generator finds by equity, as the moves of an analysed game are.
+type Puzzle struct {
+	ID       uuid.UUID     `json:"puzzle_id"`
+	Board    ScrabbleBoard `json:"board"`
+	Rack     Letters       `json:"rack"`              // tiles to play, with blanks as " "
+	Source   string        `json:"source"`            // PuzzleGenerated or PuzzleCurated
+	GameID   *uuid.UUID    `json:"game_id,omitempty"` // game a generated puzzle was taken from
+	Rating   int           `json:"rating"`            // how hard the puzzle is, rated against the players who have answered it
+	Attempts int           `json:"attempts"`          // number of players who have answered it
+	Solves   int           `json:"solves"`            // number of them who found the best play
+	Created  time.Time     `json:"created"`
+}
+
+// PuzzleAttempt is a player's first answer to a puzzle, which is the only one
+// rated
+type PuzzleAttempt struct {
+	PuzzleID   uuid.UUID `json:"puzzle_id"`
+	Player     string    `json:"player"`
+	Solved     bool      `json:"solved"`
+	EquityLost float64   `json:"equity_lost"`
+	Time       time.Time `json:"time"`
+}
+
+// PuzzleStore holds puzzles, the answers players have given to them and the
+// players' puzzle ratings, which are kept apart from their game ratings.
+// Implementations must be safe for concurrent use. A GameStore that also
+// implements PuzzleStore is used for puzzles by the server it is given to.
+type PuzzleStore interface {
+	PutPuzzle(p Puzzle) error                                     // add or replace a puzzle
+	GetPuzzle(id uuid.UUID) (Puzzle, error)                       // retrieve a puzzle, or ErrPuzzleNotFound
+	ListPuzzles() ([]Puzzle, error)                               // every puzzle, oldest first
+	PutPuzzleAttempt(a PuzzleAttempt) error                       // record a player's answer
+	GetPuzzleAttempts(player string) ([]PuzzleAttempt, error)     // the player's answers, oldest first
+	GetPuzzleRatings(players []string) (map[string]Rating, error) // puzzle ratings of those players who have one
+	PutPuzzleRatings(ratings []Rating) error                      // add or replace puzzle ratings
+}
+
+// MemoryPuzzleStore is the default PuzzleStore, which keeps puzzles in memory
+// for the lifetime of the process
+type MemoryPuzzleStore struct {
+	sync.Mutex
+	puzzles  map[uuid.UUID]Puzzle
+	attempts map[string][]PuzzleAttempt
+	ratings  *MemoryRatingStore
+}
+
+// NewMemoryPuzzleStore creates an empty in-memory puzzle store
+func NewMemoryPuzzleStore() *MemoryPuzzleStore {
+	return &MemoryPuzzleStore{
+		puzzles:  make(map[uuid.UUID]Puzzle),
+		attempts: make(map[string][]PuzzleAttempt),
+		ratings:  NewMemoryRatingStore(),
+	}
+}
+
+// PutPuzzle adds the puzzle to the store, replacing any with the same ID
+func (ms *MemoryPuzzleStore) PutPuzzle(p Puzzle) error {
+	ms.Lock()
+	defer ms.Unlock()
+	p.Board = p.Board.Clone()
+	ms.puzzles[p.ID] = p
+	return nil
+}
+
+// GetPuzzle retrieves the puzzle with the ID
+func (ms *MemoryPuzzleStore) GetPuzzle(id uuid.UUID) (Puzzle, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	p, ok := ms.puzzles[id]
+	if !ok {
+		return Puzzle{}, ErrPuzzleNotFound
+	}
+	p.Board = p.Board.Clone()
+	return p, nil
+}
+
+// ListPuzzles returns every puzzle, oldest first
+func (ms *MemoryPuzzleStore) ListPuzzles() ([]Puzzle, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	puzzles := make([]Puzzle, 0, len(ms.puzzles))
+	for _, p := range ms.puzzles {
+		p.Board = p.Board.Clone()
+		puzzles = append(puzzles, p)
+	}
+	sort.Slice(puzzles, func(i, j int) bool {
+		return puzzles[i].Created.Before(puzzles[j].Created)
+	})
+	return puzzles, nil
+}
+
+// PutPuzzleAttempt records the player's answer
+func (ms *MemoryPuzzleStore) PutPuzzleAttempt(a PuzzleAttempt) error {
+	ms.Lock()
+	defer ms.Unlock()
+	ms.attempts[a.Player] = append(ms.attempts[a.Player], a)
+	return nil
+}
+
+// GetPuzzleAttempts returns the player's answers, oldest first
+func (ms *MemoryPuzzleStore) GetPuzzleAttempts(player string) ([]PuzzleAttempt, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	return append([]PuzzleAttempt(nil), ms.attempts[player]...), nil
+}
+
+// GetPuzzleRatings retrieves the puzzle ratings of the players who have one
+func (ms *MemoryPuzzleStore) GetPuzzleRatings(players []string) (map[string]Rating, error) {
+	return ms.ratings.GetRatings(players)
+}
+
+// PutPuzzleRatings adds the puzzle ratings to the store, replacing any for the
+// same players
+func (ms *MemoryPuzzleStore) PutPuzzleRatings(ratings []Rating) error {
+	return ms.ratings.PutRatings(ratings)
+}
+
+// PuzzleRequest is the format of the request operators send to add a puzzle of
+// their own
+type PuzzleRequest struct {
+	Board  []string `json:"board"`            // rows from top to bottom, with dots for empty squares and lowercase letters for blanks
+	Rack   string   `json:"rack"`             // tiles to play, with question marks for blanks
+	Rating int      `json:"rating,omitempty"` // how hard the puzzle is thought to be, the initial rating if 0
+}
+
+// PuzzleResult is the format of the response sent to a player with how their
+// answer to a puzzle compares with the best play
+type PuzzleResult struct {
+	PuzzleID     uuid.UUID `json:"puzzle_id"`
+	Words        []string  `json:"words"`
+	Score        int       `json:"score"`
+	Equity       float64   `json:"equity"`
+	Best         Solution  `json:"best"` // play with the best equity
+	BestEquity   float64   `json:"best_equity"`
+	EquityLost   float64   `json:"equity_lost"`
+	Solved       bool      `json:"solved"`        // true if no play has a better equity than the answer
+	Rated        bool      `json:"rated"`         // false if the player had already answered the puzzle, which leaves the ratings as they were
+	Rating       int       `json:"rating"`        // player's puzzle rating after the answer
+	PuzzleRating int       `json:"puzzle_rating"` // puzzle's rating after the answer
+}
+
+// answerPuzzle checks the play can be made from the puzzle's rack and forms
+// only words given, and compares it with the best play available
+func answerPuzzle(p Puzzle, j GamePlayRequest, words *dictionary.DAWG) (PuzzleResult, error) {
+	j, err := resolvePlacements(p.Board, j)
+	if err != nil {
+		return PuzzleResult{}, err
+	} else if j.Swap {
+		return PuzzleResult{}, errors.New("Puzzles are answered with a play, not a swap")
+	} else if !engine.HasTiles(p.Rack, j.Tiles) {
+		return PuzzleResult{}, errors.New("Tiles played are not all in the puzzle's rack")
+	}
+
+	v := standardVariant
+	board, placed, formed, err := p.Board.LayTiles(j.play(), v.tiles)
+	if err != nil {
+		return PuzzleResult{}, err
+	} else if err = p.Board.CheckPlacement(placed, formed); err != nil {
+		return PuzzleResult{}, err
+	}
+
+	result := PuzzleResult{PuzzleID: p.ID, Words: make([]string, len(formed))}
+	var invalid []string
+	for i, w := range formed {
+		result.Words[i] = w.Word
+		if !words.Valid(w.Word) {
+			invalid = append(invalid, w.Word)
+		}
+	}
+	if len(invalid) > 0 {
+		return PuzzleResult{}, errors.New("Words not in dictionary: " + strings.Join(invalid, ", "))
+	}
+
+	result.Score = board.ScorePlay(placed, formed, v.bingo)
+	result.Equity = float64(result.Score) + engine.LeaveValue(leave(p.Rack, j.Tiles))
+
+	// Equities of the same play can differ in their last bits, as the leave
+	// is valued from the tiles in a different order
+	best, bestEquity, _ := bestPlay(p.Board, p.Rack, v, words)
+	if best == nil || result.Equity > bestEquity-equityTolerance {
+		result.Best = Solution{
+			Play:  GamePlayRequest{StartPos: j.StartPos, EndPos: j.EndPos, Tiles: j.Tiles, Blanks: j.Blanks},
+			Words: result.Words,
+			Score: result.Score,
+		}
+		result.BestEquity = result.Equity
+		result.Solved = true
+	} else {
+		result.Best, result.BestEquity = *best, bestEquity
+		result.EquityLost = bestEquity - result.Equity
+	}
+	return result, nil
+}
+
+// newPuzzle creates a puzzle from the position, as long as a play can be made
+// from the rack
+func newPuzzle(id uuid.UUID, board ScrabbleBoard, rack Letters, source string, words *dictionary.DAWG) (Puzzle, error) {
+	if len(rack) == 0 || len(rack) > maxTiles {
+		return Puzzle{}, errors.New("Rack must have between 1 and " + strconv.Itoa(maxTiles) + " tiles")
+	} else if best, _, _ := bestPlay(board, rack, standardVariant, words); best == nil {
+		return Puzzle{}, errors.New("No play can be made from the rack")
+	}
+	return Puzzle{
+		ID:      id,
+		Board:   board,
+		Rack:    append(Letters(nil), rack...),
+		Source:  source,
+		Rating:  initialRating,
+		Created: time.Now(),
+	}, nil
+}
+
+// puzzleWords returns the words puzzles are solved with, which are those of the
+// server's dictionary, or nil if it can't be used to find plays
+func (s *Server) puzzleWords() *dictionary.DAWG {
+	if wg, ok := s.validator.(WordGenerator); ok {
+		return wg.DAWG()
+	}
+	return nil
+}
+
+// generatePuzzle makes a puzzle from the position before a move in one of the
+// server's finished games, chosen at random, with the rack the move was made
+// from. Positions already made into puzzles are left out, as are those the
+// dictionary has no play for. Returns ErrPuzzleNotFound if there are none left.
+func (s *Server) generatePuzzle(words *dictionary.DAWG) (Puzzle, error) {
+	games, err := s.games.List()
+	if err != nil {
+		return Puzzle{}, err
+	}
+
+	type position struct {
+		replay GameReplay
+		move   int
+	}
+	var positions []position
+	for _, g := range games {
+		g.Lock()
+		if g.Finished && g.Options.standard() {
+			replay := g.replay()
+			for i, m := range replay.Moves {
+				if len(m.Rack) > 0 && !m.Resign {
+					positions = append(positions, position{replay, i})
+				}
+			}
+		}
+		g.Unlock()
+	}
+	rand.Shuffle(len(positions), func(i, j int) {
+		positions[i], positions[j] = positions[j], positions[i]
+	})
+
+	for _, pos := range positions {
+		id := uuid.NewSHA1(puzzleNamespace, []byte(pos.replay.GameID.String()+"/"+strconv.Itoa(pos.move)))
+		if _, err = s.puzzles.GetPuzzle(id); err == nil {
+			continue
+		} else if err != ErrPuzzleNotFound {
+			return Puzzle{}, err
+		}
+
+		state, err := ReplayGame(pos.replay, pos.move)
+		if err != nil {
+			continue
+		}
+		p, err := newPuzzle(id, state.Board, pos.replay.Moves[pos.move].Rack, PuzzleGenerated, words)
+		if err != nil {
+			continue
+		}
+		gameID := pos.replay.GameID
+		p.GameID = &gameID
+		if err = s.puzzles.PutPuzzle(p); err != nil {
+			return Puzzle{}, err
+		}
+		return p, nil
+	}
+	return Puzzle{}, ErrPuzzleNotFound
+}
+
+// puzzleRating returns the player's puzzle rating, or the initial rating if
+// they haven't answered a puzzle
+func (s *Server) puzzleRating(player string) (Rating, error) {
+	ratings, err := s.puzzles.GetPuzzleRatings([]string{player})
+	if err != nil {
+		return Rating{}, err
+	}
+	if r, ok := ratings[player]; ok {
+		return r, nil
+	}
+	return Rating{Player: player, Rating: initialRating}, nil
+}
+
+// nextPuzzle returns the puzzle the player hasn't answered whose rating is
+// closest to theirs, generating a new one if they have answered them all. The
+// caller must hold the server's puzzle lock.
+func (s *Server) nextPuzzle(player string, words *dictionary.DAWG) (Puzzle, error) {
+	attempts, err := s.puzzles.GetPuzzleAttempts(player)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	answered := make(map[uuid.UUID]bool, len(attempts))
+	for _, a := range attempts {
+		answered[a.PuzzleID] = true
+	}
+
+	r, err := s.puzzleRating(player)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	puzzles, err := s.puzzles.ListPuzzles()
+	if err != nil {
+		return Puzzle{}, err
+	}
+
+	distance := func(p Puzzle) int {
+		return max(p.Rating-r.Rating, r.Rating-p.Rating)
+	}
+	var next *Puzzle
+	for i, p := range puzzles {
+		if !answered[p.ID] && (next == nil || distance(p) < distance(*next)) {
+			next = &puzzles[i]
+		}
+	}
+	if next != nil {
+		return *next, nil
+	}
+	return s.generatePuzzle(words)
+}
+
+// ratePuzzle adjusts the player's puzzle rating and the puzzle's rating after
+// the player's first answer to it, as if they had played a game the player won
+// if they found the best play and lost otherwise
+func ratePuzzle(r Rating, p *Puzzle, solved bool) Rating {
+	standings := []int{0, 1}
+	if solved {
+		standings = []int{1, 0}
+	}
+	updated := updateRatings([]Rating{r, {Player: p.ID.String(), Rating: p.Rating, Games: p.Attempts}}, standings)
+
+	p.Rating = updated[1].Rating
+	p.Attempts++
+	if solved {
+		p.Solves++
+	}
+	return updated[0]
+}
+
+// writePuzzle responds with the puzzle
+func writePuzzle(w http.ResponseWriter, p Puzzle, status int) {
+	resp, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+// getPuzzle retrieves the puzzle in the request's path, responding with an
+// error if it can't
+func (s *Server) getPuzzle(w http.ResponseWriter, r *http.Request) (Puzzle, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["puzzle"])
+	if err != nil {
+		http.Error(w, "Invalid puzzle ID", http.StatusBadRequest)
+		return Puzzle{}, false
+	}
+
+	p, err := s.puzzles.GetPuzzle(id)
+	if err == ErrPuzzleNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return p, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return p, false
+	}
+	return p, true
+}
+
+// nextPuzzleHandler handles requests from registered players for a puzzle to
+// answer, close to their puzzle rating
+func (s *Server) nextPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	words := s.puzzleWords()
+	if words == nil {
+		http.Error(w, "Server's dictionary can't be used to find plays", http.StatusBadRequest)
+		return
+	}
+
+	s.puzzleMu.Lock()
+	p, err := s.nextPuzzle(a.Username, words)
+	s.puzzleMu.Unlock()
+	if err == ErrPuzzleNotFound {
+		http.Error(w, "No puzzles left to answer", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writePuzzle(w, p, http.StatusOK)
+}
+
+// getPuzzleHandler handles requests for the puzzle identified by the request's
+// path
+func (s *Server) getPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	if p, ok := s.getPuzzle(w, r); ok {
+		writePuzzle(w, p, http.StatusOK)
+	}
+}
+
+// answerPuzzleHandler handles answers from registered players to the puzzle
+// identified by the request's path. The player's first answer to a puzzle
+// changes their puzzle rating and the puzzle's, and later ones are only
+// scored.
+func (s *Server) answerPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	var j GamePlayRequest
+
+	a, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	words := s.puzzleWords()
+	if words == nil {
+		http.Error(w, "Server's dictionary can't be used to find plays", http.StatusBadRequest)
+		return
+	}
+
+	// Answers are checked before puzzleMu is taken, as finding the best play
+	// is slow and a puzzle's board and rack never change
+	p, ok := s.getPuzzle(w, r)
+	if !ok {
+		return
+	}
+	result, err := answerPuzzle(p, j, words)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = s.ratePuzzleAnswer(a.Username, p.ID, &result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// ratePuzzleAnswer changes the player's puzzle rating and the puzzle's for the
+// result if it is the player's first answer to the puzzle, filling in the
+// result's ratings either way. The puzzle is read again while answers are
+// serialized, so ratings changed by answers made meanwhile aren't lost.
+func (s *Server) ratePuzzleAnswer(player string, id uuid.UUID, result *PuzzleResult) error {
+	s.puzzleMu.Lock()
+	defer s.puzzleMu.Unlock()
+
+	p, err := s.puzzles.GetPuzzle(id)
+	if err != nil {
+		return err
+	}
+	attempts, err := s.puzzles.GetPuzzleAttempts(player)
+	if err != nil {
+		return err
+	}
+	result.Rated = true
+	for _, at := range attempts {
+		if at.PuzzleID == p.ID {
+			result.Rated = false
+		}
+	}
+
+	rating, err := s.puzzleRating(player)
+	if err != nil {
+		return err
+	}
+	if result.Rated {
+		rating = ratePuzzle(rating, &p, result.Solved)
+		err = s.puzzles.PutPuzzleAttempt(PuzzleAttempt{
+			PuzzleID:   p.ID,
+			Player:     player,
+			Solved:     result.Solved,
+			EquityLost: result.EquityLost,
+			Time:       time.Now(),
+		})
+		if err == nil {
+			err = s.puzzles.PutPuzzleRatings([]Rating{rating})
+		}
+		if err == nil {
+			err = s.puzzles.PutPuzzle(p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	result.Rating, result.PuzzleRating = rating.Rating, p.Rating
+	return nil
+}
+
+// createPuzzleHandler handles requests from operators to add a puzzle of their
+// own, which players are given alongside those generated from games
+func (s *Server) createPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	var j PuzzleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	words := s.puzzleWords()
+	if words == nil {
+		http.Error(w, "Server's dictionary can't be used to find plays", http.StatusBadRequest)
+		return
+	}
+
+	board := standardVariant.newBoard()
+	if err := positionBoard(board, j.Board); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rack, err := positionTiles(j.Rack)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := newPuzzle(uuid.New(), board, rack, PuzzleCurated, words)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if j.Rating != 0 {
+		p.Rating = j.Rating
+	}
+
+	if err = s.puzzles.PutPuzzle(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writePuzzle(w, p, http.StatusCreated)
+}
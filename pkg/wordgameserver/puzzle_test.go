@@ -0,0 +1,207 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/google/uuid"
+)
+
+func TestPuzzles(t *testing.T) {
+	wl, err := dictionary.NewWordList(strings.NewReader("CAT\nCATS\nDOG\nDOGS\nAD\nTA\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.AdminToken = "s3cret"
+	srv, err := NewServer(cfg, wl, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := newAccount("ashley", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.accounts.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+	token := srv.issueToken(a.ID, time.Now())
+
+	send := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			var err error
+			if payload, err = json.Marshal(body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req, err := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("GET", "/v2/puzzles/next", token, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting a puzzle without any returned status code %v, expected %v", rr.Code, http.StatusNotFound)
+	}
+
+	// Operators add a puzzle with CAT on the board, which can be made CATS or
+	// played through for AD
+	rows := make([]string, 15)
+	for i := range rows {
+		rows[i] = strings.Repeat(".", 15)
+	}
+	rows[7] = "......CAT......"
+	if rr := send("POST", "/admin/puzzles", "", PuzzleRequest{Board: rows, Rack: "DS"}); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Adding a puzzle without the admin token returned status code %v, expected %v", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := send("POST", "/admin/puzzles", cfg.AdminToken, PuzzleRequest{Board: rows, Rack: "XX"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Adding a puzzle without a play returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	rr := send("POST", "/admin/puzzles", cfg.AdminToken, PuzzleRequest{Board: rows, Rack: "DS"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Adding a puzzle returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var curated Puzzle
+	if err = json.NewDecoder(rr.Body).Decode(&curated); err != nil {
+		t.Fatal(err)
+	} else if curated.Source != PuzzleCurated || curated.Rating != initialRating || string(curated.Rack) != "DS" {
+		t.Errorf("Added puzzle %+v, expected a curated puzzle with rack DS", curated)
+	}
+
+	if rr = send("GET", "/v2/puzzles/next", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Getting a puzzle without an account returned status code %v, expected %v", rr.Code, http.StatusUnauthorized)
+	}
+	rr = send("GET", "/v2/puzzles/next", token, nil)
+	var next Puzzle
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Getting a puzzle returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	} else if err = json.NewDecoder(rr.Body).Decode(&next); err != nil {
+		t.Fatal(err)
+	} else if next.ID != curated.ID {
+		t.Errorf("Got puzzle %v, expected the curated puzzle %v", next.ID, curated.ID)
+	}
+
+	answer := func(j GamePlayRequest) (PuzzleResult, int) {
+		rr := send("POST", "/v2/puzzles/"+curated.ID.String()+"/answers", token, j)
+		var result PuzzleResult
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return result, rr.Code
+	}
+
+	ad := GamePlayRequest{StartPos: SquareCoordinate{Row: 8, Col: 7}, EndPos: SquareCoordinate{Row: 8, Col: 7}, Tiles: Letters("D")}
+	cats := GamePlayRequest{StartPos: SquareCoordinate{Row: 7, Col: 9}, EndPos: SquareCoordinate{Row: 7, Col: 9}, Tiles: Letters("S")}
+	dog := GamePlayRequest{StartPos: SquareCoordinate{Row: 8, Col: 7}, EndPos: SquareCoordinate{Row: 8, Col: 9}, Tiles: Letters("DOG")}
+	if _, c := answer(dog); c != http.StatusBadRequest {
+		t.Errorf("Answering with tiles not in the rack returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+
+	// Making CATS scores more, but keeping the S makes AD the better play
+	result, c := answer(cats)
+	if c != http.StatusOK {
+		t.Fatalf("Answering returned status code %v, expected %v", c, http.StatusOK)
+	} else if result.Solved || !result.Rated || result.Score != 6 || result.EquityLost <= 0 {
+		t.Errorf("Answering CATS returned %+v, expected an unsolved rated answer scoring 6", result)
+	} else if result.Best.Score != 3 || len(result.Best.Words) != 1 || result.Best.Words[0] != "AD" {
+		t.Errorf("Best play is %+v, expected AD", result.Best)
+	} else if result.Rating != initialRating-ratingK/2 || result.PuzzleRating != initialRating+ratingK/2 {
+		t.Errorf("Ratings are %v for the player and %v for the puzzle, expected %v and %v", result.Rating, result.PuzzleRating, initialRating-ratingK/2, initialRating+ratingK/2)
+	}
+
+	// Only the first answer is rated
+	result, c = answer(ad)
+	if c != http.StatusOK {
+		t.Fatalf("Answering again returned status code %v, expected %v", c, http.StatusOK)
+	} else if !result.Solved || result.Rated || result.EquityLost != 0 || result.Rating != initialRating-ratingK/2 {
+		t.Errorf("Answering AD returned %+v, expected a solved answer leaving the ratings alone", result)
+	}
+	if rr = send("GET", "/v2/puzzles/"+curated.ID.String(), "", nil); rr.Code != http.StatusOK {
+		t.Fatalf("Getting the puzzle returned status code %v, expected %v", rr.Code, http.StatusOK)
+	} else if err = json.NewDecoder(rr.Body).Decode(&curated); err != nil {
+		t.Fatal(err)
+	} else if curated.Attempts != 1 || curated.Solves != 0 || curated.Rating != initialRating+ratingK/2 {
+		t.Errorf("Puzzle has %v attempts, %v solves and rating %v, expected 1, 0 and %v", curated.Attempts, curated.Solves, curated.Rating, initialRating+ratingK/2)
+	}
+	if rr = send("GET", "/v2/puzzles/"+uuid.NewString(), "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Getting an unknown puzzle returned status code %v, expected %v", rr.Code, http.StatusNotFound)
+	}
+
+	// Answers from several players at once each count towards the puzzle's
+	// rating, even though they are checked before answers are serialized
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		other, err := newAccount("player"+strconv.Itoa(i), "hunter22")
+		if err != nil {
+			t.Fatal(err)
+		} else if err = srv.accounts.CreateAccount(other); err != nil {
+			t.Fatal(err)
+		}
+		otherToken := srv.issueToken(other.ID, time.Now())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rr := send("POST", "/v2/puzzles/"+curated.ID.String()+"/answers", otherToken, ad); rr.Code != http.StatusOK {
+				t.Errorf("Answering at once returned status code %v, expected %v", rr.Code, http.StatusOK)
+			}
+		}()
+	}
+	wg.Wait()
+	if p, err := srv.puzzles.GetPuzzle(curated.ID); err != nil {
+		t.Fatal(err)
+	} else if p.Attempts != 5 || p.Solves != 4 {
+		t.Errorf("Puzzle has %v attempts and %v solves after answers at once, expected 5 and 4", p.Attempts, p.Solves)
+	}
+
+	// Once every puzzle has been answered, one is generated from a position
+	// in a finished game
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	srv.games.Put(g)
+	err = g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uuid.UUID{ids[1], ids[0]} {
+		if err = g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr = send("GET", "/v2/puzzles/next", token, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Getting a generated puzzle returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	} else if err = json.NewDecoder(rr.Body).Decode(&next); err != nil {
+		t.Fatal(err)
+	} else if next.Source != PuzzleGenerated || next.GameID == nil || *next.GameID != g.ID {
+		t.Errorf("Got puzzle %+v, expected one generated from game %v", next, g.ID)
+	} else if _, err = srv.puzzles.GetPuzzle(next.ID); err != nil {
+		t.Errorf("Generated puzzle wasn't saved: %v", err)
+	}
+
+	if r, _ := srv.puzzleRating("ashley"); r.Rating != initialRating-ratingK/2 || r.Games != 1 {
+		t.Errorf("Player's puzzle rating is %+v, expected %v after 1 puzzle", r, initialRating-ratingK/2)
+	}
+}
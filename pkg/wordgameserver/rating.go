@@ -0,0 +1,165 @@
+package wordgameserver
+
+import (
+	"log"
+	"math"
+	"sync"
+)
+
+// Elo parameters. Ratings start at initialRating and move by up to ratingK
+// points a game.
+const (
+	initialRating = 1500
+	ratingK       = 32
+)
+
+// Rating is a player's Elo rating. Only players with an account are rated, so
+// players are identified by their account's ID.
+type Rating struct {
+	Player string `json:"player"` // ID of the player's account
+	Rating int    `json:"rating"`
+	Games  int    `json:"games"` // number of rated games the player has finished
+}
+
+// RatingStore holds the ratings of players. Implementations must be safe for
+// concurrent use. A GameStore that also implements RatingStore is used for
+// ratings by the server it is given to.
+type RatingStore interface {
+	GetRatings(players []string) (map[string]Rating, error) // ratings of those players who have one
+	PutRatings(ratings []Rating) error                      // add or replace ratings
+}
+
+// MemoryRatingStore is the default RatingStore, which keeps ratings in memory
+// for the lifetime of the process
+type MemoryRatingStore struct {
+	sync.Mutex
+	ratings map[string]Rating
+}
+
+// NewMemoryRatingStore creates an empty in-memory rating store
+func NewMemoryRatingStore() *MemoryRatingStore {
+	return &MemoryRatingStore{
+		ratings: make(map[string]Rating),
+	}
+}
+
+// GetRatings retrieves the ratings of the players who have one
+func (ms *MemoryRatingStore) GetRatings(players []string) (map[string]Rating, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	ratings := make(map[string]Rating)
+	for _, p := range players {
+		if r, ok := ms.ratings[p]; ok {
+			ratings[p] = r
+		}
+	}
+	return ratings, nil
+}
+
+// PutRatings adds the ratings to the store, replacing any for the same players
+func (ms *MemoryRatingStore) PutRatings(ratings []Rating) error {
+	ms.Lock()
+	for _, r := range ratings {
+		ms.ratings[r.Player] = r
+	}
+	ms.Unlock()
+	return nil
+}
+
+// playerRating returns the player's current rating, or the initial rating if
+// they don't have one
+func playerRating(store RatingStore, player string) (Rating, error) {
+	ratings, err := store.GetRatings([]string{player})
+	if err != nil {
+		return Rating{}, err
+	}
+	if r, ok := ratings[player]; ok {
+		return r, nil
+	}
+	return Rating{Player: player, Rating: initialRating}, nil
+}
+
+// expectedScore is the score, between 0 for a loss and 1 for a win, a player
+// rated a is expected to get against one rated b
+func expectedScore(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// updateRatings adjusts the ratings of players who have finished a game
+// together. Each player's standing is given in the same order as their
+// ratings, and a higher standing beat a lower one. Games with more than two
+// players are scored as a match between each pair of players, with each
+// player's change scaled down so a game counts the same however many play it.
+func updateRatings(ratings []Rating, standings []int) []Rating {
+	updated := make([]Rating, len(ratings))
+	for i, r := range ratings {
+		change := 0.0
+		for j, opponent := range ratings {
+			if i == j {
+				continue
+			}
+			score := 0.5
+			if standings[i] > standings[j] {
+				score = 1
+			} else if standings[i] < standings[j] {
+				score = 0
+			}
+			change += ratingK * (score - expectedScore(r.Rating, opponent.Rating))
+		}
+		if len(ratings) > 2 {
+			change /= float64(len(ratings) - 1)
+		}
+
+		r.Rating += int(math.Round(change))
+		r.Games++
+		updated[i] = r
+	}
+	return updated
+}
+
+// rate updates the ratings of the game's players once the event has finished
+// it, if the game is rated. Only players linked to an account are rated, so
+// bots and guests aren't. The game must be locked by the caller.
+func (sg *ScrabbleGame) rate(e Event) {
+	if !sg.Options.Rated || sg.ratings == nil {
+		return
+	}
+
+	var accounts []string
+	var players []*Player
+	for _, p := range sg.playerList() {
+		if !p.Bot && p.Account != nil {
+			accounts = append(accounts, p.Account.String())
+			players = append(players, p)
+		}
+	}
+	if len(players) < 2 {
+		return
+	}
+
+	current, err := sg.ratings.GetRatings(accounts)
+	if err != nil {
+		log.Printf("Failed to get ratings for game %v: %v", sg.ID, err)
+		return
+	}
+
+	// Players who resigned or lost on time finish below everyone else,
+	// otherwise the highest score wins
+	ratings := make([]Rating, len(players))
+	standings := make([]int, len(players))
+	for i, p := range players {
+		r, ok := current[accounts[i]]
+		if !ok {
+			r = Rating{Player: accounts[i], Rating: initialRating}
+		}
+		ratings[i] = r
+		standings[i] = p.Score
+		if p.Resigned || (e.Type == ClockExpired && e.Player == p.ID) {
+			standings[i] = math.MinInt
+		}
+	}
+
+	if err = sg.ratings.PutRatings(updateRatings(ratings, standings)); err != nil {
+		log.Printf("Failed to save ratings for game %v: %v", sg.ID, err)
+	}
+}
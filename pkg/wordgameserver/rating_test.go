@@ -0,0 +1,100 @@
+package wordgameserver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateRatings(t *testing.T) {
+	tests := []struct {
+		name      string
+		ratings   []int
+		standings []int
+		want      []int
+	}{
+		{"even win", []int{1500, 1500}, []int{300, 250}, []int{1516, 1484}},
+		{"draw", []int{1500, 1500}, []int{300, 300}, []int{1500, 1500}},
+		{"upset", []int{1400, 1800}, []int{300, 250}, []int{1429, 1771}},
+		{"three players", []int{1500, 1500, 1500}, []int{300, 250, math.MinInt}, []int{1516, 1500, 1484}},
+	}
+
+	for _, test := range tests {
+		ratings := make([]Rating, len(test.ratings))
+		for i, r := range test.ratings {
+			ratings[i] = Rating{Rating: r}
+		}
+		for i, r := range updateRatings(ratings, test.standings) {
+			if r.Rating != test.want[i] || r.Games != 1 {
+				t.Errorf("%v: player %v has rating %v after %v games, expected %v after 1", test.name, i, r.Rating, r.Games, test.want[i])
+			}
+		}
+	}
+}
+
+func TestRatedGame(t *testing.T) {
+	srv := newTestServer(t)
+	var accounts []Account
+	for _, name := range []string{"ashley1", "ashley2"} {
+		a, err := newAccount(name, "hunter22")
+		if err != nil {
+			t.Fatal(err)
+		}
+		accounts = append(accounts, a)
+	}
+	// A rating under a player's name isn't theirs, only one under their account
+	srv.ratings.PutRatings([]Rating{
+		{Player: accounts[0].ID.String(), Rating: 1600, Games: 3},
+		{Player: "ashley3", Rating: 1700, Games: 3},
+	})
+
+	g := createScrabbleGame()
+	g.Options.Rated = true
+	g.Lock()
+	srv.adoptGame(g)
+	resigner, _ := g.addAccountPlayer(accounts[0])
+	g.addAccountPlayer(accounts[1])
+	guest, _ := g.addPlayer("ashley3")
+	entry, _ := srv.lobbyGame(g)
+	err := g.start()
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	// Players are shown with their rating when they joined, and guests aren't
+	// rated
+	if entry.Rating != 1550 {
+		t.Errorf("Lobby shows an average rating of %v, expected 1550", entry.Rating)
+	}
+	g.Lock()
+	for _, p := range g.playerList() {
+		if want := map[string]int{"ashley1": 1600, "ashley2": initialRating}[p.Name]; p.Rating != want {
+			t.Errorf("Player %v joined with rating %v, expected %v", p.Name, p.Rating, want)
+		}
+	}
+	g.Unlock()
+
+	// Resigning loses, however the scores stand
+	for _, id := range []uuid.UUID{guest, resigner} {
+		if _, err = g.request(GamePlayRequest{PlayerID: id, Type: resignRequest}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ratings, err := srv.ratings.GetRatings([]string{accounts[0].ID.String(), accounts[1].ID.String(), "ashley3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Rating{
+		accounts[0].ID.String(): {Player: accounts[0].ID.String(), Rating: 1580, Games: 4},
+		accounts[1].ID.String(): {Player: accounts[1].ID.String(), Rating: 1520, Games: 1},
+		"ashley3":               {Player: "ashley3", Rating: 1700, Games: 3},
+	}
+	for id, r := range want {
+		if ratings[id] != r {
+			t.Errorf("Rating after the game is %+v, expected %+v", ratings[id], r)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Emotes players can react to a move with
+var Emotes = []string{"👏", "😮", "😂", "😬", "🔥", "🤔"}
+
+// Reaction is a player's emote in response to the most recent move
+type Reaction struct {
+	Player int       `json:"player"` // number of the player reacting
+	Move   int       `json:"move"`   // position of the move reacted to in the game's history, from 0
+	Emote  string    `json:"emote"`  // one of Emotes
+	Time   time.Time `json:"time"`
+}
+
+// ReactionRequest is the format of the request a client sends to react to the
+// most recent move
+type ReactionRequest struct {
+	GameID   uuid.UUID `json:"game_id,omitempty"`
+	PlayerID uuid.UUID `json:"player_id"`
+	Emote    string    `json:"emote"`
+}
+
+// react adds the player's reaction to the most recent move and sends every
+// subscribed player the new state. Each player has one reaction to a move, so
+// reacting again replaces it. Reactions are shown until the next thing happens
+// in the game, and aren't saved with it.
+func (sg *ScrabbleGame) react(j ReactionRequest, at time.Time) (Reaction, error) {
+	sg.Lock()
+	defer sg.Unlock()
+
+	p, ok := sg.Players[j.PlayerID]
+	if !ok {
+		return Reaction{}, errors.New("No player with that ID in game")
+	} else if len(sg.history) == 0 {
+		return Reaction{}, errors.New("No move to react to")
+	}
+	valid := false
+	for _, e := range Emotes {
+		valid = valid || e == j.Emote
+	}
+	if !valid {
+		return Reaction{}, errors.New("Unknown emote '" + j.Emote + "'")
+	}
+
+	r := Reaction{Player: p.Number, Move: len(sg.history) - 1, Emote: j.Emote, Time: at}
+	reactions := sg.reactions[:0]
+	for _, other := range sg.reactions {
+		if other.Player != r.Player {
+			reactions = append(reactions, other)
+		}
+	}
+	sg.reactions = append(reactions, r)
+
+	sg.broadcast(sg.playerList())
+	return r, nil
+}
+
+// reactHandler handles requests from players to react to the most recent move
+// of a game, identified by the request's path or its game_id. It responds with
+// the reaction as made.
+func (s *Server) reactHandler(w http.ResponseWriter, r *http.Request) {
+	var j ReactionRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if j.GameID, ok = pathGameID(w, r); !ok {
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+	reaction, err := g.react(j, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := json.Marshal(reaction)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
@@ -0,0 +1,79 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReactHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	srv, err := NewServer(DefaultConfig(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.games.Put(g)
+
+	react := func(j ReactionRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		b, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games/"+g.ID.String()+"/reactions", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := react(ReactionRequest{PlayerID: ids[1], Emote: Emotes[0]}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Reaction before any move returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	g.Lock()
+	err = g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := react(ReactionRequest{PlayerID: ids[1], Emote: "🙈"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Unknown emote returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := react(ReactionRequest{PlayerID: g.ID, Emote: Emotes[0]}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Reaction from an unknown player returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Reacting again replaces the player's reaction
+	for _, e := range Emotes[:2] {
+		if rr := react(ReactionRequest{PlayerID: ids[1], Emote: e}); rr.Code != http.StatusCreated {
+			t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+		}
+	}
+	g.Lock()
+	state := g.getState(ids[0], g.playerList())
+	g.Unlock()
+	if len(state.Reactions) != 1 || state.Reactions[0].Emote != Emotes[1] || state.Reactions[0].Move != 0 {
+		t.Errorf("State has reactions %+v, expected the second player's latest", state.Reactions)
+	}
+
+	// Reactions last until the next move
+	g.Lock()
+	defer g.Unlock()
+	if err = g.pass(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	} else if state = g.getState(ids[0], g.playerList()); len(state.Reactions) != 0 {
+		t.Errorf("State has reactions %+v after the next move, expected none", state.Reactions)
+	}
+}
@@ -0,0 +1,59 @@
+package wordgameserver
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// reapIdleGames periodically removes games from the store that have had no
+// activity for longer than the TTL, whether or not they were ever started,
+// until the context is cancelled
+func reapIdleGames(ctx context.Context, games GameStore, ttl time.Duration) {
+	// Check often enough that games don't outlive the TTL by much
+	interval := ttl / 10
+	if interval < time.Second {
+		interval = time.Second
+	} else if interval > time.Hour {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := removeIdleGames(games, ttl); err != nil {
+				log.Printf("Failed to remove idle games: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// removeIdleGames deletes and stops every game whose last activity was longer
+// ago than the TTL, freeing their join codes
+func removeIdleGames(games GameStore, ttl time.Duration) error {
+	list, err := games.List()
+	if err != nil {
+		return err
+	}
+
+	for _, g := range list {
+		g.Lock()
+		idle, code := time.Since(g.LastActivity) > ttl, g.JoinCode
+		g.Unlock()
+
+		if !idle {
+			continue
+		}
+		if err = games.Delete(g.ID); err != nil {
+			return err
+		}
+		releaseJoinCode(games, code)
+		g.Stop()
+	}
+	return nil
+}
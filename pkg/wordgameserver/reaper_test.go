@@ -0,0 +1,31 @@
+package wordgameserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemoveIdleGames(t *testing.T) {
+	ms := NewMemoryGameStore()
+
+	idle := createScrabbleGame()
+	idle.LastActivity = time.Now().Add(-2 * time.Hour)
+	active := createScrabbleGame()
+
+	ms.Put(idle)
+	ms.Put(active)
+
+	if err := removeIdleGames(ms, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ms.Get(idle.ID); err != ErrGameNotFound {
+		t.Error("Idle game should have been removed")
+	} else if _, err = ms.Get(active.ID); err != nil {
+		t.Error("Active game should not have been removed")
+	}
+
+	if _, err := idle.request(GamePlayRequest{}); err != ErrGameStopped {
+		t.Errorf("Removed game should be stopped, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Rematch is the game created for the players of a finished game to play
+// again, and the ID each of them plays it under
+type Rematch struct {
+	GameID  uuid.UUID               `json:"game_id"`
+	Players map[uuid.UUID]uuid.UUID `json:"players"` // each player's ID in the new game, by their ID in the finished one
+}
+
+// RematchResponse is the format of the response sent to a player of a
+// finished game with the game created for a rematch, and their ID in it
+type RematchResponse struct {
+	GameID   uuid.UUID `json:"game_id"`
+	PlayerID uuid.UUID `json:"player_id"`
+}
+
+// forPlayer returns where the player, identified by their ID in the finished
+// game, plays the rematch, or nil if there isn't one or they aren't in it
+func (rm *Rematch) forPlayer(playerID uuid.UUID) *RematchResponse {
+	if rm == nil {
+		return nil
+	}
+	id, ok := rm.Players[playerID]
+	if !ok {
+		return nil
+	}
+	return &RematchResponse{GameID: rm.GameID, PlayerID: id}
+}
+
+// rematch creates and starts a game for the players of the finished game to
+// play again, with the same options and their turn order reversed. Players who
+// were kicked out aren't asked back. Each player is told their ID in the new
+// game with the state of the finished one, which is pushed to everyone
+// watching it. A game is only rematched once, so asking again returns the
+// game already created. The game must be locked by the caller.
+func (s *Server) rematch(g *ScrabbleGame, playerID uuid.UUID) (*Rematch, error) {
+	if p, ok := g.Players[playerID]; !ok {
+		return nil, errors.New("No player with that ID in game")
+	} else if p.Kicked {
+		return nil, errors.New("Players who were kicked out can't ask for a rematch")
+	} else if !g.Finished {
+		return nil, errors.New("Game has not finished")
+	} else if g.rematch != nil {
+		return g.rematch, nil
+	} else if g.TournamentID != uuid.Nil {
+		return nil, errors.New("Tournament games can't be rematched")
+	}
+
+	var players []*Player
+	list := g.playerList()
+	for i := len(list) - 1; i >= 0; i-- {
+		if !list[i].Kicked {
+			players = append(players, list[i])
+		}
+	}
+	if len(players) < 2 {
+		return nil, errors.New("At least two players needed for a rematch")
+	}
+
+	// Seeded games draw their turn order from the seed, which would undo the
+	// reversal, and would deal the same tiles again
+	opts := g.Options
+	opts.Seed = nil
+	v, err := lookupVariant(opts.Variant)
+	if err != nil {
+		return nil, err
+	}
+	ng := createVariantGame(v, opts.Layout, opts.tileSet(), nil)
+	ng.Options = opts
+	ng.Validator = gameValidator(s.validator, opts)
+	s.cluster.claim(ng)
+
+	ng.Lock()
+	defer ng.Unlock()
+	s.adoptGame(ng)
+
+	rm := &Rematch{GameID: ng.ID, Players: make(map[uuid.UUID]uuid.UUID, len(players))}
+	for _, p := range players {
+		var id uuid.UUID
+		switch {
+		case p.Bot:
+			id, err = ng.addBot(p.Name, p.BotLevel)
+		case p.Account != nil:
+			id, err = ng.addAccountPlayer(Account{ID: *p.Account, Username: p.Name})
+		default:
+			id, err = ng.addPlayer(p.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rm.Players[p.ID] = id
+	}
+	if err = ng.start(); err != nil {
+		return nil, err
+	} else if err = s.games.Put(ng); err != nil {
+		return nil, err
+	}
+	ng.runBots(s.bot)
+
+	if err = g.record(Event{Type: RematchCreated, Player: playerID, Rematch: rm}); err != nil {
+		return nil, err
+	}
+	g.broadcast(g.playerList())
+	return rm, s.games.Put(g)
+}
+
+// rematchHandler handles requests from players of a finished game, identified
+// by the request's game_id and player_id, to play it again
+func (s *Server) rematchHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	s.createRematch(w, r, j.GameID, *j.PlayerID)
+}
+
+// addRematchHandler handles requests from players of a finished game,
+// identified by its path, to play it again
+func (s *Server) addRematchHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	j, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	s.createRematch(w, r, gameID, j.PlayerID)
+}
+
+// createRematch responds with the game created for the players of the
+// finished game to play again, and the player's ID in it
+func (s *Server) createRematch(w http.ResponseWriter, r *http.Request, gameID, playerID uuid.UUID) {
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	created := g.rematch == nil
+	g.Unlock()
+	if created && !s.checkGameLimit(w) {
+		return
+	}
+
+	g.Lock()
+	rm, err := s.rematch(g, playerID)
+	g.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := json.Marshal(rm.forPlayer(playerID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.Write(resp)
+}
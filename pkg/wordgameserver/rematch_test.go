@@ -0,0 +1,106 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRematchHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	rematch := func(playerID uuid.UUID) *httptest.ResponseRecorder {
+		body, err := json.Marshal(PlayerRequest{PlayerID: playerID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/games/"+g.ID.String()+"/rematch", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	if c := rematch(ids[0]).Code; c != http.StatusBadRequest {
+		t.Errorf("Rematching an unfinished game returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+
+	for _, id := range []uuid.UUID{ids[0], ids[1]} {
+		if err := g.pass(GamePlayRequest{PlayerID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !g.Finished {
+		t.Fatal("Game has not finished after both players passed")
+	}
+
+	if c := rematch(g.ID).Code; c != http.StatusBadRequest {
+		t.Errorf("Rematch for an unknown player returned status code %v, expected %v", c, http.StatusBadRequest)
+	}
+
+	rr := rematch(ids[0])
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var resp RematchResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	ng, err := srv.lookupGame(context.Background(), resp.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ng.Lock()
+	list := ng.playerList()
+	if !ng.Active || ng.Finished {
+		t.Error("Rematch has not started")
+	} else if len(list) != 2 || list[0].Name != "ashley2" || list[1].Name != "ashley1" {
+		t.Errorf("Rematch has players %v, expected ashley2 then ashley1", list)
+	} else if list[1].ID != resp.PlayerID {
+		t.Errorf("Returned player ID %v, expected ashley1's %v", resp.PlayerID, list[1].ID)
+	}
+	ng.Unlock()
+
+	// The other player finds the rematch in the finished game's state, and
+	// asking for it again returns the same game
+	g.Lock()
+	s := g.getState(ids[1], g.playerList())
+	g.Unlock()
+	if s.Rematch == nil || s.Rematch.GameID != resp.GameID || s.Rematch.PlayerID != list[0].ID {
+		t.Errorf("Second player's state has rematch %+v, expected game %v as player %v", s.Rematch, resp.GameID, list[0].ID)
+	}
+
+	rr = rematch(ids[1])
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Asking again returned status code %v, expected %v", rr.Code, http.StatusOK)
+	}
+	var again RematchResponse
+	if err = json.NewDecoder(rr.Body).Decode(&again); err != nil {
+		t.Fatal(err)
+	} else if again.GameID != resp.GameID || again.PlayerID != list[0].ID {
+		t.Errorf("Asking again returned %+v, expected game %v as player %v", again, resp.GameID, list[0].ID)
+	}
+
+	// The rematch is kept with the finished game
+	data, err := EncodeGame(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if rm := decoded.rematch.forPlayer(ids[0]); rm == nil || *rm != resp {
+		t.Errorf("Decoded game has rematch %+v, expected %+v", rm, resp)
+	}
+}
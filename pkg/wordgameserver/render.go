@@ -0,0 +1,370 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sizes in pixels of the pictures of boards
+const (
+	renderCell   = 32 // width and height of each square
+	renderMargin = 8  // space around the board and below the scores
+	renderLine   = 22 // height of each player's line of the scores
+)
+
+// Colors of the pictures of boards
+var (
+	backgroundColor = color.RGBA{0x2f, 0x4f, 0x3f, 0xff}
+	tileColor       = color.RGBA{0xf2, 0xd7, 0x9b, 0xff}
+	inkColor        = color.RGBA{0x2b, 0x22, 0x18, 0xff}
+	blankInkColor   = color.RGBA{0x9a, 0x3b, 0x2a, 0xff} // letters of blank tiles
+	scoreColor      = color.RGBA{0xf5, 0xf0, 0xe1, 0xff}
+)
+
+// squareColors are the colors of each type of square while it is empty
+var squareColors = map[string]color.RGBA{
+	"plain":           {0xe8, 0xe2, 0xd0, 0xff},
+	"star":            {0xf4, 0xb6, 0xc2, 0xff},
+	"doubleLetter":    {0xb8, 0xdc, 0xf0, 0xff},
+	"tripleLetter":    {0x3c, 0x8d, 0xd0, 0xff},
+	"quadrupleLetter": {0x5b, 0x4a, 0xb8, 0xff},
+	"doubleWord":      {0xf4, 0xb6, 0xc2, 0xff},
+	"tripleWord":      {0xd9, 0x4a, 0x3a, 0xff},
+	"quadrupleWord":   {0xa0, 0x2a, 0x6a, 0xff},
+}
+
+// premiumMarkers are written on empty squares of boards drawn as text to
+// show their type
+var premiumMarkers = map[string]rune{
+	"star":            '*',
+	"doubleLetter":    '\'',
+	"tripleLetter":    '"',
+	"quadrupleLetter": '^',
+	"doubleWord":      '-',
+	"tripleWord":      '=',
+	"quadrupleWord":   '#',
+}
+
+// squareLabels are written on empty premium squares
+var squareLabels = map[string]string{
+	"doubleLetter":    "DL",
+	"tripleLetter":    "TL",
+	"quadrupleLetter": "QL",
+	"doubleWord":      "DW",
+	"tripleWord":      "TW",
+	"quadrupleWord":   "QW",
+}
+
+// boardPNGHandler serves a picture of the game's board and scores as a PNG,
+// for chat bots, link previews and sharing finished games. The game is
+// identified by its path or the game_id query parameter.
+func (s *Server) boardPNGHandler(w http.ResponseWriter, r *http.Request) {
+	s.serveBoard(w, r, "image/png", renderPNG)
+}
+
+// boardSVGHandler serves the same picture as boardPNGHandler as an SVG
+func (s *Server) boardSVGHandler(w http.ResponseWriter, r *http.Request) {
+	s.serveBoard(w, r, "image/svg+xml", renderSVG)
+}
+
+// boardTextHandler serves the game's board and scores as plain text, for
+// debugging with curl and clients that have no more than a terminal
+func (s *Server) boardTextHandler(w http.ResponseWriter, r *http.Request) {
+	s.serveBoard(w, r, "text/plain; charset=UTF-8", renderText)
+}
+
+// serveBoard renders the board and scores of the game the request is for and
+// sends them as the content type given
+func (s *Server) serveBoard(w http.ResponseWriter, r *http.Request, contentType string,
+	render func(io.Writer, ScrabbleBoard, []*Player) error) {
+
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+
+	// Players are changed in place as the game goes on, so they are copied
+	// to be rendered without the lock
+	g.Lock()
+	board := g.Board.Clone()
+	playerList := g.playerList()
+	players := make([]*Player, len(playerList))
+	for i, p := range playerList {
+		players[i] = &Player{Name: p.Name, Number: p.Number}
+		players[i].Score = p.Score
+	}
+	g.Unlock()
+
+	var buf bytes.Buffer
+	if err = render(&buf, board, players); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// renderSize returns the width and height of the picture of a board with the
+// number of players given
+func renderSize(board ScrabbleBoard, players int) (int, int) {
+	width := len(board)*renderCell + 2*renderMargin
+	return width, width + players*renderLine + renderMargin
+}
+
+// renderPNG draws the board with the players' scores below it as a PNG.
+// Letters are drawn with a small bitmap font, so any it doesn't have, such as
+// accented letters, are drawn as boxes.
+func renderPNG(w io.Writer, board ScrabbleBoard, players []*Player) error {
+	width, height := renderSize(board, len(players))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(backgroundColor), image.Point{}, draw.Src)
+
+	for i, row := range board {
+		for j, squ := range row {
+			x, y := renderMargin+j*renderCell, renderMargin+i*renderCell
+			cell := image.Rect(x+1, y+1, x+renderCell-1, y+renderCell-1)
+
+			if squ.Tile.Letter == 0 {
+				draw.Draw(img, cell, image.NewUniform(squareColors[squ.SquareType]), image.Point{}, draw.Src)
+				if label := squareLabels[squ.SquareType]; label != "" {
+					drawText(img, label, x+(renderCell-textWidth(label, 1))/2, y+(renderCell-glyphHeight)/2, 1, inkColor)
+				}
+				continue
+			}
+
+			draw.Draw(img, cell, image.NewUniform(tileColor), image.Point{}, draw.Src)
+			ink := inkColor
+			if squ.Blank {
+				ink = blankInkColor
+			}
+			letter, scale := squ.Letter.String(), 3
+			if utf8.RuneCountInString(letter) > 1 {
+				// Digraphs are smaller to fit
+				scale = 2
+			}
+			drawText(img, letter, x+(renderCell-textWidth(letter, scale))/2-2, y+(renderCell-glyphHeight*scale)/2-1, scale, ink)
+			if squ.Value > 0 {
+				value := strconv.Itoa(squ.Value)
+				drawText(img, value, x+renderCell-3-textWidth(value, 1), y+renderCell-3-glyphHeight, 1, ink)
+			}
+		}
+	}
+
+	for i, p := range players {
+		y := width + i*renderLine + (renderLine-glyphHeight*2)/2
+		score := strconv.Itoa(p.Score)
+		drawText(img, p.Name, renderMargin, y, 2, scoreColor)
+		drawText(img, score, width-renderMargin-textWidth(score, 2), y, 2, scoreColor)
+	}
+
+	return png.Encode(w, img)
+}
+
+// renderSVG draws the same picture as renderPNG as an SVG, which leaves the
+// letters to be drawn by the viewer's fonts
+func renderSVG(w io.Writer, board ScrabbleBoard, players []*Player) error {
+	var buf bytes.Buffer
+	width, height := renderSize(board, len(players))
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d" font-family="sans-serif">`+"\n", width, height)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", width, height, hexColor(backgroundColor))
+
+	for i, row := range board {
+		for j, squ := range row {
+			x, y := renderMargin+j*renderCell, renderMargin+i*renderCell
+			cx, cy := x+renderCell/2, y+renderCell/2
+
+			if squ.Tile.Letter == 0 {
+				fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+					x+1, y+1, renderCell-2, renderCell-2, hexColor(squareColors[squ.SquareType]))
+				if label := squareLabels[squ.SquareType]; label != "" {
+					fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="10" text-anchor="middle" dominant-baseline="central" fill="%s">%s</text>`+"\n",
+						cx, cy, hexColor(inkColor), label)
+				}
+				continue
+			}
+
+			ink := inkColor
+			if squ.Blank {
+				ink = blankInkColor
+			}
+			letter, size := squ.Letter.String(), 18
+			if utf8.RuneCountInString(letter) > 1 {
+				size = 13
+			}
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="3" fill="%s"/>`+"\n",
+				x+1, y+1, renderCell-2, renderCell-2, hexColor(tileColor))
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="%d" font-weight="bold" text-anchor="middle" dominant-baseline="central" fill="%s">%s</text>`+"\n",
+				cx-2, cy, size, hexColor(ink), html.EscapeString(letter))
+			if squ.Value > 0 {
+				fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="8" text-anchor="end" fill="%s">%d</text>`+"\n",
+					x+renderCell-3, y+renderCell-3, hexColor(ink), squ.Value)
+			}
+		}
+	}
+
+	for i, p := range players {
+		y := width + i*renderLine + renderLine/2
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="14" dominant-baseline="central" fill="%s">%s</text>`+"\n",
+			renderMargin, y, hexColor(scoreColor), html.EscapeString(p.Name))
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="14" text-anchor="end" dominant-baseline="central" fill="%s">%d</text>`+"\n",
+			width-renderMargin, y, hexColor(scoreColor), p.Score)
+	}
+
+	buf.WriteString("</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// renderText writes the board as WriteBoardText does, followed by the
+// players' scores
+func renderText(w io.Writer, board ScrabbleBoard, players []*Player) error {
+	var buf bytes.Buffer
+	WriteBoardText(&buf, board)
+	buf.WriteString("\n")
+	for _, p := range players {
+		fmt.Fprintf(&buf, "%-20s %4d\n", p.Name, p.Score)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteBoardText writes the board as monospace text, with columns labelled
+// from A and rows numbered from 1, followed by a key to the premium squares.
+// Blank tiles are shown in lowercase.
+func WriteBoardText(w io.Writer, board ScrabbleBoard) {
+	fmt.Fprint(w, "    ")
+	for col := range board[0] {
+		fmt.Fprintf(w, " %c", 'A'+col)
+	}
+	fmt.Fprintln(w)
+
+	for row := range board {
+		fmt.Fprintf(w, "%3d ", row+1)
+		for _, squ := range board[row] {
+			c := '.'
+			if squ.Letter != 0 {
+				// Digraphs are shown by their first letter to keep the
+				// columns lined up
+				c, _ = utf8.DecodeRuneInString(squ.Letter.String())
+				if squ.Blank {
+					c = unicode.ToLower(c)
+				}
+			} else if m, ok := premiumMarkers[squ.SquareType]; ok {
+				c = m
+			}
+			fmt.Fprintf(w, " %c", c)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "\n    * start  ' double letter  \" triple letter  - double word  = triple word")
+	if len(board) > 15 {
+		fmt.Fprintln(w, "    ^ quadruple letter  # quadruple word")
+	}
+}
+
+// hexColor returns the color as an SVG color such as #2f4f3f
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Size of the glyphs of the bitmap font in pixels, before scaling
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs is a bitmap font of capital letters and digits. Each row of a glyph
+// is five pixels, from its high bit to its low bit.
+var glyphs = map[rune][glyphHeight]uint8{
+	'A': {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B': {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C': {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D': {0b11110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11110},
+	'E': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G': {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H': {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I': {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J': {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K': {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L': {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M': {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N': {0b10001, 0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001},
+	'O': {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P': {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q': {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R': {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S': {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T': {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W': {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X': {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y': {0b10001, 0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100},
+	'Z': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'-': {0b00000, 0b00000, 0b00000, 0b11111, 0b00000, 0b00000, 0b00000},
+	' ': {},
+}
+
+// missingGlyph is drawn for characters the font doesn't have
+var missingGlyph = [glyphHeight]uint8{0b11111, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11111}
+
+// textWidth returns the width in pixels of the text drawn at the scale
+func textWidth(text string, scale int) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// drawText draws the text in capitals with its top left corner at the point
+// given, scaling each pixel of the font up to a square of the scale's size
+func drawText(img *image.RGBA, text string, x, y, scale int, c color.Color) {
+	ink := image.NewUniform(c)
+	for _, r := range text {
+		glyph, ok := glyphs[unicode.ToUpper(r)]
+		if !ok {
+			glyph = missingGlyph
+		}
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				px := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale)
+				draw.Draw(img, px, ink, image.Point{}, draw.Src)
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
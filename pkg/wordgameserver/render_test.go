@@ -0,0 +1,85 @@
+package wordgameserver
+
+import (
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderBoard(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "S DOGXX")
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Players[ids[1]].Name = "<ashley2>"
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+		}
+		return rr
+	}
+
+	rr := get("/v2/games/" + g.ID.String() + "/board.png")
+	if ct := rr.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Returned content type %v, expected image/png", ct)
+	}
+	img, err := png.Decode(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	width, height := renderSize(g.Board, 2)
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Fatalf("Picture is %vx%v, expected %vx%v", b.Dx(), b.Dy(), width, height)
+	}
+
+	// Squares are the color of their tile, or of their type while empty
+	corner := func(row, col int) color.RGBA {
+		x, y := renderMargin+col*renderCell+2, renderMargin+row*renderCell+2
+		return color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+	}
+	if c := corner(7, 7); c != tileColor {
+		t.Errorf("Square with a tile is %v, expected %v", c, tileColor)
+	} else if c = corner(0, 0); c != squareColors["tripleWord"] {
+		t.Errorf("Empty triple word square is %v, expected %v", c, squareColors["tripleWord"])
+	}
+
+	rr = get("/game/" + g.ID.String() + "/board.svg")
+	svg := rr.Body.String()
+	if ct := rr.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Returned content type %v, expected image/svg+xml", ct)
+	} else if !strings.HasPrefix(svg, "<svg") || strings.Count(svg, `font-weight="bold"`) != 3 {
+		t.Errorf("SVG does not show the three tiles played:\n%v", svg)
+	} else if !strings.Contains(svg, "&lt;ashley2&gt;") {
+		t.Error("SVG does not show the escaped name of the second player")
+	}
+
+	rr = get("/v2/games/" + g.ID.String() + "/board.txt")
+	lines := strings.Split(rr.Body.String(), "\n")
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Returned content type %v, expected text/plain", ct)
+	} else if len(lines) < 17 || lines[8] != "  8  = . . ' . . C A T . . ' . . =" || lines[1] != "  1  = . . ' . . . = . . . ' . . =" {
+		t.Errorf("Board is drawn as:\n%v", rr.Body)
+	} else if !strings.Contains(rr.Body.String(), "\nashley1                 5\n") {
+		t.Errorf("Board text does not show the first player's score:\n%v", rr.Body)
+	}
+}
@@ -0,0 +1,85 @@
+package wordgameserver
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// GameReplay is the full record of a finished game, from which the board at
+// any point in the game can be rebuilt with ReplayGame
+type GameReplay struct {
+	GameID  uuid.UUID   `json:"game_id"`
+	Options GameOptions `json:"options"`
+	Players []*Player   `json:"players"`
+	Moves   []Move      `json:"moves"`
+	Winners []int       `json:"winners"`
+}
+
+// ReplayState is the state of a game rebuilt from its moves
+type ReplayState struct {
+	Move   int           `json:"move"`   // number of moves made to reach the state
+	Board  ScrabbleBoard `json:"board"`  // board after the moves were made
+	Scores []int         `json:"scores"` // score of each player after the moves were made
+}
+
+// ReplayGame rebuilds the board and scores after the first moves of a game,
+// given by index. Plays that were later challenged off are left out. Points
+// deducted when the game ended aren't included in the scores.
+func ReplayGame(r GameReplay, index int) (ReplayState, error) {
+	if index < 0 || index > len(r.Moves) {
+		return ReplayState{}, errors.New("Move index must be between 0 and " + strconv.Itoa(len(r.Moves)))
+	}
+
+	v, err := lookupVariant(r.Options.Variant)
+	if err != nil {
+		return ReplayState{}, err
+	}
+
+	state := ReplayState{
+		Move:   index,
+		Board:  v.boardFor(r.Options.Layout),
+		Scores: make([]int, len(r.Players)),
+	}
+
+	for i, m := range r.Moves[:index] {
+		if m.Retracted {
+			continue
+		} else if m.Player < 0 || m.Player >= len(state.Scores) {
+			return state, errors.New("Move " + strconv.Itoa(i+1) + " was made by an unknown player")
+		} else if err := replayMove(state.Board, m, i); err != nil {
+			return state, err
+		}
+		state.Scores[m.Player] += m.Score
+	}
+
+	return state, nil
+}
+
+// replayMove places the tiles of a move, numbered by its index in the game, on
+// the board
+func replayMove(sb ScrabbleBoard, m Move, index int) error {
+	if len(m.Tiles) != len(m.Squares) {
+		return errors.New("Move " + strconv.Itoa(index+1) + " doesn't record the tiles placed")
+	}
+	for i, sc := range m.Squares {
+		if !sb.OnBoard(sc) {
+			return errors.New("Move " + strconv.Itoa(index+1) + " places a tile off the board")
+		}
+		sb.At(sc).Tile = m.Tiles[i]
+	}
+	return nil
+}
+
+// replay exports the record of the game. The game must be locked by the
+// caller.
+func (sg *ScrabbleGame) replay() GameReplay {
+	return GameReplay{
+		GameID:  sg.ID,
+		Options: sg.Options,
+		Players: sg.playerList(),
+		Moves:   sg.History(),
+		Winners: sg.Winners,
+	}
+}
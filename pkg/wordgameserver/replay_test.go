@@ -0,0 +1,52 @@
+package wordgameserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplayGame(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.pass(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := g.replay()
+	if len(r.Moves) != 2 || len(r.Players) != 2 {
+		t.Fatalf("Replay has %v moves and %v players, expected 2 of each", len(r.Moves), len(r.Players))
+	}
+
+	s, err := ReplayGame(r, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(s.Board, NewBoard()) {
+		t.Error("Board should be empty before any moves")
+	}
+
+	s, err = ReplayGame(r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range Letters("CAT") {
+		if got := s.Board[7][6+i].Letter; got != l {
+			t.Errorf("Square 7,%v has %q, expected %q", 6+i, got, l)
+		}
+	}
+	if s.Scores[0] != 5 || s.Scores[1] != 0 {
+		t.Errorf("Replayed scores %v, expected [5 0]", s.Scores)
+	}
+
+	if _, err = ReplayGame(r, 3); err == nil {
+		t.Error("Replaying past the last move should fail")
+	}
+}
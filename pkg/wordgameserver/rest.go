@@ -0,0 +1,472 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GameMoveRequest is the format of the request a client sends to make a move
+// in version 2 of the API
+type GameMoveRequest struct {
+	PlayerID uuid.UUID        `json:"player_id"`
+	Action   string           `json:"action,omitempty"` // one of the Action constants, ActionPlay if empty
+	StartPos SquareCoordinate `json:"start_pos"`
+	EndPos   SquareCoordinate `json:"end_pos"`
+	Tiles    Letters          `json:"tiles,omitempty"`
+	Blanks   Letters          `json:"blanks,omitempty"`
+
+	// Placements lists the squares a play places tiles on, in place of the
+	// start and end positions, tiles and blanks
+	Placements []TilePlacement `json:"placements,omitempty"`
+}
+
+// Moves a player can make with a GameMoveRequest
+const (
+	ActionPlay   = "play"   // place tiles on the board
+	ActionSwap   = "swap"   // exchange tiles with the bag
+	ActionPass   = "pass"   // give up the turn
+	ActionResign = "resign" // concede the game
+)
+
+// PlayerRequest is the format of the request a client sends to act as one of
+// the players in a game in version 2 of the API
+type PlayerRequest struct {
+	PlayerID uuid.UUID `json:"player_id"`
+}
+
+// v2Routes registers the routes of version 2 of the API, which treats games
+// and their players and moves as resources rather than carrying their IDs in
+// request bodies
+func (s *Server) v2Routes(r *mux.Router) {
+	r.HandleFunc("/games", s.lobbyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games", s.createGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/import", s.importGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/join", s.joinByCodeHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}", s.getGameHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}", s.deleteGameHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/games/{id}/players", s.addPlayerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/start", s.startHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/moves", s.gameHistoryHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/moves", s.addMoveHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/challenges", s.addChallengeHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/undo", s.addUndoHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/kicks", s.kickHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/pause", s.pauseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/unpause", s.unpauseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/options", s.gameOptionsHandler).Methods(http.MethodPut)
+	r.HandleFunc("/games/{id}/owner", s.transferOwnerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/chat", s.getChatHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/chat", s.postChatHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/reactions", s.reactHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/invitations", s.inviteHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/resume", s.resumeGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/rematch", s.addRematchHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/fork", s.forkGameHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/hint", s.getHintHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/validate", s.validateGameMoveHandler).Methods(http.MethodPost)
+	r.HandleFunc("/games/{id}/replay", s.gameReplayHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/gcg", s.gameGCGHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/analysis", s.gameAnalysisHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/unseen", s.unseenTilesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/board.png", s.boardPNGHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/board.svg", s.boardSVGHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/board.txt", s.boardTextHandler).Methods(http.MethodGet)
+	r.HandleFunc("/games/{id}/ws", s.gameSocketHandler)
+	r.HandleFunc("/games/{id}/events", s.gameEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/accounts", s.registerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/accounts/login", s.loginHandler).Methods(http.MethodPost)
+	r.HandleFunc("/accounts/me", s.accountHandler).Methods(http.MethodGet)
+	r.HandleFunc("/accounts/me/devices", s.registerDeviceHandler).Methods(http.MethodPost)
+	r.HandleFunc("/accounts/me/devices", s.listDevicesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/accounts/me/devices/{device}", s.deleteDeviceHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/accounts/me/notifications", s.notificationPreferencesHandler).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/leaderboard", s.leaderboardHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tournaments", s.createTournamentHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tournaments/{tournament}", s.getTournamentHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tournaments/{tournament}/players", s.registerTournamentHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tournaments/{tournament}/start", s.startTournamentHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tournaments/{tournament}/standings", s.tournamentStandingsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/players/{player}/games", s.playerGamesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/players/{player}/stats", s.playerStatsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/words/{word}", s.wordHandler).Methods(http.MethodGet)
+	r.HandleFunc("/solve", s.solveHandler).Methods(http.MethodPost)
+	r.HandleFunc("/puzzles/next", s.nextPuzzleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/puzzles/{puzzle}", s.getPuzzleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/puzzles/{puzzle}/answers", s.answerPuzzleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/graphql", s.graphQLHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/openapi.json", apiDocumentHandler("v2")).Methods(http.MethodGet)
+}
+
+// requestGameID returns the ID of the game a request is for, taken from its
+// path if the route has one, or the game_id query parameter otherwise
+func requestGameID(r *http.Request) (uuid.UUID, error) {
+	if id, ok := mux.Vars(r)["id"]; ok {
+		return uuid.Parse(id)
+	}
+	return uuid.Parse(r.URL.Query().Get("game_id"))
+}
+
+// pathGameID returns the ID of the game in the request's path, replying to the
+// client with an error if it isn't valid
+func pathGameID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	gameID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid game ID", http.StatusBadRequest)
+		return gameID, false
+	}
+	return gameID, true
+}
+
+// decodePlayerRequest decodes the body of a request made by a player
+func decodePlayerRequest(w http.ResponseWriter, r *http.Request) (PlayerRequest, bool) {
+	var j PlayerRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return j, false
+	}
+	return j, true
+}
+
+// getGameHandler handles requests for a player's view of a game, identified
+// by the player_id query parameter
+func (s *Server) getGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	playerID, err := uuid.Parse(r.URL.Query().Get("player_id"))
+	if err != nil {
+		http.Error(w, "Invalid player_id parameter", http.StatusBadRequest)
+		return
+	}
+	since, ok := querySince(w, r)
+	if !ok {
+		return
+	}
+
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   gameID,
+		PlayerID: playerID,
+		since:    since,
+	}, w, r)
+}
+
+// deleteGameHandler handles requests from players, identified by the
+// player_id query parameter, to cancel a game
+func (s *Server) deleteGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	playerID, err := uuid.Parse(r.URL.Query().Get("player_id"))
+	if err != nil {
+		http.Error(w, "Invalid player_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	s.cancelGame(w, r, gameID, playerID)
+}
+
+// addPlayerHandler handles requests to join a game as a player or to add a
+// bot to it. It responds with the new player's ID.
+func (s *Server) addPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	j.GameID = gameID
+
+	s.joinGame(w, r, j, http.StatusCreated)
+}
+
+// joinByCodeHandler handles requests to join a game identified by its join
+// code or an invite, as a player or to add a bot to it. It responds with the
+// game's and new player's IDs.
+func (s *Server) joinByCodeHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.JoinCode == nil && j.Invite == nil {
+		http.Error(w, "Missing join_code or invite", http.StatusBadRequest)
+		return
+	}
+
+	s.joinGame(w, r, j, http.StatusCreated)
+}
+
+// startHandler handles requests to start a game
+func (s *Server) startHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	s.startGame(w, r, gameID)
+}
+
+// addMoveHandler handles requests from players to play, swap, pass or resign.
+// It will respond using the GameStateResponse struct.
+func (s *Server) addMoveHandler(w http.ResponseWriter, r *http.Request) {
+	var j GameMoveRequest
+
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := GamePlayRequest{
+		GameID:   gameID,
+		PlayerID: j.PlayerID,
+	}
+	switch j.Action {
+	case ActionPlay, "":
+		req.Type = playRequest
+		req.StartPos, req.EndPos = j.StartPos, j.EndPos
+		req.Tiles, req.Blanks = j.Tiles, j.Blanks
+		req.Placements = j.Placements
+	case ActionSwap:
+		req.Type = playRequest
+		req.Tiles = j.Tiles
+		req.Swap = true
+	case ActionPass:
+		req.Type = passRequest
+	case ActionResign:
+		req.Type = resignRequest
+	default:
+		http.Error(w, "Unknown action '"+j.Action+"'", http.StatusBadRequest)
+		return
+	}
+
+	s.gameRequestHelper(req, w, r)
+}
+
+// addChallengeHandler handles requests from players to challenge the most
+// recent play. It will respond using the GameStateResponse struct, which
+// includes the outcome of the challenge.
+func (s *Server) addChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	j, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   gameID,
+		PlayerID: j.PlayerID,
+		Type:     challengeRequest,
+	}, w, r)
+}
+
+// resumeGameHandler handles requests from players to resume their session in
+// a game, disconnecting any others
+func (s *Server) resumeGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	j, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	s.resumeGame(w, r, gameID, j.PlayerID)
+}
+
+// deprecated is middleware that marks responses from a version of the API
+// that is being phased out, pointing clients at its successor
+func deprecated(successor string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "</"+successor+">; rel=\"successor-version\"")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var (
+	gamePathParam       = apiParameter{Name: "id", In: "path", Required: true, Schema: apiSchema{Type: "string", Format: "uuid"}}
+	tournamentPathParam = apiParameter{Name: "tournament", In: "path", Required: true, Schema: apiSchema{Type: "string", Format: "uuid"}}
+	playerPathParam     = apiParameter{Name: "player", In: "path", Required: true, Schema: apiSchema{Type: "string"}} // account ID or username
+	wordPathParam       = apiParameter{Name: "word", In: "path", Required: true, Schema: apiSchema{Type: "string"}}
+	devicePathParam     = apiParameter{Name: "device", In: "path", Required: true, Schema: apiSchema{Type: "string", Format: "uuid"}}
+	puzzlePathParam     = apiParameter{Name: "puzzle", In: "path", Required: true, Schema: apiSchema{Type: "string", Format: "uuid"}}
+)
+
+// v2Operations lists the endpoints of version 2 of the API
+var v2Operations = []apiOperation{
+	{Methods: []string{http.MethodGet}, Path: "/games", Summary: "List the games waiting for players",
+		Params: []apiParameter{
+			{Name: "open_seats", In: "query", Schema: apiSchema{Type: "integer"}},
+			{Name: "timer", In: "query", Schema: apiSchema{Type: "boolean"}},
+			{Name: "variant", In: "query", Schema: apiSchema{Type: "string"}},
+			limitParam, offsetParam,
+		},
+		Status: http.StatusOK, Response: LobbyResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games", Summary: "Create a game",
+		Request: GeneralGameRequest{}, Status: http.StatusCreated, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/import", Summary: "Create a game from a GCG file or position",
+		Request: GameImportRequest{}, Status: http.StatusCreated, Response: GameImportResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/join", Summary: "Join a game by its join code or an invite",
+		Params: []apiParameter{authParam}, Request: GeneralGameRequest{},
+		Status: http.StatusCreated, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}", Summary: "Get a player's view of a game",
+		Params: []apiParameter{gamePathParam, playerIDParam, sinceParam}, Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodDelete}, Path: "/games/{id}", Summary: "Cancel a game, as its owner",
+		Params: []apiParameter{gamePathParam, playerIDParam}, Status: http.StatusOK},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/players", Summary: "Join a game as a player or add a bot",
+		Params: []apiParameter{gamePathParam, authParam}, Request: GeneralGameRequest{}, Status: http.StatusCreated, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/start", Summary: "Start a game",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/moves", Summary: "List the moves made in a game",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, Response: GameHistoryResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/moves", Summary: "Play, swap, pass or resign",
+		Params: []apiParameter{gamePathParam}, Request: GameMoveRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/challenges", Summary: "Challenge the last play",
+		Params: []apiParameter{gamePathParam}, Request: PlayerRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/undo", Summary: "Ask to undo the last move of a friendly game, or agree to",
+		Params: []apiParameter{gamePathParam}, Request: PlayerRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/kicks", Summary: "Remove an unresponsive player from a game, or vote to",
+		Params: []apiParameter{gamePathParam}, Request: KickRequest{}, Required: []string{"player_id", "player"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/pause", Summary: "Pause a game that allows it, freezing its timers",
+		Params: []apiParameter{gamePathParam}, Request: PlayerRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/unpause", Summary: "Ask to resume a paused game, which it does once every player has",
+		Params: []apiParameter{gamePathParam}, Request: PlayerRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodPut}, Path: "/games/{id}/options", Summary: "Change the options of a game before it starts, as its owner",
+		Params: []apiParameter{gamePathParam}, Request: OptionsRequest{}, Required: []string{"player_id", "options"},
+		Status: http.StatusOK, Response: GeneralGameRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/owner", Summary: "Hand a game over to another player, as its owner",
+		Params: []apiParameter{gamePathParam}, Request: OwnerRequest{}, Required: []string{"player_id", "player"},
+		Status: http.StatusOK, Response: GameStateResponse{}, Negotiated: true},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/chat", Summary: "List the messages posted to a game's chat",
+		Params: []apiParameter{gamePathParam, chatAfterParam, channelParam}, Status: http.StatusOK, Response: ChatResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/chat", Summary: "Post a message to a game's chat, as a player or a named spectator",
+		Params: []apiParameter{gamePathParam}, Request: ChatRequest{}, Required: []string{"text"},
+		Status: http.StatusCreated, Response: ChatMessage{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/reactions", Summary: "React to the most recent move with an emote",
+		Params: []apiParameter{gamePathParam}, Request: ReactionRequest{}, Required: []string{"player_id", "emote"},
+		Status: http.StatusCreated, Response: Reaction{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/invitations", Summary: "Invite people to join a game by email, as its owner",
+		Params: []apiParameter{gamePathParam}, Request: InvitationRequest{}, Required: []string{"player_id", "emails"},
+		Status: http.StatusAccepted, Response: InvitationResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/resume", Summary: "Resume a session, disconnecting any others",
+		Params: []apiParameter{gamePathParam}, Request: PlayerRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: GameStateResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/rematch", Summary: "Start a new game with the players of a finished one, in the reverse order",
+		Params: []apiParameter{gamePathParam}, Request: PlayerRequest{}, Required: []string{"player_id"},
+		Status: http.StatusCreated, Response: RematchResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/fork", Summary: "Play on from a point in a finished game, optionally against bots",
+		Params: []apiParameter{gamePathParam}, Request: GameForkRequest{}, Required: []string{"move"},
+		Status: http.StatusCreated, Response: GameImportResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/hint", Summary: "Suggest a move the player could make, as good as the level given",
+		Params: []apiParameter{gamePathParam, playerIDParam, {Name: "level", In: "query", Schema: apiSchema{Type: "string"}}},
+		Status: http.StatusOK, Response: GamePlayRequest{}},
+	{Methods: []string{http.MethodPost}, Path: "/games/{id}/validate", Summary: "Check a play or swap without making it",
+		Params: []apiParameter{gamePathParam}, Request: GameMoveRequest{}, Required: []string{"player_id"},
+		Status: http.StatusOK, Response: MoveValidationResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/replay", Summary: "Export a finished game to be replayed",
+		Params: []apiParameter{gamePathParam, {Name: "move", In: "query", Schema: apiSchema{Type: "integer"}}},
+		Status: http.StatusOK, Response: GameReplay{}},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/gcg", Summary: "Export a finished game in GCG format",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "text/plain"},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/analysis", Summary: "Compare each move of a finished game with the best play available",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, Response: GameAnalysis{}},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/unseen", Summary: "List the tiles a player can't see, in the bag and the other players' racks",
+		Params: []apiParameter{gamePathParam, playerIDParam}, Status: http.StatusOK, Response: UnseenTilesResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/board.png", Summary: "Draw a game's board and scores as a PNG",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "image/png"},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/board.svg", Summary: "Draw a game's board and scores as an SVG",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "image/svg+xml"},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/board.txt", Summary: "Write a game's board and scores as plain text",
+		Params: []apiParameter{gamePathParam}, Status: http.StatusOK, ContentType: "text/plain"},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/ws", Summary: "Receive state updates over a WebSocket",
+		Params: []apiParameter{gamePathParam, playerIDParam, sinceParam}, Status: http.StatusSwitchingProtocols},
+	{Methods: []string{http.MethodGet}, Path: "/games/{id}/events", Summary: "Receive state updates and moves as Server-Sent Events",
+		Params: []apiParameter{gamePathParam, playerIDParam, sinceParam}, Status: http.StatusOK, ContentType: "text/event-stream"},
+	{Methods: []string{http.MethodPost}, Path: "/accounts", Summary: "Register an account",
+		Request: AccountRequest{}, Required: []string{"username", "password"}, Status: http.StatusCreated, Response: AccountResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/accounts/login", Summary: "Log in to an account",
+		Request: AccountRequest{}, Required: []string{"username", "password"}, Status: http.StatusOK, Response: AccountResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/accounts/me", Summary: "Get the account a token was issued to",
+		Params: []apiParameter{authParam}, Status: http.StatusOK, Response: AccountResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/accounts/me/devices", Summary: "Register a device for push notifications",
+		Params: []apiParameter{authParam}, Request: DeviceRequest{}, Required: []string{"provider", "token"},
+		Status: http.StatusCreated, Response: Device{}},
+	{Methods: []string{http.MethodGet}, Path: "/accounts/me/devices", Summary: "List the devices registered for push notifications",
+		Params: []apiParameter{authParam}, Status: http.StatusOK, Response: DevicesResponse{}},
+	{Methods: []string{http.MethodDelete}, Path: "/accounts/me/devices/{device}", Summary: "Stop sending push notifications to a device",
+		Params: []apiParameter{authParam, devicePathParam}, Status: http.StatusOK},
+	{Methods: []string{http.MethodGet}, Path: "/accounts/me/notifications", Summary: "Get the kinds of push notification sent",
+		Params: []apiParameter{authParam}, Status: http.StatusOK, Response: NotificationPreferences{}},
+	{Methods: []string{http.MethodPut}, Path: "/accounts/me/notifications", Summary: "Choose the kinds of push notification sent",
+		Params: []apiParameter{authParam}, Request: NotificationPreferences{}, Status: http.StatusOK, Response: NotificationPreferences{}},
+	{Methods: []string{http.MethodGet}, Path: "/leaderboard", Summary: "List the top players",
+		Params: []apiParameter{
+			{Name: "sort", In: "query", Schema: apiSchema{Type: "string"}},
+			{Name: "window", In: "query", Schema: apiSchema{Type: "string"}},
+			limitParam, offsetParam,
+		},
+		Status: http.StatusOK, Response: LeaderboardResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/players/{player}/games", Summary: "List a registered player's games",
+		Params: []apiParameter{
+			playerPathParam, authParam,
+			{Name: "status", In: "query", Schema: apiSchema{Type: "string"}},
+			limitParam, offsetParam,
+		},
+		Status: http.StatusOK, Response: PlayerGamesResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/players/{player}/stats", Summary: "Get a registered player's statistics",
+		Params: []apiParameter{playerPathParam}, Status: http.StatusOK, Response: PlayerStats{}},
+	{Methods: []string{http.MethodGet}, Path: "/words/{word}", Summary: "Check a word against each dictionary and get its definition",
+		Params: []apiParameter{wordPathParam}, Status: http.StatusOK, Response: WordResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/solve", Summary: "List the plays available from a rack, highest scoring first",
+		Request: SolveRequest{}, Required: []string{"rack"}, Status: http.StatusOK, Response: SolveResponse{}},
+	{Methods: []string{http.MethodGet}, Path: "/puzzles/next", Summary: "Get a puzzle to answer, close to the player's puzzle rating",
+		Params: []apiParameter{authParam}, Status: http.StatusOK, Response: Puzzle{}},
+	{Methods: []string{http.MethodGet}, Path: "/puzzles/{puzzle}", Summary: "Get a puzzle",
+		Params: []apiParameter{puzzlePathParam}, Status: http.StatusOK, Response: Puzzle{}},
+	{Methods: []string{http.MethodPost}, Path: "/puzzles/{puzzle}/answers", Summary: "Answer a puzzle with a play, which is compared with the best play",
+		Params: []apiParameter{puzzlePathParam, authParam}, Request: GamePlayRequest{}, Status: http.StatusOK, Response: PuzzleResult{}},
+	{Methods: []string{http.MethodPost}, Path: "/tournaments", Summary: "Create a tournament",
+		Request: TournamentRequest{}, Required: []string{"format"}, Status: http.StatusCreated, Response: Tournament{}},
+	{Methods: []string{http.MethodGet}, Path: "/tournaments/{tournament}", Summary: "Get a tournament and its pairings",
+		Params: []apiParameter{tournamentPathParam}, Status: http.StatusOK, Response: Tournament{}},
+	{Methods: []string{http.MethodPost}, Path: "/tournaments/{tournament}/players", Summary: "Register for a tournament",
+		Params: []apiParameter{tournamentPathParam, authParam}, Status: http.StatusCreated, Response: TournamentPlayer{}},
+	{Methods: []string{http.MethodPost}, Path: "/tournaments/{tournament}/start", Summary: "Start a tournament's first round",
+		Params: []apiParameter{tournamentPathParam}, Status: http.StatusOK, Response: Tournament{}},
+	{Methods: []string{http.MethodGet}, Path: "/tournaments/{tournament}/standings", Summary: "Get the standings of a tournament",
+		Params: []apiParameter{tournamentPathParam}, Status: http.StatusOK, Response: TournamentStandingsResponse{}},
+	{Methods: []string{http.MethodPost}, Path: "/graphql", Summary: "Query games with GraphQL",
+		Request: GraphQLRequest{}, Required: []string{"query"}, Status: http.StatusOK},
+}
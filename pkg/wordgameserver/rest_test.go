@@ -0,0 +1,96 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRESTRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	s := httptest.NewServer(srv.newRouter())
+	defer s.Close()
+
+	send := func(method, path string, body interface{}, code int, resp interface{}) *http.Response {
+		t.Helper()
+
+		var payload []byte
+		if body != nil {
+			var err error
+			if payload, err = json.Marshal(body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req, err := http.NewRequest(method, s.URL+path, bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+
+		if r.StatusCode != code {
+			var msg bytes.Buffer
+			msg.ReadFrom(r.Body)
+			t.Fatalf("%v %v returned status code %v, expected %v. Error: %v", method, path, r.StatusCode, code, msg.String())
+		}
+		if resp != nil {
+			if err = json.NewDecoder(r.Body).Decode(resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return r
+	}
+
+	var game GeneralGameRequest
+	r := send("POST", "/v2/games", GeneralGameRequest{}, http.StatusCreated, &game)
+	if r.Header.Get("Deprecation") != "" {
+		t.Error("Current API version marked as deprecated")
+	}
+	path := "/v2/games/" + game.GameID.String()
+
+	var players []GeneralGameRequest
+	for _, name := range []string{"ashley1", "ashley2"} {
+		name := name
+		var j GeneralGameRequest
+		send("POST", path+"/players", GeneralGameRequest{PlayerName: &name}, http.StatusCreated, &j)
+		if j.PlayerID == nil || j.GameID != game.GameID {
+			t.Fatalf("Join returned %+v, expected player ID for game %v", j, game.GameID)
+		}
+		players = append(players, j)
+	}
+
+	send("POST", path+"/start", nil, http.StatusOK, nil)
+
+	var state GameStateResponse
+	send("GET", path+"?player_id="+players[0].PlayerID.String(), nil, http.StatusOK, &state)
+	if !state.Active || len(state.PlayerTiles) != maxTiles {
+		t.Fatalf("Returned state %+v, expected active game with a full rack", state)
+	}
+
+	// Whoever's turn it is passes
+	current := players[state.PlayerTurn]
+	send("POST", path+"/moves", GameMoveRequest{PlayerID: *current.PlayerID, Action: "shuffle"}, http.StatusBadRequest, nil)
+	send("POST", path+"/moves", GameMoveRequest{PlayerID: *current.PlayerID, Action: ActionPass}, http.StatusOK, &state)
+
+	var history GameHistoryResponse
+	send("GET", path+"/moves", nil, http.StatusOK, &history)
+	if len(history.Moves) != 1 || !history.Moves[0].Pass {
+		t.Errorf("History has moves %+v, expected a single pass", history.Moves)
+	}
+
+	// The old routes still work, but are deprecated
+	r = send("POST", "/v1/game/state", GeneralGameRequest{GameID: game.GameID, PlayerID: players[1].PlayerID}, http.StatusOK, &state)
+	if r.Header.Get("Deprecation") != "true" {
+		t.Error("Old API version not marked as deprecated")
+	}
+
+	// Only the game's owner, the first to join, can cancel it
+	send("DELETE", path+"?player_id="+players[1].PlayerID.String(), nil, http.StatusForbidden, nil)
+	send("DELETE", path+"?player_id="+players[0].PlayerID.String(), nil, http.StatusOK, nil)
+	send("GET", path+"/moves", nil, http.StatusBadRequest, nil)
+}
@@ -0,0 +1,277 @@
+package wordgameserver
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+// Server is a Word Game HTTP server. Each server has games of its own, so any
+// number of them can run in one process.
+type Server struct {
+	cfg           Config
+	games         GameStore
+	ratings       RatingStore
+	results       ResultStore
+	accounts      AccountStore
+	tournaments   TournamentStore
+	notifications NotificationStore
+	bans          BanStore
+	puzzles       PuzzleStore
+	tokenKey      []byte
+	validator     dictionary.WordValidator
+	bot           BotStrategy
+	tlsConfig     *tls.Config
+	layouts       map[string]BoardLayout  // board layouts games can choose by name
+	tileSets      map[string]TileSet      // tile sets games can choose by name
+	definitions   *dictionary.Definitions // what words mean, nil if the server has no definitions
+	pushProviders map[string]PushProvider // delivers push notifications through each service, by name
+	mailer        Mailer                  // sends invitations and reminders, nil if the server can't send email
+	handler       http.Handler
+	started       time.Time // when the server was created
+	recentErrors  errorLog  // server errors most recently responded with, for the dashboard
+
+	controllers  controllerGroup // the controllers running for the server's games
+	tournamentMu sync.Mutex      // serializes changes to tournaments
+	puzzleMu     sync.Mutex      // serializes answers to puzzles, which change their ratings
+	snapshots    snapshotter     // copies games to the snapshot directory, if one is configured
+	cluster      *cluster        // routes requests to the nodes that own their games, nil if the server runs on its own
+	snapshotMu   sync.Mutex      // serializes snapshots
+}
+
+// NewServer creates a server with the configuration given. Words played in its
+// games are checked against the validator, unless it is nil. Games are kept in
+// the store, or in memory if it is nil. Players' ratings and the results of
+// games are kept in the store too if it is also a RatingStore and ResultStore,
+// as are registered accounts, tournaments, the devices accounts are sent
+// notifications on, the accounts operators have banned and puzzles if it is an
+// AccountStore, TournamentStore, NotificationStore, BanStore and PuzzleStore,
+// otherwise in memory. Moves for computer players are chosen by the bot
+// strategy, and games can only be created with bots if it isn't nil. Each
+// request is logged to the logger, unless it is nil. Games snapshotted to the
+// configured snapshot directory by a previous server are restored to the
+// store. If the configuration names other nodes sharing the store, requests
+// for games they own are forwarded to them.
+func NewServer(cfg Config, validator dictionary.WordValidator, store GameStore, bot BotStrategy, logger *slog.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if store == nil {
+		store = NewMemoryGameStore()
+	}
+
+	s := &Server{
+		cfg:       cfg,
+		games:     store,
+		validator: validator,
+		bot:       bot,
+		started:   time.Now(),
+	}
+	if ratings, ok := store.(RatingStore); ok {
+		s.ratings = ratings
+	} else {
+		s.ratings = NewMemoryRatingStore()
+	}
+	if results, ok := store.(ResultStore); ok {
+		s.results = results
+	} else {
+		s.results = NewMemoryResultStore()
+	}
+	if tournaments, ok := store.(TournamentStore); ok {
+		s.tournaments = tournaments
+	} else {
+		s.tournaments = NewMemoryTournamentStore()
+	}
+	if accounts, ok := store.(AccountStore); ok {
+		s.accounts = accounts
+	} else {
+		s.accounts = NewMemoryAccountStore()
+	}
+	if notifications, ok := store.(NotificationStore); ok {
+		s.notifications = notifications
+	} else {
+		s.notifications = NewMemoryNotificationStore()
+	}
+	if bans, ok := store.(BanStore); ok {
+		s.bans = bans
+	} else {
+		s.bans = NewMemoryBanStore()
+	}
+	if puzzles, ok := store.(PuzzleStore); ok {
+		s.puzzles = puzzles
+	} else {
+		s.puzzles = NewMemoryPuzzleStore()
+	}
+
+	if cfg.Email.SMTPAddr != "" {
+		s.mailer = NewSMTPMailer(cfg.Email)
+	}
+
+	var err error
+	if s.tokenKey, err = newTokenKey(cfg.AccountSecret); err != nil {
+		return nil, err
+	}
+
+	if cfg.LayoutDir != "" {
+		if s.layouts, err = LoadLayouts(cfg.LayoutDir); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.TileSetDir != "" {
+		if s.tileSets, err = LoadTileSets(cfg.TileSetDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Definitions != "" {
+		if s.definitions, err = dictionary.LoadDefinitions(cfg.Definitions); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.TLS.Cert != "" {
+		if s.tlsConfig, err = LoadTLSConfig(cfg.TLS.Cert, cfg.TLS.Key, cfg.TLS.ClientCA); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.cluster, err = newCluster(cfg.Cluster); err != nil {
+		return nil, err
+	}
+
+	if cfg.Snapshots.Dir != "" {
+		s.snapshots = newSnapshotter(cfg.Snapshots.Dir)
+		if err = s.restoreSnapshots(); err != nil {
+			return nil, err
+		}
+	}
+
+	router := s.newRouter()
+	if logger != nil {
+		router.Use(logRequests(logger))
+	}
+	s.handler = router
+	return s, nil
+}
+
+// Handler returns the handler serving every version of the server's API, for
+// it to be served by another HTTP server or mounted alongside other routes
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// NewHandler returns the handler of a server created with the configuration,
+// without a dictionary or bots and with games kept in memory, for tests and
+// programs embedding the API to serve with httptest.NewServer or mount under a
+// path prefix of their own with http.StripPrefix. Idle games aren't removed
+// and reminders aren't sent, since those only happen in ListenAndServe.
+func NewHandler(cfg Config) (http.Handler, error) {
+	s, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.Handler(), nil
+}
+
+// ListenAndServe serves the API at the configured bind address, over TLS if the
+// configuration has a certificate, so the server can be exposed without a
+// proxy in front of it. Games with no activity for the configured idle TTL are
+// removed while it runs, and players whose turn has waited longer than the
+// reminder delay are emailed. If a snapshot directory is configured, games are
+// copied to it every snapshot interval.
+//
+// The server runs until the context is cancelled, then it waits for in-flight
+// requests to finish, saves every game to the store and stops them, waiting for
+// their controllers to return, and snapshots them a final time.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if s.cfg.IdleTTL > 0 {
+		go reapIdleGames(ctx, s.games, s.cfg.IdleTTL)
+	}
+	if s.mailer != nil && s.cfg.Email.ReminderDelay > 0 {
+		go s.remindIdlePlayers(ctx, s.cfg.Email.ReminderDelay)
+	}
+	if s.cfg.Snapshots.Dir != "" && s.cfg.Snapshots.Interval > 0 {
+		go s.snapshotGames(ctx, s.cfg.Snapshots.Interval)
+	}
+
+	srv := &http.Server{
+		Addr:      s.cfg.BindAddr,
+		Handler:   s.handler,
+		TLSConfig: s.tlsConfig,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.tlsConfig != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Stop accepting requests and let in-flight ones finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+
+	if stopErr := s.stopGames(); err == nil {
+		err = stopErr
+	}
+	if waitErr := s.controllers.wait(shutdownCtx); err == nil {
+		err = waitErr
+	}
+	if s.cfg.Snapshots.Dir != "" {
+		if snapErr := s.snapshot(); err == nil {
+			err = snapErr
+		}
+	}
+	return err
+}
+
+// Controllers returns how many of the server's games have a controller
+// running. Controllers return once their game finishes or is stopped, so this
+// only counts games still being played.
+func (s *Server) Controllers() int {
+	return int(s.controllers.running.Load())
+}
+
+// StartWordGameServer is the function that is run to start the Word Game HTTP
+// server. It creates a server with NewServer and runs it with ListenAndServe
+// until the context is cancelled.
+func StartWordGameServer(ctx context.Context, cfg Config, validator dictionary.WordValidator, store GameStore, bot BotStrategy, logger *slog.Logger) error {
+	s, err := NewServer(cfg, validator, store, bot, logger)
+	if err != nil {
+		return err
+	}
+	return s.ListenAndServe(ctx)
+}
+
+// stopGames saves every game to the store and stops its controller
+func (s *Server) stopGames() error {
+	list, err := s.games.List()
+	if err != nil {
+		return err
+	}
+
+	for _, g := range list {
+		g.Lock()
+		putErr := s.games.Put(g)
+		g.Unlock()
+		g.Stop()
+		if putErr != nil && err == nil {
+			err = putErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package wordgameserver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/google/uuid"
+)
+
+// maxSimulatedMoves is how many moves a simulated game is given to finish.
+// Games end once a player goes out or every player passes in a row, so bots
+// that keep swapping could play forever.
+const maxSimulatedMoves = 1000
+
+// Simulation is a game played between bots without a server, for regression
+// tests of the rules and tuning strategies against each other. Moves are made
+// one after another as soon as each bot chooses them, so nothing in the game
+// is timed.
+type Simulation struct {
+	game   *ScrabbleGame
+	bots   map[uuid.UUID]simulatedBot
+	played bool
+}
+
+// simulatedBot is how a player in a simulation chooses their moves
+type simulatedBot struct {
+	strategy BotStrategy
+	level    string
+}
+
+// SimulationResult is the outcome of a simulated game
+type SimulationResult struct {
+	GameID  uuid.UUID `json:"game_id"`
+	Players []*Player `json:"players"` // players in turn order, with their final scores
+	Winners []int     `json:"winners"` // numbers of the players with the highest score
+	Moves   []Move    `json:"moves"`   // every move made, with the racks they were made from
+}
+
+// CreateGame sets up a game to be simulated with the options, which can't
+// include timers, clocks or challenges. Words are checked against the
+// validator, or the lexicon, language dictionary or words chosen by the
+// options as they would be on a server with it. Seeded games play out the
+// same way every time their bots choose the same moves.
+func CreateGame(opts GameOptions, validator dictionary.WordValidator) (*Simulation, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	} else if opts.TurnTimer > 0 || opts.Clock > 0 || opts.ChallengeWindow > 0 {
+		return nil, errors.New("Simulated games can't have turn timers, clocks or challenges")
+	} else if opts.Layout != nil && opts.Layout.Size == 0 {
+		return nil, errors.New("Simulated games need layouts given in full, not by name")
+	}
+
+	v, err := lookupVariant(opts.Variant)
+	if err != nil {
+		return nil, err
+	}
+	g := createVariantGame(v, opts.Layout, opts.tileSet(), opts.Seed)
+	g.Options = opts
+	g.Validator = gameValidator(validator, opts)
+
+	return &Simulation{game: g, bots: make(map[uuid.UUID]simulatedBot)}, nil
+}
+
+// AddBot seats a bot in the game that chooses its moves with the strategy,
+// playing at the difficulty level. Bots without a name are numbered. Bots
+// take their turns in the order they are added, unless the game is seeded.
+func (s *Simulation) AddBot(name string, strategy BotStrategy, level string) error {
+	if strategy == nil {
+		return errors.New("Missing strategy")
+	} else if !validBotLevel(strategy, level) {
+		return errors.New("Unknown bot level '" + level + "'")
+	}
+
+	s.game.Lock()
+	defer s.game.Unlock()
+	id, err := s.game.addBot(name, level)
+	if err != nil {
+		return err
+	}
+	s.bots[id] = simulatedBot{strategy: strategy, level: level}
+	return nil
+}
+
+// RunToCompletion starts the game and has the bots take their turns until it
+// finishes, returning its result. Moves rejected by the rules are replaced by
+// swapping every tile, or passing if that isn't allowed either, as they are
+// for bots on a server. It gives up if the context is done first or the game
+// hasn't finished after maxSimulatedMoves moves. A game can only be run once.
+func (s *Simulation) RunToCompletion(ctx context.Context) (SimulationResult, error) {
+	g := s.game
+	g.Lock()
+	defer g.Unlock()
+
+	if s.played {
+		return SimulationResult{}, errors.New("Game has already been simulated")
+	}
+	s.played = true
+	if err := g.begin(); err != nil {
+		return SimulationResult{}, err
+	}
+
+	playerList := g.playerList()
+	var game engine.Game = g
+	for !game.Over() {
+		if err := ctx.Err(); err != nil {
+			return SimulationResult{}, err
+		} else if len(g.history) >= maxSimulatedMoves {
+			return SimulationResult{}, errors.New("Game did not finish within " + strconv.Itoa(maxSimulatedMoves) + " moves")
+		}
+
+		p := playerList[game.Turn()]
+		bot := s.bots[p.ID]
+		move := bot.strategy.NextMove(g.getState(p.ID, playerList), bot.level)
+		move.GameID = g.ID
+		move.PlayerID = p.ID
+
+		var err error
+		if move.Swap {
+			err = game.Swap(p.Number, move.Tiles)
+		} else if move, err = resolvePlacements(g.Board, move); err == nil {
+			err = game.Play(p.Number, move.play())
+		}
+		if err != nil {
+			err = game.Swap(p.Number, game.Rack(p.Number))
+		}
+		if err != nil {
+			err = game.Pass(p.Number)
+		}
+		if err != nil {
+			return SimulationResult{}, err
+		}
+	}
+
+	return SimulationResult{
+		GameID:  g.ID,
+		Players: playerList,
+		Winners: g.Winners,
+		Moves:   g.History(),
+	}, nil
+}
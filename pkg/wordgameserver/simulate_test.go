@@ -0,0 +1,93 @@
+package wordgameserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+// playBot makes the highest scoring play from its rack, or swaps every tile
+// if it has none
+type playBot struct {
+	swapBot
+	words *dictionary.DAWG
+}
+
+func (b playBot) NextMove(state GameStateResponse, level string) GamePlayRequest {
+	moves, err := VariantMoves(VariantStandard, state.Board, state.RackLetters(), b.words)
+	if err != nil || len(moves) == 0 {
+		return b.swapBot.NextMove(state, level)
+	}
+	return moves[0].Play
+}
+
+func TestSimulation(t *testing.T) {
+	if _, err := CreateGame(GameOptions{TurnTimer: 30}, nil); err == nil {
+		t.Error("Created a simulation with a turn timer")
+	}
+
+	sim, err := CreateGame(GameOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sim.AddBot("", swapBot{}, "hard"); err == nil {
+		t.Error("Added a bot at an unknown level")
+	}
+	for i := 0; i < 2; i++ {
+		if err = sim.AddBot("", swapBot{}, "easy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Bots that only ever swap never finish the game
+	if _, err = sim.RunToCompletion(context.Background()); err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Errorf("Simulation of bots that only swap returned error %v, expected it not to finish", err)
+	} else if moves := len(sim.game.history); moves != maxSimulatedMoves {
+		t.Errorf("Simulation stopped after %v moves, expected %v", moves, maxSimulatedMoves)
+	}
+	if _, err = sim.RunToCompletion(context.Background()); err == nil {
+		t.Error("Ran a simulation a second time")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sim, _ = CreateGame(GameOptions{}, nil)
+	sim.AddBot("", swapBot{}, "")
+	sim.AddBot("", swapBot{}, "")
+	if _, err = sim.RunToCompletion(ctx); err != context.Canceled {
+		t.Errorf("Cancelled simulation returned error %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestSimulationGoingOut(t *testing.T) {
+	wl, err := dictionary.NewWordList(strings.NewReader("AA\nAAA\nAAAA\nAAAAA\nAAAAAA\nAAAAAAA\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each bot is dealt a full hand of As, which empties the bag, so the
+	// first to play every tile goes out
+	sim, err := CreateGame(GameOptions{TileSet: &TileSet{Tiles: map[string]TileSpec{"A": {Count: 14, Value: 1}}}}, wl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = sim.AddBot("", playBot{words: wl.DAWG()}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := sim.RunToCompletion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(result.Moves) != 1 || len(result.Moves[0].Squares) != maxTiles {
+		t.Fatalf("Simulation made moves %+v, expected the first bot to play every tile", result.Moves)
+	}
+	if score := result.Moves[0].Score + maxTiles; result.Players[0].Score != score || result.Players[1].Score != -maxTiles {
+		t.Errorf("Bots scored %v and %v, expected %v and %v for the tiles left when the first went out", result.Players[0].Score, result.Players[1].Score, score, -maxTiles)
+	} else if len(result.Winners) != 1 || result.Winners[0] != 0 {
+		t.Errorf("Winners are %v, expected the bot that went out", result.Winners)
+	}
+}
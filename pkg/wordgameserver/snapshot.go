@@ -0,0 +1,191 @@
+package wordgameserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// snapshotInterval is how often games are snapshotted, unless configured
+// otherwise
+const snapshotInterval = 30 * time.Second
+
+// snapshotExt is the extension of snapshot files, which are named by the ID
+// of their game
+const snapshotExt = ".json"
+
+// snapshotter writes games to files in a directory, skipping those that
+// haven't changed since they were last written
+type snapshotter struct {
+	dir     string
+	written map[uuid.UUID][sha256.Size]byte // hash of each game as it was last written
+}
+
+// newSnapshotter creates a snapshotter writing games to the directory
+func newSnapshotter(dir string) snapshotter {
+	return snapshotter{dir: dir, written: make(map[uuid.UUID][sha256.Size]byte)}
+}
+
+// snapshotGames periodically snapshots every game in the store until the
+// context is cancelled
+func (s *Server) snapshotGames(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.snapshot(); err != nil {
+				log.Printf("Failed to snapshot games: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// snapshot writes every game in the store to the snapshot directory and
+// removes the snapshots of games that are no longer in it
+func (s *Server) snapshot() error {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	list, err := s.games.List()
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[uuid.UUID]bool, len(list))
+	for _, g := range list {
+		g.Lock()
+		data, encErr := EncodeGame(g)
+		g.Unlock()
+		if encErr != nil {
+			return encErr
+		}
+		kept[g.ID] = true
+
+		hash := sha256.Sum256(data)
+		if s.snapshots.written[g.ID] == hash {
+			continue
+		}
+		if err = writeFileAtomic(s.snapshots.path(g.ID), data); err != nil {
+			return err
+		}
+		s.snapshots.written[g.ID] = hash
+	}
+
+	files, err := s.snapshots.files()
+	if err != nil {
+		return err
+	}
+	for id, path := range files {
+		if kept[id] {
+			continue
+		}
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Failed to remove snapshot")
+		}
+		delete(s.snapshots.written, id)
+	}
+	return nil
+}
+
+// restoreSnapshots adds every game snapshotted to the directory to the store,
+// unless the store already has it, starting the controllers and bots of those
+// still being played. Snapshots that can't be read are logged and skipped, so
+// one corrupt file doesn't stop the server from starting.
+func (s *Server) restoreSnapshots() error {
+	if err := os.MkdirAll(s.snapshots.dir, 0o755); err != nil {
+		return errors.Wrap(err, "Failed to create snapshot directory")
+	}
+
+	files, err := s.snapshots.files()
+	if err != nil {
+		return err
+	}
+	for id, path := range files {
+		if _, err := s.games.Get(id); err == nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read snapshot of game %v: %v", id, err)
+			continue
+		}
+		g, err := DecodeGame(data, s.validator)
+		if err != nil {
+			log.Printf("Failed to restore game %v: %v", id, err)
+			continue
+		}
+
+		g.Lock()
+		s.adoptGame(g)
+		err = s.games.Put(g)
+		if err == nil {
+			g.runController()
+			g.runBots(s.bot)
+		}
+		g.Unlock()
+		if err != nil {
+			return err
+		}
+		s.snapshots.written[id] = sha256.Sum256(data)
+	}
+	return nil
+}
+
+// path returns the file the game with the ID is snapshotted to
+func (sn *snapshotter) path(id uuid.UUID) string {
+	return filepath.Join(sn.dir, id.String()+snapshotExt)
+}
+
+// files returns the snapshot files in the directory by the IDs of their games.
+// Other files are left alone.
+func (sn *snapshotter) files() (map[uuid.UUID]string, error) {
+	entries, err := os.ReadDir(sn.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list snapshots")
+	}
+
+	files := make(map[uuid.UUID]string, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), snapshotExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if id, err := uuid.Parse(name); err == nil {
+			files[id] = filepath.Join(sn.dir, e.Name())
+		}
+	}
+	return files, nil
+}
+
+// writeFileAtomic writes the data to a temporary file next to the path, then
+// renames it into place, so a crash part way through leaves the previous
+// contents rather than a partial file
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create snapshot")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrap(err, "Failed to write snapshot")
+	} else if err = f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "Failed to write snapshot")
+	} else if err = f.Close(); err != nil {
+		return errors.Wrap(err, "Failed to write snapshot")
+	}
+	return errors.Wrap(os.Rename(f.Name(), path), "Failed to write snapshot")
+}
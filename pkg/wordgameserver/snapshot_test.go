@@ -0,0 +1,75 @@
+package wordgameserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSnapshots(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Snapshots.Dir = filepath.Join(t.TempDir(), "snapshots")
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Lock()
+	srv.adoptGame(g)
+	if err = g.start(); err != nil {
+		t.Fatal(err)
+	}
+	err = srv.games.Put(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	passed, err := g.request(GamePlayRequest{PlayerID: ids[0], Type: passRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files that aren't snapshots are left alone, while those of games no
+	// longer in the store are removed
+	stale := filepath.Join(cfg.Snapshots.Dir, uuid.New().String()+snapshotExt)
+	other := filepath.Join(cfg.Snapshots.Dir, "notes.txt")
+	for _, path := range []string{stale, other} {
+		if err = os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = srv.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	g.Stop()
+	if _, err = os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Snapshot of a removed game was kept")
+	}
+	if _, err = os.Stat(other); err != nil {
+		t.Errorf("Other file was removed: %v", err)
+	}
+
+	// A new server picks up where the old one stopped
+	restored, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := restored.games.Get(g.ID)
+	if err != nil {
+		t.Fatalf("Game wasn't restored: %v", err)
+	}
+	defer r.Stop()
+	if restored.Controllers() != 1 {
+		t.Errorf("%v controllers are running, expected the restored game's", restored.Controllers())
+	}
+
+	state, err := r.request(GamePlayRequest{PlayerID: ids[1]})
+	if err != nil {
+		t.Fatal(err)
+	} else if !state.Active || state.PlayerTurn != passed.PlayerTurn {
+		t.Errorf("Restored game is on player %v's turn, expected player %v's after the pass", state.PlayerTurn, passed.PlayerTurn)
+	}
+}
@@ -0,0 +1,144 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Solutions returned by a solve request when it doesn't give a limit, and the
+// most it can ask for
+const (
+	defaultSolveLimit = 20
+	maxSolveLimit     = 100
+)
+
+// Solver is a BotStrategy that can also list every play available from a
+// rack. A server whose bot strategy is a Solver answers solve requests.
+type Solver interface {
+	// Solve returns the plays that can be made on the board from the rack,
+	// scored by the rules of the named variant, highest scoring first
+	Solve(variant string, board ScrabbleBoard, rack Letters) []Solution
+}
+
+// Solution is a play available from a rack, with the words it forms and the
+// points it scores
+type Solution struct {
+	Play  GamePlayRequest `json:"play"`
+	Words []string        `json:"words"`
+	Score int             `json:"score"`
+}
+
+// SolveRequest is the format of the request a client sends to find the words
+// that can be played from a rack
+type SolveRequest struct {
+	Rack    Letters       `json:"rack"`              // tiles to play, with blanks as " "
+	Board   ScrabbleBoard `json:"board,omitempty"`   // board to play on, or an empty one to find the best play of each word
+	Variant string        `json:"variant,omitempty"` // variant whose board and tile values are used, VariantStandard if empty
+	Limit   int           `json:"limit,omitempty"`   // most solutions to return
+}
+
+// SolveResponse is the format of the response sent to clients with the plays
+// available from a rack
+type SolveResponse struct {
+	Solutions []Solution `json:"solutions"`
+}
+
+// validate checks the rack and board of a solve request can be played, since
+// solvers play from the server's English dictionary
+func (j SolveRequest) validate(v *variant) error {
+	if len(j.Rack) == 0 || len(j.Rack) > maxTiles {
+		return errors.New("Rack must have between 1 and " + strconv.Itoa(maxTiles) + " tiles")
+	}
+	for _, l := range j.Rack {
+		if l != ' ' && (l < 'A' || l > 'Z') {
+			return errors.New("Rack can only hold letters from A to Z and blanks")
+		}
+	}
+
+	if j.Board == nil {
+		return nil
+	} else if len(j.Board) != v.size {
+		return errors.New("Board must have " + strconv.Itoa(v.size) + " rows")
+	}
+	for _, row := range j.Board {
+		if len(row) != v.size {
+			return errors.New("Board must have " + strconv.Itoa(v.size) + " columns")
+		}
+		for _, squ := range row {
+			if squ.Letter != 0 && (squ.Letter < 'A' || squ.Letter > 'Z') {
+				return errors.New("Board can only hold letters from A to Z")
+			}
+		}
+	}
+	return nil
+}
+
+// solveHandler handles requests for the plays available from a rack, highest
+// scoring first. Without a board, the best play of each word on an empty board
+// is returned, so a rack's anagrams can be found.
+func (s *Server) solveHandler(w http.ResponseWriter, r *http.Request) {
+	var j SolveRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	solver, ok := s.bot.(Solver)
+	if !ok {
+		http.Error(w, "Server has no solver available", http.StatusBadRequest)
+		return
+	}
+	v, err := lookupVariant(j.Variant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = j.validate(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if j.Limit == 0 {
+		j.Limit = defaultSolveLimit
+	} else if j.Limit < 0 || j.Limit > maxSolveLimit {
+		http.Error(w, "Limit must be between 1 and "+strconv.Itoa(maxSolveLimit), http.StatusBadRequest)
+		return
+	}
+
+	board := j.Board
+	if board == nil {
+		board = v.newBoard()
+	}
+	solutions := append([]Solution{}, solver.Solve(j.Variant, board, j.Rack)...)
+
+	if j.Board == nil {
+		// Every word can be played in several places on an empty board,
+		// only the best of which is interesting
+		best := solutions[:0]
+		seen := make(map[string]bool)
+		for _, sol := range solutions {
+			if !seen[sol.Words[0]] {
+				seen[sol.Words[0]] = true
+				best = append(best, sol)
+			}
+		}
+		solutions = best
+	}
+	if len(solutions) > j.Limit {
+		solutions = solutions[:j.Limit]
+	}
+
+	resp, err := json.Marshal(SolveResponse{Solutions: solutions})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
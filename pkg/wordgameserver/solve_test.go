@@ -0,0 +1,90 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+)
+
+// solveBot is a swapBot that solves every rack with the same plays
+type solveBot struct {
+	swapBot
+	solutions []Solution
+}
+
+func (b solveBot) Solve(variant string, board ScrabbleBoard, rack Letters) []Solution {
+	return b.solutions
+}
+
+func TestSolveHandler(t *testing.T) {
+	cat := Solution{Words: []string{"CAT"}, Score: 10, Play: GamePlayRequest{Tiles: Letters("CAT")}}
+	catDown := Solution{Words: []string{"CAT"}, Score: 8, Play: GamePlayRequest{Tiles: Letters("CAT")}}
+	at := Solution{Words: []string{"AT"}, Score: 4, Play: GamePlayRequest{Tiles: Letters("AT")}}
+	srv, err := NewServer(DefaultConfig(), nil, nil, solveBot{solutions: []Solution{cat, catDown, at}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solve := func(j SolveRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/v2/solve", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name     string
+		req      SolveRequest
+		expected []Solution
+	}{
+		{name: "anagrams", req: SolveRequest{Rack: Letters("CAT")}, expected: []Solution{cat, at}},
+		{name: "board", req: SolveRequest{Rack: Letters("CAT"), Board: NewBoard()}, expected: []Solution{cat, catDown, at}},
+		{name: "limit", req: SolveRequest{Rack: Letters("CAT"), Limit: 1}, expected: []Solution{cat}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := solve(tt.req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+			}
+			var resp SolveResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(resp.Solutions, tt.expected) {
+				t.Errorf("Returned solutions %+v, expected %+v", resp.Solutions, tt.expected)
+			}
+		})
+	}
+
+	invalid := []SolveRequest{
+		{},
+		{Rack: Letters("CATSDOGS")},
+		{Rack: Letters{engine.TileÑ}},
+		{Rack: Letters("CAT"), Board: NewBoard()[:10]},
+		{Rack: Letters("CAT"), Variant: "chess"},
+		{Rack: Letters("CAT"), Limit: maxSolveLimit + 1},
+	}
+	for _, j := range invalid {
+		if rr := solve(j); rr.Code != http.StatusBadRequest {
+			t.Errorf("Solving %+v returned status code %v, expected %v", j, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	srv.bot = swapBot{}
+	if rr := solve(SolveRequest{Rack: Letters("CAT")}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Solving without a solver returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,148 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Events sent on a player's Server-Sent Events stream
+const (
+	sseState    = "state"    // data is the player's GameStateResponse
+	sseMove     = "move"     // data is a Move made since the stream opened
+	sseReaction = "reaction" // data is a Reaction to the most recent move, made since the stream opened
+)
+
+// sseKeepAlive is how often a comment is sent on an idle event stream, so
+// proxies don't close it
+const sseKeepAlive = 15 * time.Second
+
+// gameEventsHandler streams a player's GameStateResponse every time the game
+// state changes as Server-Sent Events, along with each move made and each
+// reaction to it, for clients that can't use WebSockets. The game is
+// identified by its path or the game_id query parameter, and the player by the
+// player_id query parameter. Players who give the number of moves they have
+// seen as the since parameter are sent diffs instead of whole states.
+func (s *Server) gameEventsHandler(w http.ResponseWriter, r *http.Request) {
+	g, playerID, err := s.getWatcher(w, r)
+	if err != nil {
+		return
+	}
+	since, ok := querySince(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates := g.subscribe(playerID)
+	defer g.unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx buffering the stream
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Only moves made after subscribing are sent, as the history endpoint
+	// has the rest
+	g.Lock()
+	active := g.Active
+	seen, undos := len(g.history), g.undoCount()
+	reacted := make(map[Reaction]bool)
+	for _, reaction := range g.reactions {
+		reacted[reaction] = true
+	}
+	g.Unlock()
+	if active {
+		state, err := g.request(GamePlayRequest{
+			GameID:   g.ID,
+			PlayerID: playerID,
+			ctx:      r.Context(),
+		})
+		if err != nil || writeEvent(w, sseState, g.diffSince(state, since)) != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case state, ok := <-updates:
+			if !ok {
+				// Player resumed their session on another connection
+				return
+			}
+
+			g.Lock()
+			// Moves undone in a friendly game may have been replaced
+			// since they were sent, so moves are sent again from the
+			// earliest one undone
+			seen = g.undoneSince(undos, seen)
+			undos = g.undoCount()
+			moves := g.History()[seen:]
+			if !g.Finished {
+				// Racks would give away the tiles players hold
+				for i := range moves {
+					moves[i].Rack = nil
+					moves[i].Swapped = nil
+				}
+			}
+			seen += len(moves)
+			g.Unlock()
+
+			for _, m := range moves {
+				if err := writeEvent(w, sseMove, m); err != nil {
+					return
+				}
+			}
+			for _, reaction := range state.Reactions {
+				if reacted[reaction] {
+					continue
+				}
+				reacted[reaction] = true
+				if err := writeEvent(w, sseReaction, reaction); err != nil {
+					return
+				}
+			}
+			if err := writeEvent(w, sseState, g.diffSince(state, since)); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-g.done:
+			// Game was cancelled or removed, or has finished. The final
+			// state of a finished game is sent before it stops, so any
+			// state still waiting is delivered first.
+			if len(updates) > 0 {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// writeEvent writes the value to an event stream as a JSON encoded event of
+// the given name
+func writeEvent(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("event: " + event + "\ndata: " + string(data) + "\n\n"))
+	return err
+}
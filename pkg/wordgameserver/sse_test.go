@@ -0,0 +1,232 @@
+package wordgameserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGameEventsHandler(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	firstID, _ := newGame.addPlayer("ashley1")
+	secondID, _ := newGame.addPlayer("ashley2")
+
+	s := httptest.NewServer(http.HandlerFunc(srv.gameEventsHandler))
+	defer s.Close()
+
+	// Streaming as a player that isn't in the game should fail
+	resp, err := http.Get(s.URL + "?game_id=" + newGame.ID.String() + "&player_id=" + uuid.New().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Returned status code %v, expected %v", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp, err = http.Get(s.URL + "?game_id=" + newGame.ID.String() + "&player_id=" + secondID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Returned content type %v, expected text/event-stream", ct)
+	}
+
+	// Wait for the handler to subscribe before starting the game
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		newGame.watchMu.Lock()
+		subscribed = len(newGame.watchers) == 1
+		newGame.watchMu.Unlock()
+	}
+
+	newGame.Lock()
+	err = newGame.start()
+	newGame.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := readEvents(resp)
+
+	// Game start should be pushed to the client
+	var state GameStateResponse
+	readEvent(t, events, sseState, &state)
+	if len(state.PlayerTiles) != maxTiles {
+		t.Fatalf("Pushed state has %v tiles, expected %v", len(state.PlayerTiles), maxTiles)
+	}
+
+	// Swapping on the first turn should send the move, then the new state
+	first, err := newGame.request(GamePlayRequest{GameID: newGame.ID, PlayerID: firstID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newGame.request(GamePlayRequest{
+		GameID:   newGame.ID,
+		PlayerID: firstID,
+		Tiles:    first.RackLetters()[:2],
+		Swap:     true,
+		Type:     playRequest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m Move
+	readEvent(t, events, sseMove, &m)
+	if !m.Swap || m.Swapped != nil {
+		t.Errorf("Pushed move %+v, expected swap without the tiles swapped", m)
+	}
+	readEvent(t, events, sseState, &state)
+	if state.PlayerTurn == first.PlayerTurn {
+		t.Error("Pushed state is still on the first turn")
+	}
+
+	// Reactions to the move are sent before the state that shows them
+	if _, err = newGame.react(ReactionRequest{PlayerID: secondID, Emote: Emotes[0]}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var reaction Reaction
+	readEvent(t, events, sseReaction, &reaction)
+	if reaction.Player != 1 || reaction.Move != 0 || reaction.Emote != Emotes[0] {
+		t.Errorf("Pushed reaction %+v, expected the second player's to the first move", reaction)
+	}
+	readEvent(t, events, sseState, &state)
+	if len(state.Reactions) != 1 {
+		t.Errorf("Pushed state has %v reactions, expected 1", len(state.Reactions))
+	}
+}
+
+func TestGameEventsUndo(t *testing.T) {
+	srv := newTestServer(t)
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Friendly = true
+	srv.games.Put(g)
+
+	s := httptest.NewServer(http.HandlerFunc(srv.gameEventsHandler))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "?game_id=" + g.ID.String() + "&player_id=" + ids[1].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		g.watchMu.Lock()
+		subscribed = len(g.watchers) == 1
+		g.watchMu.Unlock()
+	}
+
+	g.Lock()
+	err = g.start()
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	events := readEvents(resp)
+	var state GameStateResponse
+	readEvent(t, events, sseState, &state)
+
+	play := GamePlayRequest{
+		PlayerID: ids[0],
+		Type:     playRequest,
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	}
+	if _, err = g.request(play); err != nil {
+		t.Fatal(err)
+	}
+	var m Move
+	readEvent(t, events, sseMove, &m)
+	readEvent(t, events, sseState, &state)
+
+	// The play is undone and replaced by another, which is sent even though
+	// as many moves have been made as had been sent before the undo
+	for _, id := range []uuid.UUID{ids[0], ids[1]} {
+		if _, err = g.request(GamePlayRequest{PlayerID: id, Type: undoRequest}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	play.StartPos, play.EndPos, play.Tiles = SquareCoordinate{Row: 7, Col: 7}, SquareCoordinate{Row: 7, Col: 8}, Letters("TA")
+	if _, err = g.request(play); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("Event stream closed before the replacement move was sent")
+			} else if e.name != sseMove {
+				continue
+			} else if err = json.Unmarshal([]byte(e.data), &m); err != nil {
+				t.Fatal(err)
+			} else if len(m.Words) != 1 || m.Words[0] != "TA" {
+				t.Fatalf("Pushed move %+v, expected the replacement TA", m)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("Replacement move was not sent")
+		}
+	}
+}
+
+type streamEvent struct {
+	name string
+	data string
+}
+
+// readEvents parses the Server-Sent Events in the response body and sends
+// them on the returned channel
+func readEvents(resp *http.Response) <-chan streamEvent {
+	events := make(chan streamEvent)
+	go func() {
+		defer close(events)
+		var e streamEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			case line == "" && e.name != "":
+				events <- e
+				e = streamEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+func readEvent(t *testing.T, events <-chan streamEvent, name string, v interface{}) {
+	t.Helper()
+
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("Event stream closed")
+		} else if e.name != name {
+			t.Fatalf("Received %v event, expected %v", e.name, name)
+		} else if err := json.Unmarshal([]byte(e.data), v); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Did not receive %v event", name)
+	}
+}
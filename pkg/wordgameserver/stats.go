@@ -0,0 +1,137 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// PlayerStats summarizes every game a registered player has finished
+type PlayerStats struct {
+	AccountID       uuid.UUID `json:"account_id"`
+	Player          string    `json:"player"`
+	Rating          int       `json:"rating,omitempty"`        // current rating, if the player has played a rated game
+	PuzzleRating    int       `json:"puzzle_rating,omitempty"` // current puzzle rating, if the player has answered a puzzle
+	Puzzles         int       `json:"puzzles"`                 // number of puzzles the player has answered
+	Games           int       `json:"games"`
+	Wins            int       `json:"wins"`
+	WinRate         float64   `json:"win_rate"` // fraction of games won, from 0 to 1
+	AverageScore    float64   `json:"average_score"`
+	BestWord        string    `json:"best_word,omitempty"`  // main word of the player's highest-scoring play
+	BestScore       int       `json:"best_score,omitempty"` // points scored by that play
+	Bingos          int       `json:"bingos"`
+	AverageTurnTime float64   `json:"average_turn_time"` // seconds the player takes over a move
+
+	// How the player's moves compare with the best plays available, over
+	// the games that were analysed
+	AnalyzedMoves     int     `json:"analyzed_moves"`
+	AverageEquityLoss float64 `json:"average_equity_loss"`         // equity given up against the best play per move
+	BiggestMiss       string  `json:"biggest_miss,omitempty"`      // main word of the play missed by the most equity
+	BiggestMissLoss   float64 `json:"biggest_miss_loss,omitempty"` // equity given up by missing it
+	MissedBingos      int     `json:"missed_bingos"`
+}
+
+// playerStats totals the results of the player's games
+func playerStats(a Account, results []GameResult) PlayerStats {
+	stats := PlayerStats{AccountID: a.ID, Player: a.Username}
+	var score, turns int
+	var turnTime, equityLost float64
+	for _, r := range results {
+		stats.Games++
+		if r.Won {
+			stats.Wins++
+		}
+		score += r.Score
+		stats.Bingos += r.Bingos
+		if r.BestScore > stats.BestScore {
+			stats.BestWord, stats.BestScore = r.BestWord, r.BestScore
+		}
+		turns += r.Turns
+		turnTime += r.TurnTime.Seconds()
+
+		stats.AnalyzedMoves += r.AnalyzedMoves
+		equityLost += r.EquityLost
+		stats.MissedBingos += r.MissedBingos
+		if r.BiggestMissLoss > stats.BiggestMissLoss {
+			stats.BiggestMiss, stats.BiggestMissLoss = r.BiggestMiss, r.BiggestMissLoss
+		}
+	}
+
+	if stats.Games > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(stats.Games)
+		stats.AverageScore = float64(score) / float64(stats.Games)
+	}
+	if turns > 0 {
+		stats.AverageTurnTime = turnTime / float64(turns)
+	}
+	if stats.AnalyzedMoves > 0 {
+		stats.AverageEquityLoss = equityLost / float64(stats.AnalyzedMoves)
+	}
+	return stats
+}
+
+// lookupAccount retrieves the account identified by the request's player path
+// variable, which is either the account's ID or its username. Responds with an
+// error if there is no such account.
+func (s *Server) lookupAccount(w http.ResponseWriter, r *http.Request) (Account, bool) {
+	id := mux.Vars(r)["player"]
+
+	var a Account
+	var err error
+	if accountID, parseErr := uuid.Parse(id); parseErr == nil {
+		a, err = s.accounts.GetAccount(accountID)
+	} else {
+		a, err = s.accounts.GetAccountByName(strings.ToLower(id))
+	}
+	if err == ErrAccountNotFound {
+		http.Error(w, "No registered player '"+id+"'", http.StatusNotFound)
+		return a, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return a, false
+	}
+	return a, true
+}
+
+// playerStatsHandler handles requests for the statistics of a registered
+// player, computed from the results of every game they have finished
+func (s *Server) playerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.lookupAccount(w, r)
+	if !ok {
+		return
+	}
+
+	results, err := s.results.GetPlayerResults(a.Username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ratings, err := s.ratings.GetRatings([]string{a.ID.String()})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	puzzleRatings, err := s.puzzles.GetPuzzleRatings([]string{a.Username})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stats := playerStats(a, results)
+	stats.Rating = ratings[a.ID.String()].Rating
+	stats.PuzzleRating = puzzleRatings[a.Username].Rating
+	stats.Puzzles = puzzleRatings[a.Username].Games
+	resp, err := json.Marshal(stats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -0,0 +1,88 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPlayerStatsHandler(t *testing.T) {
+	srv := newTestServer(t)
+
+	a, err := newAccount("ashley", "hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.accounts.CreateAccount(a); err != nil {
+		t.Fatal(err)
+	}
+	srv.ratings.PutRatings([]Rating{{Player: a.ID.String(), Rating: 1532, Games: 2}})
+	srv.puzzles.PutPuzzleRatings([]Rating{{Player: "ashley", Rating: 1480, Games: 4}})
+	srv.results.PutResults([]GameResult{
+		{GameID: uuid.New(), Player: "ashley", Score: 400, Won: true, Bingos: 2, BestWord: "QUIXOTIC", BestScore: 131,
+			Turns: 10, TurnTime: 5 * time.Minute, Finished: time.Now(),
+			AnalyzedMoves: 10, EquityLost: 45, BiggestMiss: "JINX", BiggestMissLoss: 20, MissedBingos: 1},
+		{GameID: uuid.New(), Player: "ashley", Score: 300, BestWord: "ZAX", BestScore: 62,
+			Turns: 20, TurnTime: 5 * time.Minute, Finished: time.Now(),
+			AnalyzedMoves: 20, EquityLost: 75, BiggestMiss: "QUIXOTIC", BiggestMissLoss: 35.5, MissedBingos: 2},
+		{GameID: uuid.New(), Player: "ashley", Score: 250, Finished: time.Now()},
+		{GameID: uuid.New(), Player: "ashley2", Score: 500, Won: true, BestWord: "OXYPHENBUTAZONE", BestScore: 1778,
+			Turns: 10, TurnTime: time.Minute, Finished: time.Now()},
+	})
+
+	get := func(player string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/v2/players/"+player+"/stats", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	want := PlayerStats{
+		AccountID:       a.ID,
+		Player:          "ashley",
+		Rating:          1532,
+		PuzzleRating:    1480,
+		Puzzles:         4,
+		Games:           3,
+		Wins:            1,
+		WinRate:         1.0 / 3,
+		AverageScore:    950.0 / 3,
+		BestWord:        "QUIXOTIC",
+		BestScore:       131,
+		Bingos:          2,
+		AverageTurnTime: 20,
+
+		AnalyzedMoves:     30,
+		AverageEquityLoss: 4,
+		BiggestMiss:       "QUIXOTIC",
+		BiggestMissLoss:   35.5,
+		MissedBingos:      3,
+	}
+	for _, player := range []string{a.ID.String(), "Ashley"} {
+		rr := get(player)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Getting stats of %v returned status code %v, expected %v. Error: %v", player, rr.Code, http.StatusOK, rr.Body)
+			continue
+		}
+		var stats PlayerStats
+		if err = json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+			t.Fatal(err)
+		} else if stats != want {
+			t.Errorf("Stats of %v are %+v, expected %+v", player, stats, want)
+		}
+	}
+
+	// Only registered players have stats
+	for _, player := range []string{"ashley2", uuid.NewString()} {
+		if rr := get(player); rr.Code != http.StatusNotFound {
+			t.Errorf("Getting stats of %v returned status code %v, expected %v", player, rr.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package wordgameserver
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrGameNotFound is returned by a GameStore when no game has the requested ID
+// or join code
+var ErrGameNotFound = errors.New("Game does not exist")
+
+// GameStore holds the games hosted by the server, and an index of the join
+// codes of private games. Implementations must be safe for concurrent use.
+// Games are locked by the caller when they are passed to Put.
+type GameStore interface {
+	Get(id uuid.UUID) (*ScrabbleGame, error) // retrieve a game, or ErrGameNotFound
+	Put(g *ScrabbleGame) error               // add a game or save changes to it
+	Delete(id uuid.UUID) error               // remove a game
+	List() ([]*ScrabbleGame, error)          // retrieve every game
+
+	ClaimJoinCode(code string, id uuid.UUID) (bool, error) // index the join code for the game, false if another game has it
+	GameByJoinCode(code string) (uuid.UUID, error)         // retrieve the ID of the game with the join code, or ErrGameNotFound
+	ReleaseJoinCode(code string) error                     // remove the join code from the index
+}
+
+// storeShards is how many parts a MemoryGameStore's games are split between,
+// each with its own lock, so requests for different games rarely wait for
+// each other. It must be a power of two.
+const storeShards = 64
+
+// MemoryGameStore is the default GameStore, which keeps games in memory for
+// the lifetime of the process
+type MemoryGameStore struct {
+	shards [storeShards]gameShard
+
+	codesMu sync.Mutex
+	codes   map[string]uuid.UUID // IDs of private games by their join codes
+}
+
+// gameShard holds the games of a MemoryGameStore whose IDs fall in the shard
+type gameShard struct {
+	sync.RWMutex
+	games map[uuid.UUID]*ScrabbleGame
+}
+
+// NewMemoryGameStore creates an empty in-memory game store
+func NewMemoryGameStore() *MemoryGameStore {
+	ms := &MemoryGameStore{codes: make(map[string]uuid.UUID)}
+	for i := range ms.shards {
+		ms.shards[i].games = make(map[uuid.UUID]*ScrabbleGame)
+	}
+	return ms
+}
+
+// shard returns the shard holding the game with the ID. IDs are random, so
+// any of their bytes spreads games evenly.
+func (ms *MemoryGameStore) shard(id uuid.UUID) *gameShard {
+	return &ms.shards[int(id[0])&(storeShards-1)]
+}
+
+// Get retrieves the game with the given ID
+func (ms *MemoryGameStore) Get(id uuid.UUID) (*ScrabbleGame, error) {
+	sh := ms.shard(id)
+	sh.RLock()
+	defer sh.RUnlock()
+	g, ok := sh.games[id]
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return g, nil
+}
+
+// Put adds the game to the store, replacing any game with the same ID
+func (ms *MemoryGameStore) Put(g *ScrabbleGame) error {
+	sh := ms.shard(g.ID)
+	sh.Lock()
+	sh.games[g.ID] = g
+	sh.Unlock()
+	return nil
+}
+
+// Delete removes the game with the given ID, if there is one
+func (ms *MemoryGameStore) Delete(id uuid.UUID) error {
+	sh := ms.shard(id)
+	sh.Lock()
+	delete(sh.games, id)
+	sh.Unlock()
+	return nil
+}
+
+// List retrieves every game in the store. Shards are locked one at a time, so
+// games added or removed while listing may or may not be included.
+func (ms *MemoryGameStore) List() ([]*ScrabbleGame, error) {
+	var games []*ScrabbleGame
+	for i := range ms.shards {
+		sh := &ms.shards[i]
+		sh.RLock()
+		for _, g := range sh.games {
+			games = append(games, g)
+		}
+		sh.RUnlock()
+	}
+	return games, nil
+}
+
+// ClaimJoinCode indexes the join code for the game with the ID, unless another
+// game has it
+func (ms *MemoryGameStore) ClaimJoinCode(code string, id uuid.UUID) (bool, error) {
+	ms.codesMu.Lock()
+	defer ms.codesMu.Unlock()
+	if _, ok := ms.codes[code]; ok {
+		return false, nil
+	}
+	ms.codes[code] = id
+	return true, nil
+}
+
+// GameByJoinCode retrieves the ID of the game with the join code
+func (ms *MemoryGameStore) GameByJoinCode(code string) (uuid.UUID, error) {
+	ms.codesMu.Lock()
+	defer ms.codesMu.Unlock()
+	id, ok := ms.codes[code]
+	if !ok {
+		return uuid.Nil, ErrGameNotFound
+	}
+	return id, nil
+}
+
+// ReleaseJoinCode removes the join code from the index, if it is there
+func (ms *MemoryGameStore) ReleaseJoinCode(code string) error {
+	ms.codesMu.Lock()
+	delete(ms.codes, code)
+	ms.codesMu.Unlock()
+	return nil
+}
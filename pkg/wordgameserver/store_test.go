@@ -0,0 +1,179 @@
+package wordgameserver
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryGameStore(t *testing.T) {
+	ms := NewMemoryGameStore()
+	games := []*ScrabbleGame{createScrabbleGame(), createScrabbleGame()}
+
+	for _, g := range games {
+		if err := ms.Put(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := ms.Get(games[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if g != games[0] {
+		t.Error("Retrieved the wrong game")
+	}
+
+	if _, err = ms.Get(uuid.New()); err != ErrGameNotFound {
+		t.Errorf("Expected ErrGameNotFound for unknown ID, got %v", err)
+	}
+
+	if list, err := ms.List(); err != nil {
+		t.Fatal(err)
+	} else if len(list) != len(games) {
+		t.Errorf("Listed %v games, expected %v", len(list), len(games))
+	}
+
+	if err = ms.Delete(games[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ms.Get(games[0].ID); err != ErrGameNotFound {
+		t.Error("Deleted game should not be retrievable")
+	}
+}
+
+func TestMemoryGameStoreJoinCodes(t *testing.T) {
+	ms := NewMemoryGameStore()
+	id := uuid.New()
+
+	if ok, err := ms.ClaimJoinCode("ABC234", id); err != nil || !ok {
+		t.Fatalf("Claiming an unused code returned %v, %v", ok, err)
+	}
+	if ok, _ := ms.ClaimJoinCode("ABC234", uuid.New()); ok {
+		t.Error("Claimed a code another game has")
+	}
+	if got, err := ms.GameByJoinCode("ABC234"); err != nil || got != id {
+		t.Errorf("Found game %v by code, expected %v", got, id)
+	}
+
+	if err := ms.ReleaseJoinCode("ABC234"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ms.GameByJoinCode("ABC234"); err != ErrGameNotFound {
+		t.Errorf("Finding a released code returned %v, expected %v", err, ErrGameNotFound)
+	}
+}
+
+// lockedGameStore is a GameStore with a single lock over every game, as the
+// MemoryGameStore was before it was sharded, for benchmarks to compare it to
+type lockedGameStore struct {
+	sync.Mutex
+	games map[uuid.UUID]*ScrabbleGame
+	codes map[string]uuid.UUID
+}
+
+func (ls *lockedGameStore) Get(id uuid.UUID) (*ScrabbleGame, error) {
+	ls.Lock()
+	defer ls.Unlock()
+	g, ok := ls.games[id]
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return g, nil
+}
+
+func (ls *lockedGameStore) Put(g *ScrabbleGame) error {
+	ls.Lock()
+	ls.games[g.ID] = g
+	ls.Unlock()
+	return nil
+}
+
+func (ls *lockedGameStore) Delete(id uuid.UUID) error {
+	ls.Lock()
+	delete(ls.games, id)
+	ls.Unlock()
+	return nil
+}
+
+func (ls *lockedGameStore) List() ([]*ScrabbleGame, error) {
+	ls.Lock()
+	defer ls.Unlock()
+	games := make([]*ScrabbleGame, 0, len(ls.games))
+	for _, g := range ls.games {
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+func (ls *lockedGameStore) ClaimJoinCode(code string, id uuid.UUID) (bool, error) {
+	ls.Lock()
+	defer ls.Unlock()
+	if _, ok := ls.codes[code]; ok {
+		return false, nil
+	}
+	ls.codes[code] = id
+	return true, nil
+}
+
+func (ls *lockedGameStore) GameByJoinCode(code string) (uuid.UUID, error) {
+	ls.Lock()
+	defer ls.Unlock()
+	id, ok := ls.codes[code]
+	if !ok {
+		return uuid.Nil, ErrGameNotFound
+	}
+	return id, nil
+}
+
+func (ls *lockedGameStore) ReleaseJoinCode(code string) error {
+	ls.Lock()
+	delete(ls.codes, code)
+	ls.Unlock()
+	return nil
+}
+
+// BenchmarkGameStore looks up games from many goroutines at once, creating a
+// new one every tenth request, as a busy server does. Run it with -cpu to see
+// how each store scales.
+func BenchmarkGameStore(b *testing.B) {
+	stores := []struct {
+		name  string
+		store GameStore
+	}{
+		{"Locked", &lockedGameStore{games: make(map[uuid.UUID]*ScrabbleGame), codes: make(map[string]uuid.UUID)}},
+		{"Sharded", NewMemoryGameStore()},
+	}
+
+	games := make([]*ScrabbleGame, 1024)
+	for i := range games {
+		games[i] = newScrabbleGame()
+	}
+
+	for _, s := range stores {
+		for _, g := range games {
+			s.store.Put(g)
+		}
+		b.Run(s.name, func(b *testing.B) {
+			var workers atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				// Each goroutine starts on different games
+				n := workers.Add(1) * 97
+				created := &ScrabbleGame{}
+				for pb.Next() {
+					n++
+					if n%10 == 0 {
+						created.ID = uuid.New()
+						s.store.Put(created)
+						s.store.Delete(created.ID)
+						continue
+					}
+					if _, err := s.store.Get(games[n%int64(len(games))].ID); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
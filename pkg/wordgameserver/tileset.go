@@ -0,0 +1,109 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/pkg/errors"
+)
+
+// Limits on the number of tiles in a custom set. There must be enough for two
+// players to fill their hands.
+const (
+	minTileSetSize = 2 * maxTiles
+	maxTileSetSize = 400
+)
+
+// blankKey is how a blank tile is written in a tile set, as it is in GCG files
+const blankKey = "?"
+
+// TileSet is a bag of tiles chosen by a game's creator, or loaded by the
+// server from its tile set directory, played with instead of the variant's own
+type TileSet struct {
+	Name  string              `json:"name,omitempty"`  // name of a tile set loaded by the server, or a label for one given in full
+	Tiles map[string]TileSpec `json:"tiles,omitempty"` // how many of each letter there are and what they are worth, with blanks as "?" and digraphs such as "CH" spelled out; empty to use the named set
+}
+
+// TileSpec is how many tiles of a letter are in a set and their value
+type TileSpec struct {
+	Count int `json:"count"`
+	Value int `json:"value"`
+}
+
+// validate checks that the set holds letters that can be played, and enough
+// of them for a game
+func (ts TileSet) validate() error {
+	total := 0
+	for key, spec := range ts.Tiles {
+		if l, ok := engine.ParseLetter(key); (!ok || !engine.KnownLetter(l)) && key != blankKey {
+			return errors.New("Tile '" + key + "' must be a letter from A to Z, one of Ä, Ö, Ü, Ñ, CH, LL and RR, or '" + blankKey + "' for a blank")
+		} else if spec.Count < 1 || spec.Value < 0 {
+			return errors.New("Tile '" + key + "' must have a count of at least 1 and a value that isn't negative")
+		}
+		total += spec.Count
+	}
+
+	if total < minTileSetSize || total > maxTileSetSize {
+		return errors.New("Tile set must have between " + strconv.Itoa(minTileSetSize) + " and " + strconv.Itoa(maxTileSetSize) + " tiles")
+	}
+	return nil
+}
+
+// tiles returns the set indexed by the letter of each tile
+func (ts TileSet) tiles() map[Letter]Tile {
+	set := make(map[Letter]Tile, len(ts.Tiles))
+	for key, spec := range ts.Tiles {
+		letter, _ := engine.ParseLetter(key)
+		if key == blankKey {
+			letter = ' '
+		}
+		set[letter] = Tile{Letter: letter, Count: spec.Count, Value: spec.Value}
+	}
+	return set
+}
+
+// withTiles returns a copy of the variant played with the tile set instead
+// of its own, or the variant itself if there is no tile set
+func (v *variant) withTiles(ts *TileSet) *variant {
+	if ts == nil {
+		return v
+	}
+	c := *v
+	c.tiles = ts.tiles()
+	c.bag = fillBag(c.tiles)
+	return &c
+}
+
+// LoadTileSets reads every tile set in the directory, from JSON files named
+// after the set they hold. Each set is validated, so a server refuses to start
+// with one that can't be played with.
+func LoadTileSets(dir string) (map[string]TileSet, error) {
+	sets := make(map[string]TileSet)
+	err := loadJSONDir(dir, func(name string, data []byte) error {
+		var ts TileSet
+		if err := json.Unmarshal(data, &ts); err != nil {
+			return err
+		} else if err = ts.validate(); err != nil {
+			return err
+		}
+		ts.Name = name
+		sets[name] = ts
+		return nil
+	})
+	return sets, err
+}
+
+// resolveTileSet replaces a tile set chosen by name in the options with the
+// one the server loaded, so the game keeps it even if the file later changes
+func (s *Server) resolveTileSet(o *GameOptions) error {
+	if o.TileSet == nil || len(o.TileSet.Tiles) != 0 {
+		return nil
+	}
+	ts, ok := s.tileSets[o.TileSet.Name]
+	if !ok {
+		return errors.New("Unknown tile set '" + o.TileSet.Name + "'")
+	}
+	o.TileSet = &ts
+	return nil
+}
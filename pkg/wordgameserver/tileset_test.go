@@ -0,0 +1,125 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+)
+
+func TestTileSetValidate(t *testing.T) {
+	tests := []struct {
+		tiles map[string]TileSpec
+		valid bool
+	}{
+		{map[string]TileSpec{"A": {Count: 10, Value: 1}, "?": {Count: 4, Value: 0}}, true},
+		{map[string]TileSpec{"A": {Count: 13, Value: 1}}, false},
+		{map[string]TileSpec{"A": {Count: 401, Value: 1}}, false},
+		{map[string]TileSpec{"A": {Count: 14, Value: 1}, "a": {Count: 1, Value: 1}}, false},
+		{map[string]TileSpec{"A": {Count: 14, Value: 1}, "QX": {Count: 1, Value: 1}}, false},
+		{map[string]TileSpec{"A": {Count: 14, Value: 1}, "B": {Count: 0, Value: 1}}, false},
+		{map[string]TileSpec{"A": {Count: 14, Value: -1}}, false},
+	}
+	for _, test := range tests {
+		if err := (TileSet{Tiles: test.tiles}).validate(); (err == nil) != test.valid {
+			t.Errorf("Validating %+v returned %v, expected valid to be %v", test.tiles, err, test.valid)
+		}
+	}
+}
+
+func TestTileSetGame(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "vowels.json"), []byte(`{
+	"tiles": {
+		"A": {"count": 10, "value": 5},
+		"E": {"count": 10, "value": 5},
+		"?": {"count": 2, "value": 0}
+	}
+}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.TileSetDir = dir
+	srv, err := NewServer(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(ts TileSet) (int, *ScrabbleGame) {
+		t.Helper()
+		payload, err := json.Marshal(GeneralGameRequest{Options: &GameOptions{Variant: VariantSuper, TileSet: &ts}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			return rr.Code, nil
+		}
+
+		var resp GeneralGameRequest
+		if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		g, err := srv.games.Get(resp.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(g.Stop)
+		return rr.Code, g
+	}
+
+	for _, ts := range []TileSet{{Name: "consonants"}, {}, {Tiles: map[string]TileSpec{"A": {Count: 1, Value: 1}}}} {
+		if code, _ := create(ts); code != http.StatusBadRequest {
+			t.Errorf("Creating a game with tile set %+v returned status code %v, expected %v", ts, code, http.StatusBadRequest)
+		}
+	}
+
+	_, g := create(TileSet{Name: "vowels"})
+	if g == nil {
+		t.Fatal("Creating a game with a named tile set failed")
+	}
+	g.Lock()
+	defer g.Unlock()
+	if len(g.TileBag) != 22 || len(g.Board) != 21 {
+		t.Fatalf("Game has %v tiles on a board of %v rows, expected 22 on the Super Scrabble board", len(g.TileBag), len(g.Board))
+	}
+
+	// Tiles score the values of the set
+	board, placed, words, err := g.Board.LayTiles(engine.Play{
+		StartPos: SquareCoordinate{Row: 10, Col: 10},
+		EndPos:   SquareCoordinate{Row: 10, Col: 11},
+		Tiles:    Letters("AE"),
+	}, g.variant.tiles)
+	if err != nil {
+		t.Fatal(err)
+	} else if score := board.ScorePlay(placed, words, g.variant.bingo); score != 10 {
+		t.Errorf("AE scored %v, expected 10", score)
+	}
+
+	// The tile set survives the game being saved and loaded
+	data, err := EncodeGame(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.variant.tiles['E'].Value != 5 || len(d.TileBag) != 22 {
+		t.Errorf("Decoded game has %v tiles with E worth %v, expected 22 worth 5", len(d.TileBag), d.variant.tiles['E'].Value)
+	}
+	if standardVariant.tiles['E'].Value != 1 {
+		t.Error("Playing with a tile set changed the standard variant")
+	}
+}
@@ -0,0 +1,102 @@
+package wordgameserver
+
+import (
+	"log"
+	"time"
+)
+
+// turnDeadline returns when the current turn runs out. The game must be locked
+// by the caller.
+func (sg *ScrabbleGame) turnDeadline() time.Time {
+	return sg.TurnStarted.Add(time.Duration(sg.Options.TurnTimer) * time.Second)
+}
+
+// nextDeadline returns when the current player next runs out of time, either
+// on the turn timer or their clock, if the game limits either and isn't
+// paused. The game must be locked by the caller.
+func (sg *ScrabbleGame) nextDeadline() (time.Time, bool) {
+	if !sg.Active || sg.Finished || sg.paused {
+		return time.Time{}, false
+	}
+
+	playerList := sg.playerList()
+	deadline, ok := sg.clockDeadline(playerList[sg.TurnCount%len(playerList)])
+	if sg.Options.TurnTimer > 0 {
+		if d := sg.turnDeadline(); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	if d, accept := sg.acceptDeadline(); accept && (!ok || d.Before(deadline)) {
+		deadline, ok = d, true
+	}
+	return deadline, ok
+}
+
+// expireTime acts on the current player running out of time. A play that went
+// out is accepted once its challenge window closes, which ends the game. A
+// player whose clock has run out loses, otherwise their turn is taken if the
+// turn timer has run out. The game must be locked by the caller.
+func (sg *ScrabbleGame) expireTime(playerList []*Player) {
+	// The game may have been ended or paused since the controller's timer
+	// was set
+	if sg.Finished || sg.paused {
+		return
+	}
+	cp := playerList[sg.TurnCount%len(playerList)]
+
+	if deadline, ok := sg.acceptDeadline(); ok && !time.Now().Before(deadline) {
+		if err := sg.record(Event{Type: PlayAccepted, Player: sg.lastPlay.PlayerID}); err != nil {
+			log.Printf("Failed to accept play in game %v: %v", sg.ID, err)
+			return
+		}
+		sg.broadcast(playerList)
+		sg.persist()
+		return
+	}
+
+	if deadline, ok := sg.clockDeadline(cp); ok && !time.Now().Before(deadline) {
+		if err := sg.record(Event{Type: ClockExpired, Player: cp.ID}); err != nil {
+			log.Printf("Failed to end game %v on time: %v", sg.ID, err)
+			return
+		}
+		sg.broadcast(playerList)
+		sg.persist()
+		return
+	}
+
+	sg.expireTurn(playerList)
+}
+
+// applyClockExpired ends the game with the player losing on time
+func (sg *ScrabbleGame) applyClockExpired(e Event) {
+	cp := sg.Players[e.Player]
+	sg.chargeClock(cp, e.Time)
+	sg.TurnStarted = e.Time
+	sg.endGame(cp)
+	sg.timedOut = &cp.Number
+}
+
+// expireTurn takes the turn of the current player if their time has run out,
+// passing or swapping every tile depending on the game's options, and lets
+// subscribed clients know. The game must be locked by the caller.
+func (sg *ScrabbleGame) expireTurn(playerList []*Player) {
+	if sg.Options.TurnTimer == 0 || time.Now().Before(sg.turnDeadline()) {
+		return
+	}
+
+	cp := playerList[sg.TurnCount%len(playerList)]
+
+	// Swapping isn't always possible, in which case the turn is passed
+	e := Event{Type: TurnPassed, Player: cp.ID}
+	if sg.Options.TimeoutSwap && len(cp.Tiles) > 0 && sg.CheckSwap(&cp.Seat, cp.Tiles) == nil {
+		e = sg.swapEvent(cp, cp.Tiles)
+	}
+	e.TimedOut = true
+	if err := sg.record(e); err != nil {
+		log.Printf("Failed to take turn in game %v: %v", sg.ID, err)
+		return
+	}
+
+	sg.broadcast(playerList)
+	sg.persist()
+}
@@ -0,0 +1,76 @@
+package wordgameserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpireTurn(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.TurnTimer = 60
+	g.Active = true
+	playerList := g.playerList()
+
+	// Turns that haven't run out are left alone
+	g.TurnStarted = time.Now()
+	g.expireTurn(playerList)
+	if g.TurnCount != 0 || len(g.history) != 0 {
+		t.Fatal("Turn was taken before the timer ran out")
+	}
+
+	g.TurnStarted = time.Now().Add(-61 * time.Second)
+	g.expireTurn(playerList)
+
+	if g.TurnCount != 1 {
+		t.Errorf("Turn count is %v, expected 1", g.TurnCount)
+	} else if m := g.history[0]; !m.Pass || !m.TimedOut || m.Player != 0 {
+		t.Errorf("Recorded move %+v, expected timed out pass by player 0", m)
+	} else if string(g.Players[ids[0]].Tiles) != "CATXXXX" {
+		t.Error("Passing should not change the player's hand")
+	}
+
+	state := g.getState(ids[1], playerList)
+	if state.TimedOut == nil || *state.TimedOut != 0 {
+		t.Errorf("State reports timed out player %v, expected 0", state.TimedOut)
+	} else if state.TurnEnds == nil || time.Until(*state.TurnEnds) < 59*time.Second {
+		t.Errorf("State reports turn ends at %v, expected about a minute from now", state.TurnEnds)
+	}
+}
+
+func TestTurnTimerSwap(t *testing.T) {
+	g, ids := createTestGame(t, "", "")
+	g.Options.TurnTimer = 1
+	g.Options.TimeoutSwap = true
+	defer g.Stop()
+
+	updates := g.subscribe(ids[1])
+	defer g.unsubscribe(updates)
+
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+
+	// Skip the state pushed when the game starts
+	<-updates
+
+	select {
+	case state := <-updates:
+		if state.TimedOut == nil || *state.TimedOut != 0 {
+			t.Fatalf("Pushed state reports timed out player %v, expected 0", state.TimedOut)
+		} else if state.PlayerTurn != 1 {
+			t.Fatalf("Turn is %v after timeout, expected 1", state.PlayerTurn)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Turn did not time out")
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if m := g.history[0]; !m.Swap || !m.TimedOut {
+		t.Errorf("Recorded move %+v, expected timed out swap", m)
+	} else if len(g.Players[ids[0]].Tiles) != maxTiles {
+		t.Errorf("Player has %v tiles after swapping, expected %v", len(g.Players[ids[0]].Tiles), maxTiles)
+	}
+}
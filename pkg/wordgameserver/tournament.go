@@ -0,0 +1,704 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// ErrTournamentNotFound is returned by a TournamentStore when no tournament has
+// the requested ID
+var ErrTournamentNotFound = errors.New("Tournament does not exist")
+
+// Formats a tournament can be played in
+const (
+	TournamentElimination = "elimination" // players are knocked out when they lose, until one is left
+	TournamentSwiss       = "swiss"       // every player plays each round against someone with a similar record
+)
+
+// Stages a tournament goes through
+const (
+	TournamentRegistering = "registering" // players are signing up
+	TournamentRunning     = "running"     // rounds are being played
+	TournamentFinished    = "finished"    // every round has been played
+)
+
+// maxTournamentPlayers is the most players who can register for a tournament
+const maxTournamentPlayers = 256
+
+// TournamentPlayer is a registered player taking part in a tournament
+type TournamentPlayer struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Name      string    `json:"name"`
+	Seed      int       `json:"seed"` // order the player registered in, from 1
+}
+
+// Pairing is a game between players in a round of a tournament, or a bye for a
+// player left without an opponent, which counts as a win
+type Pairing struct {
+	Players  []uuid.UUID `json:"players"`            // accounts of the players, in turn order
+	GameID   *uuid.UUID  `json:"game_id,omitempty"`  // game the players are playing, nil for a bye
+	Scores   []int       `json:"scores,omitempty"`   // final score of each player
+	Winners  []uuid.UUID `json:"winners,omitempty"`  // players who won, both of them for a draw
+	Finished bool        `json:"finished,omitempty"` // true once the game has ended
+}
+
+// byePairing gives the player a bye, which is finished as soon as it is made
+func byePairing(player uuid.UUID) Pairing {
+	return Pairing{Players: []uuid.UUID{player}, Winners: []uuid.UUID{player}, Finished: true}
+}
+
+// Tournament is a competition between registered players over a number of
+// rounds. The server creates and starts the games for each round once the one
+// before it has finished.
+type Tournament struct {
+	ID       uuid.UUID          `json:"id"`
+	Name     string             `json:"name,omitempty"`
+	Format   string             `json:"format"`  // TournamentElimination or TournamentSwiss
+	Rounds   int                `json:"rounds"`  // number of rounds, set for elimination tournaments once they start
+	Options  GameOptions        `json:"options"` // settings of every game in the tournament
+	Status   string             `json:"status"`  // TournamentRegistering, TournamentRunning or TournamentFinished
+	Players  []TournamentPlayer `json:"players"`
+	Pairings [][]Pairing        `json:"pairings"` // games of each round played so far
+	Created  time.Time          `json:"created"`
+}
+
+// TournamentStore holds tournaments. Implementations must be safe for
+// concurrent use. A GameStore that also implements TournamentStore is used for
+// tournaments by the server it is given to.
+type TournamentStore interface {
+	PutTournament(t *Tournament) error               // add or replace a tournament
+	GetTournament(id uuid.UUID) (*Tournament, error) // retrieve a tournament, or ErrTournamentNotFound
+}
+
+// MemoryTournamentStore is the default TournamentStore, which keeps
+// tournaments in memory for the lifetime of the process
+type MemoryTournamentStore struct {
+	sync.Mutex
+	tournaments map[uuid.UUID]*Tournament
+}
+
+// NewMemoryTournamentStore creates an empty in-memory tournament store
+func NewMemoryTournamentStore() *MemoryTournamentStore {
+	return &MemoryTournamentStore{
+		tournaments: make(map[uuid.UUID]*Tournament),
+	}
+}
+
+// PutTournament adds the tournament to the store, replacing any with the same
+// ID
+func (ms *MemoryTournamentStore) PutTournament(t *Tournament) error {
+	ms.Lock()
+	ms.tournaments[t.ID] = t
+	ms.Unlock()
+	return nil
+}
+
+// GetTournament retrieves the tournament with the ID
+func (ms *MemoryTournamentStore) GetTournament(id uuid.UUID) (*Tournament, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	t, ok := ms.tournaments[id]
+	if !ok {
+		return nil, ErrTournamentNotFound
+	}
+	return t, nil
+}
+
+// TournamentStanding is a player's record in a tournament
+type TournamentStanding struct {
+	TournamentPlayer
+	Points     float64 `json:"points"` // 1 for each win or bye, and a half for each draw
+	Wins       int     `json:"wins"`
+	Draws      int     `json:"draws"`
+	Losses     int     `json:"losses"`
+	Byes       int     `json:"byes"`
+	Spread     int     `json:"spread"`               // total points scored minus points conceded
+	Eliminated bool    `json:"eliminated,omitempty"` // true once the player is out of an elimination tournament
+}
+
+// standings totals the results of the tournament's finished games, ranking
+// players by points, then spread, then seed
+func (t *Tournament) standings() []TournamentStanding {
+	standings := make([]TournamentStanding, len(t.Players))
+	index := make(map[uuid.UUID]int, len(t.Players))
+	for i, p := range t.Players {
+		standings[i].TournamentPlayer = p
+		index[p.AccountID] = i
+	}
+
+	for _, round := range t.Pairings {
+		for _, p := range round {
+			if !p.Finished {
+				continue
+			}
+			if p.GameID == nil {
+				s := &standings[index[p.Players[0]]]
+				s.Points++
+				s.Byes++
+				continue
+			}
+
+			for i, id := range p.Players {
+				s := &standings[index[id]]
+				s.Spread += 2*p.Scores[i] - p.Scores[0] - p.Scores[1]
+				switch {
+				case len(p.Winners) > 1:
+					s.Points += 0.5
+					s.Draws++
+				case p.Winners[0] == id:
+					s.Points++
+					s.Wins++
+				default:
+					s.Losses++
+				}
+			}
+			if t.Format == TournamentElimination {
+				for _, id := range p.Players {
+					if id != t.advancing(p) {
+						standings[index[id]].Eliminated = true
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		} else if a.Spread != b.Spread {
+			return a.Spread > b.Spread
+		}
+		return a.Seed < b.Seed
+	})
+	return standings
+}
+
+// advancing returns the player who goes through to the next round of an
+// elimination tournament from a finished pairing. Draws are won by the higher
+// seed.
+func (t *Tournament) advancing(p Pairing) uuid.UUID {
+	if len(p.Winners) == 1 {
+		return p.Winners[0]
+	}
+	best := p.Winners[0]
+	for _, id := range p.Winners[1:] {
+		if t.seed(id) < t.seed(best) {
+			best = id
+		}
+	}
+	return best
+}
+
+// seed returns the seed of the player with the account ID
+func (t *Tournament) seed(id uuid.UUID) int {
+	for _, p := range t.Players {
+		if p.AccountID == id {
+			return p.Seed
+		}
+	}
+	return 0
+}
+
+// bracketOrder lists the seeds of a bracket of the given size, a power of two,
+// in the order they are paired, so the top seeds can only meet in later rounds
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, s := range order {
+			next = append(next, s, 2*len(order)+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+// nextRound pairs the players for the tournament's next round
+func (t *Tournament) nextRound() []Pairing {
+	if t.Format == TournamentElimination {
+		return t.nextEliminationRound()
+	}
+	return t.nextSwissRound()
+}
+
+// nextEliminationRound pairs the players of an elimination tournament. The
+// first round is seeded so the top seeds get any byes, and the players going
+// through from each pair of games in a round meet in the next.
+func (t *Tournament) nextEliminationRound() []Pairing {
+	var round []Pairing
+	if len(t.Pairings) == 0 {
+		size := 1 << bits.Len(uint(len(t.Players)-1))
+		order := bracketOrder(size)
+		for i := 0; i < size; i += 2 {
+			a, b := order[i], order[i+1]
+			if b > len(t.Players) {
+				round = append(round, byePairing(t.Players[a-1].AccountID))
+			} else {
+				round = append(round, Pairing{Players: []uuid.UUID{t.Players[a-1].AccountID, t.Players[b-1].AccountID}})
+			}
+		}
+		return round
+	}
+
+	last := t.Pairings[len(t.Pairings)-1]
+	for i := 0; i+1 < len(last); i += 2 {
+		round = append(round, Pairing{Players: []uuid.UUID{t.advancing(last[i]), t.advancing(last[i+1])}})
+	}
+	return round
+}
+
+// nextSwissRound pairs the players of a Swiss tournament by their standings,
+// each with the next highest ranked player they haven't played yet if there is
+// one. With an odd number of players, the lowest ranked player who hasn't had
+// a bye gets one.
+func (t *Tournament) nextSwissRound() []Pairing {
+	standings := t.standings()
+	played := make(map[[2]uuid.UUID]bool)
+	byes := make(map[uuid.UUID]bool)
+	for _, round := range t.Pairings {
+		for _, p := range round {
+			if len(p.Players) == 1 {
+				byes[p.Players[0]] = true
+			} else {
+				played[[2]uuid.UUID{p.Players[0], p.Players[1]}] = true
+				played[[2]uuid.UUID{p.Players[1], p.Players[0]}] = true
+			}
+		}
+	}
+
+	var round []Pairing
+	paired := make(map[uuid.UUID]bool)
+	if len(standings)%2 == 1 {
+		bye := standings[len(standings)-1].AccountID
+		for i := len(standings) - 1; i >= 0; i-- {
+			if !byes[standings[i].AccountID] {
+				bye = standings[i].AccountID
+				break
+			}
+		}
+		round = append(round, byePairing(bye))
+		paired[bye] = true
+	}
+
+	for i, s := range standings {
+		if paired[s.AccountID] {
+			continue
+		}
+		opponent := uuid.Nil
+		for _, o := range standings[i+1:] {
+			if paired[o.AccountID] {
+				continue
+			}
+			if opponent == uuid.Nil {
+				opponent = o.AccountID
+			}
+			if !played[[2]uuid.UUID{s.AccountID, o.AccountID}] {
+				opponent = o.AccountID
+				break
+			}
+		}
+		paired[s.AccountID], paired[opponent] = true, true
+		round = append(round, Pairing{Players: []uuid.UUID{s.AccountID, opponent}})
+	}
+	return round
+}
+
+// roundFinished reports whether every game of the tournament's latest round
+// has ended
+func (t *Tournament) roundFinished() bool {
+	for _, p := range t.Pairings[len(t.Pairings)-1] {
+		if !p.Finished {
+			return false
+		}
+	}
+	return true
+}
+
+// tournamentResult is the outcome of a tournament game, reported to the server
+// when the game finishes
+type tournamentResult struct {
+	TournamentID uuid.UUID
+	GameID       uuid.UUID
+	Scores       map[uuid.UUID]int // final score of each player's account
+	Winners      []uuid.UUID       // accounts of the players who won
+}
+
+// reportTournamentResult lets the server know the outcome of the game if it
+// is part of a tournament, now it has finished. The game must be locked by the
+// caller.
+func (sg *ScrabbleGame) reportTournamentResult() {
+	if sg.TournamentID == uuid.Nil || sg.tournamentResults == nil {
+		return
+	}
+
+	res := tournamentResult{
+		TournamentID: sg.TournamentID,
+		GameID:       sg.ID,
+		Scores:       make(map[uuid.UUID]int),
+	}
+	won := make(map[int]bool)
+	for _, n := range sg.Winners {
+		won[n] = true
+	}
+	for _, p := range sg.playerList() {
+		if p.Account == nil {
+			continue
+		}
+		res.Scores[*p.Account] = p.Score
+		if won[p.Number] {
+			res.Winners = append(res.Winners, *p.Account)
+		}
+	}
+	sg.tournamentResults(res)
+}
+
+// recordTournamentResult records the outcome of a tournament game, starting
+// the next round once every game of the current one has finished
+func (s *Server) recordTournamentResult(res tournamentResult) {
+	s.tournamentMu.Lock()
+	defer s.tournamentMu.Unlock()
+
+	t, err := s.tournaments.GetTournament(res.TournamentID)
+	if err != nil {
+		log.Printf("Failed to get tournament %v for game %v: %v", res.TournamentID, res.GameID, err)
+		return
+	}
+
+	if len(t.Pairings) == 0 {
+		return
+	}
+	found := false
+	round := t.Pairings[len(t.Pairings)-1]
+	for i, p := range round {
+		if p.GameID == nil || *p.GameID != res.GameID || p.Finished {
+			continue
+		}
+		found = true
+		round[i].Finished = true
+		round[i].Scores = make([]int, len(p.Players))
+		for j, id := range p.Players {
+			round[i].Scores[j] = res.Scores[id]
+		}
+		// A game with no winners, because every player resigned or ran
+		// out of time, counts as a draw
+		round[i].Winners = res.Winners
+		if len(res.Winners) == 0 {
+			round[i].Winners = p.Players
+		}
+	}
+	if !found {
+		return
+	}
+
+	if err = s.advanceTournament(t); err != nil {
+		log.Printf("Failed to start the next round of tournament %v: %v", t.ID, err)
+	}
+	if err = s.tournaments.PutTournament(t); err != nil {
+		log.Printf("Failed to save tournament %v: %v", t.ID, err)
+	}
+}
+
+// advanceTournament starts the tournament's next round once its current one
+// has finished, or finishes the tournament after its last round. The caller
+// must hold the server's tournament lock.
+func (s *Server) advanceTournament(t *Tournament) error {
+	for t.Status == TournamentRunning && t.roundFinished() {
+		if len(t.Pairings) == t.Rounds {
+			t.Status = TournamentFinished
+			return nil
+		}
+		round := t.nextRound()
+		if err := s.startPairings(t, round); err != nil {
+			return err
+		}
+		t.Pairings = append(t.Pairings, round)
+	}
+	return nil
+}
+
+// startPairings creates and starts the game for each pairing that isn't a bye.
+// Tournament games don't count towards the server's game limit, since they
+// can't be turned away once the tournament has started.
+func (s *Server) startPairings(t *Tournament, round []Pairing) error {
+	names := make(map[uuid.UUID]string, len(t.Players))
+	for _, p := range t.Players {
+		names[p.AccountID] = p.Name
+	}
+
+	for i, p := range round {
+		if len(p.Players) == 1 {
+			continue
+		}
+
+		v, err := lookupVariant(t.Options.Variant)
+		if err != nil {
+			return err
+		}
+		g := createVariantGame(v, t.Options.Layout, t.Options.tileSet(), t.Options.Seed)
+		g.Options = t.Options
+		g.Validator = gameValidator(s.validator, t.Options)
+		g.TournamentID = t.ID
+		s.cluster.claim(g)
+
+		g.Lock()
+		err = s.startTournamentGame(g, p, names)
+		g.Unlock()
+		if err != nil {
+			return err
+		}
+		id := g.ID
+		round[i].GameID = &id
+	}
+	return nil
+}
+
+// startTournamentGame adds the pairing's players to the game and starts it.
+// The game must be locked by the caller.
+func (s *Server) startTournamentGame(g *ScrabbleGame, p Pairing, names map[uuid.UUID]string) error {
+	s.adoptGame(g)
+	for _, id := range p.Players {
+		if _, err := g.addAccountPlayer(Account{ID: id, Username: names[id]}); err != nil {
+			return err
+		}
+	}
+	if err := g.start(); err != nil {
+		return err
+	}
+	return s.games.Put(g)
+}
+
+// TournamentRequest is the format of requests to create a tournament
+type TournamentRequest struct {
+	Name    string       `json:"name,omitempty"`
+	Format  string       `json:"format"`            // TournamentElimination or TournamentSwiss
+	Rounds  int          `json:"rounds,omitempty"`  // rounds of a Swiss tournament, 0 for enough to find a clear winner
+	Options *GameOptions `json:"options,omitempty"` // settings of every game, or the defaults if nil
+}
+
+// TournamentStandingsResponse is the format of the response sent to clients
+// when they request a tournament's standings
+type TournamentStandingsResponse struct {
+	TournamentID uuid.UUID            `json:"tournament_id"`
+	Status       string               `json:"status"`
+	Round        int                  `json:"round"` // number of the latest round started, 0 before the tournament starts
+	Standings    []TournamentStanding `json:"standings"`
+}
+
+// writeTournamentResponse responds to a tournament request with the value
+// encoded as JSON and the status
+func writeTournamentResponse(w http.ResponseWriter, v interface{}, status int) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+// createTournamentHandler handles requests to create a tournament, which
+// players can then register for
+func (s *Server) createTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	var j TournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t := &Tournament{
+		ID:       uuid.New(),
+		Name:     j.Name,
+		Format:   j.Format,
+		Rounds:   j.Rounds,
+		Status:   TournamentRegistering,
+		Players:  []TournamentPlayer{},
+		Pairings: [][]Pairing{},
+		Created:  time.Now(),
+	}
+	if j.Options != nil {
+		t.Options = *j.Options
+	}
+
+	switch {
+	case j.Format != TournamentElimination && j.Format != TournamentSwiss:
+		http.Error(w, "Format must be '"+TournamentElimination+"' or '"+TournamentSwiss+"'", http.StatusBadRequest)
+		return
+	case j.Rounds < 0 || (j.Rounds > 0 && j.Format != TournamentSwiss):
+		http.Error(w, "Rounds can only be chosen for Swiss tournaments, and cannot be negative", http.StatusBadRequest)
+		return
+	case t.Options.Bots > 0:
+		http.Error(w, "Tournament games cannot have bots", http.StatusBadRequest)
+		return
+	case t.Options.ChallengeWindow > 0 && gameValidator(s.validator, t.Options) == nil:
+		http.Error(w, "Challenges require the server to have a dictionary", http.StatusBadRequest)
+		return
+	}
+	if err := t.Options.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = s.resolveLayout(&t.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = s.resolveTileSet(&t.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err = s.checkLexicon(t.Options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.tournaments.PutTournament(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeTournamentResponse(w, t, http.StatusCreated)
+}
+
+// getTournament retrieves the tournament in the request's path, responding
+// with an error if it can't. The caller must hold the server's tournament
+// lock.
+func (s *Server) getTournament(w http.ResponseWriter, r *http.Request) (*Tournament, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["tournament"])
+	if err != nil {
+		http.Error(w, "Invalid tournament ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	t, err := s.tournaments.GetTournament(id)
+	if err == ErrTournamentNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return t, true
+}
+
+// getTournamentHandler handles requests for a tournament, including the
+// pairings and games of every round so far
+func (s *Server) getTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	s.tournamentMu.Lock()
+	defer s.tournamentMu.Unlock()
+
+	if t, ok := s.getTournament(w, r); ok {
+		writeTournamentResponse(w, t, http.StatusOK)
+	}
+}
+
+// registerTournamentHandler handles requests to register for a tournament,
+// which must be made with the token of the account taking part
+func (s *Server) registerTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.requestAccount(w, r)
+	if !ok {
+		return
+	} else if a == nil {
+		http.Error(w, "Registering for a tournament requires an account's bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	s.tournamentMu.Lock()
+	defer s.tournamentMu.Unlock()
+
+	t, ok := s.getTournament(w, r)
+	if !ok {
+		return
+	}
+	if t.Status != TournamentRegistering {
+		http.Error(w, "Tournament has already started", http.StatusBadRequest)
+		return
+	} else if len(t.Players) >= maxTournamentPlayers {
+		http.Error(w, "Maximum players reached for tournament", http.StatusBadRequest)
+		return
+	}
+	for _, p := range t.Players {
+		if p.AccountID == a.ID {
+			http.Error(w, "Account has already registered for the tournament", http.StatusBadRequest)
+			return
+		}
+	}
+
+	player := TournamentPlayer{AccountID: a.ID, Name: a.Username, Seed: len(t.Players) + 1}
+	t.Players = append(t.Players, player)
+	if err := s.tournaments.PutTournament(t); err != nil {
+		t.Players = t.Players[:len(t.Players)-1]
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeTournamentResponse(w, player, http.StatusCreated)
+}
+
+// startTournamentHandler handles requests to start a tournament, closing
+// registration and starting the games of its first round
+func (s *Server) startTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	s.tournamentMu.Lock()
+	defer s.tournamentMu.Unlock()
+
+	t, ok := s.getTournament(w, r)
+	if !ok {
+		return
+	}
+	if t.Status != TournamentRegistering {
+		http.Error(w, "Tournament has already started", http.StatusBadRequest)
+		return
+	} else if len(t.Players) < 2 {
+		http.Error(w, "At least two players needed to start tournament", http.StatusBadRequest)
+		return
+	}
+
+	// Elimination tournaments last until one player is left, and Swiss ones
+	// default to as many rounds
+	rounds := bits.Len(uint(len(t.Players) - 1))
+	if t.Format == TournamentElimination || t.Rounds == 0 {
+		t.Rounds = rounds
+	}
+
+	round := t.nextRound()
+	if err := s.startPairings(t, round); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t.Pairings = append(t.Pairings, round)
+	t.Status = TournamentRunning
+
+	err := s.advanceTournament(t)
+	if putErr := s.tournaments.PutTournament(t); err == nil {
+		err = putErr
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeTournamentResponse(w, t, http.StatusOK)
+}
+
+// tournamentStandingsHandler handles requests for the standings of a
+// tournament's players
+func (s *Server) tournamentStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	s.tournamentMu.Lock()
+	defer s.tournamentMu.Unlock()
+
+	t, ok := s.getTournament(w, r)
+	if !ok {
+		return
+	}
+	writeTournamentResponse(w, TournamentStandingsResponse{
+		TournamentID: t.ID,
+		Status:       t.Status,
+		Round:        len(t.Pairings),
+		Standings:    t.standings(),
+	}, http.StatusOK)
+}
@@ -0,0 +1,230 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBracketOrder(t *testing.T) {
+	want := []int{1, 8, 4, 5, 2, 7, 3, 6}
+	if order := bracketOrder(8); !reflect.DeepEqual(order, want) {
+		t.Errorf("Bracket of 8 is ordered %v, expected %v", order, want)
+	}
+}
+
+// tournamentTest sends requests for a tournament to a test server
+type tournamentTest struct {
+	t   *testing.T
+	srv *Server
+}
+
+// send makes the request with the token, unless it is empty, and decodes the
+// response into resp if it has the status expected
+func (tt tournamentTest) send(method, path, token string, body interface{}, status int, resp interface{}) {
+	tt.t.Helper()
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			tt.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, "/v2"+path, bytes.NewReader(payload))
+	if err != nil {
+		tt.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	tt.srv.Handler().ServeHTTP(rr, req)
+
+	if rr.Code != status {
+		tt.t.Fatalf("%v %v returned status code %v, expected %v. Error: %v", method, path, rr.Code, status, rr.Body)
+	}
+	if resp != nil {
+		if err = json.NewDecoder(rr.Body).Decode(resp); err != nil {
+			tt.t.Fatal(err)
+		}
+	}
+}
+
+// register creates accounts with the names and registers them for the
+// tournament, returning the accounts' IDs by name
+func (tt tournamentTest) register(id uuid.UUID, names ...string) map[string]uuid.UUID {
+	tt.t.Helper()
+	accounts := make(map[string]uuid.UUID)
+	for _, name := range names {
+		var a AccountResponse
+		tt.send("POST", "/accounts", "", AccountRequest{Username: name, Password: "hunter22"}, http.StatusCreated, &a)
+		tt.send("POST", "/tournaments/"+id.String()+"/players", a.Token, nil, http.StatusCreated, nil)
+		accounts[name] = a.ID
+	}
+	return accounts
+}
+
+// resign finishes the pairing's game by having the account resign
+func (tt tournamentTest) resign(p Pairing, loser uuid.UUID) {
+	tt.t.Helper()
+	g, err := tt.srv.games.Get(*p.GameID)
+	if err != nil {
+		tt.t.Fatal(err)
+	}
+	defer g.Stop()
+
+	g.Lock()
+	var playerID uuid.UUID
+	for _, player := range g.Players {
+		if player.Account != nil && *player.Account == loser {
+			playerID = player.ID
+		}
+	}
+	g.Unlock()
+	if _, err = g.request(GamePlayRequest{PlayerID: playerID, Type: resignRequest}); err != nil {
+		tt.t.Fatal(err)
+	}
+}
+
+func TestEliminationTournament(t *testing.T) {
+	tt := tournamentTest{t: t, srv: newTestServer(t)}
+
+	var tour Tournament
+	tt.send("POST", "/tournaments", "", TournamentRequest{Name: "Open", Format: "knockout"}, http.StatusBadRequest, nil)
+	tt.send("POST", "/tournaments", "", TournamentRequest{Format: TournamentElimination, Options: &GameOptions{Bots: 1}}, http.StatusBadRequest, nil)
+	tt.send("POST", "/tournaments", "", TournamentRequest{Name: "Open", Format: TournamentElimination}, http.StatusCreated, &tour)
+	path := "/tournaments/" + tour.ID.String()
+
+	tt.send("POST", path+"/players", "", nil, http.StatusUnauthorized, nil)
+	tt.send("POST", path+"/start", "", nil, http.StatusBadRequest, nil)
+	ids := tt.register(tour.ID, "ashley1", "ashley2", "ashley3")
+
+	// The top seed gets a bye while the others play
+	tt.send("POST", path+"/start", "", nil, http.StatusOK, &tour)
+	if tour.Status != TournamentRunning || tour.Rounds != 2 || len(tour.Pairings) != 1 {
+		t.Fatalf("Started tournament is %+v, expected the first of 2 rounds running", tour)
+	}
+	round := tour.Pairings[0]
+	if len(round) != 2 || round[0].GameID != nil || round[0].Players[0] != ids["ashley1"] {
+		t.Fatalf("First round is %+v, expected a bye for ashley1", round)
+	}
+	if !reflect.DeepEqual(round[1].Players, []uuid.UUID{ids["ashley2"], ids["ashley3"]}) || round[1].GameID == nil {
+		t.Fatalf("First round is %+v, expected a game between ashley2 and ashley3", round)
+	}
+	tt.send("POST", path+"/players", "", nil, http.StatusUnauthorized, nil)
+
+	// The players can find their game to play it
+	var games PlayerGamesResponse
+	tt.send("GET", "/players/ashley3/games", "", nil, http.StatusOK, &games)
+	if len(games.Games) != 1 || games.Games[0].GameID != *round[1].GameID || games.Games[0].Status != GameActive {
+		t.Errorf("ashley3 has games %+v, expected tournament game %v", games.Games, *round[1].GameID)
+	}
+
+	// Once the game ends the winner meets the top seed in the final
+	tt.resign(round[1], ids["ashley2"])
+	tt.send("GET", path, "", nil, http.StatusOK, &tour)
+	if len(tour.Pairings) != 2 {
+		t.Fatalf("Tournament has %v rounds, expected the final to have started", len(tour.Pairings))
+	}
+	final := tour.Pairings[1]
+	if len(final) != 1 || !reflect.DeepEqual(final[0].Players, []uuid.UUID{ids["ashley1"], ids["ashley3"]}) {
+		t.Fatalf("Final is %+v, expected ashley1 against ashley3", final)
+	}
+
+	tt.resign(final[0], ids["ashley1"])
+	var standings TournamentStandingsResponse
+	tt.send("GET", path+"/standings", "", nil, http.StatusOK, &standings)
+	if standings.Status != TournamentFinished || standings.Round != 2 {
+		t.Errorf("Tournament is %v after round %v, expected it to have finished after round 2", standings.Status, standings.Round)
+	}
+	want := []struct {
+		name       string
+		points     float64
+		eliminated bool
+	}{{"ashley3", 2, false}, {"ashley1", 1, true}, {"ashley2", 0, true}}
+	for i, w := range want {
+		s := standings.Standings[i]
+		if s.Name != w.name || s.Points != w.points || s.Eliminated != w.eliminated {
+			t.Errorf("Standing %v is %+v, expected %v with %v points", i+1, s, w.name, w.points)
+		}
+	}
+}
+
+func TestSwissTournament(t *testing.T) {
+	tt := tournamentTest{t: t, srv: newTestServer(t)}
+
+	var tour Tournament
+	tt.send("POST", "/tournaments", "", TournamentRequest{Format: TournamentSwiss, Rounds: 3}, http.StatusCreated, &tour)
+	path := "/tournaments/" + tour.ID.String()
+	ids := tt.register(tour.ID, "ashley1", "ashley2", "ashley3", "ashley4")
+	tt.send("POST", path+"/start", "", nil, http.StatusOK, &tour)
+
+	played := make(map[[2]uuid.UUID]bool)
+	for round := 0; round < 3; round++ {
+		if len(tour.Pairings) != round+1 {
+			t.Fatalf("Tournament has %v rounds, expected %v", len(tour.Pairings), round+1)
+		}
+		for _, p := range tour.Pairings[round] {
+			if len(p.Players) != 2 {
+				t.Fatalf("Round %v has pairing %+v, expected every player to play", round+1, p)
+			}
+			pair := [2]uuid.UUID{p.Players[0], p.Players[1]}
+			if played[pair] || played[[2]uuid.UUID{pair[1], pair[0]}] {
+				t.Errorf("Round %v pairs players who have already played", round+1)
+			}
+			played[pair] = true
+
+			// Lower seeds always lose
+			loser := p.Players[0]
+			if tour.seed(p.Players[1]) > tour.seed(loser) {
+				loser = p.Players[1]
+			}
+			tt.resign(p, loser)
+		}
+		tt.send("GET", path, "", nil, http.StatusOK, &tour)
+	}
+
+	var standings TournamentStandingsResponse
+	tt.send("GET", path+"/standings", "", nil, http.StatusOK, &standings)
+	if standings.Status != TournamentFinished {
+		t.Errorf("Tournament is %v, expected it to have finished", standings.Status)
+	}
+	for i, name := range []string{"ashley1", "ashley2", "ashley3", "ashley4"} {
+		s := standings.Standings[i]
+		if s.AccountID != ids[name] || s.Wins != 3-i || s.Losses != i {
+			t.Errorf("Standing %v is %+v, expected %v with %v wins", i+1, s, name, 3-i)
+		}
+	}
+}
+
+func TestTournamentResultsSurviveReload(t *testing.T) {
+	srv := newTestServer(t)
+	g := createScrabbleGame()
+	g.TournamentID = uuid.New()
+
+	g.Lock()
+	data, err := EncodeGame(g)
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if d.TournamentID != g.TournamentID {
+		t.Errorf("Decoded game is part of tournament %v, expected %v", d.TournamentID, g.TournamentID)
+	}
+
+	// Loaded games report to the server holding them
+	d.Lock()
+	srv.adoptGame(d)
+	d.Unlock()
+	if d.tournamentResults == nil {
+		t.Error("Adopted game doesn't report its tournament result")
+	}
+}
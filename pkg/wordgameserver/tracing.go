@@ -0,0 +1,96 @@
+package wordgameserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans started by the server. Spans are sent to
+// whatever tracer provider is registered with otel, so nothing is recorded
+// unless the program running the server sets one up.
+const tracerName = "github.com/fantashley/wordgame-controller/pkg/wordgameserver"
+
+// Attributes recorded on spans for the game and player they are about
+const (
+	gameIDKey      = attribute.Key("game.id")
+	playerIDKey    = attribute.Key("game.player_id")
+	requestTypeKey = attribute.Key("game.request_type")
+)
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// traceRequests returns middleware that starts a span for every request,
+// continuing any trace propagated by a gateway in front of the server. The
+// span is named after the route, and marked as failed if the response is a
+// server error.
+func traceRequests() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := r.URL.Path
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+
+			ctx, span := tracer().Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			gameID, playerID := requestIDs(r)
+			if gameID != "" {
+				span.SetAttributes(gameIDKey.String(gameID))
+			}
+			if playerID != "" {
+				span.SetAttributes(playerIDKey.String(playerID))
+			}
+
+			sr := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(sr, r.WithContext(ctx))
+
+			status := sr.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// endSpan records the error, if there is one, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// requestContext returns the context the request was made in, which carries
+// the span the stateController's handling of it belongs to
+func requestContext(r GamePlayRequest) context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
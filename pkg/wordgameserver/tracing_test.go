@@ -0,0 +1,119 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	}()
+
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	newGame.addPlayer("ashley1")
+	newGame.addPlayer("ashley2")
+
+	newGame.Lock()
+	err := newGame.start()
+	playerList := newGame.playerList()
+	playerID := playerList[newGame.TurnCount%len(playerList)].ID
+	newGame.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The trace is continued from the one propagated by the gateway. Reads of
+	// the state can be answered from the game's view, so a move is made to be
+	// sure the request goes through the controller.
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	body, err := json.Marshal(GameMoveRequest{PlayerID: playerID, Action: ActionPass})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "/v2/games/"+newGame.ID.String()+"/moves", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	rr := httptest.NewRecorder()
+	srv.newRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	// The controller ends its span after responding, so it may not have yet
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Ended()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+
+	handler, ok := spans["POST /v2/games/{id}/moves"]
+	if !ok {
+		t.Fatalf("No span for the route, recorded %v", spanNames(recorder.Ended()))
+	}
+	if got := handler.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("Request traced with ID %v, expected %v", got, traceID)
+	}
+
+	// Each step of the request is a child of the one before it
+	parents := []struct{ child, parent string }{
+		{"lookupGame", "POST /v2/games/{id}/moves"},
+		{"ScrabbleGame.request", "POST /v2/games/{id}/moves"},
+		{"ScrabbleGame.handleRequest", "ScrabbleGame.request"},
+	}
+	for _, p := range parents {
+		child, ok := spans[p.child]
+		if !ok {
+			t.Fatalf("No %v span, recorded %v", p.child, spanNames(recorder.Ended()))
+		}
+		parent, ok := spans[p.parent]
+		if !ok {
+			t.Fatalf("No %v span, recorded %v", p.parent, spanNames(recorder.Ended()))
+		}
+		if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%v span is not a child of %v", p.child, p.parent)
+		}
+	}
+
+	request, ok := spans["ScrabbleGame.request"]
+	if !ok {
+		t.Fatalf("No ScrabbleGame.request span, recorded %v", spanNames(recorder.Ended()))
+	}
+	if events := request.Events(); len(events) != 2 {
+		t.Errorf("Request span has %v events, expected the request being queued and answered", len(events))
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name()
+	}
+	return names
+}
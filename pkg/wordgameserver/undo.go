@@ -0,0 +1,203 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// undoRecord holds everything a move changes, as it was just before the move,
+// so the move can be undone in a friendly game
+type undoRecord struct {
+	player        uuid.UUID // player who made the move
+	board         ScrabbleBoard
+	bag           TileBag
+	turnCount     int
+	moves         int // number of moves made before this one
+	lastPlay      *playRecord
+	lastChallenge *ChallengeResult
+	racks         map[uuid.UUID]playerRecord
+}
+
+// undoneMoves is what clients that saw moves before they were undone need to
+// take back
+type undoneMoves struct {
+	moves   int                // fewest moves the game has been taken back to
+	squares []SquareCoordinate // squares tiles have been taken back from
+	undos   []int              // moves left by each undo, in the order they were made
+}
+
+// playerRecord holds the parts of a player a move can change
+type playerRecord struct {
+	tiles    Letters
+	score    int
+	skip     bool
+	timeLeft time.Duration
+}
+
+// keepUndo records the state of the game before the move event is applied, so
+// the move can be undone. Only the most recent move can be undone, so the
+// record is dropped by any other event except pausing and resuming.
+func (sg *ScrabbleGame) keepUndo(e Event) {
+	switch e.Type {
+	case MovePlayed, TilesExchanged, TurnPassed:
+	case MoveUndone:
+		return // the record is used, then dropped, by applyUndo
+	case GamePaused, GameResumed:
+		return // pausing doesn't change the board
+	default:
+		sg.undoable = nil
+		return
+	}
+
+	u := undoRecord{
+		player:        e.Player,
+		board:         sg.Board.Clone(),
+		bag:           append(TileBag(nil), sg.TileBag...),
+		turnCount:     sg.TurnCount,
+		moves:         len(sg.history),
+		lastPlay:      sg.lastPlay,
+		lastChallenge: sg.lastChallenge,
+		racks:         make(map[uuid.UUID]playerRecord, len(sg.Players)),
+	}
+	for id, p := range sg.Players {
+		u.racks[id] = playerRecord{
+			tiles:    append(Letters(nil), p.Tiles...),
+			score:    p.Score,
+			skip:     p.Skip,
+			timeLeft: p.TimeLeft,
+		}
+	}
+	sg.undoable = &u
+}
+
+// undo handles a request to undo the last move of a friendly game. The player
+// who made the move asks for it to be undone, and it is once every other
+// player still in the game has agreed by asking too. Bots always agree.
+func (sg *ScrabbleGame) undo(j GamePlayRequest) error {
+	if !sg.Options.Friendly || sg.TournamentID != uuid.Nil {
+		return errors.New("Moves can only be undone in friendly games")
+	} else if sg.undoable == nil {
+		return errors.New("No move to undo")
+	}
+
+	if sg.undoConsent == nil {
+		if j.PlayerID != sg.undoable.player {
+			return errors.New("Only the player who made the last move can ask to undo it")
+		}
+		sg.undoConsent = make(map[uuid.UUID]bool)
+	}
+	sg.undoConsent[j.PlayerID] = true
+
+	for _, p := range sg.Players {
+		if !p.Resigned && !p.Bot && !sg.undoConsent[p.ID] {
+			return nil
+		}
+	}
+	return sg.record(Event{Type: MoveUndone, Player: sg.undoable.player})
+}
+
+// applyUndo restores the game to how it was before its last move, giving the
+// player who made it their turn back
+func (sg *ScrabbleGame) applyUndo(e Event) error {
+	u := sg.undoable
+	if u == nil || u.player != e.Player {
+		return errors.New("No move to undo")
+	}
+
+	sg.Board = u.board
+	sg.TileBag = u.bag
+	sg.TurnCount = u.turnCount
+	sg.TurnStarted = e.Time
+	sg.keepUndone(u.moves)
+	sg.history = sg.history[:u.moves]
+	sg.lastPlay = u.lastPlay
+	sg.lastChallenge = u.lastChallenge
+	for id, r := range u.racks {
+		p := sg.Players[id]
+		p.Tiles, p.Score, p.Skip, p.TimeLeft = r.tiles, r.score, r.skip, r.timeLeft
+	}
+	sg.undoable = nil
+	return nil
+}
+
+// keepUndone notes the moves after the first moves as being undone, so clients
+// that have seen them are told to take them back
+func (sg *ScrabbleGame) keepUndone(moves int) {
+	if sg.undone == nil {
+		sg.undone = &undoneMoves{moves: moves}
+	}
+	sg.undone.moves = min(sg.undone.moves, moves)
+	sg.undone.undos = append(sg.undone.undos, moves)
+	for _, m := range sg.history[moves:] {
+		sg.undone.squares = append(sg.undone.squares, m.Squares...)
+	}
+}
+
+// undoCount returns the number of moves that have been undone in the game.
+// The game must be locked by the caller.
+func (sg *ScrabbleGame) undoCount() int {
+	if sg.undone == nil {
+		return 0
+	}
+	return len(sg.undone.undos)
+}
+
+// undoneSince returns the fewest of the moves given that are left after the
+// undos made since the number of undos given, so clients that have seen those
+// moves can be sent them again from there. The game must be locked by the
+// caller.
+func (sg *ScrabbleGame) undoneSince(undos, moves int) int {
+	if sg.undone == nil {
+		return moves
+	}
+	for _, left := range sg.undone.undos[undos:] {
+		moves = min(moves, left)
+	}
+	return moves
+}
+
+// undoHandler handles requests from players to undo the last move of a
+// friendly game, or to agree to undo it. It will respond using the
+// GameStateResponse struct.
+func (s *Server) undoHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   j.GameID,
+		PlayerID: *j.PlayerID,
+		Type:     undoRequest,
+	}, w, r)
+}
+
+// addUndoHandler handles requests from players to undo the last move of a
+// friendly game like undoHandler, for the game in the request's path
+func (s *Server) addUndoHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	j, ok := decodePlayerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	s.gameRequestHelper(GamePlayRequest{
+		GameID:   gameID,
+		PlayerID: j.PlayerID,
+		Type:     undoRequest,
+	}, w, r)
+}
@@ -0,0 +1,89 @@
+package wordgameserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	g := createScrabbleGame()
+	g.Options.Friendly = true
+	first, _ := g.addPlayer("ashley1")
+	second, _ := g.addPlayer("ashley2")
+
+	g.Lock()
+	defer g.Unlock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	if err := g.undo(GamePlayRequest{PlayerID: first}); err == nil {
+		t.Error("Undo before any move should fail")
+	}
+
+	// Any word is accepted without a dictionary, so play the first two tiles
+	rack := append(Letters(nil), g.Players[first].Tiles...)
+	bag := append(TileBag(nil), g.TileBag...)
+	play := GamePlayRequest{
+		PlayerID: first,
+		StartPos: SquareCoordinate{Row: 7, Col: 7},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    append(Letters(nil), rack[:2]...),
+	}
+	for _, t := range play.Tiles {
+		if t == ' ' {
+			play.Blanks = append(play.Blanks, 'A')
+		}
+	}
+	if err := g.executePlay(play); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.undo(GamePlayRequest{PlayerID: second}); err == nil {
+		t.Error("Undo asked for by the other player should fail")
+	}
+	if err := g.undo(GamePlayRequest{PlayerID: first}); err != nil {
+		t.Fatal(err)
+	} else if !g.getState(second, g.playerList()).UndoRequested || len(g.history) != 1 {
+		t.Fatal("Move should wait for the other player to agree to undo it")
+	}
+	if err := g.undo(GamePlayRequest{PlayerID: second}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(g.Board, NewBoard()) || len(g.history) != 0 || g.TurnCount != 0 {
+		t.Error("Undo should clear the move from the board and give the turn back")
+	} else if p := g.Players[first]; !reflect.DeepEqual(p.Tiles, rack) || p.Score != 0 {
+		t.Errorf("Player has %v scoring %v, expected %v scoring 0", p.Tiles, p.Score, rack)
+	} else if !reflect.DeepEqual(g.TileBag, bag) {
+		t.Error("Undo should return the tiles drawn to the bag")
+	} else if g.getState(second, g.playerList()).UndoRequested {
+		t.Error("Undo request should be cleared once the move is undone")
+	}
+
+	// Games restored from their events are undone the same way
+	data, err := EncodeGame(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+	if !reflect.DeepEqual(d.Board, g.Board) || !reflect.DeepEqual(d.Players[first].Tiles, rack) || d.TurnCount != 0 {
+		t.Error("Decoded game should have the move undone")
+	}
+
+	g.Options.Friendly = false
+	if err = g.executePlay(play); err != nil {
+		t.Fatal(err)
+	} else if err = g.undo(GamePlayRequest{PlayerID: first}); err == nil {
+		t.Error("Undo outside a friendly game should fail")
+	}
+
+	if err = (GameOptions{Friendly: true, Rated: true}).validate(); err == nil {
+		t.Error("Rated games should not be able to be friendly")
+	}
+}
@@ -0,0 +1,76 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// UnseenTilesResponse is the format of the response sent to a player with the
+// tiles they can't see, for tracking which tiles are still to come
+type UnseenTilesResponse struct {
+	GameID uuid.UUID `json:"game_id"`
+	Bag    int       `json:"bag"`   // number of the unseen tiles still in the bag
+	Tiles  []Tile    `json:"tiles"` // tiles in the bag and the other players' racks, in alphabetical order with blanks first
+}
+
+// unseenTiles returns the tiles the player can't see, which are those in the
+// bag and the other players' racks together. Which tiles are where isn't
+// given away.
+func (sg *ScrabbleGame) unseenTiles(playerID uuid.UUID) (UnseenTilesResponse, error) {
+	sg.Lock()
+	defer sg.Unlock()
+
+	if _, ok := sg.Players[playerID]; !ok {
+		return UnseenTilesResponse{}, errors.New("No player with that ID in game")
+	}
+
+	unseen := append(Letters(nil), sg.TileBag...)
+	for id, p := range sg.Players {
+		if id != playerID {
+			unseen = append(unseen, p.Tiles...)
+		}
+	}
+	sort.Slice(unseen, func(i, j int) bool { return unseen[i] < unseen[j] })
+
+	return UnseenTilesResponse{GameID: sg.ID, Bag: len(sg.TileBag), Tiles: sg.variant.rack(unseen)}, nil
+}
+
+// unseenTilesHandler handles requests from players, identified by the
+// player_id query parameter, for the tiles they can't see in a game,
+// identified by its path or the game_id query parameter
+func (s *Server) unseenTilesHandler(w http.ResponseWriter, r *http.Request) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return
+	}
+	playerID, err := uuid.Parse(r.URL.Query().Get("player_id"))
+	if err != nil {
+		http.Error(w, "Invalid player_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return
+	}
+	unseen, err := g.unseenTiles(playerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := json.Marshal(unseen)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -0,0 +1,50 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnseenTilesHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGS XX")
+	g.TileBag = TileBag("QZ E")
+
+	srv := newTestServer(t)
+	srv.games.Put(g)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/v2/games/"+g.ID.String()+"/unseen"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("?player_id=" + ids[0].String())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	var resp UnseenTilesResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	unseen := make(Letters, len(resp.Tiles))
+	for i, tile := range resp.Tiles {
+		unseen[i] = tile.Letter
+	}
+	if string(unseen) != "  DEGOQSXXZ" || resp.Bag != 4 || resp.GameID != g.ID {
+		t.Errorf("Got %+v, expected the bag and the second player's rack", resp)
+	} else if resp.Tiles[len(resp.Tiles)-1].Value != 10 {
+		t.Errorf("Z has value %v, expected 10", resp.Tiles[len(resp.Tiles)-1].Value)
+	}
+
+	for _, query := range []string{"", "?player_id=" + g.ID.String()} {
+		if rr = get(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("Getting %v returned status code %v, expected %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// MoveValidationResponse is the format of the response sent to clients when
+// they check a move before making it
+type MoveValidationResponse struct {
+	Valid bool     `json:"valid"`
+	Error string   `json:"error,omitempty"` // why the move would be rejected, if it isn't valid
+	Code  string   `json:"code,omitempty"`  // code of the placement rule the move breaks, if any
+	Words []string `json:"words,omitempty"` // words a valid play would form
+	Score int      `json:"score,omitempty"` // points a valid play would score
+}
+
+// validateMoveHandler handles requests from players to check a play or swap
+// without making it, so clients can warn about invalid words or misplaced
+// tiles before the move is submitted. It will respond using the
+// MoveValidationResponse struct.
+func (s *Server) validateMoveHandler(w http.ResponseWriter, r *http.Request) {
+	var j GamePlayRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.validateMove(w, r, j)
+}
+
+// validateGameMoveHandler handles requests from players to check a move like
+// validateMoveHandler, for the game in the request's path. Only plays and
+// swaps can be checked.
+func (s *Server) validateGameMoveHandler(w http.ResponseWriter, r *http.Request) {
+	var j GameMoveRequest
+
+	gameID, ok := pathGameID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := GamePlayRequest{
+		GameID:   gameID,
+		PlayerID: j.PlayerID,
+	}
+	switch j.Action {
+	case ActionPlay, "":
+		req.StartPos, req.EndPos = j.StartPos, j.EndPos
+		req.Tiles, req.Blanks = j.Tiles, j.Blanks
+		req.Placements = j.Placements
+	case ActionSwap:
+		req.Tiles = j.Tiles
+		req.Swap = true
+	default:
+		http.Error(w, "Only plays and swaps can be validated", http.StatusBadRequest)
+		return
+	}
+
+	s.validateMove(w, r, req)
+}
+
+// validateMove replies with whether the move could be made in its game now,
+// and what a play would form and score. A move that would be rejected is
+// still a successful request, with the reason in the response.
+func (s *Server) validateMove(w http.ResponseWriter, r *http.Request, j GamePlayRequest) {
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	// The move is checked against the game as it stands rather than queued
+	// for the controller, since nothing is changed
+	g.Lock()
+	var resp MoveValidationResponse
+	if _, ok := g.Players[j.PlayerID]; !ok {
+		g.Unlock()
+		http.Error(w, "No player with that ID in game", http.StatusBadRequest)
+		return
+	} else if !g.Active {
+		resp.Error = "Game has not started"
+	} else if g.Finished {
+		resp.Error = "Game is over"
+	} else if resp.Words, resp.Score, err = g.checkMove(j); err != nil {
+		resp.Error = err.Error()
+		var pe *PlacementError
+		if errors.As(err, &pe) {
+			resp.Code = pe.Code
+		}
+	} else {
+		resp.Valid = true
+	}
+	g.Unlock()
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
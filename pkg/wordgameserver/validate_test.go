@@ -0,0 +1,89 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestValidateMoveHandler(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	defer g.Stop()
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+
+	srv, err := NewServer(DefaultConfig(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.games.Put(g)
+
+	validate := func(path string, body interface{}) MoveValidationResponse {
+		t.Helper()
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", path, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+		}
+
+		var resp MoveValidationResponse
+		if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	play := GamePlayRequest{
+		GameID:   g.ID,
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	}
+	resp := validate("/v1/game/validate", play)
+	expected := MoveValidationResponse{Valid: true, Words: []string{"CAT"}, Score: 5}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Errorf("Validated %+v, expected %+v", resp, expected)
+	}
+
+	play.Tiles = Letters("TAC")
+	if resp = validate("/v1/game/validate", play); resp.Valid || resp.Error != "Words not in dictionary: TAC" {
+		t.Errorf("Validated %+v, expected an invalid word", resp)
+	}
+
+	play.StartPos = SquareCoordinate{Row: 2, Col: 2}
+	play.EndPos = SquareCoordinate{Row: 2, Col: 4}
+	if resp = validate("/v1/game/validate", play); resp.Valid || resp.Code != ErrOffCenter.Code {
+		t.Errorf("Validated %+v, expected code %v", resp, ErrOffCenter.Code)
+	}
+
+	resp = validate("/v2/games/"+g.ID.String()+"/validate", GameMoveRequest{
+		PlayerID: ids[1],
+		Action:   ActionSwap,
+		Tiles:    Letters("D"),
+	})
+	if resp.Valid || resp.Error == "" {
+		t.Errorf("Validated %+v, expected a swap out of turn to be rejected", resp)
+	}
+
+	// Validating doesn't change the game
+	g.Lock()
+	if len(g.history) != 0 || g.TurnCount != 0 || g.Players[ids[0]].Tiles.String() != "CATXXXX" {
+		t.Error("Validating a move should not make it")
+	}
+	g.Unlock()
+}
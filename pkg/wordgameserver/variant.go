@@ -0,0 +1,333 @@
+package wordgameserver
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+	"github.com/pkg/errors"
+)
+
+// Variants a game can be played as
+const (
+	VariantStandard         = "standard" // 15x15 board with 100 tiles
+	VariantSuper            = "super"    // Super Scrabble: 21x21 board with quadruple premiums and 200 tiles
+	VariantWordsWithFriends = "wwf"      // Words With Friends: its own premium layout, 104 tiles with their own values and a smaller bingo bonus
+)
+
+// variant is a version of the game with its own board and set of tiles
+type variant struct {
+	name  string
+	size  int             // number of rows and columns on the board
+	board ScrabbleBoard   // empty board, copied for each game
+	tiles map[Letter]Tile // value of each tile, and how many there are
+	bag   TileBag         // full bag, copied and shuffled for each game
+	bingo int             // bonus for playing every tile in a full hand in one turn
+}
+
+// standardVariant is played when a game doesn't choose a variant
+var standardVariant = newVariant(VariantStandard, 15, map[string][]SquareCoordinate{
+	"star": {
+		{Row: 7, Col: 7},
+	},
+	"doubleLetter": {
+		{Row: 0, Col: 3},
+		{Row: 2, Col: 6},
+		{Row: 3, Col: 0},
+		{Row: 3, Col: 7},
+		{Row: 6, Col: 2},
+		{Row: 6, Col: 6},
+		{Row: 7, Col: 3},
+	},
+	"doubleWord": {
+		{Row: 1, Col: 1},
+		{Row: 2, Col: 2},
+		{Row: 3, Col: 3},
+		{Row: 4, Col: 4},
+	},
+	"tripleLetter": {
+		{Row: 1, Col: 5},
+		{Row: 5, Col: 1},
+		{Row: 5, Col: 5},
+	},
+	"tripleWord": {
+		{Row: 0, Col: 0},
+		{Row: 0, Col: 7},
+		{Row: 7, Col: 0},
+	},
+}, tiles, bingoBonus)
+
+// superVariant is Super Scrabble, for longer games with more players
+var superVariant = newVariant(VariantSuper, 21, map[string][]SquareCoordinate{
+	"star": {
+		{Row: 10, Col: 10},
+	},
+	"doubleLetter": {
+		{Row: 0, Col: 3},
+		{Row: 0, Col: 10},
+		{Row: 3, Col: 0},
+		{Row: 3, Col: 6},
+		{Row: 5, Col: 9},
+		{Row: 6, Col: 3},
+		{Row: 6, Col: 10},
+		{Row: 9, Col: 5},
+		{Row: 9, Col: 9},
+		{Row: 10, Col: 0},
+		{Row: 10, Col: 6},
+	},
+	"doubleWord": {
+		{Row: 1, Col: 1},
+		{Row: 1, Col: 8},
+		{Row: 2, Col: 2},
+		{Row: 2, Col: 9},
+		{Row: 4, Col: 4},
+		{Row: 5, Col: 5},
+		{Row: 6, Col: 6},
+		{Row: 7, Col: 7},
+		{Row: 8, Col: 1},
+		{Row: 9, Col: 2},
+	},
+	"tripleLetter": {
+		{Row: 1, Col: 4},
+		{Row: 4, Col: 1},
+		{Row: 4, Col: 8},
+		{Row: 8, Col: 4},
+		{Row: 8, Col: 8},
+	},
+	"tripleWord": {
+		{Row: 0, Col: 7},
+		{Row: 3, Col: 3},
+		{Row: 3, Col: 10},
+		{Row: 7, Col: 0},
+		{Row: 10, Col: 3},
+	},
+	"quadrupleLetter": {
+		{Row: 2, Col: 5},
+		{Row: 5, Col: 2},
+	},
+	"quadrupleWord": {
+		{Row: 0, Col: 0},
+	},
+}, map[Letter]Tile{
+	' ': {Letter: ' ', Count: 4, Value: 0},
+	'A': {Letter: 'A', Count: 16, Value: 1},
+	'B': {Letter: 'B', Count: 4, Value: 3},
+	'C': {Letter: 'C', Count: 6, Value: 3},
+	'D': {Letter: 'D', Count: 8, Value: 2},
+	'E': {Letter: 'E', Count: 24, Value: 1},
+	'F': {Letter: 'F', Count: 4, Value: 4},
+	'G': {Letter: 'G', Count: 5, Value: 2},
+	'H': {Letter: 'H', Count: 5, Value: 4},
+	'I': {Letter: 'I', Count: 13, Value: 1},
+	'J': {Letter: 'J', Count: 2, Value: 8},
+	'K': {Letter: 'K', Count: 2, Value: 5},
+	'L': {Letter: 'L', Count: 7, Value: 1},
+	'M': {Letter: 'M', Count: 6, Value: 3},
+	'N': {Letter: 'N', Count: 13, Value: 1},
+	'O': {Letter: 'O', Count: 15, Value: 1},
+	'P': {Letter: 'P', Count: 4, Value: 3},
+	'Q': {Letter: 'Q', Count: 2, Value: 10},
+	'R': {Letter: 'R', Count: 13, Value: 1},
+	'S': {Letter: 'S', Count: 10, Value: 1},
+	'T': {Letter: 'T', Count: 15, Value: 1},
+	'U': {Letter: 'U', Count: 7, Value: 1},
+	'V': {Letter: 'V', Count: 3, Value: 4},
+	'W': {Letter: 'W', Count: 4, Value: 4},
+	'X': {Letter: 'X', Count: 2, Value: 8},
+	'Y': {Letter: 'Y', Count: 4, Value: 4},
+	'Z': {Letter: 'Z', Count: 2, Value: 10},
+}, bingoBonus)
+
+// wwfVariant is Words With Friends, for players who expect its rules
+var wwfVariant = newVariant(VariantWordsWithFriends, 15, map[string][]SquareCoordinate{
+	"star": {
+		{Row: 7, Col: 7},
+	},
+	"doubleLetter": {
+		{Row: 1, Col: 2},
+		{Row: 2, Col: 1},
+		{Row: 2, Col: 4},
+		{Row: 4, Col: 2},
+		{Row: 4, Col: 6},
+		{Row: 6, Col: 4},
+	},
+	"doubleWord": {
+		{Row: 1, Col: 5},
+		{Row: 3, Col: 7},
+		{Row: 5, Col: 1},
+		{Row: 7, Col: 3},
+	},
+	"tripleLetter": {
+		{Row: 0, Col: 6},
+		{Row: 3, Col: 3},
+		{Row: 5, Col: 5},
+		{Row: 6, Col: 0},
+	},
+	"tripleWord": {
+		{Row: 0, Col: 3},
+		{Row: 3, Col: 0},
+	},
+}, map[Letter]Tile{
+	' ': {Letter: ' ', Count: 2, Value: 0},
+	'A': {Letter: 'A', Count: 9, Value: 1},
+	'B': {Letter: 'B', Count: 2, Value: 4},
+	'C': {Letter: 'C', Count: 2, Value: 4},
+	'D': {Letter: 'D', Count: 5, Value: 2},
+	'E': {Letter: 'E', Count: 13, Value: 1},
+	'F': {Letter: 'F', Count: 2, Value: 4},
+	'G': {Letter: 'G', Count: 3, Value: 3},
+	'H': {Letter: 'H', Count: 4, Value: 3},
+	'I': {Letter: 'I', Count: 8, Value: 1},
+	'J': {Letter: 'J', Count: 1, Value: 10},
+	'K': {Letter: 'K', Count: 1, Value: 5},
+	'L': {Letter: 'L', Count: 4, Value: 2},
+	'M': {Letter: 'M', Count: 2, Value: 4},
+	'N': {Letter: 'N', Count: 5, Value: 2},
+	'O': {Letter: 'O', Count: 8, Value: 1},
+	'P': {Letter: 'P', Count: 2, Value: 4},
+	'Q': {Letter: 'Q', Count: 1, Value: 10},
+	'R': {Letter: 'R', Count: 6, Value: 1},
+	'S': {Letter: 'S', Count: 5, Value: 1},
+	'T': {Letter: 'T', Count: 7, Value: 1},
+	'U': {Letter: 'U', Count: 4, Value: 2},
+	'V': {Letter: 'V', Count: 2, Value: 5},
+	'W': {Letter: 'W', Count: 2, Value: 4},
+	'X': {Letter: 'X', Count: 1, Value: 8},
+	'Y': {Letter: 'Y', Count: 2, Value: 3},
+	'Z': {Letter: 'Z', Count: 1, Value: 10},
+}, 35)
+
+// variants holds every variant by name
+var variants = map[string]*variant{
+	VariantStandard:         standardVariant,
+	VariantSuper:            superVariant,
+	VariantWordsWithFriends: wwfVariant,
+}
+
+// newVariant lays out the variant's board, with premiums given for the top
+// left quadrant, and fills its bag with the tiles
+func newVariant(name string, size int, premiums map[string][]SquareCoordinate, tiles map[Letter]Tile, bingo int) *variant {
+	v := variant{
+		name:  name,
+		size:  size,
+		board: engine.LayoutBoard(size, premiums),
+		tiles: tiles,
+		bag:   fillBag(tiles),
+		bingo: bingo,
+	}
+	return &v
+}
+
+// fillBag returns a bag holding every tile of the set in alphabetical order,
+// so bags shuffled with the same seed always hold their tiles in the same order
+func fillBag(tiles map[Letter]Tile) TileBag {
+	var bag TileBag
+	for t := range tiles {
+		for i := 0; i < tiles[t].Count; i++ {
+			bag = append(bag, t)
+		}
+	}
+	sort.Slice(bag, func(i, j int) bool { return bag[i] < bag[j] })
+	return bag
+}
+
+// lookupVariant returns the variant with the name, or the standard one if the
+// name is empty
+func lookupVariant(name string) (*variant, error) {
+	if name == "" {
+		return standardVariant, nil
+	}
+	v, ok := variants[name]
+	if !ok {
+		return nil, errors.New("Unknown variant '" + name + "'")
+	}
+	return v, nil
+}
+
+// NewVariantBoard returns an empty board for a game of the named variant, with
+// its premium squares laid out
+func NewVariantBoard(name string) (ScrabbleBoard, error) {
+	v, err := lookupVariant(name)
+	if err != nil {
+		return nil, err
+	}
+	return v.newBoard(), nil
+}
+
+// VariantTiles returns the tiles of the named variant, with how many of each
+// there are and their values, and its bonus for playing a full hand
+func VariantTiles(name string) (map[Letter]Tile, int, error) {
+	v, err := lookupVariant(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	tiles := make(map[Letter]Tile, len(v.tiles))
+	for l, t := range v.tiles {
+		tiles[l] = t
+	}
+	return tiles, v.bingo, nil
+}
+
+// UnseenTiles returns the tiles of the named variant a player can't see from
+// their rack and the board, which are those in the bag and the other players'
+// hands together, in alphabetical order
+func UnseenTiles(name string, board ScrabbleBoard, rack Letters) (Letters, error) {
+	v, err := lookupVariant(name)
+	if err != nil {
+		return nil, err
+	}
+	return v.unseen(board, rack), nil
+}
+
+// unseen returns the tiles of the variant not on the board or in the hand,
+// with blanks played on the board counted as blanks
+func (v *variant) unseen(board ScrabbleBoard, hand Letters) Letters {
+	counts := make(map[Letter]int, len(v.tiles))
+	for l, t := range v.tiles {
+		counts[l] = t.Count
+	}
+	for _, row := range board {
+		for _, squ := range row {
+			if squ.Blank {
+				counts[' ']--
+			} else if squ.Occupied() {
+				counts[squ.Letter]--
+			}
+		}
+	}
+	for _, l := range hand {
+		counts[l]--
+	}
+
+	var unseen Letters
+	for _, l := range v.bag {
+		if counts[l] > 0 {
+			counts[l]--
+			unseen = append(unseen, l)
+		}
+	}
+	return unseen
+}
+
+// newBoard returns an empty board for a game of the variant
+func (v *variant) newBoard() ScrabbleBoard {
+	return v.board.Clone()
+}
+
+// newBag returns a bag holding every tile of the variant, shuffled by the
+// source given
+func (v *variant) newBag(r *rand.Rand) TileBag {
+	bag := make(TileBag, len(v.bag))
+	copy(bag, v.bag)
+	bag.Shuffle(r)
+	return bag
+}
+
+// rack returns the tiles of a hand with their values in the variant
+func (v *variant) rack(hand Letters) []Tile {
+	rack := make([]Tile, len(hand))
+	for i, l := range hand {
+		rack[i] = Tile{Letter: l, Value: v.tiles[l].Value}
+	}
+	return rack
+}
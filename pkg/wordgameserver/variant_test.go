@@ -0,0 +1,140 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/engine"
+)
+
+func TestSuperGame(t *testing.T) {
+	srv := newTestServer(t)
+
+	create := func(opts GameOptions) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, err := json.Marshal(GeneralGameRequest{Options: &opts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", "/game/create", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.createGameHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := create(GameOptions{Variant: "giant"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Creating a game of an unknown variant returned status code %v, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := create(GameOptions{Variant: VariantSuper})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Creating a Super Scrabble game returned status code %v. Error: %v", rr.Code, rr.Body)
+	}
+	var resp GeneralGameRequest
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	g, err := srv.games.Get(resp.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	g.Lock()
+	defer g.Unlock()
+	if len(g.Board) != 21 || len(g.TileBag) != 200 {
+		t.Fatalf("Game has a board of %v rows and %v tiles, expected 21 and 200", len(g.Board), len(g.TileBag))
+	}
+	if g.Board[0][0].SquareType != "quadrupleWord" || g.Board[10][10].SquareType != "star" {
+		t.Errorf("Board has %v and %v in the corner and center, expected quadrupleWord and star",
+			g.Board[0][0].SquareType, g.Board[10][10].SquareType)
+	}
+
+	// Plays can reach the squares beyond a standard board, and score their
+	// premiums
+	board, placed, words, err := g.Board.LayTiles(engine.Play{
+		StartPos: SquareCoordinate{Row: 0, Col: 18},
+		EndPos:   SquareCoordinate{Row: 0, Col: 20},
+		Tiles:    Letters("CAT"),
+	}, g.variant.tiles)
+	if err != nil {
+		t.Fatal(err)
+	} else if score := board.ScorePlay(placed, words, g.variant.bingo); score != 4*(3+1+1) {
+		t.Errorf("CAT across the top right corner scored %v, expected %v", score, 4*(3+1+1))
+	}
+	if g.Board[0][20].Occupied() {
+		t.Error("Laying tiles changed the game's board")
+	}
+
+	// The variant survives the game being saved and loaded
+	data, err := EncodeGame(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeGame(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(d.Board) != 21 || d.Options.Variant != VariantSuper {
+		t.Errorf("Decoded game has a board of %v rows as variant %q, expected 21 as %q", len(d.Board), d.Options.Variant, VariantSuper)
+	}
+}
+
+func TestWordsWithFriendsScoring(t *testing.T) {
+	g := createVariantGame(wwfVariant, nil, nil, nil)
+	if len(g.TileBag) != 104 {
+		t.Errorf("Words With Friends game has %v tiles, expected 104", len(g.TileBag))
+	}
+
+	tests := []struct {
+		start, end SquareCoordinate
+		tiles      string
+		score      int
+	}{
+		// Tiles have their Words With Friends values
+		{SquareCoordinate{Row: 7, Col: 5}, SquareCoordinate{Row: 7, Col: 7}, "JAB", 10 + 1 + 4},
+		// A full hand earns the smaller bingo bonus, here across a double word
+		{SquareCoordinate{Row: 7, Col: 1}, SquareCoordinate{Row: 7, Col: 7}, "ABCDEFG", (1+4+4+2+1+4+3)*2 + 35},
+	}
+	for _, tt := range tests {
+		score, _, err := ScoreVariantPlay(VariantWordsWithFriends, g.Board, GamePlayRequest{StartPos: tt.start, EndPos: tt.end, Tiles: Letters(tt.tiles)})
+		if err != nil {
+			t.Fatal(err)
+		} else if score != tt.score {
+			t.Errorf("%v scored %v, expected %v", tt.tiles, score, tt.score)
+		}
+	}
+}
+
+func TestUnseenTiles(t *testing.T) {
+	board := NewBoard()
+	for i, tile := range []Tile{{Letter: 'C', Value: 3}, {Letter: 'A', Blank: true}, {Letter: 'T', Value: 1}} {
+		board[7][6+i].Tile = tile
+	}
+
+	unseen, err := UnseenTiles(VariantStandard, board, Letters("QZ"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(unseen) != 95 {
+		t.Errorf("%v tiles unseen, expected 95", len(unseen))
+	}
+
+	counts := make(map[Letter]int)
+	for _, l := range unseen {
+		counts[l]++
+	}
+	for l, expected := range map[Letter]int{' ': 1, 'A': 9, 'C': 1, 'T': 5, 'Q': 0, 'Z': 0, 'E': 12} {
+		if counts[l] != expected {
+			t.Errorf("%v of %q unseen, expected %v", counts[l], l, expected)
+		}
+	}
+
+	if _, err = UnseenTiles("chess", board, nil); err == nil {
+		t.Error("Unseen tiles of an unknown variant should fail")
+	}
+}
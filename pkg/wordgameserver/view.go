@@ -0,0 +1,181 @@
+package wordgameserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// stateView is a copy of a game's state that is never changed once made, so
+// players' views can be read from it without the game's lock or a round trip
+// through its stateController. A new copy replaces it whenever the game
+// changes.
+type stateView struct {
+	state   GameStateResponse    // state every player sees alike
+	racks   map[uuid.UUID][]Tile // each player's rack
+	moves   []Move               // every move made, without racks until the game has finished
+	undone  undoneMoves          // moves that have been undone, with no squares if none have
+	rematch *Rematch             // game started for the players to play again, if there is one
+	taken   time.Time            // when the copy was made, so running clocks can be brought up to date
+	version string               // identifies the copy by the game's count of copies, for clients to say which they already have
+}
+
+// updateView replaces the game's view with a copy of its current state. The
+// game must be locked by the caller.
+func (sg *ScrabbleGame) updateView(playerList []*Player) {
+	v := &stateView{
+		state:   sg.sharedState(playerList),
+		racks:   make(map[uuid.UUID][]Tile, len(sg.Players)),
+		rematch: sg.rematch,
+		taken:   time.Now(),
+	}
+	sg.viewVersion++
+	v.version = strconv.FormatUint(sg.viewVersion, 36)
+
+	// Players are changed in place as the game goes on, so the view has its
+	// own copies of them
+	v.state.Players = make([]*Player, len(playerList))
+	for i, p := range playerList {
+		cp := *p
+		cp.Tiles, cp.State, cp.Play = nil, nil, nil
+		v.state.Players[i] = &cp
+	}
+	for id, p := range sg.Players {
+		v.racks[id] = sg.variant.rack(p.Tiles)
+	}
+
+	// Racks would give away the tiles players hold
+	v.moves = sg.History()
+	if !sg.Finished {
+		for i := range v.moves {
+			v.moves[i].Rack = nil
+			v.moves[i].Swapped = nil
+		}
+	}
+	if sg.undone != nil {
+		v.undone = undoneMoves{
+			moves:   sg.undone.moves,
+			squares: append([]SquareCoordinate(nil), sg.undone.squares...),
+		}
+	} else {
+		v.undone.moves = len(v.moves)
+	}
+
+	sg.view.Store(v)
+}
+
+// viewState returns the player's view of the game as of its last change,
+// without locking it. It returns false if the game has no view yet or the
+// player isn't in it, so the state must be requested from the game instead.
+func (sg *ScrabbleGame) viewState(playerID uuid.UUID) (GameStateResponse, bool) {
+	v := sg.view.Load()
+	if v == nil {
+		return GameStateResponse{}, false
+	}
+	return v.playerState(playerID)
+}
+
+// viewDiff returns the player's view of the game like viewState, but with
+// what has changed since the number of moves given in place of the board. The
+// whole board is sent to players who haven't seen any moves, as positions
+// can be imported without them.
+func (sg *ScrabbleGame) viewDiff(playerID uuid.UUID, since int) (GameStateResponse, bool) {
+	v := sg.view.Load()
+	if v == nil {
+		return GameStateResponse{}, false
+	}
+	state, ok := v.playerState(playerID)
+	if ok && since > 0 {
+		state.Diff = v.diff(since)
+		state.Board = nil
+		state.version += "-" + strconv.Itoa(since)
+	}
+	return state, ok
+}
+
+// querySince returns the number of moves given by the request's since query
+// parameter, or nil if it has none, replying to the client with an error and
+// returning false if it isn't a number of moves
+func querySince(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	param := r.URL.Query().Get("since")
+	if param == "" {
+		return nil, true
+	}
+	since, err := strconv.Atoi(param)
+	if err != nil || since < 0 {
+		http.Error(w, "Invalid since parameter", http.StatusBadRequest)
+		return nil, false
+	}
+	return &since, true
+}
+
+// diffSince turns a state about to be pushed to a watcher who asked for diffs,
+// by giving the number of moves they had seen, into a diff against what they
+// were last sent. The number seen is moved on to the state's moves. States
+// are pushed whole to watchers who didn't ask, whose since is nil.
+func (sg *ScrabbleGame) diffSince(state GameStateResponse, since *int) GameStateResponse {
+	if since == nil {
+		return state
+	}
+	if d, ok := sg.viewDiff(state.PlayerID, *since); ok {
+		state = d
+	}
+	*since = state.MoveCount
+	return state
+}
+
+// playerState returns the player's view of the game, or false if the player
+// isn't in it
+func (v *stateView) playerState(playerID uuid.UUID) (GameStateResponse, bool) {
+	rack, ok := v.racks[playerID]
+	if !ok {
+		return GameStateResponse{}, false
+	}
+
+	state := v.state
+	state.PlayerID = playerID
+	state.PlayerTiles = rack
+	state.Rematch = v.rematch.forPlayer(playerID)
+	state.version = v.version
+
+	// Only the current player's clock runs, and it has run on since the copy
+	// was made
+	if state.Clocks != nil && state.Active && !state.Finished && !state.Paused {
+		clocks := append([]float64(nil), state.Clocks...)
+		clocks[state.PlayerTurn] -= time.Since(v.taken).Seconds()
+		state.Clocks = clocks
+	}
+	return state, true
+}
+
+// diff returns what has changed since the number of moves given. As well as
+// the squares of every move since, the last move seen may have been retracted
+// by a challenge and moves seen may have been undone, so their squares are
+// sent again too.
+func (v *stateView) diff(since int) *StateDiff {
+	from := min(since, len(v.moves), v.undone.moves)
+	d := &StateDiff{Since: from, Moves: v.moves[from:], Squares: []SquareChange{}}
+
+	sent := make(map[SquareCoordinate]bool)
+	add := func(squares []SquareCoordinate) {
+		for _, sc := range squares {
+			if sent[sc] {
+				continue
+			}
+			sent[sc] = true
+
+			c := SquareChange{Row: sc.Row, Col: sc.Col}
+			if t := v.state.Board[sc.Row][sc.Col].Tile; t.Letter != 0 {
+				c.Tile = &t
+			}
+			d.Squares = append(d.Squares, c)
+		}
+	}
+	for _, m := range v.moves[max(from-1, 0):] {
+		add(m.Squares)
+	}
+	add(v.undone.squares)
+	return d
+}
@@ -0,0 +1,171 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestViewState(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Clock = 600
+	if _, ok := g.viewState(ids[0]); ok {
+		t.Error("Game has a view before it started")
+	}
+
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	passed, err := g.request(GamePlayRequest{PlayerID: ids[0], Type: passRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The view is up to date by the time the move's player hears back, and is
+	// read without the game's lock
+	g.Lock()
+	state, err := g.request(GamePlayRequest{PlayerID: ids[1]})
+	want := g.getState(ids[1], g.playerList())
+	g.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	} else if state.PlayerTurn != passed.PlayerTurn {
+		t.Errorf("View is on player %v's turn, expected player %v's after the pass", state.PlayerTurn, passed.PlayerTurn)
+	} else if !reflect.DeepEqual(state.PlayerTiles, want.PlayerTiles) || !reflect.DeepEqual(state.Board, want.Board) {
+		t.Error("View differs from the game's state")
+	}
+
+	// Clocks keep running between changes
+	time.Sleep(20 * time.Millisecond)
+	later, _ := g.viewState(ids[1])
+	if later.Clocks[later.PlayerTurn] >= state.Clocks[state.PlayerTurn] {
+		t.Errorf("Clock shows %v seconds left, expected less than %v", later.Clocks[later.PlayerTurn], state.Clocks[state.PlayerTurn])
+	} else if later.Clocks[0] != state.Clocks[0] {
+		t.Error("Clock of the player who passed kept running")
+	}
+
+	// The view's players are copies, so changes to the game don't show until
+	// it is next updated
+	g.Lock()
+	g.Players[ids[0]].Score = 100
+	g.Unlock()
+	if later, _ = g.viewState(ids[1]); later.Players[0].Score == 100 {
+		t.Error("View changed along with the game")
+	}
+}
+
+func TestStateDiff(t *testing.T) {
+	srv := newTestServer(t)
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Friendly = true
+	g.Lock()
+	srv.adoptGame(g)
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	srv.games.Put(g)
+	g.Unlock()
+	defer g.Stop()
+
+	play := GamePlayRequest{
+		PlayerID: ids[0],
+		Type:     playRequest,
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	}
+	if _, err := g.request(play); err != nil {
+		t.Fatal(err)
+	}
+
+	// Players who haven't seen a move get the whole board
+	if state, ok := g.viewDiff(ids[1], 0); !ok || state.Diff != nil || state.Board == nil {
+		t.Error("Whole board wasn't sent to a player who hadn't seen any moves")
+	}
+
+	getDiff := func(since string) (*httptest.ResponseRecorder, GameStateResponse) {
+		req, err := http.NewRequest("GET", "/v2/games/"+g.ID.String()+"?player_id="+ids[1].String()+"&since="+since, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+
+		var state GameStateResponse
+		if rr.Code == http.StatusOK {
+			if err = json.NewDecoder(rr.Body).Decode(&state); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr, state
+	}
+
+	if rr, _ := getDiff("-1"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Returned status code %v for a negative since, expected %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// A pass changes no squares, but the play before it may have been
+	// retracted since the client saw it, so its squares are sent again
+	if _, err := g.request(GamePlayRequest{PlayerID: ids[1], Type: passRequest}); err != nil {
+		t.Fatal(err)
+	}
+	rr, state := getDiff("1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	} else if state.Board != nil || state.Diff == nil {
+		t.Fatal("Diff wasn't sent in place of the board")
+	} else if state.MoveCount != 2 || state.Diff.Since != 1 || len(state.Diff.Moves) != 1 || !state.Diff.Moves[0].Pass {
+		t.Errorf("Diff is %+v with %v moves made, expected the pass since move 1", state.Diff, state.MoveCount)
+	} else if len(state.Diff.Squares) != 3 || state.Diff.Squares[0].Tile == nil || state.Diff.Squares[0].Tile.Letter != 'C' {
+		t.Errorf("Diff has squares %+v, expected those of the play", state.Diff.Squares)
+	}
+
+	// Moves seen before they were undone are taken back
+	for _, id := range []uuid.UUID{ids[1], ids[0]} {
+		if _, err := g.request(GamePlayRequest{PlayerID: id, Type: undoRequest}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, state = getDiff("2")
+	if state.Diff == nil || state.Diff.Since != 1 || len(state.Diff.Moves) != 0 {
+		t.Fatalf("Diff is %+v, expected it to follow on from the move before the undone pass", state.Diff)
+	}
+
+	// Tiles of an undone play are taken off the board
+	g, ids = createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.Options.Friendly = true
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	play.PlayerID = ids[0]
+	if _, err := g.request(play); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uuid.UUID{ids[0], ids[1]} {
+		if _, err := g.request(GamePlayRequest{PlayerID: id, Type: undoRequest}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, _ = g.viewDiff(ids[1], 1)
+	if state.Diff == nil || state.Diff.Since != 0 || len(state.Diff.Squares) != 3 {
+		t.Fatalf("Diff is %+v, expected the undone play's squares", state.Diff)
+	}
+	for _, sq := range state.Diff.Squares {
+		if sq.Tile != nil {
+			t.Errorf("Square %v,%v still has a tile after the play was undone", sq.Row, sq.Col)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// stateWait is how long a request for the state to change is held open for
+// when the client doesn't give a timeout, and maxStateWait the longest it can
+// give
+const (
+	stateWait    = 30 * time.Second
+	maxStateWait = 2 * time.Minute
+)
+
+// waitStateHandler sends a player's GameStateResponse like gameStateHandler,
+// but holds the request open until it differs from the version the client
+// already has, named by its If-None-Match header, for clients that can't use
+// WebSockets or Server-Sent Events. Clients without a version are sent the
+// state as soon as the game is underway. If nothing changes before the
+// timeout, the response is 304 Not Modified and the client asks again.
+func (s *Server) waitStateHandler(w http.ResponseWriter, r *http.Request) {
+	var j GeneralGameRequest
+
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if j.PlayerID == nil {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	} else if j.Since != nil && *j.Since < 0 {
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	wait := stateWait
+	if j.Timeout != nil {
+		wait = time.Duration(*j.Timeout) * time.Second
+		if *j.Timeout < 0 || wait > maxStateWait {
+			http.Error(w, "Timeout must be between 0 and "+strconv.Itoa(int(maxStateWait.Seconds()))+" seconds", http.StatusBadRequest)
+			return
+		}
+	}
+
+	g, err := s.getGame(r.Context(), j.GameID, w)
+	if err != nil {
+		return
+	}
+
+	g.Lock()
+	_, ok := g.Players[*j.PlayerID]
+	g.Unlock()
+	if !ok {
+		http.Error(w, "No player with that ID in game", http.StatusBadRequest)
+		return
+	}
+
+	// The view is updated before subscribers are told of a change, so one
+	// made after subscribing can't be missed
+	updates := g.subscribe(*j.PlayerID)
+	defer g.unsubscribe(updates)
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	for {
+		state, ok := g.viewState(*j.PlayerID)
+		if j.Since != nil {
+			state, ok = g.viewDiff(*j.PlayerID, *j.Since)
+		}
+		if ok && !etagMatches(ifNoneMatch, stateETag(r, state)) {
+			writeState(w, r, state)
+			return
+		}
+
+		select {
+		case _, open := <-updates:
+			if open {
+				continue
+			}
+			// Player resumed their session on another connection
+		case <-timeout.C:
+		case <-r.Context().Done():
+			return
+		case <-g.done:
+			// Game was cancelled or removed, or has finished. The final
+			// state of a finished game is sent before it stops.
+			if len(updates) > 0 {
+				continue
+			}
+		}
+
+		if ok {
+			writeState(w, r, state)
+		} else {
+			w.WriteHeader(http.StatusNotModified)
+		}
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitState(t *testing.T) {
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	srv := newTestServer(t)
+	srv.adoptGame(g)
+	srv.games.Put(g)
+
+	g.Lock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	g.Unlock()
+	defer g.Stop()
+
+	wait := func(timeout int, ifNoneMatch string) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(GeneralGameRequest{GameID: g.ID, PlayerID: &ids[1], Timeout: &timeout})
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		req, err := http.NewRequest("POST", "/game/state/wait", bytes.NewBuffer(payload))
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		req.Header.Set("If-None-Match", ifNoneMatch)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(srv.waitStateHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Clients without a version are sent the state once the game is underway
+	rr := wait(5, "")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	} else if etag == "" {
+		t.Fatal("State was sent without an ETag")
+	}
+
+	// Clients with the current version are told nothing changed once their
+	// timeout runs out
+	if rr = wait(0, etag); rr.Code != http.StatusNotModified {
+		t.Errorf("Returned status code %v for an unchanged state, expected %v", rr.Code, http.StatusNotModified)
+	}
+
+	// or are sent the state as soon as it changes
+	results := make(chan *httptest.ResponseRecorder)
+	go func() { results <- wait(5, etag) }()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-results:
+		t.Fatal("Request returned before the state changed")
+	default:
+	}
+
+	if _, err := g.request(GamePlayRequest{PlayerID: ids[0], Type: passRequest}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case rr = <-results:
+		var state GameStateResponse
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Returned status code %v after a move, expected %v", rr.Code, http.StatusOK)
+		} else if rr.Header().Get("ETag") == etag {
+			t.Error("ETag is unchanged after a move")
+		} else if err := json.NewDecoder(rr.Body).Decode(&state); err != nil {
+			t.Fatal(err)
+		} else if state.PlayerTurn != 1 {
+			t.Errorf("State is on player %v's turn, expected player 1's after the pass", state.PlayerTurn)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Request still waiting after the state changed")
+	}
+}
@@ -0,0 +1,165 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is a URL registered when a game is created, which the server posts
+// the game's events to
+type Webhook struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"` // key used to sign payloads, which are unsigned if empty
+}
+
+// Events posted to webhooks
+const (
+	WebhookMovePlayed   = "move_played"   // a player played, swapped, passed or resigned
+	WebhookTurnChanged  = "turn_changed"  // the game started or play passed to another player
+	WebhookGameFinished = "game_finished" // the game ended
+)
+
+// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 of a payload's body,
+// keyed with the webhook's secret and prefixed with "sha256="
+const WebhookSignatureHeader = "X-Wordgame-Signature"
+
+// maxWebhooks is the most webhooks a game can have
+const maxWebhooks = 5
+
+// webhookTimeout is how long a webhook has to respond to each payload
+const webhookTimeout = 10 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	Event   string    `json:"event"`
+	GameID  uuid.UUID `json:"game_id"`
+	Time    time.Time `json:"time"`
+	Players []*Player `json:"players"`
+	Turn    int       `json:"turn"`              // number of the player whose turn it is
+	Move    *Move     `json:"move,omitempty"`    // the move made, for move_played
+	Winners []int     `json:"winners,omitempty"` // numbers of the winning players, for game_finished
+}
+
+// validateWebhooks checks the webhooks can be posted to
+func validateWebhooks(hooks []Webhook) error {
+	if len(hooks) > maxWebhooks {
+		return errors.New("A game can have at most " + strconv.Itoa(maxWebhooks) + " webhooks")
+	}
+	for _, h := range hooks {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("Invalid webhook URL '" + h.URL + "'")
+		}
+	}
+	return nil
+}
+
+// notify posts payloads to the game's webhooks for whatever changed when the
+// event was applied, given the number of moves, whose turn it was and whether
+// the game had finished beforehand. Payloads are encoded straight away, while
+// the game is locked by the caller, and delivered in the background.
+func (sg *ScrabbleGame) notify(e Event, moves int, turn int, finished bool) {
+	if len(sg.webhooks) == 0 || len(sg.Players) == 0 {
+		return
+	}
+
+	playerList := sg.playerList()
+	base := WebhookPayload{
+		GameID:  sg.ID,
+		Time:    e.Time,
+		Players: playerList,
+		Turn:    sg.TurnCount % len(playerList),
+	}
+
+	var payloads []WebhookPayload
+	if len(sg.history) > moves {
+		p := base
+		p.Event = WebhookMovePlayed
+		m := sg.history[len(sg.history)-1]
+		if !sg.Finished {
+			// Racks would give away the tiles players hold
+			m.Rack, m.Swapped = nil, nil
+		}
+		p.Move = &m
+		payloads = append(payloads, p)
+	}
+	if sg.Active && !sg.Finished && (e.Type == GameStarted || base.Turn != turn) {
+		p := base
+		p.Event = WebhookTurnChanged
+		payloads = append(payloads, p)
+	}
+	if sg.Finished && !finished {
+		p := base
+		p.Event = WebhookGameFinished
+		p.Winners = sg.Winners
+		payloads = append(payloads, p)
+	}
+
+	var bodies [][]byte
+	for _, p := range payloads {
+		body, err := json.Marshal(p)
+		if err != nil {
+			log.Printf("Failed to encode webhook payload for game %v: %v", sg.ID, err)
+			return
+		}
+		bodies = append(bodies, body)
+	}
+	if len(bodies) == 0 {
+		return
+	}
+
+	hooks := append([]Webhook(nil), sg.webhooks...)
+	go func() {
+		for _, h := range hooks {
+			for _, body := range bodies {
+				if err := postWebhook(h, body); err != nil {
+					log.Printf("Failed to post to webhook %v for game %v: %v", h.URL, sg.ID, err)
+				}
+			}
+		}
+	}()
+}
+
+// postWebhook posts the payload body to the webhook, signed with its secret
+func postWebhook(h Webhook, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if h.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhook(h.Secret, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("Webhook responded with status " + resp.Status)
+	}
+	return nil
+}
+
+// signWebhook returns the hex encoded HMAC-SHA256 of the body keyed with the
+// secret
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,105 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhooks(t *testing.T) {
+	type delivery struct {
+		payload   WebhookPayload
+		signature string
+	}
+	deliveries := make(chan delivery, 10)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var d delivery
+		if err = json.Unmarshal(body, &d.payload); err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get(WebhookSignatureHeader) == "sha256="+signWebhook("secret", body) {
+			d.signature = "valid"
+		}
+		deliveries <- d
+	}))
+	defer ts.Close()
+
+	g, ids := createTestGame(t, "CATXXXX", "DOGSXXX")
+	g.webhooks = []Webhook{{URL: ts.URL, Secret: "secret"}}
+
+	g.Lock()
+	defer g.Unlock()
+	if err := g.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+
+	next := func() delivery {
+		t.Helper()
+		select {
+		case d := <-deliveries:
+			if d.signature != "valid" {
+				t.Errorf("Payload %v has an invalid signature", d.payload.Event)
+			}
+			return d
+		case <-time.After(5 * time.Second):
+			t.Fatal("No payload delivered")
+		}
+		return delivery{}
+	}
+
+	if d := next(); d.payload.Event != WebhookTurnChanged || d.payload.Turn != 0 {
+		t.Errorf("Expected turn to go to player 0 when the game started, got %+v", d.payload)
+	}
+
+	err := g.executePlay(GamePlayRequest{
+		PlayerID: ids[0],
+		StartPos: SquareCoordinate{Row: 7, Col: 6},
+		EndPos:   SquareCoordinate{Row: 7, Col: 8},
+		Tiles:    Letters("CAT"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := next(); d.payload.Event != WebhookMovePlayed || d.payload.Move == nil || d.payload.Move.Score != 5 {
+		t.Errorf("Expected CAT to be posted as played for 5, got %+v", d.payload)
+	} else if d.payload.Move.Rack != nil {
+		t.Error("Rack should be hidden until the game has finished")
+	}
+	if d := next(); d.payload.Event != WebhookTurnChanged || d.payload.Turn != 1 {
+		t.Errorf("Expected turn to change to player 1, got %+v", d.payload)
+	}
+
+	// Resigning ends a two player game
+	if err = g.resign(GamePlayRequest{PlayerID: ids[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if d := next(); d.payload.Event != WebhookMovePlayed || d.payload.Move == nil || !d.payload.Move.Resign {
+		t.Errorf("Expected resignation to be posted, got %+v", d.payload)
+	}
+	if d := next(); d.payload.Event != WebhookGameFinished || len(d.payload.Winners) != 1 || d.payload.Winners[0] != 0 {
+		t.Errorf("Expected game to finish with player 0 winning, got %+v", d.payload)
+	}
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	if err := validateWebhooks([]Webhook{{URL: "https://example.com/hook"}}); err != nil {
+		t.Error(err)
+	}
+	if err := validateWebhooks([]Webhook{{URL: "ftp://example.com/hook"}}); err == nil {
+		t.Error("Webhooks should only be posted over HTTP")
+	}
+	if err := validateWebhooks(make([]Webhook, maxWebhooks+1)); err == nil {
+		t.Errorf("A game should have at most %v webhooks", maxWebhooks)
+	}
+}
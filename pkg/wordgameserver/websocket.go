@@ -0,0 +1,182 @@
+package wordgameserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// gameSocketHandler upgrades a player's connection to a WebSocket and pushes
+// their GameStateResponse every time the game state changes, so clients don't
+// need to poll the state endpoint. The game is identified by its path or the
+// game_id query parameter, and the player by the player_id query parameter.
+// Players who give the number of moves they have seen as the since parameter
+// are sent diffs instead of whole states.
+func (s *Server) gameSocketHandler(w http.ResponseWriter, r *http.Request) {
+	g, playerID, err := s.getWatcher(w, r)
+	if err != nil {
+		return
+	}
+	since, ok := querySince(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client
+		return
+	}
+	defer conn.Close()
+
+	updates := g.subscribe(playerID)
+	defer g.unsubscribe(updates)
+
+	// Send the current state straight away if the game is underway. This is
+	// checked after subscribing so a game starting now can't be missed.
+	g.Lock()
+	active := g.Active
+	g.Unlock()
+	if active {
+		state, err := g.request(GamePlayRequest{
+			GameID:   g.ID,
+			PlayerID: playerID,
+			ctx:      r.Context(),
+		})
+		if err != nil || conn.WriteJSON(g.diffSince(state, since)) != nil {
+			return
+		}
+	}
+
+	// Clients don't send anything, but the connection must be read from to
+	// process control messages and notice when it closes
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case state, ok := <-updates:
+			if !ok {
+				// Player resumed their session on another connection
+				return
+			}
+			if err := conn.WriteJSON(g.diffSince(state, since)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-g.done:
+			// Game was cancelled or removed, or has finished. The final
+			// state of a finished game is sent before it stops, so any
+			// state still waiting is delivered first.
+			if len(updates) > 0 {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// getWatcher finds the game and player a request to push state updates is for,
+// replying with an error if the player doesn't belong to the game
+func (s *Server) getWatcher(w http.ResponseWriter, r *http.Request) (*ScrabbleGame, uuid.UUID, error) {
+	gameID, err := requestGameID(r)
+	if err != nil {
+		http.Error(w, "Invalid game_id parameter", http.StatusBadRequest)
+		return nil, uuid.Nil, err
+	}
+
+	playerID, err := uuid.Parse(r.URL.Query().Get("player_id"))
+	if err != nil {
+		http.Error(w, "Invalid player_id parameter", http.StatusBadRequest)
+		return nil, uuid.Nil, err
+	}
+
+	g, err := s.getGame(r.Context(), gameID, w)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	g.Lock()
+	_, ok := g.Players[playerID]
+	g.Unlock()
+	if !ok {
+		http.Error(w, "No player with that ID in game", http.StatusBadRequest)
+		return nil, uuid.Nil, errors.New("No player with that ID in game")
+	}
+
+	return g, playerID, nil
+}
+
+// subscribe registers a channel on which the player will receive their game
+// state whenever it changes
+func (sg *ScrabbleGame) subscribe(playerID uuid.UUID) chan GameStateResponse {
+	ch := make(chan GameStateResponse, 1)
+	sg.watchMu.Lock()
+	sg.watchers[ch] = playerID
+	sg.watchMu.Unlock()
+	return ch
+}
+
+// unsubscribe stops updates from being sent on a channel returned by subscribe
+func (sg *ScrabbleGame) unsubscribe(ch chan GameStateResponse) {
+	sg.watchMu.Lock()
+	delete(sg.watchers, ch)
+	sg.watchMu.Unlock()
+}
+
+// dropSubscriptions closes every channel the player is subscribed to, which
+// disconnects their existing WebSockets
+func (sg *ScrabbleGame) dropSubscriptions(playerID uuid.UUID) {
+	sg.watchMu.Lock()
+	defer sg.watchMu.Unlock()
+
+	for ch, id := range sg.watchers {
+		if id == playerID {
+			close(ch)
+			delete(sg.watchers, ch)
+		}
+	}
+}
+
+// broadcast sends each subscribed player their current view of the game,
+// after updating the copy of it state reads are served from. States are taken
+// from that copy, as they are written out after the game's lock is released.
+// It never blocks, so a slow client only ever misses stale states.
+func (sg *ScrabbleGame) broadcast(playerList []*Player) {
+	sg.updateView(playerList)
+
+	sg.watchMu.Lock()
+	defer sg.watchMu.Unlock()
+
+	for ch, playerID := range sg.watchers {
+		state, ok := sg.viewState(playerID)
+		if !ok {
+			state = sg.getState(playerID, playerList)
+		}
+		select {
+		case ch <- state:
+		default:
+			// Replace the state the client hasn't read yet with the latest one
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- state
+		}
+	}
+}
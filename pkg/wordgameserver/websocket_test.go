@@ -0,0 +1,151 @@
+package wordgameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func TestGameSocketHandler(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	firstID, _ := newGame.addPlayer("ashley1")
+	secondID, _ := newGame.addPlayer("ashley2")
+
+	s := httptest.NewServer(http.HandlerFunc(srv.gameSocketHandler))
+	defer s.Close()
+
+	// Connecting as a player that isn't in the game should fail
+	_, _, err := websocket.DefaultDialer.Dial(socketURL(s, newGame.ID, uuid.New()), nil)
+	if err == nil {
+		t.Fatal("Connected with player ID that is not in the game")
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(socketURL(s, newGame.ID, secondID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Wait for the handler to subscribe before starting the game
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		newGame.watchMu.Lock()
+		subscribed = len(newGame.watchers) == 1
+		newGame.watchMu.Unlock()
+	}
+
+	newGame.Lock()
+	err = newGame.start()
+	newGame.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Game start should be pushed to the client
+	state := readSocketState(t, conn)
+	if len(state.PlayerTiles) != maxTiles {
+		t.Fatalf("Pushed state has %v tiles, expected %v", len(state.PlayerTiles), maxTiles)
+	}
+
+	// Playing the first turn should push the new state to the second player
+	first, err := newGame.request(GamePlayRequest{GameID: newGame.ID, PlayerID: firstID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newGame.request(GamePlayRequest{
+		GameID:   newGame.ID,
+		PlayerID: firstID,
+		Tiles:    first.RackLetters()[:2],
+		Swap:     true,
+		Type:     playRequest,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state = readSocketState(t, conn)
+	if state.GameID != newGame.ID {
+		t.Fatalf("Pushed state for game %v, expected %v", state.GameID, newGame.ID)
+	}
+}
+
+func socketURL(s *httptest.Server, gameID, playerID uuid.UUID) string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") +
+		"?game_id=" + gameID.String() + "&player_id=" + playerID.String()
+}
+
+func readSocketState(t *testing.T, conn *websocket.Conn) GameStateResponse {
+	var s GameStateResponse
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&s); err != nil {
+		t.Fatalf("Did not receive pushed state: %v", err)
+	}
+	return s
+}
+
+func TestResumeHandler(t *testing.T) {
+	newGame := createScrabbleGame()
+
+	srv := newTestServer(t)
+	srv.games.Put(newGame)
+
+	playerID, _ := newGame.addPlayer("ashley1")
+	newGame.addPlayer("ashley2")
+
+	s := httptest.NewServer(http.HandlerFunc(srv.gameSocketHandler))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(socketURL(s, newGame.ID, playerID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		newGame.watchMu.Lock()
+		subscribed = len(newGame.watchers) == 1
+		newGame.watchMu.Unlock()
+	}
+
+	payload, err := json.Marshal(GeneralGameRequest{GameID: newGame.ID, PlayerID: &playerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "/game/resume", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.resumeHandler).ServeHTTP(rr, req)
+
+	if c := rr.Code; c != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", c, http.StatusOK, rr.Body)
+	}
+
+	var state GameStateResponse
+	if err = json.NewDecoder(rr.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	} else if state.Active || len(state.Players) != 2 {
+		t.Errorf("Resynced state does not match game: %+v", state)
+	}
+
+	// The old connection should be closed by the server
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if ne, ok := err.(net.Error); err == nil || ok && ne.Timeout() {
+		t.Fatal("Existing WebSocket was not closed on resume")
+	}
+}
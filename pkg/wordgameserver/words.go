@@ -0,0 +1,70 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// WordResponse is the format of the response sent to clients when they look
+// up a word, to show why it was rejected or what it means
+type WordResponse struct {
+	Word       string          `json:"word"`
+	Valid      bool            `json:"valid"`                // true if the server's dictionary accepts the word, or it has none
+	Lexicons   map[string]bool `json:"lexicons,omitempty"`   // whether each lexicon games can choose accepts the word, by name
+	Languages  map[string]bool `json:"languages,omitempty"`  // whether the dictionary of each other language accepts the word, by code
+	Definition string          `json:"definition,omitempty"` // what the word means, if the server's definitions have it
+}
+
+// lookupWord checks the word against every dictionary the server has loaded
+// and finds its definition
+func (s *Server) lookupWord(word string) WordResponse {
+	resp := WordResponse{
+		Word:  strings.ToUpper(word),
+		Valid: s.validator == nil || s.validator.Valid(word),
+	}
+
+	if lv, ok := s.validator.(LexiconValidator); ok {
+		for _, name := range lv.LexiconNames() {
+			if resp.Lexicons == nil {
+				resp.Lexicons = make(map[string]bool)
+			}
+			resp.Lexicons[name] = lv.Lexicon(name).Valid(word)
+		}
+	}
+	if lv, ok := s.validator.(LanguageValidator); ok {
+		for _, code := range lv.LanguageCodes() {
+			if resp.Languages == nil {
+				resp.Languages = make(map[string]bool)
+			}
+			resp.Languages[code] = lv.Language(code).Valid(word)
+		}
+	}
+
+	if s.definitions != nil {
+		resp.Definition, _ = s.definitions.Define(word)
+	}
+	return resp
+}
+
+// wordHandler handles API requests for looking up the word in the request's
+// path
+func (s *Server) wordHandler(w http.ResponseWriter, r *http.Request) {
+	word := mux.Vars(r)["word"]
+	if strings.TrimSpace(word) == "" {
+		http.Error(w, "Missing word", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := json.Marshal(s.lookupWord(word))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -0,0 +1,82 @@
+package wordgameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fantashley/wordgame-controller/pkg/dictionary"
+)
+
+func TestWordHandler(t *testing.T) {
+	twl, err := dictionary.NewWordList(strings.NewReader("CAT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sowpods, err := dictionary.NewWordList(strings.NewReader("CAT\nQI\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := dictionary.NewWordList(strings.NewReader("GATO\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := dictionary.Languages{
+		English:  twl,
+		Others:   map[string]dictionary.WordValidator{LanguageSpanish: es},
+		Lexicons: map[string]dictionary.WordValidator{"SOWPODS": sowpods},
+	}
+
+	cfg := DefaultConfig()
+	cfg.Definitions = filepath.Join(t.TempDir(), "definitions.txt")
+	if err = os.WriteFile(cfg.Definitions, []byte("QI the vital force in Chinese philosophy\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServer(cfg, validator, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/v2/words/qi", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Returned status code %v, expected %v. Error: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	var resp WordResponse
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	expected := WordResponse{
+		Word:       "QI",
+		Lexicons:   map[string]bool{"SOWPODS": true},
+		Languages:  map[string]bool{LanguageSpanish: false},
+		Definition: "the vital force in Chinese philosophy",
+	}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Errorf("Looked up %+v, expected %+v", resp, expected)
+	}
+
+	// Without a dictionary every word is accepted
+	srv, err = NewServer(DefaultConfig(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp = srv.lookupWord("zzz"); !resp.Valid || resp.Lexicons != nil || resp.Definition != "" {
+		t.Errorf("Looked up %+v without a dictionary, expected a valid word with nothing else", resp)
+	}
+
+	cfg.Definitions = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err = NewServer(cfg, validator, nil, nil, nil); err == nil {
+		t.Error("Server started with a missing definitions file")
+	}
+}